@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/cmd/exporter/config"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/aks"
+)
+
+const azureUsage = "usage: cloudcost-exporter azure prices snapshot [-output file] [-region region ...]"
+
+var errAzureUsage = errors.New(azureUsage)
+
+// runAzureCommand runs the `cloudcost-exporter azure` subcommands. Only `prices snapshot` exists for now, which writes
+// the VM and managed disk price catalog the AKS collector uses as JSON.
+func runAzureCommand(ctx context.Context, args []string, stdout io.Writer, logger *slog.Logger) error {
+	if len(args) < 2 || args[0] != "prices" || args[1] != "snapshot" {
+		return errAzureUsage
+	}
+
+	fs := flag.NewFlagSet("azure prices snapshot", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	output := fs.String("output", "-", "File to write the snapshot to, - for stdout.")
+	var regions config.StringSliceFlag
+	fs.Var(&regions, "region", "Azure region to fetch prices for, eg eastus. Can be repeated, defaults to all regions.")
+	if err := fs.Parse(args[2:]); err != nil {
+		return fmt.Errorf("%w: %s", errAzureUsage, err)
+	}
+
+	retailPricesClient, err := retailPriceSdk.NewRetailPricesClient(nil)
+	if err != nil {
+		return fmt.Errorf("error creating retail prices client: %w", err)
+	}
+	snapshot, err := aks.NewPriceSnapshot(ctx, retailPricesClient, logger, regions)
+	if err != nil {
+		return fmt.Errorf("error fetching prices: %w", err)
+	}
+
+	if *output == "-" {
+		return snapshot.Write(stdout)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", *output, err)
+	}
+	if err := snapshot.Write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing %s: %w", *output, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAzureCommand_usage(t *testing.T) {
+	tests := map[string][]string{
+		"no subcommand":      nil,
+		"unknown subcommand": {"prices", "list"},
+		"unknown flag":       {"prices", "snapshot", "-format", "csv"},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := runAzureCommand(context.Background(), args, &stdout, slog.Default())
+			require.ErrorIs(t, err, errAzureUsage)
+			require.Zero(t, stdout.Len())
+		})
+	}
+}
@@ -10,24 +10,179 @@ type Config struct {
 	ProjectID string
 	Providers struct {
 		AWS struct {
-			Profile  string
-			Region   string
-			Services StringSliceFlag
+			Profile string
+			Region  string
+			// DisableIMDS and IMDSTimeout configure the discovery of the region through the EC2 instance metadata
+			// service, see aws.IMDSConfig.
+			DisableIMDS bool
+			IMDSTimeout time.Duration
+			Services    StringSliceFlag
+			EKSMetadata bool
+			IdleCost    bool
+			// CloudWatchLogGroups enables the log group ingestion of the observability collector.
+			CloudWatchLogGroups bool
+			// S3BucketCosts enables the cost of every bucket in the s3 collector.
+			S3BucketCosts bool
+			// S3BucketInventory and S3BucketStorageMetrics enable the buckets of the account in the s3 collector.
+			S3BucketInventory      bool
+			S3BucketStorageMetrics bool
+			// ELBTags are the tags of the load balancers exported as labels, see elb.TagLabel.
+			ELBTags   StringSliceFlag
+			Endpoints StringMapFlag
+			// PricingSource selects the pricing API or the offer files, see aws.PricingSourceAPI.
+			PricingSource string
+			// SpotAggregation aggregates the spot prices of the availability zones by region, see
+			// compute.StructuredPricingMap.SpotAggregation.
+			SpotAggregation string
+			// Auth selects the credentials of the AWS clients, see aws.AuthConfig.
+			Auth                 string
+			RoleARN              string
+			WebIdentityTokenFile string
+			VaultPath            string
+			// AssumeRoleARNs are the roles of the accounts collected by the EC2, EKS and S3 collectors, see
+			// aws.Config.AccountRoleARNs.
+			AssumeRoleARNs StringSliceFlag
+			// Instance selectors narrow down the instances priced by the EKS collector, see compute.InstanceFilter.
+			EKSOnly              bool
+			InstanceTags         StringSliceFlag
+			ExcludeInstanceTags  StringSliceFlag
+			VPCIDs               StringSliceFlag
+			InstanceNames        StringSliceFlag
+			ExcludeInstanceNames StringSliceFlag
+			// Regions and ExcludeRegions are region patterns, see compute.RegionFilter.
+			Regions        StringSliceFlag
+			ExcludeRegions StringSliceFlag
+			// InstanceFamilies and ExcludeInstanceFamilies are family patterns, see pricing.FamilyFilter.
+			InstanceFamilies        StringSliceFlag
+			ExcludeInstanceFamilies StringSliceFlag
+			// CostHistoryFile and CostHistoryDays configure the daily costs recorded locally, see costhistory.History.
+			CostHistoryFile string
+			CostHistoryDays int
 		}
 		GCP struct {
 			DefaultGCSDiscount int
 			Projects           StringSliceFlag
 			Region             string
 			Services           StringSliceFlag
+			IdleCost           bool
+			// BucketCost enables the estimated cost of every bucket in the gcs collector.
+			BucketCost bool
+			// AutoscalerHeadroom enables the cost of the autoscaler headroom of the GKE clusters, see headroom.Scrape.
+			AutoscalerHeadroom bool
+			HierarchyDepth     int
+			// DiscoveryParent and DiscoveryInterval enable the discovery of the projects, see discovery.Projects.
+			DiscoveryParent   string
+			DiscoveryInterval time.Duration
+			Endpoints         StringMapFlag
+			// Auth selects the credentials of the GCP clients, see google.AuthConfig.
+			Auth            string
+			CredentialsFile string
+			VaultPath       string
+			// InstanceFilter is a filter expression of the instances.list API, see compute.Config.
+			InstanceFilter string
+			// MachineFamilies and ExcludeMachineFamilies are family patterns, see pricing.FamilyFilter.
+			MachineFamilies        StringSliceFlag
+			ExcludeMachineFamilies StringSliceFlag
 		}
 		Azure struct {
-			Services       StringSliceFlag
-			SubscriptionId string
+			Services                StringSliceFlag
+			SubscriptionId          string
+			ManagementGroup         string
+			Cloud                   string
+			AuthorityHost           string
+			ResourceManagerEndpoint string
+			// Auth selects the credential of the Azure clients, see azure.AuthConfig.
+			Auth               string
+			TenantID           string
+			ClientID           string
+			FederatedTokenFile string
+			VaultPath          string
+			// ResourceGroups and ExcludeResourceGroups are name patterns, see aks.ResourceGroupFilter.
+			ResourceGroups        StringSliceFlag
+			ExcludeResourceGroups StringSliceFlag
+			// CommitmentPricing prices the VMs covered by reservations and savings plans at their rate.
+			CommitmentPricing bool
+			// CapacityDelta compares the capacity of the scale sets with their running VMs.
+			CapacityDelta bool
+			// VMSizes and ExcludeVMSizes are VM size patterns, see pricing.FamilyFilter.
+			VMSizes        StringSliceFlag
+			ExcludeVMSizes StringSliceFlag
 		}
 	}
+	// Vault configures how the secrets of the vault auth of the providers are read, see secrets.VaultConfig.
+	Vault struct {
+		Address         string
+		TokenFile       string
+		Namespace       string
+		RefreshInterval time.Duration
+	}
+	// Egress configures how the cloud SDK clients reach the provider APIs.
+	Egress struct {
+		ProxyURL string
+		NoProxy  string
+	}
+	Kubernetes struct {
+		AllocatableCost bool
+		// NamespaceCost allocates the cost of the nodes to the namespaces of their pods, it implies AllocatableCost.
+		NamespaceCost bool
+		// StorageClassFile maps the storage classes of the clusters to the parameters their persistent volumes are priced
+		// with.
+		StorageClassFile string
+	}
+	// ClusterName configures how the cluster_name label is normalized across providers.
+	ClusterName struct {
+		Lowercase bool
+		Overrides StringMapFlag
+	}
+	// Schedule configures the off-hours of clusters that are scaled down on a weekly schedule.
+	Schedule struct {
+		OffHours       StringMapFlag
+		Timezone       string
+		OffHoursFactor float64
+	}
+	// Anomaly configures the anomaly scores of the cost of the clusters, see anomaly.Detector.
+	Anomaly struct {
+		Alpha float64
+	}
+	// Spot configures the eviction metrics of the spot instances of the clusters, see eviction.Watcher.
+	Spot struct {
+		EvictionOverhead time.Duration
+	}
+	// Pricing configures the prices layered on top of the retail prices of the catalogs, see pricesource.Layers.
+	Pricing struct {
+		OverrideFile string
+	}
+	// Commitment configures the commitment recommendations of the on-demand cores of the clusters, see
+	// commitment.Recommender.
+	Commitment struct {
+		Window   time.Duration
+		Discount float64
+	}
+	// Tenant maps the scopes of the metrics to the tenants of a shared exporter, see tenant.Tenants.
+	Tenant struct {
+		Scopes    StringMapFlag
+		Default   string
+		Label     string
+		Endpoints bool
+	}
+	// Custom configures the price and inventory feed of the resources outside the cloud providers, see custom.Config.
+	Custom struct {
+		Source          string
+		Format          string
+		RefreshInterval time.Duration
+	}
+	// PriceHistory configures the pricing map snapshots kept in memory, see pricehistory.History.
+	PriceHistory struct {
+		Snapshots int
+	}
 	Collector struct {
-		ScrapeInterval time.Duration
-		Timeout        time.Duration
+		ScrapeInterval  time.Duration
+		ScrapeIntervals DurationMapFlag
+		Timeout         time.Duration
+		// GraceWindow keeps exporting the resources that disappeared from a collection, see grace.Window.
+		GraceWindow time.Duration
+		// DeprecationWindow keeps exporting the renamed metrics under their previous name, see deprecation.Aliases.
+		DeprecationWindow time.Duration
 	}
 
 	Server struct {
@@ -35,6 +190,11 @@ type Config struct {
 		Path    string
 		Timeout time.Duration
 	}
+	// SD configures the target groups served for the HTTP service discovery, see httpsd.Config.
+	SD struct {
+		Target string
+		Labels StringMapFlag
+	}
 	LoggerOpts struct {
 		Level  string // Maps to slog levels: debug, info, warn, error
 		Output string // io.Writer interface to write out to: stdout, stderr, file
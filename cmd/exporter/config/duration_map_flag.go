@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DurationMapFlag parses repeated or comma separated key=duration pairs, eg "s3=24h,eks=15m".
+// Keys are lowercased so that they match regardless of how the service was passed in.
+type DurationMapFlag map[string]time.Duration
+
+func (f *DurationMapFlag) String() string {
+	if f == nil || *f == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f))
+	for key, value := range *f {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f *DurationMapFlag) Set(value string) error {
+	if *f == nil {
+		*f = make(DurationMapFlag)
+	}
+	for _, pair := range strings.Split(value, ",") {
+		key, rawDuration, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid value %q, expected key=duration", pair)
+		}
+		duration, err := time.ParseDuration(rawDuration)
+		if err != nil {
+			return fmt.Errorf("invalid duration for %q: %w", key, err)
+		}
+		(*f)[strings.ToLower(strings.TrimSpace(key))] = duration
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestDurationMapFlag_Set(t *testing.T) {
+	tests := map[string]struct {
+		values  []string
+		exp     DurationMapFlag
+		wantErr bool
+	}{
+		"empty": {
+			values: []string{},
+			exp:    nil,
+		},
+		"single": {
+			values: []string{"-test", "s3=24h"},
+			exp:    DurationMapFlag{"s3": 24 * time.Hour},
+		},
+		"comma separated": {
+			values: []string{"-test", "s3=24h,EKS=15m"},
+			exp:    DurationMapFlag{"s3": 24 * time.Hour, "eks": 15 * time.Minute},
+		},
+		"repeated": {
+			values: []string{"-test", "s3=24h", "-test", "eks=15m"},
+			exp:    DurationMapFlag{"s3": 24 * time.Hour, "eks": 15 * time.Minute},
+		},
+		"missing duration": {
+			values:  []string{"-test", "s3"},
+			wantErr: true,
+		},
+		"invalid duration": {
+			values:  []string{"-test", "s3=daily"},
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var dmf DurationMapFlag
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Var(&dmf, "test", "test")
+			err := fs.Parse(test.values)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exp, got := test.exp.String(), dmf.String(); exp != got {
+				t.Fatalf("expected %q, got %q", exp, got)
+			}
+		})
+	}
+}
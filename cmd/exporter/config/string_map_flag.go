@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StringMapFlag parses repeated or comma separated key=value pairs, eg "Prod-EU=prod-eu,staging_1=staging-1".
+// Keys are lowercased so that they match regardless of their casing, values are kept as is.
+type StringMapFlag map[string]string
+
+func (f *StringMapFlag) String() string {
+	if f == nil || *f == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f))
+	for key, value := range *f {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f *StringMapFlag) Set(value string) error {
+	if *f == nil {
+		*f = make(StringMapFlag)
+	}
+	for _, pair := range strings.Split(value, ",") {
+		key, mapped, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("invalid value %q, expected key=value", pair)
+		}
+		(*f)[strings.ToLower(key)] = strings.TrimSpace(mapped)
+	}
+	return nil
+}
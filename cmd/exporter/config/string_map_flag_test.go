@@ -0,0 +1,59 @@
+package config
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestStringMapFlag_Set(t *testing.T) {
+	tests := map[string]struct {
+		values  []string
+		exp     StringMapFlag
+		wantErr bool
+	}{
+		"empty": {
+			values: []string{},
+			exp:    nil,
+		},
+		"single": {
+			values: []string{"-test", "Prod-EU=prod-eu"},
+			exp:    StringMapFlag{"prod-eu": "prod-eu"},
+		},
+		"comma separated": {
+			values: []string{"-test", "Prod-EU=prod-eu,staging_1=Staging-1"},
+			exp:    StringMapFlag{"prod-eu": "prod-eu", "staging_1": "Staging-1"},
+		},
+		"repeated": {
+			values: []string{"-test", "a=b", "-test", "c=d"},
+			exp:    StringMapFlag{"a": "b", "c": "d"},
+		},
+		"missing value separator": {
+			values:  []string{"-test", "prod"},
+			wantErr: true,
+		},
+		"missing key": {
+			values:  []string{"-test", "=prod"},
+			wantErr: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var smf StringMapFlag
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Var(&smf, "test", "test")
+			err := fs.Parse(test.values)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exp, got := test.exp.String(), smf.String(); exp != got {
+				t.Fatalf("expected %q, got %q", exp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	cversion "github.com/prometheus/common/version"
+
+	"github.com/grafana/cloudcost-exporter/cmd/exporter/config"
+	"github.com/grafana/cloudcost-exporter/pkg/aws"
+	"github.com/grafana/cloudcost-exporter/pkg/azure"
+	"github.com/grafana/cloudcost-exporter/pkg/custom"
+	"github.com/grafana/cloudcost-exporter/pkg/google"
+	"github.com/grafana/cloudcost-exporter/pkg/metricsdoc"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/recordingrules"
+)
+
+const docsUsage = "usage: cloudcost-exporter docs metrics [-format markdown|json] [-provider aws|gcp|azure|custom ...] | docs rules [-provider aws|gcp|azure|custom ...]"
+
+var errDocsUsage = errors.New(docsUsage)
+
+// runDocsCommand runs `cloudcost-exporter docs metrics`, which documents the metrics of every collector of the
+// selected providers without needing any cloud credentials, and `cloudcost-exporter docs rules`, which generates the
+// recommended recording rules over those metrics.
+func runDocsCommand(ctx context.Context, args []string, stdout io.Writer, logger *slog.Logger) error {
+	if len(args) < 1 || (args[0] != "metrics" && args[0] != "rules") {
+		return errDocsUsage
+	}
+
+	fs := flag.NewFlagSet("docs "+args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := "markdown"
+	if args[0] == "metrics" {
+		fs.StringVar(&format, "format", format, "Output format: markdown, json")
+	}
+	var providers config.StringSliceFlag
+	fs.Var(&providers, "provider", "Provider to document: aws, gcp, azure or custom, the custom price feed. Can be repeated, defaults to all providers.")
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w: %s", errDocsUsage, err)
+	}
+	if format != "markdown" && format != "json" {
+		return fmt.Errorf("%w: unknown format %s", errDocsUsage, format)
+	}
+	if len(providers) == 0 {
+		providers = config.StringSliceFlag{"aws", "gcp", "azure", custom.ProviderName}
+	}
+
+	var metrics []metricsdoc.Metric
+	for _, name := range providers {
+		csp, err := docsProvider(ctx, strings.ToLower(name), logger)
+		if err != nil {
+			return err
+		}
+		providerMetrics, err := metricsdoc.Describe(name, csp)
+		if err != nil {
+			return err
+		}
+		metrics = append(metrics, providerMetrics...)
+	}
+
+	if args[0] == "rules" {
+		rules, err := recordingrules.Generate(recordingrules.Rules, metrics)
+		if err != nil {
+			return err
+		}
+		return recordingrules.WriteYAML(stdout, rules, cversion.Version)
+	}
+	if format == "json" {
+		return metricsdoc.WriteJSON(stdout, metrics)
+	}
+	return metricsdoc.WriteMarkdown(stdout, metrics)
+}
+
+func docsProvider(ctx context.Context, name string, logger *slog.Logger) (provider.Provider, error) {
+	switch name {
+	case "aws":
+		return aws.NewForDocs(ctx, logger), nil
+	case "gcp":
+		return google.NewForDocs()
+	case "azure":
+		return azure.NewForDocs(ctx, logger), nil
+	case custom.ProviderName:
+		return custom.NewForDocs(), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown provider %s", errDocsUsage, name)
+	}
+}
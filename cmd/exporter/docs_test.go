@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/metricsdoc"
+)
+
+func TestRunDocsCommand(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := map[string]struct {
+		args      []string
+		want      []string
+		wantUsage bool
+	}{
+		"no subcommand": {
+			wantUsage: true,
+		},
+		"unknown format": {
+			args:      []string{"metrics", "-format", "csv"},
+			wantUsage: true,
+		},
+		"unknown provider": {
+			args:      []string{"metrics", "-provider", "oracle"},
+			wantUsage: true,
+		},
+		"all providers": {
+			args: []string{"metrics", "-format", "json"},
+			want: []string{
+				"cloudcost_aws_eks_instance_cpu_usd_per_core_hour",
+				"cloudcost_aws_s3_storage_by_location_usd_per_gibyte_hour",
+				"cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
+				"cloudcost_gcp_gcs_storage_by_location_usd_per_gibyte_hour",
+				"cloudcost_gcp_gke_nodepool_info",
+				"cloudcost_exporter_azure_collector_success",
+			},
+		},
+		"rules with format": {
+			args:      []string{"rules", "-format", "json"},
+			wantUsage: true,
+		},
+		"single provider": {
+			args: []string{"metrics", "-format", "json", "-provider", "gcp"},
+			want: []string{"cloudcost_gcp_compute_instance_cpu_usd_per_core_hour"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := runDocsCommand(context.Background(), tt.args, &stdout, logger)
+			if tt.wantUsage {
+				require.ErrorIs(t, err, errDocsUsage)
+				return
+			}
+			require.NoError(t, err)
+
+			var metrics []metricsdoc.Metric
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &metrics))
+			names := make([]string, 0, len(metrics))
+			for _, metric := range metrics {
+				names = append(names, metric.Name)
+			}
+			for _, want := range tt.want {
+				assert.Contains(t, names, want)
+			}
+		})
+	}
+}
+
+func TestRunDocsCommandRules(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := map[string]struct {
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		"all providers": {
+			args: []string{"rules"},
+			want: []string{
+				"record: cluster:cloudcost_instance_usd_per_hour:sum",
+				"record: namespace:cloudcost_persistent_volume_usd_per_hour:sum",
+				"record: provider:cloudcost_exporter_self_cost_usd_per_hour:sum",
+			},
+		},
+		"single provider": {
+			args:    []string{"rules", "-provider", "aws"},
+			want:    []string{"cloudcost_aws_eks_usd_total"},
+			notWant: []string{"cloudcost_gcp_compute_usd_total", "namespace:cloudcost_persistent_volume_usd_per_hour:sum"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			require.NoError(t, runDocsCommand(context.Background(), tt.args, &stdout, logger))
+			for _, want := range tt.want {
+				assert.Contains(t, stdout.String(), want)
+			}
+			for _, notWant := range tt.notWant {
+				assert.NotContains(t, stdout.String(), notWant)
+			}
+		})
+	}
+}
@@ -5,14 +5,20 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"strings"
 	"syscall"
 	"time"
+	// The image is built from scratch, which has no time zone database for -schedule.timezone
+	_ "time/tzdata"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/collectors/version"
@@ -22,14 +28,57 @@ import (
 	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
 	"github.com/grafana/cloudcost-exporter/cmd/exporter/config"
 	"github.com/grafana/cloudcost-exporter/cmd/exporter/web"
+	"github.com/grafana/cloudcost-exporter/pkg/anomaly"
 	"github.com/grafana/cloudcost-exporter/pkg/aws"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
 	"github.com/grafana/cloudcost-exporter/pkg/azure"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/aks"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/commitment"
+	"github.com/grafana/cloudcost-exporter/pkg/costhistory"
+	"github.com/grafana/cloudcost-exporter/pkg/custom"
+	"github.com/grafana/cloudcost-exporter/pkg/deprecation"
+	"github.com/grafana/cloudcost-exporter/pkg/egress"
+	"github.com/grafana/cloudcost-exporter/pkg/eviction"
 	"github.com/grafana/cloudcost-exporter/pkg/google"
+	"github.com/grafana/cloudcost-exporter/pkg/grace"
+	"github.com/grafana/cloudcost-exporter/pkg/httpsd"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
 	"github.com/grafana/cloudcost-exporter/pkg/logger"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricesource"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/schedule"
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/tenant"
 )
 
+// priceHistoryPath serves the price history when -price-history.snapshots is set.
+const priceHistoryPath = "/prices/history"
+
+// subcommands run instead of the exporter when their name is the first argument.
+var subcommands = map[string]func(ctx context.Context, args []string, stdout io.Writer, logger *slog.Logger) error{
+	"azure": runAzureCommand,
+	"docs":  runDocsCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			// Logs go to stderr so that the output of subcommands can be piped from stdout
+			logs := setupLogger("info", "stderr", "text")
+			if err := run(ctx, os.Args[2:], os.Stdout, logs); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var cfg config.Config
 	providerFlags(flag.CommandLine, &cfg)
 	operationalFlags(&cfg)
@@ -45,7 +94,13 @@ func main() {
 	)
 	cfg.Logger = logs
 
-	csp, err := selectProvider(ctx, &cfg)
+	priceHistory := pricehistory.New(cfg.PriceHistory.Snapshots)
+	costHistory, err := newCostHistory(&cfg)
+	if err != nil {
+		logs.LogAttrs(ctx, slog.LevelError, "Error loading cost history", slog.String("message", err.Error()))
+		os.Exit(1)
+	}
+	csp, err := selectProvider(ctx, &cfg, priceHistory)
 	if err != nil {
 		logs.LogAttrs(ctx, slog.LevelError, "Error selecting provider",
 			slog.String("message", err.Error()),
@@ -54,7 +109,16 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = runServer(ctx, &cfg, csp, logs)
+	feed, err := newCustomFeed(ctx, &cfg)
+	if err != nil {
+		logs.LogAttrs(ctx, slog.LevelError, "Error loading custom price feed", slog.String("message", err.Error()))
+		os.Exit(1)
+	}
+	if feed != nil {
+		csp = provider.Providers{csp, feed}
+	}
+
+	err = runServer(ctx, &cfg, csp, priceHistory, costHistory, logs)
 	if err != nil {
 		logs.LogAttrs(ctx, slog.LevelError, "Error running server", slog.String("message", err.Error()))
 		os.Exit(1)
@@ -71,21 +135,110 @@ func providerFlags(fs *flag.FlagSet, cfg *config.Config) {
 	fs.Var(&cfg.Providers.AWS.Services, "aws.services", "AWS service(s).")
 	fs.Var(&cfg.Providers.Azure.Services, "azure.services", "Azure service(s).")
 	fs.Var(&cfg.Providers.GCP.Services, "gcp.services", "GCP service(s).")
-	flag.StringVar(&cfg.Providers.AWS.Region, "aws.region", "", "AWS region")
+	flag.StringVar(&cfg.Providers.AWS.Region, "aws.region", "", "AWS region. Defaults to AWS_REGION, the region of -aws.profile, and lastly the region of the EC2 instance from the instance metadata service.")
+	flag.BoolVar(&cfg.Providers.AWS.DisableIMDS, "aws.disable-imds", false, "Don't call the EC2 instance metadata service for the region or the credentials of the instance role, eg outside EC2 or on Fargate. The region then has to be set with -aws.region, AWS_REGION or -aws.profile.")
+	flag.DurationVar(&cfg.Providers.AWS.IMDSTimeout, "aws.imds-timeout", 2*time.Second, "Timeout of the discovery of the region through the EC2 instance metadata service, which fails after it outside EC2.")
+	flag.BoolVar(&cfg.Providers.AWS.EKSMetadata, "aws.eks-metadata", false, "Label EKS instance metrics with the cluster version and nodegroup capacity type. Requires eks:DescribeCluster and eks:DescribeNodegroup.")
+	flag.BoolVar(&cfg.Providers.AWS.IdleCost, "aws.idle-cost", false, "Export the idle cost of EKS instances based upon their CPU utilization over the last hour. Requires cloudwatch:GetMetricData.")
+	flag.BoolVar(&cfg.Providers.AWS.S3BucketCosts, "aws.s3-bucket-costs", false, "Export the cost of every S3 bucket averaged over the last days of resource-level data of Cost Explorer. Requires ce:GetCostAndUsageWithResources and the resource-level data of S3 to be enabled in Cost Explorer.")
+	fs.BoolVar(&cfg.Providers.AWS.S3BucketInventory, "aws.s3-bucket-inventory", false, "Export every S3 bucket of the account along with its region. Requires s3:ListAllMyBuckets and s3:GetBucketLocation.")
+	fs.BoolVar(&cfg.Providers.AWS.S3BucketStorageMetrics, "aws.s3-bucket-storage-metrics", false, "Export the size of every S3 bucket by storage class from the daily storage metrics of CloudWatch. Implies -aws.s3-bucket-inventory and requires cloudwatch:GetMetricData.")
+	fs.Var(&cfg.Providers.AWS.ELBTags, "aws.elb-tag", "Export a tag of the load balancers as a label of the elb collector, eg team as tag_team. Can be repeated.")
+	flag.BoolVar(&cfg.Providers.AWS.CloudWatchLogGroups, "aws.cloudwatch-log-groups", false, "Export the volume ingested by every CloudWatch log group over the last hour and its cost in the observability collector. Requires cloudwatch:GetMetricData.")
 	// TODO - PUT PROJECT-ID UNDER GCP
 	flag.StringVar(&cfg.ProjectID, "project-id", "ops-tools-1203", "Project ID to target.")
 	flag.StringVar(&cfg.Providers.Azure.SubscriptionId, "azure.subscription-id", "", "Azure subscription ID to pull data from.")
+	flag.StringVar(&cfg.Providers.Azure.ManagementGroup, "azure.management-group", "", "Azure management group to enumerate subscriptions from for the managementgroups service, eg the tenant root group ID. Requires Microsoft.Management/managementGroups/descendants/read and Microsoft.CostManagement/query/read.")
 	flag.IntVar(&cfg.Providers.GCP.DefaultGCSDiscount, "gcp.default-discount", 19, "GCP default discount")
+	flag.BoolVar(&cfg.Providers.GCP.IdleCost, "gcp.idle-cost", false, "Export the idle cost of compute instances based upon their CPU utilization over the last hour. Requires monitoring.timeSeries.list and compute.machineTypes.get.")
+	flag.BoolVar(&cfg.Providers.GCP.BucketCost, "gcp.bucket-cost", false, "Export the estimated cost of every GCS bucket, its size by storage class reported by Cloud Monitoring multiplied by the storage price of its location. Requires monitoring.timeSeries.list in the bucket projects.")
+	flag.BoolVar(&cfg.Providers.GCP.AutoscalerHeadroom, "gcp.autoscaler-headroom", false, "Export the cost of the nodes of the autoscaled node pools of every GKE cluster above the minimum size of their node pool as cloudcost_cluster_headroom_usd_per_hour. Requires compute.machineTypes.get.")
+	flag.StringVar(&cfg.Providers.GCP.DiscoveryParent, "gcp.discovery-parent", "", "Folder or organization whose active projects, including the projects of its folders, are collected by the compute and gke services instead of -gcp.bucket-projects, eg folders/123 or organizations/456. Requires resourcemanager.projects.list and resourcemanager.folders.list.")
+	flag.DurationVar(&cfg.Providers.GCP.DiscoveryInterval, "gcp.discovery-interval", time.Hour, "How often the projects of -gcp.discovery-parent are listed again, so that new projects are collected and deleted projects are dropped.")
+	flag.IntVar(&cfg.Providers.GCP.HierarchyDepth, "gcp.hierarchy-depth", 0, "Label GCP metrics with the organization and up to this many folders of their project, starting from the top level folder. 0 disables the labels. Requires resourcemanager.projects.get.")
+	fs.Var(&cfg.Providers.AWS.Endpoints, "aws.endpoint", "Override the endpoint of an AWS service, one of ec2, pricing, costexplorer, eks, cloudwatch, ecs, rds, elb or s3, or offers for the host of the offer files, eg pricing=https://vpce-0123.api.pricing.us-east-1.vpce.amazonaws.com. {region} is replaced by the region of regional clients. Can be repeated.")
+	flag.StringVar(&cfg.Providers.AWS.PricingSource, "aws.pricing-source", aws.PricingSourceAPI, "Where the AWS prices are listed from: api, the GetProducts API of the pricing service, or offer-files, the bulk offer files of the AWS Price List, which are fetched in a single request per region and only parsed again when they changed.")
+	flag.StringVar(&cfg.Providers.AWS.SpotAggregation, "aws.spot-price-aggregation", compute.SpotAggregationMin, "How the spot prices of the availability zones of a region are aggregated into the price of the spot instances whose zone has no spot price: min, the price of the cheapest zone, or avg, the average price of the zones. The aggregation is exported as the price_aggregation label.")
+	fs.Var(&cfg.Providers.GCP.Endpoints, "gcp.endpoint", "Override the endpoint of a GCP service, one of compute, cloudbilling, storage, monitoring, container, spanner or cloudresourcemanager, eg compute=https://compute-psc.p.googleapis.com/compute/v1/. Can be repeated.")
+	flag.StringVar(&cfg.Providers.Azure.Cloud, "azure.cloud", "public", "Azure cloud to authenticate against: public, china or usgovernment.")
+	flag.StringVar(&cfg.Providers.Azure.AuthorityHost, "azure.authority-host", "", "Override the Microsoft Entra authority host of the Azure cloud.")
+	flag.StringVar(&cfg.Providers.AWS.Auth, "aws.auth", aws.AuthDefault, "How the AWS clients authenticate: default, the default credential chain of the SDK, web-identity, which requires a role assumed with a web identity token, eg IRSA on EKS, or vault, which reads the access keys from -aws.vault-path.")
+	flag.StringVar(&cfg.Providers.AWS.RoleARN, "aws.role-arn", "", "Role assumed with -aws.auth=web-identity. Defaults to AWS_ROLE_ARN, which is set by the EKS pod identity webhook.")
+	flag.StringVar(&cfg.Providers.AWS.WebIdentityTokenFile, "aws.web-identity-token-file", "", "Token exchanged for the credentials of -aws.role-arn with -aws.auth=web-identity. Defaults to AWS_WEB_IDENTITY_TOKEN_FILE, which is set by the EKS pod identity webhook.")
+	fs.Var(&cfg.Providers.AWS.AssumeRoleARNs, "aws.assume-role-arn", "Role assumed with sts:AssumeRole from the credentials of -aws.auth to collect another account, eg arn:aws:iam::123456789012:role/cloudcost-exporter. Can be repeated, once per account. The ec2, eks and s3 services then collect every account of the roles instead of the account of the exporter and label their metrics with account_id.")
+	flag.BoolVar(&cfg.Providers.AWS.EKSOnly, "aws.eks-only", false, "Only list the EC2 instances tagged with the name of an EKS cluster instead of every instance of the account. Only applies to the EKS collector.")
+	fs.Var(&cfg.Providers.AWS.InstanceTags, "aws.instance-tag", "Only list the EC2 instances with a tag, eg team=platform, or karpenter.sh/nodepool for any value. Values support the * and ? wildcards. Can be repeated, every tag has to match. Only applies to the EKS collector.")
+	fs.Var(&cfg.Providers.AWS.ExcludeInstanceTags, "aws.exclude-instance-tag", "Drop the EC2 instances with a tag, in the same format as -aws.instance-tag. Can be repeated. Only applies to the EKS collector.")
+	fs.Var(&cfg.Providers.AWS.VPCIDs, "aws.vpc-id", "Only list the EC2 instances of a VPC. Can be repeated. Only applies to the EKS collector.")
+	fs.Var(&cfg.Providers.AWS.InstanceNames, "aws.instance-name", "Only list the EC2 instances whose Name tag matches a pattern, eg eks-prod-*. Can be repeated. Only applies to the EKS collector.")
+	fs.Var(&cfg.Providers.AWS.ExcludeInstanceNames, "aws.exclude-instance-name", "Drop the EC2 instances whose Name tag matches a pattern. Can be repeated. Only applies to the EKS collector.")
+	fs.Var(&cfg.Providers.AWS.Regions, "aws.collect-region", "Only collect the regions enabled for the account matching a pattern, eg eu-*. Can be repeated, defaults to every enabled region. The regions are listed again whenever the pricing map is refreshed.")
+	fs.Var(&cfg.Providers.AWS.ExcludeRegions, "aws.exclude-region", "Skip the regions matching a pattern, even when they match -aws.collect-region. Can be repeated.")
+	fs.Var(&cfg.Providers.AWS.InstanceFamilies, "aws.instance-family", "Only fetch the prices of the instance families or types matching a pattern, eg m5 or c6*. Can be repeated, defaults to every family. Only applies to the ec2 and eks services.")
+	fs.Var(&cfg.Providers.AWS.ExcludeInstanceFamilies, "aws.exclude-instance-family", "Drop the prices of the instance families or types matching a pattern, eg p4d or *.metal, even when they match -aws.instance-family. Can be repeated.")
+	fs.StringVar(&cfg.Providers.AWS.CostHistoryFile, "aws.cost-history-file", "", "JSON file the estimated daily cost of every AWS resource is recorded to and served from on "+costhistory.Path+", for week over week costs without Cost Explorer. It's loaded on start when it exists, so it should be on a persistent volume. Empty disables the cost history.")
+	fs.IntVar(&cfg.Providers.AWS.CostHistoryDays, "aws.cost-history-days", costhistory.DefaultDays, "Number of days kept in -aws.cost-history-file.")
+	flag.StringVar(&cfg.Providers.GCP.Auth, "gcp.auth", google.AuthDefault, "How the GCP clients authenticate: default, Application Default Credentials, eg GKE Workload Identity, workload-identity-federation, which requires an external account credentials file, or vault, which reads a service account key from -gcp.vault-path.")
+	flag.StringVar(&cfg.Providers.GCP.CredentialsFile, "gcp.credentials-file", "", "External account credentials file used with -gcp.auth=workload-identity-federation. Defaults to GOOGLE_APPLICATION_CREDENTIALS.")
+	flag.StringVar(&cfg.Providers.GCP.InstanceFilter, "gcp.instance-filter", "", "Filter expression of the instances listed by the compute and gke collectors, eg 'labels.env=prod' or 'name=gke-prod-*'. See the filter parameter of the instances.list API for the syntax.")
+	fs.Var(&cfg.Providers.GCP.MachineFamilies, "gcp.machine-family", "Only keep the prices of the machine families matching a pattern, eg n2 or c3*. Can be repeated, defaults to every family. Only applies to the compute and gke services.")
+	fs.Var(&cfg.Providers.GCP.ExcludeMachineFamilies, "gcp.exclude-machine-family", "Drop the prices of the machine families matching a pattern, eg h3, even when they match -gcp.machine-family. Can be repeated.")
+	flag.StringVar(&cfg.Providers.Azure.Auth, "azure.auth", azure.AuthDefault, "How the Azure clients authenticate: default, the default credential chain of the SDK, workload-identity, which requires Microsoft Entra Workload ID, or vault, which reads the client secret of an application from -azure.vault-path.")
+	flag.StringVar(&cfg.Providers.Azure.TenantID, "azure.tenant-id", "", "Tenant of the application used with -azure.auth=workload-identity or vault. Defaults to AZURE_TENANT_ID, which is set by the workload identity webhook.")
+	flag.StringVar(&cfg.Providers.Azure.ClientID, "azure.client-id", "", "Client ID of the application used with -azure.auth=workload-identity, or with vault when the secret has no client_id. Defaults to AZURE_CLIENT_ID, which is set by the workload identity webhook.")
+	flag.StringVar(&cfg.Providers.Azure.FederatedTokenFile, "azure.federated-token-file", "", "Service account token exchanged with -azure.auth=workload-identity. Defaults to AZURE_FEDERATED_TOKEN_FILE, which is set by the workload identity webhook.")
+	flag.StringVar(&cfg.Providers.AWS.VaultPath, "aws.vault-path", "", "Vault secret read with -aws.auth=vault, eg aws/creds/cloudcost-exporter for the AWS secrets engine or secret/data/cloudcost-exporter/aws for a KV v2 secret, with the access_key, secret_key and optionally security_token keys.")
+	flag.StringVar(&cfg.Providers.GCP.VaultPath, "gcp.vault-path", "", "Vault secret read with -gcp.auth=vault, eg gcp/key/cloudcost-exporter for the Google Cloud secrets engine, with the base64 encoded private_key_data, or a KV secret with the JSON service account key in credentials.")
+	flag.StringVar(&cfg.Providers.Azure.VaultPath, "azure.vault-path", "", "Vault secret read with -azure.auth=vault, eg azure/creds/cloudcost-exporter for the Azure secrets engine or a KV secret, with the client_secret and optionally client_id keys.")
+	flag.StringVar(&cfg.Vault.Address, "vault.address", "", "Address of Vault for the vault auth of the providers, eg https://vault.example.com:8200. Defaults to VAULT_ADDR.")
+	flag.StringVar(&cfg.Vault.TokenFile, "vault.token-file", "", "File the Vault token is read from on every request, eg the sink of the Vault Agent, so that renewed tokens are picked up. Defaults to VAULT_TOKEN when empty.")
+	flag.StringVar(&cfg.Vault.Namespace, "vault.namespace", "", "Vault Enterprise namespace of the secrets. Defaults to VAULT_NAMESPACE.")
+	flag.DurationVar(&cfg.Vault.RefreshInterval, "vault.refresh-interval", secrets.DefaultRefreshInterval, "How often the Vault secrets are read again. The clients are rebuilt when a secret changes, so that rotated credentials are picked up without a restart.")
+	fs.Var(&cfg.Providers.Azure.ResourceGroups, "azure.resource-group", "Only enumerate the resources of the resource groups matching a pattern, eg MC_*. Patterns are case-insensitive. Can be repeated, defaults to every resource group of the subscription.")
+	fs.Var(&cfg.Providers.Azure.ExcludeResourceGroups, "azure.exclude-resource-group", "Skip the resources of the resource groups matching a pattern. Patterns are case-insensitive. Can be repeated.")
+	flag.StringVar(&cfg.Providers.Azure.ResourceManagerEndpoint, "azure.resource-manager-endpoint", "", "Override the Azure Resource Manager endpoint of the Azure cloud, eg to reach it through Private Link.")
+	fs.Var(&cfg.Providers.Azure.VMSizes, "azure.vm-size", "Only keep the retail prices of the VM sizes matching a pattern, eg Standard_D*. Patterns are case-insensitive. Can be repeated, defaults to every size. Only applies to the aks service.")
+	fs.Var(&cfg.Providers.Azure.ExcludeVMSizes, "azure.exclude-vm-size", "Drop the retail prices of the VM sizes matching a pattern, eg Standard_HB*, even when they match -azure.vm-size. Patterns are case-insensitive. Can be repeated.")
+	flag.BoolVar(&cfg.Providers.Azure.CommitmentPricing, "azure.commitment-pricing", false, "Price the VMs of the aks service covered by reservations and savings plans at their rate rather than the on-demand price. Requires the Reservations Reader and Savings plan Reader roles.")
+	flag.BoolVar(&cfg.Providers.Azure.CapacityDelta, "azure.capacity-delta", false, "Export the cost of the difference between the capacity of the scale sets of the aks service and their running VMs, eg to catch stuck scale operations. Lists the VMs of every scale set on every scrape.")
 }
 
 // operationalFlags is a helper method that is responsible for setting up the flags that are used to configure the operational aspects of the application.
 // TODO: This should probably be moved over to the config package.
 func operationalFlags(cfg *config.Config) {
 	flag.DurationVar(&cfg.Collector.ScrapeInterval, "scrape-interval", 1*time.Hour, "Scrape interval")
+	flag.Var(&cfg.Collector.ScrapeIntervals, "collector.scrape-interval", "Per collector scrape interval overriding -scrape-interval, eg s3=24h,eks=15m. Can be repeated.")
 	flag.DurationVar(&cfg.Collector.Timeout, "collector-interval", 1*time.Minute, "Context timeout for collectors")
+	flag.DurationVar(&cfg.Collector.GraceWindow, "collector.grace-window", 0, "Keep exporting the gauges of a resource that disappeared from a scrape for this long, labelled with presence=\"grace\", so that list APIs temporarily returning partial pages don't break the series. 0 disables the grace window.")
+	flag.DurationVar(&cfg.Collector.DeprecationWindow, "collector.deprecation-window", deprecation.DefaultWindow, "Keep exporting the renamed metrics under their previous name for this long after their rename, labelled with deprecated=\"true\". 0 exports the metrics under their new name only.")
 	flag.DurationVar(&cfg.Server.Timeout, "server-timeout", 30*time.Second, "Server timeout")
 	flag.StringVar(&cfg.Server.Address, "server.address", ":8080", "Default address for the server to listen on.")
 	flag.StringVar(&cfg.Server.Path, "server.path", "/metrics", "Default path for the server to listen on.")
+	flag.StringVar(&cfg.SD.Target, "sd.target", "", "Address Prometheus scrapes the exporter at, served as the target of the HTTP service discovery on "+httpsd.Path+", eg cloudcost-exporter.monitoring:8080. The host the service discovery is requested at is used when empty.")
+	flag.Var(&cfg.SD.Labels, "sd.label", "Label added as is to the targets of the HTTP service discovery, eg account_id=123456789012. Can be repeated.")
+	flag.StringVar(&cfg.Egress.ProxyURL, "egress.proxy-url", "", "Proxy to send the requests of the cloud SDK clients through, eg http://proxy.internal:3128. The HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used when empty.")
+	flag.StringVar(&cfg.Egress.NoProxy, "egress.no-proxy", "", "Comma separated hosts, domains and CIDRs that bypass -egress.proxy-url, in the format of NO_PROXY.")
+	flag.BoolVar(&cfg.Kubernetes.NamespaceCost, "kubernetes.namespace-cost", false, "Allocate the cost of the nodes of the cluster the exporter runs in to the namespaces of their running pods by their requests. Implies -kubernetes.allocatable-cost and requires the service account to list nodes and pods.")
+	flag.StringVar(&cfg.Kubernetes.StorageClassFile, "kubernetes.storage-class-file", "", "Path to a YAML file mapping the storage classes of the clusters to the volume type and prices of their persistent volumes, eg the provisioned IOPS of a gp3 class. Requires the service account to list persistent volumes.")
+	flag.BoolVar(&cfg.Kubernetes.AllocatableCost, "kubernetes.allocatable-cost", false, "Export the cost of the nodes of the cluster the exporter runs in per allocatable core and GiB. Requires the service account to list nodes.")
+	flag.BoolVar(&cfg.ClusterName.Lowercase, "cluster-name.lowercase", true, "Lowercase the cluster_name label so that it can be joined across providers.")
+	flag.Var(&cfg.ClusterName.Overrides, "cluster-name.override", "Export a cluster under another cluster_name, eg Prod-EU=prod-eu. Names are matched case-insensitively. Can be repeated.")
+	flag.Var(&cfg.Schedule.OffHours, "schedule.off-hours", "Weekly off-hours during which a cluster is scaled down, eg dev=Mon-Fri 19:00-07:00;Sat-Sun. Exports the actual and expected cost of the cluster to verify the scale down. Can be repeated.")
+	flag.StringVar(&cfg.Schedule.Timezone, "schedule.timezone", "UTC", "IANA time zone the times of -schedule.off-hours are in, eg Europe/Berlin.")
+	flag.Float64Var(&cfg.Schedule.OffHoursFactor, "schedule.off-hours-cost-factor", 0, "Share of the on-hours cost a cluster is expected to cost during its off-hours, between 0 and 1.")
+	flag.Float64Var(&cfg.Anomaly.Alpha, "anomaly.alpha", 0, "Weight of the latest scrape in the moving average of the hourly cost of every EKS and GKE cluster, between 0 and 1, eg 0.1. Exports cloudcost_exporter_cost_anomaly_score. 0 disables the anomaly scores.")
+	flag.DurationVar(&cfg.Spot.EvictionOverhead, "spot.eviction-overhead", 0, "Time a replacement node is billed before it can run the workloads of an evicted EKS spot instance, eg 10m. Exports the spot evictions of every cluster and their churn cost. 0 disables the eviction metrics.")
+	flag.DurationVar(&cfg.Commitment.Window, "commitment.window", 0, "Window over which the on-demand cores of a machine family and region of the EKS and GKE clusters must run continuously to be recommended for a commitment purchase, eg 168h. The history is kept in memory, so no recommendation is exported until the exporter ran for the whole window. 0 disables the commitment recommendations.")
+	flag.Float64Var(&cfg.Commitment.Discount, "commitment.discount", 0.3, "Share of the on-demand price a commitment saves, between 0 and 1, used to estimate the potential savings of the commitment recommendations.")
+	flag.StringVar(&cfg.Pricing.OverrideFile, "pricing.override-file", "", "YAML file of price overrides and negotiated rates of the on-demand instances of the EKS and GKE clusters, layered on top of the retail prices of the catalogs. It's reloaded when it changes.")
+	flag.Var(&cfg.Tenant.Scopes, "tenant", "Label the metrics of a scope with its tenant, eg my-gcp-project=payments. A scope is a GCP project, the account_id of an AWS linked account or the subscription_id of the Azure management group costs, matched case-insensitively. Can be repeated.")
+	flag.StringVar(&cfg.Tenant.Default, "tenant.default", "", "Tenant of the metrics without a scope label, eg the prices and the costs of the account or subscription the exporter runs against.")
+	flag.StringVar(&cfg.Tenant.Label, "tenant.label", tenant.DefaultLabel, "Name of the label the tenant of a metric is injected in.")
+	flag.BoolVar(&cfg.Tenant.Endpoints, "tenant.endpoints", false, "Also serve the metrics of every tenant on their own path under -server.path, eg /metrics/payments, without the metrics of the other tenants and of the exporter itself.")
+	flag.StringVar(&cfg.Custom.Source, "custom.source", "", "Path or http(s) URL of a CSV or JSON feed of the prices and inventory of resources outside the cloud providers, eg on-prem VMware hosts, exported under cloudcost_custom_* along with the metrics of -provider. Empty disables the custom price feed.")
+	flag.StringVar(&cfg.Custom.Format, "custom.format", "", "Format of -custom.source: csv or json. Defaults to the extension of -custom.source.")
+	flag.DurationVar(&cfg.Custom.RefreshInterval, "custom.refresh-interval", custom.DefaultRefreshInterval, "How often -custom.source is read again. A feed that can't be read keeps the costs of the previous one.")
+	flag.IntVar(&cfg.PriceHistory.Snapshots, "price-history.snapshots", 0, "Number of pricing map snapshots of the eks and compute collectors kept in memory and served on "+priceHistoryPath+". 0 disables the price history.")
 	flag.StringVar(&cfg.LoggerOpts.Level, "log.level", "info", "Log level: debug, info, warn, error")
 	flag.StringVar(&cfg.LoggerOpts.Output, "log.output", "stdout", "Log output stream: stdout, stderr, file")
 	flag.StringVar(&cfg.LoggerOpts.Type, "log.type", "text", "Log type: json, text")
@@ -99,17 +252,14 @@ func setupLogger(level string, output string, logtype string) *slog.Logger {
 }
 
 // runServer is a helper method that is responsible for starting the metrics server and handling shutdown signals.
-func runServer(ctx context.Context, cfg *config.Config, csp provider.Provider, log *slog.Logger) error {
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("/", web.HomePageHandler(cfg.Server.Path)) // landing page
-	registryHandler, err := createPromRegistryHandler(csp)    // prom metrics handler
+// The price history and the cost history are only served when priceHistory and costHistory are set.
+func runServer(ctx context.Context, cfg *config.Config, csp provider.Provider, priceHistory *pricehistory.History, costHistory *costhistory.History, log *slog.Logger) error {
+	handler, err := newHandler(ctx, cfg, csp, priceHistory, costHistory)
 	if err != nil {
 		return err
 	}
-	mux.Handle(cfg.Server.Path, registryHandler) // prom metrics handler
 
-	server := &http.Server{Addr: cfg.Server.Address, Handler: mux}
+	server := &http.Server{Addr: cfg.Server.Address, Handler: handler}
 	errChan := make(chan error)
 
 	go func() {
@@ -119,26 +269,123 @@ func runServer(ctx context.Context, cfg *config.Config, csp provider.Provider, l
 		errChan <- server.ListenAndServe()
 	}()
 
+	var errs []error
+	serving := true
 	select {
 	case <-ctx.Done():
 		log.LogAttrs(ctx, slog.LevelInfo, "Shutting down server")
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.Timeout)
-		defer cancel()
-
-		err := server.Shutdown(ctx)
-		if err != nil {
-			return fmt.Errorf("error shutting down server: %w", err)
-		}
 	case err := <-errChan:
+		serving = false
 		if !errors.Is(err, http.ErrServerClosed) {
-			return fmt.Errorf("error running server: %w", err)
+			errs = append(errs, fmt.Errorf("error running server: %w", err))
 		}
 	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.Timeout)
+	defer cancel()
+
+	// Shutdown waits for the in-flight scrapes, so that the metrics they already collected are still served. Their
+	// collections were stopped along with ctx, and the provider waits for them within the same timeout
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.LogAttrs(ctx, slog.LevelWarn, "In-flight collections didn't complete before the shutdown timeout",
+			slog.Duration("timeout", cfg.Server.Timeout))
+		errs = append(errs, fmt.Errorf("error shutting down server: %w", err))
+	}
+	if serving {
+		// ListenAndServe returns as soon as Shutdown closed the listener
+		<-errChan
+	}
+	return errors.Join(append(errs, closeProvider(shutdownCtx, csp, log))...)
+}
 
+// closeProvider closes the clients of the provider once no collection uses them anymore, waiting for the collections
+// in flight until ctx is done. The clients are left open when collections are still in flight.
+func closeProvider(ctx context.Context, csp provider.Provider, log *slog.Logger) error {
+	if err := csp.Close(ctx); err != nil {
+		return fmt.Errorf("error closing provider: %w", err)
+	}
+	log.LogAttrs(ctx, slog.LevelInfo, "Closed provider clients")
 	return nil
 }
 
-func createPromRegistryHandler(csp provider.Provider) (http.Handler, error) {
+// newHandler registers the collectors of the provider and returns the handler of every endpoint of the server. The cost
+// history samples the registry until ctx is done.
+func newHandler(ctx context.Context, cfg *config.Config, csp provider.Provider, priceHistory *pricehistory.History, costHistory *costhistory.History) (http.Handler, error) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", web.HomePageHandler(cfg.Server.Path)) // landing page
+	mux.HandleFunc(web.VersionPath, web.VersionHandler)
+	registry, err := createPromRegistry(csp)
+	if err != nil {
+		return nil, err
+	}
+	// The renamed metrics are aliased after the grace window, so that their series within it are aliased as well
+	aliases := deprecation.New(deprecation.Renames, cfg.Collector.DeprecationWindow)
+	aliases.Warn(ctx, cfg.Logger)
+	gatherer := aliases.Gatherer(grace.New(cfg.Collector.GraceWindow).Gatherer(registry))
+	tenants := tenant.New(cfg.Tenant.Label, cfg.Tenant.Scopes, cfg.Tenant.Default)
+	mux.Handle(cfg.Server.Path, metricsHandler(tenants.Gatherer(gatherer))) // prom metrics handler
+	tenantPaths := make(map[string]string)
+	if cfg.Tenant.Endpoints {
+		// Every tenant is served its own metrics, eg on /metrics/payments
+		for _, name := range tenants.Names() {
+			tenantPaths[name] = path.Join(cfg.Server.Path, url.PathEscape(name))
+			mux.Handle(tenantPaths[name], metricsHandler(tenants.TenantGatherer(gatherer, name)))
+		}
+	}
+	mux.Handle(httpsd.Path, httpsd.Handler(httpsd.Config{
+		Target:      cfg.SD.Target,
+		MetricsPath: cfg.Server.Path,
+		Identity:    identity(cfg),
+		Labels:      cfg.SD.Labels,
+		TenantPaths: tenantPaths,
+	}))
+	if priceHistory != nil {
+		mux.Handle(priceHistoryPath, priceHistory.Handler())
+	}
+	if costHistory != nil {
+		// The registry is sampled rather than the gatherer of the metrics endpoint, so that the resources within
+		// their grace window and the deprecated names aren't accounted for twice
+		go costHistory.Run(ctx, registry)
+		mux.Handle(costhistory.Path, costHistory.Handler())
+	}
+	return mux, nil
+}
+
+// newCustomFeed returns the collector of the custom price feed, or nil when -custom.source isn't set.
+func newCustomFeed(ctx context.Context, cfg *config.Config) (*custom.Collector, error) {
+	if cfg.Custom.Source == "" {
+		return nil, nil
+	}
+	httpClient, err := egress.NewHTTPClient(egress.Config{ProxyURL: cfg.Egress.ProxyURL, NoProxy: cfg.Egress.NoProxy})
+	if err != nil {
+		return nil, err
+	}
+	return custom.New(ctx, custom.Config{
+		Source:          cfg.Custom.Source,
+		Format:          cfg.Custom.Format,
+		RefreshInterval: cfg.Custom.RefreshInterval,
+		Timeout:         cfg.Collector.Timeout,
+		HTTPClient:      httpClient,
+		ClusterNames:    clustername.NewNormalizer(cfg.ClusterName.Lowercase, cfg.ClusterName.Overrides),
+		Logger:          cfg.Logger,
+	})
+}
+
+// newCostHistory returns the cost history of the AWS module, or nil when -aws.cost-history-file isn't set or another
+// provider is selected.
+func newCostHistory(cfg *config.Config) (*costhistory.History, error) {
+	if cfg.Provider != "aws" {
+		return nil, nil
+	}
+	return costhistory.New(costhistory.Config{
+		File:   cfg.Providers.AWS.CostHistoryFile,
+		Days:   cfg.Providers.AWS.CostHistoryDays,
+		Prefix: cloudcost_exporter.MetricPrefix + "_aws_",
+		Logger: cfg.Logger,
+	})
+}
+
+func createPromRegistry(csp provider.Provider) (*prometheus.Registry, error) {
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(
 		collectors.NewBuildInfoCollector(),
@@ -151,41 +398,285 @@ func createPromRegistryHandler(csp provider.Provider) (http.Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	// CollectMetrics http server for prometheus
-	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+	return registry, nil
+}
+
+// metricsHandler serves the metrics of a gatherer to prometheus.
+func metricsHandler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
 		EnableOpenMetrics: true,
-	}), nil
+	})
 }
 
-func selectProvider(ctx context.Context, cfg *config.Config) (provider.Provider, error) {
+func selectProvider(ctx context.Context, cfg *config.Config, priceHistory *pricehistory.History) (provider.Provider, error) {
+	clusterNames := clustername.NewNormalizer(cfg.ClusterName.Lowercase, cfg.ClusterName.Overrides)
+	httpClient, err := egress.NewHTTPClient(egress.Config{ProxyURL: cfg.Egress.ProxyURL, NoProxy: cfg.Egress.NoProxy})
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(cfg.Schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("error loading schedule timezone: %w", err)
+	}
+	calendar, err := schedule.NewCalendar(cfg.Schedule.OffHours, location, cfg.Schedule.OffHoursFactor)
+	if err != nil {
+		return nil, err
+	}
+	anomalies, err := anomaly.New(cfg.Anomaly.Alpha)
+	if err != nil {
+		return nil, err
+	}
+	recommendations, err := commitment.New(cfg.Commitment.Window, cfg.Commitment.Discount)
+	if err != nil {
+		return nil, err
+	}
+	var prices pricesource.Layers
+	if cfg.Pricing.OverrideFile != "" {
+		overrides, err := pricesource.LoadFile(cfg.Pricing.OverrideFile)
+		if err != nil {
+			return nil, err
+		}
+		prices = pricesource.Layers{overrides}
+	}
+	var storageClasses *storageclass.Classes
+	if cfg.Kubernetes.StorageClassFile != "" {
+		storageClasses, err = storageclass.LoadFile(cfg.Kubernetes.StorageClassFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var nodes kubernetes.NodeLister
+	var pods kubernetes.PodLister
+	var volumes kubernetes.PersistentVolumeLister
+	if cfg.Kubernetes.AllocatableCost || cfg.Kubernetes.NamespaceCost || storageClasses != nil {
+		client, err := kubernetes.NewInClusterClient()
+		if err != nil {
+			return nil, fmt.Errorf("error creating kubernetes client: %w", err)
+		}
+		if cfg.Kubernetes.AllocatableCost || cfg.Kubernetes.NamespaceCost {
+			nodes = client
+		}
+		if cfg.Kubernetes.NamespaceCost {
+			pods = client
+		}
+		if storageClasses != nil {
+			volumes = client
+		}
+	}
 	switch cfg.Provider {
 	case "azure":
+		cloud, err := azure.CloudConfiguration(cfg.Providers.Azure.Cloud, cfg.Providers.Azure.AuthorityHost, cfg.Providers.Azure.ResourceManagerEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		var resourceGroups *aks.ResourceGroupFilter
+		if len(cfg.Providers.Azure.ResourceGroups) > 0 || len(cfg.Providers.Azure.ExcludeResourceGroups) > 0 {
+			resourceGroups = &aks.ResourceGroupFilter{
+				Include: cfg.Providers.Azure.ResourceGroups,
+				Exclude: cfg.Providers.Azure.ExcludeResourceGroups,
+			}
+		}
 		return azure.New(ctx, &azure.Config{
-			Logger:           cfg.Logger,
-			SubscriptionId:   cfg.Providers.Azure.SubscriptionId,
-			Services:         cfg.Providers.Azure.Services,
-			CollectorTimeout: cfg.Collector.Timeout,
+			Logger:            cfg.Logger,
+			SubscriptionId:    cfg.Providers.Azure.SubscriptionId,
+			ManagementGroup:   cfg.Providers.Azure.ManagementGroup,
+			Services:          cfg.Providers.Azure.Services,
+			CollectorTimeout:  cfg.Collector.Timeout,
+			ScrapeInterval:    cfg.Collector.ScrapeInterval,
+			ScrapeIntervals:   cfg.Collector.ScrapeIntervals,
+			Cloud:             cloud,
+			HTTPClient:        httpClient,
+			ResourceGroups:    resourceGroups,
+			StorageClasses:    storageClasses,
+			Volumes:           volumes,
+			CommitmentPricing: cfg.Providers.Azure.CommitmentPricing,
+			CapacityDelta:     cfg.Providers.Azure.CapacityDelta,
+			VMSizes:           familyFilter(cfg.Providers.Azure.VMSizes, cfg.Providers.Azure.ExcludeVMSizes),
+			Auth: azure.AuthConfig{
+				Mode:               cfg.Providers.Azure.Auth,
+				TenantID:           cfg.Providers.Azure.TenantID,
+				ClientID:           cfg.Providers.Azure.ClientID,
+				FederatedTokenFile: cfg.Providers.Azure.FederatedTokenFile,
+				VaultPath:          cfg.Providers.Azure.VaultPath,
+				Vault:              vaultConfig(cfg),
+			},
 		})
 	case "aws":
+		instanceFilter, err := awsInstanceFilter(cfg)
+		if err != nil {
+			return nil, err
+		}
 		return aws.New(ctx, &aws.Config{
-			Logger:         cfg.Logger,
-			Region:         cfg.Providers.AWS.Region,
-			Profile:        cfg.Providers.AWS.Profile,
-			ScrapeInterval: cfg.Collector.ScrapeInterval,
-			Services:       strings.Split(cfg.Providers.AWS.Services.String(), ","),
+			Logger: cfg.Logger,
+			Region: cfg.Providers.AWS.Region,
+			IMDS: aws.IMDSConfig{
+				Disabled: cfg.Providers.AWS.DisableIMDS,
+				Timeout:  cfg.Providers.AWS.IMDSTimeout,
+			},
+			Profile:                cfg.Providers.AWS.Profile,
+			ScrapeInterval:         cfg.Collector.ScrapeInterval,
+			ScrapeIntervals:        cfg.Collector.ScrapeIntervals,
+			Services:               strings.Split(cfg.Providers.AWS.Services.String(), ","),
+			EKSMetadata:            cfg.Providers.AWS.EKSMetadata,
+			IdleCost:               cfg.Providers.AWS.IdleCost,
+			CloudWatchLogGroups:    cfg.Providers.AWS.CloudWatchLogGroups,
+			S3BucketCosts:          cfg.Providers.AWS.S3BucketCosts,
+			S3BucketInventory:      cfg.Providers.AWS.S3BucketInventory,
+			S3BucketStorageMetrics: cfg.Providers.AWS.S3BucketStorageMetrics,
+			ELBTags:                cfg.Providers.AWS.ELBTags,
+			ClusterNames:           clusterNames,
+			Nodes:                  nodes,
+			Pods:                   pods,
+			Calendar:               calendar,
+			Anomalies:              anomalies,
+			Evictions:              eviction.New(cfg.Spot.EvictionOverhead),
+			Recommendations:        recommendations,
+			Prices:                 prices,
+			InstanceFilter:         instanceFilter,
+			PriceHistory:           priceHistory,
+			HTTPClient:             httpClient,
+			Endpoints:              egress.Endpoints(cfg.Providers.AWS.Endpoints),
+			PricingSource:          cfg.Providers.AWS.PricingSource,
+			SpotAggregation:        cfg.Providers.AWS.SpotAggregation,
+			AccountRoleARNs:        cfg.Providers.AWS.AssumeRoleARNs,
+			Regions:                awsRegionFilter(cfg),
+			InstanceFamilies:       familyFilter(cfg.Providers.AWS.InstanceFamilies, cfg.Providers.AWS.ExcludeInstanceFamilies),
+			StorageClasses:         storageClasses,
+			Auth: aws.AuthConfig{
+				Mode:                 cfg.Providers.AWS.Auth,
+				RoleARN:              cfg.Providers.AWS.RoleARN,
+				WebIdentityTokenFile: cfg.Providers.AWS.WebIdentityTokenFile,
+				VaultPath:            cfg.Providers.AWS.VaultPath,
+				Vault:                vaultConfig(cfg),
+			},
 		})
 
 	case "gcp":
-		return google.New(&google.Config{
-			ProjectId:       cfg.ProjectID,
-			Region:          cfg.Providers.GCP.Region,
-			Projects:        cfg.Providers.GCP.Projects.String(),
-			DefaultDiscount: cfg.Providers.GCP.DefaultGCSDiscount,
-			ScrapeInterval:  cfg.Collector.ScrapeInterval,
-			Services:        strings.Split(cfg.Providers.GCP.Services.String(), ","),
+		return google.New(ctx, &google.Config{
+			Logger:            cfg.Logger,
+			ProjectId:         cfg.ProjectID,
+			Region:            cfg.Providers.GCP.Region,
+			Projects:          cfg.Providers.GCP.Projects.String(),
+			DefaultDiscount:   cfg.Providers.GCP.DefaultGCSDiscount,
+			ScrapeInterval:    cfg.Collector.ScrapeInterval,
+			ScrapeIntervals:   cfg.Collector.ScrapeIntervals,
+			Services:          strings.Split(cfg.Providers.GCP.Services.String(), ","),
+			IdleCost:          cfg.Providers.GCP.IdleCost,
+			BucketCost:        cfg.Providers.GCP.BucketCost,
+			HierarchyDepth:    cfg.Providers.GCP.HierarchyDepth,
+			DiscoveryParent:   cfg.Providers.GCP.DiscoveryParent,
+			DiscoveryInterval: cfg.Providers.GCP.DiscoveryInterval,
+			ClusterNames:      clusterNames,
+			Nodes:             nodes,
+			Pods:              pods,
+			Calendar:          calendar,
+			Anomalies:         anomalies,
+			Recommendations:   recommendations,
+			Headroom:          cfg.Providers.GCP.AutoscalerHeadroom,
+			Prices:            prices,
+			InstanceFilter:    cfg.Providers.GCP.InstanceFilter,
+			MachineFamilies:   familyFilter(cfg.Providers.GCP.MachineFamilies, cfg.Providers.GCP.ExcludeMachineFamilies),
+			PriceHistory:      priceHistory,
+			HTTPClient:        httpClient,
+			Endpoints:         egress.Endpoints(cfg.Providers.GCP.Endpoints),
+			StorageClasses:    storageClasses,
+			Volumes:           volumes,
+			Auth: google.AuthConfig{
+				Mode:            cfg.Providers.GCP.Auth,
+				CredentialsFile: cfg.Providers.GCP.CredentialsFile,
+				VaultPath:       cfg.Providers.GCP.VaultPath,
+				Vault:           vaultConfig(cfg),
+			},
 		})
 
 	default:
 		return nil, fmt.Errorf("unknown provider")
 	}
 }
+
+// identity returns the identity labels of the cloud account the exporter collects, served by the HTTP service
+// discovery. The AWS account of the credentials of the exporter isn't known without a call to STS, only the linked
+// accounts of -aws.assume-role-arn are, so it's left to -sd.label.
+func identity(cfg *config.Config) map[string]string {
+	switch cfg.Provider {
+	case "aws":
+		var accounts []string
+		for _, roleARN := range cfg.Providers.AWS.AssumeRoleARNs {
+			if parsed, err := arn.Parse(roleARN); err == nil {
+				accounts = append(accounts, parsed.AccountID)
+			}
+		}
+		return map[string]string{
+			"provider":    cfg.Provider,
+			"region":      cfg.Providers.AWS.Region,
+			"account_ids": strings.Join(accounts, ","),
+		}
+	case "gcp":
+		return map[string]string{
+			"provider": cfg.Provider,
+			"project":  cfg.ProjectID,
+			"projects": cfg.Providers.GCP.Projects.String(),
+			"region":   cfg.Providers.GCP.Region,
+		}
+	case "azure":
+		return map[string]string{
+			"provider":         cfg.Provider,
+			"subscription_id":  cfg.Providers.Azure.SubscriptionId,
+			"management_group": cfg.Providers.Azure.ManagementGroup,
+		}
+	}
+	return map[string]string{"provider": cfg.Provider}
+}
+
+// vaultConfig returns how the providers read their secrets with the vault auth.
+func vaultConfig(cfg *config.Config) secrets.VaultConfig {
+	return secrets.VaultConfig{
+		Address:         cfg.Vault.Address,
+		TokenFile:       cfg.Vault.TokenFile,
+		Namespace:       cfg.Vault.Namespace,
+		RefreshInterval: cfg.Vault.RefreshInterval,
+	}
+}
+
+// awsRegionFilter returns the region filter of the EKS and EC2 collectors, or nil when no pattern is set.
+func awsRegionFilter(cfg *config.Config) *compute.RegionFilter {
+	if len(cfg.Providers.AWS.Regions) == 0 && len(cfg.Providers.AWS.ExcludeRegions) == 0 {
+		return nil
+	}
+	return &compute.RegionFilter{
+		Allow: cfg.Providers.AWS.Regions,
+		Deny:  cfg.Providers.AWS.ExcludeRegions,
+	}
+}
+
+// familyFilter returns the filter of the families whose prices are fetched, or nil when no pattern is set.
+func familyFilter(include []string, exclude []string) *pricing.FamilyFilter {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+	return &pricing.FamilyFilter{Include: include, Exclude: exclude}
+}
+
+// awsInstanceFilter returns the instance filter of the EKS collector, or nil when no selector is set.
+func awsInstanceFilter(cfg *config.Config) (*compute.InstanceFilter, error) {
+	tags, err := compute.ParseTagSelectors(cfg.Providers.AWS.InstanceTags)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -aws.instance-tag: %w", err)
+	}
+	excludeTags, err := compute.ParseTagSelectors(cfg.Providers.AWS.ExcludeInstanceTags)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -aws.exclude-instance-tag: %w", err)
+	}
+	filter := &compute.InstanceFilter{
+		EKSOnly:      cfg.Providers.AWS.EKSOnly,
+		Tags:         tags,
+		ExcludeTags:  excludeTags,
+		VPCIDs:       cfg.Providers.AWS.VPCIDs,
+		Names:        cfg.Providers.AWS.InstanceNames,
+		ExcludeNames: cfg.Providers.AWS.ExcludeInstanceNames,
+	}
+	if !filter.EKSOnly && len(filter.Tags) == 0 && len(filter.ExcludeTags) == 0 && len(filter.VPCIDs) == 0 && len(filter.Names) == 0 && len(filter.ExcludeNames) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/cmd/exporter/config"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+// inFlightProvider only waits for its collections in flight when it's closed.
+type inFlightProvider struct {
+	inFlight provider.InFlight
+}
+
+func (p *inFlightProvider) Describe(chan<- *prometheus.Desc) {}
+
+func (p *inFlightProvider) Collect(chan<- prometheus.Metric) {}
+
+func (p *inFlightProvider) RegisterCollectors(provider.Registry) error { return nil }
+
+func (p *inFlightProvider) Close(ctx context.Context) error { return p.inFlight.Wait(ctx) }
+
+func TestRunServer_Shutdown(t *testing.T) {
+	for name, tt := range map[string]struct {
+		completed bool
+		wantErr   error
+		wantClose bool
+	}{
+		"collections complete":                          {completed: true, wantClose: true},
+		"collections still in flight after the timeout": {wantErr: provider.ErrCollectionsInFlight},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Server.Address = "127.0.0.1:0"
+			cfg.Server.Path = "/metrics"
+			cfg.Server.Timeout = 50 * time.Millisecond
+			csp := &inFlightProvider{}
+			var out bytes.Buffer
+			ctx, cancel := context.WithCancel(context.Background())
+
+			done := csp.inFlight.Start()
+			if tt.completed {
+				done()
+			} else {
+				defer done()
+			}
+			cancel()
+
+			err := runServer(ctx, &cfg, csp, nil, nil, slog.New(slog.NewTextHandler(&out, nil)))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.wantClose {
+				assert.Contains(t, out.String(), "Closed provider clients")
+			} else {
+				assert.NotContains(t, out.String(), "Closed provider clients", "a provider with collections in flight isn't reported as closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/cmd/exporter/config"
+	"github.com/grafana/cloudcost-exporter/pkg/aws"
+	"github.com/grafana/cloudcost-exporter/pkg/azure"
+	"github.com/grafana/cloudcost-exporter/pkg/google"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden expositions in testdata/golden instead of comparing against them.")
+
+// redirectTransport sends every request of the SDK clients to a fake cloud, whatever their host, so that the
+// providers can be built with their real constructors. The fake cloud tells the APIs apart by the original host.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = t.target.Scheme
+	redirected.URL.Host = t.target.Host
+	redirected.Host = req.URL.Host
+	return http.DefaultTransport.RoundTrip(redirected)
+}
+
+// unstableMetric reports the metrics whose value changes from a run to another, eg durations, scrape times or the
+// metrics of the Go runtime, and the counters, which are global and incremented by every test of the package. They're left out
+// of the golden expositions.
+func unstableMetric(name string) bool {
+	for _, prefix := range []string{"go_", "process_", "promhttp_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range []string{"_duration_seconds", "_last_scrape_time", "_next_scrape", "_build_info", "_total"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrape returns the stable metrics served on the metrics path of the exporter, sorted by name.
+func scrape(t *testing.T, serverURL string) string {
+	req, err := http.NewRequest(http.MethodGet, serverURL+"/metrics", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	require.NoError(t, err)
+	names := make([]string, 0, len(families))
+	for name := range families {
+		if !unstableMetric(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var out bytes.Buffer
+	for _, name := range names {
+		_, err := expfmt.MetricFamilyToText(&out, families[name])
+		require.NoError(t, err)
+	}
+	return out.String()
+}
+
+// awsFakeCloud answers the pricing API with a product of every unit priced by the collectors, Cost Explorer with the
+// S3 usage of a region, and EC2 with a region, the spot price of an instance type and an on-demand and a spot
+// instance of an EKS cluster.
+func awsFakeCloud(t *testing.T) http.Handler {
+	products := map[string][]string{
+		"AWSQueueService": {
+			`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"beginRange":"0","pricePerUnit":{"USD":"0"}},"A.2":{"beginRange":"1000000","pricePerUnit":{"USD":"0.0000004"}}}}}}}`,
+			`{"product":{"attributes":{"regionCode":"eu-west-1","usagetype":"EU-Requests-FIFO-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"0.0000005"}}}}}}}`,
+		},
+		"AmazonSNS": {
+			`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"0.0000005"}}}}}}}`,
+		},
+		"AmazonKinesis": {
+			`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Storage-ShardHour"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"0.015"}}}}}}}`,
+		},
+		"AmazonCloudWatch": {
+			`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"CW:MetricMonitorUsage"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"0.30"}}}}}}}`,
+			`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"DataProcessing-Bytes"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"0.50"}}}}}}}`,
+		},
+		"AmazonEC2": {
+			`{"product":{"productFamily":"Compute Instance","attributes":{"regionCode":"us-east-1","locationType":"AWS Region","instanceType":"m5.large","instanceFamily":"General purpose","vcpu":"2","memory":"8 GiB","gpu":"NA"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"unit":"Hrs","pricePerUnit":{"USD":"0.096"}}}}}}}`,
+			`{"product":{"productFamily":"Storage","attributes":{"regionCode":"us-east-1","volumeApiName":"gp3"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"unit":"GB-Mo","pricePerUnit":{"USD":"0.08"}}}}}}}`,
+		},
+	}
+	// The S3 collector only keeps the usage types prefixed by the billing code of a region
+	costAndUsage := map[string]any{"ResultsByTime": []any{map[string]any{"Groups": []any{
+		map[string]any{"Keys": []string{"USE1-TimedStorage-ByteHrs"}, "Metrics": map[string]any{
+			"UsageQuantity": map[string]any{"Amount": "1000", "Unit": "GB-Month"},
+			"UnblendedCost": map[string]any{"Amount": "23", "Unit": "USD"},
+		}},
+		map[string]any{"Keys": []string{"USE1-Requests-Tier1"}, "Metrics": map[string]any{
+			"UsageQuantity": map[string]any{"Amount": "1000000", "Unit": "Requests"},
+			"UnblendedCost": map[string]any{"Amount": "5", "Unit": "USD"},
+		}},
+	}}}}
+	instance := func(id string, lifecycle string) string {
+		return `<item><instanceId>` + id + `</instanceId><instanceType>m5.large</instanceType>` + lifecycle +
+			`<privateDnsName>` + id + `.ec2.internal</privateDnsName><placement><availabilityZone>us-east-1a</availabilityZone></placement>` +
+			`<instanceState><code>16</code><name>running</name></instanceState><launchTime>2024-01-01T00:00:00.000Z</launchTime>` +
+			`<tagSet><item><key>eks:cluster-name</key><value>prod</value></item></tagSet></item>`
+	}
+	ec2Responses := map[string]string{
+		"DescribeRegions": `<DescribeRegionsResponse><regionInfo><item><regionName>us-east-1</regionName>` +
+			`<regionEndpoint>ec2.us-east-1.amazonaws.com</regionEndpoint><optInStatus>opt-in-not-required</optInStatus></item></regionInfo></DescribeRegionsResponse>`,
+		"DescribeSpotPriceHistory": `<DescribeSpotPriceHistoryResponse><spotPriceHistorySet><item><availabilityZone>us-east-1a</availabilityZone>` +
+			`<instanceType>m5.large</instanceType><productDescription>Linux/UNIX (Amazon VPC)</productDescription><spotPrice>0.04</spotPrice>` +
+			`<timestamp>2024-01-01T00:00:00.000Z</timestamp></item></spotPriceHistorySet></DescribeSpotPriceHistoryResponse>`,
+		"DescribeInstances": `<DescribeInstancesResponse><reservationSet><item><reservationId>r-1</reservationId><instancesSet>` +
+			instance("i-ondemand", "") + instance("i-spot", "<instanceLifecycle>spot</instanceLifecycle>") +
+			`</instancesSet></item></reservationSet></DescribeInstancesResponse>`,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.Host, "api.pricing.") && r.Header.Get("X-Amz-Target") == "AWSPriceListService.GetProducts":
+			var input struct {
+				ServiceCode string
+				Filters     []struct{ Field, Value string }
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			_ = json.NewEncoder(w).Encode(map[string]any{"FormatVersion": "aws_v1", "PriceList": productsOfFamily(products[input.ServiceCode], input.Filters)})
+		case strings.HasPrefix(r.Host, "ce.") && r.Header.Get("X-Amz-Target") == "AWSInsightsIndexService.GetCostAndUsage":
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			_ = json.NewEncoder(w).Encode(costAndUsage)
+		case strings.HasPrefix(r.Host, "ec2.") && r.Method == http.MethodPost && ec2Responses[formAction(r)] != "":
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = io.WriteString(w, ec2Responses[formAction(r)])
+		default:
+			t.Errorf("unexpected aws request %s %s%s %s", r.Method, r.Host, r.URL.Path, r.Header.Get("X-Amz-Target"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// formAction returns the action of a request of the EC2 query API, or an empty string when the form can't be parsed.
+func formAction(r *http.Request) string {
+	if err := r.ParseForm(); err != nil {
+		return ""
+	}
+	return r.PostForm.Get("Action")
+}
+
+// productsOfFamily filters the products of a service code by the productFamily filter of GetProducts, which is the
+// only filter telling apart the products the collectors list from the same service code. The other filters are
+// ignored.
+func productsOfFamily(products []string, filters []struct{ Field, Value string }) []string {
+	family := ""
+	for _, filter := range filters {
+		if filter.Field == "productFamily" {
+			family = filter.Value
+		}
+	}
+	if family == "" {
+		return products
+	}
+	var filtered []string
+	for _, product := range products {
+		var entry struct {
+			Product struct {
+				ProductFamily string `json:"productFamily"`
+			} `json:"product"`
+		}
+		if err := json.Unmarshal([]byte(product), &entry); err == nil && entry.Product.ProductFamily == family {
+			filtered = append(filtered, product)
+		}
+	}
+	return filtered
+}
+
+func newAWSProvider(t *testing.T, httpClient *http.Client, _ string) (provider.Provider, error) {
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CA_BUNDLE", "")
+	return aws.New(context.Background(), &aws.Config{
+		Services:       []string{"s3", "eks", "messaging", "observability"},
+		Region:         "us-east-1",
+		ScrapeInterval: time.Hour,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		HTTPClient:     httpClient,
+	})
+}
+
+// gcpFakeCloud answers the token endpoint of the service account, the REST catalog of Cloud Billing with a sku of
+// every unit priced by the collectors, Compute Engine with an on-demand and a spot instance in a zone, and Cloud
+// Monitoring with the same volume for every billing metric.
+func gcpFakeCloud(t *testing.T) http.Handler {
+	sku := func(description string, usageUnit string, units string, nanos int) map[string]any {
+		return map[string]any{
+			"description":    description,
+			"serviceRegions": []string{"global"},
+			"pricingInfo": []any{map[string]any{"pricingExpression": map[string]any{
+				"usageUnit":   usageUnit,
+				"tieredRates": []any{map[string]any{"unitPrice": map[string]any{"currencyCode": "USD", "units": units, "nanos": nanos}}},
+			}}},
+		}
+	}
+	// The compute skus are regional, the pricing map is keyed by the region of the instances
+	computeSku := func(description string, usageUnit string, nanos int) map[string]any {
+		s := sku(description, usageUnit, "0", nanos)
+		s["serviceRegions"] = []string{"us-central1"}
+		return s
+	}
+	skus := map[string][]any{
+		"/v1/services/6F81-5844-456A/skus": {
+			computeSku("N2 Instance Core running in Americas", "h", 31611000),
+			computeSku("N2 Instance Ram running in Americas", "GiBy.h", 4237000),
+			computeSku("Spot Preemptible N2 Instance Core running in Americas", "h", 7650000),
+			computeSku("Spot Preemptible N2 Instance Ram running in Americas", "GiBy.h", 1025000),
+		},
+		"/v1/services/pubsub/skus":     {sku("Message Delivery Basic", "TiBy", "40", 0)},
+		"/v1/services/cloudtasks/skus": {sku("Operations", "count", "0", 400)},
+		"/v1/services/logging/skus":    {sku("Log Storage cost", "GiBy", "0", 500000000)},
+		"/v1/services/monitoring/skus": {sku("Metric Volume", "MiBy", "0", 258000000), sku("Prometheus Samples Ingested", "count", "0", 60)},
+	}
+	instance := func(name string, provisioningModel string) map[string]any {
+		return map[string]any{
+			"name":              name,
+			"zone":              "https://www.googleapis.com/compute/v1/projects/testing/zones/us-central1-a",
+			"machineType":       "https://www.googleapis.com/compute/v1/projects/testing/zones/us-central1-a/machineTypes/n2-standard-2",
+			"creationTimestamp": "2024-01-01T00:00:00Z",
+			"status":            "RUNNING",
+			"scheduling":        map[string]any{"provisioningModel": provisioningModel},
+		}
+	}
+	compute := map[string]any{
+		"/compute/v1/projects/testing/zones":                                          map[string]any{"items": []any{map[string]any{"name": "us-central1-a"}}},
+		"/compute/v1/projects/testing/zones/us-central1-a/instances":                  map[string]any{"items": []any{instance("vm-standard", "STANDARD"), instance("vm-spot", "SPOT")}},
+		"/compute/v1/projects/testing/zones/us-central1-a/machineTypes/n2-standard-2": map[string]any{"name": "n2-standard-2", "guestCpus": 2, "memoryMb": 8192},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body any
+		switch {
+		case r.URL.Path == "/token":
+			body = map[string]any{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}
+		case r.Host == "cloudbilling.googleapis.com" && r.URL.Path == "/v1/services":
+			body = map[string]any{"services": []any{
+				map[string]any{"name": "services/6F81-5844-456A", "displayName": "Compute Engine"},
+				map[string]any{"name": "services/pubsub", "displayName": "Cloud Pub/Sub"},
+				map[string]any{"name": "services/cloudtasks", "displayName": "Cloud Tasks"},
+				map[string]any{"name": "services/logging", "displayName": "Cloud Logging"},
+				map[string]any{"name": "services/monitoring", "displayName": "Cloud Monitoring"},
+			}}
+		case r.Host == "cloudbilling.googleapis.com" && skus[r.URL.Path] != nil:
+			body = map[string]any{"skus": skus[r.URL.Path]}
+		case r.Host == "compute.googleapis.com" && compute[r.URL.Path] != nil:
+			body = compute[r.URL.Path]
+		case r.Host == "monitoring.googleapis.com" && r.URL.Path == "/v3/projects/testing/timeSeries":
+			body = map[string]any{"timeSeries": []any{map[string]any{"points": []any{map[string]any{"value": map[string]any{"int64Value": "1073741824"}}}}}}
+		default:
+			t.Errorf("unexpected gcp request %s %s%s", r.Method, r.Host, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
+
+// newGCPProvider authenticates with a service account key whose token endpoint is the fake cloud, as the clients
+// aren't created without credentials.
+func newGCPProvider(t *testing.T, httpClient *http.Client, fakeCloudURL string) (provider.Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	credentials, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "testing",
+		"private_key_id": "key",
+		"private_key":    string(pemKey),
+		"client_email":   "exporter@testing.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      fakeCloudURL + "/token",
+	})
+	require.NoError(t, err)
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(credentialsFile, credentials, 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentialsFile)
+	return google.New(context.Background(), &google.Config{
+		ProjectId:      "testing",
+		Projects:       "testing",
+		Services:       []string{"compute", "messaging", "observability"},
+		ScrapeInterval: time.Hour,
+		HTTPClient:     httpClient,
+	})
+}
+
+// azureFakeCloud answers the token flow of Microsoft Entra ID, the retail prices of the messaging units and lists an
+// Event Hubs and a Service Bus namespace in Resource Manager.
+func azureFakeCloud(t *testing.T) http.Handler {
+	const authority = "https://login.fake/tenant"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body any
+		switch {
+		case r.URL.Path == "/common/discovery/instance":
+			body = map[string]any{"tenant_discovery_endpoint": authority + "/v2.0/.well-known/openid-configuration", "api-version": "1.1", "metadata": []any{}}
+		case r.URL.Path == "/tenant/v2.0/.well-known/openid-configuration":
+			body = map[string]any{
+				"token_endpoint":         authority + "/oauth2/v2.0/token",
+				"authorization_endpoint": authority + "/oauth2/v2.0/authorize",
+				"issuer":                 authority + "/v2.0",
+			}
+		case r.URL.Path == "/tenant/oauth2/v2.0/token":
+			body = map[string]any{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}
+		case r.Host == "prices.azure.com":
+			body = map[string]any{"Items": []any{
+				map[string]any{"serviceName": "Event Hubs", "meterName": "Standard Throughput Unit", "armRegionName": "eastus", "unitOfMeasure": "1 Hour", "retailPrice": 0.03},
+				map[string]any{"serviceName": "Service Bus", "meterName": "Premium Messaging Unit", "armRegionName": "eastus", "unitOfMeasure": "1/Month", "retailPrice": 677.08},
+			}}
+		case r.Host == "management.azure.com" && r.URL.Path == "/subscriptions/subscription/resources":
+			resources := map[string]any{
+				"resourceType eq 'Microsoft.EventHub/namespaces'": map[string]any{
+					"id": "/subscriptions/subscription/resourceGroups/messaging/providers/Microsoft.EventHub/namespaces/events", "name": "events", "location": "eastus",
+					"sku": map[string]any{"name": "Standard", "tier": "Standard", "capacity": 2},
+				},
+				"resourceType eq 'Microsoft.ServiceBus/namespaces'": map[string]any{
+					"id": "/subscriptions/subscription/resourceGroups/messaging/providers/Microsoft.ServiceBus/namespaces/queues", "name": "queues", "location": "eastus",
+					"sku": map[string]any{"name": "Premium", "tier": "Premium", "capacity": 1},
+				},
+			}
+			body = map[string]any{"value": []any{resources[r.URL.Query().Get("$filter")]}}
+		default:
+			t.Errorf("unexpected azure request %s %s%s", r.Method, r.Host, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
+
+// newAzureProvider authenticates with workload identity against the fake authority, as it only needs a token file.
+func newAzureProvider(t *testing.T, httpClient *http.Client, _ string) (provider.Provider, error) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("assertion"), 0o600))
+	cloud, err := azure.CloudConfiguration("public", "https://login.fake/", "")
+	require.NoError(t, err)
+	return azure.New(context.Background(), &azure.Config{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		SubscriptionId: "subscription",
+		ScrapeInterval: time.Hour,
+		Services:       []string{"messaging"},
+		Cloud:          cloud,
+		HTTPClient:     httpClient,
+		Auth: azure.AuthConfig{
+			Mode:               azure.AuthWorkloadIdentity,
+			TenantID:           "tenant",
+			ClientID:           "client",
+			FederatedTokenFile: tokenFile,
+		},
+	})
+}
+
+// TestGolden builds every provider against a fake cloud, serves it with the handler of the exporter and compares the
+// scraped metrics with the golden exposition of the provider, to catch regressions of the metric names, labels and
+// registration across packages. Only the collectors the fake clouds answer are covered: S3, EKS, messaging and
+// observability on AWS, compute, messaging and observability on GCP, and messaging on Azure. Run with -update to
+// regenerate the golden expositions after an intended change.
+func TestGolden(t *testing.T) {
+	tests := map[string]struct {
+		fakeCloud   func(t *testing.T) http.Handler
+		newProvider func(t *testing.T, httpClient *http.Client, fakeCloudURL string) (provider.Provider, error)
+	}{
+		"aws":   {fakeCloud: awsFakeCloud, newProvider: newAWSProvider},
+		"gcp":   {fakeCloud: gcpFakeCloud, newProvider: newGCPProvider},
+		"azure": {fakeCloud: azureFakeCloud, newProvider: newAzureProvider},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeCloud := httptest.NewServer(tt.fakeCloud(t))
+			defer fakeCloud.Close()
+			target, err := url.Parse(fakeCloud.URL)
+			require.NoError(t, err)
+			csp, err := tt.newProvider(t, &http.Client{Transport: &redirectTransport{target: target}}, fakeCloud.URL)
+			require.NoError(t, err)
+
+			var cfg config.Config
+			cfg.Server.Path = "/metrics"
+			handler, err := newHandler(context.Background(), &cfg, csp, nil, nil)
+			require.NoError(t, err)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			// The collectors setting the gauges they registered while they're collected, eg S3, race with the gathering
+			// of their gauges, which are only stable from the second scrape
+			scrape(t, server.URL)
+			got := scrape(t, server.URL)
+			golden := filepath.Join("testdata", "golden", name+".prom")
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+				require.NoError(t, os.WriteFile(golden, []byte(got), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), got)
+			require.NoError(t, csp.Close(context.Background()))
+		})
+	}
+}
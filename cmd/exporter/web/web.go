@@ -1,16 +1,24 @@
 package web
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/prometheus/common/version"
 )
 
+// VersionPath serves the build information of the exporter.
+const VersionPath = "/version"
+
 const homepageTemplate = `<!doctype html>
 <html>
   <head><title>Cloudcost Exporter</title></head>
   <body>
     <h1>Cloudcost Exporter</h1>
     <p><a href=%q>Metrics</a></p>
+    <p><a href="/version">Version</a></p>
+    <p><a href="/sd">Service discovery</a></p>
   </body>
 </html>`
 
@@ -23,3 +31,31 @@ func HomePageHandler(metricsPath string) func(w http.ResponseWriter, r *http.Req
 		}
 	}
 }
+
+// BuildInfo is the build information of the exporter, stamped at build time with -ldflags, see the Makefile. It's the
+// same as the labels of cloudcost_exporter_build_info.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"build_user"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	GoOS      string `json:"goos"`
+	GoArch    string `json:"goarch"`
+}
+
+// VersionHandler serves the build information of the exporter as JSON.
+func VersionHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(BuildInfo{
+		Version:   version.Version,
+		Revision:  version.GetRevision(),
+		Branch:    version.Branch,
+		BuildUser: version.BuildUser,
+		BuildDate: version.BuildDate,
+		GoVersion: version.GoVersion,
+		GoOS:      version.GoOS,
+		GoArch:    version.GoArch,
+	})
+}
@@ -1,11 +1,14 @@
 package web
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/prometheus/common/version"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLandingPage(t *testing.T) {
@@ -37,3 +40,19 @@ func TestLandingPage(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionHandler(t *testing.T) {
+	version.Version = "v1.2.3"
+	version.Branch = "main"
+	req := httptest.NewRequest(http.MethodGet, VersionPath, nil)
+	resRecorder := httptest.NewRecorder()
+
+	VersionHandler(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Equal(t, "application/json", resRecorder.Header().Get("Content-Type"))
+	var info BuildInfo
+	require.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &info))
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, "main", info.Branch)
+	assert.Equal(t, version.GoVersion, info.GoVersion)
+}
@@ -96,6 +96,80 @@ func (_c *CostExplorer_GetCostAndUsage_Call) RunAndReturn(run func(context.Conte
 	return _c
 }
 
+// GetCostAndUsageWithResources provides a mock function with given fields: ctx, params, optFns
+func (_m *CostExplorer) GetCostAndUsageWithResources(ctx context.Context, params *servicecostexplorer.GetCostAndUsageWithResourcesInput, optFns ...func(*servicecostexplorer.Options)) (*servicecostexplorer.GetCostAndUsageWithResourcesOutput, error) {
+	_va := make([]interface{}, len(optFns))
+	for _i := range optFns {
+		_va[_i] = optFns[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, params)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCostAndUsageWithResources")
+	}
+
+	var r0 *servicecostexplorer.GetCostAndUsageWithResourcesOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *servicecostexplorer.GetCostAndUsageWithResourcesInput, ...func(*servicecostexplorer.Options)) (*servicecostexplorer.GetCostAndUsageWithResourcesOutput, error)); ok {
+		return rf(ctx, params, optFns...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *servicecostexplorer.GetCostAndUsageWithResourcesInput, ...func(*servicecostexplorer.Options)) *servicecostexplorer.GetCostAndUsageWithResourcesOutput); ok {
+		r0 = rf(ctx, params, optFns...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*servicecostexplorer.GetCostAndUsageWithResourcesOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *servicecostexplorer.GetCostAndUsageWithResourcesInput, ...func(*servicecostexplorer.Options)) error); ok {
+		r1 = rf(ctx, params, optFns...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CostExplorer_GetCostAndUsageWithResources_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCostAndUsageWithResources'
+type CostExplorer_GetCostAndUsageWithResources_Call struct {
+	*mock.Call
+}
+
+// GetCostAndUsageWithResources is a helper method to define mock.On call
+//   - ctx context.Context
+//   - params *servicecostexplorer.GetCostAndUsageWithResourcesInput
+//   - optFns ...func(*servicecostexplorer.Options)
+func (_e *CostExplorer_Expecter) GetCostAndUsageWithResources(ctx interface{}, params interface{}, optFns ...interface{}) *CostExplorer_GetCostAndUsageWithResources_Call {
+	return &CostExplorer_GetCostAndUsageWithResources_Call{Call: _e.mock.On("GetCostAndUsageWithResources",
+		append([]interface{}{ctx, params}, optFns...)...)}
+}
+
+func (_c *CostExplorer_GetCostAndUsageWithResources_Call) Run(run func(ctx context.Context, params *servicecostexplorer.GetCostAndUsageWithResourcesInput, optFns ...func(*servicecostexplorer.Options))) *CostExplorer_GetCostAndUsageWithResources_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]func(*servicecostexplorer.Options), len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(func(*servicecostexplorer.Options))
+			}
+		}
+		run(args[0].(context.Context), args[1].(*servicecostexplorer.GetCostAndUsageWithResourcesInput), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *CostExplorer_GetCostAndUsageWithResources_Call) Return(_a0 *servicecostexplorer.GetCostAndUsageWithResourcesOutput, _a1 error) *CostExplorer_GetCostAndUsageWithResources_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *CostExplorer_GetCostAndUsageWithResources_Call) RunAndReturn(run func(context.Context, *servicecostexplorer.GetCostAndUsageWithResourcesInput, ...func(*servicecostexplorer.Options)) (*servicecostexplorer.GetCostAndUsageWithResourcesOutput, error)) *CostExplorer_GetCostAndUsageWithResources_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewCostExplorer creates a new instance of CostExplorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCostExplorer(t interface {
@@ -22,6 +22,80 @@ func (_m *EC2) EXPECT() *EC2_Expecter {
 	return &EC2_Expecter{mock: &_m.Mock}
 }
 
+// DescribeAddresses provides a mock function with given fields: ctx, e, optFns
+func (_m *EC2) DescribeAddresses(ctx context.Context, e *serviceec2.DescribeAddressesInput, optFns ...func(*serviceec2.Options)) (*serviceec2.DescribeAddressesOutput, error) {
+	_va := make([]interface{}, len(optFns))
+	for _i := range optFns {
+		_va[_i] = optFns[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, e)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DescribeAddresses")
+	}
+
+	var r0 *serviceec2.DescribeAddressesOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *serviceec2.DescribeAddressesInput, ...func(*serviceec2.Options)) (*serviceec2.DescribeAddressesOutput, error)); ok {
+		return rf(ctx, e, optFns...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *serviceec2.DescribeAddressesInput, ...func(*serviceec2.Options)) *serviceec2.DescribeAddressesOutput); ok {
+		r0 = rf(ctx, e, optFns...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*serviceec2.DescribeAddressesOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *serviceec2.DescribeAddressesInput, ...func(*serviceec2.Options)) error); ok {
+		r1 = rf(ctx, e, optFns...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EC2_DescribeAddresses_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DescribeAddresses'
+type EC2_DescribeAddresses_Call struct {
+	*mock.Call
+}
+
+// DescribeAddresses is a helper method to define mock.On call
+//   - ctx context.Context
+//   - e *serviceec2.DescribeAddressesInput
+//   - optFns ...func(*serviceec2.Options)
+func (_e *EC2_Expecter) DescribeAddresses(ctx interface{}, e interface{}, optFns ...interface{}) *EC2_DescribeAddresses_Call {
+	return &EC2_DescribeAddresses_Call{Call: _e.mock.On("DescribeAddresses",
+		append([]interface{}{ctx, e}, optFns...)...)}
+}
+
+func (_c *EC2_DescribeAddresses_Call) Run(run func(ctx context.Context, e *serviceec2.DescribeAddressesInput, optFns ...func(*serviceec2.Options))) *EC2_DescribeAddresses_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]func(*serviceec2.Options), len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(func(*serviceec2.Options))
+			}
+		}
+		run(args[0].(context.Context), args[1].(*serviceec2.DescribeAddressesInput), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *EC2_DescribeAddresses_Call) Return(_a0 *serviceec2.DescribeAddressesOutput, _a1 error) *EC2_DescribeAddresses_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *EC2_DescribeAddresses_Call) RunAndReturn(run func(context.Context, *serviceec2.DescribeAddressesInput, ...func(*serviceec2.Options)) (*serviceec2.DescribeAddressesOutput, error)) *EC2_DescribeAddresses_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DescribeInstances provides a mock function with given fields: ctx, e, optFns
 func (_m *EC2) DescribeInstances(ctx context.Context, e *serviceec2.DescribeInstancesInput, optFns ...func(*serviceec2.Options)) (*serviceec2.DescribeInstancesOutput, error) {
 	_va := make([]interface{}, len(optFns))
@@ -170,6 +244,80 @@ func (_c *EC2_DescribeRegions_Call) RunAndReturn(run func(context.Context, *serv
 	return _c
 }
 
+// DescribeReservedInstances provides a mock function with given fields: ctx, e, optFns
+func (_m *EC2) DescribeReservedInstances(ctx context.Context, e *serviceec2.DescribeReservedInstancesInput, optFns ...func(*serviceec2.Options)) (*serviceec2.DescribeReservedInstancesOutput, error) {
+	_va := make([]interface{}, len(optFns))
+	for _i := range optFns {
+		_va[_i] = optFns[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, e)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DescribeReservedInstances")
+	}
+
+	var r0 *serviceec2.DescribeReservedInstancesOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *serviceec2.DescribeReservedInstancesInput, ...func(*serviceec2.Options)) (*serviceec2.DescribeReservedInstancesOutput, error)); ok {
+		return rf(ctx, e, optFns...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *serviceec2.DescribeReservedInstancesInput, ...func(*serviceec2.Options)) *serviceec2.DescribeReservedInstancesOutput); ok {
+		r0 = rf(ctx, e, optFns...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*serviceec2.DescribeReservedInstancesOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *serviceec2.DescribeReservedInstancesInput, ...func(*serviceec2.Options)) error); ok {
+		r1 = rf(ctx, e, optFns...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EC2_DescribeReservedInstances_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DescribeReservedInstances'
+type EC2_DescribeReservedInstances_Call struct {
+	*mock.Call
+}
+
+// DescribeReservedInstances is a helper method to define mock.On call
+//   - ctx context.Context
+//   - e *serviceec2.DescribeReservedInstancesInput
+//   - optFns ...func(*serviceec2.Options)
+func (_e *EC2_Expecter) DescribeReservedInstances(ctx interface{}, e interface{}, optFns ...interface{}) *EC2_DescribeReservedInstances_Call {
+	return &EC2_DescribeReservedInstances_Call{Call: _e.mock.On("DescribeReservedInstances",
+		append([]interface{}{ctx, e}, optFns...)...)}
+}
+
+func (_c *EC2_DescribeReservedInstances_Call) Run(run func(ctx context.Context, e *serviceec2.DescribeReservedInstancesInput, optFns ...func(*serviceec2.Options))) *EC2_DescribeReservedInstances_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]func(*serviceec2.Options), len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(func(*serviceec2.Options))
+			}
+		}
+		run(args[0].(context.Context), args[1].(*serviceec2.DescribeReservedInstancesInput), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *EC2_DescribeReservedInstances_Call) Return(_a0 *serviceec2.DescribeReservedInstancesOutput, _a1 error) *EC2_DescribeReservedInstances_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *EC2_DescribeReservedInstances_Call) RunAndReturn(run func(context.Context, *serviceec2.DescribeReservedInstancesInput, ...func(*serviceec2.Options)) (*serviceec2.DescribeReservedInstancesOutput, error)) *EC2_DescribeReservedInstances_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DescribeSpotPriceHistory provides a mock function with given fields: ctx, input, optFns
 func (_m *EC2) DescribeSpotPriceHistory(ctx context.Context, input *serviceec2.DescribeSpotPriceHistoryInput, optFns ...func(*serviceec2.Options)) (*serviceec2.DescribeSpotPriceHistoryOutput, error) {
 	_va := make([]interface{}, len(optFns))
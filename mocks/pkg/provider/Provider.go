@@ -3,6 +3,8 @@
 package provider
 
 import (
+	context "context"
+
 	prometheus "github.com/prometheus/client_golang/prometheus"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -20,6 +22,52 @@ func (_m *Provider) EXPECT() *Provider_Expecter {
 	return &Provider_Expecter{mock: &_m.Mock}
 }
 
+// Close provides a mock function with given fields: ctx
+func (_m *Provider) Close(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Provider_Close_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Close'
+type Provider_Close_Call struct {
+	*mock.Call
+}
+
+// Close is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Provider_Expecter) Close(ctx interface{}) *Provider_Close_Call {
+	return &Provider_Close_Call{Call: _e.mock.On("Close", ctx)}
+}
+
+func (_c *Provider_Close_Call) Run(run func(ctx context.Context)) *Provider_Close_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Provider_Close_Call) Return(_a0 error) *Provider_Close_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Provider_Close_Call) RunAndReturn(run func(context.Context) error) *Provider_Close_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Collect provides a mock function with given fields: _a0
 func (_m *Provider) Collect(_a0 chan<- prometheus.Metric) {
 	_m.Called(_a0)
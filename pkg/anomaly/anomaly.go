@@ -0,0 +1,146 @@
+// Package anomaly scores the hourly cost of the clusters estimated by the exporter against its exponentially weighted
+// moving average (EWMA), so that sudden node count or price spikes can be alerted on without an external anomaly
+// detection system.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+// minRelativeDeviation floors the standard deviation of a cost at a share of its average. A cost that never changed
+// has no deviation, which would otherwise make the slightest change an infinite anomaly.
+const minRelativeDeviation = 0.05
+
+var (
+	// CostAnomalyScoreDesc is the number of standard deviations the hourly cost of a cluster is away from its average.
+	// The total of every cluster of a provider is scored as well, with an empty cluster_name.
+	CostAnomalyScoreDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.ExporterName, "cost", "anomaly_score"),
+		"How many standard deviations the hourly cost of a cluster, or of every cluster of the provider when cluster_name is empty, is away from its exponentially weighted moving average. Positive values are cost increases.",
+		[]string{"provider", "cluster_name"},
+		nil,
+	)
+)
+
+// Detector keeps the EWMA and the exponentially weighted variance of the hourly cost of every cluster across scrapes.
+// It's safe for concurrent use.
+type Detector struct {
+	// alpha is the weight of the latest scrape in the averages, between 0 and 1.
+	alpha float64
+
+	m      sync.Mutex
+	series map[string]*ewma
+}
+
+// New returns a Detector weighting the latest scrape with alpha, eg 0.1 for the averages to mostly reflect the last 10
+// scrapes. nil is returned when alpha is 0, which disables the anomaly scores.
+func New(alpha float64) (*Detector, error) {
+	if alpha == 0 {
+		return nil, nil
+	}
+	if alpha < 0 || alpha > 1 {
+		return nil, fmt.Errorf("anomaly alpha must be between 0 and 1, got %v", alpha)
+	}
+	return &Detector{
+		alpha:  alpha,
+		series: make(map[string]*ewma),
+	}, nil
+}
+
+// Scrape returns an accumulator of the cost of the clusters during a single scrape. It returns nil, which is safe to
+// use, when d is nil.
+func (d *Detector) Scrape() *Costs {
+	if d == nil {
+		return nil
+	}
+	return &Costs{detector: d, clusters: make(map[string]float64)}
+}
+
+// Costs accumulates the hourly cost of the instances of every cluster during a scrape.
+type Costs struct {
+	detector *Detector
+
+	m        sync.Mutex
+	clusters map[string]float64
+}
+
+// Add adds the hourly cost of an instance to its cluster.
+func (c *Costs) Add(clusterName string, usdPerHour float64) {
+	if c == nil {
+		return
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.clusters[clusterName] += usdPerHour
+}
+
+// Metrics scores the cost of every cluster seen during the scrape, and their total, and then updates their averages
+// with it. The first scrape of a cluster only initializes its average and scores 0.
+func (c *Costs) Metrics(provider string) []prometheus.Metric {
+	if c == nil {
+		return nil
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if len(c.clusters) == 0 {
+		return nil
+	}
+	d := c.detector
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	names := make([]string, 0, len(c.clusters))
+	total := 0.0
+	for name, cost := range c.clusters {
+		names = append(names, name)
+		total += cost
+	}
+	sort.Strings(names)
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(CostAnomalyScoreDesc, prometheus.GaugeValue, d.observe(provider, "", total), provider, ""),
+	}
+	for _, name := range names {
+		score := d.observe(provider, name, c.clusters[name])
+		metrics = append(metrics, prometheus.MustNewConstMetric(CostAnomalyScoreDesc, prometheus.GaugeValue, score, provider, name))
+	}
+	return metrics
+}
+
+// observe scores cost against the average of a cluster of provider and adds it to the average. d.m must be held.
+func (d *Detector) observe(provider string, cluster string, cost float64) float64 {
+	key := provider + "/" + cluster
+	s, ok := d.series[key]
+	if !ok {
+		d.series[key] = &ewma{mean: cost}
+		return 0
+	}
+	return s.observe(cost, d.alpha)
+}
+
+// ewma is an exponentially weighted moving average and variance, see "Incremental calculation of weighted mean and
+// variance" by Tony Finch.
+type ewma struct {
+	mean     float64
+	variance float64
+}
+
+// observe returns the score of x against the average so far and then adds x to it.
+func (e *ewma) observe(x float64, alpha float64) float64 {
+	deviation := math.Max(math.Sqrt(e.variance), minRelativeDeviation*math.Abs(e.mean))
+	diff := x - e.mean
+	score := 0.0
+	if deviation > 0 {
+		score = diff / deviation
+	}
+	increment := alpha * diff
+	e.mean += increment
+	e.variance = (1 - alpha) * (e.variance + diff*increment)
+	return score
+}
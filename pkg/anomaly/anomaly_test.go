@@ -0,0 +1,55 @@
+package anomaly
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestNew(t *testing.T) {
+	detector, err := New(0)
+	require.NoError(t, err)
+	assert.Nil(t, detector)
+	assert.Nil(t, detector.Scrape().Metrics("aws"), "a disabled detector is safe to use")
+
+	_, err = New(1.5)
+	assert.Error(t, err)
+}
+
+func TestCosts_Metrics(t *testing.T) {
+	detector, err := New(0.5)
+	require.NoError(t, err)
+	scrape := func(costs map[string]float64) map[string]float64 {
+		s := detector.Scrape()
+		for cluster, cost := range costs {
+			s.Add(cluster, cost)
+		}
+		scores := make(map[string]float64)
+		for _, m := range s.Metrics("aws") {
+			result := utils.ReadMetrics(m)
+			require.Equal(t, "cloudcost_exporter_cost_anomaly_score", result.FqName)
+			require.Equal(t, prometheus.GaugeValue, result.MetricType)
+			require.Equal(t, "aws", result.Labels["provider"])
+			scores[result.Labels["cluster_name"]] = result.Value
+		}
+		return scores
+	}
+
+	assert.Equal(t, map[string]float64{"": 0, "prod": 0, "dev": 0}, scrape(map[string]float64{"prod": 10, "dev": 1}), "the first scrape initializes the averages")
+	assert.Equal(t, map[string]float64{"": 0, "prod": 0, "dev": 0}, scrape(map[string]float64{"prod": 10, "dev": 1}))
+
+	// The deviation of a stable cost is floored at 5% of its average, so doubling prod is 20 deviations away
+	scores := scrape(map[string]float64{"prod": 20, "dev": 1})
+	assert.InDelta(t, 20, scores["prod"], 1e-9)
+	assert.InDelta(t, 0, scores["dev"], 1e-9)
+	assert.InDelta(t, 10/(0.05*11), scores[""], 1e-9)
+
+	// The spike is part of the average now, and its variance makes going back less of an anomaly
+	scores = scrape(map[string]float64{"prod": 10, "dev": 1})
+	assert.Less(t, scores["prod"], 0.0)
+	assert.Greater(t, scores["prod"], -20.0)
+}
@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+// accountIDLabel labels the metrics of the collectors of the accounts of AccountRoleARNs.
+const accountIDLabel = "account_id"
+
+var ErrDuplicateAccount = errors.New("several roles of the same account")
+
+// accountServices are the services collected once per account of AccountRoleARNs. The other services are collected
+// with the credentials of the exporter, as they either only export prices or aren't supported across accounts yet.
+var accountServices = map[string]bool{
+	"EC2": true,
+	"EKS": true,
+	"S3":  true,
+}
+
+// account is an account collected with a role assumed from the credentials of the exporter.
+type account struct {
+	id      string
+	roleARN string
+}
+
+// parseAccounts returns the account of every role of roleARNs. Every role has to be in a different account.
+func parseAccounts(roleARNs []string) ([]account, error) {
+	var accounts []account
+	seen := make(map[string]bool)
+	for _, roleARN := range roleARNs {
+		parsed, err := parseRoleARN(roleARN)
+		if err != nil {
+			return nil, err
+		}
+		if seen[parsed.AccountID] {
+			return nil, fmt.Errorf("%w %s", ErrDuplicateAccount, parsed.AccountID)
+		}
+		seen[parsed.AccountID] = true
+		accounts = append(accounts, account{id: parsed.AccountID, roleARN: roleARN})
+	}
+	return accounts, nil
+}
+
+// assumeRoleCredentials returns the credentials of a role assumed with sts:AssumeRole from the credentials of base.
+func assumeRoleCredentials(base aws.Config, roleARN string) aws.CredentialsProvider {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(base), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+	})
+	return aws.NewCredentialsCache(provider)
+}
+
+// accountCollector labels every metric of the collector of an account with its account_id, including the metrics
+// the collector registers itself. Its name is suffixed with the account so that the collectors of the same service
+// in different accounts are told apart in the collector metrics of the provider.
+type accountCollector struct {
+	provider.Collector
+	accountID string
+	label     *dto.LabelPair
+}
+
+func newAccountCollector(collector provider.Collector, accountID string) *accountCollector {
+	name := accountIDLabel
+	return &accountCollector{
+		Collector: collector,
+		accountID: accountID,
+		label:     &dto.LabelPair{Name: &name, Value: &accountID},
+	}
+}
+
+func (c *accountCollector) Name() string {
+	return c.Collector.Name() + "_" + c.accountID
+}
+
+func (c *accountCollector) Register(registry provider.Registry) error {
+	return c.Collector.Register(&accountRegistry{
+		Registry:   registry,
+		registerer: prometheus.WrapRegistererWith(prometheus.Labels{accountIDLabel: c.accountID}, registry),
+	})
+}
+
+func (c *accountCollector) Collect(ch chan<- prometheus.Metric) error {
+	var err error
+	c.forward(ch, func(inner chan<- prometheus.Metric) {
+		err = c.Collector.Collect(inner)
+	})
+	return err
+}
+
+func (c *accountCollector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
+	var up float64
+	c.forward(ch, func(inner chan<- prometheus.Metric) {
+		up = c.Collector.CollectMetrics(inner)
+	})
+	return up
+}
+
+// forward labels the metrics sent by collect with the account before sending them to ch.
+func (c *accountCollector) forward(ch chan<- prometheus.Metric, collect func(chan<- prometheus.Metric)) {
+	inner := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range inner {
+			ch <- &accountMetric{Metric: metric, label: c.label}
+		}
+	}()
+	collect(inner)
+	close(inner)
+	<-done
+}
+
+// accountRegistry registers the metrics of the collector of an account with its account_id as a constant label.
+type accountRegistry struct {
+	provider.Registry
+	registerer prometheus.Registerer
+}
+
+func (r *accountRegistry) Register(c prometheus.Collector) error {
+	return r.registerer.Register(c)
+}
+
+func (r *accountRegistry) MustRegister(cs ...prometheus.Collector) {
+	r.registerer.MustRegister(cs...)
+}
+
+func (r *accountRegistry) Unregister(c prometheus.Collector) bool {
+	return r.registerer.Unregister(c)
+}
+
+// accountMetric adds the account_id label to a metric.
+type accountMetric struct {
+	prometheus.Metric
+	label *dto.LabelPair
+}
+
+func (m *accountMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	out.Label = append(out.Label, m.label)
+	sort.Slice(out.Label, func(i, j int) bool { return out.Label[i].GetName() < out.Label[j].GetName() })
+	return nil
+}
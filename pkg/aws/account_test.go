@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	mock_provider "github.com/grafana/cloudcost-exporter/pkg/provider/mocks"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func Test_parseAccounts(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		roleARNs    []string
+		expected    []account
+		expectedErr error
+	}{
+		{
+			name: "no roles",
+		},
+		{
+			name:     "roles of different accounts",
+			roleARNs: []string{"arn:aws:iam::111111111111:role/cloudcost-exporter", "arn:aws:iam::222222222222:role/cloudcost-exporter"},
+			expected: []account{
+				{id: "111111111111", roleARN: "arn:aws:iam::111111111111:role/cloudcost-exporter"},
+				{id: "222222222222", roleARN: "arn:aws:iam::222222222222:role/cloudcost-exporter"},
+			},
+		},
+		{
+			name:        "not a role",
+			roleARNs:    []string{"arn:aws:iam::111111111111:user/cloudcost-exporter"},
+			expectedErr: ErrInvalidRoleARN,
+		},
+		{
+			name:        "roles of the same account",
+			roleARNs:    []string{"arn:aws:iam::111111111111:role/a", "arn:aws:iam::111111111111:role/b"},
+			expectedErr: ErrDuplicateAccount,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			accounts, err := parseAccounts(tc.roleARNs)
+			require.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, accounts)
+		})
+	}
+}
+
+func Test_accountCollector(t *testing.T) {
+	desc := prometheus.NewDesc("cloudcost_test_usd_per_hour", "Test cost.", []string{"region"}, nil)
+	ctrl := gomock.NewController(t)
+	newCollector := func() provider.Collector {
+		c := mock_provider.NewMockCollector(ctrl)
+		c.EXPECT().Name().Return("EKS").AnyTimes()
+		c.EXPECT().Register(gomock.Any()).DoAndReturn(func(r provider.Registry) error {
+			gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "cloudcost_exporter_test_next_scrape"})
+			gauge.Set(1)
+			return r.Register(gauge)
+		})
+		c.EXPECT().Collect(gomock.Any()).DoAndReturn(func(ch chan<- prometheus.Metric) error {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 0.5, "us-east-1")
+			return nil
+		})
+		return c
+	}
+	registry := prometheus.NewRegistry()
+	collectors := []*accountCollector{
+		newAccountCollector(newCollector(), "111111111111"),
+		newAccountCollector(newCollector(), "222222222222"),
+	}
+
+	ch := make(chan prometheus.Metric, 2)
+	for _, c := range collectors {
+		// The collectors of every account register the same metrics
+		require.NoError(t, c.Register(registry))
+		require.NoError(t, c.Collect(ch))
+	}
+	close(ch)
+
+	assert.Equal(t, "EKS_111111111111", collectors[0].Name())
+	var labels []utils.LabelMap
+	for metric := range ch {
+		labels = append(labels, utils.ReadMetrics(metric).Labels)
+	}
+	assert.Equal(t, []utils.LabelMap{
+		{"region": "us-east-1", "account_id": "111111111111"},
+		{"region": "us-east-1", "account_id": "222222222222"},
+	}, labels)
+	count, err := testutil.GatherAndCount(registry, "cloudcost_exporter_test_next_scrape")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,172 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+)
+
+const (
+	// AuthDefault resolves credentials through the default chain of the SDK: environment variables, shared
+	// credentials and config files, web identity from the environment, ECS and EC2 instance roles.
+	AuthDefault = "default"
+	// AuthWebIdentity assumes a role with a web identity token, eg IAM Roles for Service Accounts (IRSA) on EKS.
+	AuthWebIdentity = "web-identity"
+	// AuthVault reads static or generated access keys from a Vault secret, eg of the KV or AWS secrets engines, and
+	// reads them again every refresh interval so that rotated keys are picked up.
+	AuthVault = "vault"
+
+	// roleARNEnv and webIdentityTokenFileEnv are injected by the EKS pod identity webhook into pods whose service
+	// account is annotated with eks.amazonaws.com/role-arn.
+	roleARNEnv              = "AWS_ROLE_ARN"
+	webIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	sessionName             = "cloudcost-exporter"
+)
+
+var (
+	ErrUnknownAuth           = errors.New("unknown aws auth")
+	ErrMissingRoleARN        = errors.New("web identity auth requires a role ARN, set -aws.role-arn or annotate the service account with eks.amazonaws.com/role-arn")
+	ErrInvalidRoleARN        = errors.New("invalid role ARN")
+	ErrMissingIdentityToken  = errors.New("web identity auth requires a token file, set -aws.web-identity-token-file or check that the EKS pod identity webhook mutated the pod")
+	ErrUnreadableTokenFile   = errors.New("web identity token file is not readable")
+	ErrIncompatibleAuthFlags = errors.New("role ARN and web identity token file are only used with web identity auth")
+	ErrMissingVaultPath      = errors.New("vault auth requires the path of the secret, set -aws.vault-path")
+	ErrIncompatibleVaultPath = errors.New("the vault path is only used with vault auth")
+	ErrInvalidVaultSecret    = errors.New("vault secret must have an access_key and a secret_key")
+)
+
+// AuthConfig selects how the AWS clients authenticate. The default chain of the SDK silently falls through to the
+// next provider when one isn't configured, which makes a missing IRSA setup surface as a confusing permissions error
+// from the instance role of the node, so web identity can be required explicitly instead.
+type AuthConfig struct {
+	// Mode is AuthDefault, AuthWebIdentity or AuthVault. An empty Mode is AuthDefault.
+	Mode string
+	// RoleARN is the role assumed with web identity. It defaults to AWS_ROLE_ARN.
+	RoleARN string
+	// WebIdentityTokenFile is the path of the token exchanged for credentials of RoleARN. It defaults to
+	// AWS_WEB_IDENTITY_TOKEN_FILE.
+	WebIdentityTokenFile string
+	// VaultPath is the path of the secret read with AuthVault, eg aws/creds/cloudcost-exporter. The secret has the
+	// access_key, secret_key and optionally security_token keys of the AWS secrets engine.
+	VaultPath string
+	// Vault is how to reach Vault with AuthVault.
+	Vault secrets.VaultConfig
+}
+
+// resolve validates the configuration and fills in the defaults from the environment.
+func (a AuthConfig) resolve() (AuthConfig, error) {
+	switch a.Mode {
+	case "", AuthDefault, AuthVault:
+		if a.RoleARN != "" || a.WebIdentityTokenFile != "" {
+			return a, ErrIncompatibleAuthFlags
+		}
+		if a.Mode == AuthVault {
+			if a.VaultPath == "" {
+				return a, ErrMissingVaultPath
+			}
+			return a, nil
+		}
+		if a.VaultPath != "" {
+			return a, ErrIncompatibleVaultPath
+		}
+		a.Mode = AuthDefault
+		return a, nil
+	case AuthWebIdentity:
+		if a.VaultPath != "" {
+			return a, ErrIncompatibleVaultPath
+		}
+	default:
+		return a, fmt.Errorf("%w %q, expected %s, %s or %s", ErrUnknownAuth, a.Mode, AuthDefault, AuthWebIdentity, AuthVault)
+	}
+	if a.RoleARN == "" {
+		a.RoleARN = os.Getenv(roleARNEnv)
+	}
+	if a.RoleARN == "" {
+		return a, ErrMissingRoleARN
+	}
+	if _, err := parseRoleARN(a.RoleARN); err != nil {
+		return a, err
+	}
+	if a.WebIdentityTokenFile == "" {
+		a.WebIdentityTokenFile = os.Getenv(webIdentityTokenFileEnv)
+	}
+	if a.WebIdentityTokenFile == "" {
+		return a, ErrMissingIdentityToken
+	}
+	// The token is only read when credentials are retrieved, so it's checked upfront to fail at startup
+	f, err := os.Open(a.WebIdentityTokenFile)
+	if err != nil {
+		return a, fmt.Errorf("%w: %w", ErrUnreadableTokenFile, err)
+	}
+	_ = f.Close()
+	return a, nil
+}
+
+// parseRoleARN parses the ARN of an IAM role.
+func parseRoleARN(roleARN string) (arn.ARN, error) {
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return parsed, fmt.Errorf("%w %q: %w", ErrInvalidRoleARN, roleARN, err)
+	}
+	if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return parsed, fmt.Errorf("%w %q: not an IAM role", ErrInvalidRoleARN, roleARN)
+	}
+	return parsed, nil
+}
+
+// credentialsProvider returns the provider of the credentials of every client, or nil to use the default chain of
+// the SDK. base is the configuration the STS client is created from. The Vault secret is read right away so that a
+// missing or malformed secret fails at startup.
+func credentialsProvider(ctx context.Context, auth AuthConfig, base aws.Config) (aws.CredentialsProvider, error) {
+	switch auth.Mode {
+	case AuthWebIdentity:
+		provider := stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(base), auth.RoleARN, stscreds.IdentityTokenFile(auth.WebIdentityTokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = sessionName
+		})
+		return aws.NewCredentialsCache(provider), nil
+	case AuthVault:
+		secret, err := auth.Vault.Rotating(ctx, auth.VaultPath)
+		if err != nil {
+			return nil, err
+		}
+		provider := &vaultCredentials{secret: secret, now: time.Now}
+		if _, err := provider.Retrieve(ctx); err != nil {
+			return nil, err
+		}
+		return aws.NewCredentialsCache(provider), nil
+	}
+	return nil, nil
+}
+
+// vaultCredentials provides the access keys of a Vault secret. They expire after the refresh interval of the secret
+// so that the credentials cache of the SDK retrieves the rotated keys.
+type vaultCredentials struct {
+	secret *secrets.Rotating
+	now    func() time.Time
+}
+
+// Retrieve satisfies aws.CredentialsProvider.
+func (v *vaultCredentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	secret, _ := v.secret.Get(ctx)
+	if secret["access_key"] == "" || secret["secret_key"] == "" {
+		return aws.Credentials{}, ErrInvalidVaultSecret
+	}
+	return aws.Credentials{
+		AccessKeyID:     secret["access_key"],
+		SecretAccessKey: secret["secret_key"],
+		SessionToken:    secret["security_token"],
+		Source:          "Vault",
+		CanExpire:       true,
+		Expires:         v.now().Add(v.secret.Interval()),
+	}, nil
+}
@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+)
+
+func TestAuthConfig_resolve(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+	roleARN := "arn:aws:iam::123456789012:role/cloudcost-exporter"
+
+	tests := map[string]struct {
+		auth    AuthConfig
+		env     map[string]string
+		want    AuthConfig
+		wantErr error
+	}{
+		"empty mode is the default chain": {
+			auth: AuthConfig{},
+			want: AuthConfig{Mode: AuthDefault},
+		},
+		"default chain ignores the irsa environment": {
+			auth: AuthConfig{Mode: AuthDefault},
+			env:  map[string]string{roleARNEnv: roleARN},
+			want: AuthConfig{Mode: AuthDefault},
+		},
+		"role ARN without web identity": {
+			auth:    AuthConfig{RoleARN: roleARN},
+			wantErr: ErrIncompatibleAuthFlags,
+		},
+		"unknown mode": {
+			auth:    AuthConfig{Mode: "irsa"},
+			wantErr: ErrUnknownAuth,
+		},
+		"web identity from flags": {
+			auth: AuthConfig{Mode: AuthWebIdentity, RoleARN: roleARN, WebIdentityTokenFile: tokenFile},
+			want: AuthConfig{Mode: AuthWebIdentity, RoleARN: roleARN, WebIdentityTokenFile: tokenFile},
+		},
+		"web identity from the pod identity webhook": {
+			auth: AuthConfig{Mode: AuthWebIdentity},
+			env:  map[string]string{roleARNEnv: roleARN, webIdentityTokenFileEnv: tokenFile},
+			want: AuthConfig{Mode: AuthWebIdentity, RoleARN: roleARN, WebIdentityTokenFile: tokenFile},
+		},
+		"missing role ARN": {
+			auth:    AuthConfig{Mode: AuthWebIdentity, WebIdentityTokenFile: tokenFile},
+			wantErr: ErrMissingRoleARN,
+		},
+		"not a role ARN": {
+			auth:    AuthConfig{Mode: AuthWebIdentity, RoleARN: "arn:aws:iam::123456789012:user/someone", WebIdentityTokenFile: tokenFile},
+			wantErr: ErrInvalidRoleARN,
+		},
+		"malformed role ARN": {
+			auth:    AuthConfig{Mode: AuthWebIdentity, RoleARN: "cloudcost-exporter", WebIdentityTokenFile: tokenFile},
+			wantErr: ErrInvalidRoleARN,
+		},
+		"missing token file": {
+			auth:    AuthConfig{Mode: AuthWebIdentity, RoleARN: roleARN},
+			wantErr: ErrMissingIdentityToken,
+		},
+		"vault": {
+			auth: AuthConfig{Mode: AuthVault, VaultPath: "aws/creds/cloudcost-exporter"},
+			want: AuthConfig{Mode: AuthVault, VaultPath: "aws/creds/cloudcost-exporter"},
+		},
+		"vault without a path": {
+			auth:    AuthConfig{Mode: AuthVault},
+			wantErr: ErrMissingVaultPath,
+		},
+		"vault path without vault": {
+			auth:    AuthConfig{VaultPath: "aws/creds/cloudcost-exporter"},
+			wantErr: ErrIncompatibleVaultPath,
+		},
+		"role ARN with vault": {
+			auth:    AuthConfig{Mode: AuthVault, VaultPath: "aws/creds/cloudcost-exporter", RoleARN: roleARN},
+			wantErr: ErrIncompatibleAuthFlags,
+		},
+		"unreadable token file": {
+			auth:    AuthConfig{Mode: AuthWebIdentity, RoleARN: roleARN, WebIdentityTokenFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: ErrUnreadableTokenFile,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(roleARNEnv, tt.env[roleARNEnv])
+			t.Setenv(webIdentityTokenFileEnv, tt.env[webIdentityTokenFileEnv])
+			got, err := tt.auth.resolve()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_credentialsProviderVault(t *testing.T) {
+	body := `{"data": {"access_key": "AKIA", "secret_key": "secret", "security_token": null}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/aws/creds/cloudcost-exporter", r.URL.Path)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_TOKEN", "token")
+	auth := AuthConfig{
+		Mode:      AuthVault,
+		VaultPath: "aws/creds/cloudcost-exporter",
+		Vault:     secrets.VaultConfig{Address: server.URL, RefreshInterval: time.Hour},
+	}
+
+	provider, err := credentialsProvider(context.Background(), auth, aws.Config{})
+	require.NoError(t, err)
+	credentials, err := provider.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "AKIA", credentials.AccessKeyID)
+	assert.Equal(t, "secret", credentials.SecretAccessKey)
+	assert.Empty(t, credentials.SessionToken)
+	assert.True(t, credentials.CanExpire)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), credentials.Expires, time.Minute)
+
+	body = `{"data": {"username": "someone"}}`
+	_, err = credentialsProvider(context.Background(), auth, aws.Config{})
+	assert.ErrorIs(t, err, ErrInvalidVaultSecret)
+}
@@ -2,39 +2,148 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsEks "github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/prometheus/client_golang/prometheus"
 
 	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/anomaly"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
 	ec2Collector "github.com/grafana/cloudcost-exporter/pkg/aws/compute/ec2"
 	"github.com/grafana/cloudcost-exporter/pkg/aws/compute/eks"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/elb"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/fargate"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/linkedaccounts"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/messaging"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/observability"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/publicip"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/rds"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/reservedinstances"
 	"github.com/grafana/cloudcost-exporter/pkg/aws/s3"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
 	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	ecsclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/ecs"
+	eksclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/eks"
+	elbclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/elb"
+	pricingclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	rdsclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/rds"
+	s3client "github.com/grafana/cloudcost-exporter/pkg/aws/services/s3"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/commitment"
+	"github.com/grafana/cloudcost-exporter/pkg/egress"
+	"github.com/grafana/cloudcost-exporter/pkg/eviction"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricesource"
+	pricelookup "github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/schedule"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 type Config struct {
-	Services       []string
-	Region         string
+	Services []string
+	Region   string
+	// IMDS configures the discovery of the region through the EC2 instance metadata service when Region isn't set.
+	IMDS           IMDSConfig
 	Profile        string
 	ScrapeInterval time.Duration
-	Logger         *slog.Logger
+	// ScrapeIntervals overrides ScrapeInterval per service, keyed by the lowercased service name.
+	ScrapeIntervals map[string]time.Duration
+	Logger          *slog.Logger
+	// EKSMetadata enables calls to eks:DescribeCluster and eks:DescribeNodegroup to label EKS instance metrics
+	// with the Kubernetes version and the declared capacity type of the nodegroup.
+	EKSMetadata bool
+	// IdleCost enables calls to cloudwatch:GetMetricData to export the idle cost of EKS instances based upon their
+	// average CPU utilization over the last hour.
+	IdleCost bool
+	// CloudWatchLogGroups enables calls to cloudwatch:GetMetricData to export the ingestion of every log group and its
+	// cost in the observability collector.
+	CloudWatchLogGroups bool
+	// S3BucketCosts enables calls to ce:GetCostAndUsageWithResources to export the cost of every S3 bucket. It requires
+	// the resource-level data of S3 to be enabled in Cost Explorer.
+	S3BucketCosts bool
+	// S3BucketInventory enables calls to s3:ListAllMyBuckets and s3:GetBucketLocation to export the buckets of the
+	// account, and S3BucketStorageMetrics calls to cloudwatch:GetMetricData to export their size by storage class.
+	S3BucketInventory      bool
+	S3BucketStorageMetrics bool
+	// ELBTags are the tags of the load balancers exported as labels by the elb collector, see elb.TagLabel.
+	ELBTags []string
+	// ClusterNames normalizes the cluster_name label of the EKS and ELB metrics.
+	ClusterNames *clustername.Normalizer
+	// Nodes enables the allocatable cost metrics of the nodes of the cluster the exporter runs in.
+	Nodes kubernetes.NodeLister
+	// Pods enables the namespace cost metrics of the cluster the exporter runs in.
+	Pods kubernetes.PodLister
+	// Calendar enables the actual and expected cost metrics of the EKS clusters with a scale down schedule.
+	Calendar *schedule.Calendar
+	// Anomalies enables the anomaly scores of the cost of the EKS clusters.
+	Anomalies *anomaly.Detector
+	// Evictions enables the eviction counters of the spot instances of the EKS clusters.
+	Evictions *eviction.Watcher
+	// Recommendations enables the commitment recommendations of the on-demand cores of the EKS clusters.
+	Recommendations *commitment.Recommender
+	// Prices overrides or discounts the on-demand prices of the instances of the EKS clusters.
+	Prices pricesource.Layers
+	// InstanceFilter selects the instances priced by the EKS collector. Every instance is listed when nil.
+	InstanceFilter *compute.InstanceFilter
+	// StorageClasses prices the storage classes of the EKS clusters. No classes are priced when nil.
+	StorageClasses *storageclass.Classes
+	// PriceHistory records the pricing maps of the EKS collector, they aren't recorded when nil.
+	PriceHistory *pricehistory.History
+	// PriceLookup is set the pricing maps of the EKS collector, so the prices of instances can be looked up when the
+	// exporter is embedded. It's left alone when nil.
+	PriceLookup *pricelookup.Lookup
+	// HTTPClient sends the requests of every AWS client, eg through an egress proxy. The SDK default is used when nil.
+	HTTPClient *http.Client
+	// Endpoints overrides the endpoints of the ec2, pricing, costexplorer, eks, cloudwatch, ecs, rds, elb and s3 clients, and
+	// the host of the offer files, eg with PrivateLink endpoints.
+	Endpoints egress.Endpoints
+	// PricingSource selects where the collectors list the prices of the products, see PricingSourceAPI and
+	// PricingSourceOfferFiles. The pricing API is used when it's empty.
+	PricingSource string
+	// SpotAggregation is how the spot prices of the availability zones of a region are aggregated into the spot price
+	// of the region, compute.SpotAggregationMin or compute.SpotAggregationAvg. The minimum is used when it's empty.
+	SpotAggregation string
+	// Auth selects how the clients authenticate, the default credential chain of the SDK is used when it's empty.
+	Auth AuthConfig
+	// AccountRoleARNs are roles assumed from the credentials of Auth, each in a different account. The EC2, EKS and S3
+	// collectors are created once per role and label their metrics with the account_id of the role, instead of
+	// collecting the account of the exporter. The other services are collected with the credentials of Auth.
+	AccountRoleARNs []string
+	// Regions selects the regions collected by the EKS and EC2 collectors among the regions enabled for the account,
+	// and the regions whose prices are exported by the messaging and observability collectors. Every region is collected when nil.
+	Regions *compute.RegionFilter
+	// InstanceFamilies selects the instance families whose prices are fetched by the EKS and EC2 collectors, every
+	// family is priced when nil.
+	InstanceFamilies *pricelookup.FamilyFilter
 }
 
 type AWS struct {
-	Config     *Config
+	Config *Config
+	// context is cancelled when the exporter shuts down, which stops the collections.
+	context    context.Context
 	collectors []provider.Collector
+	summary    provider.CollectionSummary
+	watchdog   provider.Watchdog
+	inFlight   provider.InFlight
 }
 
 var (
@@ -99,87 +208,457 @@ var (
 const (
 	subsystem        = "aws"
 	maxRetryAttempts = 10
+
+	// PricingSourceAPI lists the prices with the GetProducts API of the pricing service.
+	PricingSourceAPI = "api"
+	// PricingSourceOfferFiles lists the prices from the bulk offer files of the AWS Price List, which are fetched in a
+	// single request per region, aren't throttled and aren't billed.
+	PricingSourceOfferFiles = "offer-files"
 )
 
+var ErrUnknownPricingSource = errors.New("unknown pricing source")
+
 func New(ctx context.Context, config *Config) (*AWS, error) {
 	var collectors []provider.Collector
 	logger := config.Logger.With("provider", "aws")
+	auth, err := config.Auth.resolve()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Regions.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.InstanceFamilies.Validate(); err != nil {
+		return nil, err
+	}
+	accounts, err := parseAccounts(config.AccountRoleARNs)
+	if err != nil {
+		return nil, err
+	}
+	switch config.PricingSource {
+	case "", PricingSourceAPI, PricingSourceOfferFiles:
+	default:
+		return nil, fmt.Errorf("%w %q, must be %s or %s", ErrUnknownPricingSource, config.PricingSource, PricingSourceAPI, PricingSourceOfferFiles)
+	}
+	if err := compute.ValidateSpotAggregation(config.SpotAggregation); err != nil {
+		return nil, err
+	}
 	// There are two scenarios:
 	// 1. Running locally, the user must pass in a region and profile to use
 	// 2. Running within an EC2 instance and the region and profile can be derived
 	// I'm going to use the AWS SDK to handle this for me. If the user has provided a region and profile, it will use that.
-	// If not, it will use the EC2 instance metadata service to determine the region and credentials.
-	// This is the same logic that the AWS CLI uses, so it should be fine.
-	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithEC2IMDSRegion()}
-	if config.Region != "" {
-		options = append(options, awsconfig.WithRegion(config.Region))
-	}
+	// If not, it will use the EC2 instance metadata service to determine the region and credentials, unless it's
+	// disabled, eg outside EC2. This is the same logic that the AWS CLI uses, so it should be fine.
+	options := config.IMDS.regionOptions(config.Region)
 	if config.Profile != "" {
 		options = append(options, awsconfig.WithSharedConfigProfile(config.Profile))
 	}
 	options = append(options, awsconfig.WithRetryMaxAttempts(maxRetryAttempts))
+	options = append(options, awsconfig.WithAPIOptions([]func(*middleware.Stack) error{addSelfCostMiddleware}))
+	if config.HTTPClient != nil {
+		options = append(options, awsconfig.WithHTTPClient(config.HTTPClient))
+	}
 	ac, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		if config.Region == "" && !config.IMDS.Disabled {
+			// The metadata service is the only source of the region left, which fails outside EC2
+			return nil, fmt.Errorf("error loading the AWS config, set -aws.region or -aws.disable-imds outside EC2: %w", err)
+		}
+		return nil, err
+	}
+	if ac.Region == "" {
+		return nil, ErrMissingRegion
+	}
+	credentials, err := credentialsProvider(ctx, auth, ac)
 	if err != nil {
 		return nil, err
 	}
+	if credentials != nil {
+		ac.Credentials = credentials
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "authenticating", slog.String("auth", auth.Mode), slog.String("role_arn", auth.RoleARN))
 	for _, service := range config.Services {
-		switch strings.ToUpper(service) {
-		case "S3":
-			client := costexplorer.NewFromConfig(ac)
-			collector := s3.New(config.ScrapeInterval, client)
-			collectors = append(collectors, collector)
-		case "EKS":
-			pricingService := pricing.NewFromConfig(ac)
-			computeService := ec2.NewFromConfig(ac)
-			regions, err := computeService.DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(false)})
+		scrapeInterval := utils.ScrapeIntervalFor(config.ScrapeIntervals, service, config.ScrapeInterval)
+		if len(accounts) == 0 || !accountServices[strings.ToUpper(service)] {
+			collector, err := newCollector(ctx, service, scrapeInterval, config, ac, credentials, logger)
+			if err != nil {
+				return nil, err
+			}
+			if collector != nil {
+				collectors = append(collectors, collector)
+			}
+			continue
+		}
+		for _, account := range accounts {
+			accountCredentials := assumeRoleCredentials(ac, account.roleARN)
+			accountConfig := ac.Copy()
+			accountConfig.Credentials = accountCredentials
+			collector, err := newCollector(ctx, service, scrapeInterval, config, accountConfig, accountCredentials, logger.With("account_id", account.id))
+			if err != nil {
+				return nil, fmt.Errorf("error creating the %s collector of account %s: %w", service, account.id, err)
+			}
+			if collector != nil {
+				collectors = append(collectors, newAccountCollector(collector, account.id))
+			}
+		}
+	}
+	return &AWS{
+		Config:     config,
+		context:    ctx,
+		collectors: collectors,
+	}, nil
+}
+
+// collectorService creates the collector of a service, both to collect its costs and to describe its metrics.
+// Registering the service once keeps the documented metrics in line with the services the exporter accepts.
+type collectorService struct {
+	// new creates the collector with the config and credentials of an account.
+	new func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error)
+	// forDocs creates the collector without any AWS clients, see NewForDocs.
+	forDocs func(ctx context.Context, logger *slog.Logger) provider.Collector
+}
+
+// collectorServices are the services the exporter accepts, keyed by their uppercased name.
+var collectorServices = map[string]collectorService{
+	"S3": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			client := costexplorer.NewFromConfig(ac, func(o *costexplorer.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "costexplorer", ac.Region)
+			})
+			collector := s3.New(scrapeInterval, client, config.S3BucketCosts, newS3Inventory(ac, config, credentials))
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return s3.New(0, nil, false, nil)
+		},
+	},
+	"LINKEDACCOUNTS": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			// Only the payer account of an organization using consolidated billing sees the costs of its linked accounts
+			client := costexplorer.NewFromConfig(ac, func(o *costexplorer.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "costexplorer", ac.Region)
+			})
+			collector := linkedaccounts.New(scrapeInterval, client)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return linkedaccounts.New(0, nil)
+		},
+	},
+	"MESSAGING": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			collector := messaging.New(scrapeInterval, pricingService, config.Regions)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return messaging.New(0, nil, nil)
+		},
+	},
+	"OBSERVABILITY": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			var cloudwatchRegionClientMap map[string]cloudwatchclient.CloudWatch
+			if config.CloudWatchLogGroups {
+				computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+					o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+				})
+				regions, err := compute.ListRegions(ctx, computeService, config.Regions)
+				if err != nil {
+					return nil, fmt.Errorf("error getting regions: %w", err)
+				}
+				cloudwatchRegionClientMap = make(map[string]cloudwatchclient.CloudWatch)
+				for _, r := range regions {
+					client, err := newCloudWatchClient(*r.RegionName, config, credentials)
+					if err != nil {
+						return nil, fmt.Errorf("error creating cloudwatch client: %w", err)
+					}
+					cloudwatchRegionClientMap[*r.RegionName] = client
+				}
+			}
+			collector := observability.New(&observability.Config{
+				ScrapeInterval:          scrapeInterval,
+				Regions:                 config.Regions,
+				CloudWatchRegionClients: cloudwatchRegionClientMap,
+			}, pricingService)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return observability.New(&observability.Config{}, nil)
+		},
+	},
+	"FARGATE": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
+			if err != nil {
+				return nil, fmt.Errorf("error getting regions: %w", err)
+			}
+			regionClientMap := make(map[string]ecsclient.ECS)
+			for _, r := range regions {
+				client, err := newEcsClient(*r.RegionName, config, credentials)
+				if err != nil {
+					return nil, fmt.Errorf("error creating ecs client: %w", err)
+				}
+				regionClientMap[*r.RegionName] = client
+			}
+			collector := fargate.New(scrapeInterval, pricingService, regionClientMap, config.Regions)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return fargate.New(0, nil, nil, nil)
+		},
+	},
+	"RDS": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
+			if err != nil {
+				return nil, fmt.Errorf("error getting regions: %w", err)
+			}
+			regionClientMap := make(map[string]rdsclient.RDS)
+			for _, r := range regions {
+				client, err := newRdsClient(*r.RegionName, config, credentials)
+				if err != nil {
+					return nil, fmt.Errorf("error creating rds client: %w", err)
+				}
+				regionClientMap[*r.RegionName] = client
+			}
+			collector := rds.New(scrapeInterval, pricingService, regionClientMap, config.Regions)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return rds.New(0, nil, nil, nil)
+		},
+	},
+	"ELB": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
+			if err != nil {
+				return nil, fmt.Errorf("error getting regions: %w", err)
+			}
+			regionClientMap := make(map[string]elbclient.ELB)
+			for _, r := range regions {
+				client, err := newElbClient(*r.RegionName, config, credentials)
+				if err != nil {
+					return nil, fmt.Errorf("error creating elb client: %w", err)
+				}
+				regionClientMap[*r.RegionName] = client
+			}
+			collector := elb.New(&elb.Config{
+				ScrapeInterval: scrapeInterval,
+				Regions:        config.Regions,
+				Tags:           config.ELBTags,
+				ClusterNames:   config.ClusterNames,
+			}, pricingService, regionClientMap)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return elb.New(&elb.Config{}, nil, nil)
+		},
+	},
+	"PUBLICIPV4": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
+			if err != nil {
+				return nil, fmt.Errorf("error getting regions: %w", err)
+			}
+			regionClientMap := make(map[string]ec2client.EC2)
+			for _, r := range regions {
+				client, err := newEc2Client(*r.RegionName, config, credentials)
+				if err != nil {
+					return nil, fmt.Errorf("error creating ec2 client: %w", err)
+				}
+				regionClientMap[*r.RegionName] = client
+			}
+			collector := publicip.New(regionClientMap)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return publicip.New(nil)
+		},
+	},
+	"RESERVEDINSTANCES": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
 			if err != nil {
 				return nil, fmt.Errorf("error getting regions: %w", err)
 			}
 			regionClientMap := make(map[string]ec2client.EC2)
-			for _, r := range regions.Regions {
-				client, err := newEc2Client(*r.RegionName, config.Profile)
+			for _, r := range regions {
+				client, err := newEc2Client(*r.RegionName, config, credentials)
 				if err != nil {
 					return nil, fmt.Errorf("error creating ec2 client: %w", err)
 				}
 				regionClientMap[*r.RegionName] = client
 			}
-			collector := eks.New(config.Region, config.Profile, config.ScrapeInterval, pricingService, computeService, regions.Regions, regionClientMap)
-			collectors = append(collectors, collector)
-		case "EC2":
-			pricingService := pricing.NewFromConfig(ac)
-			computeService := ec2.NewFromConfig(ac)
-			regions, err := computeService.DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(false)})
+			collector := reservedinstances.New(regionClientMap)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return reservedinstances.New(nil)
+		},
+	},
+	"EKS": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
+			if err != nil {
+				return nil, fmt.Errorf("error getting regions: %w", err)
+			}
+			regionClientMap := make(map[string]ec2client.EC2)
+			var eksRegionClientMap map[string]eksclient.EKS
+			if config.EKSMetadata {
+				eksRegionClientMap = make(map[string]eksclient.EKS)
+			}
+			var cloudwatchRegionClientMap map[string]cloudwatchclient.CloudWatch
+			if config.IdleCost {
+				cloudwatchRegionClientMap = make(map[string]cloudwatchclient.CloudWatch)
+			}
+			newClients := func(region string) (eks.RegionClients, error) {
+				return newRegionClients(region, config, credentials)
+			}
+			for _, r := range regions {
+				clients, err := newClients(*r.RegionName)
+				if err != nil {
+					return nil, err
+				}
+				regionClientMap[*r.RegionName] = clients.EC2
+				if eksRegionClientMap != nil {
+					eksRegionClientMap[*r.RegionName] = clients.EKS
+				}
+				if cloudwatchRegionClientMap != nil {
+					cloudwatchRegionClientMap[*r.RegionName] = clients.CloudWatch
+				}
+			}
+			regionDiscovery := &eks.RegionDiscovery{Filter: config.Regions, NewClients: newClients}
+			collector := eks.New(&eks.Config{
+				Region:                  config.Region,
+				Profile:                 config.Profile,
+				ScrapeInterval:          scrapeInterval,
+				Regions:                 regions,
+				EKSRegionClients:        eksRegionClientMap,
+				CloudWatchRegionClients: cloudwatchRegionClientMap,
+				ClusterNames:            config.ClusterNames,
+				Nodes:                   config.Nodes,
+				Pods:                    config.Pods,
+				Calendar:                config.Calendar,
+				Anomalies:               config.Anomalies,
+				Evictions:               config.Evictions,
+				Recommendations:         config.Recommendations,
+				Prices:                  config.Prices,
+				InstanceFilter:          config.InstanceFilter,
+				PriceHistory:            config.PriceHistory,
+				PriceLookup:             config.PriceLookup,
+				RegionDiscovery:         regionDiscovery,
+				StorageClasses:          config.StorageClasses,
+				Families:                config.InstanceFamilies,
+				SpotAggregation:         config.SpotAggregation,
+			}, pricingService, computeService, regionClientMap)
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return eks.New(&eks.Config{}, nil, nil, nil)
+		},
+	},
+	"EC2": {
+		new: func(ctx context.Context, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+			pricingService := newPricingClient(ac, config)
+			computeService := ec2.NewFromConfig(ac, func(o *ec2.Options) {
+				o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", ac.Region)
+			})
+			regions, err := compute.ListRegions(ctx, computeService, config.Regions)
 			if err != nil {
 				return nil, fmt.Errorf("error getting regions: %w", err)
 			}
 			regionClientMap := make(map[string]ec2client.EC2)
-			for _, r := range regions.Regions {
-				client, err := newEc2Client(*r.RegionName, config.Profile)
+			for _, r := range regions {
+				client, err := newEc2Client(*r.RegionName, config, credentials)
 				if err != nil {
 					return nil, fmt.Errorf("error creating ec2 client: %w", err)
 				}
 				regionClientMap[*r.RegionName] = client
 			}
 			collector := ec2Collector.New(ctx, &ec2Collector.Config{
-				Regions: regions.Regions,
-				Logger:  logger,
+				Regions:         regions,
+				Logger:          logger,
+				ScrapeInterval:  scrapeInterval,
+				RegionFilter:    config.Regions,
+				Families:        config.InstanceFamilies,
+				SpotAggregation: config.SpotAggregation,
+				NewClient: func(region string) (ec2client.EC2, error) {
+					return newEc2Client(region, config, credentials)
+				},
 			}, pricingService, computeService, regionClientMap)
-			collectors = append(collectors, collector)
-		default:
-			log.Printf("Unknown service %s", service)
-			continue
-		}
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return ec2Collector.New(ctx, &ec2Collector.Config{Logger: logger}, nil, nil, nil)
+		},
+	},
+}
+
+// newCollector creates the collector of a service with the config and credentials of an account. It returns nil for
+// an unknown service.
+func newCollector(ctx context.Context, name string, scrapeInterval time.Duration, config *Config, ac aws.Config, credentials aws.CredentialsProvider, logger *slog.Logger) (provider.Collector, error) {
+	svc, ok := collectorServices[strings.ToUpper(name)]
+	if !ok {
+		log.Printf("Unknown service %s", name)
+		return nil, nil
+	}
+	return svc.new(ctx, scrapeInterval, config, ac, credentials, logger)
+}
+
+// NewForDocs returns an AWS provider with the collector of every service but without any AWS clients. It can't
+// collect anything and is only meant to describe the metrics the exporter exposes, eg for
+// `cloudcost-exporter docs metrics`.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *AWS {
+	names := make([]string, 0, len(collectorServices))
+	for name := range collectorServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]provider.Collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, collectorServices[name].forDocs(ctx, logger))
 	}
 	return &AWS{
-		Config:     config,
+		Config:     &Config{Logger: logger},
+		context:    ctx,
 		collectors: collectors,
-	}, nil
+	}
 }
 
 func (a *AWS) RegisterCollectors(registry provider.Registry) error {
 	log.Printf("Registering %d collectors for AWS", len(a.collectors))
 	registry.MustRegister(
 		collectorScrapesTotalCounter,
+		provider.SelfCostTotal,
+		provider.ThrottledRefreshesTotal,
+		provider.EmptyCollectionsTotal,
+		provider.SeriesEmittedTotal,
+		compute.UnpricedResourcesTotal,
+		compute.MalformedPriceEntriesTotal,
+		compute.PricingRegionErrorsTotal,
 	)
 	for _, c := range a.collectors {
 		if err := c.Register(registry); err != nil {
@@ -197,6 +676,7 @@ func (a *AWS) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collectorLastScrapeTime
 	ch <- providerLastScrapeTime
 	ch <- collectorSuccessDesc
+	ch <- provider.CollectorUpDesc
 	for _, c := range a.collectors {
 		if err := c.Describe(ch); err != nil {
 			log.Printf("Error describing collector %s: %s", c.Name(), err)
@@ -205,7 +685,16 @@ func (a *AWS) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (a *AWS) Collect(ch chan<- prometheus.Metric) {
+	defer a.inFlight.Start()()
 	start := time.Now()
+	var logger *slog.Logger
+	if a.Config != nil {
+		logger = a.Config.Logger
+	}
+	ctx := a.context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	wg := &sync.WaitGroup{}
 	wg.Add(len(a.collectors))
 	for _, c := range a.collectors {
@@ -213,7 +702,9 @@ func (a *AWS) Collect(ch chan<- prometheus.Metric) {
 			now := time.Now()
 			defer wg.Done()
 			collectorErrors := 0.0
-			if err := c.Collect(ch); err != nil {
+			err := a.watchdog.Collect(ctx, logger, subsystem, c, ch)
+			a.summary.Record(c.Name(), err)
+			if err != nil {
 				collectorErrors = 1.0
 				log.Printf("Error collecting metrics from collector %s: %s", c.Name(), err)
 			}
@@ -221,6 +712,7 @@ func (a *AWS) Collect(ch chan<- prometheus.Metric) {
 			ch <- prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, time.Since(now).Seconds(), subsystem, c.Name())
 			ch <- prometheus.MustNewConstMetric(collectorLastScrapeTime, prometheus.GaugeValue, float64(time.Now().Unix()), subsystem, c.Name())
 			ch <- prometheus.MustNewConstMetric(collectorSuccessDesc, prometheus.GaugeValue, collectorErrors, c.Name())
+			ch <- provider.NewCollectorUpMetric(subsystem, c.Name(), err)
 			collectorScrapesTotalCounter.WithLabelValues(subsystem, c.Name()).Inc()
 		}(c)
 	}
@@ -231,18 +723,166 @@ func (a *AWS) Collect(ch chan<- prometheus.Metric) {
 	providerScrapesTotalCounter.WithLabelValues(subsystem).Inc()
 }
 
-func newEc2Client(region, profile string) (*ec2.Client, error) {
-	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithEC2IMDSRegion()}
-	options = append(options, awsconfig.WithRegion(region))
-	if profile != "" {
-		options = append(options, awsconfig.WithSharedConfigProfile(profile))
+// Close waits for the collections in flight, logs the collection summary of every collector and closes the idle
+// connections of Config.HTTPClient. The clients of the SDK don't hold any other resource, and the connections of their
+// default HTTP client are only released when the process exits.
+func (a *AWS) Close(ctx context.Context) error {
+	err := a.inFlight.Wait(ctx)
+	a.summary.Log(ctx, a.Config.Logger, subsystem)
+	if err != nil {
+		return err
 	}
-	// Set max retries to 10. Throttling is possible after fetching the pricing data, so setting it to 10 ensures the next scrape will be successful.
-	options = append(options, awsconfig.WithRetryMaxAttempts(maxRetryAttempts))
-	ac, err := awsconfig.LoadDefaultConfig(context.Background(), options...)
+	if a.Config.HTTPClient != nil {
+		a.Config.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+func newEc2Client(region string, config *Config, credentials aws.CredentialsProvider) (*ec2.Client, error) {
+	ac, err := newRegionConfig(region, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return ec2.NewFromConfig(ac, func(o *ec2.Options) {
+		o.BaseEndpoint = baseEndpoint(config.Endpoints, "ec2", region)
+	}), nil
+}
+
+// newRegionClients creates the clients of the EKS collector in a region. The EKS and CloudWatch clients are only
+// created when cluster metadata and idle costs are enabled.
+func newRegionClients(region string, config *Config, credentials aws.CredentialsProvider) (eks.RegionClients, error) {
+	var clients eks.RegionClients
+	var err error
+	if clients.EC2, err = newEc2Client(region, config, credentials); err != nil {
+		return clients, fmt.Errorf("error creating ec2 client: %w", err)
+	}
+	if config.EKSMetadata {
+		if clients.EKS, err = newEksClient(region, config, credentials); err != nil {
+			return clients, fmt.Errorf("error creating eks client: %w", err)
+		}
+	}
+	if config.IdleCost {
+		if clients.CloudWatch, err = newCloudWatchClient(region, config, credentials); err != nil {
+			return clients, fmt.Errorf("error creating cloudwatch client: %w", err)
+		}
+	}
+	return clients, nil
+}
+
+func newEksClient(region string, config *Config, credentials aws.CredentialsProvider) (*awsEks.Client, error) {
+	ac, err := newRegionConfig(region, config, credentials)
 	if err != nil {
 		return nil, err
 	}
 
-	return ec2.NewFromConfig(ac), nil
+	return awsEks.NewFromConfig(ac, func(o *awsEks.Options) {
+		o.BaseEndpoint = baseEndpoint(config.Endpoints, "eks", region)
+	}), nil
+}
+
+// newEcsClient creates an ECS client of a region. Unlike the other clients it doesn't retry throttled requests, as the
+// ECS module of the SDK isn't a dependency.
+func newEcsClient(region string, config *Config, credentials aws.CredentialsProvider) (*ecsclient.Client, error) {
+	ac, err := newRegionConfig(region, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return ecsclient.NewFromConfig(ac, baseEndpoint(config.Endpoints, "ecs", region)), nil
+}
+
+// newRdsClient creates an RDS client of a region. Unlike the other clients it doesn't retry throttled requests, as the
+// RDS module of the SDK isn't a dependency.
+func newRdsClient(region string, config *Config, credentials aws.CredentialsProvider) (*rdsclient.Client, error) {
+	ac, err := newRegionConfig(region, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return rdsclient.NewFromConfig(ac, baseEndpoint(config.Endpoints, "rds", region)), nil
+}
+
+// newElbClient creates an ELB client of a region. Unlike the other clients it doesn't retry throttled requests, as the
+// ELB modules of the SDK aren't dependencies.
+func newElbClient(region string, config *Config, credentials aws.CredentialsProvider) (*elbclient.Client, error) {
+	ac, err := newRegionConfig(region, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return elbclient.NewFromConfig(ac, baseEndpoint(config.Endpoints, "elb", region)), nil
+}
+
+// newS3Inventory creates the inventory of the buckets of the S3 collector, nil when it's disabled. The CloudWatch
+// clients of the regions of the buckets are created on their first lookup. Unlike the other clients the S3 client
+// doesn't retry throttled requests, as the S3 module of the SDK isn't a dependency.
+func newS3Inventory(ac aws.Config, config *Config, credentials aws.CredentialsProvider) *s3.Inventory {
+	if !config.S3BucketInventory && !config.S3BucketStorageMetrics {
+		return nil
+	}
+	inventory := &s3.Inventory{Client: s3client.NewFromConfig(ac, baseEndpoint(config.Endpoints, "s3", ac.Region))}
+	if config.S3BucketStorageMetrics {
+		// The inventory is only listed by its collector, which holds its lock
+		clients := make(map[string]cloudwatchclient.CloudWatch)
+		inventory.CloudWatch = func(region string) (cloudwatchclient.CloudWatch, error) {
+			if client, ok := clients[region]; ok {
+				return client, nil
+			}
+			client, err := newCloudWatchClient(region, config, credentials)
+			if err != nil {
+				return nil, err
+			}
+			clients[region] = client
+			return client, nil
+		}
+	}
+	return inventory
+}
+
+// newPricingClient creates the client listing the prices of the products from the source of config. The offer files
+// are fetched with the HTTP client of config without credentials, as they're public.
+func newPricingClient(ac aws.Config, config *Config) pricingclient.Pricing {
+	if config.PricingSource == PricingSourceOfferFiles {
+		return pricingclient.NewOfferFiles(config.HTTPClient, config.Endpoints.For("offers", ac.Region))
+	}
+	return pricing.NewFromConfig(ac, func(o *pricing.Options) {
+		o.BaseEndpoint = baseEndpoint(config.Endpoints, "pricing", ac.Region)
+	})
+}
+
+func newCloudWatchClient(region string, config *Config, credentials aws.CredentialsProvider) (*cloudwatch.Client, error) {
+	ac, err := newRegionConfig(region, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudwatch.NewFromConfig(ac, func(o *cloudwatch.Options) {
+		o.BaseEndpoint = baseEndpoint(config.Endpoints, "cloudwatch", region)
+	}), nil
+}
+
+func newRegionConfig(region string, config *Config, credentials aws.CredentialsProvider) (aws.Config, error) {
+	options := config.IMDS.regionOptions(region)
+	if config.Profile != "" {
+		options = append(options, awsconfig.WithSharedConfigProfile(config.Profile))
+	}
+	// Set max retries to 10. Throttling is possible after fetching the pricing data, so setting it to 10 ensures the next scrape will be successful.
+	options = append(options, awsconfig.WithRetryMaxAttempts(maxRetryAttempts))
+	options = append(options, awsconfig.WithAPIOptions([]func(*middleware.Stack) error{addSelfCostMiddleware}))
+	if config.HTTPClient != nil {
+		options = append(options, awsconfig.WithHTTPClient(config.HTTPClient))
+	}
+	if credentials != nil {
+		options = append(options, awsconfig.WithCredentialsProvider(credentials))
+	}
+	return awsconfig.LoadDefaultConfig(context.Background(), options...)
+}
+
+// baseEndpoint returns the endpoint override of a service in a region, or nil for the SDK to resolve the endpoint.
+func baseEndpoint(endpoints egress.Endpoints, service string, region string) *string {
+	if endpoint := endpoints.For(service, region); endpoint != "" {
+		return aws.String(endpoint)
+	}
+	return nil
 }
@@ -3,9 +3,11 @@ package aws
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
@@ -37,6 +39,29 @@ func Test_New(t *testing.T) {
 	}
 }
 
+func Test_New_PricingSource(t *testing.T) {
+	_, err := New(context.Background(), &Config{Logger: slog.Default(), PricingSource: "bulk"})
+	require.ErrorIs(t, err, ErrUnknownPricingSource)
+}
+
+func Test_NewForDocs(t *testing.T) {
+	a := NewForDocs(context.Background(), slog.Default())
+	var documented []string
+	for _, c := range a.collectors {
+		documented = append(documented, c.Name())
+	}
+	var accepted []string
+	for name, svc := range collectorServices {
+		require.NotNil(t, svc.new, name)
+		require.NotNil(t, svc.forDocs, name)
+		accepted = append(accepted, svc.forDocs(context.Background(), slog.Default()).Name())
+	}
+	assert.ElementsMatch(t, accepted, documented, "every accepted service is documented")
+	for name := range accountServices {
+		assert.Contains(t, collectorServices, name)
+	}
+}
+
 func Test_RegisterCollectors(t *testing.T) {
 	for _, tc := range []struct {
 		name          string
@@ -120,7 +145,8 @@ func Test_CollectMetrics(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			c := mock_provider.NewMockCollector(ctrl)
 			if tc.collect != nil {
-				c.EXPECT().Collect(ch).DoAndReturn(tc.collect).Times(tc.numCollectors)
+				// The watchdog collects into a channel of its own to count the metrics
+				c.EXPECT().Collect(gomock.Any()).DoAndReturn(tc.collect).Times(tc.numCollectors)
 				c.EXPECT().Name().Return("test").AnyTimes()
 			}
 
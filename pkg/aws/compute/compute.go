@@ -2,20 +2,37 @@ package compute
 
 import (
 	"context"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec22 "github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/prometheus/client_golang/prometheus"
 
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
 	"github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
 )
 
 const maxResults = 1000
 
-func ListComputeInstances(ctx context.Context, client ec2.EC2) ([]types.Reservation, error) {
+var (
+	// InstanceCreatedTimestampDesc exposes the launch time of an instance so that long-running instances can be told apart
+	// from short-lived ones when amortizing costs. Only one collector should emit it to avoid duplicate series.
+	InstanceCreatedTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "aws", "instance_created_timestamp_seconds"),
+		"The time the instance was launched as a unix timestamp in seconds.",
+		[]string{"instance", "region", "machine_type"},
+		nil,
+	)
+)
+
+// ListComputeInstances lists the reservations of the instances selected by filter, which is optional. Reservations
+// whose instances are all excluded are dropped.
+func ListComputeInstances(ctx context.Context, client ec2.EC2, filter *InstanceFilter) ([]types.Reservation, error) {
 	dii := &ec22.DescribeInstancesInput{
 		// 1000 max results was decided arbitrarily. This can likely be tuned.
 		MaxResults: aws.Int32(maxResults),
+		Filters:    filter.filters(),
 	}
 	var instances []types.Reservation
 	for {
@@ -23,7 +40,15 @@ func ListComputeInstances(ctx context.Context, client ec2.EC2) ([]types.Reservat
 		if err != nil {
 			return nil, err
 		}
-		instances = append(instances, resp.Reservations...)
+		for _, reservation := range resp.Reservations {
+			if filter != nil {
+				reservation.Instances = slices.DeleteFunc(reservation.Instances, filter.Excludes)
+				if len(reservation.Instances) == 0 {
+					continue
+				}
+			}
+			instances = append(instances, reservation)
+		}
 		if resp.NextToken == nil || *resp.NextToken == "" {
 			break
 		}
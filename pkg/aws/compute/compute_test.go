@@ -128,7 +128,7 @@ func TestListComputeInstances(t *testing.T) {
 				RunAndReturn(tt.DescribeInstances).
 				Times(tt.expectedCalls)
 
-			got, err := ListComputeInstances(tt.ctx, client)
+			got, err := ListComputeInstances(tt.ctx, client, nil)
 			assert.Equal(t, tt.err, err)
 			assert.Equalf(t, tt.want, got, "ListComputeInstances(%v, %v)", tt.ctx, client)
 		})
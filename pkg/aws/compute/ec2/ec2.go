@@ -15,7 +15,9 @@ import (
 	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
 	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
 	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
@@ -42,11 +44,25 @@ type Collector struct {
 	logger          *slog.Logger
 	context         context.Context
 	pricingMap      *compute.StructuredPricingMap
+	regionFilter    *compute.RegionFilter
+	families        *pricing.FamilyFilter
+	spotAggregation string
+	newClient       func(region string) (ec2client.EC2, error)
 }
 
 type Config struct {
-	Regions []ec2Types.Region
-	Logger  *slog.Logger
+	Regions        []ec2Types.Region
+	Logger         *slog.Logger
+	ScrapeInterval time.Duration
+	// RegionFilter selects the regions listed again on every pricing map refresh, every enabled region is when nil.
+	RegionFilter *compute.RegionFilter
+	// NewClient creates the client of a region enabled after startup. The regions aren't listed again when nil.
+	NewClient func(region string) (ec2client.EC2, error)
+	// Families is optional, when set only the prices of the instance families it selects are fetched.
+	Families *pricing.FamilyFilter
+	// SpotAggregation is how the spot prices of the availability zones of a region are aggregated into the spot price
+	// of the region, see compute.StructuredPricingMap.SpotAggregation.
+	SpotAggregation string
 }
 
 // CollectMetrics is a no-op function that satisfies the provider.Collector interface.
@@ -61,6 +77,10 @@ func (c *Collector) Collect(_ chan<- prometheus.Metric) error {
 	if c.pricingMap == nil || time.Now().After(c.NextScrape) {
 		now := time.Now()
 		c.logger.LogAttrs(c.context, slog.LevelInfo, "Generating Pricing Map")
+		// The regions were already listed when the collector was created
+		if c.pricingMap != nil {
+			c.refreshRegions()
+		}
 		var prices []string
 		var spotPrices []ec2Types.SpotPrice
 		eg := new(errgroup.Group)
@@ -69,7 +89,7 @@ func (c *Collector) Collect(_ chan<- prometheus.Metric) error {
 		for _, region := range c.Regions {
 			eg.Go(func() error {
 				c.logger.LogAttrs(c.context, slog.LevelDebug, "Getting on demand prices for region", slog.String("region", *region.RegionName))
-				priceList, err := compute.ListOnDemandPrices(context.TODO(), *region.RegionName, c.pricingService)
+				priceList, err := compute.ListOnDemandPrices(context.TODO(), *region.RegionName, c.pricingService, c.families)
 				if err != nil {
 					return fmt.Errorf("%w: %w", compute.ErrListOnDemandPrices, err)
 				}
@@ -79,7 +99,7 @@ func (c *Collector) Collect(_ chan<- prometheus.Metric) error {
 				}
 				client := c.ec2RegionClient[*region.RegionName]
 				c.logger.LogAttrs(c.context, slog.LevelDebug, "Getting spot prices for region", slog.String("region", *region.RegionName))
-				spotPriceList, err := compute.ListSpotPrices(context.TODO(), client)
+				spotPriceList, err := compute.ListSpotPrices(context.TODO(), client, c.families)
 				if err != nil {
 					return fmt.Errorf("%w: %w", compute.ErrListSpotPrices, err)
 				}
@@ -96,10 +116,11 @@ func (c *Collector) Collect(_ chan<- prometheus.Metric) error {
 			return err
 		}
 		c.pricingMap = compute.NewStructuredPricingMap()
+		c.pricingMap.SpotAggregation = c.spotAggregation
 		if err := c.pricingMap.GeneratePricingMap(prices, spotPrices); err != nil {
 			return fmt.Errorf("%w: %w", ErrGeneratePricingMap, err)
 		}
-		c.NextScrape = time.Now().Add(c.ScrapeInterval)
+		c.NextScrape = utils.NextScrape(time.Now(), c.ScrapeInterval)
 		c.logger.LogAttrs(c.context, slog.LevelInfo, "Generated Pricing Map",
 			slog.Duration("duration", time.Since(now)),
 		)
@@ -123,10 +144,43 @@ func New(ctx context.Context, config *Config, ps pricingClient.Pricing, ec2s ec2
 		pricingService:  ps,
 		ec2Client:       ec2s,
 		Regions:         config.Regions,
+		ScrapeInterval:  config.ScrapeInterval,
 		ec2RegionClient: regionClientMap,
 		logger:          logger,
 		context:         ctx,
+		regionFilter:    config.RegionFilter,
+		families:        config.Families,
+		newClient:       config.NewClient,
+		spotAggregation: config.SpotAggregation,
+	}
+}
+
+// refreshRegions replaces the regions of the collector with the regions currently enabled for the account and creates
+// the clients of the new ones. The known regions are kept when the regions can't be listed.
+func (c *Collector) refreshRegions() {
+	if c.newClient == nil || c.ec2Client == nil {
+		return
+	}
+	regions, err := compute.ListRegions(c.context, c.ec2Client, c.regionFilter)
+	if err != nil {
+		c.logger.LogAttrs(c.context, slog.LevelError, "Error listing regions, keeping the known regions", slog.String("error", err.Error()))
+		return
+	}
+	var enabled []ec2Types.Region
+	for _, region := range regions {
+		name := *region.RegionName
+		if _, ok := c.ec2RegionClient[name]; !ok {
+			client, err := c.newClient(name)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "Error creating the client of a region", slog.String("region", name), slog.String("error", err.Error()))
+				continue
+			}
+			c.ec2RegionClient[name] = client
+			c.logger.LogAttrs(c.context, slog.LevelInfo, "Discovered region", slog.String("region", name))
+		}
+		enabled = append(enabled, region)
 	}
+	c.Regions = enabled
 }
 
 // Register is called by the prometheus library to register any static metrics that require persistence.
@@ -5,22 +5,39 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
 
 	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/anomaly"
 	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
 	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	eksclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/eks"
 	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/commitment"
+	"github.com/grafana/cloudcost-exporter/pkg/eviction"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricesource"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/schedule"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
-	subsystem = "aws_eks"
+	subsystem    = "aws_eks"
+	providerName = "aws"
 )
 
 var (
@@ -33,17 +50,45 @@ var (
 	InstanceCPUHourlyCostDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "instance_cpu_usd_per_core_hour"),
 		"The cpu cost a compute instance in USD/(core*h)",
-		[]string{"instance", "region", "family", "machine_type", "cluster", "price_tier"},
+		[]string{"instance", "region", "zone", "family", "machine_type", "cluster", "price_tier", "kubernetes_version", "capacity_type", "cluster_name", "price_aggregation"},
 		nil,
 	)
 	InstanceMemoryHourlyCostDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "instance_memory_usd_per_gib_hour"),
 		"The memory cost of a compute instance in USD/(GiB*h)",
-		[]string{"instance", "region", "family", "machine_type", "cluster", "price_tier"},
+		[]string{"instance", "region", "zone", "family", "machine_type", "cluster", "price_tier", "kubernetes_version", "capacity_type", "cluster_name", "price_aggregation"},
+		nil,
+	)
+	// InstanceCostTotalDesc accumulates the hourly price of every instance between scrapes, see utils.CostCounter.
+	// InstanceAcceleratorHourlyCostDesc and InstanceAcceleratorsDesc are only exported for the instances with
+	// accelerators, eg GPUs or the Inferentia and Trainium chips of the inf and trn families.
+	InstanceAcceleratorHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "instance_accelerator_usd_per_accelerator_hour"),
+		"The cost of each accelerator of a compute instance in USD/(accelerator*h). It's left out of the cpu and memory costs of the instance.",
+		[]string{"instance", "region", "zone", "family", "machine_type", "cluster", "price_tier", "kubernetes_version", "capacity_type", "cluster_name", "price_aggregation", "accelerator_type"},
+		nil,
+	)
+	InstanceAcceleratorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "instance_accelerators"),
+		"The number of accelerators of a compute instance.",
+		[]string{"instance", "region", "zone", "family", "machine_type", "cluster", "price_tier", "kubernetes_version", "capacity_type", "cluster_name", "price_aggregation", "accelerator_type"},
+		nil,
+	)
+	InstanceCostTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "usd_total"),
+		"The cost of a compute instance in USD accumulated by the exporter since it first saw the instance.",
+		[]string{"instance", "region", "machine_type", "cluster_name", "price_tier"},
 		nil,
 	)
 )
 
+// regionInstances are the instances listed in a single region along with their CPU utilization keyed by instance id.
+// utilization is nil when idle costs aren't enabled or CloudWatch couldn't be queried.
+type regionInstances struct {
+	reservations []ec2Types.Reservation
+	utilization  map[string]float64
+}
+
 // Collector is a prometheus collector that collects metrics from AWS EKS clusters.
 type Collector struct {
 	Region          string
@@ -52,10 +97,46 @@ type Collector struct {
 	Profiles        []string
 	ScrapeInterval  time.Duration
 	pricingMap      *compute.StructuredPricingMap
+	storagePrices   compute.StoragePrices
 	pricingService  pricingClient.Pricing
 	ec2Client       ec2client.EC2
 	NextScrape      time.Time
+	backoff         *provider.Backoff
 	ec2RegionClient map[string]ec2client.EC2
+	metadata        *clusterMetadata
+	// cloudwatchRegionClient is only set when idle costs are enabled
+	cloudwatchRegionClient map[string]cloudwatchclient.CloudWatch
+	clusterNames           *clustername.Normalizer
+	// nodes is only set when allocatable costs are enabled
+	nodes kubernetes.NodeLister
+	// pods is only set when namespace costs are enabled
+	pods  kubernetes.PodLister
+	costs *utils.CostCounter
+	// calendar is only set when clusters have a scale down schedule
+	calendar *schedule.Calendar
+	// anomalies is only set when the cost anomaly scores are enabled
+	anomalies *anomaly.Detector
+	// evictions is only set when the spot eviction metrics are enabled
+	evictions *eviction.Watcher
+	// recommendations is only set when the commitment recommendations are enabled
+	recommendations *commitment.Recommender
+	// prices is only set when the on-demand prices are overridden
+	prices pricesource.Layers
+	// instanceFilter is only set when the listed instances are narrowed down
+	instanceFilter *compute.InstanceFilter
+	// priceHistory is only set when the price history is enabled
+	priceHistory *pricehistory.History
+	// priceLookup is only set when the exporter is embedded and looks up the prices of instances
+	priceLookup *pricing.Lookup
+	// regionDiscovery is only set when the regions are listed again on every pricing map refresh
+	regionDiscovery *RegionDiscovery
+	// storageClasses is only set when the storage classes of the clusters are priced
+	storageClasses *storageclass.Classes
+	// families is only set when the prices of some instance families aren't fetched
+	families *pricing.FamilyFilter
+	// spotAggregation is the aggregation of the spot prices of the regions of the pricing map, see
+	// compute.StructuredPricingMap.SpotAggregation.
+	spotAggregation string
 }
 
 // CollectMetrics is a no-op function that satisfies the provider.Collector interface.
@@ -66,72 +147,213 @@ func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
 
 // Collect satisfies the provider.Collector interface.
 func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
-	if c.pricingMap == nil || time.Now().After(c.NextScrape) {
-		var prices []string
-		var spotPrices []ec2Types.SpotPrice
-		eg := new(errgroup.Group)
-		eg.SetLimit(5)
-		m := sync.Mutex{}
-		for _, region := range c.Regions {
-			eg.Go(func() error {
-				priceList, err := compute.ListOnDemandPrices(context.Background(), *region.RegionName, c.pricingService)
-				if err != nil {
-					return fmt.Errorf("%w: %w", compute.ErrListOnDemandPrices, err)
-				}
-
-				if c.ec2RegionClient[*region.RegionName] == nil {
-					return ErrClientNotFound
-				}
-				client := c.ec2RegionClient[*region.RegionName]
-				spotPriceList, err := compute.ListSpotPrices(context.Background(), client)
-				if err != nil {
-					return fmt.Errorf("%w: %w", compute.ErrListSpotPrices, err)
-				}
-				m.Lock()
-				spotPrices = append(spotPrices, spotPriceList...)
-				prices = append(prices, priceList...)
-				m.Unlock()
-				return nil
-			})
-		}
-		err := eg.Wait()
+	now := time.Now()
+	if c.pricingMap == nil || now.After(c.NextScrape) {
+		err := c.refreshPricingMap()
+		c.NextScrape = c.backoff.Next(now, err)
 		if err != nil {
 			return err
 		}
-		c.pricingMap = compute.NewStructuredPricingMap()
-		if err := c.pricingMap.GeneratePricingMap(prices, spotPrices); err != nil {
-			return fmt.Errorf("%w: %w", ErrGeneratePricingMap, err)
-		}
-		c.NextScrape = time.Now().Add(c.ScrapeInterval)
 	}
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(c.Regions))
-	instanceCh := make(chan []ec2Types.Reservation, len(c.Regions))
+	instanceCh := make(chan regionInstances, len(c.Regions))
+	regionErrs := make(map[string]error, len(c.Regions))
+	regionErrsMu := sync.Mutex{}
 	for _, region := range c.Regions {
 		go func(region ec2Types.Region) {
 			defer wg.Done()
 			client := c.ec2RegionClient[*region.RegionName]
-			reservations, err := compute.ListComputeInstances(context.Background(), client)
+			reservations, err := compute.ListComputeInstances(context.Background(), client, c.instanceFilter)
+			regionErrsMu.Lock()
+			regionErrs[*region.RegionName] = err
+			regionErrsMu.Unlock()
 			if err != nil {
-				log.Printf("error listing instances: %s", err)
+				log.Printf("error listing instances in region %s: %s", *region.RegionName, err)
 				return
 			}
 			log.Printf("found %d instances in region %s", len(reservations), *region.RegionName)
-			instanceCh <- reservations
+			instanceCh <- regionInstances{
+				reservations: reservations,
+				utilization:  c.cpuUtilization(*region.RegionName, reservations),
+			}
 		}(region)
 	}
 	go func() {
 		wg.Wait()
 		close(instanceCh)
 	}()
-	c.emitMetricsFromChannel(instanceCh, ch)
+	c.backoff.Emit(ch)
+	for _, metric := range c.storagePrices.Metrics() {
+		ch <- metric
+	}
+	for _, metric := range c.storagePrices.KubernetesStorageClassMetrics(c.storageClasses.ForProvider(storageclass.ProviderAWS)) {
+		ch <- metric
+	}
+	inventory := kubernetes.NewInventory(kubernetes.NodesByName(context.Background(), c.nodes))
+	allocation := kubernetes.NewAllocation(kubernetes.PodsByNode(context.Background(), c.pods))
+	c.emitMetricsFromChannel(instanceCh, inventory, allocation, ch)
+
+	// A single region failing shouldn't prevent the other regions from being exported, only fail if every region failed
+	var failedRegions []error
+	for region, err := range regionErrs {
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, err)
+		if err != nil {
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, err))
+		}
+	}
+	if len(c.Regions) > 0 && len(failedRegions) == len(c.Regions) {
+		return errors.Join(failedRegions...)
+	}
 	return nil
 }
 
-func (c *Collector) emitMetricsFromChannel(reservationsCh chan []ec2Types.Reservation, ch chan<- prometheus.Metric) {
-	for reservations := range reservationsCh {
-		for _, reservation := range reservations {
+// refreshPricingMap lists the prices of every region again and rebuilds the pricing map and the storage prices.
+func (c *Collector) refreshPricingMap() error {
+	// The regions were already listed when the collector was created
+	if c.pricingMap != nil {
+		c.refreshRegions(context.Background())
+	}
+	var prices []string
+	var spotPrices []ec2Types.SpotPrice
+	eg := new(errgroup.Group)
+	eg.SetLimit(5)
+	m := sync.Mutex{}
+	var pricingErrs []error
+	for _, region := range c.Regions {
+		eg.Go(func() error {
+			priceList, spotPriceList, err := c.listRegionPrices(*region.RegionName)
+			m.Lock()
+			defer m.Unlock()
+			// A single region failing shouldn't prevent the pricing map from being refreshed for the other regions
+			if err != nil {
+				log.Printf("error listing prices in region %s: %s", *region.RegionName, err)
+				compute.PricingRegionErrorsTotal.WithLabelValues(subsystem, *region.RegionName).Inc()
+				pricingErrs = append(pricingErrs, fmt.Errorf("region %s: %w", *region.RegionName, err))
+				return nil
+			}
+			spotPrices = append(spotPrices, spotPriceList...)
+			prices = append(prices, priceList...)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	if len(c.Regions) > 0 && len(pricingErrs) == len(c.Regions) {
+		return errors.Join(pricingErrs...)
+	}
+	c.pricingMap = compute.NewStructuredPricingMap()
+	c.pricingMap.SpotAggregation = c.spotAggregation
+	if err := c.pricingMap.GeneratePricingMap(prices, spotPrices); err != nil {
+		return fmt.Errorf("%w: %w", ErrGeneratePricingMap, err)
+	}
+	if c.priceHistory != nil {
+		c.priceHistory.Record(subsystem, c.pricingMap.HistoryPrices())
+	}
+	c.priceLookup.Set(pricing.ProviderAWS, c.pricingMap)
+	c.storagePrices = c.listStoragePrices()
+	c.metadata.reset()
+	return nil
+}
+
+// listStoragePrices lists the prices of the EBS volume types of every region. The prices are only a price sheet of the
+// storage classes, so failing to list them for a region is logged rather than failing the pricing map.
+func (c *Collector) listStoragePrices() compute.StoragePrices {
+	var products []string
+	for _, region := range c.Regions {
+		priceList, err := compute.ListStoragePrices(context.Background(), *region.RegionName, c.pricingService)
+		if err != nil {
+			log.Printf("error listing storage prices in region %s: %s", *region.RegionName, err)
+			continue
+		}
+		products = append(products, priceList...)
+	}
+	return compute.ParseStoragePrices(products)
+}
+
+// cpuUtilization looks up the CPU utilization of the instances in a region. Failing to do so only drops the idle cost
+// metrics, so the error is logged rather than failing the region.
+func (c *Collector) cpuUtilization(region string, reservations []ec2Types.Reservation) map[string]float64 {
+	client, ok := c.cloudwatchRegionClient[region]
+	if !ok {
+		return nil
+	}
+	var instanceIDs []string
+	for _, reservation := range reservations {
+		for _, instance := range reservation.Instances {
+			if instance.InstanceId != nil {
+				instanceIDs = append(instanceIDs, *instance.InstanceId)
+			}
+		}
+	}
+	if len(instanceIDs) == 0 {
+		return nil
+	}
+	utilization, err := compute.ListCPUUtilization(context.Background(), client, instanceIDs, time.Now())
+	if err != nil {
+		log.Printf("error getting cpu utilization in region %s: %s", region, err)
+		return nil
+	}
+	return utilization
+}
+
+// listRegionPrices lists the on-demand and spot prices of a region.
+func (c *Collector) listRegionPrices(region string) ([]string, []ec2Types.SpotPrice, error) {
+	priceList, err := compute.ListOnDemandPrices(context.Background(), region, c.pricingService, c.families)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", compute.ErrListOnDemandPrices, err)
+	}
+	client := c.ec2RegionClient[region]
+	if client == nil {
+		return nil, nil, ErrClientNotFound
+	}
+	spotPriceList, err := compute.ListSpotPrices(context.Background(), client, c.families)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", compute.ErrListSpotPrices, err)
+	}
+	return priceList, spotPriceList, nil
+}
+
+// emitMetricsFromChannel emits the metrics of every instance of an EKS cluster. inventory holds the nodes of the
+// cluster the exporter runs in and is only set when the Kubernetes integration is enabled, allocation holds its pods
+// and is only set when namespace costs are enabled.
+func (c *Collector) emitMetricsFromChannel(instanceCh chan regionInstances, inventory *kubernetes.Inventory, allocation *kubernetes.Allocation, ch chan<- prometheus.Metric) {
+	unpriced := compute.NewUnpricedMachineTypes(subsystem)
+	defer unpriced.Emit(ch)
+	defer c.costs.Emit(ch)
+	defer func() {
+		for _, m := range inventory.Metrics(providerName) {
+			ch <- m
+		}
+		for _, m := range allocation.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	spend := c.calendar.Spend()
+	defer func() {
+		for _, m := range spend.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	clusterCosts := c.anomalies.Scrape()
+	defer func() {
+		for _, m := range clusterCosts.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	defer func() {
+		for _, m := range c.evictions.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	usage := c.recommendations.Scrape()
+	defer func() {
+		for _, m := range usage.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	for instances := range instanceCh {
+		for _, reservation := range instances.reservations {
 			for _, instance := range reservation.Instances {
 				clusterName := compute.ClusterNameFromInstance(instance)
 				if clusterName == "" {
@@ -142,58 +364,236 @@ func (c *Collector) emitMetricsFromChannel(reservationsCh chan []ec2Types.Reserv
 					log.Printf("no private dns name found for instance %s", *instance.InstanceId)
 					continue
 				}
-				if instance.Placement == nil || instance.Placement.AvailabilityZone == nil {
+				// The region is derived by trimming the availability zone, which can't be done on an empty one
+				if instance.Placement == nil || instance.Placement.AvailabilityZone == nil || *instance.Placement.AvailabilityZone == "" {
 					log.Printf("no availability zone found for instance %s", *instance.InstanceId)
 					continue
 				}
 
-				region := *instance.Placement.AvailabilityZone
+				// Only nodes of the cluster the exporter runs in are known, and EKS names nodes after their private DNS name
+				if instance.State != nil && instance.State.Name == ec2Types.InstanceStateNameRunning {
+					inventory.Observe(c.clusterNames.Normalize(clusterName), *instance.PrivateDnsName, aws.ToTime(instance.LaunchTime))
+				}
+
+				zone := *instance.Placement.AvailabilityZone
+				// The EKS API is regional, so the availability zone needs to be trimmed regardless of the price tier
+				eksRegion := compute.RegionFromAvailabilityZone(zone)
+				if instance.LaunchTime != nil {
+					ch <- prometheus.MustNewConstMetric(compute.InstanceCreatedTimestampDesc, prometheus.GaugeValue, float64(instance.LaunchTime.Unix()), *instance.PrivateDnsName, eksRegion, string(instance.InstanceType))
+				}
+				// region is the region, or the zone group of the Local Zone or Wavelength Zone, the instance runs in
+				// whatever its price tier, the availability zone is exported as the zone
+				region := compute.PricingLocation(zone)
 
 				pricetier := "spot"
-				if instance.InstanceLifecycle != ec2Types.InstanceLifecycleTypeSpot {
+				// Spot prices are keyed by availability zone, and on-demand prices by region. aggregation is set when
+				// the zone has no spot price and the instance is priced at the spot price of the region instead
+				priceLocation := zone
+				var price *compute.Prices
+				var aggregation string
+				var err error
+				if instance.InstanceLifecycle == ec2Types.InstanceLifecycleTypeSpot {
+					price, aggregation, err = c.pricingMap.GetSpotPrice(priceLocation, string(instance.InstanceType))
+				} else {
 					pricetier = "ondemand"
-					// Ondemand instances are keyed based upon their region, so we need to remove the availability zone
-					region = region[:len(region)-1]
+					priceLocation = region
+					price, err = c.pricingMap.GetPriceForInstanceType(priceLocation, string(instance.InstanceType))
 				}
-				price, err := c.pricingMap.GetPriceForInstanceType(region, string(instance.InstanceType))
 				if err != nil {
 					log.Printf("error getting price for instance type %s: %s", instance.InstanceType, err)
+					unpriced.Add(priceLocation, string(instance.InstanceType), err)
 					continue
 				}
+				unpriced.Priced()
+				// Spot prices are market prices, only the on-demand prices are overridden or discounted
+				if pricetier == "ondemand" {
+					if scale := c.prices.Scale(providerName, region, string(instance.InstanceType), price.Total); scale != 1 {
+						price = &compute.Prices{Cpu: price.Cpu * scale, Ram: price.Ram * scale, Total: price.Total * scale, Accelerator: price.Accelerator * scale}
+					}
+				}
 				labelValues := []string{
 					*instance.PrivateDnsName,
 					region,
+					zone,
 					c.pricingMap.InstanceDetails[string(instance.InstanceType)].InstanceFamily,
 					string(instance.InstanceType),
 					clusterName,
 					pricetier,
+					c.metadata.kubernetesVersion(context.Background(), eksRegion, clusterName),
+					c.metadata.capacityType(context.Background(), eksRegion, clusterName, instance),
+					// cluster is kept as tagged for backwards compatibility, cluster_name is normalized to join with
+					// the other providers
+					c.clusterNames.Normalize(clusterName),
+					aggregation,
 				}
 				ch <- prometheus.MustNewConstMetric(InstanceCPUHourlyCostDesc, prometheus.GaugeValue, price.Cpu, labelValues...)
 				ch <- prometheus.MustNewConstMetric(InstanceMemoryHourlyCostDesc, prometheus.GaugeValue, price.Ram, labelValues...)
+				if price.Accelerator > 0 {
+					acceleratorLabelValues := append(labelValues, compute.AcceleratorType(string(instance.InstanceType)))
+					accelerators := c.pricingMap.InstanceDetails[string(instance.InstanceType)].Accelerators()
+					ch <- prometheus.MustNewConstMetric(InstanceAcceleratorHourlyCostDesc, prometheus.GaugeValue, price.Accelerator, acceleratorLabelValues...)
+					ch <- prometheus.MustNewConstMetric(InstanceAcceleratorsDesc, prometheus.GaugeValue, accelerators, acceleratorLabelValues...)
+				}
+				c.costs.Observe(price.Total, *instance.PrivateDnsName, region, string(instance.InstanceType), c.clusterNames.Normalize(clusterName), pricetier)
+				spend.Add(c.clusterNames.Normalize(clusterName), price.Total)
+				clusterCosts.Add(c.clusterNames.Normalize(clusterName), price.Total)
+				// Only running on-demand instances can be covered by a Reserved Instance or a Savings Plan
+				if pricetier == "ondemand" && instance.State != nil && instance.State.Name == ec2Types.InstanceStateNameRunning {
+					if cores, err := strconv.ParseFloat(c.pricingMap.InstanceDetails[string(instance.InstanceType)].VCPU, 64); err == nil {
+						family, _, _ := strings.Cut(string(instance.InstanceType), ".")
+						usage.Add(region, family, cores, price.Total)
+					}
+				}
+				if isSpotEviction(instance) {
+					c.evictions.Observe(providerName, c.clusterNames.Normalize(clusterName), *instance.InstanceId, price.Total)
+				}
+				if node, ok := inventory.Node(*instance.PrivateDnsName); ok {
+					for _, m := range kubernetes.AllocatableMetrics(node, price.Cpu, price.Ram, c.clusterNames.Normalize(clusterName), providerName, string(instance.InstanceType), pricetier) {
+						ch <- m
+					}
+					allocation.Observe(c.clusterNames.Normalize(clusterName), node, price.Cpu, price.Ram)
+				}
+				if utilization, ok := instances.utilization[*instance.InstanceId]; ok {
+					ch <- prometheus.MustNewConstMetric(compute.InstanceIdleHourlyCostDesc, prometheus.GaugeValue, compute.IdleCost(price.Total, utilization), *instance.PrivateDnsName, region, string(instance.InstanceType), pricetier)
+				}
 			}
 		}
 	}
 }
 
+// spotInstanceTerminationCode is the state reason of the spot instances AWS interrupted, as opposed to instances
+// terminated by the user or an autoscaler.
+const spotInstanceTerminationCode = "Server.SpotInstanceTermination"
+
+// isSpotEviction returns whether a spot instance was terminated, or is being terminated, by a spot interruption.
+func isSpotEviction(instance ec2Types.Instance) bool {
+	return instance.InstanceLifecycle == ec2Types.InstanceLifecycleTypeSpot &&
+		instance.StateReason != nil && aws.ToString(instance.StateReason.Code) == spotInstanceTerminationCode
+}
+
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
 	ch <- InstanceCPUHourlyCostDesc
 	ch <- InstanceMemoryHourlyCostDesc
+	ch <- InstanceAcceleratorHourlyCostDesc
+	ch <- InstanceAcceleratorsDesc
+	ch <- InstanceCostTotalDesc
+	ch <- compute.UnpricedMachineTypeInfoDesc
+	ch <- utils.PricingCoverageDesc
+	ch <- compute.InstanceCreatedTimestampDesc
+	ch <- compute.InstanceIdleHourlyCostDesc
+	ch <- compute.StorageClassHourlyPriceDesc
+	ch <- storageclass.HourlyPriceDesc
+	ch <- storageclass.VolumeHourlyPriceDesc
+	ch <- kubernetes.NodeCPUAllocatableHourlyCostDesc
+	ch <- kubernetes.NodeMemoryAllocatableHourlyCostDesc
+	ch <- kubernetes.OrphanedInstancesDesc
+	ch <- kubernetes.NamespaceHourlyCostDesc
+	ch <- schedule.ClusterActualHourlyCostDesc
+	ch <- anomaly.CostAnomalyScoreDesc
+	ch <- eviction.SpotEvictionsTotalDesc
+	ch <- eviction.SpotEvictionChurnCostDesc
+	ch <- commitment.RecommendedCoresDesc
+	ch <- commitment.PotentialSavingsDesc
+	ch <- schedule.ClusterExpectedHourlyCostDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
 	return nil
 }
 
+// ResourceDescs returns the metrics of the instances listed by the collector, the prices of the storage classes aren't
+// resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		InstanceCPUHourlyCostDesc,
+		InstanceMemoryHourlyCostDesc,
+		InstanceAcceleratorHourlyCostDesc,
+		InstanceAcceleratorsDesc,
+		InstanceCostTotalDesc,
+		compute.InstanceCreatedTimestampDesc,
+		compute.InstanceIdleHourlyCostDesc,
+		kubernetes.NodeCPUAllocatableHourlyCostDesc,
+		kubernetes.NodeMemoryAllocatableHourlyCostDesc,
+		kubernetes.NamespaceHourlyCostDesc,
+	}
+}
+
 func (c *Collector) Name() string {
 	return subsystem
 }
 
-func New(region string, profile string, scrapeInterval time.Duration, ps pricingClient.Pricing, ec2s ec2client.EC2, regions []ec2Types.Region, regionClientMap map[string]ec2client.EC2) *Collector {
+type Config struct {
+	Region         string
+	Profile        string
+	ScrapeInterval time.Duration
+	Regions        []ec2Types.Region
+	// EKSRegionClients is optional, when set the EKS API is used to add the Kubernetes version of the cluster and the
+	// capacity type declared by the nodegroup to the instance metrics.
+	EKSRegionClients map[string]eksclient.EKS
+	// CloudWatchRegionClients is optional, when set CloudWatch is used to export the idle cost of each instance.
+	CloudWatchRegionClients map[string]cloudwatchclient.CloudWatch
+	ClusterNames            *clustername.Normalizer
+	// Nodes is optional, when set the allocatable costs of the nodes are exported.
+	Nodes kubernetes.NodeLister
+	// Pods is optional, when set along with Nodes the costs of the nodes are allocated to the namespaces of their pods.
+	Pods kubernetes.PodLister
+	// Calendar is optional, when set the actual and expected costs of the clusters with a scale down schedule are exported.
+	Calendar *schedule.Calendar
+	// Anomalies is optional, when set the anomaly scores of the cost of the clusters are exported.
+	Anomalies *anomaly.Detector
+	// Evictions is optional, when set the evictions of the spot instances of the clusters and their cost are exported.
+	Evictions *eviction.Watcher
+	// Recommendations is optional, when set the commitment recommendations of the on-demand cores of the clusters are
+	// exported.
+	Recommendations *commitment.Recommender
+	// Prices is optional, when set the on-demand prices of the instances are overridden or discounted by its layers.
+	Prices pricesource.Layers
+	// InstanceFilter is optional, when set only the instances it selects are listed and priced.
+	InstanceFilter *compute.InstanceFilter
+	// PriceHistory is optional, when set every pricing map is recorded in it.
+	PriceHistory *pricehistory.History
+	// PriceLookup is optional, when set every pricing map is set in it.
+	PriceLookup *pricing.Lookup
+	// RegionDiscovery is optional, when set the regions are listed again on every pricing map refresh.
+	RegionDiscovery *RegionDiscovery
+	// StorageClasses is optional, when set the price sheet of the storage classes of the clusters is exported.
+	StorageClasses *storageclass.Classes
+	// Families is optional, when set only the prices of the instance families it selects are fetched.
+	Families *pricing.FamilyFilter
+	// SpotAggregation is how the spot prices of the availability zones of a region are aggregated into the spot price
+	// the spot instances of the zones without a price are priced at, see compute.StructuredPricingMap.SpotAggregation.
+	SpotAggregation string
+}
+
+// New creates an EKS collector. regionClientMap holds the ec2 client of every region of config.Regions.
+func New(config *Config, ps pricingClient.Pricing, ec2s ec2client.EC2, regionClientMap map[string]ec2client.EC2) *Collector {
 	return &Collector{
-		Region:          region,
-		Profile:         profile,
-		ScrapeInterval:  scrapeInterval,
+		Region:          config.Region,
+		Profile:         config.Profile,
+		ScrapeInterval:  config.ScrapeInterval,
 		pricingService:  ps,
 		ec2Client:       ec2s,
-		Regions:         regions,
+		backoff:         provider.NewBackoff(providerName, subsystem, config.ScrapeInterval),
+		Regions:         config.Regions,
 		ec2RegionClient: regionClientMap,
+		metadata:        newClusterMetadata(config.EKSRegionClients),
+
+		cloudwatchRegionClient: config.CloudWatchRegionClients,
+		clusterNames:           config.ClusterNames,
+		nodes:                  config.Nodes,
+		pods:                   config.Pods,
+		costs:                  utils.NewCostCounter(InstanceCostTotalDesc),
+		calendar:               config.Calendar,
+		anomalies:              config.Anomalies,
+		evictions:              config.Evictions,
+		recommendations:        config.Recommendations,
+		prices:                 config.Prices,
+		instanceFilter:         config.InstanceFilter,
+		priceHistory:           config.PriceHistory,
+		priceLookup:            config.PriceLookup,
+		regionDiscovery:        config.RegionDiscovery,
+		storageClasses:         config.StorageClasses,
+		families:               config.Families,
+		spotAggregation:        config.SpotAggregation,
 	}
 }
 
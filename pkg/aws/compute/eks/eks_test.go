@@ -6,18 +6,27 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	mockcloudwatch "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/cloudwatch"
 	mockec2 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ec2"
 	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
 	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
 	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/schedule"
 	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
@@ -98,7 +107,7 @@ func TestCollector_ListOnDemandPrices(t *testing.T) {
 				GetProducts(mock.Anything, mock.Anything, mock.Anything).
 				RunAndReturn(tt.GetProducts).
 				Times(tt.expectedCalls)
-			got, err := compute.ListOnDemandPrices(tt.ctx, tt.region, client)
+			got, err := compute.ListOnDemandPrices(tt.ctx, tt.region, client, nil)
 			if tt.err != nil {
 				assert.Equal(t, tt.err, err)
 			}
@@ -132,7 +141,7 @@ func TestNewCollector(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			collector := New(tt.region, tt.profile, tt.scrapeInternal, tt.ps, tt.ec2s, nil, nil)
+			collector := New(&Config{Region: tt.region, Profile: tt.profile, ScrapeInterval: tt.scrapeInternal}, tt.ps, tt.ec2s, nil)
 			assert.NotNil(t, collector)
 		})
 	}
@@ -140,7 +149,7 @@ func TestNewCollector(t *testing.T) {
 
 func TestCollector_Name(t *testing.T) {
 	t.Run("Name should return the same name as the subsystem const", func(t *testing.T) {
-		collector := New("", "", 0, nil, nil, nil, nil)
+		collector := New(&Config{}, nil, nil, nil)
 		assert.Equal(t, subsystem, collector.Name())
 	})
 }
@@ -152,7 +161,7 @@ func TestCollector_Collect(t *testing.T) {
 		},
 	}
 	t.Run("Collect should return no error", func(t *testing.T) {
-		collector := New("", "", 0, nil, nil, nil, nil)
+		collector := New(&Config{}, nil, nil, nil)
 		ch := make(chan prometheus.Metric)
 		go func() {
 			err := collector.Collect(ch)
@@ -173,7 +182,7 @@ func TestCollector_Collect(t *testing.T) {
 				func(ctx context.Context, input *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
 					return nil, assert.AnError
 				}).Times(1)
-		collector := New("us-east-1", "", 0, ps, nil, regions, nil)
+		collector := New(&Config{Region: "us-east-1", Regions: regions}, ps, nil, nil)
 		ch := make(chan prometheus.Metric)
 		err := collector.Collect(ch)
 		close(ch)
@@ -188,7 +197,7 @@ func TestCollector_Collect(t *testing.T) {
 						PriceList: []string{},
 					}, nil
 				}).Times(1)
-		collector := New("", "", 0, ps, nil, regions, nil)
+		collector := New(&Config{Regions: regions}, ps, nil, nil)
 		ch := make(chan prometheus.Metric)
 		err := collector.Collect(ch)
 		close(ch)
@@ -213,7 +222,7 @@ func TestCollector_Collect(t *testing.T) {
 		for _, r := range regions {
 			regionClientMap[*r.RegionName] = ec2s
 		}
-		collector := New("us-east-1", "", 0, ps, ec2s, regions, regionClientMap)
+		collector := New(&Config{Region: "us-east-1", Regions: regions}, ps, ec2s, regionClientMap)
 		ch := make(chan prometheus.Metric)
 		err := collector.Collect(ch)
 		close(ch)
@@ -248,7 +257,7 @@ func TestCollector_Collect(t *testing.T) {
 		for _, r := range regions {
 			regionClientMap[*r.RegionName] = ec2s
 		}
-		collector := New("us-east-1", "", 0, ps, ec2s, regions, regionClientMap)
+		collector := New(&Config{Region: "us-east-1", Regions: regions}, ps, ec2s, regionClientMap)
 		ch := make(chan prometheus.Metric)
 		defer close(ch)
 		assert.ErrorIs(t, collector.Collect(ch), ErrGeneratePricingMap)
@@ -281,7 +290,7 @@ func TestCollector_Collect(t *testing.T) {
 										Tags: []ec2Types.Tag{
 											{
 												Key:   aws.String("eks:cluster-name"),
-												Value: aws.String("cluster-name"),
+												Value: aws.String("Cluster-Name"),
 											},
 										},
 										PrivateDnsName: aws.String("ip-172-31-0-1.ec2.internal"),
@@ -289,6 +298,8 @@ func TestCollector_Collect(t *testing.T) {
 											AvailabilityZone: aws.String("us-east-1a"),
 										},
 										InstanceLifecycle: ec2Types.InstanceLifecycleTypeSpot,
+										LaunchTime:        aws.Time(time.Unix(1714521600, 0)),
+										State:             &ec2Types.InstanceState{Name: ec2Types.InstanceStateNameRunning},
 									},
 									{
 										InstanceId:   aws.String("i-1234567891abcdef0"),
@@ -319,6 +330,7 @@ func TestCollector_Collect(t *testing.T) {
 											AvailabilityZone: aws.String("us-east-1a"),
 										},
 										InstanceLifecycle: ec2Types.InstanceLifecycleTypeCapacityBlock,
+										State:             &ec2Types.InstanceState{Name: ec2Types.InstanceStateNameRunning},
 									},
 								},
 							},
@@ -335,11 +347,29 @@ func TestCollector_Collect(t *testing.T) {
 						},
 					}, nil
 				}).Times(1)
+		// The storage prices are listed once the pricing map is generated
+		ps.EXPECT().GetProducts(mock.Anything, mock.Anything, mock.Anything).
+			Return(&pricing.GetProductsOutput{
+				PriceList: []string{
+					`{"product":{"productFamily":"Storage","attributes":{"regionCode":"us-east-1","volumeApiName":"gp3"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.C":{"unit":"GB-Mo","pricePerUnit":{"USD":"0.0800000000"}}}}}}}`,
+				},
+			}, nil).Times(1)
 		regionClientMap := make(map[string]ec2client.EC2)
 		for _, r := range regions {
 			regionClientMap[*r.RegionName] = ec2s
 		}
-		collector := New("us-east-1", "", 0, ps, ec2s, regions, regionClientMap)
+		nodes := &fakeNodeLister{nodes: []kubernetes.Node{
+			{
+				Name:        "ip-172-31-0-1.ec2.internal",
+				Capacity:    kubernetes.Resources{Cpus: 8, MemoryGiB: 16},
+				Allocatable: kubernetes.Resources{Cpus: 7.91, MemoryGiB: 14.5},
+			},
+		}}
+		// A schedule covering the whole week is always in its off-hours, which only exports the actual cost until the
+		// cluster has been scraped during its on-hours
+		calendar, err := schedule.NewCalendar(map[string]string{"cluster-name": "Mon-Sun"}, nil, 0)
+		require.NoError(t, err)
+		collector := New(&Config{Region: "us-east-1", Regions: regions, ClusterNames: clustername.NewNormalizer(true, nil), Nodes: nodes, Calendar: calendar}, ps, ec2s, regionClientMap)
 
 		ch := make(chan prometheus.Metric)
 		go func() {
@@ -354,6 +384,209 @@ func TestCollector_Collect(t *testing.T) {
 			assert.NotNil(t, metric)
 			metrics = append(metrics, utils.ReadMetrics(metric))
 		}
-		assert.Len(t, metrics, 4)
+		// Two priced instances emit cpu and memory metrics, the instance with a launch time emits its creation timestamp,
+		// the instance that is a known node emits its allocatable costs, the cluster of that node emits its orphaned
+		// instances, priced instances emit their cost counters, the instance in a non-existent region emits an unpriced
+		// info metric followed by the pricing coverage, the scheduled cluster emits its actual cost and the region emits
+		// its scope status. The refresh interval and the storage prices are emitted first.
+		assert.Len(t, metrics, 16)
+		assert.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[0].FqName)
+		assert.Equal(t, utils.LabelMap{"provider": "aws", "collector": subsystem}, metrics[0].Labels)
+		metrics = metrics[1:]
+		storageClass := metrics[0]
+		assert.Equal(t, "cloudcost_aws_storage_class_usd_per_gib_hour", storageClass.FqName)
+		assert.Equal(t, utils.LabelMap{"storage_class": "gp3", "region": "us-east-1"}, storageClass.Labels)
+		assert.Greater(t, storageClass.Value, 0.0)
+		metrics = metrics[1:]
+		created := metrics[0]
+		assert.Equal(t, "cloudcost_aws_instance_created_timestamp_seconds", created.FqName)
+		assert.Equal(t, 1714521600.0, created.Value)
+		assert.Equal(t, utils.LabelMap{
+			"instance":     "ip-172-31-0-1.ec2.internal",
+			"region":       "us-east-1",
+			"machine_type": "c5ad.2xlarge",
+		}, created.Labels)
+		cpu := metrics[1]
+		assert.Equal(t, "cloudcost_aws_eks_instance_cpu_usd_per_core_hour", cpu.FqName)
+		assert.Equal(t, "Cluster-Name", cpu.Labels["cluster"])
+		assert.Equal(t, "cluster-name", cpu.Labels["cluster_name"], "cluster names should be normalized")
+		assert.Equal(t, "us-east-1", cpu.Labels["region"])
+		assert.Equal(t, "us-east-1a", cpu.Labels["zone"])
+		// The spot instance is priced at the spot price of its availability zone
+		assert.Equal(t, "", cpu.Labels["price_aggregation"])
+		allocatableCPU := metrics[3]
+		assert.Equal(t, "cloudcost_node_cpu_allocatable_usd_per_core_hour", allocatableCPU.FqName)
+		assert.InDelta(t, metrics[1].Value*8/7.91, allocatableCPU.Value, 1e-9)
+		assert.Equal(t, utils.LabelMap{
+			"node":         "ip-172-31-0-1.ec2.internal",
+			"cluster_name": "cluster-name",
+			"provider":     "aws",
+			"machine_type": "c5ad.2xlarge",
+			"price_tier":   "spot",
+		}, allocatableCPU.Labels)
+		assert.Equal(t, "cloudcost_node_memory_allocatable_usd_per_gib_hour", metrics[4].FqName)
+		assert.InDelta(t, metrics[2].Value*16/14.5, metrics[4].Value, 1e-9)
+		actual := metrics[len(metrics)-7]
+		assert.Equal(t, "cloudcost_cluster_actual_usd_per_hour", actual.FqName)
+		assert.Equal(t, utils.LabelMap{"cluster_name": "cluster-name", "provider": "aws", "period": "off_hours"}, actual.Labels)
+		assert.Greater(t, actual.Value, 0.0)
+		orphaned := metrics[len(metrics)-6]
+		assert.Equal(t, "cloudcost_cluster_orphaned_instances", orphaned.FqName)
+		// The running instance that isn't a node and has no launch time is orphaned
+		assert.Equal(t, 1.0, orphaned.Value)
+		assert.Equal(t, utils.LabelMap{"cluster_name": "cluster-name", "provider": "aws"}, orphaned.Labels)
+		for _, total := range metrics[len(metrics)-5 : len(metrics)-3] {
+			assert.Equal(t, prometheus.CounterValue, total.MetricType)
+			// The counters start at 0 the first time an instance is seen
+			assert.Equal(t, 0.0, total.Value)
+			assert.Equal(t, "cluster-name", total.Labels["cluster_name"])
+		}
+		unpriced := metrics[len(metrics)-3]
+		assert.Equal(t, "cloudcost_aws_unpriced_machine_type_info", unpriced.FqName)
+		assert.Equal(t, utils.LabelMap{
+			"collector":    subsystem,
+			"region":       "not-existen",
+			"machine_type": "c5ad.2xlarge",
+			"reason":       "region_not_found",
+		}, unpriced.Labels)
+		assert.Equal(t, &utils.MetricResult{
+			FqName:     "cloudcost_exporter_pricing_coverage_ratio",
+			Labels:     utils.LabelMap{"provider": "aws", "collector": subsystem},
+			Value:      2.0 / 3,
+			MetricType: prometheus.GaugeValue,
+		}, metrics[len(metrics)-2])
+		scope := metrics[len(metrics)-1]
+		assert.Equal(t, "cloudcost_exporter_collector_scope_last_scrape_error", scope.FqName)
+		assert.Equal(t, 0.0, scope.Value)
+		assert.Equal(t, utils.LabelMap{
+			"provider":  "aws",
+			"collector": subsystem,
+			"scope":     "us-east-1",
+		}, scope.Labels)
 	})
 }
+
+func TestCollector_cpuUtilization(t *testing.T) {
+	reservations := []ec2Types.Reservation{
+		{
+			Instances: []ec2Types.Instance{
+				{InstanceId: aws.String("i-1234567890abcdef0")},
+			},
+		},
+	}
+	tests := map[string]struct {
+		region        string
+		GetMetricData func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+		want          map[string]float64
+	}{
+		"no client for the region": {
+			region: "us-west-2",
+		},
+		"cloudwatch errors are swallowed": {
+			region: "us-east-1",
+			GetMetricData: func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+				return nil, assert.AnError
+			},
+		},
+		"utilization is keyed by instance id": {
+			region: "us-east-1",
+			GetMetricData: func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+				return &cloudwatch.GetMetricDataOutput{
+					MetricDataResults: []cloudwatchTypes.MetricDataResult{{Id: aws.String("q0"), Values: []float64{40}}},
+				}, nil
+			},
+			want: map[string]float64{"i-1234567890abcdef0": 0.4},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := mockcloudwatch.NewCloudWatch(t)
+			if tt.GetMetricData != nil {
+				client.EXPECT().GetMetricData(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(tt.GetMetricData).Times(1)
+			}
+			collector := New(&Config{Region: "us-east-1", CloudWatchRegionClients: map[string]cloudwatchclient.CloudWatch{"us-east-1": client}}, nil, nil, nil)
+			assert.Equal(t, tt.want, collector.cpuUtilization(tt.region, reservations))
+		})
+	}
+}
+
+type fakeNodeLister struct {
+	nodes []kubernetes.Node
+}
+
+func (f *fakeNodeLister) ListNodes(_ context.Context) ([]kubernetes.Node, error) {
+	return f.nodes, nil
+}
+
+func TestCollector_emitMetricsFromChannel_EmptyAvailabilityZone(t *testing.T) {
+	collector := New(&Config{}, nil, nil, nil)
+	instanceCh := make(chan regionInstances, 1)
+	instanceCh <- regionInstances{reservations: []ec2Types.Reservation{{
+		Instances: []ec2Types.Instance{{
+			InstanceId:     aws.String("i-1234"),
+			PrivateDnsName: aws.String("ip-10-0-0-1.ec2.internal"),
+			Placement:      &ec2Types.Placement{AvailabilityZone: aws.String("")},
+			Tags:           []ec2Types.Tag{{Key: aws.String("eks:cluster-name"), Value: aws.String("cluster")}},
+		}},
+	}}}
+	close(instanceCh)
+	ch := make(chan prometheus.Metric, 10)
+	// An instance without an availability zone is skipped rather than panicking on trimming it
+	assert.NotPanics(t, func() { collector.emitMetricsFromChannel(instanceCh, nil, nil, ch) })
+	close(ch)
+	for metric := range ch {
+		assert.NotEqual(t, InstanceCPUHourlyCostDesc, metric.Desc())
+	}
+}
+
+func TestCollector_Collect_RegionPricingError(t *testing.T) {
+	regions := []ec2Types.Region{{RegionName: aws.String("us-east-1")}, {RegionName: aws.String("eu-west-1")}}
+	ps := mockpricing.NewPricing(t)
+	// The on-demand and storage prices of both regions
+	ps.EXPECT().GetProducts(mock.Anything, mock.Anything, mock.Anything).
+		Return(&pricing.GetProductsOutput{PriceList: []string{
+			`{"product":{"attributes":{"instanceType":"c5ad.2xlarge","regionCode":"us-east-1","vcpu":"8","memory":"16 GiB"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.C":{"unit":"Hrs","pricePerUnit":{"USD":"0.4680000000"}}}}}}}`,
+		}}, nil).Times(4)
+	healthy := mockec2.NewEC2(t)
+	healthy.EXPECT().DescribeSpotPriceHistory(mock.Anything, mock.Anything, mock.Anything).
+		Return(&ec2.DescribeSpotPriceHistoryOutput{}, nil).Times(1)
+	healthy.EXPECT().DescribeInstances(mock.Anything, mock.Anything, mock.Anything).
+		Return(&ec2.DescribeInstancesOutput{}, nil).Times(1)
+	failing := mockec2.NewEC2(t)
+	failing.EXPECT().DescribeSpotPriceHistory(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, assert.AnError).Times(1)
+	failing.EXPECT().DescribeInstances(mock.Anything, mock.Anything, mock.Anything).
+		Return(&ec2.DescribeInstancesOutput{}, nil).Times(1)
+	regionClientMap := map[string]ec2client.EC2{"us-east-1": healthy, "eu-west-1": failing}
+	collector := New(&Config{Region: "us-east-1", Regions: regions}, ps, nil, regionClientMap)
+	errors := compute.PricingRegionErrorsTotal.WithLabelValues(subsystem, "eu-west-1")
+	before := testutil.ToFloat64(errors)
+
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, collector.Collect(ch))
+	close(ch)
+	assert.Equal(t, 1.0, testutil.ToFloat64(errors)-before)
+	_, err := collector.pricingMap.GetPriceForInstanceType("us-east-1", "c5ad.2xlarge")
+	assert.NoError(t, err)
+}
+
+func TestIsSpotEviction(t *testing.T) {
+	interrupted := &ec2Types.StateReason{Code: aws.String(spotInstanceTerminationCode)}
+	tests := map[string]struct {
+		instance ec2Types.Instance
+		want     bool
+	}{
+		"interrupted spot instance": {instance: ec2Types.Instance{InstanceLifecycle: ec2Types.InstanceLifecycleTypeSpot, StateReason: interrupted}, want: true},
+		"running spot instance":     {instance: ec2Types.Instance{InstanceLifecycle: ec2Types.InstanceLifecycleTypeSpot}, want: false},
+		"spot instance terminated by the user": {instance: ec2Types.Instance{
+			InstanceLifecycle: ec2Types.InstanceLifecycleTypeSpot,
+			StateReason:       &ec2Types.StateReason{Code: aws.String("Client.UserInitiatedShutdown")},
+		}, want: false},
+		"on-demand instance": {instance: ec2Types.Instance{StateReason: interrupted}, want: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSpotEviction(tt.instance))
+		})
+	}
+}
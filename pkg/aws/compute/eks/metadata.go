@@ -0,0 +1,152 @@
+package eks
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsEks "github.com/aws/aws-sdk-go-v2/service/eks"
+
+	eksclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/eks"
+)
+
+// nodegroupTag is set by EKS on every instance that is part of a managed nodegroup.
+const nodegroupTag = "eks:nodegroup-name"
+
+type clusterKey struct {
+	region  string
+	cluster string
+}
+
+type nodegroupKey struct {
+	region    string
+	cluster   string
+	nodegroup string
+}
+
+// clusterMetadata caches the responses of eks:DescribeCluster and eks:DescribeNodegroup so that the EKS API is
+// only called once per cluster and nodegroup between pricing map refreshes.
+// Lookups that fail are cached as empty values so that a missing permission doesn't result in an API call per instance.
+// The lock only guards the caches and isn't held across the API calls, so that a slow call doesn't block the lookups
+// of other clusters. Concurrent misses of the same key may call the API twice, which is harmless.
+type clusterMetadata struct {
+	mu               sync.Mutex
+	clients          map[string]eksclient.EKS
+	versions         map[clusterKey]string
+	nodegroupCapType map[nodegroupKey]string
+}
+
+func newClusterMetadata(clients map[string]eksclient.EKS) *clusterMetadata {
+	return &clusterMetadata{
+		clients:          clients,
+		versions:         make(map[clusterKey]string),
+		nodegroupCapType: make(map[nodegroupKey]string),
+	}
+}
+
+// addClient adds the client of a region discovered after startup. It's a no-op when enrichment is disabled.
+func (m *clusterMetadata) addClient(region string, client eksclient.EKS) {
+	if m == nil || m.clients == nil || client == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[region] = client
+}
+
+// client returns the client of a region, nil when enrichment is disabled or the region has no client. The clients are
+// read under the lock as addClient may add one concurrently.
+func (m *clusterMetadata) client(region string) eksclient.EKS {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.clients[region]
+}
+
+// reset drops all cached responses so that they are fetched again on the next lookup.
+func (m *clusterMetadata) reset() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions = make(map[clusterKey]string)
+	m.nodegroupCapType = make(map[nodegroupKey]string)
+}
+
+// kubernetesVersion returns the Kubernetes version of a cluster, eg "1.29".
+// An empty string is returned when enrichment is disabled or the cluster can't be described.
+func (m *clusterMetadata) kubernetesVersion(ctx context.Context, region string, cluster string) string {
+	if m == nil {
+		return ""
+	}
+	client := m.client(region)
+	if client == nil {
+		return ""
+	}
+	key := clusterKey{region: region, cluster: cluster}
+	m.mu.Lock()
+	version, ok := m.versions[key]
+	m.mu.Unlock()
+	if ok {
+		return version
+	}
+	resp, err := client.DescribeCluster(ctx, &awsEks.DescribeClusterInput{Name: aws.String(cluster)})
+	if err != nil {
+		log.Printf("error describing cluster %s in region %s: %s", cluster, region, err)
+	} else if resp.Cluster != nil && resp.Cluster.Version != nil {
+		version = *resp.Cluster.Version
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[key] = version
+	return version
+}
+
+// capacityType returns the capacity type declared by the nodegroup an instance belongs to, either ON_DEMAND or SPOT.
+// The declared capacity type is useful to reconcile instances whose lifecycle is missing from the ec2 metadata.
+// An empty string is returned when enrichment is disabled, the instance isn't part of a managed nodegroup
+// or the nodegroup can't be described.
+func (m *clusterMetadata) capacityType(ctx context.Context, region string, cluster string, instance ec2Types.Instance) string {
+	if m == nil {
+		return ""
+	}
+	client := m.client(region)
+	if client == nil {
+		return ""
+	}
+	nodegroup := nodegroupFromInstance(instance)
+	if nodegroup == "" {
+		return ""
+	}
+	key := nodegroupKey{region: region, cluster: cluster, nodegroup: nodegroup}
+	m.mu.Lock()
+	capacityType, ok := m.nodegroupCapType[key]
+	m.mu.Unlock()
+	if ok {
+		return capacityType
+	}
+	resp, err := client.DescribeNodegroup(ctx, &awsEks.DescribeNodegroupInput{
+		ClusterName:   aws.String(cluster),
+		NodegroupName: aws.String(nodegroup),
+	})
+	if err != nil {
+		log.Printf("error describing nodegroup %s of cluster %s in region %s: %s", nodegroup, cluster, region, err)
+	} else if resp.Nodegroup != nil {
+		capacityType = string(resp.Nodegroup.CapacityType)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodegroupCapType[key] = capacityType
+	return capacityType
+}
+
+func nodegroupFromInstance(instance ec2Types.Instance) string {
+	for _, tag := range instance.Tags {
+		if tag.Key != nil && *tag.Key == nodegroupTag && tag.Value != nil {
+			return *tag.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package eks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsEks "github.com/aws/aws-sdk-go-v2/service/eks"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockeks "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/eks"
+	eksclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/eks"
+)
+
+func TestClusterMetadata_KubernetesVersion(t *testing.T) {
+	tests := map[string]struct {
+		region   string
+		version  *string
+		err      error
+		expected string
+	}{
+		"no client for region": {
+			region:   "eu-west-1",
+			expected: "",
+		},
+		"cluster version": {
+			region:   "us-east-1",
+			version:  aws.String("1.29"),
+			expected: "1.29",
+		},
+		"describe cluster fails": {
+			region:   "us-east-1",
+			err:      errors.New("access denied"),
+			expected: "",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := mockeks.NewEKS(t)
+			if tt.region == "us-east-1" {
+				// The response is cached, so the API must only be called once for repeated lookups
+				client.EXPECT().DescribeCluster(mock.Anything, mock.Anything).
+					Return(&awsEks.DescribeClusterOutput{Cluster: &eksTypes.Cluster{Version: tt.version}}, tt.err).
+					Times(1)
+			}
+			m := newClusterMetadata(map[string]eksclient.EKS{"us-east-1": client})
+			assert.Equal(t, tt.expected, m.kubernetesVersion(context.Background(), tt.region, "cluster"))
+			assert.Equal(t, tt.expected, m.kubernetesVersion(context.Background(), tt.region, "cluster"))
+		})
+	}
+}
+
+func TestClusterMetadata_CapacityType(t *testing.T) {
+	nodegroupInstance := ec2Types.Instance{
+		Tags: []ec2Types.Tag{
+			{Key: aws.String(nodegroupTag), Value: aws.String("spot-pool")},
+		},
+	}
+	tests := map[string]struct {
+		instance     ec2Types.Instance
+		callsAPI     bool
+		capacityType eksTypes.CapacityTypes
+		expected     string
+	}{
+		"instance outside of a managed nodegroup": {
+			instance: ec2Types.Instance{},
+			expected: "",
+		},
+		"spot nodegroup": {
+			instance:     nodegroupInstance,
+			callsAPI:     true,
+			capacityType: eksTypes.CapacityTypesSpot,
+			expected:     "SPOT",
+		},
+		"on demand nodegroup": {
+			instance:     nodegroupInstance,
+			callsAPI:     true,
+			capacityType: eksTypes.CapacityTypesOnDemand,
+			expected:     "ON_DEMAND",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := mockeks.NewEKS(t)
+			if tt.callsAPI {
+				client.EXPECT().DescribeNodegroup(mock.Anything, mock.Anything).
+					Return(&awsEks.DescribeNodegroupOutput{Nodegroup: &eksTypes.Nodegroup{CapacityType: tt.capacityType}}, nil).
+					Times(1)
+			}
+			m := newClusterMetadata(map[string]eksclient.EKS{"us-east-1": client})
+			assert.Equal(t, tt.expected, m.capacityType(context.Background(), "us-east-1", "cluster", tt.instance))
+			assert.Equal(t, tt.expected, m.capacityType(context.Background(), "us-east-1", "cluster", tt.instance))
+		})
+	}
+}
+
+func TestClusterMetadata_Disabled(t *testing.T) {
+	var m *clusterMetadata
+	m.reset()
+	assert.Equal(t, "", m.kubernetesVersion(context.Background(), "us-east-1", "cluster"))
+	assert.Equal(t, "", newClusterMetadata(nil).capacityType(context.Background(), "us-east-1", "cluster", ec2Types.Instance{}))
+}
+
+func TestClusterMetadata_LookupsDontWaitOnOtherClusters(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	client := mockeks.NewEKS(t)
+	client.EXPECT().DescribeCluster(mock.Anything, &awsEks.DescribeClusterInput{Name: aws.String("slow")}).
+		RunAndReturn(func(_ context.Context, _ *awsEks.DescribeClusterInput, _ ...func(*awsEks.Options)) (*awsEks.DescribeClusterOutput, error) {
+			close(started)
+			<-release
+			return &awsEks.DescribeClusterOutput{Cluster: &eksTypes.Cluster{Version: aws.String("1.28")}}, nil
+		}).Times(1)
+	client.EXPECT().DescribeCluster(mock.Anything, &awsEks.DescribeClusterInput{Name: aws.String("fast")}).
+		Return(&awsEks.DescribeClusterOutput{Cluster: &eksTypes.Cluster{Version: aws.String("1.29")}}, nil).
+		Times(1)
+	m := newClusterMetadata(map[string]eksclient.EKS{"us-east-1": client})
+
+	slow := make(chan string)
+	go func() {
+		slow <- m.kubernetesVersion(context.Background(), "us-east-1", "slow")
+	}()
+	<-started
+	// The lookup of another cluster must not wait for the pending call
+	assert.Equal(t, "1.29", m.kubernetesVersion(context.Background(), "us-east-1", "fast"))
+	close(release)
+	assert.Equal(t, "1.28", <-slow)
+}
+
+func TestClusterMetadata_AddClientDuringLookups(t *testing.T) {
+	client := mockeks.NewEKS(t)
+	client.EXPECT().DescribeCluster(mock.Anything, mock.Anything).
+		Return(&awsEks.DescribeClusterOutput{Cluster: &eksTypes.Cluster{Version: aws.String("1.29")}}, nil).
+		Maybe()
+	m := newClusterMetadata(map[string]eksclient.EKS{})
+
+	// Run with -race to catch unguarded reads of the clients
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.addClient("us-east-1", client)
+	}()
+	go func() {
+		defer wg.Done()
+		m.kubernetesVersion(context.Background(), "us-east-1", "cluster")
+		m.capacityType(context.Background(), "us-east-1", "cluster", ec2Types.Instance{})
+	}()
+	wg.Wait()
+	assert.Equal(t, "1.29", m.kubernetesVersion(context.Background(), "us-east-1", "cluster"))
+}
@@ -0,0 +1,62 @@
+package eks
+
+import (
+	"context"
+	"log"
+
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
+	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	eksclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/eks"
+)
+
+// RegionClients are the clients of a single region. EKS and CloudWatch are nil when cluster metadata and idle costs
+// are disabled.
+type RegionClients struct {
+	EC2        ec2client.EC2
+	EKS        eksclient.EKS
+	CloudWatch cloudwatchclient.CloudWatch
+}
+
+// RegionDiscovery lists the regions of the account again every time the pricing map is refreshed, so that the regions
+// enabled for the account after startup are collected without a restart.
+type RegionDiscovery struct {
+	// Filter selects the regions that are collected, every enabled region is when it's nil.
+	Filter *compute.RegionFilter
+	// NewClients creates the clients of a region that wasn't enabled at startup.
+	NewClients func(region string) (RegionClients, error)
+}
+
+// refreshRegions replaces the regions of the collector with the regions currently enabled for the account and creates
+// the clients of the new ones. The known regions are kept when the regions can't be listed.
+func (c *Collector) refreshRegions(ctx context.Context) {
+	if c.regionDiscovery == nil || c.ec2Client == nil {
+		return
+	}
+	regions, err := compute.ListRegions(ctx, c.ec2Client, c.regionDiscovery.Filter)
+	if err != nil {
+		log.Printf("error listing regions, keeping the %d known regions: %s", len(c.Regions), err)
+		return
+	}
+	var enabled []ec2Types.Region
+	for _, region := range regions {
+		name := *region.RegionName
+		if _, ok := c.ec2RegionClient[name]; !ok {
+			clients, err := c.regionDiscovery.NewClients(name)
+			if err != nil {
+				log.Printf("error creating the clients of region %s: %s", name, err)
+				continue
+			}
+			c.ec2RegionClient[name] = clients.EC2
+			if c.cloudwatchRegionClient != nil && clients.CloudWatch != nil {
+				c.cloudwatchRegionClient[name] = clients.CloudWatch
+			}
+			c.metadata.addClient(name, clients.EKS)
+			log.Printf("discovered region %s", name)
+		}
+		enabled = append(enabled, region)
+	}
+	c.Regions = enabled
+}
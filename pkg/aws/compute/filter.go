@@ -0,0 +1,114 @@
+package compute
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const nameTag = "Name"
+
+// InstanceFilter selects the instances that are listed, eg to skip the VMs of a shared account that aren't part of a
+// cluster. Every selector is optional and they are combined with a logical AND. Inclusions are passed to
+// DescribeInstances as filters so that the instances they exclude are never listed. The API has no negation, so
+// exclusions are applied once the instances are listed.
+//
+// Tag values and name patterns support the wildcards of the EC2 API, * matches any sequence of characters and ? any
+// single character.
+type InstanceFilter struct {
+	// EKSOnly only lists instances tagged with the name of an EKS cluster.
+	EKSOnly bool
+	// Tags only lists instances with every tag, keyed by tag key.
+	Tags map[string]string
+	// ExcludeTags drops the instances with any of the tags, keyed by tag key.
+	ExcludeTags map[string]string
+	// VPCIDs only lists the instances of one of the VPCs.
+	VPCIDs []string
+	// Names only lists the instances whose Name tag matches one of the patterns.
+	Names []string
+	// ExcludeNames drops the instances whose Name tag matches any of the patterns.
+	ExcludeNames []string
+}
+
+// ParseTagSelectors parses tag selectors such as "team=platform" or "karpenter.sh/nodepool", which matches any value,
+// into a map keyed by tag key. Tag keys are case-sensitive, so they are kept as is.
+func ParseTagSelectors(selectors []string) (map[string]string, error) {
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(selectors))
+	for _, selector := range selectors {
+		key, value, ok := strings.Cut(selector, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid tag selector %q, expected key or key=value", selector)
+		}
+		if !ok {
+			value = "*"
+		}
+		tags[key] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// filters returns the DescribeInstances filters of the inclusions. It's safe to call on a nil filter.
+func (f *InstanceFilter) filters() []types.Filter {
+	if f == nil {
+		return nil
+	}
+	var filters []types.Filter
+	if f.EKSOnly {
+		filters = append(filters, types.Filter{Name: aws.String("tag-key"), Values: clusterTags})
+	}
+	// Sorted to send the same filters on every call
+	keys := make([]string, 0, len(f.Tags))
+	for key := range f.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filters = append(filters, types.Filter{Name: aws.String("tag:" + key), Values: []string{f.Tags[key]}})
+	}
+	if len(f.VPCIDs) > 0 {
+		filters = append(filters, types.Filter{Name: aws.String("vpc-id"), Values: f.VPCIDs})
+	}
+	if len(f.Names) > 0 {
+		filters = append(filters, types.Filter{Name: aws.String("tag:" + nameTag), Values: f.Names})
+	}
+	return filters
+}
+
+// Excludes reports whether instance matches one of the exclusions. It's safe to call on a nil filter.
+func (f *InstanceFilter) Excludes(instance types.Instance) bool {
+	if f == nil {
+		return false
+	}
+	for _, tag := range instance.Tags {
+		key, value := aws.ToString(tag.Key), aws.ToString(tag.Value)
+		if pattern, ok := f.ExcludeTags[key]; ok && matchWildcard(pattern, value) {
+			return true
+		}
+		if key != nameTag {
+			continue
+		}
+		for _, pattern := range f.ExcludeNames {
+			if matchWildcard(pattern, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchWildcard matches value against a pattern with the wildcards of the EC2 API.
+func matchWildcard(pattern string, value string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+expr+"$", value)
+	return matched
+}
@@ -0,0 +1,170 @@
+package compute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	ec22 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ec2"
+)
+
+func TestParseTagSelectors(t *testing.T) {
+	tests := map[string]struct {
+		selectors []string
+		want      map[string]string
+		wantErr   bool
+	}{
+		"no selectors": {},
+		"key and value": {
+			selectors: []string{"team=platform", "Env = prod"},
+			want:      map[string]string{"team": "platform", "Env": "prod"},
+		},
+		"key only matches any value": {
+			selectors: []string{"karpenter.sh/nodepool"},
+			want:      map[string]string{"karpenter.sh/nodepool": "*"},
+		},
+		"empty key": {
+			selectors: []string{"=platform"},
+			wantErr:   true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTagSelectors(tt.selectors)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInstanceFilter_filters(t *testing.T) {
+	tests := map[string]struct {
+		filter *InstanceFilter
+		want   []types.Filter
+	}{
+		"nil filter": {},
+		"exclusions only": {
+			filter: &InstanceFilter{ExcludeTags: map[string]string{"team": "data"}, ExcludeNames: []string{"bastion"}},
+		},
+		"every inclusion": {
+			filter: &InstanceFilter{
+				EKSOnly: true,
+				Tags:    map[string]string{"team": "platform", "env": "prod*"},
+				VPCIDs:  []string{"vpc-1", "vpc-2"},
+				Names:   []string{"eks-*"},
+			},
+			want: []types.Filter{
+				{Name: aws.String("tag-key"), Values: clusterTags},
+				{Name: aws.String("tag:env"), Values: []string{"prod*"}},
+				{Name: aws.String("tag:team"), Values: []string{"platform"}},
+				{Name: aws.String("vpc-id"), Values: []string{"vpc-1", "vpc-2"}},
+				{Name: aws.String("tag:Name"), Values: []string{"eks-*"}},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.filters())
+		})
+	}
+}
+
+func TestInstanceFilter_Excludes(t *testing.T) {
+	filter := &InstanceFilter{
+		ExcludeTags:  map[string]string{"team": "data-*", "ephemeral": "*"},
+		ExcludeNames: []string{"bastion-?"},
+	}
+	tests := map[string]struct {
+		filter *InstanceFilter
+		tags   map[string]string
+		want   bool
+	}{
+		"nil filter": {
+			tags: map[string]string{"team": "data-platform"},
+		},
+		"no tags": {
+			filter: filter,
+		},
+		"tag value matches": {
+			filter: filter,
+			tags:   map[string]string{"team": "data-platform"},
+			want:   true,
+		},
+		"tag value doesn't match": {
+			filter: filter,
+			tags:   map[string]string{"team": "platform"},
+		},
+		"tag key only": {
+			filter: filter,
+			tags:   map[string]string{"ephemeral": ""},
+			want:   true,
+		},
+		"tag keys are case-sensitive": {
+			filter: filter,
+			tags:   map[string]string{"Team": "data-platform"},
+		},
+		"name matches": {
+			filter: filter,
+			tags:   map[string]string{"Name": "bastion-1"},
+			want:   true,
+		},
+		"name doesn't match": {
+			filter: filter,
+			tags:   map[string]string{"Name": "bastion-10"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var instance types.Instance
+			for key, value := range tt.tags {
+				instance.Tags = append(instance.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+			}
+			assert.Equal(t, tt.want, tt.filter.Excludes(instance))
+		})
+	}
+}
+
+func TestListComputeInstances_filter(t *testing.T) {
+	filter := &InstanceFilter{
+		VPCIDs:      []string{"vpc-1"},
+		ExcludeTags: map[string]string{"team": "data"},
+	}
+	client := ec22.NewEC2(t)
+	client.EXPECT().
+		DescribeInstances(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, e *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			assert.Equal(t, []types.Filter{{Name: aws.String("vpc-id"), Values: []string{"vpc-1"}}}, e.Filters)
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []types.Reservation{
+					{
+						Instances: []types.Instance{
+							{InstanceId: aws.String("i-1"), Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}}},
+							{InstanceId: aws.String("i-2"), Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("data")}}},
+						},
+					},
+					{
+						Instances: []types.Instance{
+							{InstanceId: aws.String("i-3"), Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("data")}}},
+						},
+					},
+				},
+			}, nil
+		}).
+		Times(1)
+
+	got, err := ListComputeInstances(context.Background(), client, filter)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Len(t, got[0].Instances, 1)
+	assert.Equal(t, "i-1", *got[0].Instances[0].InstanceId)
+}
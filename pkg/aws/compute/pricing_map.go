@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,13 +18,41 @@ import (
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
 	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/prometheus/client_golang/prometheus"
 
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
 	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
 	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	pricelookup "github.com/grafana/cloudcost-exporter/pkg/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
 	defaultInstanceFamily = "General purpose"
+
+	priceSourceOnDemand = "ondemand"
+	priceSourceSpot     = "spot"
+
+	// locationTypeLocalZone and locationTypeWavelengthZone are the location types of the offers of the instances
+	// launched in a Local Zone or a Wavelength Zone, which are priced separately from their parent region.
+	locationTypeLocalZone      = "AWS Local Zone"
+	locationTypeWavelengthZone = "AWS Wavelength Zone"
+
+	// hourlyUnit is the unit of the ondemand prices of the instances.
+	hourlyUnit = "Hrs"
+	// maxInstanceHourlyPrice is the highest sane price of an instance in USD/h, well above the price of the largest
+	// instances, so that a price read in the wrong unit is rejected rather than exported.
+	maxInstanceHourlyPrice = 1000.0
+)
+
+// Aggregations of the spot prices of the availability zones of a region into the spot price of the region, see
+// StructuredPricingMap.SpotAggregation.
+const (
+	// SpotAggregationMin is the price of the cheapest availability zone.
+	SpotAggregationMin = "min"
+	// SpotAggregationAvg is the average price of the availability zones.
+	SpotAggregationAvg = "avg"
 )
 
 var (
@@ -32,6 +62,27 @@ var (
 	ErrInstanceTypeNotFound      = errors.New("no instance type found")
 	ErrListSpotPrices            = errors.New("error listing spot prices")
 	ErrListOnDemandPrices        = errors.New("error listing ondemand prices")
+	ErrMalformedPrice            = errors.New("malformed price entry")
+	ErrNoValidPrices             = errors.New("no valid ondemand prices found")
+	ErrUnknownLocation           = errors.New("unknown pricing location")
+	ErrUnknownSpotAggregation    = errors.New("unknown spot price aggregation")
+)
+
+var (
+	// MalformedPriceEntriesTotal counts price entries that were skipped while generating the pricing map because they
+	// didn't match the expected structure, unit or currency, or their price isn't sane. A single malformed entry only
+	// drops the price of that entry. It's shared across the ec2 and eks collectors and registered once by the aws
+	// provider.
+	MalformedPriceEntriesTotal = utils.NewMalformedPriceEntriesTotal("aws")
+	// PricingRegionErrorsTotal counts the regions whose prices couldn't be listed while refreshing the pricing map.
+	// The pricing map is still refreshed from the other regions, so the instances of a failing region are unpriced
+	// until the next refresh. It's registered once by the aws provider.
+	PricingRegionErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "aws", "pricing_region_errors_total"),
+		Help: "Total number of regions whose prices could not be listed while refreshing the pricing map.",
+	},
+		[]string{"collector", "region"},
+	)
 )
 
 // cpuToCostRatio was generated by analysing Grafana Labs spend in GCP and finding the ratio of CPU to Memory spend by instance type.
@@ -43,6 +94,23 @@ var cpuToCostRatio = map[string]float64{
 	"Storage optimized": 0.48,
 }
 
+// acceleratorToCostRatio is the share of the price of an instance with accelerators, eg GPUs or Inferentia and Trainium
+// chips, that is attributed to the accelerators rather than to the CPU and RAM. Like cpuToCostRatio it's an
+// approximation.
+var acceleratorToCostRatio = map[string]float64{
+	"GPU instance":                    0.7,
+	"Machine Learning ASIC Instances": 0.75,
+}
+
+const defaultAcceleratorToCostRatio = 0.7
+
+// Accelerator types of the instances with accelerators, see AcceleratorType.
+const (
+	AcceleratorTypeGPU        = "gpu"
+	AcceleratorTypeInferentia = "inferentia"
+	AcceleratorTypeTrainium   = "trainium"
+)
+
 // StructuredPricingMap collects a map of FamilyPricing structs where the key is the region
 type StructuredPricingMap struct {
 	// Regions is a map of region code to FamilyPricing
@@ -50,7 +118,16 @@ type StructuredPricingMap struct {
 	// value is a map of instance type to PriceTiers
 	Regions         map[string]*FamilyPricing
 	InstanceDetails map[string]Attributes
-	m               sync.RWMutex
+	// priceTiers is the price tier of every key of Regions filled by GeneratePricingMap, either ondemand for the
+	// regions or spot for the availability zones.
+	priceTiers map[string]string
+	// SpotAggregation is how GeneratePricingMap aggregates the spot prices of the availability zones of a region into
+	// the spot price of the region, SpotAggregationMin when empty.
+	SpotAggregation string
+	// spotRegions is the spot price of every instance type of a region aggregated from its availability zones, see
+	// GetSpotPrice.
+	spotRegions map[string]*FamilyPricing
+	m           sync.RWMutex
 }
 
 // FamilyPricing is a map of instance type to a list of PriceTiers where the key is the ec2 compute instance type
@@ -63,12 +140,16 @@ type Prices struct {
 	Cpu   float64
 	Ram   float64
 	Total float64
+	// Accelerator is the price of each accelerator of the instance, it's zero for instances without accelerators.
+	Accelerator float64
 }
 
 func NewStructuredPricingMap() *StructuredPricingMap {
 	return &StructuredPricingMap{
 		Regions:         make(map[string]*FamilyPricing),
 		InstanceDetails: make(map[string]Attributes),
+		priceTiers:      make(map[string]string),
+		spotRegions:     make(map[string]*FamilyPricing),
 		m:               sync.RWMutex{},
 	}
 }
@@ -77,33 +158,77 @@ func NewStructuredPricingMap() *StructuredPricingMap {
 // The method needs to
 // 1. Parse out the ondemand prices and generate a productTerm map for each instance type
 // 2. Parse out spot prices and use the productTerm map to generate a spot price map
+// 3. Aggregate the spot prices of the availability zones of every region into the spot price of the region
+// Malformed entries are skipped and counted in MalformedPriceEntriesTotal. ErrNoValidPrices is only returned when
+// none of the ondemand prices could be parsed, as the pricing map would be empty otherwise.
 func (spm *StructuredPricingMap) GeneratePricingMap(ondemandPrices []string, spotPrices []ec2Types.SpotPrice) error {
+	parsed := 0
 	for _, product := range ondemandPrices {
 		var productInfo productTerm
 		if err := json.Unmarshal([]byte(product), &productInfo); err != nil {
-			return err
+			log.Printf("error parsing ondemand price entry: %s, skipping", err)
+			MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, "invalid_json").Inc()
+			continue
 		}
+		parsed++
 		if productInfo.Product.Attributes.InstanceType == "" {
 			// If there are no instance types, let's just continue on. This is the most important key
 			continue
 		}
+		location, err := productInfo.Product.Attributes.PricingLocation()
+		if err != nil {
+			// Keying the offer by its parent region would price the instances of the region at the edge price
+			log.Printf("error getting the location of instance type %s: %s, skipping", productInfo.Product.Attributes.InstanceType, err)
+			MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, "unknown_location").Inc()
+			continue
+		}
+		productInfo.Product.Attributes.Region = location
 		for _, term := range productInfo.Terms.OnDemand {
 			for _, priceDimension := range term.PriceDimensions {
-				price, err := strconv.ParseFloat(priceDimension.PricePerUnit["USD"], 64)
+				usd, ok := priceDimension.PricePerUnit["USD"]
+				if !ok && len(priceDimension.PricePerUnit) > 0 {
+					log.Printf("price of instance type %s isn't in USD, skipping", productInfo.Product.Attributes.InstanceType)
+					MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, utils.ReasonUnexpectedCurrency).Inc()
+					continue
+				}
+				if priceDimension.Unit != "" && priceDimension.Unit != hourlyUnit {
+					log.Printf("price of instance type %s is per %s rather than per hour, skipping", productInfo.Product.Attributes.InstanceType, priceDimension.Unit)
+					MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, utils.ReasonUnexpectedUnit).Inc()
+					continue
+				}
+				price, err := parsePrice(usd)
 				if err != nil {
 					log.Printf("error parsing price: %s, skipping", err)
+					MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, "invalid_price").Inc()
+					continue
+				}
+				if !utils.SanePrice(price, maxInstanceHourlyPrice) {
+					log.Printf("price %f of instance type %s isn't sane, skipping", price, productInfo.Product.Attributes.InstanceType)
+					MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, utils.ReasonOutlier).Inc()
 					continue
 				}
 				err = spm.AddToPricingMap(price, productInfo.Product.Attributes)
 				if err != nil {
 					log.Printf("error adding to pricing map: %s", err)
+					if errors.Is(err, ErrParseAttributes) {
+						MalformedPriceEntriesTotal.WithLabelValues(priceSourceOnDemand, "invalid_attributes").Inc()
+					}
 					continue
 				}
 				spm.AddInstanceDetails(productInfo.Product.Attributes)
+				spm.setPriceTier(productInfo.Product.Attributes.Region, priceSourceOnDemand)
 			}
 		}
 	}
+	if len(ondemandPrices) > 0 && parsed == 0 {
+		return fmt.Errorf("%w: all %d entries are malformed", ErrNoValidPrices, len(ondemandPrices))
+	}
 	for _, spotPrice := range spotPrices {
+		if spotPrice.AvailabilityZone == nil || spotPrice.SpotPrice == nil {
+			log.Printf("spot price for instance type %s is missing its availability zone or price, skipping", spotPrice.InstanceType)
+			MalformedPriceEntriesTotal.WithLabelValues(priceSourceSpot, "missing_field").Inc()
+			continue
+		}
 		region := *spotPrice.AvailabilityZone
 		instanceType := string(spotPrice.InstanceType)
 		if _, ok := spm.InstanceDetails[instanceType]; !ok {
@@ -113,9 +238,15 @@ func (spm *StructuredPricingMap) GeneratePricingMap(ondemandPrices []string, spo
 		spotProductTerm := spm.InstanceDetails[instanceType]
 		// Override the region with the availability zone
 		spotProductTerm.Region = region
-		price, err := strconv.ParseFloat(*spotPrice.SpotPrice, 64)
+		price, err := parsePrice(*spotPrice.SpotPrice)
 		if err != nil {
 			log.Printf("error parsing spot price: %s, skipping", err)
+			MalformedPriceEntriesTotal.WithLabelValues(priceSourceSpot, "invalid_price").Inc()
+			continue
+		}
+		if !utils.SanePrice(price, maxInstanceHourlyPrice) {
+			log.Printf("spot price %f of instance type %s isn't sane, skipping", price, instanceType)
+			MalformedPriceEntriesTotal.WithLabelValues(priceSourceSpot, utils.ReasonOutlier).Inc()
 			continue
 		}
 		err = spm.AddToPricingMap(price, spotProductTerm)
@@ -123,10 +254,101 @@ func (spm *StructuredPricingMap) GeneratePricingMap(ondemandPrices []string, spo
 			log.Printf("error adding to pricing map: %s", err)
 			continue
 		}
+		spm.setPriceTier(region, priceSourceSpot)
 	}
+	spm.aggregateSpotPrices()
 	return nil
 }
 
+// ValidateSpotAggregation returns ErrUnknownSpotAggregation when aggregation isn't empty, SpotAggregationMin or
+// SpotAggregationAvg.
+func ValidateSpotAggregation(aggregation string) error {
+	switch aggregation {
+	case "", SpotAggregationMin, SpotAggregationAvg:
+		return nil
+	}
+	return fmt.Errorf("%w %q, must be %s or %s", ErrUnknownSpotAggregation, aggregation, SpotAggregationMin, SpotAggregationAvg)
+}
+
+func (spm *StructuredPricingMap) spotAggregation() string {
+	if spm.SpotAggregation == "" {
+		return SpotAggregationMin
+	}
+	return spm.SpotAggregation
+}
+
+// aggregateSpotPrices fills spotRegions with the spot prices of the availability zones aggregated by region. The
+// availability zones of a Local Zone are aggregated into the Local Zone, and the Wavelength Zones, which have a single
+// zone, aren't aggregated.
+func (spm *StructuredPricingMap) aggregateSpotPrices() {
+	spm.m.Lock()
+	defer spm.m.Unlock()
+	var zones []string
+	for location := range spm.Regions {
+		if spm.priceTiers[location] == priceSourceSpot && PricingLocation(location) != location {
+			zones = append(zones, location)
+		}
+	}
+	// The zones are sorted so that the cheapest zone and the sums of the average don't depend on the order of the map
+	sort.Strings(zones)
+	zonePrices := make(map[string]map[string][]*Prices)
+	for _, zone := range zones {
+		region := PricingLocation(zone)
+		if zonePrices[region] == nil {
+			zonePrices[region] = make(map[string][]*Prices)
+		}
+		for instanceType, price := range spm.Regions[zone].Family {
+			zonePrices[region][instanceType] = append(zonePrices[region][instanceType], price)
+		}
+	}
+	spm.spotRegions = make(map[string]*FamilyPricing, len(zonePrices))
+	for region, instanceTypes := range zonePrices {
+		family := &FamilyPricing{Family: make(map[string]*Prices, len(instanceTypes))}
+		for instanceType, prices := range instanceTypes {
+			family.Family[instanceType] = aggregatePrices(prices, spm.spotAggregation())
+		}
+		spm.spotRegions[region] = family
+	}
+}
+
+func aggregatePrices(prices []*Prices, aggregation string) *Prices {
+	if aggregation == SpotAggregationAvg {
+		avg := &Prices{}
+		for _, price := range prices {
+			avg.Cpu += price.Cpu / float64(len(prices))
+			avg.Ram += price.Ram / float64(len(prices))
+			avg.Total += price.Total / float64(len(prices))
+			avg.Accelerator += price.Accelerator / float64(len(prices))
+		}
+		return avg
+	}
+	cheapest := prices[0]
+	for _, price := range prices[1:] {
+		if price.Total < cheapest.Total {
+			cheapest = price
+		}
+	}
+	return cheapest
+}
+
+func (spm *StructuredPricingMap) setPriceTier(region string, priceTier string) {
+	spm.m.Lock()
+	defer spm.m.Unlock()
+	spm.priceTiers[region] = priceTier
+}
+
+// parsePrice parses a price in USD. Only finite, non-negative prices are valid.
+func parsePrice(price string) (float64, error) {
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrMalformedPrice, err)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		return 0, fmt.Errorf("%w: %q is not a valid price", ErrMalformedPrice, price)
+	}
+	return value, nil
+}
+
 // AddToPricingMap adds a price to the pricing map. The price is weighted based upon the instance type's CPU and RAM.
 func (spm *StructuredPricingMap) AddToPricingMap(price float64, attribute Attributes) error {
 	spm.m.Lock()
@@ -145,9 +367,10 @@ func (spm *StructuredPricingMap) AddToPricingMap(price float64, attribute Attrib
 		return err
 	}
 	spm.Regions[attribute.Region].Family[attribute.InstanceType] = &Prices{
-		Cpu:   weightedPrice.Cpu,
-		Ram:   weightedPrice.Ram,
-		Total: price,
+		Cpu:         weightedPrice.Cpu,
+		Ram:         weightedPrice.Ram,
+		Total:       price,
+		Accelerator: weightedPrice.Accelerator,
 	}
 	return nil
 }
@@ -172,18 +395,38 @@ func weightedPriceForInstance(price float64, attributes Attributes) (*Prices, er
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrParseAttributes, err)
 	}
+	// Dividing by a non-positive or non-finite amount of cpus or memory would poison the pricing map with Inf or NaN prices
+	if !isPositiveFinite(cpus) || !isPositiveFinite(ram) {
+		return nil, fmt.Errorf("%w: vcpu %q and memory %q must be positive", ErrParseAttributes, attributes.VCPU, attributes.Memory)
+	}
 	ratio, ok := cpuToCostRatio[attributes.InstanceFamily]
 	if !ok {
 		log.Printf("no ratio found for instance type %s, defaulting to %s", attributes.InstanceType, defaultInstanceFamily)
 		ratio = cpuToCostRatio[defaultInstanceFamily]
 	}
 
+	// The accelerators take their share of the price first, the rest is split between the CPU and RAM
+	acceleratorPrice := 0.0
+	if accelerators := attributes.Accelerators(); accelerators > 0 {
+		acceleratorRatio, ok := acceleratorToCostRatio[attributes.InstanceFamily]
+		if !ok {
+			acceleratorRatio = defaultAcceleratorToCostRatio
+		}
+		acceleratorPrice = price * acceleratorRatio / accelerators
+		price *= 1 - acceleratorRatio
+	}
+
 	return &Prices{
-		Cpu: price * ratio / cpus,
-		Ram: price * (1 - ratio) / ram,
+		Cpu:         price * ratio / cpus,
+		Ram:         price * (1 - ratio) / ram,
+		Accelerator: acceleratorPrice,
 	}, nil
 }
 
+func isPositiveFinite(value float64) bool {
+	return value > 0 && !math.IsInf(value, 0)
+}
+
 func (spm *StructuredPricingMap) GetPriceForInstanceType(region string, instanceType string) (*Prices, error) {
 	spm.m.RLock()
 	defer spm.m.RUnlock()
@@ -197,6 +440,51 @@ func (spm *StructuredPricingMap) GetPriceForInstanceType(region string, instance
 	return spm.Regions[region].Family[instanceType], nil
 }
 
+// GetSpotPrice returns the spot price of an instance type in location, an availability zone or a region. The spot
+// prices are keyed by availability zone, so a location without a spot price of the instance type, eg a region or an
+// availability zone whose price didn't change over the last hour, is priced at the spot price of its region aggregated
+// by SpotAggregation. The aggregation is returned along with the price, and is empty for the price of an availability
+// zone.
+func (spm *StructuredPricingMap) GetSpotPrice(location string, instanceType string) (*Prices, string, error) {
+	spm.m.RLock()
+	defer spm.m.RUnlock()
+	if spm.priceTiers[location] == priceSourceSpot {
+		if price := spm.Regions[location].Family[instanceType]; price != nil {
+			return price, "", nil
+		}
+	}
+	family, ok := spm.spotRegions[PricingLocation(location)]
+	if !ok {
+		return nil, "", ErrRegionNotFound
+	}
+	price := family.Family[instanceType]
+	if price == nil {
+		return nil, "", ErrInstanceTypeNotFound
+	}
+	return price, spm.spotAggregation(), nil
+}
+
+// PriceForInstance returns the price of an instance type in region at tier, which makes the pricing map a
+// pricing.Source. On-demand prices are keyed by region, and spot prices are looked up with GetSpotPrice.
+func (spm *StructuredPricingMap) PriceForInstance(region string, instanceType string, tier string) (pricelookup.Price, error) {
+	var price *Prices
+	var err error
+	if tier == pricelookup.TierSpot {
+		price, _, err = spm.GetSpotPrice(region, instanceType)
+	} else {
+		price, err = spm.GetPriceForInstanceType(region, instanceType)
+	}
+	if err != nil {
+		return pricelookup.Price{}, fmt.Errorf("%w: %w", pricelookup.ErrPriceNotFound, err)
+	}
+	return pricelookup.Price{
+		USDPerHour:            price.Total,
+		USDPerCoreHour:        price.Cpu,
+		USDPerGiBHour:         price.Ram,
+		USDPerAcceleratorHour: price.Accelerator,
+	}, nil
+}
+
 // Attributes represents ec2 instance attributes that are pulled from AWS api's describing instances.
 // It's specifically pulled out of productTerm to enable usage during tests.
 type Attributes struct {
@@ -211,6 +499,60 @@ type Attributes struct {
 	OperatingSystem   string `json:"operatingSystem"`
 	ClockSpeed        string `json:"clockSpeed"`
 	UsageType         string `json:"usageType"`
+	// LocationType is AWS Region for the offers of a region, and AWS Local Zone or AWS Wavelength Zone for the offers
+	// of the edge zones of the region, see PricingLocation.
+	LocationType string `json:"locationType"`
+	// GPU is the number of accelerators of the instance type, which covers the Inferentia and Trainium chips of the
+	// inf and trn families as well. It's NA for instance types without accelerators.
+	GPU string `json:"gpu"`
+}
+
+// PricingLocation returns the key of the offer in the pricing map, the region for the offers of a region and the zone
+// group of the edge zone for the offers of a Local Zone or a Wavelength Zone, which share the region code of their
+// parent region. The zone group is encoded in the usage type between the region prefix and the usage, eg
+// USE1-BOS1-BoxUsage:c5.large is the us-east-1-bos-1 Local Zone and USE1-WL1-BOS-WLZ1-BoxUsage:t3.medium the
+// us-east-1-wl1-bos-wlz-1 Wavelength Zone.
+func (a Attributes) PricingLocation() (string, error) {
+	if a.LocationType != locationTypeLocalZone && a.LocationType != locationTypeWavelengthZone {
+		return a.Region, nil
+	}
+	// The region code already names the zone group
+	if RegionFromAvailabilityZone(a.Region) != a.Region {
+		return a.Region, nil
+	}
+	segments := strings.Split(a.UsageType, "-")
+	if a.Region == "" || len(segments) < 3 {
+		return "", fmt.Errorf("%w: %s usage type %q of region %q", ErrUnknownLocation, a.LocationType, a.UsageType, a.Region)
+	}
+	zone := strings.ToLower(strings.Join(segments[1:len(segments)-1], "-"))
+	// The number of the zone group is separated from its name, eg bos1 is bos-1
+	if i := strings.LastIndexFunc(zone, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 && i < len(zone)-1 && zone[i] != '-' {
+		zone = zone[:i+1] + "-" + zone[i+1:]
+	}
+	return a.Region + "-" + zone, nil
+}
+
+// Accelerators returns the number of accelerators of the instance type, zero when it has none or the count can't be
+// parsed.
+func (a Attributes) Accelerators() float64 {
+	accelerators, err := strconv.ParseFloat(a.GPU, 64)
+	if err != nil || !isPositiveFinite(accelerators) {
+		return 0
+	}
+	return accelerators
+}
+
+// AcceleratorType returns the kind of accelerators of an instance type, Inferentia for the inf families, Trainium for
+// the trn families and GPU otherwise.
+func AcceleratorType(instanceType string) string {
+	switch {
+	case strings.HasPrefix(instanceType, "inf"):
+		return AcceleratorTypeInferentia
+	case strings.HasPrefix(instanceType, "trn"):
+		return AcceleratorTypeTrainium
+	default:
+		return AcceleratorTypeGPU
+	}
 }
 
 // productTerm represents the nested json response returned by the AWS pricing API.
@@ -221,13 +563,17 @@ type productTerm struct {
 	Terms struct {
 		OnDemand map[string]struct {
 			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
 				PricePerUnit map[string]string `json:"pricePerUnit"`
 			}
 		}
 	}
 }
 
-func ListOnDemandPrices(ctx context.Context, region string, client pricingClient.Pricing) ([]string, error) {
+// ListOnDemandPrices lists the on-demand prices of the Linux instances of a region. families is optional, GetProducts
+// only matches exact attribute values, so the prices of the families it drops are dropped from every page as it's
+// listed rather than parsed along with the pricing map.
+func ListOnDemandPrices(ctx context.Context, region string, client pricingClient.Pricing, families *pricelookup.FamilyFilter) ([]string, error) {
 	var productOutputs []string
 	input := &pricing.GetProductsInput{
 		ServiceCode: aws.String("AmazonEC2"),
@@ -287,7 +633,11 @@ func ListOnDemandPrices(ctx context.Context, region string, client pricingClient
 			break
 		}
 
-		productOutputs = append(productOutputs, products.PriceList...)
+		for _, product := range products.PriceList {
+			if keepProduct(product, families) {
+				productOutputs = append(productOutputs, product)
+			}
+		}
 		if products.NextToken == nil {
 			break
 		}
@@ -296,7 +646,35 @@ func ListOnDemandPrices(ctx context.Context, region string, client pricingClient
 	return productOutputs, nil
 }
 
-func ListSpotPrices(ctx context.Context, client ec2client.EC2) ([]ec2Types.SpotPrice, error) {
+// keepProduct reports whether families keeps the price list entry of an instance type. Entries that can't be decoded
+// are kept so that GeneratePricingMap counts them as malformed.
+func keepProduct(product string, families *pricelookup.FamilyFilter) bool {
+	if families == nil {
+		return true
+	}
+	var entry struct {
+		Product struct {
+			Attributes struct {
+				InstanceType string `json:"instanceType"`
+			} `json:"attributes"`
+		} `json:"product"`
+	}
+	if err := json.Unmarshal([]byte(product), &entry); err != nil {
+		return true
+	}
+	instanceType := entry.Product.Attributes.InstanceType
+	return families.Matches(instanceFamily(instanceType), instanceType)
+}
+
+// instanceFamily returns the family of an instance type, m5 for m5.xlarge.
+func instanceFamily(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	return family
+}
+
+// ListSpotPrices lists the spot prices of the Linux instances of the last hour. families is optional, the prices of the
+// families it drops are skipped, as their on-demand prices aren't listed either.
+func ListSpotPrices(ctx context.Context, client ec2client.EC2, families *pricelookup.FamilyFilter) ([]ec2Types.SpotPrice, error) {
 	var spotPrices []ec2Types.SpotPrice
 	startTime := time.Now().Add(-time.Hour)
 	endTime := time.Now()
@@ -314,7 +692,12 @@ func ListSpotPrices(ctx context.Context, client ec2client.EC2) ([]ec2Types.SpotP
 			// If there's an error, return the set of processed spotPrices and the error.
 			return spotPrices, err
 		}
-		spotPrices = append(spotPrices, resp.SpotPriceHistory...)
+		for _, spotPrice := range resp.SpotPriceHistory {
+			instanceType := string(spotPrice.InstanceType)
+			if families.Matches(instanceFamily(instanceType), instanceType) {
+				spotPrices = append(spotPrices, spotPrice)
+			}
+		}
 		if resp.NextToken == nil || *resp.NextToken == "" {
 			break
 		}
@@ -322,3 +705,21 @@ func ListSpotPrices(ctx context.Context, client ec2client.EC2) ([]ec2Types.SpotP
 	}
 	return spotPrices, nil
 }
+
+// HistoryPrices returns the total hourly price of every instance type of the map, with the price tier recorded by
+// GeneratePricingMap. Prices added by other means are recorded as on-demand.
+func (spm *StructuredPricingMap) HistoryPrices() []pricehistory.Price {
+	spm.m.RLock()
+	defer spm.m.RUnlock()
+	var prices []pricehistory.Price
+	for region, family := range spm.Regions {
+		priceTier, ok := spm.priceTiers[region]
+		if !ok {
+			priceTier = priceSourceOnDemand
+		}
+		for instanceType, price := range family.Family {
+			prices = append(prices, pricehistory.Price{Region: region, SKU: instanceType, PriceTier: priceTier, Unit: "usd_per_hour", Value: price.Total})
+		}
+	}
+	return prices
+}
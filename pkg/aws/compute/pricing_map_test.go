@@ -1,17 +1,28 @@
 package compute
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	ec22 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ec2"
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	pricelookup "github.com/grafana/cloudcost-exporter/pkg/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 func TestStructuredPricingMap_AddToPricingMap(t *testing.T) {
@@ -60,7 +71,8 @@ func TestStructuredPricingMap_AddToPricingMap(t *testing.T) {
 				err := tt.spm.AddToPricingMap(tt.Prices[i], attr)
 				assert.NoError(t, err)
 			}
-			assert.Equal(t, tt.want, tt.spm)
+			assert.Equal(t, tt.want.Regions, tt.spm.Regions)
+			assert.Equal(t, tt.want.InstanceDetails, tt.spm.InstanceDetails)
 		})
 	}
 }
@@ -70,8 +82,27 @@ func TestStructuredPricingMap_GeneratePricingMap(t *testing.T) {
 		smp        *StructuredPricingMap
 		prices     []string
 		spotPrices []ec2Types.SpotPrice
+		malformed  float64
+		err        error
 		want       *StructuredPricingMap
 	}{
+		"Only malformed prices should return an error": {
+			smp:       NewStructuredPricingMap(),
+			prices:    []string{"Unparsable String into json", `{"terms":[]}`},
+			malformed: 2,
+			err:       ErrNoValidPrices,
+		},
+		"Prices with an unexpected unit, currency or value should be rejected": {
+			smp: NewStructuredPricingMap(),
+			prices: []string{
+				`{"product":{"productFamily":"Compute Instance","attributes":{"regionCode":"us-east-1","instanceType":"m5.large","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"SKU.JRTCKXETXF":{"priceDimensions":{"SKU.JRTCKXETXF.6YS6EN2CT7":{"unit":"Quantity","pricePerUnit":{"USD":"0.096"}}}}}}}`,
+				`{"product":{"productFamily":"Compute Instance","attributes":{"regionCode":"us-east-1","instanceType":"m5.large","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"SKU.JRTCKXETXF":{"priceDimensions":{"SKU.JRTCKXETXF.6YS6EN2CT7":{"unit":"Hrs","pricePerUnit":{"CNY":"0.7"}}}}}}}`,
+				`{"product":{"productFamily":"Compute Instance","attributes":{"regionCode":"us-east-1","instanceType":"m5.large","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"SKU.JRTCKXETXF":{"priceDimensions":{"SKU.JRTCKXETXF.6YS6EN2CT7":{"unit":"Hrs","pricePerUnit":{"USD":"0"}}}}}}}`,
+				`{"product":{"productFamily":"Compute Instance","attributes":{"regionCode":"us-east-1","instanceType":"m5.large","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"SKU.JRTCKXETXF":{"priceDimensions":{"SKU.JRTCKXETXF.6YS6EN2CT7":{"unit":"Hrs","pricePerUnit":{"USD":"5000"}}}}}}}`,
+			},
+			malformed: 4,
+			want:      NewStructuredPricingMap(),
+		},
 		"No prices input": {
 			smp:        NewStructuredPricingMap(),
 			prices:     []string{},
@@ -109,6 +140,87 @@ func TestStructuredPricingMap_GeneratePricingMap(t *testing.T) {
 						OperatingSystem:   "Linux",
 						ClockSpeed:        "3.3 GHz",
 						UsageType:         "AFS1-UnusedBox:c5ad.2xlarge",
+						LocationType:      "AWS Region",
+					},
+				},
+			},
+		},
+		"Malformed entries are skipped": {
+			smp: NewStructuredPricingMap(),
+			prices: []string{
+				"Unparsable String into json",
+				`{"product":{"attributes":{"instanceType":"c5ad.2xlarge"}},"terms":{"OnDemand":["unexpected"]}}`,
+				`{"product":{"attributes":{"instanceType":"m5.large","regionCode":"af-south-1","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"term":{"priceDimensions":{"dimension":{"pricePerUnit":{"USD":"NaN"}}}}}}}`,
+				`{"product":{"attributes":{"instanceType":"m5.xlarge","regionCode":"af-south-1","vcpu":"0","memory":"16 GiB"}},"terms":{"OnDemand":{"term":{"priceDimensions":{"dimension":{"pricePerUnit":{"USD":"0.2"}}}}}}}`,
+				`{"product":{"productFamily":"Compute Instance","attributes":{"enhancedNetworkingSupported":"Yes","intelTurboAvailable":"No","memory":"16 GiB","dedicatedEbsThroughput":"Up to 3170 Mbps","vcpu":"8","classicnetworkingsupport":"false","capacitystatus":"UnusedCapacityReservation","locationType":"AWS Region","storage":"1 x 300 NVMe SSD","instanceFamily":"Compute optimized","operatingSystem":"Linux","intelAvx2Available":"No","regionCode":"af-south-1","physicalProcessor":"AMD EPYC 7R32","clockSpeed":"3.3 GHz","ecu":"NA","networkPerformance":"Up to 10 Gigabit","servicename":"Amazon Elastic Compute Cloud","instancesku":"Q7GDF95MM7MZ7Y5Q","gpuMemory":"NA","vpcnetworkingsupport":"true","instanceType":"c5ad.2xlarge","tenancy":"Shared","usagetype":"AFS1-UnusedBox:c5ad.2xlarge","normalizationSizeFactor":"16","intelAvxAvailable":"No","processorFeatures":"AMD Turbo; AVX; AVX2","servicecode":"AmazonEC2","licenseModel":"No License required","currentGeneration":"Yes","preInstalledSw":"NA","location":"Africa (Cape Town)","processorArchitecture":"64-bit","marketoption":"OnDemand","operation":"RunInstances","availabilityzone":"NA"},"sku":"2257YY4K7BWZ4F46"},"serviceCode":"AmazonEC2","terms":{"OnDemand":{"2257YY4K7BWZ4F46.JRTCKXETXF":{"priceDimensions":{"2257YY4K7BWZ4F46.JRTCKXETXF.6YS6EN2CT7":{"unit":"Hrs","endRange":"Inf","description":"$0.468 per Unused Reservation Linux c5ad.2xlarge Instance Hour","appliesTo":[],"rateCode":"2257YY4K7BWZ4F46.JRTCKXETXF.6YS6EN2CT7","beginRange":"0","pricePerUnit":{"USD":"0.4680000000"}}},"sku":"2257YY4K7BWZ4F46","effectiveDate":"2024-04-01T00:00:00Z","offerTermCode":"JRTCKXETXF","termAttributes":{}}}},"version":"20240508191027","publicationDate":"2024-05-08T19:10:27Z"}`,
+			},
+			spotPrices: []ec2Types.SpotPrice{
+				{
+					InstanceType: ec2Types.InstanceTypeC5ad2xlarge,
+					SpotPrice:    aws.String("0.4680000000"),
+				},
+			},
+			malformed: 5,
+			want: &StructuredPricingMap{
+				Regions: map[string]*FamilyPricing{
+					"af-south-1": {
+						Family: map[string]*Prices{
+							"c5ad.2xlarge": {
+								Cpu:   0.051480000000000005,
+								Ram:   0.00351,
+								Total: 0.4680000000,
+							},
+						},
+					},
+				},
+				InstanceDetails: map[string]Attributes{
+					"c5ad.2xlarge": {
+						Region:            "af-south-1",
+						InstanceType:      "c5ad.2xlarge",
+						VCPU:              "8",
+						Memory:            "16 GiB",
+						InstanceFamily:    "Compute optimized",
+						PhysicalProcessor: "AMD EPYC 7R32",
+						Tenancy:           "Shared",
+						MarketOption:      "OnDemand",
+						OperatingSystem:   "Linux",
+						ClockSpeed:        "3.3 GHz",
+						UsageType:         "AFS1-UnusedBox:c5ad.2xlarge",
+						LocationType:      "AWS Region",
+					},
+				},
+			},
+		},
+		"Local Zone prices are keyed by their zone group": {
+			smp: NewStructuredPricingMap(),
+			prices: []string{
+				`{"product":{"attributes":{"instanceType":"c5.large","regionCode":"us-east-1","locationType":"AWS Region","usagetype":"BoxUsage:c5.large","vcpu":"2","memory":"4 GiB","instanceFamily":"Compute optimized"}},"terms":{"OnDemand":{"term":{"priceDimensions":{"dimension":{"pricePerUnit":{"USD":"0.085"}}}}}}}`,
+				`{"product":{"attributes":{"instanceType":"c5.large","regionCode":"us-east-1","locationType":"AWS Local Zone","usagetype":"USE1-BOS1-BoxUsage:c5.large","vcpu":"2","memory":"4 GiB","instanceFamily":"Compute optimized"}},"terms":{"OnDemand":{"term":{"priceDimensions":{"dimension":{"pricePerUnit":{"USD":"0.102"}}}}}}}`,
+				`{"product":{"attributes":{"instanceType":"c5.large","regionCode":"us-east-1","locationType":"AWS Wavelength Zone","usagetype":"BoxUsage:c5.large","vcpu":"2","memory":"4 GiB","instanceFamily":"Compute optimized"}},"terms":{"OnDemand":{"term":{"priceDimensions":{"dimension":{"pricePerUnit":{"USD":"0.2"}}}}}}}`,
+			},
+			malformed: 1,
+			want: &StructuredPricingMap{
+				Regions: map[string]*FamilyPricing{
+					"us-east-1": {
+						Family: map[string]*Prices{
+							"c5.large": {Cpu: 0.0374, Ram: 0.00255, Total: 0.085},
+						},
+					},
+					"us-east-1-bos-1": {
+						Family: map[string]*Prices{
+							"c5.large": {Cpu: 0.044879999999999996, Ram: 0.00306, Total: 0.102},
+						},
+					},
+				},
+				InstanceDetails: map[string]Attributes{
+					"c5.large": {
+						Region:         "us-east-1",
+						InstanceType:   "c5.large",
+						VCPU:           "2",
+						Memory:         "4 GiB",
+						InstanceFamily: "Compute optimized",
+						UsageType:      "BoxUsage:c5.large",
+						LocationType:   "AWS Region",
 					},
 				},
 			},
@@ -159,6 +271,7 @@ func TestStructuredPricingMap_GeneratePricingMap(t *testing.T) {
 						OperatingSystem:   "Linux",
 						ClockSpeed:        "3.3 GHz",
 						UsageType:         "AFS1-UnusedBox:c5ad.2xlarge",
+						LocationType:      "AWS Region",
 					},
 				},
 			},
@@ -166,9 +279,16 @@ func TestStructuredPricingMap_GeneratePricingMap(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
+			before := malformedPriceEntries()
 			err := tt.smp.GeneratePricingMap(tt.prices, tt.spotPrices)
+			assert.Equal(t, tt.malformed, malformedPriceEntries()-before)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
 			assert.NoError(t, err)
-			assert.Equal(t, tt.want, tt.smp)
+			assert.Equal(t, tt.want.Regions, tt.smp.Regions)
+			assert.Equal(t, tt.want.InstanceDetails, tt.smp.InstanceDetails)
 		})
 	}
 }
@@ -245,6 +365,14 @@ func Test_weightedPriceForInstance(t *testing.T) {
 			attributes: Attributes{},
 			err:        ErrParseAttributes,
 		},
+		"No cpus should return a parse error": {
+			price: 0.65,
+			attributes: Attributes{
+				VCPU:   "0",
+				Memory: "1 GiB",
+			},
+			err: ErrParseAttributes,
+		},
 		"No memory should return a parse error": {
 			price: 0.65,
 			attributes: Attributes{
@@ -323,6 +451,33 @@ func Test_weightedPriceForInstance(t *testing.T) {
 				Ram: 0.35,
 			},
 		},
+		"Accelerators take their share before the cpu and memory": {
+			price: 4.0,
+			attributes: Attributes{
+				VCPU:           "1",
+				Memory:         "1 GiB",
+				InstanceFamily: "Machine Learning ASIC Instances",
+				GPU:            "2",
+			},
+			want: &Prices{
+				Cpu:         0.65,
+				Ram:         0.35,
+				Accelerator: 1.5,
+			},
+		},
+		"No accelerators": {
+			price: 1.0,
+			attributes: Attributes{
+				VCPU:           "1",
+				Memory:         "1 GiB",
+				InstanceFamily: "General purpose",
+				GPU:            "NA",
+			},
+			want: &Prices{
+				Cpu: 0.65,
+				Ram: 0.35,
+			},
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -341,6 +496,7 @@ func TestListSpotPrices(t *testing.T) {
 	tests := map[string]struct {
 		ctx                      context.Context
 		DescribeSpotPriceHistory func(ctx context.Context, input *ec2.DescribeSpotPriceHistoryInput, optFns ...func(options *ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error)
+		families                 *pricelookup.FamilyFilter
 		err                      error
 		want                     []ec2Types.SpotPrice
 		expectedCalls            int
@@ -377,6 +533,34 @@ func TestListSpotPrices(t *testing.T) {
 			},
 			expectedCalls: 1,
 		},
+		"Excluded families should be dropped": {
+			ctx: context.Background(),
+			DescribeSpotPriceHistory: func(ctx context.Context, input *ec2.DescribeSpotPriceHistoryInput, optFns ...func(options *ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error) {
+				return &ec2.DescribeSpotPriceHistoryOutput{
+					SpotPriceHistory: []ec2Types.SpotPrice{
+						{
+							AvailabilityZone: aws.String("us-east-1a"),
+							InstanceType:     ec2Types.InstanceTypeC5ad2xlarge,
+							SpotPrice:        aws.String("0.4680000000"),
+						},
+						{
+							AvailabilityZone: aws.String("us-east-1a"),
+							InstanceType:     ec2Types.InstanceTypeP4d24xlarge,
+							SpotPrice:        aws.String("9.8300000000"),
+						},
+					},
+				}, nil
+			},
+			families: &pricelookup.FamilyFilter{Exclude: []string{"p4d"}},
+			want: []ec2Types.SpotPrice{
+				{
+					AvailabilityZone: aws.String("us-east-1a"),
+					InstanceType:     ec2Types.InstanceTypeC5ad2xlarge,
+					SpotPrice:        aws.String("0.4680000000"),
+				},
+			},
+			expectedCalls: 1,
+		},
 		"Ensure errors propagate": {
 			ctx: context.Background(),
 			DescribeSpotPriceHistory: func(ctx context.Context, input *ec2.DescribeSpotPriceHistoryInput, optFns ...func(options *ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error) {
@@ -435,7 +619,7 @@ func TestListSpotPrices(t *testing.T) {
 				RunAndReturn(tt.DescribeSpotPriceHistory).
 				Times(tt.expectedCalls)
 
-			got, err := ListSpotPrices(tt.ctx, client)
+			got, err := ListSpotPrices(tt.ctx, client, tt.families)
 			if tt.err != nil {
 				assert.Equal(t, tt.err, err)
 			}
@@ -443,3 +627,330 @@ func TestListSpotPrices(t *testing.T) {
 		})
 	}
 }
+
+func TestListOnDemandPrices_families(t *testing.T) {
+	product := func(instanceType string) string {
+		return fmt.Sprintf(`{"product":{"attributes":{"instanceType":%q}}}`, instanceType)
+	}
+	client := mockpricing.NewPricing(t)
+	client.EXPECT().
+		GetProducts(mock.Anything, mock.Anything, mock.Anything).
+		Return(&pricing.GetProductsOutput{
+			PriceList: []string{product("m5.large"), product("m5.metal"), product("p4d.24xlarge"), "not json"},
+		}, nil).
+		Once()
+
+	got, err := ListOnDemandPrices(context.Background(), "us-east-1", client, &pricelookup.FamilyFilter{
+		Include: []string{"m5", "p4d"},
+		Exclude: []string{"*.metal", "p4d.24xlarge"},
+	})
+	require.NoError(t, err)
+	// Entries that can't be decoded are kept so that they're counted as malformed
+	assert.Equal(t, []string{product("m5.large"), "not json"}, got)
+}
+
+// malformedPriceEntries sums MalformedPriceEntriesTotal across all of its label values.
+func malformedPriceEntries() float64 {
+	ch := make(chan prometheus.Metric, 32)
+	MalformedPriceEntriesTotal.Collect(ch)
+	close(ch)
+	total := 0.0
+	for metric := range ch {
+		total += utils.ReadMetrics(metric).Value
+	}
+	return total
+}
+
+// FuzzGeneratePricingMap ensures that no offer, however malformed, causes a panic or ends up as an invalid price in the map.
+// The corpus is seeded with the offers in testdata/ondemand_offers.jsonl, one offer per line.
+func FuzzGeneratePricingMap(f *testing.F) {
+	file, err := os.Open("testdata/ondemand_offers.jsonl")
+	require.NoError(f, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		f.Add(scanner.Text(), "0.1")
+	}
+	require.NoError(f, scanner.Err())
+
+	f.Fuzz(func(t *testing.T, offer string, spotPrice string) {
+		spm := NewStructuredPricingMap()
+		spotPrices := []ec2Types.SpotPrice{
+			{
+				AvailabilityZone: aws.String("us-east-1a"),
+				InstanceType:     ec2Types.InstanceTypeM5Large,
+				SpotPrice:        aws.String(spotPrice),
+			},
+		}
+		err := spm.GeneratePricingMap([]string{offer}, spotPrices)
+		if err != nil {
+			require.ErrorIs(t, err, ErrNoValidPrices)
+		}
+		for region, family := range spm.Regions {
+			for instanceType, price := range family.Family {
+				for _, value := range []float64{price.Cpu, price.Ram, price.Total} {
+					if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+						t.Fatalf("invalid price %v for %s in %s", value, instanceType, region)
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestStructuredPricingMap_HistoryPrices(t *testing.T) {
+	spm := NewStructuredPricingMap()
+	require.NoError(t, spm.GeneratePricingMap(
+		[]string{`{"product":{"attributes":{"instanceType":"m5.large","regionCode":"us-east-1","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.C":{"pricePerUnit":{"USD":"0.096"}}}}}}}`},
+		[]ec2Types.SpotPrice{
+			{AvailabilityZone: aws.String("us-east-1a"), InstanceType: "m5.large", SpotPrice: aws.String("0.04")},
+			// The zones of Wavelength end with a digit, like regions
+			{AvailabilityZone: aws.String("us-east-1-wl1-bos-wlz-1"), InstanceType: "m5.large", SpotPrice: aws.String("0.05")},
+		},
+	))
+
+	assert.ElementsMatch(t, []pricehistory.Price{
+		{Region: "us-east-1", SKU: "m5.large", PriceTier: "ondemand", Unit: "usd_per_hour", Value: 0.096},
+		{Region: "us-east-1a", SKU: "m5.large", PriceTier: "spot", Unit: "usd_per_hour", Value: 0.04},
+		{Region: "us-east-1-wl1-bos-wlz-1", SKU: "m5.large", PriceTier: "spot", Unit: "usd_per_hour", Value: 0.05},
+	}, spm.HistoryPrices())
+}
+
+func TestStructuredPricingMap_PriceForInstance(t *testing.T) {
+	spm := NewStructuredPricingMap()
+	require.NoError(t, spm.GeneratePricingMap(
+		[]string{`{"product":{"attributes":{"instanceType":"m5.large","regionCode":"us-east-1","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.C":{"pricePerUnit":{"USD":"0.096"}}}}}}}`},
+		[]ec2Types.SpotPrice{
+			{AvailabilityZone: aws.String("us-east-1a"), InstanceType: "m5.large", SpotPrice: aws.String("0.04")},
+			{AvailabilityZone: aws.String("us-east-1b"), InstanceType: "m5.large", SpotPrice: aws.String("0.03")},
+			// The Local Zones of the region aren't availability zones of it
+			{AvailabilityZone: aws.String("us-east-1-bos-1a"), InstanceType: "m5.large", SpotPrice: aws.String("0.01")},
+		},
+	))
+
+	tests := map[string]struct {
+		region  string
+		tier    string
+		want    float64
+		wantErr error
+	}{
+		"on-demand": {
+			region: "us-east-1",
+			tier:   pricelookup.TierOnDemand,
+			want:   0.096,
+		},
+		"spot of the cheapest availability zone": {
+			region: "us-east-1",
+			tier:   pricelookup.TierSpot,
+			want:   0.03,
+		},
+		"spot of an availability zone": {
+			region: "us-east-1a",
+			tier:   pricelookup.TierSpot,
+			want:   0.04,
+		},
+		"region without prices": {
+			region:  "eu-west-1",
+			tier:    pricelookup.TierOnDemand,
+			wantErr: pricelookup.ErrPriceNotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			price, err := spm.PriceForInstance(tt.region, "m5.large", tt.tier)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, price.USDPerHour, 1e-9)
+			assert.InDelta(t, tt.want, price.USDPerCoreHour*2+price.USDPerGiBHour*8, 1e-9)
+		})
+	}
+}
+
+func TestStructuredPricingMap_GetSpotPrice(t *testing.T) {
+	ondemand := []string{
+		`{"product":{"attributes":{"instanceType":"m5.large","regionCode":"us-east-1","vcpu":"2","memory":"8 GiB"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.C":{"pricePerUnit":{"USD":"0.096"}}}}}}}`,
+	}
+	spot := []ec2Types.SpotPrice{
+		{AvailabilityZone: aws.String("us-east-1a"), InstanceType: "m5.large", SpotPrice: aws.String("0.04")},
+		{AvailabilityZone: aws.String("us-east-1b"), InstanceType: "m5.large", SpotPrice: aws.String("0.03")},
+		{AvailabilityZone: aws.String("us-east-1c"), InstanceType: "m5.large", SpotPrice: aws.String("0.05")},
+		// The Local Zones are aggregated on their own, and the Wavelength Zones aren't
+		{AvailabilityZone: aws.String("us-east-1-bos-1a"), InstanceType: "m5.large", SpotPrice: aws.String("0.01")},
+		{AvailabilityZone: aws.String("us-east-1-wl1-bos-wlz-1"), InstanceType: "m5.large", SpotPrice: aws.String("0.02")},
+	}
+	tests := map[string]struct {
+		aggregation     string
+		location        string
+		want            float64
+		wantAggregation string
+		wantErr         error
+	}{
+		"availability zone": {
+			location: "us-east-1a",
+			want:     0.04,
+		},
+		"region at the minimum by default": {
+			location:        "us-east-1",
+			want:            0.03,
+			wantAggregation: SpotAggregationMin,
+		},
+		"region at the average": {
+			aggregation:     SpotAggregationAvg,
+			location:        "us-east-1",
+			want:            0.04,
+			wantAggregation: SpotAggregationAvg,
+		},
+		"availability zone without a spot price": {
+			aggregation:     SpotAggregationAvg,
+			location:        "us-east-1d",
+			want:            0.04,
+			wantAggregation: SpotAggregationAvg,
+		},
+		"local zone": {
+			location:        "us-east-1-bos-1",
+			want:            0.01,
+			wantAggregation: SpotAggregationMin,
+		},
+		"wavelength zone": {
+			location: "us-east-1-wl1-bos-wlz-1",
+			want:     0.02,
+		},
+		"region without spot prices": {
+			location: "eu-west-1",
+			wantErr:  ErrRegionNotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			spm := NewStructuredPricingMap()
+			spm.SpotAggregation = tt.aggregation
+			require.NoError(t, spm.GeneratePricingMap(ondemand, spot))
+
+			price, aggregation, err := spm.GetSpotPrice(tt.location, "m5.large")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAggregation, aggregation)
+			assert.InDelta(t, tt.want, price.Total, 1e-9)
+			assert.InDelta(t, tt.want, price.Cpu*2+price.Ram*8, 1e-9)
+			_, _, err = spm.GetSpotPrice(tt.location, "c5.large")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateSpotAggregation(t *testing.T) {
+	assert.NoError(t, ValidateSpotAggregation(""))
+	assert.NoError(t, ValidateSpotAggregation(SpotAggregationAvg))
+	assert.ErrorIs(t, ValidateSpotAggregation("max"), ErrUnknownSpotAggregation)
+}
+
+func TestAcceleratorType(t *testing.T) {
+	for instanceType, want := range map[string]string{
+		"inf2.xlarge":   AcceleratorTypeInferentia,
+		"trn1.32xlarge": AcceleratorTypeTrainium,
+		"g5.xlarge":     AcceleratorTypeGPU,
+		"p4d.24xlarge":  AcceleratorTypeGPU,
+	} {
+		assert.Equal(t, want, AcceleratorType(instanceType), instanceType)
+	}
+}
+
+func TestAttributes_PricingLocation(t *testing.T) {
+	tests := map[string]struct {
+		attributes Attributes
+		want       string
+		wantErr    error
+	}{
+		"region": {
+			attributes: Attributes{Region: "us-east-1", LocationType: "AWS Region", UsageType: "BoxUsage:c5.large"},
+			want:       "us-east-1",
+		},
+		"local zone": {
+			attributes: Attributes{Region: "us-west-2", LocationType: "AWS Local Zone", UsageType: "USW2-LAX1-BoxUsage:c5.large"},
+			want:       "us-west-2-lax-1",
+		},
+		"wavelength zone": {
+			attributes: Attributes{Region: "us-east-1", LocationType: "AWS Wavelength Zone", UsageType: "USE1-WL1-BOS-WLZ1-BoxUsage:t3.medium"},
+			want:       "us-east-1-wl1-bos-wlz-1",
+		},
+		"region code of the zone group": {
+			attributes: Attributes{Region: "us-west-2-lax-1", LocationType: "AWS Local Zone", UsageType: "USW2-LAX1-BoxUsage:c5.large"},
+			want:       "us-west-2-lax-1",
+		},
+		"usage type without a zone group": {
+			attributes: Attributes{Region: "us-west-2", LocationType: "AWS Local Zone", UsageType: "USW2-BoxUsage:c5.large"},
+			wantErr:    ErrUnknownLocation,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tt.attributes.PricingLocation()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// benchmarkRegions, benchmarkFamilies and benchmarkSizes span about as many instance types per region as the AWS
+// pricing API lists.
+var (
+	benchmarkRegions = []string{
+		"af-south-1", "ap-east-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ap-south-1", "ap-south-2",
+		"ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4", "ca-central-1", "ca-west-1",
+		"eu-central-1", "eu-central-2", "eu-north-1", "eu-south-1", "eu-south-2", "eu-west-1", "eu-west-2", "eu-west-3",
+		"il-central-1", "me-central-1", "me-south-1", "sa-east-1", "us-east-1", "us-east-2", "us-west-1", "us-west-2",
+		"us-gov-west-1",
+	}
+	benchmarkFamilies = []string{"c", "m", "r", "t", "x"}
+	benchmarkGens     = []string{"4", "5", "5a", "5d", "5n", "6a", "6g", "6i", "6in", "7a", "7g", "7i"}
+	benchmarkSizes    = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "32xlarge", "48xlarge", "metal", "medium"}
+)
+
+// benchmarkOffers generates n distinct on-demand offers shaped like the ones returned by the AWS pricing API and a
+// spot price in the first availability zone of every offer.
+func benchmarkOffers(n int) ([]string, []ec2Types.SpotPrice) {
+	offers := make([]string, 0, n)
+	spotPrices := make([]ec2Types.SpotPrice, 0, n)
+	for i := 0; i < n; i++ {
+		region := benchmarkRegions[i%len(benchmarkRegions)]
+		j := i / len(benchmarkRegions)
+		family := benchmarkFamilies[j%len(benchmarkFamilies)] + benchmarkGens[(j/len(benchmarkFamilies))%len(benchmarkGens)]
+		size := j / (len(benchmarkFamilies) * len(benchmarkGens)) % len(benchmarkSizes)
+		instanceType := family + "." + benchmarkSizes[size]
+		vcpu := 2 << (size % 6)
+		offers = append(offers, fmt.Sprintf(`{"product":{"productFamily":"Compute Instance","attributes":{"regionCode":%q,"instanceType":%q,"vcpu":"%d","memory":"%d GiB","instanceFamily":"General purpose","physicalProcessor":"Intel Xeon Platinum 8175","tenancy":"Shared","operatingSystem":"Linux","clockSpeed":"3.1 GHz","usageType":"BoxUsage:%s","gpu":"NA"},"sku":"SKU%d"},"serviceCode":"AmazonEC2","terms":{"OnDemand":{"SKU%d.JRTCKXETXF":{"priceDimensions":{"SKU%d.JRTCKXETXF.6YS6EN2CT7":{"unit":"Hrs","pricePerUnit":{"USD":"%.4f"}}}}}}}`,
+			region, instanceType, vcpu, vcpu*4, instanceType, i, i, i, float64(vcpu)*0.048))
+		spotPrices = append(spotPrices, ec2Types.SpotPrice{
+			AvailabilityZone: aws.String(region + "a"),
+			InstanceType:     ec2Types.InstanceType(instanceType),
+			SpotPrice:        aws.String(fmt.Sprintf("%.4f", float64(vcpu)*0.018)),
+		})
+	}
+	return offers, spotPrices
+}
+
+func BenchmarkStructuredPricingMap_GeneratePricingMap(b *testing.B) {
+	for _, n := range []int{1_000, 20_000} {
+		offers, spotPrices := benchmarkOffers(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				spm := NewStructuredPricingMap()
+				if err := spm.GeneratePricingMap(offers, spotPrices); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec22 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+)
+
+const (
+	// optInNotRequired and optedIn are the opt-in statuses of the regions enabled for the account. Regions that
+	// aren't opted in can't be called.
+	optInNotRequired = "opt-in-not-required"
+	optedIn          = "opted-in"
+)
+
+var ErrInvalidRegionPattern = errors.New("invalid region pattern")
+
+// RegionFilter selects the regions that are collected among the regions enabled for the account. Patterns use the
+// syntax of path.Match, eg eu-*.
+type RegionFilter struct {
+	// Allow only collects the regions matching one of the patterns, every enabled region is collected when it's empty.
+	Allow []string
+	// Deny skips the regions matching any of the patterns, even when they are allowed.
+	Deny []string
+}
+
+// Validate checks the syntax of every pattern. It's safe to call on a nil filter.
+func (f *RegionFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrInvalidRegionPattern, pattern, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a region is collected. It's safe to call on a nil filter.
+func (f *RegionFilter) Matches(region string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Allow) > 0 && !matchesRegion(f.Allow, region) {
+		return false
+	}
+	return !matchesRegion(f.Deny, region)
+}
+
+func matchesRegion(patterns []string, region string) bool {
+	for _, pattern := range patterns {
+		// The patterns are validated upfront, so the error can be ignored
+		if matched, _ := path.Match(pattern, region); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRegions returns the regions enabled for the account that match filter, which is optional. Every region is
+// described along with its opt-in status, so that the regions enabled after startup are found by listing them again.
+func ListRegions(ctx context.Context, client ec2.EC2, filter *RegionFilter) ([]types.Region, error) {
+	resp, err := client.DescribeRegions(ctx, &ec22.DescribeRegionsInput{AllRegions: aws.Bool(true)})
+	if err != nil {
+		return nil, err
+	}
+	var regions []types.Region
+	for _, region := range resp.Regions {
+		if region.RegionName == nil {
+			continue
+		}
+		if status := aws.ToString(region.OptInStatus); status != optInNotRequired && status != optedIn {
+			continue
+		}
+		if !filter.Matches(*region.RegionName) {
+			continue
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// RegionFromAvailabilityZone returns the region of an availability zone, Local Zone or Wavelength Zone, eg us-east-1
+// for us-east-1a, us-east-1-bos-1a and us-east-1-wl1-bos-wlz-1. The region ends with the first numeric segment of the
+// zone name.
+func RegionFromAvailabilityZone(zone string) string {
+	segments := strings.Split(zone, "-")
+	for i, segment := range segments {
+		if i > 0 && segment != "" && strings.IndexFunc(segment, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+			return strings.Join(segments[:i+1], "-")
+		}
+	}
+	// The zone of a regular availability zone ends with a letter right after the region number, eg us-east-1a
+	return trimZoneLetter(zone)
+}
+
+// PricingLocation returns the location the on-demand prices of the instances in an availability zone are keyed by:
+// the region for a regular availability zone and the zone group for a Local Zone or a Wavelength Zone, which are
+// priced separately from their parent region, eg us-east-1-bos-1 for us-east-1-bos-1a.
+func PricingLocation(zone string) string {
+	region := RegionFromAvailabilityZone(zone)
+	if len(zone) <= len(region)+1 {
+		return region
+	}
+	// Local Zones end with a letter like availability zones, Wavelength Zones don't
+	return trimZoneLetter(zone)
+}
+
+func trimZoneLetter(zone string) string {
+	if zone != "" && unicode.IsLetter(rune(zone[len(zone)-1])) {
+		return zone[:len(zone)-1]
+	}
+	return zone
+}
@@ -0,0 +1,98 @@
+package compute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	ec22 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ec2"
+)
+
+func TestRegionFilter_Matches(t *testing.T) {
+	tests := map[string]struct {
+		filter *RegionFilter
+		region string
+		want   bool
+	}{
+		"nil filter": {
+			region: "us-east-1",
+			want:   true,
+		},
+		"allowed": {
+			filter: &RegionFilter{Allow: []string{"eu-*"}},
+			region: "eu-west-1",
+			want:   true,
+		},
+		"not allowed": {
+			filter: &RegionFilter{Allow: []string{"eu-*"}},
+			region: "us-east-1",
+		},
+		"denied": {
+			filter: &RegionFilter{Deny: []string{"ap-*"}},
+			region: "ap-east-1",
+		},
+		"denied even when allowed": {
+			filter: &RegionFilter{Allow: []string{"eu-*"}, Deny: []string{"eu-south-*"}},
+			region: "eu-south-2",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(tt.region))
+		})
+	}
+}
+
+func TestRegionFilter_Validate(t *testing.T) {
+	assert.NoError(t, (*RegionFilter)(nil).Validate())
+	assert.NoError(t, (&RegionFilter{Allow: []string{"eu-*"}}).Validate())
+	assert.ErrorIs(t, (&RegionFilter{Deny: []string{"eu-["}}).Validate(), ErrInvalidRegionPattern)
+}
+
+func TestListRegions(t *testing.T) {
+	region := func(name, status string) types.Region {
+		return types.Region{RegionName: aws.String(name), OptInStatus: aws.String(status)}
+	}
+	client := ec22.NewEC2(t)
+	client.EXPECT().
+		DescribeRegions(mock.Anything, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)}).
+		Return(&ec2.DescribeRegionsOutput{Regions: []types.Region{
+			region("us-east-1", optInNotRequired),
+			region("eu-south-2", optedIn),
+			region("ap-east-1", "not-opted-in"),
+			region("eu-west-1", optInNotRequired),
+		}}, nil).Times(1)
+
+	regions, err := ListRegions(context.Background(), client, &RegionFilter{Deny: []string{"eu-west-*"}})
+	require.NoError(t, err)
+	var names []string
+	for _, r := range regions {
+		names = append(names, *r.RegionName)
+	}
+	assert.Equal(t, []string{"us-east-1", "eu-south-2"}, names)
+}
+
+func TestPricingLocation(t *testing.T) {
+	tests := map[string]struct {
+		zone         string
+		wantRegion   string
+		wantLocation string
+	}{
+		"availability zone": {zone: "us-east-1a", wantRegion: "us-east-1", wantLocation: "us-east-1"},
+		"govcloud":          {zone: "us-gov-west-1b", wantRegion: "us-gov-west-1", wantLocation: "us-gov-west-1"},
+		"local zone":        {zone: "us-east-1-bos-1a", wantRegion: "us-east-1", wantLocation: "us-east-1-bos-1"},
+		"wavelength zone":   {zone: "us-east-1-wl1-bos-wlz-1", wantRegion: "us-east-1", wantLocation: "us-east-1-wl1-bos-wlz-1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.wantRegion, RegionFromAvailabilityZone(tt.zone))
+			assert.Equal(t, tt.wantLocation, PricingLocation(tt.zone))
+		})
+	}
+}
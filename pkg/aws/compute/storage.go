@@ -0,0 +1,163 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	storageUnit = "GB-Mo"
+	// gibPerGB converts a price per GB into a price per GiB, as the pricing API quotes storage per decimal GB.
+	gibPerGB = (1 << 30) / 1e9
+)
+
+var (
+	// StorageClassHourlyPriceDesc is a price sheet of the EBS volume types backing the storage classes of EKS clusters,
+	// eg gp3, rather than the cost of any volume.
+	StorageClassHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "aws", "storage_class_usd_per_gib_hour"),
+		"The price of the storage of an EBS volume type in USD/(GiB*h). storage_class is the volume type, eg gp3.",
+		[]string{"storage_class", "region"},
+		nil,
+	)
+)
+
+// StoragePrices are the prices of the EBS volume types in USD/(GiB*h), keyed by region and then by volume type.
+type StoragePrices map[string]map[string]float64
+
+// storageProduct represents the nested json response returned by the AWS pricing API for EBS volumes.
+type storageProduct struct {
+	Product struct {
+		Attributes struct {
+			Region        string `json:"regionCode"`
+			VolumeAPIName string `json:"volumeApiName"`
+		}
+	}
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			}
+		}
+	}
+}
+
+// ListStoragePrices lists the price of the storage of every EBS volume type in a region. IOPS and throughput are
+// priced separately and aren't part of it.
+func ListStoragePrices(ctx context.Context, region string, client pricingClient.Pricing) ([]string, error) {
+	var productOutputs []string
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []types.Filter{
+			{
+				Field: aws.String("regionCode"),
+				Type:  "TERM_MATCH",
+				Value: aws.String(region),
+			},
+			{
+				Field: aws.String("productFamily"),
+				Type:  "TERM_MATCH",
+				Value: aws.String("Storage"),
+			},
+		},
+	}
+	for {
+		products, err := client.GetProducts(ctx, input)
+		if err != nil {
+			return productOutputs, err
+		}
+		if products == nil {
+			break
+		}
+		productOutputs = append(productOutputs, products.PriceList...)
+		if products.NextToken == nil {
+			break
+		}
+		input.NextToken = products.NextToken
+	}
+	return productOutputs, nil
+}
+
+// ParseStoragePrices parses the output of ListStoragePrices into prices per GiB and hour. Entries that can't be parsed
+// or aren't priced per GB-month are skipped.
+func ParseStoragePrices(products []string) StoragePrices {
+	prices := make(StoragePrices)
+	for _, product := range products {
+		var productInfo storageProduct
+		if err := json.Unmarshal([]byte(product), &productInfo); err != nil {
+			log.Printf("error parsing storage price entry: %s, skipping", err)
+			continue
+		}
+		attributes := productInfo.Product.Attributes
+		if attributes.Region == "" || attributes.VolumeAPIName == "" {
+			continue
+		}
+		for _, term := range productInfo.Terms.OnDemand {
+			for _, priceDimension := range term.PriceDimensions {
+				if priceDimension.Unit != storageUnit {
+					continue
+				}
+				price, err := parsePrice(priceDimension.PricePerUnit["USD"])
+				if err != nil {
+					log.Printf("error parsing storage price: %s, skipping", err)
+					continue
+				}
+				if prices[attributes.Region] == nil {
+					prices[attributes.Region] = make(map[string]float64)
+				}
+				prices[attributes.Region][attributes.VolumeAPIName] = price * gibPerGB / utils.HoursInMonth
+			}
+		}
+	}
+	return prices
+}
+
+// Metrics returns a cloudcost_aws_storage_class_usd_per_gib_hour series per region and volume type, sorted by region
+// and volume type.
+func (p StoragePrices) Metrics() []prometheus.Metric {
+	var metrics []prometheus.Metric
+	for _, region := range sortedKeys(p) {
+		for _, volumeType := range sortedKeys(p[region]) {
+			metrics = append(metrics, prometheus.MustNewConstMetric(StorageClassHourlyPriceDesc, prometheus.GaugeValue, p[region][volumeType], volumeType, region))
+		}
+	}
+	return metrics
+}
+
+// KubernetesStorageClassMetrics returns the price sheet of the Kubernetes storage classes per region, sorted by region
+// and class. A class is priced at the price of its volume type.
+func (p StoragePrices) KubernetesStorageClassMetrics(classes []storageclass.Class) []prometheus.Metric {
+	if len(classes) == 0 {
+		return nil
+	}
+	var metrics []prometheus.Metric
+	for _, region := range sortedKeys(p) {
+		for _, class := range classes {
+			price, ok := p[region][class.Type]
+			metrics = append(metrics, class.Metrics(region, price, ok)...)
+		}
+	}
+	return metrics
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package compute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	gp3StorageProduct  = `{"product":{"productFamily":"Storage","attributes":{"regionCode":"us-east-1","volumeApiName":"gp3"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.C":{"unit":"GB-Mo","pricePerUnit":{"USD":"0.0800000000"}}}}}}}`
+	io2StorageProduct  = `{"product":{"productFamily":"Storage","attributes":{"regionCode":"eu-west-1","volumeApiName":"io2"}},"terms":{"OnDemand":{"D.E":{"priceDimensions":{"D.E.F":{"unit":"GB-Mo","pricePerUnit":{"USD":"0.1375000000"}}}}}}}`
+	iopsStorageProduct = `{"product":{"productFamily":"Storage","attributes":{"regionCode":"us-east-1","volumeApiName":"io1"}},"terms":{"OnDemand":{"G.H":{"priceDimensions":{"G.H.I":{"unit":"IOPS-Mo","pricePerUnit":{"USD":"0.0650000000"}}}}}}}`
+)
+
+func TestParseStoragePrices(t *testing.T) {
+	tests := map[string]struct {
+		products []string
+		want     StoragePrices
+	}{
+		"no products": {
+			want: StoragePrices{},
+		},
+		"prices are converted to USD per GiB and hour": {
+			products: []string{gp3StorageProduct, io2StorageProduct},
+			want: StoragePrices{
+				"us-east-1": {"gp3": 0.08 * gibPerGB / utils.HoursInMonth},
+				"eu-west-1": {"io2": 0.1375 * gibPerGB / utils.HoursInMonth},
+			},
+		},
+		"other units are skipped": {
+			products: []string{iopsStorageProduct},
+			want:     StoragePrices{},
+		},
+		"malformed entries are skipped": {
+			products: []string{"not json", `{"product":{"attributes":{"regionCode":"us-east-1"}}}`, gp3StorageProduct},
+			want: StoragePrices{
+				"us-east-1": {"gp3": 0.08 * gibPerGB / utils.HoursInMonth},
+			},
+		},
+		"invalid prices are skipped": {
+			products: []string{`{"product":{"attributes":{"regionCode":"us-east-1","volumeApiName":"gp2"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"B":{"unit":"GB-Mo","pricePerUnit":{"USD":"-1"}}}}}}}`},
+			want:     StoragePrices{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ParseStoragePrices(tt.products)
+			require.Len(t, got, len(tt.want))
+			for region, prices := range tt.want {
+				for volumeType, price := range prices {
+					assert.InDelta(t, price, got[region][volumeType], 1e-12)
+				}
+			}
+		})
+	}
+}
+
+func TestListStoragePrices(t *testing.T) {
+	client := mockpricing.NewPricing(t)
+	client.EXPECT().GetProducts(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+			if input.NextToken == nil {
+				return &pricing.GetProductsOutput{PriceList: []string{gp3StorageProduct}, NextToken: aws.String("token")}, nil
+			}
+			return &pricing.GetProductsOutput{PriceList: []string{iopsStorageProduct}}, nil
+		}).Times(2)
+	got, err := ListStoragePrices(context.Background(), "us-east-1", client)
+	require.NoError(t, err)
+	assert.Equal(t, []string{gp3StorageProduct, iopsStorageProduct}, got)
+}
+
+func TestStoragePrices_Metrics(t *testing.T) {
+	prices := StoragePrices{
+		"us-east-1": {"gp3": 0.2, "gp2": 0.1},
+		"eu-west-1": {"io2": 0.3},
+	}
+	metrics := prices.Metrics()
+	require.Len(t, metrics, 3)
+	var got []utils.LabelMap
+	for _, m := range metrics {
+		result := utils.ReadMetrics(m)
+		assert.Equal(t, "cloudcost_aws_storage_class_usd_per_gib_hour", result.FqName)
+		got = append(got, result.Labels)
+	}
+	assert.Equal(t, []utils.LabelMap{
+		{"storage_class": "io2", "region": "eu-west-1"},
+		{"storage_class": "gp2", "region": "us-east-1"},
+		{"storage_class": "gp3", "region": "us-east-1"},
+	}, got)
+}
+
+func TestStoragePrices_KubernetesStorageClassMetrics(t *testing.T) {
+	prices := StoragePrices{
+		"us-east-1": {"gp3": 0.2},
+		"eu-west-1": {"io2": 0.3},
+	}
+	classes := []storageclass.Class{{Name: "fast-ssd", Provider: storageclass.ProviderAWS, Type: "gp3", USDPerHour: 0.01}}
+	metrics := prices.KubernetesStorageClassMetrics(classes)
+	require.Len(t, metrics, 2, "the class is only priced in the regions pricing its volume type")
+	price := utils.ReadMetrics(metrics[0])
+	assert.Equal(t, "cloudcost_kubernetes_storage_class_usd_per_gib_hour", price.FqName)
+	assert.Equal(t, utils.LabelMap{"storage_class": "fast-ssd", "provider": "aws", "region": "us-east-1", "volume_type": "gp3"}, price.Labels)
+	assert.Equal(t, 0.2, price.Value)
+	assert.Equal(t, 0.01, utils.ReadMetrics(metrics[1]).Value)
+
+	assert.Empty(t, prices.KubernetesStorageClassMetrics(nil))
+}
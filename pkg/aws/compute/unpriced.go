@@ -0,0 +1,31 @@
+package compute
+
+import (
+	"errors"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var (
+	// UnpricedResourcesTotal is incremented by the eks collector and registered once by the aws provider.
+	UnpricedResourcesTotal = utils.NewUnpricedResourcesTotal("aws")
+	// UnpricedMachineTypeInfoDesc lists the machine types that could not be priced during the last collection.
+	UnpricedMachineTypeInfoDesc = utils.NewUnpricedMachineTypeInfoDesc("aws")
+)
+
+// UnpricedReason maps an error returned by GetPriceForInstanceType to a low cardinality reason label.
+func UnpricedReason(err error) string {
+	switch {
+	case errors.Is(err, ErrRegionNotFound):
+		return "region_not_found"
+	case errors.Is(err, ErrInstanceTypeNotFound):
+		return "instance_type_not_found"
+	default:
+		return "unknown"
+	}
+}
+
+// NewUnpricedMachineTypes returns a tracker of the machine types a collector couldn't price during a collection.
+func NewUnpricedMachineTypes(collector string) *utils.UnpricedMachineTypes {
+	return utils.NewUnpricedMachineTypes("aws", collector, UnpricedMachineTypeInfoDesc, UnpricedResourcesTotal, UnpricedReason)
+}
@@ -0,0 +1,33 @@
+package compute
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnpricedReason(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"region not found": {
+			err:  ErrRegionNotFound,
+			want: "region_not_found",
+		},
+		"instance type not found": {
+			err:  fmt.Errorf("%w: m7i.large", ErrInstanceTypeNotFound),
+			want: "instance_type_not_found",
+		},
+		"unknown error": {
+			err:  assert.AnError,
+			want: "unknown",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, UnpricedReason(tt.err))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
+)
+
+const (
+	// UtilizationWindow is how far back the average CPU utilization of an instance is looked up.
+	UtilizationWindow = time.Hour
+	// maxMetricDataQueries is the maximum number of queries CloudWatch accepts in a single GetMetricData call.
+	maxMetricDataQueries = 500
+)
+
+var (
+	// InstanceIdleHourlyCostDesc is the share of the hourly price of an instance that wasn't used over the last hour, based
+	// upon the average CPU utilization reported by CloudWatch. Only one collector should emit it to avoid duplicate series.
+	InstanceIdleHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "aws", "instance_idle_usd_per_hour"),
+		"The hourly cost of an instance in USD multiplied by its unused CPU share over the last hour.",
+		[]string{"instance", "region", "machine_type", "price_tier"},
+		nil,
+	)
+)
+
+// ListCPUUtilization returns the average CPU utilization between 0 and 1 of each instance over the UtilizationWindow
+// ending at now, keyed by instance id. Instances that CloudWatch has no datapoints for are left out of the result.
+func ListCPUUtilization(ctx context.Context, client cloudwatchclient.CloudWatch, instanceIDs []string, now time.Time) (map[string]float64, error) {
+	utilization := make(map[string]float64, len(instanceIDs))
+	for start := 0; start < len(instanceIDs); start += maxMetricDataQueries {
+		end := min(start+maxMetricDataQueries, len(instanceIDs))
+		batch := instanceIDs[start:end]
+		queries := make([]cloudwatchTypes.MetricDataQuery, 0, len(batch))
+		for i, id := range batch {
+			queries = append(queries, cloudwatchTypes.MetricDataQuery{
+				// Query ids have to start with a lowercase letter, instance ids can't be used as is because of the dash
+				Id: aws.String(fmt.Sprintf("q%d", i)),
+				MetricStat: &cloudwatchTypes.MetricStat{
+					Metric: &cloudwatchTypes.Metric{
+						Namespace:  aws.String("AWS/EC2"),
+						MetricName: aws.String("CPUUtilization"),
+						Dimensions: []cloudwatchTypes.Dimension{
+							{Name: aws.String("InstanceId"), Value: aws.String(id)},
+						},
+					},
+					Period: aws.Int32(int32(UtilizationWindow.Seconds())),
+					Stat:   aws.String("Average"),
+				},
+			})
+		}
+		input := &cloudwatch.GetMetricDataInput{
+			MetricDataQueries: queries,
+			StartTime:         aws.Time(now.Add(-UtilizationWindow)),
+			EndTime:           aws.Time(now),
+		}
+		for {
+			resp, err := client.GetMetricData(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			for _, result := range resp.MetricDataResults {
+				if result.Id == nil || len(result.Values) == 0 {
+					continue
+				}
+				var i int
+				if _, err := fmt.Sscanf(*result.Id, "q%d", &i); err != nil || i >= len(batch) {
+					continue
+				}
+				// CloudWatch reports the utilization as a percentage
+				utilization[batch[i]] = clampUtilization(result.Values[0] / 100)
+			}
+			if resp.NextToken == nil || *resp.NextToken == "" {
+				break
+			}
+			input.NextToken = resp.NextToken
+		}
+	}
+	return utilization, nil
+}
+
+// IdleCost returns the share of hourlyPrice that wasn't used given a utilization between 0 and 1.
+func IdleCost(hourlyPrice float64, utilization float64) float64 {
+	return hourlyPrice * (1 - clampUtilization(utilization))
+}
+
+func clampUtilization(utilization float64) float64 {
+	return max(0, min(1, utilization))
+}
@@ -0,0 +1,139 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockcloudwatch "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/cloudwatch"
+)
+
+func TestListCPUUtilization(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		instanceIDs   []string
+		GetMetricData func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+		expectedCalls int
+		want          map[string]float64
+		err           error
+	}{
+		"no instances should not call cloudwatch": {
+			want: map[string]float64{},
+		},
+		"utilization is converted from a percentage": {
+			instanceIDs: []string{"i-1", "i-2", "i-3"},
+			GetMetricData: func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+				assert.Len(t, params.MetricDataQueries, 3)
+				assert.Equal(t, now.Add(-UtilizationWindow), *params.StartTime)
+				return &cloudwatch.GetMetricDataOutput{
+					MetricDataResults: []cloudwatchTypes.MetricDataResult{
+						{Id: aws.String("q0"), Values: []float64{25}},
+						{Id: aws.String("q1"), Values: []float64{100}},
+						// i-3 has no datapoints, eg because it was just launched
+						{Id: aws.String("q2")},
+					},
+				}, nil
+			},
+			expectedCalls: 1,
+			want:          map[string]float64{"i-1": 0.25, "i-2": 1},
+		},
+		"next token is followed": {
+			instanceIDs: []string{"i-1", "i-2"},
+			GetMetricData: func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+				if params.NextToken == nil {
+					return &cloudwatch.GetMetricDataOutput{
+						NextToken:         aws.String("token"),
+						MetricDataResults: []cloudwatchTypes.MetricDataResult{{Id: aws.String("q0"), Values: []float64{10}}},
+					}, nil
+				}
+				return &cloudwatch.GetMetricDataOutput{
+					MetricDataResults: []cloudwatchTypes.MetricDataResult{{Id: aws.String("q1"), Values: []float64{20}}},
+				}, nil
+			},
+			expectedCalls: 2,
+			want:          map[string]float64{"i-1": 0.1, "i-2": 0.2},
+		},
+		"instances are queried in batches": {
+			instanceIDs: func() []string {
+				var ids []string
+				for i := 0; i < maxMetricDataQueries+1; i++ {
+					ids = append(ids, fmt.Sprintf("i-%d", i))
+				}
+				return ids
+			}(),
+			GetMetricData: func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+				assert.LessOrEqual(t, len(params.MetricDataQueries), maxMetricDataQueries)
+				var results []cloudwatchTypes.MetricDataResult
+				if len(params.MetricDataQueries) == 1 {
+					results = append(results, cloudwatchTypes.MetricDataResult{Id: aws.String("q0"), Values: []float64{50}})
+				}
+				return &cloudwatch.GetMetricDataOutput{MetricDataResults: results}, nil
+			},
+			expectedCalls: 2,
+			want:          map[string]float64{fmt.Sprintf("i-%d", maxMetricDataQueries): 0.5},
+		},
+		"errors propagate": {
+			instanceIDs: []string{"i-1"},
+			GetMetricData: func(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+				return nil, assert.AnError
+			},
+			expectedCalls: 1,
+			err:           assert.AnError,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := mockcloudwatch.NewCloudWatch(t)
+			if tt.GetMetricData != nil {
+				client.EXPECT().
+					GetMetricData(mock.Anything, mock.Anything, mock.Anything).
+					RunAndReturn(tt.GetMetricData).
+					Times(tt.expectedCalls)
+			}
+
+			got, err := ListCPUUtilization(context.Background(), client, tt.instanceIDs, now)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDeltaMapValues(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestIdleCost(t *testing.T) {
+	tests := map[string]struct {
+		price       float64
+		utilization float64
+		want        float64
+	}{
+		"idle instance costs its full price": {
+			price: 0.4,
+			want:  0.4,
+		},
+		"partially used instance": {
+			price:       0.4,
+			utilization: 0.25,
+			want:        0.3,
+		},
+		"utilization above 1 is capped": {
+			price:       0.4,
+			utilization: 1.2,
+			want:        0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, IdleCost(tt.price, tt.utilization), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package elb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	elbclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/elb"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "aws"
+	subsystem    = "aws_elb"
+
+	serviceCode        = "AWSELB"
+	locationTypeRegion = "AWS Region"
+
+	TypeClassic     = "classic"
+	TypeApplication = "application"
+	TypeNetwork     = "network"
+	TypeGateway     = "gateway"
+
+	// usageTypeHourly and usageTypeLCU are the usage types of the hourly price of a load balancer and of the price of an
+	// hour of a capacity unit, prefixed by the region outside of us-east-1, eg USE1-LoadBalancerUsage.
+	usageTypeHourly = "LoadBalancerUsage"
+	usageTypeLCU    = "LCUUsage"
+
+	// maxPageSize is the number of load balancers the DescribeLoadBalancers APIs return at most per page, and
+	// maxDescribeTags the number of load balancers whose tags are described at once.
+	maxPageSize     = 400
+	maxDescribeTags = 20
+
+	// tagLabelPrefix prefixes the labels of the tags of the load balancers, so that they can't clash with the other
+	// labels.
+	tagLabelPrefix = "tag_"
+)
+
+// productFamilies are the types of the load balancers of the product families of the pricing API.
+var productFamilies = map[string]string{
+	"Load Balancer":             TypeClassic,
+	"Load Balancer-Application": TypeApplication,
+	"Load Balancer-Network":     TypeNetwork,
+	"Load Balancer-Gateway":     TypeGateway,
+}
+
+// clusterTags are the tags the AWS Load Balancer Controller and the in-tree cloud provider of Kubernetes set to the
+// name of the cluster of the load balancers they create. clusterTagPrefix is the prefix of the tag of the in-tree cloud
+// provider, whose key rather than its value has the name of the cluster, eg kubernetes.io/cluster/prod=owned.
+var clusterTags = []string{"elbv2.k8s.aws/cluster", "eks:cluster-name"}
+
+const clusterTagPrefix = "kubernetes.io/cluster/"
+
+var (
+	LCUHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "lcu_usd_per_hour"),
+		"The price of an hour of a load balancer capacity unit in USD/h, the LCU of the application load balancers, the NLCU of the network load balancers or the GLCU of the gateway load balancers.",
+		[]string{"region", "type"},
+		nil,
+	)
+	errInvalidPrice = errors.New("invalid price")
+)
+
+// priceKey selects the prices of the load balancers of a type in a region.
+type priceKey struct {
+	region string
+	lbType string
+}
+
+// prices are the on-demand hourly prices of the load balancers and of their capacity units in USD/h.
+type prices struct {
+	hourly map[priceKey]float64
+	lcu    map[priceKey]float64
+}
+
+// loadBalancer is a load balancer of a region and its tags.
+type loadBalancer struct {
+	name   string
+	lbType string
+	tags   map[string]string
+}
+
+// product represents the nested json response returned by the AWS pricing API for the load balancers.
+type product struct {
+	Product struct {
+		ProductFamily string `json:"productFamily"`
+		Attributes    struct {
+			Region    string `json:"regionCode"`
+			UsageType string `json:"usagetype"`
+		}
+	}
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			}
+		}
+	}
+}
+
+// Config configures the Collector.
+type Config struct {
+	ScrapeInterval time.Duration
+	// Regions selects the regions whose prices are listed, every region is when nil.
+	Regions *compute.RegionFilter
+	// Tags are the tags of the load balancers exported as labels, named after the tag prefixed with tag_, eg
+	// tag_team for the team tag.
+	Tags []string
+	// ClusterNames normalizes the cluster_name label.
+	ClusterNames *clustername.Normalizer
+}
+
+// Collector exports the hourly cost of the classic, application, network and gateway load balancers. The prices are
+// listed every scrape interval and the load balancers on every scrape. The capacity units consumed by the load
+// balancers aren't known, so their price is exported on its own.
+type Collector struct {
+	config        *Config
+	pricingClient pricingClient.Pricing
+	regionClients map[string]elbclient.ELB
+	hourlyDesc    *prometheus.Desc
+	tagLabels     []string
+	backoff       *provider.Backoff
+	nextScrape    time.Time
+	prices        *prices
+	m             sync.Mutex
+}
+
+// New creates a Collector listing the load balancers of the regions of regionClients. Tags whose labels would clash
+// with the label of another tag are dropped.
+func New(config *Config, client pricingClient.Pricing, regionClients map[string]elbclient.ELB) *Collector {
+	c := &Collector{
+		config:        config,
+		pricingClient: client,
+		regionClients: regionClients,
+	}
+	labels := []string{"region", "load_balancer", "type", "cluster_name"}
+	seen := make(map[string]bool)
+	for _, tag := range config.Tags {
+		label := TagLabel(tag)
+		if seen[label] {
+			log.Printf("skipping the elb tag %s, its label %s is the label of another tag", tag, label)
+			continue
+		}
+		seen[label] = true
+		labels = append(labels, label)
+		c.tagLabels = append(c.tagLabels, tag)
+	}
+	c.hourlyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "usd_per_hour"),
+		"The hourly cost of a load balancer in USD/h, at the on-demand hourly price of its type. The capacity units it consumes are billed on top, see cloudcost_aws_elb_lcu_usd_per_hour.",
+		labels,
+		nil,
+	)
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// TagLabel returns the label of a tag of the load balancers, the tag with the characters that aren't valid in a label
+// name replaced by underscores, prefixed with tag_, eg tag_kubernetes_io_service_name for kubernetes.io/service-name.
+func TagLabel(tag string) string {
+	return tagLabelPrefix + invalidLabelChars.ReplaceAllString(tag, "_")
+}
+
+func (c *Collector) Name() string {
+	return "ELB"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- c.hourlyDesc
+	ch <- LCUHourlyPriceDesc
+	ch <- provider.RefreshIntervalDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		c.hourlyDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices again when the scrape interval has passed, and the load balancers of every region. A region
+// failing is reported in its scope error metric and only fails the collector when every region failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listPrices(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing elb prices: %w", err)
+		}
+		c.prices = prices
+	}
+	lcuKeys := make([]priceKey, 0, len(c.prices.lcu))
+	for key := range c.prices.lcu {
+		lcuKeys = append(lcuKeys, key)
+	}
+	sort.Slice(lcuKeys, func(i, j int) bool {
+		if lcuKeys[i].region != lcuKeys[j].region {
+			return lcuKeys[i].region < lcuKeys[j].region
+		}
+		return lcuKeys[i].lbType < lcuKeys[j].lbType
+	})
+	for _, key := range lcuKeys {
+		ch <- prometheus.MustNewConstMetric(LCUHourlyPriceDesc, prometheus.GaugeValue, c.prices.lcu[key], key.region, key.lbType)
+	}
+	c.backoff.Emit(ch)
+
+	regions := make([]string, 0, len(c.regionClients))
+	for region := range c.regionClients {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	loadBalancers := make([][]loadBalancer, len(regions))
+	errs := make([]error, len(regions))
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loadBalancers[i], errs[i] = listLoadBalancers(context.Background(), c.regionClients[region])
+		}()
+	}
+	wg.Wait()
+
+	var failedRegions []error
+	for i, region := range regions {
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, errs[i])
+		if errs[i] != nil {
+			log.Printf("error listing load balancers in region %s: %s", region, errs[i])
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, errs[i]))
+			continue
+		}
+		for _, lb := range loadBalancers[i] {
+			price, ok := c.prices.hourly[priceKey{region: region, lbType: lb.lbType}]
+			if !ok {
+				log.Printf("no price found for %s load balancer %s in region %s", lb.lbType, lb.name, region)
+				continue
+			}
+			labelValues := []string{region, lb.name, lb.lbType, c.config.ClusterNames.Normalize(clusterName(lb.tags))}
+			for _, tag := range c.tagLabels {
+				labelValues = append(labelValues, lb.tags[tag])
+			}
+			ch <- prometheus.MustNewConstMetric(c.hourlyDesc, prometheus.GaugeValue, price, labelValues...)
+		}
+	}
+	if len(regions) > 0 && len(failedRegions) == len(regions) {
+		return errors.Join(failedRegions...)
+	}
+	return nil
+}
+
+// clusterName returns the name of the Kubernetes cluster a load balancer was created for, or an empty string.
+func clusterName(tags map[string]string) string {
+	for _, tag := range clusterTags {
+		if name := tags[tag]; name != "" {
+			return name
+		}
+	}
+	for key := range tags {
+		if name, ok := strings.CutPrefix(key, clusterTagPrefix); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// listPrices lists the on-demand prices of the load balancers of the regions of the filter.
+func (c *Collector) listPrices(ctx context.Context) (*prices, error) {
+	var products []string
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String(serviceCode),
+		Filters: []types.Filter{
+			{
+				Field: aws.String("locationType"),
+				Type:  "TERM_MATCH",
+				Value: aws.String(locationTypeRegion),
+			},
+		},
+	}
+	for {
+		output, err := c.pricingClient.GetProducts(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if output == nil {
+			break
+		}
+		products = append(products, output.PriceList...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return parsePrices(products, c.config.Regions), nil
+}
+
+// parsePrices returns the hourly prices of the load balancers and of their capacity units in the regions matching the
+// filter. The reserved and provisioned capacity units are billed differently and are skipped, as are the entries that
+// can't be parsed.
+func parsePrices(products []string, regions *compute.RegionFilter) *prices {
+	p := &prices{hourly: make(map[priceKey]float64), lcu: make(map[priceKey]float64)}
+	for _, entry := range products {
+		var prod product
+		if err := json.Unmarshal([]byte(entry), &prod); err != nil {
+			log.Printf("error parsing %s price entry: %s, skipping", serviceCode, err)
+			continue
+		}
+		attributes := prod.Product.Attributes
+		lbType, ok := productFamilies[prod.Product.ProductFamily]
+		if !ok || attributes.Region == "" || !regions.Matches(attributes.Region) {
+			continue
+		}
+		if strings.Contains(attributes.UsageType, "Reserved") || strings.Contains(attributes.UsageType, "Provisioned") {
+			continue
+		}
+		price, err := onDemandPrice(prod)
+		if err != nil {
+			continue
+		}
+		key := priceKey{region: attributes.Region, lbType: lbType}
+		switch {
+		case attributes.UsageType == usageTypeHourly || strings.HasSuffix(attributes.UsageType, "-"+usageTypeHourly):
+			p.hourly[key] = price
+		// The capacity units of the network and gateway load balancers are NLCUUsage and GLCUUsage
+		case strings.HasSuffix(attributes.UsageType, usageTypeLCU):
+			p.lcu[key] = price
+		}
+	}
+	return p
+}
+
+func onDemandPrice(p product) (float64, error) {
+	for _, term := range p.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			usd, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err == nil && !math.IsNaN(usd) && !math.IsInf(usd, 0) && usd > 0 {
+				return usd, nil
+			}
+		}
+	}
+	return 0, errInvalidPrice
+}
+
+// listLoadBalancers lists the load balancers of a region along with their tags, the application, network and gateway
+// load balancers as well as the classic ones.
+func listLoadBalancers(ctx context.Context, client elbclient.ELB) ([]loadBalancer, error) {
+	var loadBalancers []loadBalancer
+	var arns []string
+	input := &elbclient.DescribeLoadBalancersInput{PageSize: aws.Int32(maxPageSize)}
+	for {
+		resp, err := client.DescribeLoadBalancers(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error describing load balancers: %w", err)
+		}
+		for _, lb := range resp.LoadBalancers {
+			loadBalancers = append(loadBalancers, loadBalancer{name: aws.ToString(lb.LoadBalancerName), lbType: aws.ToString(lb.Type)})
+			arns = append(arns, aws.ToString(lb.LoadBalancerArn))
+		}
+		if resp.NextMarker == nil || *resp.NextMarker == "" {
+			break
+		}
+		input.Marker = resp.NextMarker
+	}
+	tags, err := describeTags(arns, func(arns []string) (*elbclient.DescribeTagsOutput, error) {
+		return client.DescribeTags(ctx, &elbclient.DescribeTagsInput{ResourceArns: arns})
+	}, func(d elbclient.TagDescription) string {
+		return aws.ToString(d.ResourceArn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range loadBalancers {
+		loadBalancers[i].tags = tags[arns[i]]
+	}
+
+	classic := len(loadBalancers)
+	var names []string
+	classicInput := &elbclient.DescribeClassicLoadBalancersInput{PageSize: aws.Int32(maxPageSize)}
+	for {
+		resp, err := client.DescribeClassicLoadBalancers(ctx, classicInput)
+		if err != nil {
+			return nil, fmt.Errorf("error describing classic load balancers: %w", err)
+		}
+		for _, lb := range resp.LoadBalancerDescriptions {
+			loadBalancers = append(loadBalancers, loadBalancer{name: aws.ToString(lb.LoadBalancerName), lbType: TypeClassic})
+			names = append(names, aws.ToString(lb.LoadBalancerName))
+		}
+		if resp.NextMarker == nil || *resp.NextMarker == "" {
+			break
+		}
+		classicInput.Marker = resp.NextMarker
+	}
+	tags, err = describeTags(names, func(names []string) (*elbclient.DescribeTagsOutput, error) {
+		return client.DescribeClassicTags(ctx, &elbclient.DescribeClassicTagsInput{LoadBalancerNames: names})
+	}, func(d elbclient.TagDescription) string {
+		return aws.ToString(d.LoadBalancerName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		loadBalancers[classic+i].tags = tags[name]
+	}
+	return loadBalancers, nil
+}
+
+// describeTags describes the tags of the load balancers of ids by batches of maxDescribeTags and returns them keyed by
+// the id of their load balancer.
+func describeTags(ids []string, describe func([]string) (*elbclient.DescribeTagsOutput, error), id func(elbclient.TagDescription) string) (map[string]map[string]string, error) {
+	tags := make(map[string]map[string]string, len(ids))
+	for start := 0; start < len(ids); start += maxDescribeTags {
+		resp, err := describe(ids[start:min(start+maxDescribeTags, len(ids))])
+		if err != nil {
+			return nil, fmt.Errorf("error describing tags: %w", err)
+		}
+		for _, d := range resp.TagDescriptions {
+			lbTags := make(map[string]string, len(d.Tags))
+			for _, tag := range d.Tags {
+				lbTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			tags[id(d)] = lbTags
+		}
+	}
+	return tags, nil
+}
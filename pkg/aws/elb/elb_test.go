@@ -0,0 +1,129 @@
+package elb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockelb "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/elb"
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	elbclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/elb"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	applicationHourlyProduct = `{"product":{"productFamily":"Load Balancer-Application","attributes":{"regionCode":"us-east-1","usagetype":"LoadBalancerUsage"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.1":{"pricePerUnit":{"USD":"0.0225000000"}}}}}}}`
+	applicationLCUProduct    = `{"product":{"productFamily":"Load Balancer-Application","attributes":{"regionCode":"us-east-1","usagetype":"LCUUsage"}},"terms":{"OnDemand":{"C.D":{"priceDimensions":{"C.D.1":{"pricePerUnit":{"USD":"0.0080000000"}}}}}}}`
+	reservedLCUProduct       = `{"product":{"productFamily":"Load Balancer-Application","attributes":{"regionCode":"us-east-1","usagetype":"ReservedLCUUsage"}},"terms":{"OnDemand":{"E.F":{"priceDimensions":{"E.F.1":{"pricePerUnit":{"USD":"0.0050000000"}}}}}}}`
+	networkHourlyProduct     = `{"product":{"productFamily":"Load Balancer-Network","attributes":{"regionCode":"us-east-1","usagetype":"LoadBalancerUsage"}},"terms":{"OnDemand":{"G.H":{"priceDimensions":{"G.H.1":{"pricePerUnit":{"USD":"0.0225000000"}}}}}}}`
+	networkLCUProduct        = `{"product":{"productFamily":"Load Balancer-Network","attributes":{"regionCode":"us-east-1","usagetype":"NLCUUsage"}},"terms":{"OnDemand":{"I.J":{"priceDimensions":{"I.J.1":{"pricePerUnit":{"USD":"0.0060000000"}}}}}}}`
+	classicHourlyProduct     = `{"product":{"productFamily":"Load Balancer","attributes":{"regionCode":"us-east-1","usagetype":"LoadBalancerUsage"}},"terms":{"OnDemand":{"K.L":{"priceDimensions":{"K.L.1":{"pricePerUnit":{"USD":"0.0250000000"}}}}}}}`
+	classicDataProduct       = `{"product":{"productFamily":"Load Balancer","attributes":{"regionCode":"us-east-1","usagetype":"DataProcessing-Bytes"}},"terms":{"OnDemand":{"M.N":{"priceDimensions":{"M.N.1":{"pricePerUnit":{"USD":"0.0080000000"}}}}}}}`
+	euWestHourlyProduct      = `{"product":{"productFamily":"Load Balancer-Application","attributes":{"regionCode":"eu-west-1","usagetype":"EU-LoadBalancerUsage"}},"terms":{"OnDemand":{"O.P":{"priceDimensions":{"O.P.1":{"pricePerUnit":{"USD":"0.0252000000"}}}}}}}`
+)
+
+func Test_parsePrices(t *testing.T) {
+	p := parsePrices(
+		[]string{applicationHourlyProduct, applicationLCUProduct, reservedLCUProduct, networkHourlyProduct, networkLCUProduct, classicHourlyProduct, classicDataProduct, euWestHourlyProduct, "invalid"},
+		&compute.RegionFilter{Allow: []string{"us-*"}},
+	)
+	assert.Equal(t, map[priceKey]float64{
+		{region: "us-east-1", lbType: TypeApplication}: 0.0225,
+		{region: "us-east-1", lbType: TypeNetwork}:     0.0225,
+		{region: "us-east-1", lbType: TypeClassic}:     0.025,
+	}, p.hourly)
+	assert.Equal(t, map[priceKey]float64{
+		{region: "us-east-1", lbType: TypeApplication}: 0.008,
+		{region: "us-east-1", lbType: TypeNetwork}:     0.006,
+	}, p.lcu)
+}
+
+func TestTagLabel(t *testing.T) {
+	assert.Equal(t, "tag_team", TagLabel("team"))
+	assert.Equal(t, "tag_kubernetes_io_service_name", TagLabel("kubernetes.io/service-name"))
+}
+
+func Test_clusterName(t *testing.T) {
+	assert.Equal(t, "prod", clusterName(map[string]string{"elbv2.k8s.aws/cluster": "prod"}))
+	assert.Equal(t, "dev", clusterName(map[string]string{"kubernetes.io/cluster/dev": "owned", "team": "payments"}))
+	assert.Equal(t, "", clusterName(map[string]string{"team": "payments"}))
+}
+
+func TestCollector_Collect(t *testing.T) {
+	pricingClient := mockpricing.NewPricing(t)
+	pricingClient.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+			if input.NextToken == nil {
+				return &pricing.GetProductsOutput{PriceList: []string{applicationHourlyProduct, applicationLCUProduct}, NextToken: aws.String("next")}, nil
+			}
+			return &pricing.GetProductsOutput{PriceList: []string{classicHourlyProduct}}, nil
+		}).
+		Times(2)
+
+	usEast := mockelb.NewELB(t)
+	usEast.EXPECT().DescribeLoadBalancers(mock.Anything, &elbclient.DescribeLoadBalancersInput{PageSize: aws.Int32(400)}).
+		Return(&elbclient.DescribeLoadBalancersOutput{
+			LoadBalancers: []elbclient.LoadBalancer{
+				{LoadBalancerArn: aws.String("arn:ingress"), LoadBalancerName: aws.String("ingress"), Type: aws.String(TypeApplication)},
+				// There's no price of the network load balancers
+				{LoadBalancerArn: aws.String("arn:nlb"), LoadBalancerName: aws.String("nlb"), Type: aws.String(TypeNetwork)},
+			},
+		}, nil).Once()
+	usEast.EXPECT().DescribeTags(mock.Anything, &elbclient.DescribeTagsInput{ResourceArns: []string{"arn:ingress", "arn:nlb"}}).
+		Return(&elbclient.DescribeTagsOutput{
+			TagDescriptions: []elbclient.TagDescription{{
+				ResourceArn: aws.String("arn:ingress"),
+				Tags: []elbclient.Tag{
+					{Key: aws.String("elbv2.k8s.aws/cluster"), Value: aws.String("Prod")},
+					{Key: aws.String("team"), Value: aws.String("payments")},
+				},
+			}},
+		}, nil).Once()
+	usEast.EXPECT().DescribeClassicLoadBalancers(mock.Anything, &elbclient.DescribeClassicLoadBalancersInput{PageSize: aws.Int32(400)}).
+		Return(&elbclient.DescribeClassicLoadBalancersOutput{
+			LoadBalancerDescriptions: []elbclient.ClassicLoadBalancer{{LoadBalancerName: aws.String("legacy")}},
+		}, nil).Once()
+	usEast.EXPECT().DescribeClassicTags(mock.Anything, &elbclient.DescribeClassicTagsInput{LoadBalancerNames: []string{"legacy"}}).
+		Return(&elbclient.DescribeTagsOutput{}, nil).Once()
+	euWest := mockelb.NewELB(t)
+	euWest.EXPECT().DescribeLoadBalancers(mock.Anything, mock.Anything).Return(nil, errors.New("AccessDenied")).Once()
+
+	c := New(&Config{Tags: []string{"team"}, ClusterNames: clustername.NewNormalizer(true, nil)}, pricingClient, map[string]elbclient.ELB{"us-east-1": usEast, "eu-west-1": euWest})
+	ch := make(chan prometheus.Metric)
+	go func() {
+		assert.NoError(t, c.Collect(ch))
+		close(ch)
+	}()
+	var metrics []*utils.MetricResult
+	for m := range ch {
+		metrics = append(metrics, utils.ReadMetrics(m))
+	}
+	require.Equal(t, []*utils.MetricResult{
+		{FqName: "cloudcost_aws_elb_lcu_usd_per_hour", Labels: map[string]string{"region": "us-east-1", "type": TypeApplication}, Value: 0.008, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_exporter_collector_refresh_interval_seconds", Labels: map[string]string{"provider": "aws", "collector": "ELB"}, Value: 0, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_exporter_collector_scope_last_scrape_error", Labels: map[string]string{"provider": "aws", "collector": "aws_elb", "scope": "eu-west-1"}, Value: 1, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_exporter_collector_scope_last_scrape_error", Labels: map[string]string{"provider": "aws", "collector": "aws_elb", "scope": "us-east-1"}, Value: 0, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_aws_elb_usd_per_hour", Labels: map[string]string{"region": "us-east-1", "load_balancer": "ingress", "type": TypeApplication, "cluster_name": "prod", "tag_team": "payments"}, Value: 0.0225, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_aws_elb_usd_per_hour", Labels: map[string]string{"region": "us-east-1", "load_balancer": "legacy", "type": TypeClassic, "cluster_name": "", "tag_team": ""}, Value: 0.025, MetricType: prometheus.GaugeValue},
+	}, metrics)
+}
+
+func TestCollector_CollectEveryRegionFails(t *testing.T) {
+	pricingClient := mockpricing.NewPricing(t)
+	pricingClient.EXPECT().GetProducts(mock.Anything, mock.Anything).Return(&pricing.GetProductsOutput{}, nil).Once()
+	usEast := mockelb.NewELB(t)
+	usEast.EXPECT().DescribeLoadBalancers(mock.Anything, mock.Anything).Return(nil, errors.New("AccessDenied")).Once()
+
+	c := New(&Config{}, pricingClient, map[string]elbclient.ELB{"us-east-1": usEast})
+	ch := make(chan prometheus.Metric, 10)
+	require.ErrorContains(t, c.Collect(ch), "us-east-1")
+}
@@ -0,0 +1,348 @@
+package fargate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	ecsclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/ecs"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/unitprice"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "aws"
+	subsystem    = "aws_fargate"
+
+	serviceCode = "AmazonECS"
+
+	priceTierOnDemand = "ondemand"
+	priceTierSpot     = "spot"
+
+	architectureX86 = "x86_64"
+	architectureARM = "arm64"
+
+	// spotCapacityProvider is the capacity provider of the tasks running on spare capacity
+	spotCapacityProvider = "FARGATE_SPOT"
+	// architectureAttribute is the attribute of a task holding its cpu architecture
+	architectureAttribute = "ecs.cpu-architecture"
+
+	// cpuUnitsPerVCPU and mibPerGiB convert the cpu and memory of a task into the units Fargate is billed in
+	cpuUnitsPerVCPU = 1024
+	mibPerGiB       = 1024
+	// maxDescribeTasks is the number of tasks DescribeTasks accepts at once
+	maxDescribeTasks = 100
+)
+
+var (
+	CPUHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "cpu_usd_per_vcpu_hour"),
+		"The price of a vCPU of a Fargate task in USD/(vCPU*h).",
+		[]string{"region", "price_tier", "architecture"},
+		nil,
+	)
+	MemoryHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "memory_usd_per_gib_hour"),
+		"The price of a GiB of memory of a Fargate task in USD/(GiB*h).",
+		[]string{"region", "price_tier", "architecture"},
+		nil,
+	)
+	TaskCPUHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "task_cpu_usd_per_hour"),
+		"The hourly cost of the vCPUs of a running ECS task on Fargate in USD/h. service is empty for the tasks that don't belong to a service.",
+		[]string{"region", "cluster", "service", "task", "price_tier", "architecture"},
+		nil,
+	)
+	TaskMemoryHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "task_memory_usd_per_hour"),
+		"The hourly cost of the memory of a running ECS task on Fargate in USD/h. service is empty for the tasks that don't belong to a service.",
+		[]string{"region", "cluster", "service", "task", "price_tier", "architecture"},
+		nil,
+	)
+)
+
+// unitPrices are the prices of the vCPU and GB hours of the Linux tasks. The usage types of the ARM tasks and of the
+// Spot tasks have their own suffix, eg USE1-Fargate-ARM-vCPU-Hours:perCPU.
+var unitPrices = []unitprice.UnitPrice{
+	{ServiceCode: serviceCode, UsageTypeSuffix: "Fargate-vCPU-Hours:perCPU", Desc: CPUHourlyPriceDesc, Labels: []string{priceTierOnDemand, architectureX86}, Scale: 1},
+	{ServiceCode: serviceCode, UsageTypeSuffix: "Fargate-GB-Hours", Desc: MemoryHourlyPriceDesc, Labels: []string{priceTierOnDemand, architectureX86}, Scale: 1},
+	{ServiceCode: serviceCode, UsageTypeSuffix: "Fargate-ARM-vCPU-Hours:perCPU", Desc: CPUHourlyPriceDesc, Labels: []string{priceTierOnDemand, architectureARM}, Scale: 1},
+	{ServiceCode: serviceCode, UsageTypeSuffix: "Fargate-ARM-GB-Hours", Desc: MemoryHourlyPriceDesc, Labels: []string{priceTierOnDemand, architectureARM}, Scale: 1},
+	{ServiceCode: serviceCode, UsageTypeSuffix: "Fargate-SpotUsage-vCPU-Hours:perCPU", Desc: CPUHourlyPriceDesc, Labels: []string{priceTierSpot, architectureX86}, Scale: 1},
+	{ServiceCode: serviceCode, UsageTypeSuffix: "Fargate-SpotUsage-GB-Hours", Desc: MemoryHourlyPriceDesc, Labels: []string{priceTierSpot, architectureX86}, Scale: 1},
+}
+
+// priceKey selects the prices of a task.
+type priceKey struct {
+	region       string
+	priceTier    string
+	architecture string
+}
+
+// rates are the prices of a vCPU and of a GiB of memory in USD/h.
+type rates struct {
+	cpu    float64
+	memory float64
+}
+
+// task is a running Fargate task and the resources it's billed for.
+type task struct {
+	cluster      string
+	service      string
+	id           string
+	priceTier    string
+	architecture string
+	vcpus        float64
+	memoryGiB    float64
+}
+
+// Collector exports the prices of Fargate per region and the cost of the running ECS tasks on Fargate. The prices are
+// listed every scrape interval and the tasks on every scrape.
+type Collector struct {
+	pricingClient pricingClient.Pricing
+	regionClients map[string]ecsclient.ECS
+	regions       *compute.RegionFilter
+	backoff       *provider.Backoff
+	nextScrape    time.Time
+	prices        []unitprice.Price
+	rates         map[priceKey]rates
+	m             sync.Mutex
+}
+
+// New creates a Collector listing the tasks of the regions of regionClients. The prices are only listed again every
+// scrapeInterval, or less often while the pricing API throttles the collector. regions selects the regions whose
+// prices are exported, every region is when nil.
+func New(scrapeInterval time.Duration, client pricingClient.Pricing, regionClients map[string]ecsclient.ECS, regions *compute.RegionFilter) *Collector {
+	c := &Collector{
+		pricingClient: client,
+		regionClients: regionClients,
+		regions:       regions,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), scrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Fargate"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- CPUHourlyPriceDesc
+	ch <- MemoryHourlyPriceDesc
+	ch <- TaskCPUHourlyCostDesc
+	ch <- TaskMemoryHourlyCostDesc
+	ch <- provider.RefreshIntervalDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		TaskCPUHourlyCostDesc,
+		TaskMemoryHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices again when the scrape interval has passed, and the running tasks of every region. A region
+// failing is reported in its scope error metric and only fails the collector when every region failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := unitprice.List(context.TODO(), c.pricingClient, unitPrices, c.regions)
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing fargate prices: %w", err)
+		}
+		c.prices = prices
+		c.rates = ratesOf(prices)
+	}
+	for _, p := range c.prices {
+		ch <- p.Metric()
+	}
+	c.backoff.Emit(ch)
+
+	regions := make([]string, 0, len(c.regionClients))
+	for region := range c.regionClients {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	tasks := make([][]task, len(regions))
+	errs := make([]error, len(regions))
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tasks[i], errs[i] = listTasks(context.Background(), c.regionClients[region])
+		}()
+	}
+	wg.Wait()
+
+	var failedRegions []error
+	for i, region := range regions {
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, errs[i])
+		if errs[i] != nil {
+			log.Printf("error listing fargate tasks in region %s: %s", region, errs[i])
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, errs[i]))
+			continue
+		}
+		for _, t := range tasks[i] {
+			r, ok := c.rates[priceKey{region: region, priceTier: t.priceTier, architecture: t.architecture}]
+			if !ok {
+				log.Printf("no %s %s fargate prices found for task %s in region %s", t.priceTier, t.architecture, t.id, region)
+				continue
+			}
+			labelValues := []string{region, t.cluster, t.service, t.id, t.priceTier, t.architecture}
+			ch <- prometheus.MustNewConstMetric(TaskCPUHourlyCostDesc, prometheus.GaugeValue, t.vcpus*r.cpu, labelValues...)
+			ch <- prometheus.MustNewConstMetric(TaskMemoryHourlyCostDesc, prometheus.GaugeValue, t.memoryGiB*r.memory, labelValues...)
+		}
+	}
+	if len(regions) > 0 && len(failedRegions) == len(regions) {
+		return errors.Join(failedRegions...)
+	}
+	return nil
+}
+
+// ratesOf returns the prices of a vCPU and of a GiB of memory keyed by region, price tier and architecture. Only the
+// keys with both prices are kept.
+func ratesOf(prices []unitprice.Price) map[priceKey]rates {
+	cpu := make(map[priceKey]float64)
+	memory := make(map[priceKey]float64)
+	for _, p := range prices {
+		key := priceKey{region: p.Region, priceTier: p.UnitPrice.Labels[0], architecture: p.UnitPrice.Labels[1]}
+		if p.UnitPrice.Desc == CPUHourlyPriceDesc {
+			cpu[key] = p.Value
+		} else {
+			memory[key] = p.Value
+		}
+	}
+	r := make(map[priceKey]rates)
+	for key, cpuPrice := range cpu {
+		if memoryPrice, ok := memory[key]; ok {
+			r[key] = rates{cpu: cpuPrice, memory: memoryPrice}
+		}
+	}
+	return r
+}
+
+// listTasks lists the running Fargate tasks of every cluster of a region.
+func listTasks(ctx context.Context, client ecsclient.ECS) ([]task, error) {
+	var clusters []string
+	input := &ecsclient.ListClustersInput{}
+	for {
+		resp, err := client.ListClusters(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error listing clusters: %w", err)
+		}
+		clusters = append(clusters, resp.ClusterArns...)
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+
+	var tasks []task
+	for _, cluster := range clusters {
+		arns, err := listTaskArns(ctx, client, cluster)
+		if err != nil {
+			return nil, err
+		}
+		for start := 0; start < len(arns); start += maxDescribeTasks {
+			end := min(start+maxDescribeTasks, len(arns))
+			resp, err := client.DescribeTasks(ctx, &ecsclient.DescribeTasksInput{Cluster: aws.String(cluster), Tasks: arns[start:end]})
+			if err != nil {
+				return nil, fmt.Errorf("error describing tasks of cluster %s: %w", cluster, err)
+			}
+			for _, t := range resp.Tasks {
+				parsed, err := parseTask(t)
+				if err != nil {
+					log.Printf("error parsing fargate task %s: %s, skipping", aws.ToString(t.TaskArn), err)
+					continue
+				}
+				tasks = append(tasks, parsed)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+func listTaskArns(ctx context.Context, client ecsclient.ECS, cluster string) ([]string, error) {
+	var arns []string
+	input := &ecsclient.ListTasksInput{Cluster: aws.String(cluster), LaunchType: "FARGATE", DesiredStatus: "RUNNING"}
+	for {
+		resp, err := client.ListTasks(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error listing tasks of cluster %s: %w", cluster, err)
+		}
+		arns = append(arns, resp.TaskArns...)
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+	return arns, nil
+}
+
+// parseTask returns the resources a task is billed for. The service of a task is its group when the group is
+// service:<name>, the tasks started on their own are grouped by family instead.
+func parseTask(t ecsclient.Task) (task, error) {
+	cpuUnits, err := strconv.ParseFloat(aws.ToString(t.Cpu), 64)
+	if err != nil {
+		return task{}, fmt.Errorf("invalid cpu %q: %w", aws.ToString(t.Cpu), err)
+	}
+	memoryMiB, err := strconv.ParseFloat(aws.ToString(t.Memory), 64)
+	if err != nil {
+		return task{}, fmt.Errorf("invalid memory %q: %w", aws.ToString(t.Memory), err)
+	}
+	parsed := task{
+		cluster:      lastSegment(aws.ToString(t.ClusterArn)),
+		id:           lastSegment(aws.ToString(t.TaskArn)),
+		priceTier:    priceTierOnDemand,
+		architecture: architectureX86,
+		vcpus:        cpuUnits / cpuUnitsPerVCPU,
+		memoryGiB:    memoryMiB / mibPerGiB,
+	}
+	if service, ok := strings.CutPrefix(aws.ToString(t.Group), "service:"); ok {
+		parsed.service = service
+	}
+	if aws.ToString(t.CapacityProviderName) == spotCapacityProvider {
+		parsed.priceTier = priceTierSpot
+	}
+	for _, attribute := range t.Attributes {
+		if aws.ToString(attribute.Name) == architectureAttribute && aws.ToString(attribute.Value) == architectureARM {
+			parsed.architecture = architectureARM
+		}
+	}
+	return parsed, nil
+}
+
+// lastSegment returns the name of a resource from its ARN, eg the cluster of arn:aws:ecs:us-east-1:123:cluster/name.
+func lastSegment(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
@@ -0,0 +1,126 @@
+package fargate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockecs "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ecs"
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	ecsclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/ecs"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	vcpuProduct     = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"USE1-Fargate-vCPU-Hours:perCPU"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.1":{"beginRange":"0","pricePerUnit":{"USD":"0.0404800000"}}}}}}}`
+	memoryProduct   = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"USE1-Fargate-GB-Hours"}},"terms":{"OnDemand":{"C.D":{"priceDimensions":{"C.D.1":{"beginRange":"0","pricePerUnit":{"USD":"0.0044450000"}}}}}}}`
+	armVCPUProduct  = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"USE1-Fargate-ARM-vCPU-Hours:perCPU"}},"terms":{"OnDemand":{"E.F":{"priceDimensions":{"E.F.1":{"beginRange":"0","pricePerUnit":{"USD":"0.0323800000"}}}}}}}`
+	spotVCPUProduct = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"USE1-Fargate-SpotUsage-vCPU-Hours:perCPU"}},"terms":{"OnDemand":{"G.H":{"priceDimensions":{"G.H.1":{"beginRange":"0","pricePerUnit":{"USD":"0.0121400000"}}}}}}}`
+	spotGBProduct   = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"USE1-Fargate-SpotUsage-GB-Hours"}},"terms":{"OnDemand":{"I.J":{"priceDimensions":{"I.J.1":{"beginRange":"0","pricePerUnit":{"USD":"0.0013300000"}}}}}}}`
+)
+
+func Test_parseTask(t *testing.T) {
+	tests := map[string]struct {
+		task    ecsclient.Task
+		want    task
+		wantErr bool
+	}{
+		"service task": {
+			task: ecsclient.Task{
+				TaskArn:    aws.String("arn:aws:ecs:us-east-1:123:task/prod/abc"),
+				ClusterArn: aws.String("arn:aws:ecs:us-east-1:123:cluster/prod"),
+				Group:      aws.String("service:api"),
+				Cpu:        aws.String("512"),
+				Memory:     aws.String("2048"),
+			},
+			want: task{cluster: "prod", service: "api", id: "abc", priceTier: "ondemand", architecture: "x86_64", vcpus: 0.5, memoryGiB: 2},
+		},
+		"standalone spot arm task": {
+			task: ecsclient.Task{
+				TaskArn:              aws.String("arn:aws:ecs:us-east-1:123:task/prod/def"),
+				ClusterArn:           aws.String("arn:aws:ecs:us-east-1:123:cluster/prod"),
+				Group:                aws.String("family:migrate"),
+				CapacityProviderName: aws.String("FARGATE_SPOT"),
+				Cpu:                  aws.String("1024"),
+				Memory:               aws.String("512"),
+				Attributes:           []ecsclient.Attribute{{Name: aws.String("ecs.cpu-architecture"), Value: aws.String("arm64")}},
+			},
+			want: task{cluster: "prod", id: "def", priceTier: "spot", architecture: "arm64", vcpus: 1, memoryGiB: 0.5},
+		},
+		"missing cpu": {
+			task:    ecsclient.Task{Memory: aws.String("512")},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseTask(tt.task)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	pricingClient := mockpricing.NewPricing(t)
+	pricingClient.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		Return(&pricing.GetProductsOutput{PriceList: []string{vcpuProduct, memoryProduct, armVCPUProduct, spotVCPUProduct, spotGBProduct}}, nil).
+		Once()
+
+	usEast := mockecs.NewECS(t)
+	usEast.EXPECT().ListClusters(mock.Anything, mock.Anything).Return(&ecsclient.ListClustersOutput{ClusterArns: []string{"arn:aws:ecs:us-east-1:123:cluster/prod"}}, nil)
+	usEast.EXPECT().ListTasks(mock.Anything, mock.Anything).Return(&ecsclient.ListTasksOutput{TaskArns: []string{"abc", "def"}}, nil)
+	usEast.EXPECT().DescribeTasks(mock.Anything, mock.Anything).Return(&ecsclient.DescribeTasksOutput{Tasks: []ecsclient.Task{
+		{TaskArn: aws.String("abc"), ClusterArn: aws.String("arn:aws:ecs:us-east-1:123:cluster/prod"), Group: aws.String("service:api"), Cpu: aws.String("512"), Memory: aws.String("2048")},
+		// The arm tasks have no memory price, so they can't be priced
+		{TaskArn: aws.String("def"), ClusterArn: aws.String("arn:aws:ecs:us-east-1:123:cluster/prod"), Cpu: aws.String("512"), Memory: aws.String("2048"),
+			Attributes: []ecsclient.Attribute{{Name: aws.String("ecs.cpu-architecture"), Value: aws.String("arm64")}}},
+	}}, nil)
+	euWest := mockecs.NewECS(t)
+	euWest.EXPECT().ListClusters(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(time.Hour, pricingClient, map[string]ecsclient.ECS{"us-east-1": usEast, "eu-west-1": euWest}, nil)
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, c.Collect(ch))
+	close(ch)
+	var metrics []*utils.MetricResult
+	for metric := range ch {
+		metrics = append(metrics, utils.ReadMetrics(metric))
+	}
+
+	require.Len(t, metrics, 10)
+	assert.Equal(t, "cloudcost_aws_fargate_cpu_usd_per_vcpu_hour", metrics[0].FqName)
+	assert.Equal(t, utils.LabelMap{"region": "us-east-1", "price_tier": "ondemand", "architecture": "x86_64"}, metrics[0].Labels)
+	assert.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[5].FqName)
+	assert.Equal(t, "cloudcost_exporter_collector_scope_last_scrape_error", metrics[6].FqName)
+	assert.Equal(t, utils.LabelMap{"provider": "aws", "collector": "aws_fargate", "scope": "eu-west-1"}, metrics[6].Labels)
+	assert.Equal(t, 1.0, metrics[6].Value)
+
+	labels := utils.LabelMap{"region": "us-east-1", "cluster": "prod", "service": "api", "task": "abc", "price_tier": "ondemand", "architecture": "x86_64"}
+	assert.Equal(t, "cloudcost_aws_fargate_task_cpu_usd_per_hour", metrics[8].FqName)
+	assert.Equal(t, labels, metrics[8].Labels)
+	assert.InDelta(t, 0.5*0.04048, metrics[8].Value, 1e-9)
+	assert.Equal(t, "cloudcost_aws_fargate_task_memory_usd_per_hour", metrics[9].FqName)
+	assert.InDelta(t, 2*0.004445, metrics[9].Value, 1e-9)
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	pricingClient := mockpricing.NewPricing(t)
+	pricingClient.EXPECT().GetProducts(mock.Anything, mock.Anything).Return(&pricing.GetProductsOutput{}, nil).Once()
+	client := mockecs.NewECS(t)
+	client.EXPECT().ListClusters(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(time.Hour, pricingClient, map[string]ecsclient.ECS{"us-east-1": client}, nil)
+	ch := make(chan prometheus.Metric, 10)
+	assert.ErrorIs(t, c.Collect(ch), assert.AnError)
+}
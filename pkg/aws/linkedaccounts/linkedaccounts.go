@@ -0,0 +1,171 @@
+package linkedaccounts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscostexplorer "github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/services/costexplorer"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	subsystem = "aws_linked_account"
+	// costMetric is the cost that is reported. Unblended costs are what each linked account is actually charged
+	// before any sharing of reservations or savings plans across the organization.
+	costMetric = "UnblendedCost"
+	// accountNameAttribute is the attribute Cost Explorer uses for the name of a linked account.
+	accountNameAttribute = "description"
+	dateFormat           = "2006-01-02"
+)
+
+var (
+	MonthToDateCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "month_to_date_cost_usd"),
+		"The unblended cost of a linked account of the organization since the start of the month in USD.",
+		[]string{"account_id", "account_name"},
+		nil,
+	)
+)
+
+// accountCost is the spend of a single linked account.
+type accountCost struct {
+	id   string
+	name string
+	cost float64
+}
+
+// Collector exports the actual spend of every linked account of an organization using consolidated billing. It's
+// meant to run against the payer account, which is the only account that can see the costs of all linked accounts.
+type Collector struct {
+	client     costexplorer.CostExplorer
+	interval   time.Duration
+	nextScrape time.Time
+	costs      []accountCost
+	m          sync.Mutex
+}
+
+// New creates a Collector. Cost Explorer charges per request, so the costs are only refreshed every scrapeInterval.
+func New(scrapeInterval time.Duration, client costexplorer.CostExplorer) *Collector {
+	return &Collector{
+		client:   client,
+		interval: scrapeInterval,
+	}
+}
+
+func (c *Collector) Name() string {
+	return "LinkedAccounts"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- MonthToDateCostDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect refreshes the costs of the linked accounts when the scrape interval has passed and exports them.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.costs == nil || now.After(c.nextScrape) {
+		costs, err := getMonthToDateCosts(context.TODO(), c.client, now)
+		if err != nil {
+			return fmt.Errorf("error getting costs of linked accounts: %w", err)
+		}
+		c.costs = costs
+		c.nextScrape = utils.NextScrape(now, c.interval)
+	}
+	for _, account := range c.costs {
+		ch <- prometheus.MustNewConstMetric(MonthToDateCostDesc, prometheus.GaugeValue, account.cost, account.id, account.name)
+	}
+	return nil
+}
+
+// getMonthToDateCosts returns the costs of every linked account from the start of the month of now.
+func getMonthToDateCosts(ctx context.Context, client costexplorer.CostExplorer, now time.Time) ([]accountCost, error) {
+	// Cost Explorer dates are in UTC
+	now = now.UTC()
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	input := &awscostexplorer.GetCostAndUsageInput{
+		TimePeriod: &types.DateInterval{
+			Start: aws.String(startOfMonth.Format(dateFormat)),
+			// The end date is exclusive, so tomorrow is used to include the costs of today so far. This also keeps
+			// the interval valid on the first day of the month.
+			End: aws.String(now.AddDate(0, 0, 1).Format(dateFormat)),
+		},
+		Granularity: types.GranularityMonthly,
+		Metrics:     []string{costMetric},
+		GroupBy: []types.GroupDefinition{
+			{
+				Type: types.GroupDefinitionTypeDimension,
+				Key:  aws.String(string(types.DimensionLinkedAccount)),
+			},
+		},
+	}
+
+	costsByAccount := make(map[string]float64)
+	names := make(map[string]string)
+	var accounts []string
+	for {
+		output, err := client.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, attributes := range output.DimensionValueAttributes {
+			if attributes.Value != nil {
+				names[*attributes.Value] = attributes.Attributes[accountNameAttribute]
+			}
+		}
+		for _, result := range output.ResultsByTime {
+			for _, group := range result.Groups {
+				if len(group.Keys) == 0 {
+					log.Printf("skipping group without keys")
+					continue
+				}
+				metric, ok := group.Metrics[costMetric]
+				if !ok || metric.Amount == nil {
+					continue
+				}
+				cost, err := strconv.ParseFloat(*metric.Amount, 64)
+				if err != nil {
+					log.Printf("Error parsing cost of linked account %s: %v", group.Keys[0], err)
+					continue
+				}
+				if _, ok := costsByAccount[group.Keys[0]]; !ok {
+					accounts = append(accounts, group.Keys[0])
+				}
+				costsByAccount[group.Keys[0]] += cost
+			}
+		}
+		if output.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+
+	costs := make([]accountCost, 0, len(accounts))
+	for _, id := range accounts {
+		costs = append(costs, accountCost{id: id, name: names[id], cost: costsByAccount[id]})
+	}
+	return costs, nil
+}
@@ -0,0 +1,143 @@
+package linkedaccounts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscostexplorer "github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockcostexplorer "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/costexplorer"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func accountGroup(id string, amount string) types.Group {
+	return types.Group{
+		Keys:    []string{id},
+		Metrics: map[string]types.MetricValue{costMetric: {Amount: aws.String(amount), Unit: aws.String("USD")}},
+	}
+}
+
+func Test_getMonthToDateCosts(t *testing.T) {
+	now := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		GetCostAndUsage func(ctx context.Context, params *awscostexplorer.GetCostAndUsageInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageOutput, error)
+		expectedCalls   int
+		want            []accountCost
+		err             error
+	}{
+		"costs are grouped by linked account": {
+			GetCostAndUsage: func(ctx context.Context, params *awscostexplorer.GetCostAndUsageInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageOutput, error) {
+				assert.Equal(t, "2024-07-01", *params.TimePeriod.Start)
+				assert.Equal(t, "2024-07-16", *params.TimePeriod.End)
+				assert.Equal(t, types.DimensionLinkedAccount, types.Dimension(*params.GroupBy[0].Key))
+				return &awscostexplorer.GetCostAndUsageOutput{
+					DimensionValueAttributes: []types.DimensionValuesWithAttributes{
+						{Value: aws.String("111111111111"), Attributes: map[string]string{"description": "production"}},
+						{Value: aws.String("222222222222"), Attributes: map[string]string{"description": "staging"}},
+					},
+					ResultsByTime: []types.ResultByTime{{
+						Groups: []types.Group{
+							accountGroup("111111111111", "1234.5"),
+							accountGroup("222222222222", "10"),
+							// Unparsable amounts and groups without keys are skipped
+							accountGroup("333333333333", "NaN?"),
+							{Metrics: map[string]types.MetricValue{costMetric: {Amount: aws.String("1")}}},
+						},
+					}},
+				}, nil
+			},
+			expectedCalls: 1,
+			want: []accountCost{
+				{id: "111111111111", name: "production", cost: 1234.5},
+				{id: "222222222222", name: "staging", cost: 10},
+			},
+		},
+		"pages are followed": {
+			GetCostAndUsage: func(ctx context.Context, params *awscostexplorer.GetCostAndUsageInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageOutput, error) {
+				if params.NextPageToken == nil {
+					return &awscostexplorer.GetCostAndUsageOutput{
+						NextPageToken: aws.String("token"),
+						ResultsByTime: []types.ResultByTime{{Groups: []types.Group{accountGroup("111111111111", "1")}}},
+					}, nil
+				}
+				return &awscostexplorer.GetCostAndUsageOutput{
+					ResultsByTime: []types.ResultByTime{{Groups: []types.Group{accountGroup("111111111111", "2")}}},
+				}, nil
+			},
+			expectedCalls: 2,
+			want:          []accountCost{{id: "111111111111", cost: 3}},
+		},
+		"errors propagate": {
+			GetCostAndUsage: func(ctx context.Context, params *awscostexplorer.GetCostAndUsageInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageOutput, error) {
+				return nil, assert.AnError
+			},
+			expectedCalls: 1,
+			err:           assert.AnError,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := mockcostexplorer.NewCostExplorer(t)
+			client.EXPECT().
+				GetCostAndUsage(mock.Anything, mock.Anything, mock.Anything).
+				RunAndReturn(tt.GetCostAndUsage).
+				Times(tt.expectedCalls)
+
+			got, err := getMonthToDateCosts(context.Background(), client, now)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	client := mockcostexplorer.NewCostExplorer(t)
+	client.EXPECT().
+		GetCostAndUsage(mock.Anything, mock.Anything, mock.Anything).
+		Return(&awscostexplorer.GetCostAndUsageOutput{
+			DimensionValueAttributes: []types.DimensionValuesWithAttributes{
+				{Value: aws.String("111111111111"), Attributes: map[string]string{"description": "production"}},
+			},
+			ResultsByTime: []types.ResultByTime{{Groups: []types.Group{accountGroup("111111111111", "42")}}},
+		}, nil).
+		// The costs are cached until the next scrape
+		Once()
+	collector := New(time.Hour, client)
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 1)
+		require.NoError(t, collector.Collect(ch))
+		close(ch)
+		var got []*utils.MetricResult
+		for m := range ch {
+			got = append(got, utils.ReadMetrics(m))
+		}
+		assert.Equal(t, []*utils.MetricResult{{
+			FqName:     "cloudcost_aws_linked_account_month_to_date_cost_usd",
+			Labels:     utils.LabelMap{"account_id": "111111111111", "account_name": "production"},
+			Value:      42,
+			MetricType: prometheus.GaugeValue,
+		}}, got)
+	}
+}
+
+func TestCollector_Collect_error(t *testing.T) {
+	client := mockcostexplorer.NewCostExplorer(t)
+	client.EXPECT().
+		GetCostAndUsage(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, assert.AnError)
+	collector := New(time.Hour, client)
+
+	require.ErrorIs(t, collector.Collect(make(chan prometheus.Metric)), assert.AnError)
+}
@@ -0,0 +1,126 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/unitprice"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "aws"
+
+	sqsServiceCode     = "AWSQueueService"
+	snsServiceCode     = "AmazonSNS"
+	kinesisServiceCode = "AmazonKinesis"
+
+	perMillion = 1e6
+)
+
+var (
+	SQSRequestPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "aws_sqs", "usd_per_million_requests"),
+		"The price of a million SQS requests in USD, at the first paid tier. queue_type is either standard or fifo.",
+		[]string{"region", "queue_type"},
+		nil,
+	)
+	SNSRequestPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "aws_sns", "usd_per_million_requests"),
+		"The price of a million SNS API requests, eg publishes, in USD at the first paid tier. Deliveries are priced separately.",
+		[]string{"region"},
+		nil,
+	)
+	KinesisShardHourPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "aws_kinesis", "shard_usd_per_hour"),
+		"The price of a provisioned Kinesis Data Streams shard in USD/h.",
+		[]string{"region"},
+		nil,
+	)
+	KinesisPutPayloadUnitPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "aws_kinesis", "put_payload_usd_per_million_units"),
+		"The price of a million PUT payload units of a provisioned Kinesis Data Stream in USD. A record is billed a unit per started 25KB.",
+		[]string{"region"},
+		nil,
+	)
+)
+
+var unitPrices = []unitprice.UnitPrice{
+	{ServiceCode: sqsServiceCode, UsageTypeSuffix: "Requests-Tier1", Desc: SQSRequestPriceDesc, Labels: []string{"standard"}, Scale: perMillion},
+	{ServiceCode: sqsServiceCode, UsageTypeSuffix: "Requests-FIFO-Tier1", Desc: SQSRequestPriceDesc, Labels: []string{"fifo"}, Scale: perMillion},
+	{ServiceCode: snsServiceCode, UsageTypeSuffix: "Requests-Tier1", Desc: SNSRequestPriceDesc, Scale: perMillion},
+	{ServiceCode: kinesisServiceCode, UsageTypeSuffix: "Storage-ShardHour", Desc: KinesisShardHourPriceDesc, Scale: 1},
+	{ServiceCode: kinesisServiceCode, UsageTypeSuffix: "PutRequestPayloadUnits", Desc: KinesisPutPayloadUnitPriceDesc, Scale: perMillion},
+}
+
+// Collector exports the unit prices of SQS, SNS and Kinesis Data Streams per region. Combined with the usage metrics
+// of CloudWatch, eg NumberOfMessagesSent, they estimate the spend of the messaging services in PromQL.
+type Collector struct {
+	client     pricingClient.Pricing
+	regions    *compute.RegionFilter
+	backoff    *provider.Backoff
+	nextScrape time.Time
+	prices     []unitprice.Price
+	m          sync.Mutex
+}
+
+// New creates a Collector. The prices are only listed again every scrapeInterval, or less often while the pricing API
+// throttles the collector. regions selects the regions whose prices are exported, every region is when nil.
+func New(scrapeInterval time.Duration, client pricingClient.Pricing, regions *compute.RegionFilter) *Collector {
+	c := &Collector{
+		client:  client,
+		regions: regions,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), scrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Messaging"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- SQSRequestPriceDesc
+	ch <- SNSRequestPriceDesc
+	ch <- KinesisShardHourPriceDesc
+	ch <- KinesisPutPayloadUnitPriceDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the unit prices again when the scrape interval has passed and exports them.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := unitprice.List(context.TODO(), c.client, unitPrices, c.regions)
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing messaging prices: %w", err)
+		}
+		c.prices = prices
+	}
+	for _, p := range c.prices {
+		ch <- p.Metric()
+	}
+	c.backoff.Emit(ch)
+	return nil
+}
@@ -0,0 +1,145 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/unitprice"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	sqsStandardProduct = `{"product":{"productFamily":"API Request","attributes":{"regionCode":"eu-west-1","usagetype":"EU-Requests-Tier1"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.1":{"beginRange":"0","unit":"Requests","pricePerUnit":{"USD":"0.0000000000"}},"A.B.2":{"beginRange":"100000000000","unit":"Requests","pricePerUnit":{"USD":"0.0000003000"}},"A.B.3":{"beginRange":"1000000","unit":"Requests","pricePerUnit":{"USD":"0.0000004000"}}}}}}}`
+	sqsFifoProduct     = `{"product":{"productFamily":"API Request","attributes":{"regionCode":"us-east-1","usagetype":"Requests-FIFO-Tier1"}},"terms":{"OnDemand":{"C.D":{"priceDimensions":{"C.D.1":{"beginRange":"0","unit":"Requests","pricePerUnit":{"USD":"0.0000005000"}}}}}}}`
+	snsProduct         = `{"product":{"productFamily":"API Request","attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"E.F":{"priceDimensions":{"E.F.1":{"beginRange":"0","unit":"Requests","pricePerUnit":{"USD":"0.0000005000"}}}}}}}`
+	shardHourProduct   = `{"product":{"productFamily":"Kinesis Streams","attributes":{"regionCode":"us-east-1","usagetype":"USE1-Storage-ShardHour"}},"terms":{"OnDemand":{"G.H":{"priceDimensions":{"G.H.1":{"beginRange":"0","unit":"ShardHour","pricePerUnit":{"USD":"0.0150000000"}}}}}}}`
+	putPayloadProduct  = `{"product":{"productFamily":"Kinesis Streams","attributes":{"regionCode":"us-east-1","usagetype":"USE1-PutRequestPayloadUnits"}},"terms":{"OnDemand":{"I.J":{"priceDimensions":{"I.J.1":{"beginRange":"0","unit":"PutRequestPayloadUnits","pricePerUnit":{"USD":"0.0000000140"}}}}}}}`
+	retentionProduct   = `{"product":{"productFamily":"Kinesis Streams","attributes":{"regionCode":"us-east-1","usagetype":"USE1-Extended-ShardHour"}},"terms":{"OnDemand":{"K.L":{"priceDimensions":{"K.L.1":{"beginRange":"0","unit":"ShardHour","pricePerUnit":{"USD":"0.0200000000"}}}}}}}`
+)
+
+func Test_parsePrices(t *testing.T) {
+	tests := map[string]struct {
+		serviceCode string
+		products    []string
+		want        map[string]float64
+	}{
+		"sqs requests are priced at the first paid tier": {
+			serviceCode: sqsServiceCode,
+			products:    []string{sqsStandardProduct, sqsFifoProduct},
+			want: map[string]float64{
+				"eu-west-1/standard": 0.4,
+				"us-east-1/fifo":     0.5,
+			},
+		},
+		"sns requests": {
+			serviceCode: snsServiceCode,
+			products:    []string{snsProduct},
+			want:        map[string]float64{"us-east-1": 0.5},
+		},
+		"kinesis shard hours and put payload units": {
+			serviceCode: kinesisServiceCode,
+			products:    []string{shardHourProduct, putPayloadProduct, retentionProduct},
+			want: map[string]float64{
+				"us-east-1/shard":   0.015,
+				"us-east-1/payload": 0.014,
+			},
+		},
+		"products of another service are skipped": {
+			serviceCode: kinesisServiceCode,
+			products:    []string{snsProduct},
+			want:        map[string]float64{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make(map[string]float64)
+			for _, p := range unitprice.Parse(tt.serviceCode, tt.products, unitPrices) {
+				key := p.Region
+				switch p.UnitPrice.Desc {
+				case SQSRequestPriceDesc:
+					key += "/" + p.UnitPrice.Labels[0]
+				case KinesisShardHourPriceDesc:
+					key += "/shard"
+				case KinesisPutPayloadUnitPriceDesc:
+					key += "/payload"
+				}
+				got[key] = p.Value
+			}
+			require.Len(t, got, len(tt.want))
+			for key, value := range tt.want {
+				assert.InDelta(t, value, got[key], 1e-9, key)
+			}
+		})
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	products := map[string][]string{
+		sqsServiceCode:     {sqsStandardProduct, sqsFifoProduct},
+		snsServiceCode:     {snsProduct},
+		kinesisServiceCode: {shardHourProduct, putPayloadProduct},
+	}
+	client := mockpricing.NewPricing(t)
+	client.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+			return &pricing.GetProductsOutput{PriceList: products[aws.ToString(input.ServiceCode)]}, nil
+		}).
+		// The prices are only listed once per scrape interval
+		Times(3)
+	c := New(time.Hour, client, &compute.RegionFilter{Allow: []string{"us-*"}})
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		var metrics []*utils.MetricResult
+		for metric := range ch {
+			metrics = append(metrics, utils.ReadMetrics(metric))
+		}
+		// eu-west-1 is filtered out
+		require.Len(t, metrics, 5)
+		assert.Equal(t, "cloudcost_aws_sqs_usd_per_million_requests", metrics[0].FqName)
+		assert.Equal(t, utils.LabelMap{"region": "us-east-1", "queue_type": "fifo"}, metrics[0].Labels)
+		assert.Equal(t, "cloudcost_aws_sns_usd_per_million_requests", metrics[1].FqName)
+		assert.Equal(t, "cloudcost_aws_kinesis_shard_usd_per_hour", metrics[2].FqName)
+		assert.InDelta(t, 0.015, metrics[2].Value, 1e-9)
+		assert.Equal(t, "cloudcost_aws_kinesis_put_payload_usd_per_million_units", metrics[3].FqName)
+		assert.Equal(t, utils.LabelMap{"region": "us-east-1"}, metrics[3].Labels)
+		assert.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[4].FqName)
+		assert.Equal(t, time.Hour.Seconds(), metrics[4].Value)
+	}
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	client := mockpricing.NewPricing(t)
+	client.EXPECT().GetProducts(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+	c := New(time.Hour, client, nil)
+	ch := make(chan prometheus.Metric, 10)
+	assert.ErrorIs(t, c.Collect(ch), assert.AnError)
+	assert.Empty(t, ch)
+}
+
+func TestCollector_CollectThrottled(t *testing.T) {
+	client := mockpricing.NewPricing(t)
+	// The prices are listed again until they were listed once, every throttled refresh lengthens the interval
+	client.EXPECT().GetProducts(mock.Anything, mock.Anything).Return(nil, &smithy.GenericAPIError{Code: "ThrottlingException"}).Twice()
+	c := New(time.Hour, client, nil)
+	for _, want := range []time.Duration{2 * time.Hour, 4 * time.Hour} {
+		ch := make(chan prometheus.Metric, 10)
+		assert.True(t, provider.IsThrottled(c.Collect(ch)))
+		assert.Empty(t, ch)
+		assert.Equal(t, want, c.backoff.Interval())
+	}
+}
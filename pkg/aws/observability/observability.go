@@ -0,0 +1,274 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/unitprice"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "aws"
+	subsystem    = "aws_cloudwatch"
+
+	cloudWatchServiceCode = "AmazonCloudWatch"
+
+	// IngestionWindow is how far back the volume ingested by a log group is looked up.
+	IngestionWindow = time.Hour
+
+	// bytesPerGB is the size of the GB CloudWatch Logs bills ingestion in, which is binary.
+	bytesPerGB = 1 << 30
+)
+
+// logGroupSearch sums the bytes ingested by every log group of a region over the IngestionWindow. A search returns
+// at most 500 log groups.
+var logGroupSearch = fmt.Sprintf(`SEARCH('{AWS/Logs,LogGroupName} MetricName="IncomingBytes"', 'Sum', %d)`, int(IngestionWindow.Seconds()))
+
+var (
+	MetricPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "metric_usd_per_month"),
+		"The price of a custom CloudWatch metric for a month in USD, at the first paid tier.",
+		[]string{"region"},
+		nil,
+	)
+	LogsIngestionPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "logs_ingestion_usd_per_gb"),
+		"The price of a GB of logs ingested into the Standard log class of CloudWatch Logs in USD.",
+		[]string{"region"},
+		nil,
+	)
+	DashboardPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "dashboard_usd_per_month"),
+		"The price of a CloudWatch dashboard for a month in USD.",
+		[]string{"region"},
+		nil,
+	)
+	LogGroupIngestedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "log_group_ingested_gb"),
+		"The volume of logs ingested into a log group over the last hour in GB, uncompressed.",
+		[]string{"region", "log_group"},
+		nil,
+	)
+	LogGroupHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "log_group_usd_per_hour"),
+		"The ingestion cost of a log group over the last hour in USD, at the price of the Standard log class. Storage is billed separately.",
+		[]string{"region", "log_group"},
+		nil,
+	)
+)
+
+var unitPrices = []unitprice.UnitPrice{
+	{ServiceCode: cloudWatchServiceCode, UsageTypeSuffix: "CW:MetricMonitorUsage", Desc: MetricPriceDesc, Scale: 1},
+	{ServiceCode: cloudWatchServiceCode, UsageTypeSuffix: "DataProcessing-Bytes", Desc: LogsIngestionPriceDesc, Scale: 1},
+	{ServiceCode: cloudWatchServiceCode, UsageTypeSuffix: "DashboardsUsageHour", Desc: DashboardPriceDesc, Scale: 1},
+}
+
+// logGroup is the volume a log group ingested over the IngestionWindow.
+type logGroup struct {
+	region string
+	name   string
+	bytes  float64
+}
+
+// Collector exports the unit prices of CloudWatch metrics, log ingestion and dashboards per region. When the
+// CloudWatch clients of the regions are given, it also exports the volume every log group ingested over the last
+// hour and its cost.
+type Collector struct {
+	client           pricingClient.Pricing
+	regions          *compute.RegionFilter
+	cloudwatchClient map[string]cloudwatchclient.CloudWatch
+	backoff          *provider.Backoff
+	nextScrape       time.Time
+	prices           []unitprice.Price
+	logGroups        []logGroup
+	regionErrs       map[string]error
+	m                sync.Mutex
+}
+
+type Config struct {
+	ScrapeInterval time.Duration
+	// Regions selects the regions whose prices are exported, every region is when nil.
+	Regions *compute.RegionFilter
+	// CloudWatchRegionClients enables the ingestion of the log groups of their regions. It isn't looked up when nil.
+	CloudWatchRegionClients map[string]cloudwatchclient.CloudWatch
+}
+
+// New creates a Collector. The prices and the ingestion of the log groups are only listed again every scrape interval,
+// or less often while the pricing API throttles the collector.
+func New(config *Config, client pricingClient.Pricing) *Collector {
+	c := &Collector{
+		client:           client,
+		regions:          config.Regions,
+		cloudwatchClient: config.CloudWatchRegionClients,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Observability"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- MetricPriceDesc
+	ch <- LogsIngestionPriceDesc
+	ch <- DashboardPriceDesc
+	ch <- LogGroupIngestedDesc
+	ch <- LogGroupHourlyCostDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		LogGroupIngestedDesc,
+		LogGroupHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices and the ingestion of the log groups again when the scrape interval has passed and exports
+// them. A region whose log groups can't be looked up is reported in its scope error metric and only fails the
+// collector when every region failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := unitprice.List(context.TODO(), c.client, unitPrices, c.regions)
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing cloudwatch prices: %w", err)
+		}
+		c.prices = prices
+		c.logGroups, c.regionErrs = c.listLogGroups(now)
+	}
+	c.backoff.Emit(ch)
+	ingestionPrices := make(map[string]float64)
+	for _, p := range c.prices {
+		ch <- p.Metric()
+		if p.UnitPrice.Desc == LogsIngestionPriceDesc {
+			ingestionPrices[p.Region] = p.Value
+		}
+	}
+	for _, group := range c.logGroups {
+		gb := group.bytes / bytesPerGB
+		ch <- prometheus.MustNewConstMetric(LogGroupIngestedDesc, prometheus.GaugeValue, gb, group.region, group.name)
+		if price, ok := ingestionPrices[group.region]; ok {
+			ch <- prometheus.MustNewConstMetric(LogGroupHourlyCostDesc, prometheus.GaugeValue, gb*price, group.region, group.name)
+		}
+	}
+
+	var failedRegions []error
+	for _, region := range sortedKeys(c.regionErrs) {
+		err := c.regionErrs[region]
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, err)
+		if err != nil {
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, err))
+		}
+	}
+	if len(c.regionErrs) > 0 && len(failedRegions) == len(c.regionErrs) {
+		return errors.Join(failedRegions...)
+	}
+	return nil
+}
+
+// listLogGroups looks up the ingestion of the log groups of every region with a CloudWatch client, sorted by region
+// and log group, along with the error of every region.
+func (c *Collector) listLogGroups(now time.Time) ([]logGroup, map[string]error) {
+	regions := sortedKeys(c.cloudwatchClient)
+	results := make([][]logGroup, len(regions))
+	errs := make([]error, len(regions))
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = listLogGroupIngestion(context.TODO(), c.cloudwatchClient[region], region, now)
+		}()
+	}
+	wg.Wait()
+
+	var groups []logGroup
+	regionErrs := make(map[string]error, len(regions))
+	for i, region := range regions {
+		regionErrs[region] = errs[i]
+		if errs[i] != nil {
+			log.Printf("error listing the log group ingestion of region %s: %s", region, errs[i])
+			continue
+		}
+		groups = append(groups, results[i]...)
+	}
+	return groups, regionErrs
+}
+
+// listLogGroupIngestion returns the bytes ingested by every log group of a region over the IngestionWindow ending at
+// now, sorted by log group. Log groups without datapoints are left out.
+func listLogGroupIngestion(ctx context.Context, client cloudwatchclient.CloudWatch, region string, now time.Time) ([]logGroup, error) {
+	input := &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: []cloudwatchTypes.MetricDataQuery{{
+			Id:         aws.String("incoming"),
+			Expression: aws.String(logGroupSearch),
+		}},
+		StartTime: aws.Time(now.Add(-IngestionWindow)),
+		EndTime:   aws.Time(now),
+	}
+	bytes := make(map[string]float64)
+	for {
+		resp, err := client.GetMetricData(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range resp.MetricDataResults {
+			// The label of a search result is the name of its log group
+			if result.Label == nil || len(result.Values) == 0 {
+				continue
+			}
+			bytes[*result.Label] += result.Values[0]
+		}
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+	groups := make([]logGroup, 0, len(bytes))
+	for _, name := range sortedKeys(bytes) {
+		groups = append(groups, logGroup{region: region, name: name, bytes: bytes[name]})
+	}
+	return groups, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockcloudwatch "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/cloudwatch"
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var cloudWatchProducts = []string{
+	`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"CW:MetricMonitorUsage"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"beginRange":"0","pricePerUnit":{"USD":"0.30"}},"A.2":{"beginRange":"10000","pricePerUnit":{"USD":"0.10"}}}}}}}`,
+	`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"DataProcessing-Bytes"}},"terms":{"OnDemand":{"B":{"priceDimensions":{"B.1":{"pricePerUnit":{"USD":"0.50"}}}}}}}`,
+	`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"DashboardsUsageHour"}},"terms":{"OnDemand":{"C":{"priceDimensions":{"C.1":{"pricePerUnit":{"USD":"3"}}}}}}}`,
+	// Vended logs are priced separately
+	`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"VendedLog-Bytes"}},"terms":{"OnDemand":{"D":{"priceDimensions":{"D.1":{"pricePerUnit":{"USD":"0.25"}}}}}}}`,
+}
+
+func Test_listLogGroupIngestion(t *testing.T) {
+	now := time.Now()
+	client := mockcloudwatch.NewCloudWatch(t)
+	client.EXPECT().GetMetricData(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *cloudwatch.GetMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+			assert.Equal(t, logGroupSearch, aws.ToString(input.MetricDataQueries[0].Expression))
+			assert.Equal(t, now.Add(-IngestionWindow), aws.ToTime(input.StartTime))
+			if input.NextToken == nil {
+				return &cloudwatch.GetMetricDataOutput{
+					MetricDataResults: []cloudwatchTypes.MetricDataResult{
+						{Label: aws.String("/aws/lambda/b"), Values: []float64{2 * bytesPerGB}},
+						// A log group without datapoints didn't ingest anything
+						{Label: aws.String("/aws/lambda/idle")},
+					},
+					NextToken: aws.String("next"),
+				}, nil
+			}
+			return &cloudwatch.GetMetricDataOutput{MetricDataResults: []cloudwatchTypes.MetricDataResult{
+				{Label: aws.String("/aws/lambda/a"), Values: []float64{bytesPerGB}},
+			}}, nil
+		}).Times(2)
+
+	groups, err := listLogGroupIngestion(context.Background(), client, "us-east-1", now)
+	require.NoError(t, err)
+	assert.Equal(t, []logGroup{
+		{region: "us-east-1", name: "/aws/lambda/a", bytes: bytesPerGB},
+		{region: "us-east-1", name: "/aws/lambda/b", bytes: 2 * bytesPerGB},
+	}, groups)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	pricingService := mockpricing.NewPricing(t)
+	pricingService.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		Return(&pricing.GetProductsOutput{PriceList: cloudWatchProducts}, nil).Once()
+	usEast := mockcloudwatch.NewCloudWatch(t)
+	usEast.EXPECT().GetMetricData(mock.Anything, mock.Anything).Return(&cloudwatch.GetMetricDataOutput{
+		MetricDataResults: []cloudwatchTypes.MetricDataResult{
+			{Label: aws.String("/aws/lambda/a"), Values: []float64{2 * bytesPerGB}},
+		},
+	}, nil).Once()
+	euWest := mockcloudwatch.NewCloudWatch(t)
+	euWest.EXPECT().GetMetricData(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	c := New(&Config{
+		ScrapeInterval:          time.Hour,
+		CloudWatchRegionClients: map[string]cloudwatchclient.CloudWatch{"us-east-1": usEast, "eu-west-1": euWest},
+	}, pricingService)
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		metrics := make(map[string][]*utils.MetricResult)
+		for metric := range ch {
+			result := utils.ReadMetrics(metric)
+			metrics[result.FqName] = append(metrics[result.FqName], result)
+		}
+		for name, value := range map[string]float64{
+			"cloudcost_aws_cloudwatch_metric_usd_per_month":      0.30,
+			"cloudcost_aws_cloudwatch_logs_ingestion_usd_per_gb": 0.50,
+			"cloudcost_aws_cloudwatch_dashboard_usd_per_month":   3,
+		} {
+			require.Len(t, metrics[name], 1, name)
+			assert.Equal(t, utils.LabelMap{"region": "us-east-1"}, metrics[name][0].Labels)
+			assert.InDelta(t, value, metrics[name][0].Value, 1e-9, name)
+		}
+		assert.Equal(t, []*utils.MetricResult{{
+			FqName:     "cloudcost_aws_cloudwatch_log_group_ingested_gb",
+			Labels:     utils.LabelMap{"region": "us-east-1", "log_group": "/aws/lambda/a"},
+			Value:      2,
+			MetricType: prometheus.GaugeValue,
+		}}, metrics["cloudcost_aws_cloudwatch_log_group_ingested_gb"])
+		require.Len(t, metrics["cloudcost_aws_cloudwatch_log_group_usd_per_hour"], 1)
+		assert.InDelta(t, 2*0.50, metrics["cloudcost_aws_cloudwatch_log_group_usd_per_hour"][0].Value, 1e-9)
+
+		scopeErrors := metrics["cloudcost_exporter_collector_scope_last_scrape_error"]
+		require.Len(t, scopeErrors, 2)
+		assert.Equal(t, utils.LabelMap{"provider": "aws", "collector": "aws_cloudwatch", "scope": "eu-west-1"}, scopeErrors[0].Labels)
+		assert.Equal(t, 1.0, scopeErrors[0].Value)
+		assert.Equal(t, 0.0, scopeErrors[1].Value)
+	}
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	pricingService := mockpricing.NewPricing(t)
+	pricingService.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		Return(&pricing.GetProductsOutput{PriceList: cloudWatchProducts}, nil).Once()
+	client := mockcloudwatch.NewCloudWatch(t)
+	client.EXPECT().GetMetricData(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	c := New(&Config{CloudWatchRegionClients: map[string]cloudwatchclient.CloudWatch{"us-east-1": client}}, pricingService)
+	ch := make(chan prometheus.Metric, 10)
+	assert.ErrorIs(t, c.Collect(ch), assert.AnError)
+}
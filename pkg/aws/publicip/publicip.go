@@ -0,0 +1,171 @@
+package publicip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "aws"
+	subsystem    = "aws_public_ipv4"
+
+	// HourlyPrice is what AWS bills for a public IPv4 address, in use or idle, in USD/h. It's the same in every
+	// commercial region and isn't listed by the pricing API alongside the EC2 instances.
+	HourlyPrice = 0.005
+
+	addressTypeElastic      = "elastic"
+	addressTypeAutoAssigned = "auto_assigned"
+)
+
+var (
+	PublicIPv4HourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "usd_per_hour"),
+		"The price of a public IPv4 address in USD/h.",
+		[]string{"region"},
+		nil,
+	)
+	PublicIPv4AddressHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "address_usd_per_hour"),
+		"The hourly cost of a public IPv4 address in USD/h. address_type is elastic for Elastic IPs, attached or not, and auto_assigned for the public IPs of instances.",
+		[]string{"region", "public_ip", "address_type", "instance_id"},
+		nil,
+	)
+)
+
+// address is a public IPv4 address billed in a region.
+type address struct {
+	publicIP    string
+	addressType string
+	instanceID  string
+}
+
+// Collector exports the cost of the public IPv4 addresses of the account, its Elastic IPs and the public IPs assigned
+// to its instances. The addresses are listed on every scrape.
+type Collector struct {
+	regionClients map[string]ec2client.EC2
+}
+
+// New creates a Collector listing the addresses of the regions of regionClients.
+func New(regionClients map[string]ec2client.EC2) *Collector {
+	return &Collector{
+		regionClients: regionClients,
+	}
+}
+
+func (c *Collector) Name() string {
+	return "PublicIPv4"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- PublicIPv4HourlyPriceDesc
+	ch <- PublicIPv4AddressHourlyCostDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		PublicIPv4AddressHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the public IPv4 addresses of every region and exports their cost. A region failing is reported in its
+// scope error metric and only fails the collector when every region failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	regions := make([]string, 0, len(c.regionClients))
+	for region := range c.regionClients {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	addresses := make([][]address, len(regions))
+	errs := make([]error, len(regions))
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addresses[i], errs[i] = listAddresses(context.Background(), c.regionClients[region])
+		}()
+	}
+	wg.Wait()
+
+	var failedRegions []error
+	for i, region := range regions {
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, errs[i])
+		if errs[i] != nil {
+			log.Printf("error listing public ipv4 addresses in region %s: %s", region, errs[i])
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, errs[i]))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(PublicIPv4HourlyPriceDesc, prometheus.GaugeValue, HourlyPrice, region)
+		for _, a := range addresses[i] {
+			ch <- prometheus.MustNewConstMetric(PublicIPv4AddressHourlyCostDesc, prometheus.GaugeValue, HourlyPrice, region, a.publicIP, a.addressType, a.instanceID)
+		}
+	}
+	if len(regions) > 0 && len(failedRegions) == len(regions) {
+		return errors.Join(failedRegions...)
+	}
+	return nil
+}
+
+// listAddresses lists the Elastic IPs of a region and the public IPs of its instances that aren't Elastic IPs, sorted
+// by address. Elastic IPs are billed whether they're associated or not, while instances only keep their auto-assigned
+// public IP while running.
+func listAddresses(ctx context.Context, client ec2client.EC2) ([]address, error) {
+	resp, err := client.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, fmt.Errorf("error describing addresses: %w", err)
+	}
+	seen := make(map[string]bool)
+	var addresses []address
+	for _, a := range resp.Addresses {
+		publicIP := aws.ToString(a.PublicIp)
+		if publicIP == "" || seen[publicIP] {
+			continue
+		}
+		seen[publicIP] = true
+		addresses = append(addresses, address{publicIP: publicIP, addressType: addressTypeElastic, instanceID: aws.ToString(a.InstanceId)})
+	}
+
+	reservations, err := compute.ListComputeInstances(ctx, client, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing instances: %w", err)
+	}
+	for _, reservation := range reservations {
+		for _, instance := range reservation.Instances {
+			publicIP := aws.ToString(instance.PublicIpAddress)
+			if publicIP == "" || seen[publicIP] {
+				continue
+			}
+			seen[publicIP] = true
+			addresses = append(addresses, address{publicIP: publicIP, addressType: addressTypeAutoAssigned, instanceID: aws.ToString(instance.InstanceId)})
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].publicIP < addresses[j].publicIP })
+	return addresses, nil
+}
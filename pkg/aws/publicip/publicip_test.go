@@ -0,0 +1,75 @@
+package publicip
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockec2 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ec2"
+	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestCollector_Collect(t *testing.T) {
+	usEast := mockec2.NewEC2(t)
+	usEast.EXPECT().DescribeAddresses(mock.Anything, mock.Anything).Return(&ec2.DescribeAddressesOutput{Addresses: []types.Address{
+		{PublicIp: aws.String("3.3.3.3"), InstanceId: aws.String("i-eip")},
+		// Elastic IPs are billed while they're not associated
+		{PublicIp: aws.String("1.1.1.1")},
+	}}, nil)
+	usEast.EXPECT().DescribeInstances(mock.Anything, mock.Anything).Return(&ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{
+		Instances: []types.Instance{
+			// The Elastic IP of the instance is only counted once
+			{InstanceId: aws.String("i-eip"), PublicIpAddress: aws.String("3.3.3.3")},
+			{InstanceId: aws.String("i-public"), PublicIpAddress: aws.String("2.2.2.2")},
+			{InstanceId: aws.String("i-private")},
+		},
+	}}}, nil)
+	euWest := mockec2.NewEC2(t)
+	euWest.EXPECT().DescribeAddresses(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(map[string]ec2client.EC2{"us-east-1": usEast, "eu-west-1": euWest})
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, c.Collect(ch))
+	close(ch)
+	var metrics []*utils.MetricResult
+	for metric := range ch {
+		metrics = append(metrics, utils.ReadMetrics(metric))
+	}
+
+	require.Len(t, metrics, 6)
+	assert.Equal(t, "cloudcost_exporter_collector_scope_last_scrape_error", metrics[0].FqName)
+	assert.Equal(t, utils.LabelMap{"provider": "aws", "collector": "aws_public_ipv4", "scope": "eu-west-1"}, metrics[0].Labels)
+	assert.Equal(t, 1.0, metrics[0].Value)
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_aws_public_ipv4_usd_per_hour",
+		Labels:     utils.LabelMap{"region": "us-east-1"},
+		Value:      HourlyPrice,
+		MetricType: prometheus.GaugeValue,
+	}, metrics[2])
+	for i, labels := range []utils.LabelMap{
+		{"region": "us-east-1", "public_ip": "1.1.1.1", "address_type": "elastic", "instance_id": ""},
+		{"region": "us-east-1", "public_ip": "2.2.2.2", "address_type": "auto_assigned", "instance_id": "i-public"},
+		{"region": "us-east-1", "public_ip": "3.3.3.3", "address_type": "elastic", "instance_id": "i-eip"},
+	} {
+		assert.Equal(t, "cloudcost_aws_public_ipv4_address_usd_per_hour", metrics[3+i].FqName)
+		assert.Equal(t, labels, metrics[3+i].Labels)
+		assert.Equal(t, HourlyPrice, metrics[3+i].Value)
+	}
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	client := mockec2.NewEC2(t)
+	client.EXPECT().DescribeAddresses(mock.Anything, mock.Anything).Return(&ec2.DescribeAddressesOutput{}, nil)
+	client.EXPECT().DescribeInstances(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(map[string]ec2client.EC2{"us-east-1": client})
+	ch := make(chan prometheus.Metric, 10)
+	assert.ErrorIs(t, c.Collect(ch), assert.AnError)
+}
@@ -0,0 +1,500 @@
+package rds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+	rdsclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/rds"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	providerName = "aws"
+	subsystem    = "aws_rds"
+
+	serviceCode = "AmazonRDS"
+
+	productFamilyInstance = "Database Instance"
+	productFamilyStorage  = "Database Storage"
+	locationTypeRegion    = "AWS Region"
+
+	// deploymentSingleAZ and deploymentMultiAZ are the deployment options of the pricing API, a Multi-AZ instance
+	// being billed for its standby as well.
+	deploymentSingleAZ = "Single-AZ"
+	deploymentMultiAZ  = "Multi-AZ"
+
+	licenseIncluded     = "License included"
+	licenseBringYourOwn = "Bring your own license"
+	licenseNotRequired  = "No license required"
+
+	statusStopped = "stopped"
+	// maxDescribeInstances is the number of instances DescribeDBInstances returns at most per page
+	maxDescribeInstances = 100
+)
+
+var (
+	InstanceHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_usd_per_hour"),
+		"The hourly cost of an RDS instance in USD/h, at the on-demand price of its instance class, engine, deployment option and license model. Stopped instances aren't billed and aren't exported.",
+		[]string{"region", "instance", "instance_class", "engine", "deployment_option", "license_model"},
+		nil,
+	)
+	StorageHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "storage_usd_per_gib_hour"),
+		"The price of the storage of an RDS volume type in USD/(GiB*h). IOPS and throughput are priced separately.",
+		[]string{"region", "storage_type", "deployment_option"},
+		nil,
+	)
+	InstanceStorageHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_storage_usd_per_hour"),
+		"The hourly cost of the allocated storage of an RDS instance in USD/h. Aurora storage is billed per cluster and isn't exported.",
+		[]string{"region", "instance", "storage_type", "deployment_option"},
+		nil,
+	)
+)
+
+// engine is the database engine and edition of the pricing API of an engine of the RDS API.
+type engine struct {
+	name    string
+	edition string
+	// aurora engines are only priced as Single-AZ, every instance of a cluster being billed on its own, and their
+	// storage is billed per cluster.
+	aurora bool
+}
+
+var engines = map[string]engine{
+	"mysql":             {name: "MySQL"},
+	"mariadb":           {name: "MariaDB"},
+	"postgres":          {name: "PostgreSQL"},
+	"aurora-mysql":      {name: "Aurora MySQL", aurora: true},
+	"aurora-postgresql": {name: "Aurora PostgreSQL", aurora: true},
+	"oracle-ee":         {name: "Oracle", edition: "Enterprise"},
+	"oracle-se2":        {name: "Oracle", edition: "Standard Two"},
+	"sqlserver-ee":      {name: "SQL Server", edition: "Enterprise"},
+	"sqlserver-se":      {name: "SQL Server", edition: "Standard"},
+	"sqlserver-ex":      {name: "SQL Server", edition: "Express"},
+	"sqlserver-web":     {name: "SQL Server", edition: "Web"},
+}
+
+// editionEngines are the engines of the pricing API priced per edition.
+var editionEngines = map[string]bool{"Oracle": true, "SQL Server": true}
+
+// volumeTypes are the volume types of the pricing API of the storage types of the RDS API.
+var volumeTypes = map[string]string{
+	"gp2": "General Purpose",
+	"gp3": "General Purpose-GP3",
+	"io1": "Provisioned IOPS",
+}
+
+// instanceKey selects the price of an instance.
+type instanceKey struct {
+	region           string
+	instanceClass    string
+	engine           string
+	edition          string
+	deploymentOption string
+	licenseModel     string
+}
+
+// storageKey selects the price of the storage of an instance.
+type storageKey struct {
+	region           string
+	storageType      string
+	deploymentOption string
+}
+
+// prices are the on-demand prices of the instances in USD/h and of the storage in USD/(GiB*h).
+type prices struct {
+	instances map[instanceKey]float64
+	storage   map[storageKey]float64
+}
+
+// instance is an RDS instance and the resources it's billed for.
+type instance struct {
+	id           string
+	class        string
+	engine       string
+	licenseModel string
+	storageType  string
+	multiAZ      bool
+	storageGiB   float64
+	stopped      bool
+}
+
+// product represents the nested json response returned by the AWS pricing API for the instances and storage of RDS.
+type product struct {
+	Product struct {
+		Attributes struct {
+			Region           string `json:"regionCode"`
+			UsageType        string `json:"usagetype"`
+			InstanceType     string `json:"instanceType"`
+			DatabaseEngine   string `json:"databaseEngine"`
+			DatabaseEdition  string `json:"databaseEdition"`
+			DeploymentOption string `json:"deploymentOption"`
+			LicenseModel     string `json:"licenseModel"`
+			VolumeType       string `json:"volumeType"`
+		}
+	}
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			}
+		}
+	}
+}
+
+// Collector exports the cost of the RDS instances and of their storage. The prices are listed every scrape interval
+// and the instances on every scrape.
+type Collector struct {
+	pricingClient pricingClient.Pricing
+	regionClients map[string]rdsclient.RDS
+	regions       *compute.RegionFilter
+	backoff       *provider.Backoff
+	nextScrape    time.Time
+	prices        *prices
+	m             sync.Mutex
+}
+
+// New creates a Collector listing the instances of the regions of regionClients. The prices are only listed again every
+// scrapeInterval, or less often while the pricing API throttles the collector. regions selects the regions whose
+// prices are listed, every region is when nil.
+func New(scrapeInterval time.Duration, client pricingClient.Pricing, regionClients map[string]rdsclient.RDS, regions *compute.RegionFilter) *Collector {
+	c := &Collector{
+		pricingClient: client,
+		regionClients: regionClients,
+		regions:       regions,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), scrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "RDS"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- InstanceHourlyCostDesc
+	ch <- StorageHourlyPriceDesc
+	ch <- InstanceStorageHourlyCostDesc
+	ch <- provider.RefreshIntervalDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		InstanceHourlyCostDesc,
+		InstanceStorageHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices again when the scrape interval has passed, and the instances of every region. A region
+// failing is reported in its scope error metric and only fails the collector when every region failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listPrices(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing rds prices: %w", err)
+		}
+		c.prices = prices
+	}
+	storageKeys := make([]storageKey, 0, len(c.prices.storage))
+	for key := range c.prices.storage {
+		storageKeys = append(storageKeys, key)
+	}
+	sort.Slice(storageKeys, func(i, j int) bool {
+		a, b := storageKeys[i], storageKeys[j]
+		if a.region != b.region {
+			return a.region < b.region
+		}
+		if a.storageType != b.storageType {
+			return a.storageType < b.storageType
+		}
+		return a.deploymentOption < b.deploymentOption
+	})
+	for _, key := range storageKeys {
+		ch <- prometheus.MustNewConstMetric(StorageHourlyPriceDesc, prometheus.GaugeValue, c.prices.storage[key], key.region, key.storageType, deploymentLabel(key.deploymentOption))
+	}
+	c.backoff.Emit(ch)
+
+	regions := make([]string, 0, len(c.regionClients))
+	for region := range c.regionClients {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	instances := make([][]instance, len(regions))
+	errs := make([]error, len(regions))
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instances[i], errs[i] = listInstances(context.Background(), c.regionClients[region])
+		}()
+	}
+	wg.Wait()
+
+	var failedRegions []error
+	for i, region := range regions {
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, errs[i])
+		if errs[i] != nil {
+			log.Printf("error listing rds instances in region %s: %s", region, errs[i])
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, errs[i]))
+			continue
+		}
+		for _, inst := range instances[i] {
+			c.collectInstance(ch, region, inst)
+		}
+	}
+	if len(regions) > 0 && len(failedRegions) == len(regions) {
+		return errors.Join(failedRegions...)
+	}
+	return nil
+}
+
+// collectInstance sends the cost of an instance and of its storage. An instance or a storage type without a price is
+// logged and skipped.
+func (c *Collector) collectInstance(ch chan<- prometheus.Metric, region string, inst instance) {
+	e, ok := engines[inst.engine]
+	if !ok {
+		log.Printf("skipping rds instance %s in region %s: unsupported engine %s", inst.id, region, inst.engine)
+		return
+	}
+	deployment := deploymentSingleAZ
+	if inst.multiAZ && !e.aurora {
+		deployment = deploymentMultiAZ
+	}
+	if !inst.stopped {
+		key := instanceKey{
+			region:           region,
+			instanceClass:    inst.class,
+			engine:           e.name,
+			edition:          e.edition,
+			deploymentOption: deployment,
+			licenseModel:     licenseModel(inst.licenseModel),
+		}
+		if price, ok := c.prices.instances[key]; ok {
+			ch <- prometheus.MustNewConstMetric(InstanceHourlyCostDesc, prometheus.GaugeValue, price,
+				region, inst.id, inst.class, inst.engine, deploymentLabel(deployment), inst.licenseModel)
+		} else {
+			log.Printf("no %s %s price found for rds instance %s of class %s in region %s", deployment, e.name, inst.id, inst.class, region)
+		}
+	}
+	if e.aurora {
+		return
+	}
+	price, ok := c.prices.storage[storageKey{region: region, storageType: inst.storageType, deploymentOption: deployment}]
+	if !ok {
+		log.Printf("no %s price found for the %s storage of rds instance %s in region %s", deployment, inst.storageType, inst.id, region)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(InstanceStorageHourlyCostDesc, prometheus.GaugeValue, inst.storageGiB*price,
+		region, inst.id, inst.storageType, deploymentLabel(deployment))
+}
+
+// listPrices lists the on-demand prices of the instances and of the storage of RDS in the regions of the filter.
+func (c *Collector) listPrices(ctx context.Context) (*prices, error) {
+	instanceProducts, err := listProducts(ctx, c.pricingClient, productFamilyInstance)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the instance prices: %w", err)
+	}
+	storageProducts, err := listProducts(ctx, c.pricingClient, productFamilyStorage)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the storage prices: %w", err)
+	}
+	return parsePrices(instanceProducts, storageProducts, c.regions), nil
+}
+
+func listProducts(ctx context.Context, client pricingClient.Pricing, productFamily string) ([]string, error) {
+	var products []string
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String(serviceCode),
+		Filters: []types.Filter{
+			{
+				Field: aws.String("productFamily"),
+				Type:  "TERM_MATCH",
+				Value: aws.String(productFamily),
+			},
+			{
+				Field: aws.String("locationType"),
+				Type:  "TERM_MATCH",
+				Value: aws.String(locationTypeRegion),
+			},
+		},
+	}
+	for {
+		output, err := client.GetProducts(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if output == nil {
+			break
+		}
+		products = append(products, output.PriceList...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return products, nil
+}
+
+// parsePrices returns the prices of the instances and of the storage types of the regions matching the filter. The
+// instances of the Aurora I/O-Optimized configuration are skipped, as the instances are listed without their cluster
+// configuration and priced at the Standard configuration. The storage is priced the same for every engine, so the
+// storage price of a region and volume type is the first one found. Entries that can't be parsed are skipped.
+func parsePrices(instanceProducts []string, storageProducts []string, regions *compute.RegionFilter) *prices {
+	p := &prices{instances: make(map[instanceKey]float64), storage: make(map[storageKey]float64)}
+	storageTypes := make(map[string]string, len(volumeTypes))
+	for storageType, volumeType := range volumeTypes {
+		storageTypes[volumeType] = storageType
+	}
+	for _, entry := range instanceProducts {
+		product, price, ok := parseProduct(entry, regions)
+		if !ok {
+			continue
+		}
+		attributes := product.Product.Attributes
+		if strings.Contains(attributes.UsageType, "IOOptimized") {
+			continue
+		}
+		key := instanceKey{
+			region:           attributes.Region,
+			instanceClass:    attributes.InstanceType,
+			engine:           attributes.DatabaseEngine,
+			deploymentOption: attributes.DeploymentOption,
+			licenseModel:     attributes.LicenseModel,
+		}
+		if editionEngines[attributes.DatabaseEngine] {
+			key.edition = attributes.DatabaseEdition
+		}
+		p.instances[key] = price
+	}
+	for _, entry := range storageProducts {
+		product, price, ok := parseProduct(entry, regions)
+		if !ok {
+			continue
+		}
+		attributes := product.Product.Attributes
+		storageType, ok := storageTypes[attributes.VolumeType]
+		if !ok {
+			continue
+		}
+		key := storageKey{region: attributes.Region, storageType: storageType, deploymentOption: attributes.DeploymentOption}
+		if _, ok := p.storage[key]; !ok {
+			p.storage[key] = price / utils.HoursInMonth
+		}
+	}
+	return p
+}
+
+// parseProduct parses a price entry and returns its on-demand price, the entries of the regions that don't match the
+// filter or without a paid price aren't returned.
+func parseProduct(entry string, regions *compute.RegionFilter) (product, float64, bool) {
+	var p product
+	if err := json.Unmarshal([]byte(entry), &p); err != nil {
+		log.Printf("error parsing %s price entry: %s, skipping", serviceCode, err)
+		return p, 0, false
+	}
+	if p.Product.Attributes.Region == "" || !regions.Matches(p.Product.Attributes.Region) {
+		return p, 0, false
+	}
+	for _, term := range p.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			usd, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err == nil && !math.IsNaN(usd) && !math.IsInf(usd, 0) && usd > 0 {
+				return p, usd, true
+			}
+		}
+	}
+	return p, 0, false
+}
+
+// listInstances lists the instances of a region.
+func listInstances(ctx context.Context, client rdsclient.RDS) ([]instance, error) {
+	var instances []instance
+	input := &rdsclient.DescribeDBInstancesInput{MaxRecords: aws.Int32(maxDescribeInstances)}
+	for {
+		resp, err := client.DescribeDBInstances(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error describing db instances: %w", err)
+		}
+		for _, i := range resp.DBInstances {
+			instances = append(instances, parseInstance(i))
+		}
+		if resp.Marker == nil || *resp.Marker == "" {
+			break
+		}
+		input.Marker = resp.Marker
+	}
+	return instances, nil
+}
+
+func parseInstance(i rdsclient.DBInstance) instance {
+	return instance{
+		id:           aws.ToString(i.DBInstanceIdentifier),
+		class:        aws.ToString(i.DBInstanceClass),
+		engine:       aws.ToString(i.Engine),
+		licenseModel: aws.ToString(i.LicenseModel),
+		storageType:  aws.ToString(i.StorageType),
+		multiAZ:      aws.ToBool(i.MultiAZ),
+		storageGiB:   float64(aws.ToInt32(i.AllocatedStorage)),
+		stopped:      aws.ToString(i.DBInstanceStatus) == statusStopped,
+	}
+}
+
+// licenseModel returns the license model of the pricing API of a license model of the RDS API. The open source engines
+// have their own license model, eg postgresql-license, which doesn't require a license.
+func licenseModel(model string) string {
+	switch model {
+	case "license-included":
+		return licenseIncluded
+	case "bring-your-own-license":
+		return licenseBringYourOwn
+	default:
+		return licenseNotRequired
+	}
+}
+
+// deploymentLabel returns the value of the deployment_option label of a deployment option of the pricing API.
+func deploymentLabel(deploymentOption string) string {
+	if deploymentOption == deploymentMultiAZ {
+		return "multi_az"
+	}
+	return "single_az"
+}
@@ -0,0 +1,126 @@
+package rds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	mockrds "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/rds"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	rdsclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/rds"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	postgresProduct        = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Multi-AZUsage:db.m5.large","instanceType":"db.m5.large","databaseEngine":"PostgreSQL","deploymentOption":"Multi-AZ","licenseModel":"No license required"}},"terms":{"OnDemand":{"A.B":{"priceDimensions":{"A.B.1":{"pricePerUnit":{"USD":"0.3560000000"}}}}}}}`
+	sqlServerProduct       = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"InstanceUsage:db.m5.large","instanceType":"db.m5.large","databaseEngine":"SQL Server","databaseEdition":"Standard","deploymentOption":"Single-AZ","licenseModel":"License included"}},"terms":{"OnDemand":{"C.D":{"priceDimensions":{"C.D.1":{"pricePerUnit":{"USD":"0.9770000000"}}}}}}}`
+	auroraProduct          = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"InstanceUsage:db.r6g.large","instanceType":"db.r6g.large","databaseEngine":"Aurora MySQL","deploymentOption":"Single-AZ","licenseModel":"No license required"}},"terms":{"OnDemand":{"E.F":{"priceDimensions":{"E.F.1":{"pricePerUnit":{"USD":"0.2600000000"}}}}}}}`
+	auroraIOOptimized      = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"InstanceUsageIOOptimized:db.r6g.large","instanceType":"db.r6g.large","databaseEngine":"Aurora MySQL","deploymentOption":"Single-AZ","licenseModel":"No license required"}},"terms":{"OnDemand":{"G.H":{"priceDimensions":{"G.H.1":{"pricePerUnit":{"USD":"0.3380000000"}}}}}}}`
+	euWestPostgresProduct  = `{"product":{"attributes":{"regionCode":"eu-west-1","usagetype":"EU-Multi-AZUsage:db.m5.large","instanceType":"db.m5.large","databaseEngine":"PostgreSQL","deploymentOption":"Multi-AZ","licenseModel":"No license required"}},"terms":{"OnDemand":{"I.J":{"priceDimensions":{"I.J.1":{"pricePerUnit":{"USD":"0.3900000000"}}}}}}}`
+	gp3MultiAZProduct      = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"RDS:Multi-AZ-GP3-Storage","volumeType":"General Purpose-GP3","databaseEngine":"PostgreSQL","deploymentOption":"Multi-AZ"}},"terms":{"OnDemand":{"K.L":{"priceDimensions":{"K.L.1":{"pricePerUnit":{"USD":"0.2300000000"}}}}}}}`
+	io1SingleAZProduct     = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"RDS:PIOPS-Storage","volumeType":"Provisioned IOPS","databaseEngine":"SQL Server","deploymentOption":"Single-AZ"}},"terms":{"OnDemand":{"M.N":{"priceDimensions":{"M.N.1":{"pricePerUnit":{"USD":"0.1250000000"}}}}}}}`
+	magneticStorageProduct = `{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"RDS:StorageUsage","volumeType":"Magnetic","databaseEngine":"MySQL","deploymentOption":"Single-AZ"}},"terms":{"OnDemand":{"O.P":{"priceDimensions":{"O.P.1":{"pricePerUnit":{"USD":"0.1000000000"}}}}}}}`
+)
+
+func Test_parsePrices(t *testing.T) {
+	p := parsePrices(
+		[]string{postgresProduct, sqlServerProduct, auroraProduct, auroraIOOptimized, euWestPostgresProduct, "invalid"},
+		[]string{gp3MultiAZProduct, io1SingleAZProduct, magneticStorageProduct},
+		&compute.RegionFilter{Allow: []string{"us-*"}},
+	)
+	assert.Equal(t, map[instanceKey]float64{
+		{region: "us-east-1", instanceClass: "db.m5.large", engine: "PostgreSQL", deploymentOption: "Multi-AZ", licenseModel: "No license required"}:                    0.356,
+		{region: "us-east-1", instanceClass: "db.m5.large", engine: "SQL Server", edition: "Standard", deploymentOption: "Single-AZ", licenseModel: "License included"}: 0.977,
+		{region: "us-east-1", instanceClass: "db.r6g.large", engine: "Aurora MySQL", deploymentOption: "Single-AZ", licenseModel: "No license required"}:                0.26,
+	}, p.instances)
+	require.Len(t, p.storage, 2)
+	assert.InDelta(t, 0.23/utils.HoursInMonth, p.storage[storageKey{region: "us-east-1", storageType: "gp3", deploymentOption: "Multi-AZ"}], 1e-12)
+	assert.InDelta(t, 0.125/utils.HoursInMonth, p.storage[storageKey{region: "us-east-1", storageType: "io1", deploymentOption: "Single-AZ"}], 1e-12)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	pricingClient := mockpricing.NewPricing(t)
+	pricingClient.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+			if aws.ToString(input.Filters[0].Value) == productFamilyStorage {
+				return &pricing.GetProductsOutput{PriceList: []string{gp3MultiAZProduct, io1SingleAZProduct}}, nil
+			}
+			return &pricing.GetProductsOutput{PriceList: []string{postgresProduct, sqlServerProduct, auroraProduct}}, nil
+		}).
+		Times(2)
+
+	usEast := mockrds.NewRDS(t)
+	usEast.EXPECT().DescribeDBInstances(mock.Anything, &rdsclient.DescribeDBInstancesInput{MaxRecords: aws.Int32(100)}).
+		Return(&rdsclient.DescribeDBInstancesOutput{
+			DBInstances: []rdsclient.DBInstance{
+				{DBInstanceIdentifier: aws.String("orders"), DBInstanceClass: aws.String("db.m5.large"), DBInstanceStatus: aws.String("available"), Engine: aws.String("postgres"),
+					LicenseModel: aws.String("postgresql-license"), MultiAZ: aws.Bool(true), StorageType: aws.String("gp3"), AllocatedStorage: aws.Int32(100)},
+			},
+			Marker: aws.String("next"),
+		}, nil).Once()
+	usEast.EXPECT().DescribeDBInstances(mock.Anything, &rdsclient.DescribeDBInstancesInput{MaxRecords: aws.Int32(100), Marker: aws.String("next")}).
+		Return(&rdsclient.DescribeDBInstancesOutput{
+			DBInstances: []rdsclient.DBInstance{
+				// A stopped instance is only billed for its storage
+				{DBInstanceIdentifier: aws.String("reports"), DBInstanceClass: aws.String("db.m5.large"), DBInstanceStatus: aws.String("stopped"), Engine: aws.String("sqlserver-se"),
+					LicenseModel: aws.String("license-included"), MultiAZ: aws.Bool(false), StorageType: aws.String("io1"), AllocatedStorage: aws.Int32(200)},
+				// The storage of an Aurora instance is billed per cluster
+				{DBInstanceIdentifier: aws.String("ledger-1"), DBInstanceClass: aws.String("db.r6g.large"), DBInstanceStatus: aws.String("available"), Engine: aws.String("aurora-mysql"),
+					LicenseModel: aws.String("general-public-license"), MultiAZ: aws.Bool(true), StorageType: aws.String("aurora"), AllocatedStorage: aws.Int32(1)},
+			},
+		}, nil).Once()
+	euWest := mockrds.NewRDS(t)
+	euWest.EXPECT().DescribeDBInstances(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(time.Hour, pricingClient, map[string]rdsclient.RDS{"us-east-1": usEast, "eu-west-1": euWest}, nil)
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, c.Collect(ch))
+	close(ch)
+	metrics := make(map[string][]*utils.MetricResult)
+	for metric := range ch {
+		result := utils.ReadMetrics(metric)
+		metrics[result.FqName] = append(metrics[result.FqName], result)
+	}
+
+	scopes := metrics["cloudcost_exporter_collector_scope_last_scrape_error"]
+	require.Len(t, scopes, 2)
+	assert.Equal(t, utils.LabelMap{"provider": "aws", "collector": "aws_rds", "scope": "eu-west-1"}, scopes[0].Labels)
+	assert.Equal(t, 1.0, scopes[0].Value)
+
+	storagePrices := metrics["cloudcost_aws_rds_storage_usd_per_gib_hour"]
+	require.Len(t, storagePrices, 2)
+	assert.Equal(t, utils.LabelMap{"region": "us-east-1", "storage_type": "gp3", "deployment_option": "multi_az"}, storagePrices[0].Labels)
+
+	instances := metrics["cloudcost_aws_rds_instance_usd_per_hour"]
+	require.Len(t, instances, 2)
+	assert.Equal(t, utils.LabelMap{"region": "us-east-1", "instance": "orders", "instance_class": "db.m5.large", "engine": "postgres", "deployment_option": "multi_az", "license_model": "postgresql-license"}, instances[0].Labels)
+	assert.InDelta(t, 0.356, instances[0].Value, 1e-9)
+	assert.Equal(t, utils.LabelMap{"region": "us-east-1", "instance": "ledger-1", "instance_class": "db.r6g.large", "engine": "aurora-mysql", "deployment_option": "single_az", "license_model": "general-public-license"}, instances[1].Labels)
+	assert.InDelta(t, 0.26, instances[1].Value, 1e-9)
+
+	storage := metrics["cloudcost_aws_rds_instance_storage_usd_per_hour"]
+	require.Len(t, storage, 2)
+	assert.Equal(t, utils.LabelMap{"region": "us-east-1", "instance": "orders", "storage_type": "gp3", "deployment_option": "multi_az"}, storage[0].Labels)
+	assert.InDelta(t, 100*0.23/utils.HoursInMonth, storage[0].Value, 1e-9)
+	assert.Equal(t, "reports", storage[1].Labels["instance"])
+	assert.InDelta(t, 200*0.125/utils.HoursInMonth, storage[1].Value, 1e-9)
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	pricingClient := mockpricing.NewPricing(t)
+	pricingClient.EXPECT().GetProducts(mock.Anything, mock.Anything).Return(&pricing.GetProductsOutput{}, nil).Times(2)
+	client := mockrds.NewRDS(t)
+	client.EXPECT().DescribeDBInstances(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(time.Hour, pricingClient, map[string]rdsclient.RDS{"us-east-1": client}, nil)
+	ch := make(chan prometheus.Metric, 10)
+	assert.ErrorIs(t, c.Collect(ch), assert.AnError)
+}
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+)
+
+// defaultIMDSTimeout bounds the discovery of the region through the instance metadata service when IMDSTimeout isn't
+// set. The metadata service answers within milliseconds on EC2, so it only delays the startup outside EC2.
+const defaultIMDSTimeout = 2 * time.Second
+
+var ErrMissingRegion = errors.New("no AWS region configured, set -aws.region, AWS_REGION or the region of the profile")
+
+// IMDSConfig configures the EC2 instance metadata service (IMDS), which is the last source of the region after Region,
+// AWS_REGION and the region of the profile, and a source of the credentials of the instance role.
+type IMDSConfig struct {
+	// Disabled skips the metadata service entirely, eg in local development or on Fargate where there is none, so
+	// that the region has to be configured explicitly.
+	Disabled bool
+	// Timeout bounds the discovery of the region through the metadata service, defaultIMDSTimeout is used when it's 0.
+	Timeout time.Duration
+}
+
+// regionOptions returns the options that resolve the region of the AWS config: region when it's set, then AWS_REGION
+// and the region of the profile, and lastly the metadata service unless it's disabled.
+func (c IMDSConfig) regionOptions(region string) []func(*awsconfig.LoadOptions) error {
+	var options []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		options = append(options, awsconfig.WithRegion(region))
+	}
+	if c.Disabled {
+		// Disabling the client also skips the credentials of the instance role
+		return append(options, awsconfig.WithEC2IMDSClientEnableState(imds.ClientDisabled))
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultIMDSTimeout
+	}
+	// The metadata service is only called when no other source set the region. Without retries, a host without
+	// it fails after a single timeout instead of the retries of the default client.
+	return append(options, awsconfig.WithEC2IMDSRegion(func(o *awsconfig.UseEC2IMDSRegion) {
+		o.Client = imds.New(imds.Options{
+			HTTPClient: &http.Client{Timeout: timeout},
+			Retryer:    aws.NopRetryer{},
+		})
+	}))
+}
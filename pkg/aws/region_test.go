@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIMDSConfig_regionOptions(t *testing.T) {
+	imdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			w.Header().Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+			_, _ = w.Write([]byte("token"))
+		case "/latest/dynamic/instance-identity/document":
+			_, _ = w.Write([]byte(`{"region": "eu-west-1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer imdsServer.Close()
+	hangingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hangingServer.Close()
+
+	tests := map[string]struct {
+		imds         IMDSConfig
+		region       string
+		env          map[string]string
+		imdsEndpoint string
+		want         string
+		wantErr      bool
+	}{
+		"region takes precedence over the environment": {
+			region:       "us-east-2",
+			env:          map[string]string{"AWS_REGION": "us-west-2"},
+			imdsEndpoint: imdsServer.URL,
+			want:         "us-east-2",
+		},
+		"environment takes precedence over imds": {
+			env:          map[string]string{"AWS_REGION": "us-west-2"},
+			imdsEndpoint: imdsServer.URL,
+			want:         "us-west-2",
+		},
+		"imds is the last source of the region": {
+			imdsEndpoint: imdsServer.URL,
+			want:         "eu-west-1",
+		},
+		"disabled imds isn't called": {
+			imds:         IMDSConfig{Disabled: true},
+			imdsEndpoint: imdsServer.URL,
+			want:         "",
+		},
+		"unreachable imds fails after the timeout": {
+			imds:         IMDSConfig{Timeout: 50 * time.Millisecond},
+			imdsEndpoint: hangingServer.URL,
+			wantErr:      true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Isolate the test from the region of the environment and of the shared config files of the host
+			t.Setenv("AWS_REGION", "")
+			t.Setenv("AWS_DEFAULT_REGION", "")
+			t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+			t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", tt.imdsEndpoint)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			start := time.Now()
+			ac, err := awsconfig.LoadDefaultConfig(context.Background(), tt.imds.regionOptions(tt.region)...)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Less(t, time.Since(start), time.Second)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ac.Region)
+		})
+	}
+}
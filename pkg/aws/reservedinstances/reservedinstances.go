@@ -0,0 +1,173 @@
+package reservedinstances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "aws"
+	subsystem    = "aws_ri"
+)
+
+var (
+	ExpiryTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "expiry_timestamp_seconds"),
+		"The time at which an active EC2 Reserved Instance expires, in seconds since the epoch.",
+		[]string{"region", "reserved_instances_id", "instance_type", "instance_family", "offering_class"},
+		nil,
+	)
+	ActiveInstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "active_instances"),
+		"The number of instances covered by the active EC2 Reserved Instances of an instance family.",
+		[]string{"region", "instance_family"},
+		nil,
+	)
+)
+
+// reservation is an active Reserved Instance purchase of a region.
+type reservation struct {
+	id            string
+	instanceType  string
+	offeringClass string
+	count         int32
+	end           float64
+}
+
+// Collector exports the expiration calendar of the active EC2 Reserved Instances of the account, so that renewals can
+// be planned. The Reserved Instances are listed on every scrape.
+type Collector struct {
+	regionClients map[string]ec2client.EC2
+}
+
+// New creates a Collector listing the Reserved Instances of the regions of regionClients.
+func New(regionClients map[string]ec2client.EC2) *Collector {
+	return &Collector{
+		regionClients: regionClients,
+	}
+}
+
+func (c *Collector) Name() string {
+	return "ReservedInstances"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- ExpiryTimestampDesc
+	ch <- ActiveInstancesDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the active Reserved Instances of every region and exports when they expire and how many instances of
+// each family they cover. A region failing is reported in its scope error metric and only fails the collector when
+// every region failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	regions := make([]string, 0, len(c.regionClients))
+	for region := range c.regionClients {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	reservations := make([][]reservation, len(regions))
+	errs := make([]error, len(regions))
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservations[i], errs[i] = listReservations(context.Background(), c.regionClients[region])
+		}()
+	}
+	wg.Wait()
+
+	var failedRegions []error
+	for i, region := range regions {
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, region, errs[i])
+		if errs[i] != nil {
+			log.Printf("error listing reserved instances in region %s: %s", region, errs[i])
+			failedRegions = append(failedRegions, fmt.Errorf("region %s: %w", region, errs[i]))
+			continue
+		}
+		families := make(map[string]int32)
+		for _, r := range reservations[i] {
+			family := instanceFamily(r.instanceType)
+			families[family] += r.count
+			ch <- prometheus.MustNewConstMetric(ExpiryTimestampDesc, prometheus.GaugeValue, r.end, region, r.id, r.instanceType, family, r.offeringClass)
+		}
+		names := make([]string, 0, len(families))
+		for family := range families {
+			names = append(names, family)
+		}
+		sort.Strings(names)
+		for _, family := range names {
+			ch <- prometheus.MustNewConstMetric(ActiveInstancesDesc, prometheus.GaugeValue, float64(families[family]), region, family)
+		}
+	}
+	if len(regions) > 0 && len(failedRegions) == len(regions) {
+		return errors.Join(failedRegions...)
+	}
+	return nil
+}
+
+// listReservations lists the active Reserved Instances of a region, sorted by expiration and ID.
+func listReservations(ctx context.Context, client ec2client.EC2) ([]reservation, error) {
+	resp, err := client.DescribeReservedInstances(ctx, &ec2.DescribeReservedInstancesInput{
+		Filters: []types.Filter{{
+			Name:   aws.String("state"),
+			Values: []string{string(types.ReservedInstanceStateActive)},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing reserved instances: %w", err)
+	}
+	reservations := make([]reservation, 0, len(resp.ReservedInstances))
+	for _, ri := range resp.ReservedInstances {
+		if ri.End == nil {
+			continue
+		}
+		reservations = append(reservations, reservation{
+			id:            aws.ToString(ri.ReservedInstancesId),
+			instanceType:  string(ri.InstanceType),
+			offeringClass: string(ri.OfferingClass),
+			count:         aws.ToInt32(ri.InstanceCount),
+			end:           float64(ri.End.Unix()),
+		})
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].end != reservations[j].end {
+			return reservations[i].end < reservations[j].end
+		}
+		return reservations[i].id < reservations[j].id
+	})
+	return reservations, nil
+}
+
+// instanceFamily returns the family of an instance type, m5 for m5.xlarge.
+func instanceFamily(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	return family
+}
@@ -0,0 +1,84 @@
+package reservedinstances
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockec2 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/ec2"
+	ec2client "github.com/grafana/cloudcost-exporter/pkg/aws/services/ec2"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestCollector_Collect(t *testing.T) {
+	soon := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	usEast := mockec2.NewEC2(t)
+	usEast.EXPECT().DescribeReservedInstances(mock.Anything, mock.MatchedBy(func(input *ec2.DescribeReservedInstancesInput) bool {
+		return len(input.Filters) == 1 && aws.ToString(input.Filters[0].Name) == "state" && input.Filters[0].Values[0] == "active"
+	})).Return(&ec2.DescribeReservedInstancesOutput{ReservedInstances: []types.ReservedInstances{
+		{ReservedInstancesId: aws.String("ri-2"), InstanceType: types.InstanceTypeM5Large, InstanceCount: aws.Int32(2), OfferingClass: types.OfferingClassTypeStandard, End: aws.Time(later)},
+		{ReservedInstancesId: aws.String("ri-1"), InstanceType: types.InstanceTypeM5Xlarge, InstanceCount: aws.Int32(3), OfferingClass: types.OfferingClassTypeConvertible, End: aws.Time(soon)},
+		{ReservedInstancesId: aws.String("ri-3"), InstanceType: types.InstanceTypeC6gMedium, InstanceCount: aws.Int32(1), OfferingClass: types.OfferingClassTypeStandard, End: aws.Time(later)},
+	}}, nil)
+	euWest := mockec2.NewEC2(t)
+	euWest.EXPECT().DescribeReservedInstances(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(map[string]ec2client.EC2{"us-east-1": usEast, "eu-west-1": euWest})
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, c.Collect(ch))
+	close(ch)
+	var metrics []*utils.MetricResult
+	for metric := range ch {
+		metrics = append(metrics, utils.ReadMetrics(metric))
+	}
+
+	require.Len(t, metrics, 7)
+	assert.Equal(t, "cloudcost_exporter_collector_scope_last_scrape_error", metrics[0].FqName)
+	assert.Equal(t, utils.LabelMap{"provider": "aws", "collector": "aws_ri", "scope": "eu-west-1"}, metrics[0].Labels)
+	assert.Equal(t, 1.0, metrics[0].Value)
+	assert.Equal(t, 0.0, metrics[1].Value)
+	for i, want := range []struct {
+		labels utils.LabelMap
+		end    time.Time
+	}{
+		{utils.LabelMap{"region": "us-east-1", "reserved_instances_id": "ri-1", "instance_type": "m5.xlarge", "instance_family": "m5", "offering_class": "convertible"}, soon},
+		{utils.LabelMap{"region": "us-east-1", "reserved_instances_id": "ri-2", "instance_type": "m5.large", "instance_family": "m5", "offering_class": "standard"}, later},
+		{utils.LabelMap{"region": "us-east-1", "reserved_instances_id": "ri-3", "instance_type": "c6g.medium", "instance_family": "c6g", "offering_class": "standard"}, later},
+	} {
+		assert.Equal(t, &utils.MetricResult{
+			FqName:     "cloudcost_aws_ri_expiry_timestamp_seconds",
+			Labels:     want.labels,
+			Value:      float64(want.end.Unix()),
+			MetricType: prometheus.GaugeValue,
+		}, metrics[2+i])
+	}
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_aws_ri_active_instances",
+		Labels:     utils.LabelMap{"region": "us-east-1", "instance_family": "c6g"},
+		Value:      1,
+		MetricType: prometheus.GaugeValue,
+	}, metrics[5])
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_aws_ri_active_instances",
+		Labels:     utils.LabelMap{"region": "us-east-1", "instance_family": "m5"},
+		Value:      5,
+		MetricType: prometheus.GaugeValue,
+	}, metrics[6])
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	client := mockec2.NewEC2(t)
+	client.EXPECT().DescribeReservedInstances(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	c := New(map[string]ec2client.EC2{"us-east-1": client})
+	ch := make(chan prometheus.Metric, 10)
+	assert.ErrorIs(t, c.Collect(ch), assert.AnError)
+}
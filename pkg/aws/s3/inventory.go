@@ -0,0 +1,203 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
+	s3client "github.com/grafana/cloudcost-exporter/pkg/aws/services/s3"
+)
+
+const (
+	// maxBucketsPerPage paginates ListBuckets, which only returns the region of the buckets when paginated.
+	maxBucketsPerPage = 1000
+	// storageMetricsPeriod is the period of the daily storage metrics of S3 in CloudWatch.
+	storageMetricsPeriod = 24 * time.Hour
+	// storageMetricsWindow is how far back the size of a bucket is looked up. S3 reports it once a day, a couple of
+	// days cover a late report.
+	storageMetricsWindow = 3 * storageMetricsPeriod
+	// maxMetricDataQueries is the maximum number of queries CloudWatch accepts in a single GetMetricData call.
+	maxMetricDataQueries = 500
+)
+
+// StorageTypes are the storage types of the BucketSizeBytes metric of CloudWatch whose size is exported for every
+// bucket. They're the class label of the storage cost, eg StandardLabel, and of the metrics of yace.
+var StorageTypes = []string{
+	StandardLabel,
+	"IntelligentTieringFAStorage",
+	"IntelligentTieringIAStorage",
+	"IntelligentTieringAIAStorage",
+	"IntelligentTieringAAStorage",
+	"IntelligentTieringDAAStorage",
+	"StandardIAStorage",
+	"OneZoneIAStorage",
+	"ReducedRedundancyStorage",
+	"GlacierInstantRetrievalStorage",
+	"GlacierStorage",
+	"DeepArchiveStorage",
+}
+
+// Inventory lists the buckets of the account, and their size by storage type when CloudWatch is set.
+type Inventory struct {
+	Client s3client.S3
+	// CloudWatch returns the CloudWatch client of a region. The size of the buckets isn't looked up when it's nil.
+	CloudWatch func(region string) (cloudwatchclient.CloudWatch, error)
+}
+
+// BucketInventory is a bucket of the account along with its size in bytes by storage type. Sizes is nil when the size
+// of the buckets isn't looked up, and misses the storage types without any object.
+type BucketInventory struct {
+	Bucket
+	Sizes map[string]float64
+}
+
+// listBuckets lists the buckets of the account, sorted by region and name, along with their size when CloudWatch is
+// set. The size of the buckets of a region that can't be looked up is logged and left out rather than failing the
+// inventory.
+func (i *Inventory) listBuckets(ctx context.Context, now time.Time) ([]BucketInventory, error) {
+	buckets, err := i.buckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if i.CloudWatch == nil {
+		return buckets, nil
+	}
+	byRegion := make(map[string][]int)
+	for j, bucket := range buckets {
+		byRegion[bucket.Region] = append(byRegion[bucket.Region], j)
+	}
+	for region, indexes := range byRegion {
+		client, err := i.CloudWatch(region)
+		if err != nil {
+			log.Printf("Error creating the cloudwatch client of region %s: %v\n", region, err)
+			continue
+		}
+		names := make([]string, 0, len(indexes))
+		for _, j := range indexes {
+			names = append(names, buckets[j].Name)
+		}
+		sizes, err := listBucketSizes(ctx, client, names, now)
+		if err != nil {
+			log.Printf("Error getting the size of the buckets of region %s: %v\n", region, err)
+			continue
+		}
+		for _, j := range indexes {
+			buckets[j].Sizes = sizes[buckets[j].Name]
+		}
+	}
+	return buckets, nil
+}
+
+// buckets lists the buckets of the account, sorted by region and name. The region of a bucket is looked up with
+// GetBucketLocation when ListBuckets doesn't return it, eg for S3 compatible endpoints.
+func (i *Inventory) buckets(ctx context.Context) ([]BucketInventory, error) {
+	var buckets []BucketInventory
+	input := &s3client.ListBucketsInput{MaxBuckets: aws.Int32(maxBucketsPerPage)}
+	for {
+		output, err := i.Client.ListBuckets(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error listing buckets: %w", err)
+		}
+		for _, bucket := range output.Buckets {
+			if bucket.Name == nil || *bucket.Name == "" {
+				continue
+			}
+			region := aws.ToString(bucket.BucketRegion)
+			if region == "" {
+				location, err := i.Client.GetBucketLocation(ctx, &s3client.GetBucketLocationInput{Bucket: bucket.Name})
+				if err != nil {
+					return nil, fmt.Errorf("error getting the location of bucket %s: %w", *bucket.Name, err)
+				}
+				region = location.Region()
+			}
+			buckets = append(buckets, BucketInventory{Bucket: Bucket{Name: *bucket.Name, Region: region}})
+		}
+		if output.ContinuationToken == nil || *output.ContinuationToken == "" {
+			break
+		}
+		input.ContinuationToken = output.ContinuationToken
+	}
+	sort.Slice(buckets, func(a, b int) bool {
+		if buckets[a].Region != buckets[b].Region {
+			return buckets[a].Region < buckets[b].Region
+		}
+		return buckets[a].Name < buckets[b].Name
+	})
+	return buckets, nil
+}
+
+// listBucketSizes returns the latest size in bytes of every StorageTypes of the buckets of a region, keyed by bucket
+// and storage type. Storage types without datapoints are left out.
+func listBucketSizes(ctx context.Context, client cloudwatchclient.CloudWatch, buckets []string, now time.Time) (map[string]map[string]float64, error) {
+	type query struct {
+		bucket      string
+		storageType string
+	}
+	var queries []query
+	for _, bucket := range buckets {
+		for _, storageType := range StorageTypes {
+			queries = append(queries, query{bucket: bucket, storageType: storageType})
+		}
+	}
+	sizes := make(map[string]map[string]float64, len(buckets))
+	for start := 0; start < len(queries); start += maxMetricDataQueries {
+		batch := queries[start:min(start+maxMetricDataQueries, len(queries))]
+		dataQueries := make([]cloudwatchTypes.MetricDataQuery, 0, len(batch))
+		for j, q := range batch {
+			dataQueries = append(dataQueries, cloudwatchTypes.MetricDataQuery{
+				// Query ids have to start with a lowercase letter, bucket names can't be used as is because of the dots
+				Id: aws.String(fmt.Sprintf("q%d", j)),
+				MetricStat: &cloudwatchTypes.MetricStat{
+					Metric: &cloudwatchTypes.Metric{
+						Namespace:  aws.String("AWS/S3"),
+						MetricName: aws.String("BucketSizeBytes"),
+						Dimensions: []cloudwatchTypes.Dimension{
+							{Name: aws.String("BucketName"), Value: aws.String(q.bucket)},
+							{Name: aws.String("StorageType"), Value: aws.String(q.storageType)},
+						},
+					},
+					Period: aws.Int32(int32(storageMetricsPeriod.Seconds())),
+					Stat:   aws.String("Average"),
+				},
+			})
+		}
+		input := &cloudwatch.GetMetricDataInput{
+			MetricDataQueries: dataQueries,
+			StartTime:         aws.Time(now.Add(-storageMetricsWindow)),
+			EndTime:           aws.Time(now),
+		}
+		for {
+			resp, err := client.GetMetricData(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			for _, result := range resp.MetricDataResults {
+				if result.Id == nil || len(result.Values) == 0 {
+					continue
+				}
+				var j int
+				if _, err := fmt.Sscanf(*result.Id, "q%d", &j); err != nil || j >= len(batch) {
+					continue
+				}
+				// The values are sorted by descending timestamp, the first one is the latest size
+				q := batch[j]
+				if _, ok := sizes[q.bucket]; !ok {
+					sizes[q.bucket] = make(map[string]float64)
+				}
+				sizes[q.bucket][q.storageType] = result.Values[0]
+			}
+			if resp.NextToken == nil || *resp.NextToken == "" {
+				break
+			}
+			input.NextToken = resp.NextToken
+		}
+	}
+	return sizes, nil
+}
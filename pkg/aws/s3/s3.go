@@ -27,6 +27,11 @@ const (
 	// This needs to line up with yace so we can properly join the data in PromQL
 	StandardLabel = "StandardStorage"
 	subsystem     = "aws_s3"
+	// bucketCostDays is how many days of bucket costs are averaged. Cost Explorer only keeps the resource-level data
+	// of the last 14 days.
+	bucketCostDays = 13
+	// bucketArnPrefix prefixes the resource id of a bucket when Cost Explorer returns its ARN rather than its name.
+	bucketArnPrefix = "arn:aws:s3:::"
 )
 
 // billingToRegionMap maps the AWS billing region code to the AWS region
@@ -83,6 +88,15 @@ type Metrics struct {
 
 	// NextScrapeGauge is a gauge that tracks the next time the exporter will scrape AWS billing data
 	NextScrapeGauge prometheus.Gauge
+
+	// BucketGauge measures the cost of each bucket in $/h, averaged over the resource-level data of Cost Explorer.
+	BucketGauge *prometheus.GaugeVec
+
+	// BucketInfoGauge is 1 for each bucket of the account, labelled with its region.
+	BucketInfoGauge *prometheus.GaugeVec
+
+	// BucketSizeGauge measures the size of each bucket in bytes by storage class, from the storage metrics of CloudWatch.
+	BucketSizeGauge *prometheus.GaugeVec
 }
 
 // NewMetrics returns a new Metrics instance.
@@ -116,6 +130,27 @@ func NewMetrics() Metrics {
 			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, subsystem, "next_scrape"),
 			Help: "The next time the exporter will scrape AWS billing data. Can be used to trigger alerts if now - nextScrape > interval",
 		}),
+
+		BucketGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "bucket_usd_per_hour"),
+			Help: "Cost of an S3 bucket averaged over the last days of resource-level data of Cost Explorer. Cost represented in USD/h",
+		},
+			[]string{"bucket", "region"},
+		),
+
+		BucketInfoGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "bucket_info"),
+			Help: "Region information of an S3 bucket by bucket",
+		},
+			[]string{"bucket", "region"},
+		),
+
+		BucketSizeGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "bucket_size_bytes"),
+			Help: "Size of an S3 bucket by storage class, as last reported by the daily storage metrics of CloudWatch",
+		},
+			[]string{"bucket", "region", "class"},
+		),
 	}
 }
 
@@ -127,7 +162,14 @@ type Collector struct {
 	nextScrape  time.Time
 	metrics     Metrics
 	billingData *BillingData
-	m           sync.Mutex
+	// bucketCosts enables the cost of each bucket, which requires the resource-level data of S3 to be enabled in Cost
+	// Explorer.
+	bucketCosts bool
+	buckets     map[Bucket]float64
+	// inventory lists the buckets of the account when it's set.
+	inventory       *Inventory
+	bucketInventory []BucketInventory
+	m               sync.Mutex
 }
 
 // Describe is used to register the metrics with the Prometheus client
@@ -144,11 +186,14 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 	return nil
 }
 
-// New creates a new Collector with a client and scrape interval defined.
-func New(scrapeInterval time.Duration, client costexplorer.CostExplorer) *Collector {
+// New creates a new Collector with a client and scrape interval defined. The cost of each bucket is exported when
+// bucketCosts is set, and the buckets of the account when inventory is set.
+func New(scrapeInterval time.Duration, client costexplorer.CostExplorer, bucketCosts bool, inventory *Inventory) *Collector {
 	return &Collector{
-		client:   client,
-		interval: scrapeInterval,
+		client:      client,
+		interval:    scrapeInterval,
+		bucketCosts: bucketCosts,
+		inventory:   inventory,
 		// Initially Set nextScrape to the current time minus the scrape interval so that the first scrape will run immediately
 		nextScrape: time.Now().Add(-scrapeInterval),
 		metrics:    NewMetrics(),
@@ -167,6 +212,9 @@ func (c *Collector) Register(registry provider.Registry) error {
 	registry.MustRegister(c.metrics.RequestCount)
 	registry.MustRegister(c.metrics.NextScrapeGauge)
 	registry.MustRegister(c.metrics.RequestErrorsCount)
+	registry.MustRegister(c.metrics.BucketGauge)
+	registry.MustRegister(c.metrics.BucketInfoGauge)
+	registry.MustRegister(c.metrics.BucketSizeGauge)
 
 	return nil
 }
@@ -187,11 +235,36 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 			return 0
 		}
 		c.billingData = billingData
-		c.nextScrape = time.Now().Add(c.interval)
+		if c.bucketCosts {
+			// The costs of the buckets are kept from the previous refresh when they can't be fetched, eg when the
+			// resource-level data isn't enabled yet, rather than failing the storage and operation costs as well
+			buckets, err := getBucketCosts(c.client, endDate.AddDate(0, 0, -bucketCostDays), endDate, c.metrics)
+			if err != nil {
+				log.Printf("Error getting bucket costs: %v\n", err)
+			} else {
+				c.buckets = buckets
+			}
+		}
+		if c.inventory != nil {
+			// Like the costs, the inventory is kept from the previous refresh when the buckets can't be listed
+			buckets, err := c.inventory.listBuckets(context.TODO(), now)
+			if err != nil {
+				log.Printf("Error listing bucket inventory: %v\n", err)
+			} else {
+				c.bucketInventory = buckets
+			}
+		}
+		c.nextScrape = utils.NextScrape(time.Now(), c.interval)
 		c.metrics.NextScrapeGauge.Set(float64(c.nextScrape.Unix()))
 	}
 
 	exportMetrics(c.billingData, c.metrics)
+	if c.bucketCosts {
+		exportBucketMetrics(c.buckets, c.metrics)
+	}
+	if c.inventory != nil {
+		exportBucketInventory(c.bucketInventory, c.metrics)
+	}
 	return 1.0
 }
 
@@ -321,6 +394,100 @@ func getBillingData(client costexplorer.CostExplorer, startDate time.Time, endDa
 	return parseBillingData(outputs), nil
 }
 
+// Bucket identifies an S3 bucket in the resource-level data of Cost Explorer.
+type Bucket struct {
+	Name   string
+	Region string
+}
+
+// getBucketCosts fetches the resource-level costs of S3 between startDate and endDate and returns the average cost of
+// each bucket in USD/h.
+func getBucketCosts(client costexplorer.CostExplorer, startDate time.Time, endDate time.Time, m Metrics) (map[Bucket]float64, error) {
+	input := &awscostexplorer.GetCostAndUsageWithResourcesInput{
+		TimePeriod: &types.DateInterval{
+			Start: aws.String(startDate.Format("2006-01-02")),
+			End:   aws.String(endDate.Format("2006-01-02")),
+		},
+		Granularity: types.GranularityDaily,
+		Metrics:     []string{"UnblendedCost"},
+		GroupBy: []types.GroupDefinition{
+			{
+				Type: types.GroupDefinitionTypeDimension,
+				Key:  aws.String("RESOURCE_ID"),
+			},
+			{
+				Type: types.GroupDefinitionTypeDimension,
+				Key:  aws.String("REGION"),
+			},
+		},
+		// The resource-level data must be filtered by service
+		Filter: &types.Expression{
+			Dimensions: &types.DimensionValues{
+				Key:    types.DimensionService,
+				Values: []string{"Amazon Simple Storage Service"},
+			},
+		},
+	}
+
+	var outputs []*awscostexplorer.GetCostAndUsageWithResourcesOutput
+	for {
+		m.RequestCount.Inc()
+		output, err := client.GetCostAndUsageWithResources(context.TODO(), input)
+		if err != nil {
+			m.RequestErrorsCount.Inc()
+			return nil, err
+		}
+		outputs = append(outputs, output)
+		if output.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+
+	return parseBucketCosts(outputs), nil
+}
+
+// parseBucketCosts sums the cost of each bucket over the days of the outputs and divides it by the hours of these days.
+func parseBucketCosts(outputs []*awscostexplorer.GetCostAndUsageWithResourcesOutput) map[Bucket]float64 {
+	costs := make(map[Bucket]float64)
+	days := make(map[string]bool)
+	for _, output := range outputs {
+		for _, result := range output.ResultsByTime {
+			if result.TimePeriod != nil && result.TimePeriod.Start != nil {
+				days[*result.TimePeriod.Start] = true
+			}
+			for _, group := range result.Groups {
+				if len(group.Keys) < 2 {
+					log.Printf("skipping bucket group without resource id and region")
+					continue
+				}
+				name := strings.TrimPrefix(group.Keys[0], bucketArnPrefix)
+				if name == "" {
+					continue
+				}
+				metric, ok := group.Metrics["UnblendedCost"]
+				if !ok || metric.Amount == nil {
+					continue
+				}
+				cost, err := strconv.ParseFloat(*metric.Amount, 64)
+				if err != nil {
+					log.Printf("Error parsing cost amount of bucket %s: %v\n", name, err)
+					continue
+				}
+				costs[Bucket{Name: name, Region: group.Keys[1]}] += cost
+			}
+		}
+	}
+	if len(days) == 0 {
+		return costs
+	}
+	hours := float64(len(days)) * 24
+	for bucket, cost := range costs {
+		costs[bucket] = cost / hours
+	}
+	return costs
+}
+
 // parseBillingData takes the output from the AWS Cost Explorer API and parses it into a S3BillingData struct
 func parseBillingData(outputs []*awscostexplorer.GetCostAndUsageOutput) *BillingData {
 	billingData := NewS3BillingData()
@@ -407,6 +574,27 @@ func exportMetrics(s3BillingData *BillingData, m Metrics) {
 	}
 }
 
+// exportBucketMetrics exports the cost of every bucket. The gauge is reset first so that deleted buckets are dropped.
+func exportBucketMetrics(buckets map[Bucket]float64, m Metrics) {
+	m.BucketGauge.Reset()
+	for bucket, cost := range buckets {
+		m.BucketGauge.WithLabelValues(bucket.Name, bucket.Region).Set(cost)
+	}
+}
+
+// exportBucketInventory exports the info and the size by storage class of every bucket. The gauges are reset first so
+// that deleted buckets are dropped.
+func exportBucketInventory(buckets []BucketInventory, m Metrics) {
+	m.BucketInfoGauge.Reset()
+	m.BucketSizeGauge.Reset()
+	for _, bucket := range buckets {
+		m.BucketInfoGauge.WithLabelValues(bucket.Name, bucket.Region).Set(1)
+		for class, size := range bucket.Sizes {
+			m.BucketSizeGauge.WithLabelValues(bucket.Name, bucket.Region, class).Set(size)
+		}
+	}
+}
+
 // unitCostForComponent will calculate the unit cost for a given component. This is necessary because the
 // unit cost will depend on the type of component.
 func unitCostForComponent(component string, pricing *Pricing) float64 {
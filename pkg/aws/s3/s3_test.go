@@ -9,6 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 	awscostexplorer "github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,7 +21,11 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	mockcloudwatch "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/cloudwatch"
 	mockcostexplorer "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/costexplorer"
+	mocks3 "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/s3"
+	cloudwatchclient "github.com/grafana/cloudcost-exporter/pkg/aws/services/cloudwatch"
+	s3client "github.com/grafana/cloudcost-exporter/pkg/aws/services/s3"
 	mock_provider "github.com/grafana/cloudcost-exporter/pkg/provider/mocks"
 )
 
@@ -178,7 +185,7 @@ func TestNewCollector(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			c := mockcostexplorer.NewCostExplorer(t)
 
-			got := New(tt.args.interval, c)
+			got := New(tt.args.interval, c, false, nil)
 			assert.NotNil(t, got)
 			assert.Equal(t, tt.args.interval, got.interval)
 		})
@@ -193,7 +200,7 @@ func TestCollector_Name(t *testing.T) {
 func TestCollector_Register(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	r := mock_provider.NewMockRegistry(ctrl)
-	r.EXPECT().MustRegister(gomock.Any()).Times(5)
+	r.EXPECT().MustRegister(gomock.Any()).Times(8)
 
 	c := &Collector{}
 	err := c.Register(r)
@@ -613,6 +620,145 @@ cloudcost_aws_s3_storage_by_location_usd_per_gibyte_hour{class="StandardStorage"
 	}
 }
 
+func TestCollector_BucketCosts(t *testing.T) {
+	bucketGroup := func(resourceID string, region string, cost string) types.ResultByTime {
+		return types.ResultByTime{
+			Groups: []types.Group{{
+				Keys:    []string{resourceID, region},
+				Metrics: map[string]types.MetricValue{"UnblendedCost": {Amount: aws.String(cost)}},
+			}},
+		}
+	}
+	day := func(start string, result types.ResultByTime) types.ResultByTime {
+		result.TimePeriod = &types.DateInterval{Start: aws.String(start)}
+		return result
+	}
+
+	for _, tc := range []struct {
+		name                         string
+		GetCostAndUsageWithResources func(ctx context.Context, params *awscostexplorer.GetCostAndUsageWithResourcesInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageWithResourcesOutput, error)
+		expectedExposition           string
+	}{
+		{
+			name: "costs averaged over the days of every page",
+			GetCostAndUsageWithResources: func(ctx context.Context, params *awscostexplorer.GetCostAndUsageWithResourcesInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageWithResourcesOutput, error) {
+				assert.Equal(t, "RESOURCE_ID", *params.GroupBy[0].Key)
+				if params.NextPageToken == nil {
+					return &awscostexplorer.GetCostAndUsageWithResourcesOutput{
+						ResultsByTime: []types.ResultByTime{day("2024-05-01", bucketGroup("logs", "us-east-1", "24"))},
+						NextPageToken: aws.String("next"),
+					}, nil
+				}
+				return &awscostexplorer.GetCostAndUsageWithResourcesOutput{
+					ResultsByTime: []types.ResultByTime{
+						day("2024-05-02", bucketGroup("logs", "us-east-1", "24")),
+						day("2024-05-02", bucketGroup("arn:aws:s3:::backups", "eu-west-1", "4.8")),
+						day("2024-05-02", bucketGroup("", "eu-west-1", "100")),
+					},
+				}, nil
+			},
+			expectedExposition: `
+# HELP cloudcost_aws_s3_bucket_usd_per_hour Cost of an S3 bucket averaged over the last days of resource-level data of Cost Explorer. Cost represented in USD/h
+# TYPE cloudcost_aws_s3_bucket_usd_per_hour gauge
+cloudcost_aws_s3_bucket_usd_per_hour{bucket="backups",region="eu-west-1"} 0.09999999999999999
+cloudcost_aws_s3_bucket_usd_per_hour{bucket="logs",region="us-east-1"} 1
+`,
+		},
+		{
+			name: "resource-level data not enabled",
+			GetCostAndUsageWithResources: func(ctx context.Context, params *awscostexplorer.GetCostAndUsageWithResourcesInput, optFns ...func(*awscostexplorer.Options)) (*awscostexplorer.GetCostAndUsageWithResourcesOutput, error) {
+				return nil, &types.DataUnavailableException{Message: aws.String("resource-level data is not enabled")}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ce := mockcostexplorer.NewCostExplorer(t)
+			ce.EXPECT().
+				GetCostAndUsage(mock.Anything, mock.Anything, mock.Anything).
+				Return(&awscostexplorer.GetCostAndUsageOutput{}, nil).
+				Once()
+			ce.EXPECT().
+				GetCostAndUsageWithResources(mock.Anything, mock.Anything, mock.Anything).
+				RunAndReturn(tc.GetCostAndUsageWithResources)
+
+			c := New(time.Hour, ce, true, nil)
+			// The storage and operation costs are exported even when the costs of the buckets can't be fetched
+			require.Equal(t, 1.0, c.CollectMetrics(nil))
+
+			r := prometheus.NewPedanticRegistry()
+			require.NoError(t, c.Register(r))
+			assert.NoError(t, testutil.CollectAndCompare(r, strings.NewReader(tc.expectedExposition), "cloudcost_aws_s3_bucket_usd_per_hour"))
+		})
+	}
+}
+
+func TestCollector_BucketInventory(t *testing.T) {
+	client := mocks3.NewS3(t)
+	client.EXPECT().ListBuckets(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *s3client.ListBucketsInput) (*s3client.ListBucketsOutput, error) {
+			assert.Equal(t, int32(maxBucketsPerPage), aws.ToInt32(input.MaxBuckets))
+			if input.ContinuationToken == nil {
+				return &s3client.ListBucketsOutput{
+					Buckets: []s3client.Bucket{
+						{Name: aws.String("logs"), BucketRegion: aws.String("us-east-1")},
+						// The region of a bucket is looked up when it isn't listed
+						{Name: aws.String("legacy")},
+					},
+					ContinuationToken: aws.String("next"),
+				}, nil
+			}
+			return &s3client.ListBucketsOutput{Buckets: []s3client.Bucket{{Name: aws.String("backups"), BucketRegion: aws.String("eu-west-1")}}}, nil
+		}).Times(2)
+	client.EXPECT().GetBucketLocation(mock.Anything, &s3client.GetBucketLocationInput{Bucket: aws.String("legacy")}).
+		Return(&s3client.GetBucketLocationOutput{LocationConstraint: "EU"}, nil).Once()
+
+	sizes := map[string]float64{"logs/StandardStorage": 1024, "logs/GlacierStorage": 2048}
+	usEast1 := mockcloudwatch.NewCloudWatch(t)
+	usEast1.EXPECT().GetMetricData(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *cloudwatch.GetMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+			assert.Len(t, input.MetricDataQueries, len(StorageTypes))
+			var results []cloudwatchTypes.MetricDataResult
+			for _, query := range input.MetricDataQueries {
+				dimensions := query.MetricStat.Metric.Dimensions
+				if size, ok := sizes[aws.ToString(dimensions[0].Value)+"/"+aws.ToString(dimensions[1].Value)]; ok {
+					results = append(results, cloudwatchTypes.MetricDataResult{Id: query.Id, Values: []float64{size, 1}})
+				} else {
+					results = append(results, cloudwatchTypes.MetricDataResult{Id: query.Id})
+				}
+			}
+			return &cloudwatch.GetMetricDataOutput{MetricDataResults: results}, nil
+		}).Once()
+	euWest1 := mockcloudwatch.NewCloudWatch(t)
+	euWest1.EXPECT().GetMetricData(mock.Anything, mock.Anything).Return(nil, fmt.Errorf("access denied")).Once()
+	clients := map[string]cloudwatchclient.CloudWatch{"us-east-1": usEast1, "eu-west-1": euWest1}
+
+	ce := mockcostexplorer.NewCostExplorer(t)
+	ce.EXPECT().
+		GetCostAndUsage(mock.Anything, mock.Anything, mock.Anything).
+		Return(&awscostexplorer.GetCostAndUsageOutput{}, nil).
+		Once()
+	c := New(time.Hour, ce, false, &Inventory{
+		Client:     client,
+		CloudWatch: func(region string) (cloudwatchclient.CloudWatch, error) { return clients[region], nil },
+	})
+	require.Equal(t, 1.0, c.CollectMetrics(nil))
+
+	// The buckets whose size can't be looked up are still exported
+	r := prometheus.NewPedanticRegistry()
+	require.NoError(t, c.Register(r))
+	assert.NoError(t, testutil.CollectAndCompare(r, strings.NewReader(`
+# HELP cloudcost_aws_s3_bucket_info Region information of an S3 bucket by bucket
+# TYPE cloudcost_aws_s3_bucket_info gauge
+cloudcost_aws_s3_bucket_info{bucket="backups",region="eu-west-1"} 1
+cloudcost_aws_s3_bucket_info{bucket="legacy",region="eu-west-1"} 1
+cloudcost_aws_s3_bucket_info{bucket="logs",region="us-east-1"} 1
+# HELP cloudcost_aws_s3_bucket_size_bytes Size of an S3 bucket by storage class, as last reported by the daily storage metrics of CloudWatch
+# TYPE cloudcost_aws_s3_bucket_size_bytes gauge
+cloudcost_aws_s3_bucket_size_bytes{bucket="logs",class="GlacierStorage",region="us-east-1"} 2048
+cloudcost_aws_s3_bucket_size_bytes{bucket="logs",class="StandardStorage",region="us-east-1"} 1024
+`), "cloudcost_aws_s3_bucket_info", "cloudcost_aws_s3_bucket_size_bytes"))
+}
+
 func Test_unitCostForComponent(t *testing.T) {
 	tests := map[string]struct {
 		component string
@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"context"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsEks "github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	// costExplorerRequestPrice is the price of every request to the Cost Explorer API, see https://aws.amazon.com/aws-cost-management/pricing/
+	costExplorerRequestPrice = 0.01
+	// cloudWatchMetricPrice is the price of every metric requested through GetMetricData, see https://aws.amazon.com/cloudwatch/pricing/
+	cloudWatchMetricPrice = 0.01 / 1000
+)
+
+// services maps the service IDs of the SDK to the names used by -aws.endpoint.
+var services = map[string]string{
+	costexplorer.ServiceID: "costexplorer",
+	cloudwatch.ServiceID:   "cloudwatch",
+	pricing.ServiceID:      "pricing",
+	ec2.ServiceID:          "ec2",
+	awsEks.ServiceID:       "eks",
+}
+
+// addSelfCostMiddleware records the estimated cost of every request of a client in provider.SelfCostTotal. Retries
+// aren't counted as the middleware runs once per operation.
+func addSelfCostMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SelfCost", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		serviceID, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+		service, ok := services[serviceID]
+		if !ok {
+			service = strings.ToLower(strings.ReplaceAll(serviceID, " ", ""))
+		}
+		provider.SelfCostTotal.WithLabelValues(subsystem, service, operation).Add(requestCost(serviceID, in.Parameters))
+		return next.HandleInitialize(ctx, in)
+	}), middleware.After)
+}
+
+// requestCost returns the estimated price in USD of a request given its input. The Pricing, EC2 and EKS APIs used by
+// the exporter are free.
+func requestCost(serviceID string, input interface{}) float64 {
+	switch serviceID {
+	case costexplorer.ServiceID:
+		return costExplorerRequestPrice
+	case cloudwatch.ServiceID:
+		if in, ok := input.(*cloudwatch.GetMetricDataInput); ok {
+			return float64(len(in.MetricDataQueries)) * cloudWatchMetricPrice
+		}
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	costexplorerTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+func Test_requestCost(t *testing.T) {
+	tests := map[string]struct {
+		serviceID string
+		input     interface{}
+		expected  float64
+	}{
+		"cost explorer is charged per request": {
+			serviceID: costexplorer.ServiceID,
+			input:     &costexplorer.GetCostAndUsageInput{},
+			expected:  0.01,
+		},
+		"cloudwatch is charged per metric": {
+			serviceID: cloudwatch.ServiceID,
+			input:     &cloudwatch.GetMetricDataInput{MetricDataQueries: make([]cloudwatchTypes.MetricDataQuery, 500)},
+			expected:  0.005,
+		},
+		"other cloudwatch operations": {
+			serviceID: cloudwatch.ServiceID,
+			input:     &cloudwatch.ListMetricsInput{},
+			expected:  0,
+		},
+		"pricing is free": {
+			serviceID: pricing.ServiceID,
+			input:     &pricing.GetProductsInput{},
+			expected:  0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, requestCost(tt.serviceID, tt.input), 1e-9)
+		})
+	}
+}
+
+func Test_addSelfCostMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := costexplorer.New(costexplorer.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		APIOptions:   []func(*middleware.Stack) error{addSelfCostMiddleware},
+	})
+	counter := provider.SelfCostTotal.WithLabelValues(subsystem, "costexplorer", "GetCostAndUsage")
+	before := testutil.ToFloat64(counter)
+	for i := 0; i < 3; i++ {
+		_, err := client.GetCostAndUsage(context.Background(), &costexplorer.GetCostAndUsageInput{
+			TimePeriod:  &costexplorerTypes.DateInterval{Start: aws.String("2024-01-01"), End: aws.String("2024-01-02")},
+			Granularity: costexplorerTypes.GranularityDaily,
+			Metrics:     []string{"UnblendedCost"},
+		})
+		require.NoError(t, err)
+	}
+	assert.InDelta(t, 0.03, testutil.ToFloat64(counter)-before, 1e-9)
+}
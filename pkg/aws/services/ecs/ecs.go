@@ -0,0 +1,156 @@
+// Package ecs is a client of the subset of the ECS API used by the exporter. The ECS module of the AWS SDK v2 isn't a
+// dependency, so the requests are made with the JSON protocol of the API and signed with the signer of the SDK.
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	signingName  = "ecs"
+	targetPrefix = "AmazonEC2ContainerServiceV20141113."
+)
+
+type ECS interface {
+	ListClusters(ctx context.Context, params *ListClustersInput) (*ListClustersOutput, error)
+	ListTasks(ctx context.Context, params *ListTasksInput) (*ListTasksOutput, error)
+	DescribeTasks(ctx context.Context, params *DescribeTasksInput) (*DescribeTasksOutput, error)
+}
+
+type ListClustersInput struct {
+	NextToken *string `json:"nextToken,omitempty"`
+}
+
+type ListClustersOutput struct {
+	ClusterArns []string `json:"clusterArns"`
+	NextToken   *string  `json:"nextToken"`
+}
+
+type ListTasksInput struct {
+	Cluster       *string `json:"cluster,omitempty"`
+	LaunchType    string  `json:"launchType,omitempty"`
+	DesiredStatus string  `json:"desiredStatus,omitempty"`
+	NextToken     *string `json:"nextToken,omitempty"`
+}
+
+type ListTasksOutput struct {
+	TaskArns  []string `json:"taskArns"`
+	NextToken *string  `json:"nextToken"`
+}
+
+type DescribeTasksInput struct {
+	Cluster *string  `json:"cluster,omitempty"`
+	Tasks   []string `json:"tasks"`
+}
+
+type DescribeTasksOutput struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// Task is a task of DescribeTasks. Cpu is in CPU units, 1024 being a vCPU, and Memory in MiB.
+type Task struct {
+	TaskArn              *string     `json:"taskArn"`
+	ClusterArn           *string     `json:"clusterArn"`
+	Group                *string     `json:"group"`
+	LaunchType           string      `json:"launchType"`
+	CapacityProviderName *string     `json:"capacityProviderName"`
+	LastStatus           *string     `json:"lastStatus"`
+	Cpu                  *string     `json:"cpu"`
+	Memory               *string     `json:"memory"`
+	Attributes           []Attribute `json:"attributes"`
+}
+
+type Attribute struct {
+	Name  *string `json:"name"`
+	Value *string `json:"value"`
+}
+
+// Client calls the ECS API of a region.
+type Client struct {
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	httpClient  aws.HTTPClient
+	signer      *v4.Signer
+}
+
+// NewFromConfig returns a Client of the region of cfg. baseEndpoint overrides the regional endpoint when it's set.
+func NewFromConfig(cfg aws.Config, baseEndpoint *string) *Client {
+	endpoint := fmt.Sprintf("https://ecs.%s.amazonaws.com", cfg.Region)
+	if baseEndpoint != nil {
+		endpoint = *baseEndpoint
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		endpoint:    endpoint,
+		region:      cfg.Region,
+		credentials: cfg.Credentials,
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+	}
+}
+
+func (c *Client) ListClusters(ctx context.Context, params *ListClustersInput) (*ListClustersOutput, error) {
+	var output ListClustersOutput
+	return &output, c.call(ctx, "ListClusters", params, &output)
+}
+
+func (c *Client) ListTasks(ctx context.Context, params *ListTasksInput) (*ListTasksOutput, error) {
+	var output ListTasksOutput
+	return &output, c.call(ctx, "ListTasks", params, &output)
+}
+
+func (c *Client) DescribeTasks(ctx context.Context, params *DescribeTasksInput) (*DescribeTasksOutput, error) {
+	var output DescribeTasksOutput
+	return &output, c.call(ctx, "DescribeTasks", params, &output)
+}
+
+func (c *Client) call(ctx context.Context, operation string, input any, output any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", targetPrefix+operation)
+	if c.credentials != nil {
+		credentials, err := c.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("error retrieving credentials: %w", err)
+		}
+		hash := sha256.Sum256(body)
+		if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, time.Now()); err != nil {
+			return fmt.Errorf("error signing %s request: %w", operation, err)
+		}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ecs %s: %s: %s", operation, resp.Status, content)
+	}
+	return json.Unmarshal(content, output)
+}
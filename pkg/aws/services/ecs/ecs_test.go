@@ -0,0 +1,48 @@
+package ecs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListTasks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "AmazonEC2ContainerServiceV20141113.ListTasks", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/ecs/aws4_request")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"cluster": "prod", "launchType": "FARGATE", "desiredStatus": "RUNNING"}`, string(body))
+		_, _ = w.Write([]byte(`{"taskArns": ["arn:aws:ecs:eu-west-1:123:task/prod/abc"], "nextToken": "next"}`))
+	}))
+	defer server.Close()
+
+	client := NewFromConfig(aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIA", "secret", ""),
+		HTTPClient:  server.Client(),
+	}, aws.String(server.URL))
+	output, err := client.ListTasks(context.Background(), &ListTasksInput{Cluster: aws.String("prod"), LaunchType: "FARGATE", DesiredStatus: "RUNNING"})
+	require.NoError(t, err)
+	assert.Equal(t, &ListTasksOutput{TaskArns: []string{"arn:aws:ecs:eu-west-1:123:task/prod/abc"}, NextToken: aws.String("next")}, output)
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type": "AccessDeniedException"}`))
+	}))
+	defer server.Close()
+
+	client := NewFromConfig(aws.Config{Region: "eu-west-1", HTTPClient: server.Client()}, aws.String(server.URL))
+	_, err := client.ListClusters(context.Background(), &ListClustersInput{})
+	assert.ErrorContains(t, err, "AccessDeniedException")
+}
@@ -0,0 +1,205 @@
+// Package elb is a client of the subset of the Elastic Load Balancing APIs used by the exporter, the API of the
+// application, network and gateway load balancers and the API of the classic load balancers. The ELB modules of the
+// AWS SDK v2 aren't dependencies, so the requests are made with the query protocol of the APIs and signed with the
+// signer of the SDK.
+package elb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	signingName = "elasticloadbalancing"
+	// apiVersion is the version of the API of the application, network and gateway load balancers, and
+	// classicAPIVersion the one of the classic load balancers. Both are served by the same endpoint.
+	apiVersion        = "2015-12-01"
+	classicAPIVersion = "2012-06-01"
+)
+
+type ELB interface {
+	DescribeLoadBalancers(ctx context.Context, params *DescribeLoadBalancersInput) (*DescribeLoadBalancersOutput, error)
+	DescribeTags(ctx context.Context, params *DescribeTagsInput) (*DescribeTagsOutput, error)
+	DescribeClassicLoadBalancers(ctx context.Context, params *DescribeClassicLoadBalancersInput) (*DescribeClassicLoadBalancersOutput, error)
+	DescribeClassicTags(ctx context.Context, params *DescribeClassicTagsInput) (*DescribeTagsOutput, error)
+}
+
+type DescribeLoadBalancersInput struct {
+	Marker   *string
+	PageSize *int32
+}
+
+type DescribeLoadBalancersOutput struct {
+	LoadBalancers []LoadBalancer `xml:"DescribeLoadBalancersResult>LoadBalancers>member"`
+	NextMarker    *string        `xml:"DescribeLoadBalancersResult>NextMarker"`
+}
+
+// LoadBalancer is a load balancer of DescribeLoadBalancers. Type is application, network or gateway.
+type LoadBalancer struct {
+	LoadBalancerArn  *string `xml:"LoadBalancerArn"`
+	LoadBalancerName *string `xml:"LoadBalancerName"`
+	Type             *string `xml:"Type"`
+	Scheme           *string `xml:"Scheme"`
+	State            *string `xml:"State>Code"`
+}
+
+// DescribeTagsInput lists the tags of up to 20 load balancers by ARN.
+type DescribeTagsInput struct {
+	ResourceArns []string
+}
+
+type DescribeTagsOutput struct {
+	TagDescriptions []TagDescription `xml:"DescribeTagsResult>TagDescriptions>member"`
+}
+
+// TagDescription is the tags of a load balancer, identified by its ResourceArn or, for a classic load balancer, by its
+// LoadBalancerName.
+type TagDescription struct {
+	ResourceArn      *string `xml:"ResourceArn"`
+	LoadBalancerName *string `xml:"LoadBalancerName"`
+	Tags             []Tag   `xml:"Tags>member"`
+}
+
+type Tag struct {
+	Key   *string `xml:"Key"`
+	Value *string `xml:"Value"`
+}
+
+type DescribeClassicLoadBalancersInput struct {
+	Marker   *string
+	PageSize *int32
+}
+
+type DescribeClassicLoadBalancersOutput struct {
+	LoadBalancerDescriptions []ClassicLoadBalancer `xml:"DescribeLoadBalancersResult>LoadBalancerDescriptions>member"`
+	NextMarker               *string               `xml:"DescribeLoadBalancersResult>NextMarker"`
+}
+
+// ClassicLoadBalancer is a load balancer of the DescribeLoadBalancers API of the classic load balancers.
+type ClassicLoadBalancer struct {
+	LoadBalancerName *string `xml:"LoadBalancerName"`
+	Scheme           *string `xml:"Scheme"`
+}
+
+// DescribeClassicTagsInput lists the tags of up to 20 classic load balancers by name.
+type DescribeClassicTagsInput struct {
+	LoadBalancerNames []string
+}
+
+// Client calls the Elastic Load Balancing APIs of a region.
+type Client struct {
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	httpClient  aws.HTTPClient
+	signer      *v4.Signer
+}
+
+// NewFromConfig returns a Client of the region of cfg. baseEndpoint overrides the regional endpoint when it's set.
+func NewFromConfig(cfg aws.Config, baseEndpoint *string) *Client {
+	endpoint := fmt.Sprintf("https://elasticloadbalancing.%s.amazonaws.com", cfg.Region)
+	if baseEndpoint != nil {
+		endpoint = *baseEndpoint
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		endpoint:    endpoint,
+		region:      cfg.Region,
+		credentials: cfg.Credentials,
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+	}
+}
+
+func (c *Client) DescribeLoadBalancers(ctx context.Context, params *DescribeLoadBalancersInput) (*DescribeLoadBalancersOutput, error) {
+	values := pageValues(params.Marker, params.PageSize)
+	var output DescribeLoadBalancersOutput
+	return &output, c.call(ctx, apiVersion, "DescribeLoadBalancers", values, &output)
+}
+
+func (c *Client) DescribeTags(ctx context.Context, params *DescribeTagsInput) (*DescribeTagsOutput, error) {
+	values := memberValues("ResourceArns", params.ResourceArns)
+	var output DescribeTagsOutput
+	return &output, c.call(ctx, apiVersion, "DescribeTags", values, &output)
+}
+
+func (c *Client) DescribeClassicLoadBalancers(ctx context.Context, params *DescribeClassicLoadBalancersInput) (*DescribeClassicLoadBalancersOutput, error) {
+	values := pageValues(params.Marker, params.PageSize)
+	var output DescribeClassicLoadBalancersOutput
+	return &output, c.call(ctx, classicAPIVersion, "DescribeLoadBalancers", values, &output)
+}
+
+func (c *Client) DescribeClassicTags(ctx context.Context, params *DescribeClassicTagsInput) (*DescribeTagsOutput, error) {
+	values := memberValues("LoadBalancerNames", params.LoadBalancerNames)
+	var output DescribeTagsOutput
+	return &output, c.call(ctx, classicAPIVersion, "DescribeTags", values, &output)
+}
+
+func pageValues(marker *string, pageSize *int32) url.Values {
+	values := url.Values{}
+	if marker != nil {
+		values.Set("Marker", *marker)
+	}
+	if pageSize != nil {
+		values.Set("PageSize", strconv.Itoa(int(*pageSize)))
+	}
+	return values
+}
+
+// memberValues encodes a list parameter of the query protocol, eg ResourceArns.member.1.
+func memberValues(name string, members []string) url.Values {
+	values := url.Values{}
+	for i, member := range members {
+		values.Set(fmt.Sprintf("%s.member.%d", name, i+1), member)
+	}
+	return values
+}
+
+func (c *Client) call(ctx context.Context, version string, operation string, values url.Values, output any) error {
+	values.Set("Action", operation)
+	values.Set("Version", version)
+	body := values.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if c.credentials != nil {
+		credentials, err := c.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("error retrieving credentials: %w", err)
+		}
+		hash := sha256.Sum256([]byte(body))
+		if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, time.Now()); err != nil {
+			return fmt.Errorf("error signing %s request: %w", operation, err)
+		}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("elb %s: %s: %s", operation, resp.Status, content)
+	}
+	return xml.Unmarshal(content, output)
+}
@@ -0,0 +1,116 @@
+package elb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const describeLoadBalancersResponse = `<DescribeLoadBalancersResponse xmlns="http://elasticloadbalancing.amazonaws.com/doc/2015-12-01/">
+  <DescribeLoadBalancersResult>
+    <LoadBalancers>
+      <member>
+        <LoadBalancerArn>arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/ingress/50dc6c495c0c9188</LoadBalancerArn>
+        <LoadBalancerName>ingress</LoadBalancerName>
+        <Type>application</Type>
+        <Scheme>internet-facing</Scheme>
+        <State>
+          <Code>active</Code>
+        </State>
+      </member>
+    </LoadBalancers>
+    <NextMarker>next</NextMarker>
+  </DescribeLoadBalancersResult>
+</DescribeLoadBalancersResponse>`
+
+const describeClassicTagsResponse = `<DescribeTagsResponse xmlns="http://elasticloadbalancing.amazonaws.com/doc/2012-06-01/">
+  <DescribeTagsResult>
+    <TagDescriptions>
+      <member>
+        <LoadBalancerName>legacy</LoadBalancerName>
+        <Tags>
+          <member>
+            <Key>team</Key>
+            <Value>payments</Value>
+          </member>
+        </Tags>
+      </member>
+    </TagDescriptions>
+  </DescribeTagsResult>
+</DescribeTagsResponse>`
+
+// newServer returns a server checking that the requests are signed for the region and have the parameters of want,
+// and answering with response.
+func newServer(t *testing.T, want url.Values, response string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-www-form-urlencoded; charset=utf-8", r.Header.Get("Content-Type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/elasticloadbalancing/aws4_request")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		values, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		assert.Equal(t, want, values)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(server *httptest.Server) *Client {
+	return NewFromConfig(aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIA", "secret", ""),
+		HTTPClient:  server.Client(),
+	}, aws.String(server.URL))
+}
+
+func TestClient_DescribeLoadBalancers(t *testing.T) {
+	server := newServer(t, url.Values{"Action": {"DescribeLoadBalancers"}, "Version": {"2015-12-01"}, "Marker": {"previous"}, "PageSize": {"400"}}, describeLoadBalancersResponse)
+
+	output, err := newClient(server).DescribeLoadBalancers(context.Background(), &DescribeLoadBalancersInput{Marker: aws.String("previous"), PageSize: aws.Int32(400)})
+	require.NoError(t, err)
+	assert.Equal(t, &DescribeLoadBalancersOutput{
+		LoadBalancers: []LoadBalancer{{
+			LoadBalancerArn:  aws.String("arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/ingress/50dc6c495c0c9188"),
+			LoadBalancerName: aws.String("ingress"),
+			Type:             aws.String("application"),
+			Scheme:           aws.String("internet-facing"),
+			State:            aws.String("active"),
+		}},
+		NextMarker: aws.String("next"),
+	}, output)
+}
+
+func TestClient_DescribeClassicTags(t *testing.T) {
+	server := newServer(t, url.Values{"Action": {"DescribeTags"}, "Version": {"2012-06-01"}, "LoadBalancerNames.member.1": {"legacy"}, "LoadBalancerNames.member.2": {"deleted"}}, describeClassicTagsResponse)
+
+	output, err := newClient(server).DescribeClassicTags(context.Background(), &DescribeClassicTagsInput{LoadBalancerNames: []string{"legacy", "deleted"}})
+	require.NoError(t, err)
+	assert.Equal(t, &DescribeTagsOutput{
+		TagDescriptions: []TagDescription{{
+			LoadBalancerName: aws.String("legacy"),
+			Tags:             []Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+		}},
+	}, output)
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code></Error></ErrorResponse>`))
+	}))
+	defer server.Close()
+
+	client := NewFromConfig(aws.Config{Region: "eu-west-1", HTTPClient: server.Client()}, aws.String(server.URL))
+	_, err := client.DescribeClassicLoadBalancers(context.Background(), &DescribeClassicLoadBalancersInput{})
+	assert.ErrorContains(t, err, "AccessDenied")
+}
@@ -0,0 +1,240 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+// DefaultOffersURL is the public endpoint of the bulk offer files of the AWS Price List.
+const DefaultOffersURL = "https://pricing.us-east-1.amazonaws.com"
+
+const regionCodeField = "regionCode"
+
+var (
+	ErrMissingServiceCode  = errors.New("the service code of the products is required")
+	ErrUnsupportedFilter   = errors.New("unsupported filter type")
+	ErrUnexpectedStatus    = errors.New("unexpected status fetching an offer file")
+	ErrMalformedOfferFiles = errors.New("malformed offer file")
+)
+
+// OfferFiles implements Pricing with the bulk offer files of the AWS Price List instead of the GetProducts API. The
+// regional offer files of a service hold the same products as GetProducts, so a region is fetched with a single
+// request rather than paged, and isn't throttled.
+//
+// Files are fetched with a conditional GET on every call and only parsed again when their ETag changed. Only the
+// on-demand terms are kept, which are the only ones read by the collectors. Filters other than TERM_MATCH aren't
+// supported, and every product is returned in a single page.
+type OfferFiles struct {
+	client  *http.Client
+	baseURL string
+
+	lock  sync.Mutex
+	files map[string]*offerFile
+}
+
+// offerFile is a cached offer file. lock is held while the file is fetched, so that concurrent calls for the same
+// file only fetch it once.
+type offerFile struct {
+	lock     sync.Mutex
+	etag     string
+	products []offerProduct
+	regions  []string
+}
+
+type offerProduct struct {
+	product  json.RawMessage
+	onDemand json.RawMessage
+
+	productFamily string
+	attributes    map[string]string
+}
+
+// NewOfferFiles creates an OfferFiles fetching the files from baseURL with client. http.DefaultClient and
+// DefaultOffersURL are used when they're empty.
+func NewOfferFiles(client *http.Client, baseURL string) *OfferFiles {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if baseURL == "" {
+		baseURL = DefaultOffersURL
+	}
+	return &OfferFiles{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		files:   make(map[string]*offerFile),
+	}
+}
+
+// GetProducts returns the products of the service of params matching its filters. The offer file of the region of
+// the regionCode filter is fetched, or the offer files of every region of the service without it.
+func (o *OfferFiles) GetProducts(ctx context.Context, params *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+	serviceCode := aws.ToString(params.ServiceCode)
+	if serviceCode == "" {
+		return nil, ErrMissingServiceCode
+	}
+	var regions []string
+	for _, filter := range params.Filters {
+		if filter.Type != types.FilterTypeTermMatch {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFilter, filter.Type)
+		}
+		if aws.ToString(filter.Field) == regionCodeField {
+			regions = []string{aws.ToString(filter.Value)}
+		}
+	}
+	if regions == nil {
+		index, err := o.fetch(ctx, fmt.Sprintf("/offers/v1.0/aws/%s/current/region_index.json", serviceCode), parseRegionIndex)
+		if err != nil {
+			return nil, err
+		}
+		regions = index.regions
+	}
+
+	output := &pricing.GetProductsOutput{FormatVersion: aws.String("aws_v1")}
+	for _, region := range regions {
+		file, err := o.fetch(ctx, fmt.Sprintf("/offers/v1.0/aws/%s/current/%s/index.json", serviceCode, region), parseOffer)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range file.products {
+			if !p.matches(params.Filters) {
+				continue
+			}
+			entry, err := p.priceListEntry(serviceCode)
+			if err != nil {
+				return nil, err
+			}
+			output.PriceList = append(output.PriceList, entry)
+		}
+	}
+	return output, nil
+}
+
+// fetch returns the file at path, which is only parsed again when it changed since the last call.
+func (o *OfferFiles) fetch(ctx context.Context, path string, parse func(*json.Decoder, *offerFile) error) (*offerFile, error) {
+	o.lock.Lock()
+	cached, ok := o.files[path]
+	if !ok {
+		cached = &offerFile{}
+		o.files[path] = cached
+	}
+	o.lock.Unlock()
+
+	cached.lock.Lock()
+	defer cached.lock.Unlock()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return cached, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("%w %s: %s", ErrUnexpectedStatus, path, resp.Status)
+	}
+
+	file := &offerFile{}
+	if err := parse(json.NewDecoder(resp.Body), file); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrMalformedOfferFiles, path, err)
+	}
+	cached.etag = resp.Header.Get("ETag")
+	cached.products = file.products
+	cached.regions = file.regions
+	return cached, nil
+}
+
+// parseRegionIndex reads the regions of a region index file.
+func parseRegionIndex(decoder *json.Decoder, file *offerFile) error {
+	var index struct {
+		Regions map[string]json.RawMessage `json:"regions"`
+	}
+	if err := decoder.Decode(&index); err != nil {
+		return err
+	}
+	for region := range index.Regions {
+		file.regions = append(file.regions, region)
+	}
+	sort.Strings(file.regions)
+	return nil
+}
+
+// parseOffer reads the products of an offer file along with their on-demand terms.
+func parseOffer(decoder *json.Decoder, file *offerFile) error {
+	var offer struct {
+		Products map[string]json.RawMessage `json:"products"`
+		Terms    struct {
+			OnDemand map[string]json.RawMessage `json:"OnDemand"`
+		} `json:"terms"`
+	}
+	if err := decoder.Decode(&offer); err != nil {
+		return err
+	}
+	file.products = make([]offerProduct, 0, len(offer.Products))
+	for sku, raw := range offer.Products {
+		var product struct {
+			ProductFamily string            `json:"productFamily"`
+			Attributes    map[string]string `json:"attributes"`
+		}
+		if err := json.Unmarshal(raw, &product); err != nil {
+			return fmt.Errorf("product %s: %w", sku, err)
+		}
+		file.products = append(file.products, offerProduct{
+			product:       raw,
+			onDemand:      offer.Terms.OnDemand[sku],
+			productFamily: product.ProductFamily,
+			attributes:    product.Attributes,
+		})
+	}
+	return nil
+}
+
+// matches reports whether every filter matches the product. Values are matched regardless of casing, like
+// GetProducts.
+func (p *offerProduct) matches(filters []types.Filter) bool {
+	for _, filter := range filters {
+		field := aws.ToString(filter.Field)
+		value := p.attributes[field]
+		if field == "productFamily" {
+			value = p.productFamily
+		}
+		if !strings.EqualFold(value, aws.ToString(filter.Value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// priceListEntry formats the product like an entry of the price list of GetProducts.
+func (p *offerProduct) priceListEntry(serviceCode string) (string, error) {
+	entry := struct {
+		Product     json.RawMessage `json:"product"`
+		ServiceCode string          `json:"serviceCode"`
+		Terms       struct {
+			OnDemand json.RawMessage `json:"OnDemand,omitempty"`
+		} `json:"terms"`
+	}{Product: p.product, ServiceCode: serviceCode}
+	entry.Terms.OnDemand = p.onDemand
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,123 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	regionIndex = `{"formatVersion":"v1.0","regions":{"us-east-1":{"regionCode":"us-east-1"},"eu-west-1":{"regionCode":"eu-west-1"}}}`
+	usEastOffer = `{
+  "offerCode": "AmazonEC2",
+  "products": {
+    "SKU1": {"sku": "SKU1", "productFamily": "Compute Instance", "attributes": {"regionCode": "us-east-1", "instanceType": "m5.large", "tenancy": "Shared"}},
+    "SKU2": {"sku": "SKU2", "productFamily": "Storage", "attributes": {"regionCode": "us-east-1", "volumeApiName": "gp3"}}
+  },
+  "terms": {
+    "OnDemand": {
+      "SKU1": {"SKU1.JRTCKXETXF": {"priceDimensions": {"SKU1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0960000000"}}}}},
+      "SKU2": {"SKU2.JRTCKXETXF": {"priceDimensions": {"SKU2.JRTCKXETXF.6YS6EN2CT7": {"unit": "GB-Mo", "pricePerUnit": {"USD": "0.0800000000"}}}}}
+    },
+    "Reserved": {
+      "SKU1": {"SKU1.4NA7Y494T4": {"priceDimensions": {}}}
+    }
+  }
+}`
+	euWestOffer = `{"products": {"SKU3": {"sku": "SKU3", "productFamily": "Storage", "attributes": {"regionCode": "eu-west-1", "volumeApiName": "gp3"}}}, "terms": {"OnDemand": {}}}`
+)
+
+func newOfferServer(t *testing.T, requests map[string]int) *httptest.Server {
+	t.Helper()
+	files := map[string]string{
+		"/offers/v1.0/aws/AmazonEC2/current/region_index.json":    regionIndex,
+		"/offers/v1.0/aws/AmazonEC2/current/us-east-1/index.json": usEastOffer,
+		"/offers/v1.0/aws/AmazonEC2/current/eu-west-1/index.json": euWestOffer,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		body, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOfferFiles_GetProducts(t *testing.T) {
+	requests := make(map[string]int)
+	server := newOfferServer(t, requests)
+	o := NewOfferFiles(server.Client(), server.URL+"/")
+
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []types.Filter{
+			{Field: aws.String("regionCode"), Type: types.FilterTypeTermMatch, Value: aws.String("us-east-1")},
+			{Field: aws.String("productFamily"), Type: types.FilterTypeTermMatch, Value: aws.String("Compute Instance")},
+			{Field: aws.String("tenancy"), Type: types.FilterTypeTermMatch, Value: aws.String("shared")},
+		},
+	}
+	output, err := o.GetProducts(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, output.PriceList, 1)
+	assert.JSONEq(t, `{
+  "product": {"sku": "SKU1", "productFamily": "Compute Instance", "attributes": {"regionCode": "us-east-1", "instanceType": "m5.large", "tenancy": "Shared"}},
+  "serviceCode": "AmazonEC2",
+  "terms": {"OnDemand": {"SKU1.JRTCKXETXF": {"priceDimensions": {"SKU1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0960000000"}}}}}}
+}`, output.PriceList[0])
+	assert.Nil(t, output.NextToken)
+
+	// The file is fetched again with its ETag and isn't parsed again when it didn't change
+	output, err = o.GetProducts(context.Background(), input)
+	require.NoError(t, err)
+	assert.Len(t, output.PriceList, 1)
+	assert.Equal(t, 2, requests["/offers/v1.0/aws/AmazonEC2/current/us-east-1/index.json"])
+}
+
+func TestOfferFiles_GetProducts_EveryRegion(t *testing.T) {
+	requests := make(map[string]int)
+	server := newOfferServer(t, requests)
+	o := NewOfferFiles(server.Client(), server.URL)
+
+	output, err := o.GetProducts(context.Background(), &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters:     []types.Filter{{Field: aws.String("productFamily"), Type: types.FilterTypeTermMatch, Value: aws.String("Storage")}},
+	})
+	require.NoError(t, err)
+	require.Len(t, output.PriceList, 2)
+	assert.Contains(t, output.PriceList[0], `"SKU3"`)
+	assert.Contains(t, output.PriceList[1], `"SKU2"`)
+	assert.Equal(t, 1, requests["/offers/v1.0/aws/AmazonEC2/current/region_index.json"])
+}
+
+func TestOfferFiles_GetProducts_Errors(t *testing.T) {
+	server := newOfferServer(t, make(map[string]int))
+	o := NewOfferFiles(server.Client(), server.URL)
+
+	_, err := o.GetProducts(context.Background(), &pricing.GetProductsInput{})
+	assert.ErrorIs(t, err, ErrMissingServiceCode)
+
+	_, err = o.GetProducts(context.Background(), &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters:     []types.Filter{{Field: aws.String("regionCode"), Type: "CONTAINS", Value: aws.String("us")}},
+	})
+	assert.ErrorIs(t, err, ErrUnsupportedFilter)
+
+	_, err = o.GetProducts(context.Background(), &pricing.GetProductsInput{ServiceCode: aws.String("AmazonRDS")})
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+}
@@ -0,0 +1,126 @@
+// Package rds is a client of the subset of the RDS API used by the exporter. The RDS module of the AWS SDK v2 isn't a
+// dependency, so the requests are made with the query protocol of the API and signed with the signer of the SDK.
+package rds
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	signingName = "rds"
+	apiVersion  = "2014-10-31"
+)
+
+type RDS interface {
+	DescribeDBInstances(ctx context.Context, params *DescribeDBInstancesInput) (*DescribeDBInstancesOutput, error)
+}
+
+type DescribeDBInstancesInput struct {
+	Marker     *string
+	MaxRecords *int32
+}
+
+type DescribeDBInstancesOutput struct {
+	DBInstances []DBInstance `xml:"DescribeDBInstancesResult>DBInstances>DBInstance"`
+	Marker      *string      `xml:"DescribeDBInstancesResult>Marker"`
+}
+
+// DBInstance is an instance of DescribeDBInstances. AllocatedStorage is in GiB.
+type DBInstance struct {
+	DBInstanceIdentifier *string `xml:"DBInstanceIdentifier"`
+	DBInstanceClass      *string `xml:"DBInstanceClass"`
+	DBInstanceStatus     *string `xml:"DBInstanceStatus"`
+	Engine               *string `xml:"Engine"`
+	LicenseModel         *string `xml:"LicenseModel"`
+	MultiAZ              *bool   `xml:"MultiAZ"`
+	StorageType          *string `xml:"StorageType"`
+	AllocatedStorage     *int32  `xml:"AllocatedStorage"`
+	AvailabilityZone     *string `xml:"AvailabilityZone"`
+}
+
+// Client calls the RDS API of a region.
+type Client struct {
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	httpClient  aws.HTTPClient
+	signer      *v4.Signer
+}
+
+// NewFromConfig returns a Client of the region of cfg. baseEndpoint overrides the regional endpoint when it's set.
+func NewFromConfig(cfg aws.Config, baseEndpoint *string) *Client {
+	endpoint := fmt.Sprintf("https://rds.%s.amazonaws.com", cfg.Region)
+	if baseEndpoint != nil {
+		endpoint = *baseEndpoint
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		endpoint:    endpoint,
+		region:      cfg.Region,
+		credentials: cfg.Credentials,
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+	}
+}
+
+func (c *Client) DescribeDBInstances(ctx context.Context, params *DescribeDBInstancesInput) (*DescribeDBInstancesOutput, error) {
+	values := url.Values{}
+	if params.Marker != nil {
+		values.Set("Marker", *params.Marker)
+	}
+	if params.MaxRecords != nil {
+		values.Set("MaxRecords", strconv.Itoa(int(*params.MaxRecords)))
+	}
+	var output DescribeDBInstancesOutput
+	return &output, c.call(ctx, "DescribeDBInstances", values, &output)
+}
+
+func (c *Client) call(ctx context.Context, operation string, values url.Values, output any) error {
+	values.Set("Action", operation)
+	values.Set("Version", apiVersion)
+	body := values.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if c.credentials != nil {
+		credentials, err := c.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("error retrieving credentials: %w", err)
+		}
+		hash := sha256.Sum256([]byte(body))
+		if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, time.Now()); err != nil {
+			return fmt.Errorf("error signing %s request: %w", operation, err)
+		}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rds %s: %s: %s", operation, resp.Status, content)
+	}
+	return xml.Unmarshal(content, output)
+}
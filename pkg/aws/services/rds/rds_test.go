@@ -0,0 +1,82 @@
+package rds
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const describeDBInstancesResponse = `<DescribeDBInstancesResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">
+  <DescribeDBInstancesResult>
+    <DBInstances>
+      <DBInstance>
+        <DBInstanceIdentifier>orders</DBInstanceIdentifier>
+        <DBInstanceClass>db.m5.large</DBInstanceClass>
+        <DBInstanceStatus>available</DBInstanceStatus>
+        <Engine>postgres</Engine>
+        <LicenseModel>postgresql-license</LicenseModel>
+        <MultiAZ>true</MultiAZ>
+        <StorageType>gp3</StorageType>
+        <AllocatedStorage>100</AllocatedStorage>
+        <AvailabilityZone>eu-west-1a</AvailabilityZone>
+      </DBInstance>
+    </DBInstances>
+    <Marker>next</Marker>
+  </DescribeDBInstancesResult>
+</DescribeDBInstancesResponse>`
+
+func TestClient_DescribeDBInstances(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-www-form-urlencoded; charset=utf-8", r.Header.Get("Content-Type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/rds/aws4_request")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		values, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"Action": {"DescribeDBInstances"}, "Version": {"2014-10-31"}, "Marker": {"previous"}}, values)
+		_, _ = w.Write([]byte(describeDBInstancesResponse))
+	}))
+	defer server.Close()
+
+	client := NewFromConfig(aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIA", "secret", ""),
+		HTTPClient:  server.Client(),
+	}, aws.String(server.URL))
+	output, err := client.DescribeDBInstances(context.Background(), &DescribeDBInstancesInput{Marker: aws.String("previous")})
+	require.NoError(t, err)
+	assert.Equal(t, &DescribeDBInstancesOutput{
+		DBInstances: []DBInstance{{
+			DBInstanceIdentifier: aws.String("orders"),
+			DBInstanceClass:      aws.String("db.m5.large"),
+			DBInstanceStatus:     aws.String("available"),
+			Engine:               aws.String("postgres"),
+			LicenseModel:         aws.String("postgresql-license"),
+			MultiAZ:              aws.Bool(true),
+			StorageType:          aws.String("gp3"),
+			AllocatedStorage:     aws.Int32(100),
+			AvailabilityZone:     aws.String("eu-west-1a"),
+		}},
+		Marker: aws.String("next"),
+	}, output)
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code></Error></ErrorResponse>`))
+	}))
+	defer server.Close()
+
+	client := NewFromConfig(aws.Config{Region: "eu-west-1", HTTPClient: server.Client()}, aws.String(server.URL))
+	_, err := client.DescribeDBInstances(context.Background(), &DescribeDBInstancesInput{})
+	assert.ErrorContains(t, err, "AccessDenied")
+}
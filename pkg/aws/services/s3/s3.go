@@ -0,0 +1,161 @@
+// Package s3 is a client of the subset of the S3 API used by the exporter. The S3 module of the AWS SDK v2 isn't a
+// dependency, so the requests are made with the REST protocol of the API and signed with the signer of the SDK.
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	signingName = "s3"
+	// legacyEULocation is the location constraint of the buckets created in eu-west-1 before it was a region name.
+	legacyEULocation = "EU"
+)
+
+// emptyPayloadHash is the hash of the empty body of the GET requests, which S3 requires in X-Amz-Content-Sha256.
+var emptyPayloadHash = func() string {
+	hash := sha256.Sum256(nil)
+	return hex.EncodeToString(hash[:])
+}()
+
+type S3 interface {
+	ListBuckets(ctx context.Context, params *ListBucketsInput) (*ListBucketsOutput, error)
+	GetBucketLocation(ctx context.Context, params *GetBucketLocationInput) (*GetBucketLocationOutput, error)
+}
+
+type ListBucketsInput struct {
+	ContinuationToken *string
+	// MaxBuckets paginates the buckets, S3 only returns their region when it's set.
+	MaxBuckets *int32
+}
+
+type ListBucketsOutput struct {
+	Buckets           []Bucket `xml:"Buckets>Bucket"`
+	ContinuationToken *string  `xml:"ContinuationToken"`
+}
+
+// Bucket is a bucket of ListBuckets. BucketRegion is only set when the buckets are paginated.
+type Bucket struct {
+	Name         *string    `xml:"Name"`
+	CreationDate *time.Time `xml:"CreationDate"`
+	BucketRegion *string    `xml:"BucketRegion"`
+}
+
+type GetBucketLocationInput struct {
+	Bucket *string
+}
+
+type GetBucketLocationOutput struct {
+	// LocationConstraint is the region of the bucket. It's empty for us-east-1 and EU for the oldest eu-west-1
+	// buckets, see Region.
+	LocationConstraint string `xml:",chardata"`
+}
+
+// Region returns the region of the bucket.
+func (o *GetBucketLocationOutput) Region() string {
+	switch o.LocationConstraint {
+	case "":
+		return "us-east-1"
+	case legacyEULocation:
+		return "eu-west-1"
+	}
+	return o.LocationConstraint
+}
+
+// Client calls the S3 API of a region.
+type Client struct {
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	httpClient  aws.HTTPClient
+	signer      *v4.Signer
+}
+
+// NewFromConfig returns a Client of the region of cfg. baseEndpoint overrides the regional endpoint when it's set. The
+// buckets are addressed with the path style, as their names can contain dots.
+func NewFromConfig(cfg aws.Config, baseEndpoint *string) *Client {
+	endpoint := fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	if baseEndpoint != nil {
+		endpoint = *baseEndpoint
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		endpoint:    endpoint,
+		region:      cfg.Region,
+		credentials: cfg.Credentials,
+		httpClient:  httpClient,
+		// S3 signs the path as is rather than escaping it twice like the other services
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true
+		}),
+	}
+}
+
+func (c *Client) ListBuckets(ctx context.Context, params *ListBucketsInput) (*ListBucketsOutput, error) {
+	values := url.Values{}
+	if params.ContinuationToken != nil {
+		values.Set("continuation-token", *params.ContinuationToken)
+	}
+	if params.MaxBuckets != nil {
+		values.Set("max-buckets", strconv.Itoa(int(*params.MaxBuckets)))
+	}
+	var output ListBucketsOutput
+	return &output, c.call(ctx, "ListBuckets", "/", values, &output)
+}
+
+func (c *Client) GetBucketLocation(ctx context.Context, params *GetBucketLocationInput) (*GetBucketLocationOutput, error) {
+	if params.Bucket == nil || *params.Bucket == "" {
+		return nil, fmt.Errorf("s3 GetBucketLocation: missing bucket")
+	}
+	var output GetBucketLocationOutput
+	return &output, c.call(ctx, "GetBucketLocation", "/"+url.PathEscape(*params.Bucket), url.Values{"location": {""}}, &output)
+}
+
+func (c *Client) call(ctx context.Context, operation string, path string, values url.Values, output any) error {
+	u := c.endpoint + path
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if c.credentials != nil {
+		credentials, err := c.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("error retrieving credentials: %w", err)
+		}
+		if err := c.signer.SignHTTP(ctx, credentials, req, emptyPayloadHash, signingName, c.region, time.Now()); err != nil {
+			return fmt.Errorf("error signing %s request: %w", operation, err)
+		}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3 %s: %s: %s", operation, resp.Status, content)
+	}
+	return xml.Unmarshal(content, output)
+}
@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const listBucketsResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<ListAllMyBucketsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Owner><ID>owner</ID></Owner>
+  <Buckets>
+    <Bucket>
+      <Name>logs.example.com</Name>
+      <CreationDate>2023-04-01T10:00:00.000Z</CreationDate>
+      <BucketRegion>eu-west-1</BucketRegion>
+    </Bucket>
+  </Buckets>
+  <ContinuationToken>next</ContinuationToken>
+</ListAllMyBucketsResult>`
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewFromConfig(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIA", "secret", ""),
+		HTTPClient:  server.Client(),
+	}, aws.String(server.URL))
+}
+
+func TestClient_ListBuckets(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/", r.URL.Path)
+		assert.Equal(t, "previous", r.URL.Query().Get("continuation-token"))
+		assert.Equal(t, "1000", r.URL.Query().Get("max-buckets"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/s3/aws4_request")
+		assert.Equal(t, emptyPayloadHash, r.Header.Get("X-Amz-Content-Sha256"))
+		_, _ = w.Write([]byte(listBucketsResponse))
+	})
+	output, err := client.ListBuckets(context.Background(), &ListBucketsInput{ContinuationToken: aws.String("previous"), MaxBuckets: aws.Int32(1000)})
+	require.NoError(t, err)
+	assert.Equal(t, &ListBucketsOutput{
+		Buckets: []Bucket{{
+			Name:         aws.String("logs.example.com"),
+			CreationDate: aws.Time(time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC)),
+			BucketRegion: aws.String("eu-west-1"),
+		}},
+		ContinuationToken: aws.String("next"),
+	}, output)
+}
+
+func TestClient_GetBucketLocation(t *testing.T) {
+	for _, tc := range []struct {
+		response string
+		expected string
+	}{
+		{response: `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">ap-south-1</LocationConstraint>`, expected: "ap-south-1"},
+		{response: `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"/>`, expected: "us-east-1"},
+		{response: `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">EU</LocationConstraint>`, expected: "eu-west-1"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/logs.example.com", r.URL.Path)
+				_, ok := r.URL.Query()["location"]
+				assert.True(t, ok)
+				_, _ = w.Write([]byte(tc.response))
+			})
+			output, err := client.GetBucketLocation(context.Background(), &GetBucketLocationInput{Bucket: aws.String("logs.example.com")})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, output.Region())
+		})
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code></Error>`))
+	})
+	_, err := client.ListBuckets(context.Background(), &ListBucketsInput{})
+	assert.ErrorContains(t, err, "AccessDenied")
+}
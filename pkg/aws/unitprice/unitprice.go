@@ -0,0 +1,160 @@
+// Package unitprice lists the unit prices of the AWS services billed by usage, eg per request or per GB, from the
+// pricing API. They aren't costs on their own, but combined with the usage metrics of CloudWatch they estimate the
+// spend of a service in PromQL.
+package unitprice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+	pricingClient "github.com/grafana/cloudcost-exporter/pkg/aws/services/pricing"
+)
+
+// UnitPrice selects the price of a unit among the products of a service by the suffix of their usage type, as the
+// usage types are prefixed with a region code, eg USE1-Requests-Tier1, except in us-east-1.
+type UnitPrice struct {
+	ServiceCode     string
+	UsageTypeSuffix string
+	// Desc is the metric of the price, its first label is the region followed by Labels.
+	Desc   *prometheus.Desc
+	Labels []string
+	// Scale converts the price per unit of the pricing API into the unit of the metric
+	Scale float64
+}
+
+// Price is the unit price of a region, in the unit of the metric of its UnitPrice.
+type Price struct {
+	UnitPrice *UnitPrice
+	Region    string
+	Value     float64
+}
+
+// Metric returns the metric of the price.
+func (p Price) Metric() prometheus.Metric {
+	return prometheus.MustNewConstMetric(p.UnitPrice.Desc, prometheus.GaugeValue, p.Value, append([]string{p.Region}, p.UnitPrice.Labels...)...)
+}
+
+// product represents the nested json response returned by the AWS pricing API for the services billed by usage.
+type product struct {
+	Product struct {
+		Attributes struct {
+			Region    string `json:"regionCode"`
+			UsageType string `json:"usagetype"`
+		}
+	}
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				BeginRange   string            `json:"beginRange"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			}
+		}
+	}
+}
+
+// List lists the products of the services of unitPrices and returns the prices of the regions matching the filter,
+// sorted by region. Every region matches a nil filter.
+func List(ctx context.Context, client pricingClient.Pricing, unitPrices []UnitPrice, regions *compute.RegionFilter) ([]Price, error) {
+	prices := []Price{}
+	var serviceCodes []string
+	for _, up := range unitPrices {
+		if !slices.Contains(serviceCodes, up.ServiceCode) {
+			serviceCodes = append(serviceCodes, up.ServiceCode)
+		}
+	}
+	for _, serviceCode := range serviceCodes {
+		products, err := listProducts(ctx, client, serviceCode)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the products of %s: %w", serviceCode, err)
+		}
+		for _, p := range Parse(serviceCode, products, unitPrices) {
+			if regions.Matches(p.Region) {
+				prices = append(prices, p)
+			}
+		}
+	}
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].Region < prices[j].Region })
+	return prices, nil
+}
+
+func listProducts(ctx context.Context, client pricingClient.Pricing, serviceCode string) ([]string, error) {
+	var products []string
+	input := &pricing.GetProductsInput{ServiceCode: aws.String(serviceCode)}
+	for {
+		output, err := client.GetProducts(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if output == nil {
+			break
+		}
+		products = append(products, output.PriceList...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return products, nil
+}
+
+// Parse returns the unit prices found among the products of a service. A unit is priced at its first paid tier, as
+// the free tiers, eg the first million SQS requests of the month, are shared by the whole account.
+// Entries that can't be parsed are skipped.
+func Parse(serviceCode string, products []string, unitPrices []UnitPrice) []Price {
+	var prices []Price
+	for _, entry := range products {
+		var p product
+		if err := json.Unmarshal([]byte(entry), &p); err != nil {
+			log.Printf("error parsing %s price entry: %s, skipping", serviceCode, err)
+			continue
+		}
+		attributes := p.Product.Attributes
+		if attributes.Region == "" {
+			continue
+		}
+		for i := range unitPrices {
+			up := &unitPrices[i]
+			if up.ServiceCode != serviceCode || !strings.HasSuffix(attributes.UsageType, up.UsageTypeSuffix) {
+				continue
+			}
+			if value, ok := firstPaidTier(p); ok {
+				prices = append(prices, Price{UnitPrice: up, Region: attributes.Region, Value: value * up.Scale})
+			}
+		}
+	}
+	return prices
+}
+
+// firstPaidTier returns the price per unit of the non-free tier with the lowest begin range.
+func firstPaidTier(p product) (float64, bool) {
+	found := false
+	var lowest, value float64
+	for _, term := range p.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			usd, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err != nil || math.IsNaN(usd) || math.IsInf(usd, 0) || usd <= 0 {
+				continue
+			}
+			begin, err := strconv.ParseFloat(dimension.BeginRange, 64)
+			if err != nil {
+				begin = 0
+			}
+			if !found || begin < lowest {
+				found, lowest, value = true, begin, usd
+			}
+		}
+	}
+	return value, found
+}
@@ -0,0 +1,91 @@
+package unitprice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockpricing "github.com/grafana/cloudcost-exporter/mocks/pkg/aws/services/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/aws/compute"
+)
+
+var (
+	testPriceDesc  = prometheus.NewDesc("test_usd_per_million_requests", "", []string{"region", "tier"}, nil)
+	testUnitPrices = []UnitPrice{
+		{ServiceCode: "AmazonTest", UsageTypeSuffix: "Requests-Tier1", Desc: testPriceDesc, Labels: []string{"tier1"}, Scale: 1e6},
+	}
+)
+
+func Test_Parse(t *testing.T) {
+	tests := map[string]struct {
+		serviceCode string
+		products    []string
+		want        map[string]float64
+	}{
+		"the first paid tier is kept": {
+			serviceCode: "AmazonTest",
+			products: []string{
+				`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"beginRange":"0","pricePerUnit":{"USD":"0"}},"A.2":{"beginRange":"1000000","pricePerUnit":{"USD":"0.0000004"}},"A.3":{"beginRange":"100000000000","pricePerUnit":{"USD":"0.0000003"}}}}}}}`,
+				`{"product":{"attributes":{"regionCode":"eu-west-1","usagetype":"EU-Requests-Tier1"}},"terms":{"OnDemand":{"B":{"priceDimensions":{"B.1":{"pricePerUnit":{"USD":"0.0000005"}}}}}}}`,
+			},
+			want: map[string]float64{"us-east-1": 0.4, "eu-west-1": 0.5},
+		},
+		"products of another service or usage type are skipped": {
+			serviceCode: "AmazonOther",
+			products: []string{
+				`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"1"}}}}}}}`,
+			},
+			want: map[string]float64{},
+		},
+		"malformed entries are skipped": {
+			serviceCode: "AmazonTest",
+			products: []string{
+				"not json",
+				`{"product":{"attributes":{"usagetype":"Requests-Tier1"}}}`,
+				`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"B":{"pricePerUnit":{"USD":"NaN"}}}}}}}`,
+			},
+			want: map[string]float64{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make(map[string]float64)
+			for _, p := range Parse(tt.serviceCode, tt.products, testUnitPrices) {
+				got[p.Region] = p.Value
+			}
+			require.Len(t, got, len(tt.want))
+			for region, value := range tt.want {
+				assert.InDelta(t, value, got[region], 1e-9, region)
+			}
+		})
+	}
+}
+
+func Test_List(t *testing.T) {
+	client := mockpricing.NewPricing(t)
+	client.EXPECT().GetProducts(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+			assert.Equal(t, "AmazonTest", aws.ToString(input.ServiceCode))
+			if input.NextToken == nil {
+				return &pricing.GetProductsOutput{
+					PriceList: []string{`{"product":{"attributes":{"regionCode":"us-east-1","usagetype":"Requests-Tier1"}},"terms":{"OnDemand":{"A":{"priceDimensions":{"A.1":{"pricePerUnit":{"USD":"0.0000004"}}}}}}}`},
+					NextToken: aws.String("next"),
+				}, nil
+			}
+			return &pricing.GetProductsOutput{
+				PriceList: []string{`{"product":{"attributes":{"regionCode":"eu-west-1","usagetype":"EU-Requests-Tier1"}},"terms":{"OnDemand":{"B":{"priceDimensions":{"B.1":{"pricePerUnit":{"USD":"0.0000005"}}}}}}}`},
+			}, nil
+		}).Times(2)
+
+	prices, err := List(context.Background(), client, testUnitPrices, &compute.RegionFilter{Deny: []string{"eu-*"}})
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, "us-east-1", prices[0].Region)
+	assert.InDelta(t, 0.4, prices[0].Value, 1e-9)
+}
@@ -4,30 +4,71 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"github.com/prometheus/client_golang/prometheus"
 
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/reservations"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 
 	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
 )
 
 const (
 	subsystem = "azure_aks"
+
+	provisioningStatePrefix = "ProvisioningState/"
 )
 
 // Errors
 var (
 	ErrClientCreationFailure = errors.New("failed to create client")
 	ErrPageAdvanceFailure    = errors.New("failed to advance page")
+	ErrRegionNotFound        = errors.New("region not found in price store")
+	ErrSkuNotFound           = errors.New("sku not found in price store")
 )
 
 // Prometheus Metrics
 var (
-// TODO - define Prometheus Metrics
+	// TODO - define the cost metrics
+	InstanceCreatedTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure", "instance_created_timestamp_seconds"),
+		"The time the virtual machine was provisioned as a unix timestamp in seconds.",
+		[]string{"instance", "region", "machine_type"},
+		nil,
+	)
+	// InstanceSpotMaxPriceDesc and InstanceSpotRetailPriceDesc are emitted side by side for spot scale sets so that
+	// policies on the max price, eg a cap below the retail spot price that causes evictions, can be audited.
+	InstanceSpotMaxPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "spot_max_usd_per_hour"),
+		"The max price of the spot VMs of a scale set in USD/h. Spot VMs are evicted rather than billed above it. A scale set without a max price is capped at the on-demand price.",
+		[]string{"vmss", "cluster_name", "region", "machine_type", "max_price_source"},
+		nil,
+	)
+	InstanceSpotRetailPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "spot_retail_usd_per_hour"),
+		"The retail spot price of the VMs of a scale set in USD/h.",
+		[]string{"vmss", "cluster_name", "region", "machine_type"},
+		nil,
+	)
+	// StorageClassHourlyPriceDesc is a price sheet of the managed disk types backing the storage classes of AKS
+	// clusters, eg Premium_LRS, rather than the cost of any disk. Disks are billed per performance tier, so the price
+	// per GiB is exported per tier.
+	StorageClassHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure", "storage_class_usd_per_gib_hour"),
+		"The price of the capacity of a managed disk performance tier in USD/(GiB*h). storage_class is the storage account type of the disk, eg Premium_LRS.",
+		[]string{"storage_class", "region", "disk_tier"},
+		nil,
+	)
 )
 
 // Collector is a prometheus collector that collects metrics from AKS clusters.
@@ -36,50 +77,123 @@ type Collector struct {
 	logger  *slog.Logger
 
 	resourceGroupClient          *armresources.ResourceGroupsClient
+	resourceClient               *armresources.Client
 	virtualMachineClient         *armcompute.VirtualMachineScaleSetVMsClient
 	virtualMachineScaleSetClient *armcompute.VirtualMachineScaleSetsClient
+	diskClient                   *armcompute.DisksClient
+	hostGroupClient              *armcompute.DedicatedHostGroupsClient
+	hostClient                   *armcompute.DedicatedHostsClient
+
+	PriceStore       *PriceStore
+	VolumePriceStore *VolumePriceStore
 
-	PriceStore *PriceStore
+	// resourceGroups is only set when the enumerated resource groups are narrowed down
+	resourceGroups *ResourceGroupFilter
+	// storageClasses and volumes are only set when the persistent volumes are priced by their storage class
+	storageClasses *storageclass.Classes
+	volumes        kubernetes.PersistentVolumeLister
+	// benefits is only set when the VMs covered by reservations and savings plans are priced at their rate
+	benefits *reservations.BenefitsLister
+	// capacityDelta lists the VMs of every scale set to compare them with its capacity
+	capacityDelta bool
 }
 
 type Config struct {
 	Logger      *slog.Logger
-	Credentials *azidentity.DefaultAzureCredential
+	Credentials azcore.TokenCredential
+	// ClientOptions configures the cloud and transport of the clients, the SDK defaults are used when nil.
+	ClientOptions *arm.ClientOptions
 
 	SubscriptionId string
+	// ResourceGroups selects the resource groups whose resources are enumerated, every resource group is when nil.
+	ResourceGroups *ResourceGroupFilter
+	// StorageClasses adjusts the cost of the persistent volumes of the cluster the exporter runs in by their storage
+	// class, along with Volumes which lists them.
+	StorageClasses *storageclass.Classes
+	Volumes        kubernetes.PersistentVolumeLister
+	// PriceLookup is set the price store of the virtual machines, it's left alone when nil.
+	PriceLookup *pricing.Lookup
+	// Benefits lists the reservations and savings plans covering the VMs, which are priced at the retail on-demand
+	// price when nil.
+	Benefits *reservations.BenefitsLister
+	// VMSizes selects the VM sizes whose retail prices are kept, every size is priced when nil.
+	VMSizes *pricing.FamilyFilter
+	// CapacityDelta lists the VMs of every scale set on every collection to export the cost of the difference between
+	// the capacity of the scale set and its running VMs.
+	CapacityDelta bool
 }
 
 func New(ctx context.Context, cfg *Config) (*Collector, error) {
 	logger := cfg.Logger.With("collector", "aks")
+	if err := cfg.ResourceGroups.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.VMSizes.Validate(); err != nil {
+		return nil, err
+	}
 
-	retailPricesClient, err := retailPriceSdk.NewRetailPricesClient(nil)
+	retailPricesClient, err := retailPriceSdk.NewRetailPricesClient(cfg.ClientOptions)
 	if err != nil {
 		logger.LogAttrs(ctx, slog.LevelError, "failed to create retail prices client", slog.String("err", err.Error()))
 		return nil, ErrClientCreationFailure
 	}
 
-	rgClient, err := armresources.NewResourceGroupsClient(cfg.SubscriptionId, cfg.Credentials, nil)
+	rgClient, err := armresources.NewResourceGroupsClient(cfg.SubscriptionId, cfg.Credentials, cfg.ClientOptions)
 	if err != nil {
 		logger.LogAttrs(ctx, slog.LevelError, "failed to create resource group client", slog.String("err", err.Error()))
 		return nil, ErrClientCreationFailure
 	}
 
-	computeClientFactory, err := armcompute.NewClientFactory(cfg.SubscriptionId, cfg.Credentials, nil)
+	resourceClient, err := armresources.NewClient(cfg.SubscriptionId, cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create resource client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+
+	computeClientFactory, err := armcompute.NewClientFactory(cfg.SubscriptionId, cfg.Credentials, cfg.ClientOptions)
 	if err != nil {
 		logger.LogAttrs(ctx, slog.LevelError, "failed to create compute client factory", slog.String("err", err.Error()))
 		return nil, ErrClientCreationFailure
 	}
 
-	return &Collector{
+	// The VM and managed disk prices of a region are fetched in a single pass
+	retailPrices := NewRetailPrices(retailPricesClient, logger, ctx)
+	if cfg.Benefits != nil {
+		retailPrices.FetchReservationPrices()
+	}
+	c := &Collector{
 		context: ctx,
 		logger:  logger,
 
 		resourceGroupClient:          rgClient,
+		resourceClient:               resourceClient,
 		virtualMachineClient:         computeClientFactory.NewVirtualMachineScaleSetVMsClient(),
 		virtualMachineScaleSetClient: computeClientFactory.NewVirtualMachineScaleSetsClient(),
+		diskClient:                   computeClientFactory.NewDisksClient(),
+		hostGroupClient:              computeClientFactory.NewDedicatedHostGroupsClient(),
+		hostClient:                   computeClientFactory.NewDedicatedHostsClient(),
 
-		PriceStore: NewPricingStore(cfg.SubscriptionId, retailPricesClient, logger, ctx),
-	}, nil
+		PriceStore:       retailPrices.NewPricingStore(cfg.SubscriptionId),
+		VolumePriceStore: retailPrices.NewVolumePriceStore(),
+
+		resourceGroups: cfg.ResourceGroups,
+		storageClasses: cfg.StorageClasses,
+		volumes:        cfg.Volumes,
+		benefits:       cfg.Benefits,
+		capacityDelta:  cfg.CapacityDelta,
+	}
+	c.PriceStore.vmSizes = cfg.VMSizes
+	cfg.PriceLookup.Set(pricing.ProviderAzure, c.PriceStore)
+	go c.warmPriceStores()
+	return c, nil
+}
+
+// NewForDocs returns a Collector without any clients or price stores, which is only able to describe its metrics.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *Collector {
+	return &Collector{
+		context: ctx,
+		logger:  logger.With("collector", "aks"),
+	}
 }
 
 // CollectMetrics is a no-op function that satisfies the provider.Collector interface.
@@ -90,17 +204,128 @@ func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
 
 // Collect satisfies the provider.Collector interface.
 func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
-	// TODO - implement
-	c.logger.LogAttrs(c.context, slog.LevelInfo, "TODO - implement AKS collector Collect method")
+	scaleSets, err := c.listScaleSets()
+	if err != nil {
+		return err
+	}
+	disks, err := c.listDisks()
+	if err != nil {
+		return err
+	}
+	clusters, err := c.clustersByNodeResourceGroup()
+	if err != nil {
+		// The scale sets are still attributed by the aks-managed-cluster-name tag
+		c.logger.LogAttrs(c.context, slog.LevelWarn, "failed to map the node resource groups to their cluster", slog.String("err", err.Error()))
+	}
+	for _, metric := range c.VolumePriceStore.StorageClassMetrics() {
+		ch <- metric
+	}
+	unpriced := NewUnpricedMachineTypes(subsystem)
+	defer unpriced.Emit(ch)
+	benefits, err := c.benefits.List(c.context)
+	if err != nil {
+		// The VMs are still priced, at their retail price
+		c.logger.LogAttrs(c.context, slog.LevelWarn, "failed to list the reservations and savings plans", slog.String("err", err.Error()))
+	}
+	for _, metric := range instanceMetrics(c.PriceStore, scaleSets, clusters, benefits, unpriced) {
+		ch <- metric
+	}
+	for _, vmss := range scaleSets {
+		for _, metric := range spotPriceMetrics(c.PriceStore, vmss, ClusterNameFromVmss(vmss, clusters), unpriced) {
+			ch <- metric
+		}
+		if metric, ok := osDiskMetric(c.VolumePriceStore, vmss, ClusterNameFromVmss(vmss, clusters), unpriced); ok {
+			ch <- metric
+		}
+		if !c.capacityDelta || !hasCapacity(vmss) {
+			continue
+		}
+		running, err := c.runningInstances(vmss)
+		if err != nil {
+			// The other scale sets are still compared with their capacity
+			continue
+		}
+		for _, metric := range capacityMetrics(c.PriceStore, vmss, running, ClusterNameFromVmss(vmss, clusters)) {
+			ch <- metric
+		}
+	}
+	hosts, err := c.listDedicatedHosts()
+	if err != nil {
+		// The other resources are still collected when the dedicated hosts can't be listed, eg without the permission
+		// to read host groups
+		c.logger.LogAttrs(c.context, slog.LevelWarn, "failed to list the dedicated hosts", slog.String("err", err.Error()))
+	}
+	for _, host := range hosts {
+		for _, metric := range dedicatedHostMetrics(c.PriceStore, host, clusters, unpriced) {
+			ch <- metric
+		}
+	}
+	classes := c.storageClasses.ByVolume(c.context, storageclass.ProviderAzure, c.volumes)
+	for _, disk := range disks {
+		clusterName := ClusterNameFromDisk(disk, clusters)
+		if metric, ok := persistentVolumeMetric(c.VolumePriceStore, disk, clusterName, classes, unpriced); ok {
+			ch <- metric
+		}
+		if metric, ok := persistentVolumeSizeMetric(disk, clusterName); ok {
+			ch <- metric
+		}
+	}
 	return nil
 }
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
-	// TODO - implement
-	c.logger.LogAttrs(c.context, slog.LevelInfo, "TODO - implement AKS collector Describe method")
+	ch <- InstanceHourlyCostDesc
+	ch <- InstancesDesc
+	ch <- InstanceSpotMaxPriceDesc
+	ch <- InstanceSpotRetailPriceDesc
+	ch <- StorageClassHourlyPriceDesc
+	ch <- PersistentVolumeHourlyCostDesc
+	ch <- PersistentVolumeSizeDesc
+	ch <- OSDiskHourlyCostDesc
+	ch <- DedicatedHostHourlyCostDesc
+	ch <- RunningInstancesDesc
+	ch <- CapacityDeltaHourlyCostDesc
+	ch <- UnpricedMachineTypeInfoDesc
+	ch <- utils.PricingCoverageDesc
 	return nil
 }
 
+// ResourceDescs returns the metrics of the scale sets, disks and dedicated hosts listed by the collector. The prices of the
+// storage classes are exported whatever is listed, so they aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		InstanceHourlyCostDesc,
+		InstancesDesc,
+		InstanceSpotMaxPriceDesc,
+		InstanceSpotRetailPriceDesc,
+		PersistentVolumeHourlyCostDesc,
+		PersistentVolumeSizeDesc,
+		OSDiskHourlyCostDesc,
+		DedicatedHostHourlyCostDesc,
+		RunningInstancesDesc,
+		CapacityDeltaHourlyCostDesc,
+	}
+}
+
+// vmCreatedTimestamp returns the provisioning time of a scale set VM as a unix timestamp in seconds.
+// Scale set VMs don't expose a creation time, so the time of the provisioning status from the instance view is used instead.
+// The instance view is only populated when the VMs are listed with the instanceView expand option.
+// The second return value is false when Azure didn't report a provisioning time.
+func vmCreatedTimestamp(vm *armcompute.VirtualMachineScaleSetVM) (float64, bool) {
+	if vm == nil || vm.Properties == nil || vm.Properties.InstanceView == nil {
+		return 0, false
+	}
+	for _, status := range vm.Properties.InstanceView.Statuses {
+		if status == nil || status.Code == nil || status.Time == nil {
+			continue
+		}
+		if strings.HasPrefix(*status.Code, provisioningStatePrefix) {
+			return float64(status.Time.Unix()), true
+		}
+	}
+	return 0, false
+}
+
 func (c *Collector) Name() string {
 	return subsystem
 }
@@ -5,39 +5,253 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 var (
-	parentCtx  context.Context                    = context.TODO()
-	testLogger *slog.Logger                       = slog.New(slog.NewTextHandler(os.Stdout, nil))
-	fakeCreds  *azidentity.DefaultAzureCredential = &azidentity.DefaultAzureCredential{}
-	testSubId  string                             = "1234-asdf-adsf-adsf"
+	parentCtx  context.Context = context.TODO()
+	testLogger *slog.Logger    = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	testSubId  string          = "1234-asdf-adsf-adsf"
 )
 
 func Test_New(t *testing.T) {
 	for _, tc := range []struct {
 		name           string
 		subscriptionId string
+		resourceGroups *ResourceGroupFilter
 		expectedError  error
 	}{
 		{
 			subscriptionId: testSubId,
 			name:           "no error",
 		},
+		{
+			subscriptionId: testSubId,
+			name:           "invalid resource group pattern",
+			resourceGroups: &ResourceGroupFilter{Include: []string{"MC_["}},
+			expectedError:  ErrInvalidResourceGroupPattern,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			c, err := New(parentCtx, &Config{
 				Logger:         testLogger,
 				SubscriptionId: tc.subscriptionId,
-				Credentials:    fakeCreds,
+				Credentials:    fakeCredential{},
+				// New warms the price stores in the background, the fake transport keeps it from reaching Azure
+				ClientOptions:  &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: &fakeTransport{}}},
+				ResourceGroups: tc.resourceGroups,
 			})
-			require.NotNil(t, c)
 			if tc.expectedError != nil {
-				require.NotNil(t, err)
+				require.ErrorIs(t, err, tc.expectedError)
+				return
 			}
+			require.NotNil(t, c)
+		})
+	}
+}
+
+func Test_vmCreatedTimestamp(t *testing.T) {
+	created := time.Unix(1714521600, 0)
+	for _, tc := range []struct {
+		name          string
+		vm            *armcompute.VirtualMachineScaleSetVM
+		expectedValue float64
+		expectedFound bool
+	}{
+		{
+			name: "nil vm",
+		},
+		{
+			name: "no instance view",
+			vm: &armcompute.VirtualMachineScaleSetVM{
+				Properties: &armcompute.VirtualMachineScaleSetVMProperties{},
+			},
+		},
+		{
+			name: "no provisioning status",
+			vm: &armcompute.VirtualMachineScaleSetVM{
+				Properties: &armcompute.VirtualMachineScaleSetVMProperties{
+					InstanceView: &armcompute.VirtualMachineScaleSetVMInstanceView{
+						Statuses: []*armcompute.InstanceViewStatus{
+							{Code: to.StringPtr("PowerState/running")},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "provisioning time",
+			vm: &armcompute.VirtualMachineScaleSetVM{
+				Properties: &armcompute.VirtualMachineScaleSetVMProperties{
+					InstanceView: &armcompute.VirtualMachineScaleSetVMInstanceView{
+						Statuses: []*armcompute.InstanceViewStatus{
+							{Code: to.StringPtr("PowerState/running")},
+							{Code: to.StringPtr("ProvisioningState/succeeded"), Time: &created},
+						},
+					},
+				},
+			},
+			expectedValue: 1714521600,
+			expectedFound: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			value, found := vmCreatedTimestamp(tc.vm)
+			require.Equal(t, tc.expectedFound, found)
+			require.Equal(t, tc.expectedValue, value)
 		})
 	}
 }
+
+func TestCollector_Collect(t *testing.T) {
+	subscriptionPath := "/subscriptions/" + testSubId
+	nodeResourceGroupPath := subscriptionPath + "/resourceGroups/MC_prod-rg_prod_eastus"
+	// The scale set has no aks-managed-cluster-name tag, so it's only attributed through the node resource group
+	vmss := spotScaleSet(to.Float64Ptr(0.04), armcompute.OperatingSystemTypesLinux)
+	vmss.ID = to.StringPtr(nodeResourceGroupPath + "/providers/Microsoft.Compute/virtualMachineScaleSets/aks-spot-1234-vmss")
+	vmss.Tags = nil
+	responses := managedClusterResponses("prod-rg", "prod", "MC_prod-rg_prod_eastus")
+	responses[subscriptionPath+"/providers/Microsoft.Compute/virtualMachineScaleSets"] = map[string]any{"value": []any{vmss}}
+	responses[subscriptionPath+"/providers/Microsoft.Compute/disks"] = map[string]any{"value": []any{
+		map[string]any{
+			"id":         nodeResourceGroupPath + "/providers/Microsoft.Compute/disks/pvc-1234",
+			"name":       "pvc-1234",
+			"location":   "eastus",
+			"sku":        map[string]any{"name": "Premium_LRS"},
+			"tags":       map[string]any{pvNameTag: "pvc-1234", pvcNamespaceTag: "monitoring"},
+			"properties": map[string]any{"tier": "P10", "diskSizeGB": 128},
+		},
+	}}
+	hostGroupPath := subscriptionPath + "/resourceGroups/hosts-rg/providers/Microsoft.Compute/hostGroups/compliance"
+	responses[subscriptionPath+"/providers/Microsoft.Compute/hostGroups"] = map[string]any{"value": []any{
+		map[string]any{"id": hostGroupPath, "name": "compliance", "location": "eastus"},
+	}}
+	responses[hostGroupPath+"/hosts"] = map[string]any{"value": []any{
+		map[string]any{
+			"id":         hostGroupPath + "/hosts/host-1",
+			"name":       "host-1",
+			"location":   "eastus",
+			"sku":        map[string]any{"name": "DSv3-Type1"},
+			"properties": map[string]any{"virtualMachines": []any{map[string]any{"id": *vmss.ID + "/virtualMachines/0"}}},
+		},
+	}}
+	transport := &fakeTransport{responses: responses}
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+	resourceClient, err := armresources.NewClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	vmssClient, err := armcompute.NewVirtualMachineScaleSetsClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	diskClient, err := armcompute.NewDisksClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	hostGroupClient, err := armcompute.NewDedicatedHostGroupsClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	hostClient, err := armcompute.NewDedicatedHostsClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+
+	priceStore := newPricingStore(testSubId, nil, testLogger, parentCtx)
+	priceStore.addMachinePrice(retailPriceSdk.ResourceSKU{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.05})
+	priceStore.addMachinePrice(retailPriceSdk.ResourceSKU{ArmRegionName: "eastus", ProductName: "Dedicated Host", SkuName: "DSv3 Type1", RetailPrice: 4.5})
+	diskPrice := 19.71
+	volumePriceStore := newVolumePriceStore(nil, testLogger, parentCtx)
+	volumePriceStore.RegionMap["eastus"] = VolumePriceBySku{"P10 LRS": {Disk: diskPrice}}
+	c := &Collector{
+		context:                      parentCtx,
+		logger:                       testLogger,
+		resourceClient:               resourceClient,
+		virtualMachineScaleSetClient: vmssClient,
+		diskClient:                   diskClient,
+		hostGroupClient:              hostGroupClient,
+		hostClient:                   hostClient,
+		PriceStore:                   priceStore,
+		VolumePriceStore:             volumePriceStore,
+	}
+
+	ch := make(chan prometheus.Metric, 20)
+	require.NoError(t, c.Collect(ch))
+	close(ch)
+	got := map[string]*utils.MetricResult{}
+	for metric := range ch {
+		result := utils.ReadMetrics(metric)
+		got[result.FqName] = result
+	}
+	assert.Equal(t, utils.LabelMap{"vmss": "aks-spot-1234-vmss", "cluster_name": "prod", "region": "eastus", "machine_type": "Standard_D4_v5"}, got["cloudcost_azure_aks_spot_retail_usd_per_hour"].Labels)
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_azure_aks_persistent_volume_usd_per_hour",
+		Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "", "storage_class": "Premium_LRS", "disk_tier": "P10"},
+		Value:      diskPrice / utils.HoursInMonth,
+		MetricType: prometheus.GaugeValue,
+	}, got["cloudcost_azure_aks_persistent_volume_usd_per_hour"])
+	assert.Equal(t, 128.0, got["cloudcost_azure_aks_persistent_volume_size_gib"].Value)
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_azure_aks_dedicated_host_usd_per_hour",
+		Labels:     utils.LabelMap{"host_group": "compliance", "host": "host-1", "cluster_name": "prod", "region": "eastus", "machine_type": "DSv3-Type1"},
+		Value:      4.5,
+		MetricType: prometheus.GaugeValue,
+	}, got["cloudcost_azure_aks_dedicated_host_usd_per_hour"])
+}
+
+func TestCollector_CollectEmptyInventory(t *testing.T) {
+	subscriptionPath := "/subscriptions/" + testSubId
+	vmss := testScaleSet("aks-default-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesLinux)
+	vmss.ID = to.StringPtr(subscriptionPath + "/resourceGroups/MC_prod_prod_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/aks-default-vmss")
+	transport := &fakeTransport{responses: map[string]any{
+		subscriptionPath + "/providers/Microsoft.Compute/virtualMachineScaleSets": map[string]any{"value": []any{vmss}},
+		subscriptionPath + "/providers/Microsoft.Compute/disks":                   map[string]any{"value": []any{}},
+	}}
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+	resourceClient, err := armresources.NewClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	vmssClient, err := armcompute.NewVirtualMachineScaleSetsClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	diskClient, err := armcompute.NewDisksClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	hostGroupClient, err := armcompute.NewDedicatedHostGroupsClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	volumePriceStore := newVolumePriceStore(nil, testLogger, parentCtx)
+	volumePriceStore.RegionMap["eastus"] = VolumePriceBySku{"P10 LRS": {Disk: 19.71}}
+	c := &Collector{
+		context:                      parentCtx,
+		logger:                       testLogger,
+		resourceClient:               resourceClient,
+		virtualMachineScaleSetClient: vmssClient,
+		diskClient:                   diskClient,
+		hostGroupClient:              hostGroupClient,
+		PriceStore:                   retailPriceStore(),
+		VolumePriceStore:             volumePriceStore,
+	}
+
+	var watchdog provider.Watchdog
+	empty := provider.EmptyCollectionsTotal.WithLabelValues("azure", subsystem)
+	before := testutil.ToFloat64(empty)
+	collect := func() map[string]bool {
+		ch := make(chan prometheus.Metric, 20)
+		require.NoError(t, watchdog.Collect(parentCtx, testLogger, "azure", c, ch))
+		close(ch)
+		names := map[string]bool{}
+		for metric := range ch {
+			names[utils.ReadMetrics(metric).FqName] = true
+		}
+		return names
+	}
+
+	assert.True(t, collect()["cloudcost_azure_aks_instances"])
+	assert.Equal(t, before, testutil.ToFloat64(empty))
+	// The scale sets are gone while the prices of the storage classes are still exported
+	transport.responses[subscriptionPath+"/providers/Microsoft.Compute/virtualMachineScaleSets"] = map[string]any{"value": []any{}}
+	assert.Equal(t, map[string]bool{"cloudcost_azure_storage_class_usd_per_gib_hour": true}, collect())
+	assert.Equal(t, before+1, testutil.ToFloat64(empty))
+}
@@ -0,0 +1,108 @@
+package aks
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+const (
+	powerStatePrefix = "PowerState/"
+
+	// instanceViewExpand lists the VMs of a scale set along with their instance view, which holds their power state.
+	instanceViewExpand = "instanceView"
+)
+
+// unbilledPowerStates are the power states of the VMs whose compute isn't billed. Stopped VMs are still allocated and
+// billed, only deallocating them stops the billing.
+var unbilledPowerStates = map[string]bool{
+	"deallocated":  true,
+	"deallocating": true,
+}
+
+var (
+	// RunningInstancesDesc and CapacityDeltaHourlyCostDesc compare the VMs of a scale set with its capacity, which is
+	// the number of VMs InstancesDesc reports, to catch the scale operations stuck with VMs billed or missing.
+	RunningInstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "running_instances"),
+		"The number of VMs of a scale set that are billed, which are the VMs that aren't deallocated.",
+		[]string{"vmss", "cluster_name", "region", "machine_type"},
+		nil,
+	)
+	CapacityDeltaHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "capacity_delta_usd_per_hour"),
+		"The cost in USD/h of the difference between the capacity of a scale set and its billed VMs at the retail on-demand or spot price. It's positive when the capacity isn't running, eg a stuck scale-out, and negative when more VMs than the capacity are billed, eg a stuck scale-in.",
+		[]string{"vmss", "cluster_name", "region", "machine_type"},
+		nil,
+	)
+)
+
+// isBilled reports whether the compute of a scale set VM is billed from the power state of its instance view. A VM
+// without a power state, eg listed without its instance view, is assumed to be billed.
+func isBilled(vm *armcompute.VirtualMachineScaleSetVM) bool {
+	if vm == nil || vm.Properties == nil || vm.Properties.InstanceView == nil {
+		return true
+	}
+	for _, status := range vm.Properties.InstanceView.Statuses {
+		if status == nil || status.Code == nil || !strings.HasPrefix(*status.Code, powerStatePrefix) {
+			continue
+		}
+		return !unbilledPowerStates[strings.TrimPrefix(*status.Code, powerStatePrefix)]
+	}
+	return true
+}
+
+// runningInstances lists the VMs of a scale set along with their instance view and returns the number of billed VMs.
+func (c *Collector) runningInstances(vmss *armcompute.VirtualMachineScaleSet) (int64, error) {
+	pager := c.virtualMachineClient.NewListPager(resourceGroupNameFromID(*vmss.ID), *vmss.Name, &armcompute.VirtualMachineScaleSetVMsClientListOptions{
+		Expand: to.Ptr(instanceViewExpand),
+	})
+	var running int64
+	for pager.More() {
+		page, err := pager.NextPage(c.context)
+		if err != nil {
+			c.logger.LogAttrs(c.context, slog.LevelError, "failed to list scale set VMs", slog.String("vmss", *vmss.Name), slog.String("err", err.Error()))
+			return 0, ErrPageAdvanceFailure
+		}
+		for _, vm := range page.Value {
+			if isBilled(vm) {
+				running++
+			}
+		}
+	}
+	return running, nil
+}
+
+// hasCapacity reports whether the running VMs of a scale set are compared with its capacity. The scale sets placed on
+// a dedicated host are skipped, their VMs are billed through their host whatever their number.
+func hasCapacity(vmss *armcompute.VirtualMachineScaleSet) bool {
+	if vmss == nil || vmss.ID == nil || vmss.Name == nil || vmss.Location == nil || vmss.SKU == nil || vmss.SKU.Name == nil || vmss.SKU.Capacity == nil {
+		return false
+	}
+	return vmss.Properties == nil || vmss.Properties.HostGroup == nil
+}
+
+// capacityMetrics returns the running VMs of a scale set and the cost of the difference between its capacity and
+// running VMs, priced at the retail spot or on-demand price. The cost isn't returned when the sku can't be priced, in
+// which case the scale set was already recorded as unpriced by its instance metrics.
+func capacityMetrics(prices *PriceStore, vmss *armcompute.VirtualMachineScaleSet, running int64, clusterName string) []prometheus.Metric {
+	region, sku := *vmss.Location, *vmss.SKU.Name
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(RunningInstancesDesc, prometheus.GaugeValue, float64(running), *vmss.Name, clusterName, region, sku),
+	}
+	priority := OnDemand
+	if isSpot(vmss) {
+		priority = Spot
+	}
+	price, err := prices.getPrice(region, priority, operatingSystem(vmss), sku)
+	if err != nil {
+		return metrics
+	}
+	delta := float64(*vmss.SKU.Capacity - running)
+	return append(metrics, prometheus.MustNewConstMetric(CapacityDeltaHourlyCostDesc, prometheus.GaugeValue, delta*price, *vmss.Name, clusterName, region, sku))
+}
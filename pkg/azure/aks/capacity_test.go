@@ -0,0 +1,156 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testScaleSetVM(codes ...string) *armcompute.VirtualMachineScaleSetVM {
+	var statuses []*armcompute.InstanceViewStatus
+	for _, code := range codes {
+		statuses = append(statuses, &armcompute.InstanceViewStatus{Code: to.StringPtr(code)})
+	}
+	return &armcompute.VirtualMachineScaleSetVM{
+		Properties: &armcompute.VirtualMachineScaleSetVMProperties{
+			InstanceView: &armcompute.VirtualMachineScaleSetVMInstanceView{Statuses: statuses},
+		},
+	}
+}
+
+func Test_isBilled(t *testing.T) {
+	assert.True(t, isBilled(testScaleSetVM("ProvisioningState/succeeded", "PowerState/running")))
+	assert.True(t, isBilled(testScaleSetVM("ProvisioningState/succeeded", "PowerState/stopped")), "stopped VMs are still allocated")
+	assert.False(t, isBilled(testScaleSetVM("ProvisioningState/succeeded", "PowerState/deallocated")))
+	assert.False(t, isBilled(testScaleSetVM("PowerState/deallocating")))
+	assert.True(t, isBilled(testScaleSetVM("ProvisioningState/creating")), "a VM without a power state is assumed to be billed")
+	assert.True(t, isBilled(&armcompute.VirtualMachineScaleSetVM{}))
+}
+
+func capacityScaleSet(capacity int64) *armcompute.VirtualMachineScaleSet {
+	return &armcompute.VirtualMachineScaleSet{
+		ID:       to.StringPtr("/subscriptions/" + testSubId + "/resourceGroups/MC_prod_prod_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/aks-default-vmss"),
+		Name:     to.StringPtr("aks-default-vmss"),
+		Location: to.StringPtr("eastus"),
+		SKU:      &armcompute.SKU{Name: to.StringPtr("Standard_D4_v5"), Capacity: to.Int64Ptr(capacity)},
+	}
+}
+
+func Test_hasCapacity(t *testing.T) {
+	assert.True(t, hasCapacity(capacityScaleSet(0)))
+	assert.False(t, hasCapacity(&armcompute.VirtualMachineScaleSet{Name: to.StringPtr("aks-default-vmss")}))
+
+	onHost := capacityScaleSet(3)
+	onHost.Properties = &armcompute.VirtualMachineScaleSetProperties{HostGroup: &armcompute.SubResource{ID: to.StringPtr("compliance")}}
+	assert.False(t, hasCapacity(onHost), "the VMs of a scale set on a dedicated host are billed through the host")
+}
+
+func Test_capacityMetrics(t *testing.T) {
+	priceStore := newPricingStore(testSubId, nil, testLogger, parentCtx)
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.2},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.05},
+	} {
+		priceStore.addMachinePrice(item)
+	}
+	labels := utils.LabelMap{"vmss": "aks-default-vmss", "cluster_name": "prod", "region": "eastus", "machine_type": "Standard_D4_v5"}
+	spot := spotScaleSet(nil, armcompute.OperatingSystemTypesLinux)
+	spot.SKU.Capacity = to.Int64Ptr(2)
+	spotLabels := utils.LabelMap{"vmss": "aks-spot-1234-vmss", "cluster_name": "prod", "region": "eastus", "machine_type": "Standard_D4_v5"}
+	unpriced := capacityScaleSet(1)
+	unpriced.SKU.Name = to.StringPtr("Standard_HB120rs_v3")
+
+	for _, tc := range []struct {
+		name     string
+		vmss     *armcompute.VirtualMachineScaleSet
+		running  int64
+		expected []*utils.MetricResult
+	}{
+		{
+			name:    "stuck scale-out",
+			vmss:    capacityScaleSet(5),
+			running: 3,
+			expected: []*utils.MetricResult{
+				{FqName: "cloudcost_azure_aks_running_instances", Labels: labels, Value: 3, MetricType: prometheus.GaugeValue},
+				{FqName: "cloudcost_azure_aks_capacity_delta_usd_per_hour", Labels: labels, Value: 0.4, MetricType: prometheus.GaugeValue},
+			},
+		},
+		{
+			name:    "stuck scale-in to zero",
+			vmss:    capacityScaleSet(0),
+			running: 2,
+			expected: []*utils.MetricResult{
+				{FqName: "cloudcost_azure_aks_running_instances", Labels: labels, Value: 2, MetricType: prometheus.GaugeValue},
+				{FqName: "cloudcost_azure_aks_capacity_delta_usd_per_hour", Labels: labels, Value: -0.4, MetricType: prometheus.GaugeValue},
+			},
+		},
+		{
+			name:    "spot VMs evicted at the spot price",
+			vmss:    spot,
+			running: 1,
+			expected: []*utils.MetricResult{
+				{FqName: "cloudcost_azure_aks_running_instances", Labels: spotLabels, Value: 1, MetricType: prometheus.GaugeValue},
+				{FqName: "cloudcost_azure_aks_capacity_delta_usd_per_hour", Labels: spotLabels, Value: 0.05, MetricType: prometheus.GaugeValue},
+			},
+		},
+		{
+			name:    "sku without a price",
+			vmss:    unpriced,
+			running: 1,
+			expected: []*utils.MetricResult{
+				{FqName: "cloudcost_azure_aks_running_instances", Labels: utils.LabelMap{"vmss": "aks-default-vmss", "cluster_name": "prod", "region": "eastus", "machine_type": "Standard_HB120rs_v3"}, Value: 1, MetricType: prometheus.GaugeValue},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []*utils.MetricResult
+			for _, m := range capacityMetrics(priceStore, tc.vmss, tc.running, "prod") {
+				got = append(got, utils.ReadMetrics(m))
+			}
+			require.Len(t, got, len(tc.expected))
+			for i := range got {
+				assert.Equal(t, tc.expected[i].FqName, got[i].FqName)
+				assert.Equal(t, tc.expected[i].Labels, got[i].Labels)
+				assert.InDelta(t, tc.expected[i].Value, got[i].Value, 1e-9)
+			}
+		})
+	}
+}
+
+func Test_runningInstances(t *testing.T) {
+	vmss := capacityScaleSet(3)
+	vm := func(powerState string) map[string]any {
+		return map[string]any{"properties": map[string]any{"instanceView": map[string]any{"statuses": []any{
+			map[string]any{"code": "ProvisioningState/succeeded"},
+			map[string]any{"code": powerState},
+		}}}}
+	}
+	transport := &fakeTransport{responses: map[string]any{
+		*vmss.ID + "/virtualMachines": map[string]any{"value": []any{
+			vm("PowerState/running"),
+			vm("PowerState/deallocated"),
+			vm("PowerState/stopped"),
+		}},
+	}}
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+	vmClient, err := armcompute.NewVirtualMachineScaleSetVMsClient(testSubId, fakeCredential{}, options)
+	require.NoError(t, err)
+	c := &Collector{context: parentCtx, logger: testLogger, virtualMachineClient: vmClient}
+
+	running, err := c.runningInstances(vmss)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), running)
+
+	vmss.Name = to.StringPtr("missing-vmss")
+	_, err = c.runningInstances(vmss)
+	assert.ErrorIs(t, err, ErrPageAdvanceFailure)
+}
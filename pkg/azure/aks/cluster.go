@@ -0,0 +1,106 @@
+package aks
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+const (
+	// AksClusterNameTag is set by AKS on the scale sets it manages. It's only used as a fallback for scale sets that
+	// live outside the node resource group of their cluster, eg node pools created in a custom resource group.
+	AksClusterNameTag = "aks-managed-cluster-name"
+
+	resourceGroupsSegment = "resourcegroups"
+
+	managedClusterResourceType = "Microsoft.ContainerService/managedClusters"
+	// managedClusterAPIVersion is the version of the AKS API used to read the node resource group of the clusters.
+	managedClusterAPIVersion = "2024-02-01"
+)
+
+// ClusterNameFromVmss returns the name of the AKS cluster a scale set belongs to.
+// clustersByNodeResourceGroup maps a lowercased node resource group to the cluster that owns it and is the primary
+// source of attribution. When the scale set's resource group isn't a known node resource group the
+// aks-managed-cluster-name tag is used instead. An empty string is returned if the cluster can't be determined.
+func ClusterNameFromVmss(vmss *armcompute.VirtualMachineScaleSet, clustersByNodeResourceGroup map[string]string) string {
+	if vmss == nil {
+		return ""
+	}
+	if vmss.ID != nil {
+		resourceGroup := resourceGroupFromID(*vmss.ID)
+		if clusterName, ok := clustersByNodeResourceGroup[resourceGroup]; ok {
+			return clusterName
+		}
+	}
+	if clusterName, ok := vmss.Tags[AksClusterNameTag]; ok && clusterName != nil {
+		return *clusterName
+	}
+	return ""
+}
+
+// resourceGroupFromID extracts the lowercased resource group name from an Azure resource ID, eg
+// /subscriptions/<id>/resourceGroups/<rg>/providers/Microsoft.Compute/virtualMachineScaleSets/<name>.
+// Resource group names are case-insensitive, which is why the result is lowercased.
+func resourceGroupFromID(id string) string {
+	return strings.ToLower(resourceGroupNameFromID(id))
+}
+
+// resourceGroupNameFromID extracts the resource group name from an Azure resource ID as is, to address the resource
+// group in requests.
+func resourceGroupNameFromID(id string) string {
+	segments := strings.Split(id, "/")
+	for i, segment := range segments {
+		if strings.ToLower(segment) == resourceGroupsSegment && i+1 < len(segments) {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+// ClusterNameFromDisk returns the name of the AKS cluster whose node resource group a disk lives in, the disks of
+// persistent volumes being provisioned there. An empty string is returned if the cluster can't be determined.
+func ClusterNameFromDisk(disk *armcompute.Disk, clustersByNodeResourceGroup map[string]string) string {
+	if disk == nil || disk.ID == nil {
+		return ""
+	}
+	return clustersByNodeResourceGroup[resourceGroupFromID(*disk.ID)]
+}
+
+// clustersByNodeResourceGroup maps the lowercased node resource group of every AKS cluster of the subscription to the
+// name of the cluster. The list of resources doesn't include their properties, so every cluster is fetched to read
+// its node resource group. Clusters that can't be fetched are skipped, leaving their scale sets to the
+// aks-managed-cluster-name tag.
+func (c *Collector) clustersByNodeResourceGroup() (map[string]string, error) {
+	clusters := make(map[string]string)
+	pager := c.resourceClient.NewListPager(&armresources.ClientListOptions{
+		Filter: to.Ptr("resourceType eq '" + managedClusterResourceType + "'"),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(c.context)
+		if err != nil {
+			c.logger.LogAttrs(c.context, slog.LevelError, "failed to list managed clusters", slog.String("err", err.Error()))
+			return nil, ErrPageAdvanceFailure
+		}
+		for _, cluster := range page.Value {
+			if cluster.ID == nil || cluster.Name == nil {
+				continue
+			}
+			resp, err := c.resourceClient.GetByID(c.context, *cluster.ID, managedClusterAPIVersion, nil)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to get managed cluster", slog.String("cluster", *cluster.Name), slog.String("err", err.Error()))
+				continue
+			}
+			properties, ok := resp.Properties.(map[string]any)
+			if !ok {
+				continue
+			}
+			if nodeResourceGroup, ok := properties["nodeResourceGroup"].(string); ok && nodeResourceGroup != "" {
+				clusters[strings.ToLower(nodeResourceGroup)] = *cluster.Name
+			}
+		}
+	}
+	return clusters, nil
+}
@@ -0,0 +1,118 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterNameFromVmss(t *testing.T) {
+	nodeResourceGroups := map[string]string{
+		"mc_dev_dev-cluster_eastus": "dev-cluster",
+	}
+	testTable := map[string]struct {
+		vmss     *armcompute.VirtualMachineScaleSet
+		expected string
+	}{
+		"nil scale set": {
+			vmss:     nil,
+			expected: "",
+		},
+		"scale set in node resource group": {
+			vmss: &armcompute.VirtualMachineScaleSet{
+				ID: to.StringPtr("/subscriptions/1234/resourceGroups/MC_dev_dev-cluster_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/aks-default-1234-vmss"),
+			},
+			expected: "dev-cluster",
+		},
+		"node resource group takes precedence over tag": {
+			vmss: &armcompute.VirtualMachineScaleSet{
+				ID: to.StringPtr("/subscriptions/1234/resourceGroups/MC_dev_dev-cluster_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/aks-default-1234-vmss"),
+				Tags: map[string]*string{
+					AksClusterNameTag: to.StringPtr("other-cluster"),
+				},
+			},
+			expected: "dev-cluster",
+		},
+		"scale set in custom resource group falls back to tag": {
+			vmss: &armcompute.VirtualMachineScaleSet{
+				ID: to.StringPtr("/subscriptions/1234/resourceGroups/byo-nodes/providers/Microsoft.Compute/virtualMachineScaleSets/aks-byo-1234-vmss"),
+				Tags: map[string]*string{
+					AksClusterNameTag: to.StringPtr("prod-cluster"),
+				},
+			},
+			expected: "prod-cluster",
+		},
+		"scale set without attribution": {
+			vmss: &armcompute.VirtualMachineScaleSet{
+				ID: to.StringPtr("/subscriptions/1234/resourceGroups/unrelated/providers/Microsoft.Compute/virtualMachineScaleSets/unrelated-vmss"),
+			},
+			expected: "",
+		},
+	}
+
+	for name, test := range testTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ClusterNameFromVmss(test.vmss, nodeResourceGroups))
+		})
+	}
+}
+
+// managedClusterResponses returns the responses listing a managed cluster and fetching it.
+func managedClusterResponses(resourceGroup string, name string, nodeResourceGroup string) map[string]any {
+	subscriptionPath := "/subscriptions/" + testSubId
+	id := subscriptionPath + "/resourceGroups/" + resourceGroup + "/providers/" + managedClusterResourceType + "/" + name
+	return map[string]any{
+		subscriptionPath + "/resources": map[string]any{"value": []any{
+			map[string]any{"id": id, "name": name, "type": managedClusterResourceType},
+		}},
+		id: map[string]any{"id": id, "name": name, "properties": map[string]any{"nodeResourceGroup": nodeResourceGroup}},
+	}
+}
+
+func TestCollector_clustersByNodeResourceGroup(t *testing.T) {
+	subscriptionPath := "/subscriptions/" + testSubId
+	for _, tc := range []struct {
+		name      string
+		responses map[string]any
+		expected  map[string]string
+		err       error
+	}{
+		{
+			name:      "node resource group of the cluster",
+			responses: managedClusterResponses("prod-rg", "prod", "MC_prod-rg_prod_eastus"),
+			expected:  map[string]string{"mc_prod-rg_prod_eastus": "prod"},
+		},
+		{
+			name: "cluster that can't be fetched is skipped",
+			responses: map[string]any{
+				subscriptionPath + "/resources": map[string]any{"value": []any{
+					map[string]any{"id": subscriptionPath + "/resourceGroups/prod-rg/providers/" + managedClusterResourceType + "/gone", "name": "gone"},
+				}},
+			},
+			expected: map[string]string{},
+		},
+		{
+			name:      "clusters can't be listed",
+			responses: map[string]any{},
+			err:       ErrPageAdvanceFailure,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &fakeTransport{responses: tc.responses}
+			options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+			resourceClient, err := armresources.NewClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			c := &Collector{context: parentCtx, logger: testLogger, resourceClient: resourceClient}
+
+			clusters, err := c.clustersByNodeResourceGroup()
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, clusters)
+		})
+	}
+}
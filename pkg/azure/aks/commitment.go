@@ -0,0 +1,187 @@
+package aks
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/azure/reservations"
+)
+
+const (
+	// reservationPriceType is the price type of the reservation prices, which are the price of the whole term.
+	reservationPriceType = "Reservation"
+	// reservationPriceSource is the source label of the malformed reservation prices.
+	reservationPriceSource = "reservation"
+	hoursInYear            = 24 * 365
+
+	PriceTierReserved    = "reserved"
+	PriceTierSavingsPlan = "savings_plan"
+)
+
+// ReservedPriceBySku holds the hourly reservation price of a sku by retail term, eg 1 Year.
+type ReservedPriceBySku map[string]map[string]float64
+
+// retailTerm returns the term of the retail prices, eg 3 Years, of the ISO 8601 term of a reservation or a savings
+// plan, eg P3Y.
+func retailTerm(term string) (string, bool) {
+	years, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(term, "P"), "Y"))
+	if err != nil || years <= 0 {
+		return "", false
+	}
+	if years == 1 {
+		return "1 Year", true
+	}
+	return fmt.Sprintf("%d Years", years), true
+}
+
+// termYears returns the number of years of a retail term, eg 3 for 3 Years.
+func termYears(term string) (int, bool) {
+	years, _, _ := strings.Cut(term, " ")
+	n, err := strconv.Atoi(years)
+	return n, err == nil && n > 0
+}
+
+// addReservationPrice files the hourly price of a reservation item under its region, sku and term. Reservation items
+// are priced for their whole term, which is spread over its hours. The caller must hold the write lock.
+func (p *PriceStore) addReservationPrice(v retailPriceSdk.ResourceSKU) {
+	// The reservations only cover the compute, the license of the Windows VMs is billed on top of them
+	if v.ArmSkuName == "" || strings.Contains(v.ProductName, "Windows") {
+		return
+	}
+	years, ok := termYears(v.ReservationTerm)
+	if !ok {
+		return
+	}
+	hourly := v
+	hourly.RetailPrice = v.RetailPrice / float64(years*hoursInYear)
+	if reason := validateRetailPrice(hourly, hourlyUnitOfMeasure, maxMachineHourlyPrice); reason != "" {
+		p.logger.LogAttrs(p.context, slog.LevelWarn, "skipping malformed reservation price", slog.String("sku", v.SkuName), slog.String("reason", reason))
+		MalformedPriceEntriesTotal.WithLabelValues(reservationPriceSource, reason).Inc()
+		return
+	}
+	if _, ok := p.ReservationRegionMap[v.ArmRegionName]; !ok {
+		p.ReservationRegionMap[v.ArmRegionName] = make(ReservedPriceBySku)
+	}
+	if _, ok := p.ReservationRegionMap[v.ArmRegionName][v.ArmSkuName]; !ok {
+		p.ReservationRegionMap[v.ArmRegionName][v.ArmSkuName] = make(map[string]float64)
+	}
+	p.ReservationRegionMap[v.ArmRegionName][v.ArmSkuName][v.ReservationTerm] = hourly.RetailPrice
+}
+
+// getReservationPrice returns the hourly reservation price in USD/h of a sku in a region for a retail term.
+func (p *PriceStore) getReservationPrice(region string, sku string, term string) (float64, error) {
+	if err := p.EnsureRegions([]string{region}); err != nil {
+		return 0, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	price, ok := p.ReservationRegionMap[region][sku][term]
+	if !ok {
+		return 0, ErrSkuNotFound
+	}
+	return price, nil
+}
+
+// getSavingsPlanPrice returns the savings plan price in USD/h of a sku in a region for a retail term. It's listed
+// along with the on-demand price of the Linux VMs.
+func (p *PriceStore) getSavingsPlanPrice(region string, sku string, term string) (float64, error) {
+	if err := p.EnsureRegions([]string{region}); err != nil {
+		return 0, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, plan := range p.RegionMap[region][OnDemand][Linux][sku].SavingsPlan {
+		if plan.Term == term && plan.RetailPrice > 0 {
+			return plan.RetailPrice, nil
+		}
+	}
+	return 0, ErrSkuNotFound
+}
+
+// cover moves n on-demand VMs to a price tier, at the price of its compute plus the license of the VMs.
+func (s *scaleSetInstances) cover(tier string, n int64, computePrice float64) {
+	s.onDemand -= n
+	s.add(tier, n, computePrice+s.license)
+}
+
+// applyReservations covers the on-demand VMs of the reservations' sku and region, up to their quantity, in the order
+// of the scale sets. A reservation whose price isn't found covers nothing.
+func applyReservations(prices *PriceStore, scaleSets []*scaleSetInstances, benefits []reservations.VMReservation) {
+	for _, r := range benefits {
+		term, ok := retailTerm(r.Term)
+		if !ok {
+			continue
+		}
+		remaining := int64(r.Quantity)
+		for _, s := range scaleSets {
+			if remaining == 0 {
+				break
+			}
+			if s.onDemand == 0 || s.region != r.Region || !strings.EqualFold(s.sku, r.SKU) {
+				continue
+			}
+			price, err := prices.getReservationPrice(s.region, s.sku, term)
+			if err != nil {
+				break
+			}
+			n := min(remaining, s.onDemand)
+			s.cover(PriceTierReserved, n, price)
+			remaining -= n
+		}
+	}
+}
+
+// applySavingsPlans covers the on-demand VMs left by the reservations with the hourly commitment of every savings
+// plan, the VMs with the highest discount first like Azure does. A VM is only covered when the commitment left covers
+// it entirely.
+func applySavingsPlans(prices *PriceStore, scaleSets []*scaleSetInstances, plans []reservations.SavingsPlan) {
+	type candidate struct {
+		scaleSet *scaleSetInstances
+		price    float64
+	}
+	for _, plan := range plans {
+		term, ok := retailTerm(plan.Term)
+		if !ok {
+			continue
+		}
+		var candidates []candidate
+		for _, s := range scaleSets {
+			if s.onDemand == 0 {
+				continue
+			}
+			if price, err := prices.getSavingsPlanPrice(s.region, s.sku, term); err == nil && price < s.onDemandPrice-s.license {
+				candidates = append(candidates, candidate{scaleSet: s, price: price})
+			}
+		}
+		discount := func(c candidate) float64 { return 1 - c.price/(c.scaleSet.onDemandPrice-c.scaleSet.license) }
+		sort.SliceStable(candidates, func(i, j int) bool { return discount(candidates[i]) > discount(candidates[j]) })
+		remaining := plan.HourlyCommitment
+		for _, c := range candidates {
+			// The epsilon keeps a commitment of exactly n VMs from covering n-1 because of rounding
+			n := min(c.scaleSet.onDemand, int64(math.Floor(remaining/c.price+1e-9)))
+			if n <= 0 {
+				continue
+			}
+			c.scaleSet.cover(PriceTierSavingsPlan, n, c.price)
+			remaining -= float64(n) * c.price
+		}
+	}
+}
+
+// applyBenefits covers the on-demand VMs of the scale sets with the reservations first, then with the savings plans.
+// The VMs are left at their retail price when benefits is nil.
+func applyBenefits(prices *PriceStore, scaleSets []*scaleSetInstances, benefits *reservations.Benefits) {
+	if benefits == nil {
+		return
+	}
+	applyReservations(prices, scaleSets, benefits.Reservations)
+	applySavingsPlans(prices, scaleSets, benefits.SavingsPlans)
+}
@@ -0,0 +1,136 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/azure/reservations"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func Test_retailTerm(t *testing.T) {
+	for term, expected := range map[string]string{"P1Y": "1 Year", "P3Y": "3 Years", "P5Y": "5 Years", "P1M": "", "": ""} {
+		got, ok := retailTerm(term)
+		assert.Equal(t, expected != "", ok, term)
+		assert.Equal(t, expected, got, term)
+	}
+}
+
+func commitmentPriceStore() *PriceStore {
+	p := newPricingStore(testSubId, nil, testLogger, parentCtx)
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.2,
+			SavingsPlan: []retailPriceSdk.SavingsPlan{{Term: "1 Year", RetailPrice: 0.15}, {Term: "3 Years", RetailPrice: 0.1}}},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series Windows", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.3},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.05},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D2 v5", ArmSkuName: "Standard_D2_v5", RetailPrice: 0.1,
+			SavingsPlan: []retailPriceSdk.SavingsPlan{{Term: "3 Years", RetailPrice: 0.07}}},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", Type: reservationPriceType, ReservationTerm: "1 Year", RetailPrice: 0.08 * hoursInYear},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series Windows", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", Type: reservationPriceType, ReservationTerm: "1 Year", RetailPrice: 0.2 * hoursInYear},
+	} {
+		p.addMachinePrice(item)
+	}
+	return p
+}
+
+func TestPriceStore_commitmentPrices(t *testing.T) {
+	p := commitmentPriceStore()
+
+	price, err := p.getReservationPrice("eastus", "Standard_D4_v5", "1 Year")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.08, price, 1e-9, "the price of the term is spread over its hours, and the Windows items aren't reservation prices")
+	_, err = p.getReservationPrice("eastus", "Standard_D4_v5", "3 Years")
+	assert.ErrorIs(t, err, ErrSkuNotFound)
+	assert.Len(t, p.RegionMap["eastus"][OnDemand][Linux], 2, "the reservation prices aren't on-demand prices")
+
+	price, err = p.getSavingsPlanPrice("eastus", "Standard_D4_v5", "3 Years")
+	require.NoError(t, err)
+	assert.Equal(t, 0.1, price)
+	_, err = p.getSavingsPlanPrice("westeurope", "Standard_D4_v5", "3 Years")
+	assert.ErrorIs(t, err, ErrSkuNotFound)
+
+	malformed := MalformedPriceEntriesTotal.WithLabelValues(reservationPriceSource, utils.ReasonUnexpectedUnit)
+	before := testutil.ToFloat64(malformed)
+	p.addMachinePrice(retailPriceSdk.ResourceSKU{ArmRegionName: "eastus", ArmSkuName: "Standard_D2_v5", Type: reservationPriceType, ReservationTerm: "3 Years", UnitOfMeasure: "1/Month", RetailPrice: 1000})
+	assert.Equal(t, before+1, testutil.ToFloat64(malformed))
+}
+
+func Test_instanceMetrics_Benefits(t *testing.T) {
+	p := commitmentPriceStore()
+	spot := testScaleSet("aks-spot-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesLinux)
+	spotPriority := armcompute.VirtualMachinePriorityTypesSpot
+	spot.Properties.VirtualMachineProfile.Priority = &spotPriority
+	dedicated := testScaleSet("aks-dedicated-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesLinux)
+	dedicated.Properties.HostGroup = &armcompute.SubResource{ID: to.StringPtr("/subscriptions/" + testSubId + "/resourceGroups/hosts/providers/Microsoft.Compute/hostGroups/compliance")}
+	vmss := []*armcompute.VirtualMachineScaleSet{
+		testScaleSet("aks-win-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesWindows),
+		testScaleSet("aks-linux-vmss", "Standard_D4_v5", 4, armcompute.OperatingSystemTypesLinux),
+		testScaleSet("aks-small-vmss", "Standard_D2_v5", 3, armcompute.OperatingSystemTypesLinux),
+		testScaleSet("aks-empty-vmss", "Standard_D2_v5", 0, armcompute.OperatingSystemTypesLinux),
+		testScaleSet("aks-unpriced-vmss", "Standard_E4_v5", 1, armcompute.OperatingSystemTypesLinux),
+		spot,
+		dedicated,
+	}
+
+	type tier struct {
+		instances float64
+		price     float64
+	}
+	for _, tc := range []struct {
+		name     string
+		benefits *reservations.Benefits
+		expected map[string]tier
+	}{
+		{
+			name: "reservations cover their quantity, then savings plans cover the highest discount first",
+			benefits: &reservations.Benefits{
+				Reservations: []reservations.VMReservation{
+					{ID: "d4", Region: "eastus", SKU: "Standard_D4_v5", Quantity: 3, Term: "P1Y"},
+					// A reservation without a retail price covers nothing
+					{ID: "d2", Region: "eastus", SKU: "Standard_D2_v5", Quantity: 3, Term: "P3Y"},
+				},
+				// The commitment covers the last on-demand Linux D4 VM and both Windows ones, whose compute is
+				// discounted by 50%, leaving too little for a D2 VM discounted by 30%
+				SavingsPlans: []reservations.SavingsPlan{{ID: "sp", Term: "P3Y", HourlyCommitment: 0.35}},
+			},
+			expected: map[string]tier{
+				"aks-linux-vmss/reserved":     {3, 0.08},
+				"aks-linux-vmss/savings_plan": {1, 0.1},
+				"aks-small-vmss/ondemand":     {3, 0.1},
+				"aks-spot-vmss/spot":          {2, 0.05},
+				"aks-win-vmss/savings_plan":   {2, 0.2},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			unpriced := NewUnpricedMachineTypes(subsystem)
+			costs := map[string]float64{}
+			instances := map[string]float64{}
+			for _, metric := range instanceMetrics(p, vmss, nil, tc.benefits, unpriced) {
+				result := utils.ReadMetrics(metric)
+				assert.Equal(t, "prod", result.Labels["cluster_name"])
+				key := result.Labels["vmss"] + "/" + result.Labels["price_tier"]
+				switch result.FqName {
+				case "cloudcost_azure_aks_instance_usd_per_hour":
+					costs[key] = result.Value
+				case "cloudcost_azure_aks_instances":
+					instances[key] = result.Value
+				default:
+					t.Fatalf("unexpected metric %s", result.FqName)
+				}
+			}
+			require.Len(t, costs, len(tc.expected))
+			require.Len(t, instances, len(tc.expected))
+			for key, expected := range tc.expected {
+				assert.Equal(t, expected.instances, instances[key], key)
+				assert.InDelta(t, expected.price, costs[key], 1e-9, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package aks
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	// dedicatedHostProduct is part of the product name of the dedicated host meters, which are listed under the
+	// Virtual Machines service along with the VM meters.
+	dedicatedHostProduct = "Dedicated Host"
+	// dedicatedHostPriceSource is the source label of the malformed dedicated host prices.
+	dedicatedHostPriceSource = "dedicated_host"
+)
+
+var (
+	// DedicatedHostHourlyCostDesc is the cost of a dedicated host. The VMs placed on a dedicated host aren't billed
+	// per VM, the host is billed whatever runs on it, so the cost of the host is split between the clusters of its
+	// VMs rather than added to the cost of every VM.
+	DedicatedHostHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "dedicated_host_usd_per_hour"),
+		"The cost of a dedicated host in USD/h, split between the AKS clusters of the VMs placed on it by their number of VMs. A host without any VM has an empty cluster_name.",
+		[]string{"host_group", "host", "cluster_name", "region", "machine_type"},
+		nil,
+	)
+)
+
+// isDedicatedHostPrice reports whether a retail price item is the meter of a dedicated host rather than of a VM.
+func isDedicatedHostPrice(v retailPriceSdk.ResourceSKU) bool {
+	return strings.Contains(v.ProductName, dedicatedHostProduct)
+}
+
+// hostSkuKey normalizes the sku of a dedicated host, so that the sku of a host, eg DSv3-Type1, matches the sku name
+// of its meter, eg DSv3 Type1.
+func hostSkuKey(sku string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, sku)
+}
+
+// addDedicatedHostPrice files the hourly price of a dedicated host under its region and normalized sku. The Windows
+// Server license meters of the hosts aren't filed, the license of the VMs of a host isn't priced. The caller must
+// hold the write lock.
+func (p *PriceStore) addDedicatedHostPrice(v retailPriceSdk.ResourceSKU) {
+	if strings.Contains(v.ProductName, "Windows") {
+		return
+	}
+	if reason := validateRetailPrice(v, hourlyUnitOfMeasure, maxMachineHourlyPrice); reason != "" {
+		p.logger.LogAttrs(p.context, slog.LevelWarn, "skipping malformed dedicated host price", slog.String("sku", v.SkuName), slog.String("reason", reason))
+		MalformedPriceEntriesTotal.WithLabelValues(dedicatedHostPriceSource, reason).Inc()
+		return
+	}
+	sku := v.ArmSkuName
+	if sku == "" {
+		sku = v.SkuName
+	}
+	if _, ok := p.HostRegionMap[v.ArmRegionName]; !ok {
+		p.HostRegionMap[v.ArmRegionName] = make(PriceBySku)
+	}
+	p.HostRegionMap[v.ArmRegionName][hostSkuKey(sku)] = v
+}
+
+// getDedicatedHostPrice returns the retail price in USD/h of a dedicated host sku, eg DSv3-Type1, in a region.
+func (p *PriceStore) getDedicatedHostPrice(region string, sku string) (float64, error) {
+	if err := p.EnsureRegions([]string{region}); err != nil {
+		return 0, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	prices, ok := p.HostRegionMap[region]
+	if _, priced := p.RegionMap[region]; !ok && !priced {
+		return 0, ErrRegionNotFound
+	}
+	price, ok := prices[hostSkuKey(sku)]
+	if !ok {
+		return 0, ErrSkuNotFound
+	}
+	return price.RetailPrice, nil
+}
+
+// dedicatedHost is a dedicated host along with the name of its host group.
+type dedicatedHost struct {
+	group string
+	host  *armcompute.DedicatedHost
+}
+
+// listDedicatedHosts lists the dedicated hosts of the host groups of the resource groups selected by the resource
+// group filter.
+func (c *Collector) listDedicatedHosts() ([]dedicatedHost, error) {
+	groups, err := c.listHostGroups()
+	if err != nil {
+		return nil, err
+	}
+	var hosts []dedicatedHost
+	for _, group := range groups {
+		if group.ID == nil || group.Name == nil {
+			continue
+		}
+		pager := c.hostClient.NewListByHostGroupPager(resourceGroupNameFromID(*group.ID), *group.Name, nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list dedicated hosts", slog.String("host_group", *group.Name), slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			for _, host := range page.Value {
+				hosts = append(hosts, dedicatedHost{group: *group.Name, host: host})
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// listHostGroups lists the dedicated host groups of the resource groups selected by the resource group filter.
+func (c *Collector) listHostGroups() ([]*armcompute.DedicatedHostGroup, error) {
+	var groups []*armcompute.DedicatedHostGroup
+	if c.resourceGroups.listsAll() {
+		pager := c.hostGroupClient.NewListBySubscriptionPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list dedicated host groups", slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			for _, group := range page.Value {
+				if group.ID != nil && !c.resourceGroups.Matches(resourceGroupFromID(*group.ID)) {
+					continue
+				}
+				groups = append(groups, group)
+			}
+		}
+		return groups, nil
+	}
+
+	resourceGroups, err := c.listResourceGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, resourceGroup := range resourceGroups {
+		pager := c.hostGroupClient.NewListByResourceGroupPager(resourceGroup, nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list dedicated host groups", slog.String("resource_group", resourceGroup), slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			groups = append(groups, page.Value...)
+		}
+	}
+	return groups, nil
+}
+
+// hostClusterShares returns the share of a dedicated host of every cluster of the VMs placed on it, by their number of
+// VMs. The VMs are attributed to a cluster by their node resource group, VMs outside of a node resource group have an
+// empty cluster name, and so does a host without any VM.
+func hostClusterShares(host *armcompute.DedicatedHost, clustersByNodeResourceGroup map[string]string) map[string]float64 {
+	vms := make(map[string]int)
+	total := 0
+	if host.Properties != nil {
+		for _, vm := range host.Properties.VirtualMachines {
+			if vm == nil || vm.ID == nil {
+				continue
+			}
+			vms[clustersByNodeResourceGroup[resourceGroupFromID(*vm.ID)]]++
+			total++
+		}
+	}
+	if total == 0 {
+		return map[string]float64{"": 1}
+	}
+	shares := make(map[string]float64, len(vms))
+	for cluster, count := range vms {
+		shares[cluster] = float64(count) / float64(total)
+	}
+	return shares
+}
+
+// dedicatedHostMetrics returns the cost metrics of a dedicated host, one per cluster of the VMs placed on it, whose
+// sum is the price of the host. Nothing is returned when the host can't be priced, in which case it's recorded in
+// unpriced.
+func dedicatedHostMetrics(prices *PriceStore, host dedicatedHost, clustersByNodeResourceGroup map[string]string, unpriced *utils.UnpricedMachineTypes) []prometheus.Metric {
+	h := host.host
+	if h == nil || h.Name == nil || h.Location == nil || h.SKU == nil || h.SKU.Name == nil {
+		return nil
+	}
+	region, sku := *h.Location, *h.SKU.Name
+	price, err := prices.getDedicatedHostPrice(region, sku)
+	if err != nil {
+		unpriced.Add(region, sku, err)
+		return nil
+	}
+	unpriced.Priced()
+	shares := hostClusterShares(h, clustersByNodeResourceGroup)
+	clusters := make([]string, 0, len(shares))
+	for cluster := range shares {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	metrics := make([]prometheus.Metric, 0, len(clusters))
+	for _, cluster := range clusters {
+		metrics = append(metrics, prometheus.MustNewConstMetric(DedicatedHostHourlyCostDesc, prometheus.GaugeValue, price*shares[cluster], host.group, *h.Name, cluster, region, sku))
+	}
+	return metrics
+}
@@ -0,0 +1,189 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func Test_hostSkuKey(t *testing.T) {
+	assert.Equal(t, "dsv3type1", hostSkuKey("DSv3-Type1"))
+	assert.Equal(t, "dsv3type1", hostSkuKey("DSv3 Type1"))
+	assert.Equal(t, "esv5type2", hostSkuKey("Esv5_Type2"))
+}
+
+func TestPriceStore_addDedicatedHostPrice(t *testing.T) {
+	p := newPricingStore(testSubId, nil, testLogger, parentCtx)
+	malformed := MalformedPriceEntriesTotal.WithLabelValues(dedicatedHostPriceSource, utils.ReasonUnexpectedUnit)
+	before := testutil.ToFloat64(malformed)
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.192},
+		{ArmRegionName: "eastus", ProductName: "Dedicated Host", SkuName: "DSv3 Type1", UnitOfMeasure: "1 Hour", RetailPrice: 4.5},
+		{ArmRegionName: "eastus", ProductName: "Dedicated Host Windows", SkuName: "DSv3 Type1", UnitOfMeasure: "1 Hour", RetailPrice: 2},
+		{ArmRegionName: "eastus", ProductName: "Dedicated Host", SkuName: "ESv3 Type1", UnitOfMeasure: "1/Month", RetailPrice: 3000},
+	} {
+		p.addMachinePrice(item)
+	}
+
+	price, err := p.getDedicatedHostPrice("eastus", "DSv3-Type1")
+	require.NoError(t, err)
+	assert.Equal(t, 4.5, price, "the Windows license meter isn't the price of the host")
+	_, err = p.getDedicatedHostPrice("eastus", "ESv3-Type1")
+	assert.ErrorIs(t, err, ErrSkuNotFound)
+	assert.Equal(t, before+1, testutil.ToFloat64(malformed))
+	_, err = p.getDedicatedHostPrice("westeurope", "DSv3-Type1")
+	assert.ErrorIs(t, err, ErrRegionNotFound)
+
+	// The host meters aren't VM prices
+	assert.Len(t, p.RegionMap["eastus"][OnDemand][Linux], 1)
+}
+
+func testDedicatedHost(vmResourceGroups ...string) dedicatedHost {
+	var vms []*armcompute.SubResourceReadOnly
+	for i, resourceGroup := range vmResourceGroups {
+		vms = append(vms, &armcompute.SubResourceReadOnly{
+			ID: to.StringPtr("/subscriptions/" + testSubId + "/resourceGroups/" + resourceGroup + "/providers/Microsoft.Compute/virtualMachineScaleSets/aks-pool-vmss/virtualMachines/" + string(rune('0'+i))),
+		})
+	}
+	return dedicatedHost{
+		group: "compliance",
+		host: &armcompute.DedicatedHost{
+			Name:       to.StringPtr("host-1"),
+			Location:   to.StringPtr("eastus"),
+			SKU:        &armcompute.SKU{Name: to.StringPtr("DSv3-Type1")},
+			Properties: &armcompute.DedicatedHostProperties{VirtualMachines: vms},
+		},
+	}
+}
+
+func Test_dedicatedHostMetrics(t *testing.T) {
+	p := newPricingStore(testSubId, nil, testLogger, parentCtx)
+	p.addMachinePrice(retailPriceSdk.ResourceSKU{ArmRegionName: "eastus", ProductName: "Dedicated Host", SkuName: "DSv3 Type1", RetailPrice: 4})
+	clusters := map[string]string{"mc_prod_prod_eastus": "prod", "mc_dev_dev_eastus": "dev"}
+
+	for _, tc := range []struct {
+		name     string
+		host     dedicatedHost
+		expected map[string]float64
+	}{
+		{
+			name:     "host split between the clusters of its VMs",
+			host:     testDedicatedHost("MC_prod_prod_eastus", "MC_prod_prod_eastus", "MC_dev_dev_eastus", "standalone-rg"),
+			expected: map[string]float64{"prod": 2, "dev": 1, "": 1},
+		},
+		{
+			name:     "host without any VM",
+			host:     testDedicatedHost(),
+			expected: map[string]float64{"": 4},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			unpriced := NewUnpricedMachineTypes(subsystem)
+			got := map[string]float64{}
+			for _, metric := range dedicatedHostMetrics(p, tc.host, clusters, unpriced) {
+				result := utils.ReadMetrics(metric)
+				require.Equal(t, "cloudcost_azure_aks_dedicated_host_usd_per_hour", result.FqName)
+				assert.Equal(t, "host-1", result.Labels["host"])
+				assert.Equal(t, "compliance", result.Labels["host_group"])
+				got[result.Labels["cluster_name"]] = result.Value
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+
+	host := testDedicatedHost("MC_prod_prod_eastus")
+	host.host.SKU.Name = to.StringPtr("FSv2-Type2")
+	assert.Empty(t, dedicatedHostMetrics(p, host, clusters, NewUnpricedMachineTypes(subsystem)), "a host that can't be priced isn't exported")
+}
+
+func Test_listDedicatedHosts(t *testing.T) {
+	subscriptionPath := "/subscriptions/" + testSubId
+	hostGroupsPath := "/providers/Microsoft.Compute/hostGroups"
+	hostGroup := func(resourceGroup string, name string) map[string]any {
+		return map[string]any{"id": subscriptionPath + "/resourceGroups/" + resourceGroup + hostGroupsPath + "/" + name, "name": name}
+	}
+	responses := map[string]any{
+		subscriptionPath + hostGroupsPath: map[string]any{"value": []any{
+			hostGroup("Hosts-RG", "compliance"),
+			hostGroup("shared-rg", "legacy"),
+		}},
+		subscriptionPath + "/resourcegroups": map[string]any{"value": []any{
+			map[string]any{"name": "Hosts-RG"},
+			map[string]any{"name": "shared-rg"},
+		}},
+		subscriptionPath + "/resourceGroups/Hosts-RG" + hostGroupsPath: map[string]any{"value": []any{
+			hostGroup("Hosts-RG", "compliance"),
+		}},
+		subscriptionPath + "/resourceGroups/Hosts-RG" + hostGroupsPath + "/compliance/hosts": map[string]any{"value": []any{
+			map[string]any{"name": "host-1"},
+			map[string]any{"name": "host-2"},
+		}},
+		subscriptionPath + "/resourceGroups/shared-rg" + hostGroupsPath + "/legacy/hosts": map[string]any{"value": []any{
+			map[string]any{"name": "host-3"},
+		}},
+	}
+	for _, tc := range []struct {
+		name          string
+		filter        *ResourceGroupFilter
+		expected      []string
+		expectedPaths []string
+	}{
+		{
+			name:     "no filter lists the hosts of every host group",
+			expected: []string{"compliance/host-1", "compliance/host-2", "legacy/host-3"},
+			expectedPaths: []string{
+				subscriptionPath + hostGroupsPath,
+				subscriptionPath + "/resourceGroups/Hosts-RG" + hostGroupsPath + "/compliance/hosts",
+				subscriptionPath + "/resourceGroups/shared-rg" + hostGroupsPath + "/legacy/hosts",
+			},
+		},
+		{
+			name:     "inclusions only list the host groups of the matching resource groups",
+			filter:   &ResourceGroupFilter{Include: []string{"hosts-*"}},
+			expected: []string{"compliance/host-1", "compliance/host-2"},
+			expectedPaths: []string{
+				subscriptionPath + "/resourcegroups",
+				subscriptionPath + "/resourceGroups/Hosts-RG" + hostGroupsPath,
+				subscriptionPath + "/resourceGroups/Hosts-RG" + hostGroupsPath + "/compliance/hosts",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &fakeTransport{responses: responses}
+			options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+			rgClient, err := armresources.NewResourceGroupsClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			hostGroupClient, err := armcompute.NewDedicatedHostGroupsClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			hostClient, err := armcompute.NewDedicatedHostsClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			c := &Collector{
+				context:             parentCtx,
+				logger:              testLogger,
+				resourceGroupClient: rgClient,
+				hostGroupClient:     hostGroupClient,
+				hostClient:          hostClient,
+				resourceGroups:      tc.filter,
+			}
+
+			hosts, err := c.listDedicatedHosts()
+			require.NoError(t, err)
+			var names []string
+			for _, host := range hosts {
+				names = append(names, host.group+"/"+*host.host.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+			assert.Equal(t, tc.expectedPaths, transport.paths)
+		})
+	}
+}
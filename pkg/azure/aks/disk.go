@@ -0,0 +1,233 @@
+package aks
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	// The Azure Disk CSI driver tags the disks it provisions with the persistent volume and claim they back.
+	pvNameTag       = "kubernetes.io-created-for-pv-name"
+	pvcNamespaceTag = "kubernetes.io-created-for-pvc-namespace"
+)
+
+var ErrUnknownDiskTier = errors.New("unknown disk performance tier")
+
+var (
+	PersistentVolumeHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "persistent_volume_usd_per_hour"),
+		"The cost of an AKS persistent volume in USD/h. Managed disks are billed per performance tier rather than per GiB, so the cost is the price of the tier of the disk.",
+		[]string{"cluster_name", "namespace", "persistentvolume", "disk", "region", "zone", "storage_class", "disk_tier"},
+		nil,
+	)
+	PersistentVolumeSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "persistent_volume_size_gib"),
+		"The provisioned size of the managed disk backing an AKS persistent volume in GiB.",
+		[]string{"cluster_name", "namespace", "persistentvolume", "disk", "region", "zone", "storage_class"},
+		nil,
+	)
+)
+
+// listDisks lists the managed disks backing persistent volumes in the resource groups selected by the resource group
+// filter. Disks that weren't provisioned by the Azure Disk CSI driver, eg OS disks, are skipped.
+func (c *Collector) listDisks() ([]*armcompute.Disk, error) {
+	var disks []*armcompute.Disk
+	if c.resourceGroups.listsAll() {
+		pager := c.diskClient.NewListPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list disks", slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			for _, disk := range page.Value {
+				if disk.ID != nil && !c.resourceGroups.Matches(resourceGroupFromID(*disk.ID)) {
+					continue
+				}
+				if isPersistentVolume(disk) {
+					disks = append(disks, disk)
+				}
+			}
+		}
+		return disks, nil
+	}
+
+	resourceGroups, err := c.listResourceGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, resourceGroup := range resourceGroups {
+		pager := c.diskClient.NewListByResourceGroupPager(resourceGroup, nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list disks", slog.String("resource_group", resourceGroup), slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			for _, disk := range page.Value {
+				if isPersistentVolume(disk) {
+					disks = append(disks, disk)
+				}
+			}
+		}
+	}
+	return disks, nil
+}
+
+func isPersistentVolume(disk *armcompute.Disk) bool {
+	if disk == nil {
+		return false
+	}
+	name, ok := disk.Tags[pvNameTag]
+	return ok && name != nil
+}
+
+// diskTier returns the performance tier a disk is billed at, eg P10. The tier reported by Azure is used when set, as
+// it can be raised above the tier of the provisioned size. Otherwise it's the smallest tier of the storage account type
+// fitting the provisioned size.
+func diskTier(disk *armcompute.Disk) (string, error) {
+	if disk.Properties != nil && disk.Properties.Tier != nil && *disk.Properties.Tier != "" {
+		return *disk.Properties.Tier, nil
+	}
+	if disk.SKU == nil || disk.SKU.Name == nil || disk.Properties == nil || disk.Properties.DiskSizeGB == nil {
+		return "", ErrUnknownDiskTier
+	}
+	return diskTierForSize(string(*disk.SKU.Name), *disk.Properties.DiskSizeGB)
+}
+
+// diskTierForSize returns the smallest performance tier of a storage account type, eg Premium_LRS, fitting a size in
+// GiB.
+func diskTierForSize(storageClass string, sizeGiB int32) (string, error) {
+	accountType, _, _ := strings.Cut(storageClass, "_")
+	prefix := ""
+	for p, t := range diskTierAccountTypes {
+		if t == accountType {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return "", ErrUnknownDiskTier
+	}
+	tiers := make([]string, 0, len(diskTierSizesGiB))
+	for tier := range diskTierSizesGiB {
+		tiers = append(tiers, tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return diskTierSizesGiB[tiers[i]] < diskTierSizesGiB[tiers[j]] })
+	size := float64(sizeGiB)
+	for _, tier := range tiers {
+		// Standard SSD and Standard HDD disks have no 1, 2 and 3 tiers, smaller disks are billed as a 4
+		if prefix != "P" {
+			if n, _ := strconv.Atoi(tier); n < 4 {
+				continue
+			}
+		}
+		if diskTierSizesGiB[tier] >= size {
+			return prefix + tier, nil
+		}
+	}
+	return "", ErrUnknownDiskTier
+}
+
+// persistentVolumeMetric returns the cost metric of a disk backing a persistent volume. classes are the storage
+// classes of the persistent volumes keyed by name, which adjust the catalog price of their disks. The second return
+// value is false when the disk can't be priced, in which case it's recorded in unpriced.
+func persistentVolumeMetric(prices *VolumePriceStore, disk *armcompute.Disk, clusterName string, classes map[string]storageclass.Class, unpriced *utils.UnpricedMachineTypes) (prometheus.Metric, bool) {
+	if disk.Location == nil || disk.SKU == nil || disk.SKU.Name == nil {
+		return nil, false
+	}
+	region, storageClass := *disk.Location, string(*disk.SKU.Name)
+	class, hasClass := classes[tagValue(disk.Tags, pvNameTag)]
+	tier, price, err := catalogVolumePrice(prices, disk, region, storageClass)
+	if err != nil && !(hasClass && class.OverridesCatalog()) {
+		unpriced.AddResource(utils.ResourceTypeDisk, err)
+		return nil, false
+	}
+	if hasClass {
+		var sizeGiB float64
+		if disk.Properties != nil && disk.Properties.DiskSizeGB != nil {
+			sizeGiB = float64(*disk.Properties.DiskSizeGB)
+		}
+		price = class.Cost(sizeGiB, price)
+	}
+	unpriced.Priced()
+	return prometheus.MustNewConstMetric(PersistentVolumeHourlyCostDesc, prometheus.GaugeValue, price,
+		clusterName,
+		tagValue(disk.Tags, pvcNamespaceTag),
+		tagValue(disk.Tags, pvNameTag),
+		diskName(disk),
+		region,
+		diskZone(disk, region),
+		storageClass,
+		tier,
+	), true
+}
+
+// persistentVolumeSizeMetric returns the provisioned size of a disk backing a persistent volume, whether it can be
+// priced or not. The second return value is false when Azure didn't report the size of the disk.
+func persistentVolumeSizeMetric(disk *armcompute.Disk, clusterName string) (prometheus.Metric, bool) {
+	if disk.Location == nil || disk.SKU == nil || disk.SKU.Name == nil || disk.Properties == nil || disk.Properties.DiskSizeGB == nil {
+		return nil, false
+	}
+	return prometheus.MustNewConstMetric(PersistentVolumeSizeDesc, prometheus.GaugeValue, float64(*disk.Properties.DiskSizeGB),
+		clusterName,
+		tagValue(disk.Tags, pvcNamespaceTag),
+		tagValue(disk.Tags, pvNameTag),
+		diskName(disk),
+		*disk.Location,
+		diskZone(disk, *disk.Location),
+		string(*disk.SKU.Name),
+	), true
+}
+
+// catalogVolumePrice returns the performance tier of a disk and its hourly retail price.
+func catalogVolumePrice(prices *VolumePriceStore, disk *armcompute.Disk, region string, storageClass string) (string, float64, error) {
+	tier, err := diskTier(disk)
+	if err != nil {
+		return "", 0, err
+	}
+	skuName, ok := VolumeSkuName(tier, storageClass)
+	if !ok {
+		return tier, 0, ErrSkuNotFound
+	}
+	burstingEnabled := disk.Properties != nil && disk.Properties.BurstingEnabled != nil && *disk.Properties.BurstingEnabled
+	price, err := prices.GetVolumePrice(region, skuName, burstingEnabled)
+	if err != nil {
+		return tier, 0, err
+	}
+	return tier, price, nil
+}
+
+// diskName returns the name of a managed disk, which differs from the name of its persistent volume when the volume
+// was provisioned statically.
+func diskName(disk *armcompute.Disk) string {
+	if disk.Name == nil {
+		return ""
+	}
+	return *disk.Name
+}
+
+// diskZone returns the zone of a zonal disk the way Kubernetes labels it, eg eastus-1, and an empty string for a disk
+// that isn't pinned to a zone.
+func diskZone(disk *armcompute.Disk, region string) string {
+	if len(disk.Zones) == 0 || disk.Zones[0] == nil {
+		return ""
+	}
+	return region + "-" + *disk.Zones[0]
+}
+
+func tagValue(tags map[string]*string, key string) string {
+	if value, ok := tags[key]; ok && value != nil {
+		return *value
+	}
+	return ""
+}
@@ -0,0 +1,261 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testDisk(accountType armcompute.DiskStorageAccountTypes, tier *string, sizeGB int32) *armcompute.Disk {
+	return &armcompute.Disk{
+		ID:       to.StringPtr("/subscriptions/" + testSubId + "/resourceGroups/MC_prod_cluster_eastus/providers/Microsoft.Compute/disks/pvc-1234"),
+		Name:     to.StringPtr("pvc-1234"),
+		Location: to.StringPtr("eastus"),
+		SKU:      &armcompute.DiskSKU{Name: &accountType},
+		Tags: map[string]*string{
+			pvNameTag:       to.StringPtr("pvc-1234"),
+			pvcNamespaceTag: to.StringPtr("monitoring"),
+		},
+		Properties: &armcompute.DiskProperties{Tier: tier, DiskSizeGB: &sizeGB},
+	}
+}
+
+func Test_diskTier(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		disk     *armcompute.Disk
+		expected string
+		err      error
+	}{
+		{
+			name:     "tier reported by azure",
+			disk:     testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, to.StringPtr("P30"), 100),
+			expected: "P30",
+		},
+		{
+			name:     "premium tier fitting the size",
+			disk:     testDisk(armcompute.DiskStorageAccountTypesPremiumZRS, nil, 100),
+			expected: "P10",
+		},
+		{
+			name:     "small premium disk",
+			disk:     testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 4),
+			expected: "P1",
+		},
+		{
+			name:     "small standard ssd disk is billed as a 4",
+			disk:     testDisk(armcompute.DiskStorageAccountTypesStandardSSDLRS, nil, 4),
+			expected: "E4",
+		},
+		{
+			name:     "standard hdd",
+			disk:     testDisk(armcompute.DiskStorageAccountTypesStandardLRS, nil, 1024),
+			expected: "S30",
+		},
+		{
+			name: "ultra disks aren't billed per tier",
+			disk: testDisk(armcompute.DiskStorageAccountTypesUltraSSDLRS, nil, 100),
+			err:  ErrUnknownDiskTier,
+		},
+		{
+			name: "larger than every tier",
+			disk: testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 65536),
+			err:  ErrUnknownDiskTier,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tier, err := diskTier(tc.disk)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, tier)
+		})
+	}
+}
+
+func Test_persistentVolumeMetric(t *testing.T) {
+	diskPrice, burstEnablement := 19.71, 7.3
+	prices := newVolumePriceStore(nil, testLogger, parentCtx)
+	prices.RegionMap["eastus"] = VolumePriceBySku{"P10 LRS": {Disk: diskPrice, BurstEnablement: burstEnablement}}
+	for _, tc := range []struct {
+		name     string
+		disk     *armcompute.Disk
+		classes  map[string]storageclass.Class
+		expected *utils.MetricResult
+	}{
+		{
+			name: "priced disk",
+			disk: testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 128),
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_persistent_volume_usd_per_hour",
+				Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "", "storage_class": "Premium_LRS", "disk_tier": "P10"},
+				Value:      diskPrice / utils.HoursInMonth,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name: "bursting enabled",
+			disk: func() *armcompute.Disk {
+				disk := testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 128)
+				disk.Properties.BurstingEnabled = to.BoolPtr(true)
+				return disk
+			}(),
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_persistent_volume_usd_per_hour",
+				Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "", "storage_class": "Premium_LRS", "disk_tier": "P10"},
+				Value:      (diskPrice + burstEnablement) / utils.HoursInMonth,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name: "zonal disk",
+			disk: func() *armcompute.Disk {
+				disk := testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 128)
+				disk.Zones = []*string{to.StringPtr("2")}
+				return disk
+			}(),
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_persistent_volume_usd_per_hour",
+				Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "eastus-2", "storage_class": "Premium_LRS", "disk_tier": "P10"},
+				Value:      diskPrice / utils.HoursInMonth,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name: "sku not found",
+			disk: testDisk(armcompute.DiskStorageAccountTypesPremiumZRS, nil, 128),
+		},
+		{
+			name:    "storage class with provisioned performance",
+			disk:    testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 128),
+			classes: map[string]storageclass.Class{"pvc-1234": {Name: "fast", Provider: storageclass.ProviderAzure, USDPerHour: 0.01}},
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_persistent_volume_usd_per_hour",
+				Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "", "storage_class": "Premium_LRS", "disk_tier": "P10"},
+				Value:      diskPrice/utils.HoursInMonth + 0.01,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name:    "storage class with a negotiated rate of an unpriced sku",
+			disk:    testDisk(armcompute.DiskStorageAccountTypesPremiumZRS, nil, 128),
+			classes: map[string]storageclass.Class{"pvc-1234": {Name: "zrs", Provider: storageclass.ProviderAzure, USDPerGiBHour: 0.001}},
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_persistent_volume_usd_per_hour",
+				Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "", "storage_class": "Premium_ZRS", "disk_tier": "P10"},
+				Value:      0.128,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name: "unknown tier",
+			disk: testDisk(armcompute.DiskStorageAccountTypesUltraSSDLRS, nil, 128),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			metric, ok := persistentVolumeMetric(prices, tc.disk, "prod", tc.classes, NewUnpricedMachineTypes("test"))
+			assert.Equal(t, tc.expected != nil, ok)
+			assert.Equal(t, tc.expected, utils.ReadMetrics(metric))
+		})
+	}
+}
+
+func Test_persistentVolumeSizeMetric(t *testing.T) {
+	metric, ok := persistentVolumeSizeMetric(testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 128), "prod")
+	require.True(t, ok)
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_azure_aks_persistent_volume_size_gib",
+		Labels:     utils.LabelMap{"cluster_name": "prod", "namespace": "monitoring", "persistentvolume": "pvc-1234", "disk": "pvc-1234", "region": "eastus", "zone": "", "storage_class": "Premium_LRS"},
+		Value:      128,
+		MetricType: prometheus.GaugeValue,
+	}, utils.ReadMetrics(metric))
+
+	disk := testDisk(armcompute.DiskStorageAccountTypesPremiumLRS, nil, 128)
+	disk.Properties.DiskSizeGB = nil
+	_, ok = persistentVolumeSizeMetric(disk, "prod")
+	assert.False(t, ok)
+}
+
+func Test_listDisks(t *testing.T) {
+	subscriptionPath := "/subscriptions/" + testSubId
+	disksPath := "/providers/Microsoft.Compute/disks"
+	disk := func(resourceGroup string, name string, tags map[string]any) map[string]any {
+		return map[string]any{"id": subscriptionPath + "/resourceGroups/" + resourceGroup + disksPath + "/" + name, "name": name, "tags": tags}
+	}
+	pvTags := map[string]any{pvNameTag: "pvc-1234"}
+	responses := map[string]any{
+		subscriptionPath + disksPath: map[string]any{"value": []any{
+			disk("MC_prod_cluster_eastus", "pvc-1234", pvTags),
+			disk("MC_prod_cluster_eastus", "aks-default-osdisk", nil),
+			disk("shared-rg", "pvc-5678", pvTags),
+		}},
+		subscriptionPath + "/resourcegroups": map[string]any{"value": []any{
+			map[string]any{"name": "MC_prod_cluster_eastus"},
+			map[string]any{"name": "shared-rg"},
+		}},
+		subscriptionPath + "/resourceGroups/MC_prod_cluster_eastus" + disksPath: map[string]any{"value": []any{
+			disk("MC_prod_cluster_eastus", "pvc-1234", pvTags),
+			disk("MC_prod_cluster_eastus", "aks-default-osdisk", nil),
+		}},
+	}
+	for _, tc := range []struct {
+		name          string
+		filter        *ResourceGroupFilter
+		expected      []string
+		expectedPaths []string
+	}{
+		{
+			name:          "no filter lists the persistent volumes of every resource group",
+			expected:      []string{"pvc-1234", "pvc-5678"},
+			expectedPaths: []string{subscriptionPath + disksPath},
+		},
+		{
+			name:          "exclusions are applied to every disk",
+			filter:        &ResourceGroupFilter{Exclude: []string{"shared-*"}},
+			expected:      []string{"pvc-1234"},
+			expectedPaths: []string{subscriptionPath + disksPath},
+		},
+		{
+			name:     "inclusions only list the matching resource groups",
+			filter:   &ResourceGroupFilter{Include: []string{"mc_*"}},
+			expected: []string{"pvc-1234"},
+			expectedPaths: []string{
+				subscriptionPath + "/resourcegroups",
+				subscriptionPath + "/resourceGroups/MC_prod_cluster_eastus" + disksPath,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &fakeTransport{responses: responses}
+			options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+			rgClient, err := armresources.NewResourceGroupsClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			diskClient, err := armcompute.NewDisksClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			c := &Collector{
+				context:             parentCtx,
+				logger:              testLogger,
+				resourceGroupClient: rgClient,
+				diskClient:          diskClient,
+				resourceGroups:      tc.filter,
+			}
+
+			disks, err := c.listDisks()
+			require.NoError(t, err)
+			var names []string
+			for _, disk := range disks {
+				names = append(names, *disk.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+			assert.Equal(t, tc.expectedPaths, transport.paths)
+		})
+	}
+}
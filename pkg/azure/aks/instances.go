@@ -0,0 +1,145 @@
+package aks
+
+import (
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/reservations"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	PriceTierOnDemand = "ondemand"
+	PriceTierSpot     = "spot"
+)
+
+var (
+	// InstanceHourlyCostDesc and InstancesDesc break the VMs of a scale set down by price tier, the cost of the VMs of
+	// a tier being the product of both.
+	InstanceHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_usd_per_hour"),
+		"The effective cost of each VM of a scale set at a price tier in USD/h. The VMs covered by a reservation or a savings plan are priced at its rate plus their Windows license, the others at the retail on-demand or spot price.",
+		[]string{"vmss", "cluster_name", "region", "machine_type", "price_tier"},
+		nil,
+	)
+	InstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instances"),
+		"The number of VMs of a scale set at a price tier.",
+		[]string{"vmss", "cluster_name", "region", "machine_type", "price_tier"},
+		nil,
+	)
+)
+
+// tierCost is the number of VMs of a scale set at a price tier and their cost in USD/h.
+type tierCost struct {
+	instances int64
+	cost      float64
+}
+
+// scaleSetInstances are the VMs of a scale set broken down by price tier.
+type scaleSetInstances struct {
+	name        string
+	clusterName string
+	region      string
+	sku         string
+	// onDemandPrice is the on-demand price of a VM, license included, and license the price of the Windows license
+	// on top of the compute price, which is billed whatever covers the compute.
+	onDemandPrice float64
+	license       float64
+	// onDemand is the number of VMs that aren't covered by a reservation or a savings plan, and aren't spot VMs.
+	onDemand int64
+	tiers    map[string]*tierCost
+}
+
+func (s *scaleSetInstances) add(tier string, n int64, price float64) {
+	t, ok := s.tiers[tier]
+	if !ok {
+		t = &tierCost{}
+		s.tiers[tier] = t
+	}
+	t.instances += n
+	t.cost += float64(n) * price
+}
+
+// newScaleSetInstances returns the VMs of a scale set, either spot VMs or on-demand VMs left for the reservations and
+// savings plans to cover. nil is returned for scale sets without any VM, for scale sets placed on a dedicated host,
+// whose VMs are billed through their host, and for scale sets that can't be priced. The on-demand scale sets that
+// can't be priced are recorded in unpriced, the spot ones already are by spotPriceMetrics.
+func newScaleSetInstances(prices *PriceStore, vmss *armcompute.VirtualMachineScaleSet, clusterName string, unpriced *utils.UnpricedMachineTypes) *scaleSetInstances {
+	if vmss == nil || vmss.Name == nil || vmss.Location == nil || vmss.SKU == nil || vmss.SKU.Name == nil || vmss.SKU.Capacity == nil || *vmss.SKU.Capacity <= 0 {
+		return nil
+	}
+	if vmss.Properties != nil && vmss.Properties.HostGroup != nil {
+		return nil
+	}
+	s := &scaleSetInstances{
+		name:        *vmss.Name,
+		clusterName: clusterName,
+		region:      *vmss.Location,
+		sku:         *vmss.SKU.Name,
+		tiers:       make(map[string]*tierCost),
+	}
+	os := operatingSystem(vmss)
+	if isSpot(vmss) {
+		price, err := prices.getPrice(s.region, Spot, os, s.sku)
+		if err != nil {
+			return nil
+		}
+		s.add(PriceTierSpot, *vmss.SKU.Capacity, price)
+		return s
+	}
+	price, err := prices.getPrice(s.region, OnDemand, os, s.sku)
+	if err != nil {
+		unpriced.Add(s.region, s.sku, err)
+		return nil
+	}
+	unpriced.Priced()
+	s.onDemandPrice, s.onDemand = price, *vmss.SKU.Capacity
+	if os == Windows {
+		if linux, err := prices.getPrice(s.region, OnDemand, Linux, s.sku); err == nil && linux < price {
+			s.license = price - linux
+		}
+	}
+	return s
+}
+
+// instanceMetrics returns the effective cost and the number of the VMs of every scale set by price tier. The on-demand
+// VMs are covered by the reservations first, then by the savings plans, when benefits is set.
+func instanceMetrics(prices *PriceStore, vmss []*armcompute.VirtualMachineScaleSet, clusters map[string]string, benefits *reservations.Benefits, unpriced *utils.UnpricedMachineTypes) []prometheus.Metric {
+	var scaleSets []*scaleSetInstances
+	for _, v := range vmss {
+		if s := newScaleSetInstances(prices, v, ClusterNameFromVmss(v, clusters), unpriced); s != nil {
+			scaleSets = append(scaleSets, s)
+		}
+	}
+	// The scale sets are covered in a stable order so that the coverage doesn't move between collections
+	sort.Slice(scaleSets, func(i, j int) bool {
+		if scaleSets[i].clusterName != scaleSets[j].clusterName {
+			return scaleSets[i].clusterName < scaleSets[j].clusterName
+		}
+		return scaleSets[i].name < scaleSets[j].name
+	})
+	applyBenefits(prices, scaleSets, benefits)
+
+	var metrics []prometheus.Metric
+	for _, s := range scaleSets {
+		if s.onDemand > 0 {
+			s.add(PriceTierOnDemand, s.onDemand, s.onDemandPrice)
+		}
+		for _, tier := range []string{PriceTierOnDemand, PriceTierReserved, PriceTierSavingsPlan, PriceTierSpot} {
+			t, ok := s.tiers[tier]
+			if !ok || t.instances == 0 {
+				continue
+			}
+			labelValues := []string{s.name, s.clusterName, s.region, s.sku, tier}
+			metrics = append(metrics,
+				prometheus.MustNewConstMetric(InstanceHourlyCostDesc, prometheus.GaugeValue, t.cost/float64(t.instances), labelValues...),
+				prometheus.MustNewConstMetric(InstancesDesc, prometheus.GaugeValue, float64(t.instances), labelValues...),
+			)
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,103 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testScaleSet(name string, sku string, capacity int64, osType armcompute.OperatingSystemTypes) *armcompute.VirtualMachineScaleSet {
+	return &armcompute.VirtualMachineScaleSet{
+		Name:     to.StringPtr(name),
+		Location: to.StringPtr("eastus"),
+		SKU:      &armcompute.SKU{Name: to.StringPtr(sku), Capacity: to.Int64Ptr(capacity)},
+		Tags:     map[string]*string{AksClusterNameTag: to.StringPtr("prod")},
+		Properties: &armcompute.VirtualMachineScaleSetProperties{
+			VirtualMachineProfile: &armcompute.VirtualMachineScaleSetVMProfile{
+				StorageProfile: &armcompute.VirtualMachineScaleSetStorageProfile{
+					OSDisk: &armcompute.VirtualMachineScaleSetOSDisk{OSType: &osType},
+				},
+			},
+		},
+	}
+}
+
+func retailPriceStore() *PriceStore {
+	p := newPricingStore(testSubId, nil, testLogger, parentCtx)
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.2},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series Windows", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.3},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.05},
+	} {
+		p.addMachinePrice(item)
+	}
+	return p
+}
+
+func Test_newScaleSetInstances(t *testing.T) {
+	p := retailPriceStore()
+	windows := newScaleSetInstances(p, testScaleSet("aks-win-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesWindows), "prod", NewUnpricedMachineTypes(subsystem))
+	require.NotNil(t, windows)
+	assert.Equal(t, int64(2), windows.onDemand)
+	assert.Equal(t, 0.3, windows.onDemandPrice)
+	assert.InDelta(t, 0.1, windows.license, 1e-9, "the license is the difference between the Windows and Linux prices")
+
+	spot := testScaleSet("aks-spot-vmss", "Standard_D4_v5", 3, armcompute.OperatingSystemTypesLinux)
+	spotPriority := armcompute.VirtualMachinePriorityTypesSpot
+	spot.Properties.VirtualMachineProfile.Priority = &spotPriority
+	s := newScaleSetInstances(p, spot, "prod", NewUnpricedMachineTypes(subsystem))
+	require.NotNil(t, s)
+	assert.Zero(t, s.onDemand, "spot VMs aren't left for the benefits to cover")
+	require.Contains(t, s.tiers, PriceTierSpot)
+	assert.Equal(t, int64(3), s.tiers[PriceTierSpot].instances)
+	assert.InDelta(t, 0.15, s.tiers[PriceTierSpot].cost, 1e-9)
+
+	dedicated := testScaleSet("aks-dedicated-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesLinux)
+	dedicated.Properties.HostGroup = &armcompute.SubResource{ID: to.StringPtr("compliance")}
+	assert.Nil(t, newScaleSetInstances(p, dedicated, "prod", NewUnpricedMachineTypes(subsystem)), "the VMs on a dedicated host are billed through the host")
+	assert.Nil(t, newScaleSetInstances(p, testScaleSet("aks-empty-vmss", "Standard_D4_v5", 0, armcompute.OperatingSystemTypesLinux), "prod", NewUnpricedMachineTypes(subsystem)))
+	assert.Nil(t, newScaleSetInstances(p, &armcompute.VirtualMachineScaleSet{}, "prod", NewUnpricedMachineTypes(subsystem)))
+}
+
+func Test_instanceMetrics(t *testing.T) {
+	p := retailPriceStore()
+	spot := testScaleSet("aks-spot-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesLinux)
+	spotPriority := armcompute.VirtualMachinePriorityTypesSpot
+	spot.Properties.VirtualMachineProfile.Priority = &spotPriority
+	vmss := []*armcompute.VirtualMachineScaleSet{
+		testScaleSet("aks-win-vmss", "Standard_D4_v5", 2, armcompute.OperatingSystemTypesWindows),
+		testScaleSet("aks-linux-vmss", "Standard_D4_v5", 4, armcompute.OperatingSystemTypesLinux),
+		testScaleSet("aks-unpriced-vmss", "Standard_E4_v5", 1, armcompute.OperatingSystemTypesLinux),
+		spot,
+	}
+
+	unpriced := NewUnpricedMachineTypes(subsystem)
+	var got []*utils.MetricResult
+	for _, metric := range instanceMetrics(p, vmss, nil, nil, unpriced) {
+		got = append(got, utils.ReadMetrics(metric))
+	}
+	labels := func(name string, tier string) utils.LabelMap {
+		return utils.LabelMap{"vmss": name, "cluster_name": "prod", "region": "eastus", "machine_type": "Standard_D4_v5", "price_tier": tier}
+	}
+	// The scale sets are sorted by cluster and name, and the unpriced one isn't exported
+	assert.Equal(t, []*utils.MetricResult{
+		{FqName: "cloudcost_azure_aks_instance_usd_per_hour", Labels: labels("aks-linux-vmss", PriceTierOnDemand), Value: 0.2, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_azure_aks_instances", Labels: labels("aks-linux-vmss", PriceTierOnDemand), Value: 4, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_azure_aks_instance_usd_per_hour", Labels: labels("aks-spot-vmss", PriceTierSpot), Value: 0.05, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_azure_aks_instances", Labels: labels("aks-spot-vmss", PriceTierSpot), Value: 2, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_azure_aks_instance_usd_per_hour", Labels: labels("aks-win-vmss", PriceTierOnDemand), Value: 0.3, MetricType: prometheus.GaugeValue},
+		{FqName: "cloudcost_azure_aks_instances", Labels: labels("aks-win-vmss", PriceTierOnDemand), Value: 2, MetricType: prometheus.GaugeValue},
+	}, got)
+
+	ch := make(chan prometheus.Metric, 2)
+	unpriced.Emit(ch)
+	close(ch)
+	assert.Equal(t, utils.LabelMap{"collector": subsystem, "region": "eastus", "machine_type": "Standard_E4_v5", "reason": "sku_not_found"}, utils.ReadMetrics(<-ch).Labels)
+}
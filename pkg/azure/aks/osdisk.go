@@ -0,0 +1,82 @@
+package aks
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	// osDiskTypeEphemeral OS disks are stored on the local storage of the VM, which is included in the price of the VM.
+	osDiskTypeEphemeral = "ephemeral"
+	// osDiskTypeManaged OS disks are managed disks billed per performance tier, like the disks of persistent volumes.
+	osDiskTypeManaged = "managed"
+)
+
+var (
+	OSDiskHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "os_disk_usd_per_hour"),
+		"The cost of the OS disk of each VM of a scale set in USD/h. Ephemeral OS disks are free, managed OS disks are billed at the price of their performance tier.",
+		[]string{"vmss", "cluster_name", "region", "storage_class", "disk_tier", "os_disk_type"},
+		nil,
+	)
+)
+
+// osDiskType returns whether the OS disks of the VMs of a scale set are ephemeral or managed disks, which is the
+// default.
+func osDiskType(vmss *armcompute.VirtualMachineScaleSet) string {
+	profile := vmssProfile(vmss)
+	if profile == nil || profile.StorageProfile == nil || profile.StorageProfile.OSDisk == nil {
+		return osDiskTypeManaged
+	}
+	settings := profile.StorageProfile.OSDisk.DiffDiskSettings
+	if settings != nil && settings.Option != nil && *settings.Option == armcompute.DiffDiskOptionsLocal {
+		return osDiskTypeEphemeral
+	}
+	return osDiskTypeManaged
+}
+
+// osDiskMetric returns the cost metric of the OS disk of each VM of a scale set. Ephemeral OS disks cost nothing and
+// don't have a storage class or tier. The second return value is false when a managed OS disk can't be priced, in which
+// case it's recorded in unpriced.
+func osDiskMetric(prices *VolumePriceStore, vmss *armcompute.VirtualMachineScaleSet, clusterName string, unpriced *utils.UnpricedMachineTypes) (prometheus.Metric, bool) {
+	if vmss == nil || vmss.Name == nil || vmss.Location == nil {
+		return nil, false
+	}
+	name, region := *vmss.Name, *vmss.Location
+	diskType := osDiskType(vmss)
+	if diskType == osDiskTypeEphemeral {
+		unpriced.Priced()
+		return prometheus.MustNewConstMetric(OSDiskHourlyCostDesc, prometheus.GaugeValue, 0, name, clusterName, region, "", "", diskType), true
+	}
+
+	profile := vmssProfile(vmss)
+	if profile == nil || profile.StorageProfile == nil || profile.StorageProfile.OSDisk == nil {
+		return nil, false
+	}
+	osDisk := profile.StorageProfile.OSDisk
+	if osDisk.ManagedDisk == nil || osDisk.ManagedDisk.StorageAccountType == nil || osDisk.DiskSizeGB == nil {
+		unpriced.AddResource(utils.ResourceTypeDisk, ErrUnknownDiskTier)
+		return nil, false
+	}
+	storageClass := string(*osDisk.ManagedDisk.StorageAccountType)
+	tier, err := diskTierForSize(storageClass, *osDisk.DiskSizeGB)
+	if err != nil {
+		unpriced.AddResource(utils.ResourceTypeDisk, err)
+		return nil, false
+	}
+	skuName, ok := VolumeSkuName(tier, storageClass)
+	if !ok {
+		unpriced.AddResource(utils.ResourceTypeDisk, ErrSkuNotFound)
+		return nil, false
+	}
+	price, err := prices.GetVolumePrice(region, skuName, false)
+	if err != nil {
+		unpriced.AddResource(utils.ResourceTypeDisk, err)
+		return nil, false
+	}
+	unpriced.Priced()
+	return prometheus.MustNewConstMetric(OSDiskHourlyCostDesc, prometheus.GaugeValue, price, name, clusterName, region, storageClass, tier, diskType), true
+}
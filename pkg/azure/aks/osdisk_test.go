@@ -0,0 +1,82 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func osDiskScaleSet(osDisk *armcompute.VirtualMachineScaleSetOSDisk) *armcompute.VirtualMachineScaleSet {
+	return &armcompute.VirtualMachineScaleSet{
+		Name:     to.StringPtr("aks-system-1234-vmss"),
+		Location: to.StringPtr("eastus"),
+		Properties: &armcompute.VirtualMachineScaleSetProperties{
+			VirtualMachineProfile: &armcompute.VirtualMachineScaleSetVMProfile{
+				StorageProfile: &armcompute.VirtualMachineScaleSetStorageProfile{OSDisk: osDisk},
+			},
+		},
+	}
+}
+
+func managedOSDisk(accountType armcompute.StorageAccountTypes, sizeGB int32) *armcompute.VirtualMachineScaleSetOSDisk {
+	return &armcompute.VirtualMachineScaleSetOSDisk{
+		DiskSizeGB:  &sizeGB,
+		ManagedDisk: &armcompute.VirtualMachineScaleSetManagedDiskParameters{StorageAccountType: &accountType},
+	}
+}
+
+func Test_osDiskMetric(t *testing.T) {
+	diskPrice := 19.71
+	prices := newVolumePriceStore(nil, testLogger, parentCtx)
+	prices.RegionMap["eastus"] = VolumePriceBySku{"P10 LRS": {Disk: diskPrice}}
+	local := armcompute.DiffDiskOptionsLocal
+	for _, tc := range []struct {
+		name     string
+		vmss     *armcompute.VirtualMachineScaleSet
+		expected *utils.MetricResult
+	}{
+		{
+			name: "managed os disk",
+			vmss: osDiskScaleSet(managedOSDisk(armcompute.StorageAccountTypesPremiumLRS, 128)),
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_os_disk_usd_per_hour",
+				Labels:     utils.LabelMap{"vmss": "aks-system-1234-vmss", "cluster_name": "prod", "region": "eastus", "storage_class": "Premium_LRS", "disk_tier": "P10", "os_disk_type": "managed"},
+				Value:      diskPrice / utils.HoursInMonth,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name: "ephemeral os disk",
+			vmss: func() *armcompute.VirtualMachineScaleSet {
+				osDisk := managedOSDisk(armcompute.StorageAccountTypesStandardLRS, 128)
+				osDisk.DiffDiskSettings = &armcompute.DiffDiskSettings{Option: &local}
+				return osDiskScaleSet(osDisk)
+			}(),
+			expected: &utils.MetricResult{
+				FqName:     "cloudcost_azure_aks_os_disk_usd_per_hour",
+				Labels:     utils.LabelMap{"vmss": "aks-system-1234-vmss", "cluster_name": "prod", "region": "eastus", "storage_class": "", "disk_tier": "", "os_disk_type": "ephemeral"},
+				Value:      0,
+				MetricType: prometheus.GaugeValue,
+			},
+		},
+		{
+			name: "managed os disk without a size",
+			vmss: osDiskScaleSet(&armcompute.VirtualMachineScaleSetOSDisk{}),
+		},
+		{
+			name: "sku not found",
+			vmss: osDiskScaleSet(managedOSDisk(armcompute.StorageAccountTypesPremiumZRS, 128)),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			metric, ok := osDiskMetric(prices, tc.vmss, "prod", NewUnpricedMachineTypes("test"))
+			assert.Equal(t, tc.expected != nil, ok)
+			assert.Equal(t, tc.expected, utils.ReadMetrics(metric))
+		})
+	}
+}
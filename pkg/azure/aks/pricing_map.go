@@ -9,11 +9,47 @@ import (
 	"time"
 
 	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
 	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
 	AZ_API_VERSION string = "2023-01-01-preview" // using latest API Version https://learn.microsoft.com/en-us/rest/api/cost-management/retail-prices/azure-retail-prices
+
+	virtualMachinesService = "Virtual Machines"
+
+	// hourlyUnitOfMeasure is the unit of the VM prices. maxMachineHourlyPrice is the highest sane price of a VM in
+	// USD/h, well above the price of the largest sizes.
+	hourlyUnitOfMeasure   = "1 Hour"
+	maxMachineHourlyPrice = 1000.0
+	// usdCurrencyCode is the currency every price is expected in, as the exporter exports USD.
+	usdCurrencyCode = "USD"
+)
+
+// allRegions is the region label of the fetches of the prices of every region.
+const allRegions = "all"
+
+var (
+	// PriceFetchDuration, PriceFetchFailuresTotal and PricedRegions instrument the fetches of the Retail Prices API by
+	// the price stores. A page of a fetch of several regions is observed once per region. They're registered once by
+	// the azure provider.
+	PriceFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    prometheus.BuildFQName(cloudcost_exporter.ExporterName, subsystem, "price_fetch_duration_seconds"),
+		Help:    "Duration of the fetches of a page of the Azure Retail Prices API in seconds.",
+		Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"region"})
+	PriceFetchFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, subsystem, "price_fetch_failures_total"),
+		Help: "Total number of failed fetches of a page of the Azure Retail Prices API.",
+	}, []string{"region"})
+	PricedRegions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, subsystem, "priced_regions"),
+		Help: "Number of regions the machine price store holds prices for.",
+	})
 )
 
 type MachineOperatingSystem int
@@ -26,7 +62,12 @@ const (
 var machineOperatingSystemNames [2]string = [2]string{"Linux", "Windows"}
 
 func (o MachineOperatingSystem) String() string {
-	return machineOperatingSystemNames[o-1]
+	return machineOperatingSystemNames[o]
+}
+
+// MarshalText keys price snapshots by operating system name rather than by its number.
+func (o MachineOperatingSystem) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
 }
 
 type MachinePriority int
@@ -39,7 +80,12 @@ const (
 var machinePriorityNames [2]string = [2]string{"OnDemand", "Spot"}
 
 func (v MachinePriority) String() string {
-	return machinePriorityNames[v-1]
+	return machinePriorityNames[v]
+}
+
+// MarshalText keys price snapshots by priority name rather than by its number.
+func (v MachinePriority) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
 }
 
 type PriceBySku map[string]retailPriceSdk.ResourceSKU
@@ -56,45 +102,51 @@ type PriceStore struct {
 	retailPriceClient *retailPriceSdk.RetailPricesClient
 
 	RegionMap map[string]PriceByPriority
-	Cache     map[string]*retailPriceSdk.ResourceSKU
+	// HostRegionMap holds the prices of the dedicated hosts by region and normalized sku, see hostSkuKey.
+	HostRegionMap map[string]PriceBySku
+	// ReservationRegionMap holds the hourly reservation prices of the VMs by region, only when they're fetched.
+	ReservationRegionMap map[string]ReservedPriceBySku
+	Cache                map[string]*retailPriceSdk.ResourceSKU
+	fetched              *fetchedRegions
+	// shared fetches the prices of the store along with the other stores it feeds, it's nil when the store fetches
+	// its prices on its own.
+	shared *RetailPrices
+	// vmSizes selects the VM sizes whose prices are kept, every size is priced when it's nil.
+	vmSizes *pricing.FamilyFilter
 }
 
+// NewPricingStore creates an empty PriceStore. The prices of a region are fetched by EnsureRegions or on its first
+// lookup, rather than the prices of every region at startup.
 func NewPricingStore(subId string, priceClient *retailPriceSdk.RetailPricesClient, parentLogger *slog.Logger, parentContext context.Context) *PriceStore {
-	logger := parentLogger.With("subsystem", "pricingMap")
+	return newPricingStore(subId, priceClient, parentLogger, parentContext)
+}
 
-	p := &PriceStore{
+// newPricingStore creates an empty PriceStore, leaving it up to the caller to populate it.
+func newPricingStore(subId string, priceClient *retailPriceSdk.RetailPricesClient, parentLogger *slog.Logger, parentContext context.Context) *PriceStore {
+	return &PriceStore{
 		lock:              &sync.RWMutex{},
-		logger:            logger,
+		logger:            parentLogger.With("subsystem", "pricingMap"),
 		context:           parentContext,
 		subscriptionId:    subId,
 		retailPriceClient: priceClient,
 
-		RegionMap: make(map[string]PriceByPriority),
-		Cache:     make(map[string]*retailPriceSdk.ResourceSKU),
+		RegionMap:            make(map[string]PriceByPriority),
+		HostRegionMap:        make(map[string]PriceBySku),
+		ReservationRegionMap: make(map[string]ReservedPriceBySku),
+		Cache:                make(map[string]*retailPriceSdk.ResourceSKU),
+		fetched:              newFetchedRegions(),
 	}
+}
 
-	go func() {
-		err := p.PopulatePriceStore([]string{})
-		if err != nil {
-			p.logger.LogAttrs(p.context, slog.LevelError, "error populating initial price store", slog.String("error", err.Error()))
-		}
-	}()
-
-	return p
+func (p *PriceStore) serviceFilter() string {
+	return fmt.Sprintf(`serviceName eq '%s'`, virtualMachinesService)
 }
 
 func (p *PriceStore) buildQueryFilter(locationList []string) string {
 	if len(locationList) == 0 {
-		return `serviceName eq 'Virtual Machines' and priceType eq 'Consumption'`
-	}
-
-	locationListFilter := []string{}
-	for _, region := range locationList {
-		locationListFilter = append(locationListFilter, fmt.Sprintf("armRegionName eq '%s'", region))
+		return p.serviceFilter() + ` and priceType eq 'Consumption'`
 	}
-
-	locationListStr := strings.Join(locationListFilter, " or ")
-	return fmt.Sprintf(`serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and (%s)`, locationListStr)
+	return fmt.Sprintf(`%s and priceType eq 'Consumption' and (%s)`, p.serviceFilter(), regionFilter(locationList))
 }
 
 func (p *PriceStore) buildListOptions(locationList []string) *retailPriceSdk.RetailPricesClientListOptions {
@@ -125,48 +177,150 @@ func (p *PriceStore) determineMachinePriority(sku retailPriceSdk.ResourceSKU) Ma
 
 }
 
+// validateRetailPrice returns the reason a retail price item isn't per unitOfMeasure, in USD or sane, or an empty string
+// when it's valid. The unit and the currency are only checked when they're set.
+func validateRetailPrice(v retailPriceSdk.ResourceSKU, unitOfMeasure string, max float64) string {
+	switch {
+	case v.UnitOfMeasure != "" && v.UnitOfMeasure != unitOfMeasure:
+		return utils.ReasonUnexpectedUnit
+	case v.CurrencyCode != "" && v.CurrencyCode != usdCurrencyCode:
+		return utils.ReasonUnexpectedCurrency
+	case !utils.SanePrice(v.RetailPrice, max):
+		return utils.ReasonOutlier
+	default:
+		return ""
+	}
+}
+
+// priceSource is the source label of the malformed machine prices of a priority.
+func priceSource(priority MachinePriority) string {
+	if priority == Spot {
+		return "spot"
+	}
+	return "ondemand"
+}
+
 func (p *PriceStore) PopulatePriceStore(locationList []string) error {
 	startTime := time.Now()
 	p.logger.LogAttrs(p.context, slog.LevelInfo, "populating price map")
 
+	// The prices are listed before taking the lock so that lookups of the regions that were already fetched don't
+	// wait on the API
+	items, err := listRetailPrices(p.context, p.retailPriceClient, p.buildListOptions(locationList), locationList)
+	if err != nil {
+		p.logger.LogAttrs(p.context, slog.LevelError, "error paging", slog.Any("regions", locationList), slog.String("err", err.Error()))
+		return ErrPageAdvanceFailure
+	}
+	p.addRetailPrices(items)
+	p.fetched.add(locationList)
+
+	p.logger.LogAttrs(p.context, slog.LevelInfo, "price map populated", slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
+
+// addRetailPrices files the VM prices among the items of a fetch.
+func (p *PriceStore) addRetailPrices(items []retailPriceSdk.ResourceSKU) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	for _, v := range items {
+		// The items of a fetch shared with other stores carry their service name
+		if v.ServiceName != "" && v.ServiceName != virtualMachinesService {
+			continue
+		}
+		// The Retail Prices API has no pattern matching, so the sizes are filtered once listed. Dedicated hosts aren't
+		// VM sizes and are always kept.
+		if !isDedicatedHostPrice(v) && !p.vmSizes.Matches("", v.ArmSkuName) {
+			continue
+		}
+		p.addMachinePrice(v)
+	}
+	PricedRegions.Set(float64(len(p.RegionMap)))
+}
 
-	pager := p.retailPriceClient.NewListPager(p.buildListOptions(locationList))
+// EnsureRegions fetches the prices of the regions that weren't fetched yet or are stale in a single query. Regions
+// whose last fetch failed are skipped until their backoff expired. It's a no-op when no region is missing or when the
+// store has no client. A store fed by RetailPrices fetches the regions of every store it feeds.
+func (p *PriceStore) EnsureRegions(regions []string) error {
+	if p.shared != nil {
+		return p.shared.EnsureRegions(regions)
+	}
+	if p.retailPriceClient == nil {
+		return nil
+	}
+	return p.fetched.ensure(regions, p.PopulatePriceStore)
+}
 
-	for pager.More() {
-		page, err := pager.NextPage(p.context)
-		if err != nil {
-			p.logger.LogAttrs(p.context, slog.LevelError, "error paging")
-			return ErrPageAdvanceFailure
-		}
+// addMachinePrice files a single retail price item under its region, priority, operating system and sku name.
+// Reservation and dedicated host prices are filed apart, see addReservationPrice and addDedicatedHostPrice. The caller
+// must hold the write lock.
+func (p *PriceStore) addMachinePrice(v retailPriceSdk.ResourceSKU) {
+	regionName := v.ArmRegionName
+	if regionName == "" {
+		p.logger.LogAttrs(p.context, slog.LevelInfo, "region name for price not found", slog.String("sku", v.SkuName))
+		return
+	}
+	if v.Type == reservationPriceType {
+		p.addReservationPrice(v)
+		return
+	}
+	if isDedicatedHostPrice(v) {
+		p.addDedicatedHostPrice(v)
+		return
+	}
 
-		for _, v := range page.Items {
-			regionName := v.ArmRegionName
-			if regionName == "" {
-				p.logger.LogAttrs(p.context, slog.LevelInfo, "region name for price not found", slog.String("sku", v.SkuName))
-				continue
-			}
-
-			if _, ok := p.RegionMap[regionName]; !ok {
-				p.logger.LogAttrs(p.context, slog.LevelInfo, "populating machine prices for region", slog.String("region", regionName))
-				p.RegionMap[regionName] = make(PriceByPriority)
-				p.RegionMap[regionName][Spot] = make(PriceByOperatingSystem)
-				p.RegionMap[regionName][OnDemand] = make(PriceByOperatingSystem)
-			}
-
-			machineOperatingSystem := p.determineMachineOperatingSystem(v)
-			machinePriority := p.determineMachinePriority(v)
-
-			if _, ok := p.RegionMap[regionName][machinePriority][machineOperatingSystem]; !ok {
-				p.RegionMap[regionName][machinePriority][machineOperatingSystem] = make(PriceBySku)
-			}
-			p.RegionMap[regionName][machinePriority][machineOperatingSystem][v.ArmSkuName] = v
-		}
+	machinePriority := p.determineMachinePriority(v)
+	if reason := validateRetailPrice(v, hourlyUnitOfMeasure, maxMachineHourlyPrice); reason != "" {
+		p.logger.LogAttrs(p.context, slog.LevelWarn, "skipping malformed machine price", slog.String("sku", v.SkuName), slog.String("reason", reason))
+		MalformedPriceEntriesTotal.WithLabelValues(priceSource(machinePriority), reason).Inc()
+		return
 	}
 
-	p.logger.LogAttrs(p.context, slog.LevelInfo, "price map populated", slog.Duration("duration", time.Since(startTime)))
-	return nil
+	if _, ok := p.RegionMap[regionName]; !ok {
+		p.logger.LogAttrs(p.context, slog.LevelInfo, "populating machine prices for region", slog.String("region", regionName))
+		p.RegionMap[regionName] = make(PriceByPriority)
+		p.RegionMap[regionName][Spot] = make(PriceByOperatingSystem)
+		p.RegionMap[regionName][OnDemand] = make(PriceByOperatingSystem)
+	}
+
+	machineOperatingSystem := p.determineMachineOperatingSystem(v)
+
+	if _, ok := p.RegionMap[regionName][machinePriority][machineOperatingSystem]; !ok {
+		p.RegionMap[regionName][machinePriority][machineOperatingSystem] = make(PriceBySku)
+	}
+	p.RegionMap[regionName][machinePriority][machineOperatingSystem][v.ArmSkuName] = v
+}
+
+// getPrice returns the retail price in USD/h of a sku in a region.
+func (p *PriceStore) getPrice(region string, priority MachinePriority, operatingSystem MachineOperatingSystem, sku string) (float64, error) {
+	if err := p.EnsureRegions([]string{region}); err != nil {
+		return 0, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	prices, ok := p.RegionMap[region]
+	if !ok {
+		return 0, ErrRegionNotFound
+	}
+	price, ok := prices[priority][operatingSystem][sku]
+	if !ok {
+		return 0, ErrSkuNotFound
+	}
+	return price.RetailPrice, nil
+}
+
+// PriceForInstance returns the retail price of a Linux VM size in region at tier, which makes the price store a
+// pricing.Source. The prices of a region that wasn't priced yet are fetched first.
+func (p *PriceStore) PriceForInstance(region string, sku string, tier string) (pricing.Price, error) {
+	priority := OnDemand
+	if tier == pricing.TierSpot {
+		priority = Spot
+	}
+	price, err := p.getPrice(region, priority, Linux, sku)
+	if err != nil {
+		return pricing.Price{}, fmt.Errorf("%w: %w", pricing.ErrPriceNotFound, err)
+	}
+	return pricing.Price{USDPerHour: price}, nil
 }
 
 // TODO - implement ability to lookup a certain VM's
@@ -1,11 +1,24 @@
 package aks
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 )
 
 func TestMapCreation(t *testing.T) {
@@ -136,3 +149,149 @@ func TestDetermineMachinePriority(t *testing.T) {
 		})
 	}
 }
+
+func TestPopulatePriceStore_Metrics(t *testing.T) {
+	newClient := func(t *testing.T, transport *fakeTransport) *retailPriceSdk.RetailPricesClient {
+		client, err := retailPriceSdk.NewRetailPricesClient(&arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}})
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("successful fetch", func(t *testing.T) {
+		client := newClient(t, &fakeTransport{responses: map[string]any{
+			"/api/retail/prices": map[string]any{"Items": []any{
+				map[string]any{"armRegionName": "westus2", "armSkuName": "Standard_D4s_v5", "skuName": "D4s v5", "retailPrice": 0.192},
+				map[string]any{"armRegionName": "westus3", "armSkuName": "Standard_D4s_v5", "skuName": "D4s v5", "retailPrice": 0.192},
+			}},
+		}})
+		failuresBefore := testutil.ToFloat64(PriceFetchFailuresTotal.WithLabelValues("westus2"))
+		fetchesBefore := histogramCount(t, PriceFetchDuration.WithLabelValues("westus3"))
+
+		p := newPricingStore("", client, testLogger, parentCtx)
+		require.NoError(t, p.PopulatePriceStore([]string{"westus2", "westus3"}))
+
+		assert.Equal(t, 2.0, testutil.ToFloat64(PricedRegions))
+		assert.Equal(t, failuresBefore, testutil.ToFloat64(PriceFetchFailuresTotal.WithLabelValues("westus2")))
+		assert.Equal(t, fetchesBefore+1, histogramCount(t, PriceFetchDuration.WithLabelValues("westus3")))
+	})
+
+	t.Run("failed fetch", func(t *testing.T) {
+		client := newClient(t, &fakeTransport{})
+		failuresBefore := testutil.ToFloat64(PriceFetchFailuresTotal.WithLabelValues(allRegions))
+
+		p := newPricingStore("", client, testLogger, parentCtx)
+		require.ErrorIs(t, p.PopulatePriceStore(nil), ErrPageAdvanceFailure)
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(PriceFetchFailuresTotal.WithLabelValues(allRegions))-failuresBefore)
+		assert.Empty(t, p.RegionMap)
+	})
+}
+
+func TestPriceStore_addMachinePrice_Malformed(t *testing.T) {
+	p := newPricingStore("", nil, testLogger, parentCtx)
+	for _, tc := range []struct {
+		name   string
+		item   retailPriceSdk.ResourceSKU
+		source string
+		reason string
+	}{
+		{
+			name:   "unexpected unit",
+			item:   retailPriceSdk.ResourceSKU{ArmRegionName: "westus2", ArmSkuName: "Standard_D4s_v5", SkuName: "D4s v5", UnitOfMeasure: "1/Month", RetailPrice: 140},
+			source: "ondemand",
+			reason: "unexpected_unit",
+		},
+		{
+			name:   "unexpected currency",
+			item:   retailPriceSdk.ResourceSKU{ArmRegionName: "westus2", ArmSkuName: "Standard_D4s_v5", SkuName: "D4s v5", CurrencyCode: "EUR", RetailPrice: 0.18},
+			source: "ondemand",
+			reason: "unexpected_currency",
+		},
+		{
+			name:   "zero price",
+			item:   retailPriceSdk.ResourceSKU{ArmRegionName: "westus2", ArmSkuName: "Standard_D4s_v5", SkuName: "D4s v5 Spot", UnitOfMeasure: "1 Hour"},
+			source: "spot",
+			reason: "outlier",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := testutil.ToFloat64(MalformedPriceEntriesTotal.WithLabelValues(tc.source, tc.reason))
+			p.addMachinePrice(tc.item)
+			assert.Equal(t, 1.0, testutil.ToFloat64(MalformedPriceEntriesTotal.WithLabelValues(tc.source, tc.reason))-before)
+			assert.Empty(t, p.RegionMap)
+		})
+	}
+}
+
+func TestPriceStore_addRetailPrices_VMSizes(t *testing.T) {
+	p := newPricingStore("", nil, testLogger, parentCtx)
+	p.vmSizes = &pricing.FamilyFilter{Include: []string{"standard_d*"}, Exclude: []string{"Standard_D*_v3"}}
+	p.addRetailPrices([]retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ArmSkuName: "Standard_D4s_v5", SkuName: "D4s v5", RetailPrice: 0.192},
+		{ArmRegionName: "eastus", ArmSkuName: "Standard_D4_v3", SkuName: "D4 v3", RetailPrice: 0.192},
+		{ArmRegionName: "eastus", ArmSkuName: "Standard_HB120rs_v3", SkuName: "HB120rs v3", RetailPrice: 3.6},
+		{ArmRegionName: "eastus", ProductName: "Dedicated Host", SkuName: "DSv3 Type1", RetailPrice: 4.5},
+	})
+
+	assert.Len(t, p.RegionMap["eastus"][OnDemand][Linux], 1)
+	assert.Contains(t, p.RegionMap["eastus"][OnDemand][Linux], "Standard_D4s_v5")
+	price, err := p.getDedicatedHostPrice("eastus", "DSv3-Type1")
+	require.NoError(t, err)
+	assert.Equal(t, 4.5, price, "dedicated hosts aren't VM sizes")
+}
+
+// histogramCount returns the number of observations of a histogram.
+func histogramCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, observer.(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+// benchmarkPriceItems generates n retail price items spread over regions, skus, priorities and operating systems the
+// way the Azure Retail Prices API lists them.
+func benchmarkPriceItems(n int) []retailPriceSdk.ResourceSKU {
+	regions := []string{
+		"australiaeast", "brazilsouth", "canadacentral", "centralindia", "centralus", "eastasia", "eastus", "eastus2",
+		"francecentral", "germanywestcentral", "japaneast", "koreacentral", "northcentralus", "northeurope",
+		"norwayeast", "southafricanorth", "southcentralus", "southeastasia", "swedencentral", "switzerlandnorth",
+		"uaenorth", "uksouth", "westeurope", "westus", "westus2", "westus3",
+	}
+	items := make([]retailPriceSdk.ResourceSKU, 0, n)
+	for i := 0; i < n; i++ {
+		armSkuName := fmt.Sprintf("Standard_D%ds_v%d", 2<<(i/len(regions)%6), 3+i/(len(regions)*6)%400)
+		item := retailPriceSdk.ResourceSKU{
+			ArmRegionName: regions[i%len(regions)],
+			ArmSkuName:    armSkuName,
+			SkuName:       armSkuName,
+			ProductName:   "Virtual Machines Dsv5 Series",
+			RetailPrice:   0.096 + float64(i%100)*0.001,
+		}
+		if i%2 == 1 {
+			item.SkuName += " Spot"
+		}
+		if i%4 >= 2 {
+			item.ProductName += " Windows"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func BenchmarkPriceStore_addMachinePrice(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, n := range []int{1_000, 50_000} {
+		items := benchmarkPriceItems(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := newPricingStore("", nil, logger, context.Background())
+				p.lock.Lock()
+				for _, item := range items {
+					p.addMachinePrice(item)
+				}
+				p.lock.Unlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package aks
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+)
+
+const (
+	// priceRefreshInterval is how long the prices of a region are used before they are fetched again.
+	priceRefreshInterval = 24 * time.Hour
+	// minFetchBackoff and maxFetchBackoff bound how long a region whose fetch failed is skipped before the next
+	// attempt. The backoff doubles with every consecutive failure.
+	minFetchBackoff = time.Minute
+	maxFetchBackoff = time.Hour
+)
+
+// fetchedRegions keeps track of the regions whose prices were fetched, so that a price store only fetches the prices
+// of a region when they are missing or stale instead of the prices of every region at startup.
+// Failed fetches are recorded with a backoff so that every lookup of a failing region doesn't query the API again, and
+// concurrent lookups of a region wait for the pending fetch rather than fetching it again.
+type fetchedRegions struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	// all is when every region was last fetched
+	all       time.Time
+	fetchedAt map[string]time.Time
+	failures  map[string]fetchFailure
+	pending   map[string]*pendingFetch
+}
+
+type fetchFailure struct {
+	at    time.Time
+	count int
+}
+
+type pendingFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func newFetchedRegions() *fetchedRegions {
+	return &fetchedRegions{
+		now:       time.Now,
+		fetchedAt: make(map[string]time.Time),
+		failures:  make(map[string]fetchFailure),
+		pending:   make(map[string]*pendingFetch),
+	}
+}
+
+// add records the regions of a successful fetch, an empty list being every region.
+func (f *fetchedRegions) add(regions []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if len(regions) == 0 {
+		f.all = now
+		f.failures = make(map[string]fetchFailure)
+		return
+	}
+	for _, region := range regions {
+		f.fetchedAt[region] = now
+		delete(f.failures, region)
+	}
+}
+
+// fail records a failed fetch of the regions.
+func (f *fetchedRegions) fail(regions []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, region := range regions {
+		f.failures[region] = fetchFailure{at: f.now(), count: f.failures[region].count + 1}
+	}
+}
+
+// missing returns the regions whose prices weren't fetched yet or are older than the refresh interval, leaving out the
+// regions whose last fetch failed until their backoff expired.
+func (f *fetchedRegions) missing(regions []string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.missingLocked(regions)
+}
+
+func (f *fetchedRegions) missingLocked(regions []string) []string {
+	now := f.now()
+	seen := make(map[string]bool)
+	var missing []string
+	for _, region := range regions {
+		if region == "" || seen[region] {
+			continue
+		}
+		seen[region] = true
+		fetchedAt := f.fetchedAt[region]
+		if f.all.After(fetchedAt) {
+			fetchedAt = f.all
+		}
+		if !fetchedAt.IsZero() && now.Sub(fetchedAt) < priceRefreshInterval {
+			continue
+		}
+		if failure, ok := f.failures[region]; ok && now.Sub(failure.at) < fetchBackoff(failure.count) {
+			continue
+		}
+		missing = append(missing, region)
+	}
+	return missing
+}
+
+// ensure calls fetch with the missing regions, see missing, and records its result. Regions that are already being
+// fetched aren't fetched again, ensure waits for the pending fetch instead and returns its error.
+func (f *fetchedRegions) ensure(regions []string, fetch func(regions []string) error) error {
+	f.mu.Lock()
+	var waiting []*pendingFetch
+	var toFetch []string
+	for _, region := range f.missingLocked(regions) {
+		if pending, ok := f.pending[region]; ok {
+			if !slices.Contains(waiting, pending) {
+				waiting = append(waiting, pending)
+			}
+			continue
+		}
+		toFetch = append(toFetch, region)
+	}
+	var own *pendingFetch
+	if len(toFetch) > 0 {
+		own = &pendingFetch{done: make(chan struct{})}
+		for _, region := range toFetch {
+			f.pending[region] = own
+		}
+	}
+	f.mu.Unlock()
+
+	var errs []error
+	if own != nil {
+		own.err = fetch(toFetch)
+		if own.err != nil {
+			f.fail(toFetch)
+		} else {
+			f.add(toFetch)
+		}
+		f.mu.Lock()
+		for _, region := range toFetch {
+			delete(f.pending, region)
+		}
+		f.mu.Unlock()
+		close(own.done)
+		errs = append(errs, own.err)
+	}
+	for _, pending := range waiting {
+		<-pending.done
+		errs = append(errs, pending.err)
+	}
+	return errors.Join(errs...)
+}
+
+// fetchBackoff returns how long a region is skipped after count consecutive failed fetches.
+func fetchBackoff(count int) time.Duration {
+	backoff := minFetchBackoff
+	for i := 1; i < count && backoff < maxFetchBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxFetchBackoff)
+}
+
+// scaleSetRegions returns the distinct regions of the scale sets, sorted by name.
+func scaleSetRegions(scaleSets []*armcompute.VirtualMachineScaleSet) []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, vmss := range scaleSets {
+		if vmss == nil || vmss.Location == nil || seen[*vmss.Location] {
+			continue
+		}
+		seen[*vmss.Location] = true
+		regions = append(regions, *vmss.Location)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// warmPriceStores fetches the prices of the regions the scale sets of the subscription are deployed in, the prices of
+// any other region are fetched on their first lookup.
+func (c *Collector) warmPriceStores() {
+	scaleSets, err := c.listScaleSets()
+	if err != nil {
+		c.logger.LogAttrs(c.context, slog.LevelError, "error listing scale sets to warm the price stores", slog.String("error", err.Error()))
+		return
+	}
+	regions := scaleSetRegions(scaleSets)
+	if err := c.PriceStore.EnsureRegions(regions); err != nil {
+		c.logger.LogAttrs(c.context, slog.LevelError, "error populating initial price store", slog.String("error", err.Error()))
+	}
+	if err := c.VolumePriceStore.EnsureRegions(regions); err != nil {
+		c.logger.LogAttrs(c.context, slog.LevelError, "error populating initial volume price store", slog.String("error", err.Error()))
+	}
+}
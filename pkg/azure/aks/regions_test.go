@@ -0,0 +1,124 @@
+package aks
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchedRegions(t *testing.T) {
+	f := newFetchedRegions()
+	assert.Equal(t, []string{"eastus", "westeurope"}, f.missing([]string{"eastus", "westeurope", ""}))
+
+	f.add([]string{"eastus"})
+	assert.Equal(t, []string{"westeurope"}, f.missing([]string{"eastus", "westeurope"}))
+
+	f.add(nil)
+	assert.Empty(t, f.missing([]string{"eastus", "westeurope"}))
+}
+
+func TestFetchedRegions_Refresh(t *testing.T) {
+	now := time.Now()
+	f := newFetchedRegions()
+	f.now = func() time.Time { return now }
+
+	f.add([]string{"eastus"})
+	now = now.Add(priceRefreshInterval - time.Minute)
+	assert.Empty(t, f.missing([]string{"eastus"}))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, []string{"eastus"}, f.missing([]string{"eastus"}))
+}
+
+func TestFetchedRegions_Ensure(t *testing.T) {
+	now := time.Now()
+	f := newFetchedRegions()
+	f.now = func() time.Time { return now }
+	errFetch := errors.New("fetch failed")
+	var calls [][]string
+	fetch := func(regions []string) error {
+		calls = append(calls, regions)
+		if len(regions) > 0 && regions[0] == "failing" {
+			return errFetch
+		}
+		return nil
+	}
+
+	assert.NoError(t, f.ensure([]string{"eastus", "westeurope", "eastus"}, fetch))
+	assert.NoError(t, f.ensure([]string{"eastus"}, fetch))
+	assert.Equal(t, [][]string{{"eastus", "westeurope"}}, calls)
+
+	// A failing region is only fetched again once its backoff expired, and the backoff grows with every failure
+	assert.ErrorIs(t, f.ensure([]string{"failing"}, fetch), errFetch)
+	assert.NoError(t, f.ensure([]string{"failing"}, fetch))
+	now = now.Add(minFetchBackoff)
+	assert.ErrorIs(t, f.ensure([]string{"failing"}, fetch), errFetch)
+	now = now.Add(minFetchBackoff)
+	assert.NoError(t, f.ensure([]string{"failing"}, fetch))
+	assert.Len(t, calls, 3)
+	now = now.Add(minFetchBackoff)
+	assert.ErrorIs(t, f.ensure([]string{"failing"}, fetch), errFetch)
+	assert.Len(t, calls, 4)
+}
+
+func TestFetchedRegions_EnsureConcurrentLookups(t *testing.T) {
+	f := newFetchedRegions()
+	started, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	fetch := func([]string) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		close(started)
+		<-release
+		return nil
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- f.ensure([]string{"eastus"}, fetch)
+	}()
+	<-started
+	waiting := make(chan error)
+	go func() {
+		// Waits for the pending fetch instead of fetching the region again, which would close started twice
+		waiting <- f.ensure([]string{"eastus"}, fetch)
+	}()
+	close(release)
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-waiting)
+	assert.Equal(t, 1, calls)
+}
+
+func TestFetchBackoff(t *testing.T) {
+	assert.Equal(t, minFetchBackoff, fetchBackoff(1))
+	assert.Equal(t, 2*minFetchBackoff, fetchBackoff(2))
+	assert.Equal(t, maxFetchBackoff, fetchBackoff(100))
+}
+
+func TestScaleSetRegions(t *testing.T) {
+	scaleSets := []*armcompute.VirtualMachineScaleSet{
+		{Location: to.StringPtr("westeurope")},
+		{Location: to.StringPtr("eastus")},
+		{Location: to.StringPtr("westeurope")},
+		{},
+		nil,
+	}
+	assert.Equal(t, []string{"eastus", "westeurope"}, scaleSetRegions(scaleSets))
+}
+
+func TestEnsureRegions_NoClient(t *testing.T) {
+	p := newPricingStore("", nil, testLogger, parentCtx)
+	assert.NoError(t, p.EnsureRegions([]string{"eastus"}))
+	assert.Empty(t, p.RegionMap)
+
+	v := newVolumePriceStore(nil, testLogger, parentCtx)
+	assert.NoError(t, v.EnsureRegions([]string{"eastus"}))
+	assert.Empty(t, v.RegionMap)
+}
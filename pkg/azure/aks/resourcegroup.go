@@ -0,0 +1,124 @@
+package aks
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+)
+
+var ErrInvalidResourceGroupPattern = errors.New("invalid resource group pattern")
+
+// ResourceGroupFilter selects the resource groups whose resources are enumerated, so that subscriptions shared with
+// thousands of unrelated resource groups can be scoped to the node resource groups of the clusters. Patterns use the
+// syntax of path.Match, eg MC_* for the node resource groups AKS creates by default, and are matched regardless of
+// casing as resource group names are case-insensitive.
+type ResourceGroupFilter struct {
+	// Include only enumerates the resource groups matching one of the patterns, every resource group is enumerated
+	// when it's empty.
+	Include []string
+	// Exclude skips the resource groups matching any of the patterns.
+	Exclude []string
+}
+
+// Validate checks the syntax of every pattern. It's safe to call on a nil filter.
+func (f *ResourceGroupFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrInvalidResourceGroupPattern, pattern, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the resources of a resource group are enumerated. It's safe to call on a nil filter.
+func (f *ResourceGroupFilter) Matches(resourceGroup string) bool {
+	if f == nil {
+		return true
+	}
+	resourceGroup = strings.ToLower(resourceGroup)
+	if len(f.Include) > 0 && !matchesAny(f.Include, resourceGroup) {
+		return false
+	}
+	return !matchesAny(f.Exclude, resourceGroup)
+}
+
+// listsAll reports whether the resources are listed across the subscription. Otherwise only the resource groups
+// matching the inclusions are listed, which saves listing every resource of the subscription.
+func (f *ResourceGroupFilter) listsAll() bool {
+	return f == nil || len(f.Include) == 0
+}
+
+func matchesAny(patterns []string, resourceGroup string) bool {
+	for _, pattern := range patterns {
+		// The patterns are validated upfront, so the error can be ignored
+		if matched, _ := path.Match(strings.ToLower(pattern), resourceGroup); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// listScaleSets lists the scale sets of the resource groups selected by the resource group filter.
+func (c *Collector) listScaleSets() ([]*armcompute.VirtualMachineScaleSet, error) {
+	var scaleSets []*armcompute.VirtualMachineScaleSet
+	if c.resourceGroups.listsAll() {
+		pager := c.virtualMachineScaleSetClient.NewListAllPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list scale sets", slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			for _, vmss := range page.Value {
+				if vmss.ID != nil && !c.resourceGroups.Matches(resourceGroupFromID(*vmss.ID)) {
+					continue
+				}
+				scaleSets = append(scaleSets, vmss)
+			}
+		}
+		return scaleSets, nil
+	}
+
+	resourceGroups, err := c.listResourceGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, resourceGroup := range resourceGroups {
+		pager := c.virtualMachineScaleSetClient.NewListPager(resourceGroup, nil)
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list scale sets", slog.String("resource_group", resourceGroup), slog.String("err", err.Error()))
+				return nil, ErrPageAdvanceFailure
+			}
+			scaleSets = append(scaleSets, page.Value...)
+		}
+	}
+	return scaleSets, nil
+}
+
+// listResourceGroups lists the names of the resource groups of the subscription matching the resource group filter.
+func (c *Collector) listResourceGroups() ([]string, error) {
+	var resourceGroups []string
+	pager := c.resourceGroupClient.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(c.context)
+		if err != nil {
+			c.logger.LogAttrs(c.context, slog.LevelError, "failed to list resource groups", slog.String("err", err.Error()))
+			return nil, ErrPageAdvanceFailure
+		}
+		for _, resourceGroup := range page.Value {
+			if resourceGroup.Name != nil && c.resourceGroups.Matches(*resourceGroup.Name) {
+				resourceGroups = append(resourceGroups, *resourceGroup.Name)
+			}
+		}
+	}
+	return resourceGroups, nil
+}
@@ -0,0 +1,158 @@
+package aks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResourceGroupFilter_Matches(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		filter        *ResourceGroupFilter
+		resourceGroup string
+		expected      bool
+	}{
+		{
+			name:          "nil filter",
+			resourceGroup: "shared-rg",
+			expected:      true,
+		},
+		{
+			name:          "included",
+			filter:        &ResourceGroupFilter{Include: []string{"MC_*"}},
+			resourceGroup: "mc_prod_cluster_eastus",
+			expected:      true,
+		},
+		{
+			name:          "not included",
+			filter:        &ResourceGroupFilter{Include: []string{"MC_*"}},
+			resourceGroup: "shared-rg",
+		},
+		{
+			name:          "excluded",
+			filter:        &ResourceGroupFilter{Exclude: []string{"*-sandbox"}},
+			resourceGroup: "Team-Sandbox",
+		},
+		{
+			name:          "included and excluded",
+			filter:        &ResourceGroupFilter{Include: []string{"MC_*"}, Exclude: []string{"MC_dev_*"}},
+			resourceGroup: "MC_dev_cluster_eastus",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filter.Matches(tc.resourceGroup))
+		})
+	}
+}
+
+// fakeCredential returns a token without authenticating.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeTransport answers the requests of the Resource Manager clients with the JSON responses keyed by URL path.
+type fakeTransport struct {
+	responses map[string]any
+	paths     []string
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	f.paths = append(f.paths, req.URL.Path)
+	body, ok := f.responses[req.URL.Path]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}, Request: req}, nil
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(buf))), Header: http.Header{"Content-Type": {"application/json"}}, Request: req}, nil
+}
+
+func Test_listScaleSets(t *testing.T) {
+	subscriptionPath := "/subscriptions/" + testSubId
+	scaleSetsPath := "/providers/Microsoft.Compute/virtualMachineScaleSets"
+	scaleSet := func(resourceGroup string, name string) map[string]any {
+		return map[string]any{"id": subscriptionPath + "/resourceGroups/" + resourceGroup + scaleSetsPath + "/" + name, "name": name}
+	}
+	responses := map[string]any{
+		subscriptionPath + scaleSetsPath: map[string]any{"value": []any{
+			scaleSet("MC_prod_cluster_eastus", "aks-default"),
+			scaleSet("shared-rg", "legacy"),
+		}},
+		subscriptionPath + "/resourcegroups": map[string]any{"value": []any{
+			map[string]any{"name": "MC_prod_cluster_eastus"},
+			map[string]any{"name": "shared-rg"},
+		}},
+		subscriptionPath + "/resourceGroups/MC_prod_cluster_eastus" + scaleSetsPath: map[string]any{"value": []any{
+			scaleSet("MC_prod_cluster_eastus", "aks-default"),
+		}},
+	}
+	for _, tc := range []struct {
+		name          string
+		filter        *ResourceGroupFilter
+		expected      []string
+		expectedPaths []string
+	}{
+		{
+			name:          "no filter lists every scale set",
+			expected:      []string{"aks-default", "legacy"},
+			expectedPaths: []string{subscriptionPath + scaleSetsPath},
+		},
+		{
+			name:          "exclusions are applied to every scale set",
+			filter:        &ResourceGroupFilter{Exclude: []string{"shared-*"}},
+			expected:      []string{"aks-default"},
+			expectedPaths: []string{subscriptionPath + scaleSetsPath},
+		},
+		{
+			name:     "inclusions only list the matching resource groups",
+			filter:   &ResourceGroupFilter{Include: []string{"mc_*"}},
+			expected: []string{"aks-default"},
+			expectedPaths: []string{
+				subscriptionPath + "/resourcegroups",
+				subscriptionPath + "/resourceGroups/MC_prod_cluster_eastus" + scaleSetsPath,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &fakeTransport{responses: responses}
+			options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}}
+			rgClient, err := armresources.NewResourceGroupsClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			vmssClient, err := armcompute.NewVirtualMachineScaleSetsClient(testSubId, fakeCredential{}, options)
+			require.NoError(t, err)
+			c := &Collector{
+				context:                      parentCtx,
+				logger:                       testLogger,
+				resourceGroupClient:          rgClient,
+				virtualMachineScaleSetClient: vmssClient,
+				resourceGroups:               tc.filter,
+			}
+
+			scaleSets, err := c.listScaleSets()
+			require.NoError(t, err)
+			var names []string
+			for _, vmss := range scaleSets {
+				names = append(names, *vmss.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+			assert.Equal(t, tc.expectedPaths, transport.paths)
+		})
+	}
+}
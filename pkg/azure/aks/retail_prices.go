@@ -0,0 +1,167 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+)
+
+// retailPriceSink is a price store filled by the items of the Retail Prices API selected by its service filter.
+type retailPriceSink interface {
+	// serviceFilter returns the OData filter selecting the items of the store, without the price type and the regions.
+	serviceFilter() string
+	// addRetailPrices files the items of a fetch. The items of the other stores of the fetch are ignored.
+	addRetailPrices(items []retailPriceSdk.ResourceSKU)
+}
+
+// RetailPrices fetches the retail prices of every price store it feeds in a single pass of the Retail Prices API, so
+// that the VM and managed disk prices of a region aren't paged twice. The stores share its record of the fetched
+// regions, so a lookup of a missing region in either store fetches the region for both.
+type RetailPrices struct {
+	lock              sync.Mutex
+	parentLogger      *slog.Logger
+	logger            *slog.Logger
+	context           context.Context
+	retailPriceClient *retailPriceSdk.RetailPricesClient
+
+	sinks   []retailPriceSink
+	fetched *fetchedRegions
+	// reservationPrices also fetches the reservation prices of the VMs, see FetchReservationPrices.
+	reservationPrices bool
+}
+
+// NewRetailPrices creates a RetailPrices without any store, the stores are created with NewPricingStore and
+// NewVolumePriceStore.
+func NewRetailPrices(priceClient *retailPriceSdk.RetailPricesClient, parentLogger *slog.Logger, parentContext context.Context) *RetailPrices {
+	return &RetailPrices{
+		parentLogger:      parentLogger,
+		logger:            parentLogger.With("subsystem", "retailPrices"),
+		context:           parentContext,
+		retailPriceClient: priceClient,
+		fetched:           newFetchedRegions(),
+	}
+}
+
+// NewPricingStore creates an empty PriceStore fed by r.
+func (r *RetailPrices) NewPricingStore(subId string) *PriceStore {
+	p := newPricingStore(subId, nil, r.parentLogger, r.context)
+	p.shared = r
+	r.feed(p)
+	return p
+}
+
+// NewVolumePriceStore creates an empty VolumePriceStore fed by r.
+func (r *RetailPrices) NewVolumePriceStore() *VolumePriceStore {
+	p := newVolumePriceStore(nil, r.parentLogger, r.context)
+	p.shared = r
+	r.feed(p)
+	return p
+}
+
+// FetchReservationPrices also fetches the reservation prices of the VMs, which are only needed to price the VMs covered
+// by a reservation. It must be called before the first fetch.
+func (r *RetailPrices) FetchReservationPrices() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reservationPrices = true
+}
+
+func (r *RetailPrices) feed(sink retailPriceSink) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.sinks = append(r.sinks, sink)
+}
+
+// buildQueryFilter selects the items of every store of r in the regions of locationList, or every region when empty.
+func (r *RetailPrices) buildQueryFilter(locationList []string) string {
+	r.lock.Lock()
+	serviceFilters := make([]string, 0, len(r.sinks))
+	for _, sink := range r.sinks {
+		serviceFilters = append(serviceFilters, "("+sink.serviceFilter()+")")
+	}
+	reservationPrices := r.reservationPrices
+	r.lock.Unlock()
+	filter := fmt.Sprintf(`priceType eq 'Consumption' and (%s)`, strings.Join(serviceFilters, " or "))
+	if reservationPrices {
+		filter = fmt.Sprintf(`(%s or (priceType eq '%s' and serviceName eq '%s'))`, filter, reservationPriceType, virtualMachinesService)
+	}
+	if len(locationList) == 0 {
+		return filter
+	}
+	return fmt.Sprintf(`%s and (%s)`, filter, regionFilter(locationList))
+}
+
+// Populate fetches the prices of the regions of locationList, or of every region when empty, and files them in every
+// store of r.
+func (r *RetailPrices) Populate(locationList []string) error {
+	startTime := time.Now()
+	r.logger.LogAttrs(r.context, slog.LevelInfo, "populating retail prices", slog.Any("regions", locationList))
+	items, err := listRetailPrices(r.context, r.retailPriceClient, &retailPriceSdk.RetailPricesClientListOptions{
+		APIVersion:  to.StringPtr(AZ_API_VERSION),
+		Filter:      to.StringPtr(r.buildQueryFilter(locationList)),
+		MeterRegion: to.StringPtr(`'primary'`),
+	}, locationList)
+	if err != nil {
+		r.logger.LogAttrs(r.context, slog.LevelError, "error paging", slog.Any("regions", locationList), slog.String("err", err.Error()))
+		return err
+	}
+	r.lock.Lock()
+	sinks := append([]retailPriceSink{}, r.sinks...)
+	r.lock.Unlock()
+	for _, sink := range sinks {
+		sink.addRetailPrices(items)
+	}
+	r.logger.LogAttrs(r.context, slog.LevelInfo, "retail prices populated", slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
+
+// EnsureRegions fetches the prices of the regions that weren't fetched yet or are stale in a single query for every
+// store of r, see PriceStore.EnsureRegions. It's a no-op when r has no client.
+func (r *RetailPrices) EnsureRegions(regions []string) error {
+	if r.retailPriceClient == nil {
+		return nil
+	}
+	return r.fetched.ensure(regions, r.Populate)
+}
+
+// listRetailPrices pages the items of the Retail Prices API selected by options. Every page is observed in
+// PriceFetchDuration, and PriceFetchFailuresTotal when it failed, once per region of locationList or once for all
+// regions when empty.
+func listRetailPrices(ctx context.Context, client *retailPriceSdk.RetailPricesClient, options *retailPriceSdk.RetailPricesClientListOptions, locationList []string) ([]retailPriceSdk.ResourceSKU, error) {
+	regions := locationList
+	if len(regions) == 0 {
+		regions = []string{allRegions}
+	}
+	var items []retailPriceSdk.ResourceSKU
+	pager := client.NewListPager(options)
+	for pager.More() {
+		pageStart := time.Now()
+		page, err := pager.NextPage(ctx)
+		for _, region := range regions {
+			PriceFetchDuration.WithLabelValues(region).Observe(time.Since(pageStart).Seconds())
+			if err != nil {
+				PriceFetchFailuresTotal.WithLabelValues(region).Inc()
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPageAdvanceFailure, err)
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+// regionFilter returns the OData filter matching any of the regions.
+func regionFilter(locationList []string) string {
+	filters := make([]string, 0, len(locationList))
+	for _, region := range locationList {
+		filters = append(filters, fmt.Sprintf("armRegionName eq '%s'", region))
+	}
+	return strings.Join(filters, " or ")
+}
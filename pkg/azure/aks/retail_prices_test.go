@@ -0,0 +1,55 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+)
+
+func TestRetailPrices_BuildQueryFilter(t *testing.T) {
+	r := NewRetailPrices(nil, testLogger, parentCtx)
+	r.NewPricingStore("")
+	r.NewVolumePriceStore()
+
+	assert.Equal(t, `priceType eq 'Consumption' and ((serviceName eq 'Virtual Machines') or (serviceName eq 'Storage' and contains(productName, 'Managed Disks')))`, r.buildQueryFilter(nil))
+	assert.Equal(t, `priceType eq 'Consumption' and ((serviceName eq 'Virtual Machines') or (serviceName eq 'Storage' and contains(productName, 'Managed Disks'))) and (armRegionName eq 'eastus' or armRegionName eq 'westus2')`, r.buildQueryFilter([]string{"eastus", "westus2"}))
+
+	r.FetchReservationPrices()
+	assert.Equal(t, `(priceType eq 'Consumption' and ((serviceName eq 'Virtual Machines') or (serviceName eq 'Storage' and contains(productName, 'Managed Disks'))) or (priceType eq 'Reservation' and serviceName eq 'Virtual Machines')) and (armRegionName eq 'eastus')`, r.buildQueryFilter([]string{"eastus"}))
+}
+
+func TestRetailPrices_EnsureRegions(t *testing.T) {
+	transport := &fakeTransport{responses: map[string]any{
+		"/api/retail/prices": map[string]any{"Items": []any{
+			map[string]any{"serviceName": "Virtual Machines", "armRegionName": "westus2", "productName": "Virtual Machines Dsv5 Series", "armSkuName": "Standard_D4s_v5", "skuName": "D4s v5", "retailPrice": 0.192},
+			map[string]any{"serviceName": "Storage", "armRegionName": "westus2", "productName": "Premium SSD Managed Disks", "meterName": "P10 LRS Disk", "skuName": "P10 LRS", "unitOfMeasure": "1/Month", "retailPrice": 19.71},
+		}},
+	}}
+	client, err := retailPriceSdk.NewRetailPricesClient(&arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}})
+	require.NoError(t, err)
+
+	r := NewRetailPrices(client, testLogger, parentCtx)
+	priceStore := r.NewPricingStore("")
+	volumePriceStore := r.NewVolumePriceStore()
+
+	require.NoError(t, volumePriceStore.EnsureRegions([]string{"westus2"}))
+	require.NoError(t, priceStore.EnsureRegions([]string{"westus2"}))
+
+	// A single fetch fills both stores
+	assert.Len(t, transport.paths, 1)
+	assert.Contains(t, priceStore.RegionMap["westus2"][OnDemand][Linux], "Standard_D4s_v5")
+	require.Contains(t, volumePriceStore.RegionMap["westus2"], "P10 LRS")
+	assert.Equal(t, 19.71, volumePriceStore.RegionMap["westus2"]["P10 LRS"].Disk)
+}
+
+func TestRetailPrices_EnsureRegions_NoClient(t *testing.T) {
+	r := NewRetailPrices(nil, testLogger, parentCtx)
+	priceStore := r.NewPricingStore("")
+
+	require.NoError(t, priceStore.EnsureRegions([]string{"westus2"}))
+	assert.Empty(t, priceStore.RegionMap)
+}
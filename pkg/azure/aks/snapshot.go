@@ -0,0 +1,48 @@
+package aks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+)
+
+// PriceSnapshot is the catalog of VM and managed disk retail prices the AKS collector prices resources with.
+// It's built by the same price stores the collector uses, so a snapshot always matches what the exporter sees.
+type PriceSnapshot struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Regions the snapshot was restricted to, empty when all regions were fetched.
+	Regions  []string                    `json:"regions,omitempty"`
+	Machines map[string]PriceByPriority  `json:"machines"`
+	Volumes  map[string]VolumePriceBySku `json:"volumes"`
+	// DedicatedHosts are keyed by region and normalized sku, eg dsv3type1.
+	DedicatedHosts map[string]PriceBySku `json:"dedicated_hosts,omitempty"`
+}
+
+// NewPriceSnapshot populates a PriceStore and a VolumePriceStore for the given regions, or all regions when empty,
+// and returns their contents.
+func NewPriceSnapshot(ctx context.Context, priceClient *retailPriceSdk.RetailPricesClient, logger *slog.Logger, regions []string) (*PriceSnapshot, error) {
+	retailPrices := NewRetailPrices(priceClient, logger, ctx)
+	priceStore := retailPrices.NewPricingStore("")
+	volumePriceStore := retailPrices.NewVolumePriceStore()
+	if err := retailPrices.Populate(regions); err != nil {
+		return nil, err
+	}
+	return &PriceSnapshot{
+		GeneratedAt:    time.Now().UTC(),
+		Regions:        regions,
+		Machines:       priceStore.RegionMap,
+		Volumes:        volumePriceStore.RegionMap,
+		DedicatedHosts: priceStore.HostRegionMap,
+	}, nil
+}
+
+// Write encodes the snapshot as indented JSON.
+func (s *PriceSnapshot) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
@@ -0,0 +1,46 @@
+package aks
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+)
+
+func TestPriceSnapshot_Write(t *testing.T) {
+	priceStore := newPricingStore("", nil, testLogger, parentCtx)
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.192},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series Windows", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.05},
+	} {
+		priceStore.addMachinePrice(item)
+	}
+	volumePriceStore := newVolumePriceStore(nil, testLogger, parentCtx)
+	volumePriceStore.addVolumePrice(retailPriceSdk.ResourceSKU{ArmRegionName: "eastus", ProductName: "Premium SSD Managed Disks", SkuName: "P30 LRS", MeterName: "P30 LRS Disk", UnitOfMeasure: "1/Month", RetailPrice: 135.168})
+
+	snapshot := &PriceSnapshot{
+		GeneratedAt: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		Regions:     []string{"eastus"},
+		Machines:    priceStore.RegionMap,
+		Volumes:     volumePriceStore.RegionMap,
+	}
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.Write(&buf))
+
+	var got struct {
+		GeneratedAt time.Time                                                              `json:"generated_at"`
+		Regions     []string                                                               `json:"regions"`
+		Machines    map[string]map[string]map[string]map[string]retailPriceSdk.ResourceSKU `json:"machines"`
+		Volumes     map[string]map[string]VolumePrice                                      `json:"volumes"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, snapshot.GeneratedAt, got.GeneratedAt)
+	assert.Equal(t, []string{"eastus"}, got.Regions)
+	assert.Equal(t, 0.192, got.Machines["eastus"]["OnDemand"]["Linux"]["Standard_D4_v5"].RetailPrice)
+	assert.Equal(t, 0.05, got.Machines["eastus"]["Spot"]["Windows"]["Standard_D4_v5"].RetailPrice)
+	assert.Equal(t, VolumePrice{Disk: 135.168}, got.Volumes["eastus"]["P30 LRS"])
+}
@@ -0,0 +1,86 @@
+package aks
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	// uncappedMaxPrice is the max price of a spot scale set that is only evicted for capacity, in which case Azure
+	// caps the price at the on-demand price of the sku.
+	uncappedMaxPrice = -1
+
+	maxPriceSourceScaleSet = "vmss"
+	maxPriceSourceOnDemand = "on_demand"
+)
+
+// spotMaxPrice returns the max price in USD/h set on the billing profile of a spot scale set. The second return value
+// is false for regular scale sets and for spot scale sets without a max price, which are billed up to the on-demand
+// price instead.
+func spotMaxPrice(vmss *armcompute.VirtualMachineScaleSet) (float64, bool) {
+	profile := vmssProfile(vmss)
+	if profile == nil || profile.Priority == nil || *profile.Priority != armcompute.VirtualMachinePriorityTypesSpot {
+		return 0, false
+	}
+	if profile.BillingProfile == nil || profile.BillingProfile.MaxPrice == nil || *profile.BillingProfile.MaxPrice == uncappedMaxPrice {
+		return 0, false
+	}
+	return *profile.BillingProfile.MaxPrice, true
+}
+
+// isSpot returns whether the VMs of a scale set are spot VMs.
+func isSpot(vmss *armcompute.VirtualMachineScaleSet) bool {
+	profile := vmssProfile(vmss)
+	return profile != nil && profile.Priority != nil && *profile.Priority == armcompute.VirtualMachinePriorityTypesSpot
+}
+
+// operatingSystem returns the operating system of the VMs of a scale set, which defaults to Linux.
+func operatingSystem(vmss *armcompute.VirtualMachineScaleSet) MachineOperatingSystem {
+	profile := vmssProfile(vmss)
+	if profile == nil || profile.StorageProfile == nil || profile.StorageProfile.OSDisk == nil || profile.StorageProfile.OSDisk.OSType == nil {
+		return Linux
+	}
+	if *profile.StorageProfile.OSDisk.OSType == armcompute.OperatingSystemTypesWindows {
+		return Windows
+	}
+	return Linux
+}
+
+func vmssProfile(vmss *armcompute.VirtualMachineScaleSet) *armcompute.VirtualMachineScaleSetVMProfile {
+	if vmss == nil || vmss.Properties == nil {
+		return nil
+	}
+	return vmss.Properties.VirtualMachineProfile
+}
+
+// spotPriceMetrics returns the max price and retail spot price metrics of a spot scale set. Nothing is returned for
+// regular scale sets or when the sku of the scale set isn't priced. The max price of a scale set without one is the
+// on-demand price, with max_price_source set to on_demand. Scale sets that can't be priced are recorded in unpriced.
+func spotPriceMetrics(prices *PriceStore, vmss *armcompute.VirtualMachineScaleSet, clusterName string, unpriced *utils.UnpricedMachineTypes) []prometheus.Metric {
+	if !isSpot(vmss) || vmss.Name == nil || vmss.Location == nil || vmss.SKU == nil || vmss.SKU.Name == nil {
+		return nil
+	}
+	region, sku, os := *vmss.Location, *vmss.SKU.Name, operatingSystem(vmss)
+	retailPrice, err := prices.getPrice(region, Spot, os, sku)
+	if err != nil {
+		unpriced.Add(region, sku, err)
+		return nil
+	}
+	maxPrice, ok := spotMaxPrice(vmss)
+	source := maxPriceSourceScaleSet
+	if !ok {
+		maxPrice, err = prices.getPrice(region, OnDemand, os, sku)
+		if err != nil {
+			unpriced.Add(region, sku, err)
+			return nil
+		}
+		source = maxPriceSourceOnDemand
+	}
+	unpriced.Priced()
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(InstanceSpotMaxPriceDesc, prometheus.GaugeValue, maxPrice, *vmss.Name, clusterName, region, sku, source),
+		prometheus.MustNewConstMetric(InstanceSpotRetailPriceDesc, prometheus.GaugeValue, retailPrice, *vmss.Name, clusterName, region, sku),
+	}
+}
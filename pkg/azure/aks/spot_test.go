@@ -0,0 +1,166 @@
+package aks
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func spotScaleSet(maxPrice *float64, osType armcompute.OperatingSystemTypes) *armcompute.VirtualMachineScaleSet {
+	priority := armcompute.VirtualMachinePriorityTypesSpot
+	return &armcompute.VirtualMachineScaleSet{
+		Name:     to.StringPtr("aks-spot-1234-vmss"),
+		Location: to.StringPtr("eastus"),
+		SKU:      &armcompute.SKU{Name: to.StringPtr("Standard_D4_v5")},
+		Tags:     map[string]*string{AksClusterNameTag: to.StringPtr("dev-cluster")},
+		Properties: &armcompute.VirtualMachineScaleSetProperties{
+			VirtualMachineProfile: &armcompute.VirtualMachineScaleSetVMProfile{
+				Priority:       &priority,
+				BillingProfile: &armcompute.BillingProfile{MaxPrice: maxPrice},
+				StorageProfile: &armcompute.VirtualMachineScaleSetStorageProfile{
+					OSDisk: &armcompute.VirtualMachineScaleSetOSDisk{OSType: &osType},
+				},
+			},
+		},
+	}
+}
+
+func Test_spotMaxPrice(t *testing.T) {
+	regular := armcompute.VirtualMachinePriorityTypesRegular
+	for _, tc := range []struct {
+		name          string
+		vmss          *armcompute.VirtualMachineScaleSet
+		expectedValue float64
+		expectedFound bool
+	}{
+		{
+			name: "nil scale set",
+		},
+		{
+			name: "regular scale set",
+			vmss: &armcompute.VirtualMachineScaleSet{
+				Properties: &armcompute.VirtualMachineScaleSetProperties{
+					VirtualMachineProfile: &armcompute.VirtualMachineScaleSetVMProfile{Priority: &regular},
+				},
+			},
+		},
+		{
+			name: "spot without a billing profile",
+			vmss: spotScaleSet(nil, armcompute.OperatingSystemTypesLinux),
+		},
+		{
+			name: "spot capped at the on-demand price",
+			vmss: spotScaleSet(to.Float64Ptr(-1), armcompute.OperatingSystemTypesLinux),
+		},
+		{
+			name:          "spot with a max price",
+			vmss:          spotScaleSet(to.Float64Ptr(0.04), armcompute.OperatingSystemTypesLinux),
+			expectedValue: 0.04,
+			expectedFound: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			value, found := spotMaxPrice(tc.vmss)
+			require.Equal(t, tc.expectedFound, found)
+			require.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
+
+func Test_spotPriceMetrics(t *testing.T) {
+	priceStore := newPricingStore("", nil, testLogger, parentCtx)
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.192},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.05},
+		{ArmRegionName: "eastus", ProductName: "Virtual Machines Dv5 Series Windows", SkuName: "D4 v5 Spot", ArmSkuName: "Standard_D4_v5", RetailPrice: 0.09},
+	} {
+		priceStore.addMachinePrice(item)
+	}
+	for _, tc := range []struct {
+		name     string
+		vmss     *armcompute.VirtualMachineScaleSet
+		expected []*utils.MetricResult
+	}{
+		{
+			name: "regular scale set",
+			vmss: &armcompute.VirtualMachineScaleSet{},
+		},
+		{
+			name: "max price below the retail spot price",
+			vmss: spotScaleSet(to.Float64Ptr(0.04), armcompute.OperatingSystemTypesLinux),
+			expected: []*utils.MetricResult{
+				{
+					FqName:     "cloudcost_azure_aks_spot_max_usd_per_hour",
+					Labels:     utils.LabelMap{"vmss": "aks-spot-1234-vmss", "cluster_name": "dev-cluster", "region": "eastus", "machine_type": "Standard_D4_v5", "max_price_source": "vmss"},
+					Value:      0.04,
+					MetricType: prometheus.GaugeValue,
+				},
+				{
+					FqName:     "cloudcost_azure_aks_spot_retail_usd_per_hour",
+					Labels:     utils.LabelMap{"vmss": "aks-spot-1234-vmss", "cluster_name": "dev-cluster", "region": "eastus", "machine_type": "Standard_D4_v5"},
+					Value:      0.05,
+					MetricType: prometheus.GaugeValue,
+				},
+			},
+		},
+		{
+			name: "no max price is capped at the on-demand price",
+			vmss: spotScaleSet(to.Float64Ptr(-1), armcompute.OperatingSystemTypesLinux),
+			expected: []*utils.MetricResult{
+				{
+					FqName:     "cloudcost_azure_aks_spot_max_usd_per_hour",
+					Labels:     utils.LabelMap{"vmss": "aks-spot-1234-vmss", "cluster_name": "dev-cluster", "region": "eastus", "machine_type": "Standard_D4_v5", "max_price_source": "on_demand"},
+					Value:      0.192,
+					MetricType: prometheus.GaugeValue,
+				},
+				{
+					FqName:     "cloudcost_azure_aks_spot_retail_usd_per_hour",
+					Labels:     utils.LabelMap{"vmss": "aks-spot-1234-vmss", "cluster_name": "dev-cluster", "region": "eastus", "machine_type": "Standard_D4_v5"},
+					Value:      0.05,
+					MetricType: prometheus.GaugeValue,
+				},
+			},
+		},
+		{
+			name: "on-demand price not found",
+			vmss: spotScaleSet(nil, armcompute.OperatingSystemTypesWindows),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []*utils.MetricResult
+			for _, m := range spotPriceMetrics(priceStore, tc.vmss, ClusterNameFromVmss(tc.vmss, nil), NewUnpricedMachineTypes("test")) {
+				got = append(got, utils.ReadMetrics(m))
+			}
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func Test_spotPriceMetrics_Unpriced(t *testing.T) {
+	priceStore := newPricingStore("", nil, testLogger, parentCtx)
+	unpriced := NewUnpricedMachineTypes("test")
+	assert.Empty(t, spotPriceMetrics(priceStore, spotScaleSet(nil, armcompute.OperatingSystemTypesLinux), "dev-cluster", unpriced))
+
+	ch := make(chan prometheus.Metric, 2)
+	unpriced.Emit(ch)
+	close(ch)
+	require.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_azure_unpriced_machine_type_info",
+		Labels:     utils.LabelMap{"collector": "test", "region": "eastus", "machine_type": "Standard_D4_v5", "reason": "region_not_found"},
+		Value:      1,
+		MetricType: prometheus.GaugeValue,
+	}, utils.ReadMetrics(<-ch))
+	require.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_exporter_pricing_coverage_ratio",
+		Labels:     utils.LabelMap{"provider": "azure", "collector": "test"},
+		Value:      0,
+		MetricType: prometheus.GaugeValue,
+	}, utils.ReadMetrics(<-ch))
+}
@@ -0,0 +1,36 @@
+package aks
+
+import (
+	"errors"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var (
+	// UnpricedResourcesTotal is incremented by the aks collector and registered once by the azure provider.
+	UnpricedResourcesTotal = utils.NewUnpricedResourcesTotal("azure")
+	// UnpricedMachineTypeInfoDesc lists the machine types that could not be priced during the last collection.
+	UnpricedMachineTypeInfoDesc = utils.NewUnpricedMachineTypeInfoDesc("azure")
+	// MalformedPriceEntriesTotal counts the retail prices skipped by the price stores because of their unit, currency
+	// or price. It's registered once by the azure provider.
+	MalformedPriceEntriesTotal = utils.NewMalformedPriceEntriesTotal("azure")
+)
+
+// UnpricedReason maps an error returned by the price stores to a low cardinality reason label.
+func UnpricedReason(err error) string {
+	switch {
+	case errors.Is(err, ErrRegionNotFound):
+		return "region_not_found"
+	case errors.Is(err, ErrSkuNotFound):
+		return "sku_not_found"
+	case errors.Is(err, ErrUnknownDiskTier):
+		return "disk_tier_not_found"
+	default:
+		return "unknown"
+	}
+}
+
+// NewUnpricedMachineTypes returns a tracker of the machine types a collector couldn't price during a collection.
+func NewUnpricedMachineTypes(collector string) *utils.UnpricedMachineTypes {
+	return utils.NewUnpricedMachineTypes("azure", collector, UnpricedMachineTypeInfoDesc, UnpricedResourcesTotal, UnpricedReason)
+}
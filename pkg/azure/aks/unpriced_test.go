@@ -0,0 +1,24 @@
+package aks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnpricedReason(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"region not found":      {err: ErrRegionNotFound, want: "region_not_found"},
+		"wrapped sku not found": {err: fmt.Errorf("%w: P10 LRS", ErrSkuNotFound), want: "sku_not_found"},
+		"other error":           {err: ErrPageAdvanceFailure, want: "unknown"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, UnpricedReason(tt.err))
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/prometheus/client_golang/prometheus"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	managedDiskProductSuffix = "Managed Disks"
+	diskMeterSuffix          = " Disk"
+	burstEnablementMeter     = "Burst Enablement"
+	monthlyUnitOfMeasure     = "1/Month"
+	// maxVolumeMonthlyPrice is the highest sane monthly price of a managed disk in USD, well above the price of the
+	// largest disks.
+	maxVolumeMonthlyPrice = 20000.0
+)
+
+// VolumePrice holds the monthly retail prices of a single managed disk sku, eg "P10 ZRS".
+type VolumePrice struct {
+	// Disk is the provisioned capacity price of the disk per month.
+	Disk float64 `json:"disk"`
+	// BurstEnablement is the flat monthly fee charged when on-demand bursting is enabled on the disk.
+	BurstEnablement float64 `json:"burst_enablement"`
+}
+
+// VolumePriceBySku is keyed by the full sku name including redundancy, eg "P10 LRS" or "P10 ZRS".
+// LRS and ZRS disks carry different meters, so the redundancy can't be dropped from the key.
+type VolumePriceBySku map[string]*VolumePrice
+
+type VolumePriceStore struct {
+	lock              *sync.RWMutex
+	logger            *slog.Logger
+	context           context.Context
+	retailPriceClient *retailPriceSdk.RetailPricesClient
+
+	RegionMap map[string]VolumePriceBySku
+	fetched   *fetchedRegions
+	// shared fetches the prices of the store along with the other stores it feeds, it's nil when the store fetches
+	// its prices on its own.
+	shared *RetailPrices
+}
+
+// NewVolumePriceStore creates an empty VolumePriceStore. The prices of a region are fetched by EnsureRegions or on its
+// first lookup, rather than the prices of every region at startup.
+func NewVolumePriceStore(priceClient *retailPriceSdk.RetailPricesClient, parentLogger *slog.Logger, parentContext context.Context) *VolumePriceStore {
+	return newVolumePriceStore(priceClient, parentLogger, parentContext)
+}
+
+// newVolumePriceStore creates an empty VolumePriceStore, leaving it up to the caller to populate it.
+func newVolumePriceStore(priceClient *retailPriceSdk.RetailPricesClient, parentLogger *slog.Logger, parentContext context.Context) *VolumePriceStore {
+	return &VolumePriceStore{
+		lock:              &sync.RWMutex{},
+		logger:            parentLogger.With("subsystem", "volumePriceMap"),
+		context:           parentContext,
+		retailPriceClient: priceClient,
+
+		RegionMap: make(map[string]VolumePriceBySku),
+		fetched:   newFetchedRegions(),
+	}
+}
+
+func (p *VolumePriceStore) serviceFilter() string {
+	return fmt.Sprintf(`serviceName eq 'Storage' and contains(productName, '%s')`, managedDiskProductSuffix)
+}
+
+func (p *VolumePriceStore) buildQueryFilter(locationList []string) string {
+	baseFilter := fmt.Sprintf(`serviceName eq 'Storage' and priceType eq 'Consumption' and contains(productName, '%s')`, managedDiskProductSuffix)
+	if len(locationList) == 0 {
+		return baseFilter
+	}
+	return fmt.Sprintf(`%s and (%s)`, baseFilter, regionFilter(locationList))
+}
+
+func (p *VolumePriceStore) buildListOptions(locationList []string) *retailPriceSdk.RetailPricesClientListOptions {
+	return &retailPriceSdk.RetailPricesClientListOptions{
+		APIVersion:  to.StringPtr(AZ_API_VERSION),
+		Filter:      to.StringPtr(p.buildQueryFilter(locationList)),
+		MeterRegion: to.StringPtr(`'primary'`),
+	}
+}
+
+func (p *VolumePriceStore) PopulateVolumePriceStore(locationList []string) error {
+	startTime := time.Now()
+	p.logger.LogAttrs(p.context, slog.LevelInfo, "populating volume price map")
+
+	// The prices are listed before taking the lock so that lookups of the regions that were already fetched don't
+	// wait on the API
+	items, err := listRetailPrices(p.context, p.retailPriceClient, p.buildListOptions(locationList), locationList)
+	if err != nil {
+		p.logger.LogAttrs(p.context, slog.LevelError, "error paging", slog.Any("regions", locationList), slog.String("err", err.Error()))
+		return ErrPageAdvanceFailure
+	}
+	p.addRetailPrices(items)
+	p.fetched.add(locationList)
+
+	p.logger.LogAttrs(p.context, slog.LevelInfo, "volume price map populated", slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
+
+// addRetailPrices files the managed disk prices among the items of a fetch, see addVolumePrice.
+func (p *VolumePriceStore) addRetailPrices(items []retailPriceSdk.ResourceSKU) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, v := range items {
+		p.addVolumePrice(v)
+	}
+}
+
+// EnsureRegions fetches the prices of the regions that weren't fetched yet or are stale in a single query. Regions
+// whose last fetch failed are skipped until their backoff expired. It's a no-op when no region is missing or when the
+// store has no client. A store fed by RetailPrices fetches the regions of every store it feeds.
+func (p *VolumePriceStore) EnsureRegions(regions []string) error {
+	if p.shared != nil {
+		return p.shared.EnsureRegions(regions)
+	}
+	if p.retailPriceClient == nil {
+		return nil
+	}
+	return p.fetched.ensure(regions, p.PopulateVolumePriceStore)
+}
+
+// addVolumePrice files a single retail price item under its region and full sku name.
+// Only the monthly disk capacity meter and the bursting enablement meter are kept, transaction based meters are ignored.
+// The caller must hold the write lock.
+func (p *VolumePriceStore) addVolumePrice(v retailPriceSdk.ResourceSKU) {
+	if !strings.HasSuffix(v.ProductName, managedDiskProductSuffix) || v.UnitOfMeasure != monthlyUnitOfMeasure {
+		return
+	}
+	regionName := v.ArmRegionName
+	if regionName == "" {
+		p.logger.LogAttrs(p.context, slog.LevelInfo, "region name for volume price not found", slog.String("sku", v.SkuName))
+		return
+	}
+
+	// The unit is already checked above, as the transaction meters of the managed disks are listed along their
+	// monthly meters
+	if reason := validateRetailPrice(v, monthlyUnitOfMeasure, maxVolumeMonthlyPrice); reason != "" {
+		p.logger.LogAttrs(p.context, slog.LevelWarn, "skipping malformed volume price", slog.String("sku", v.SkuName), slog.String("reason", reason))
+		MalformedPriceEntriesTotal.WithLabelValues("volume", reason).Inc()
+		return
+	}
+
+	if _, ok := p.RegionMap[regionName]; !ok {
+		p.RegionMap[regionName] = make(VolumePriceBySku)
+	}
+	price, ok := p.RegionMap[regionName][v.SkuName]
+	if !ok {
+		price = &VolumePrice{}
+	}
+
+	switch {
+	case strings.Contains(v.MeterName, burstEnablementMeter):
+		price.BurstEnablement = v.RetailPrice
+	case strings.HasSuffix(v.MeterName, diskMeterSuffix):
+		price.Disk = v.RetailPrice
+	default:
+		return
+	}
+	p.RegionMap[regionName][v.SkuName] = price
+}
+
+// GetVolumePrice returns the hourly price in USD for a disk of the given sku in a region.
+// skuName must include the redundancy, see VolumeSkuName. When burstingEnabled is set the flat
+// bursting enablement fee is added to the capacity price.
+func (p *VolumePriceStore) GetVolumePrice(region string, skuName string, burstingEnabled bool) (float64, error) {
+	if err := p.EnsureRegions([]string{region}); err != nil {
+		return 0, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	prices, ok := p.RegionMap[region]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrRegionNotFound, region)
+	}
+	price, ok := prices[skuName]
+	if !ok || price.Disk == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrSkuNotFound, skuName)
+	}
+
+	monthly := price.Disk
+	if burstingEnabled {
+		monthly += price.BurstEnablement
+	}
+	return monthly / utils.HoursInMonth, nil
+}
+
+// VolumeSkuName builds the retail price sku name for a disk from its performance tier (eg "P10") and
+// its storage account type (eg "Premium_ZRS"), resulting in "P10 ZRS". The second return value is false when the
+// storage account type carries no redundancy, eg "Premium", as the sku name can't be built without it.
+func VolumeSkuName(tier string, storageAccountType string) (string, bool) {
+	i := strings.LastIndex(storageAccountType, "_")
+	if i < 0 || i == len(storageAccountType)-1 {
+		return "", false
+	}
+	return fmt.Sprintf("%s %s", tier, storageAccountType[i+1:]), true
+}
+
+// diskTierSizesGiB are the provisioned capacities of the managed disk performance tiers, which are shared by the
+// Premium SSD (P), Standard SSD (E) and Standard HDD (S) tiers of the same number.
+var diskTierSizesGiB = map[string]float64{
+	"1": 4, "2": 8, "3": 16, "4": 32, "6": 64, "10": 128, "15": 256, "20": 512,
+	"30": 1024, "40": 2048, "50": 4096, "60": 8192, "70": 16384, "80": 32767,
+}
+
+// diskTierAccountTypes maps the prefix of a performance tier to the storage account type of its disks.
+var diskTierAccountTypes = map[string]string{
+	"P": "Premium",
+	"E": "StandardSSD",
+	"S": "Standard",
+}
+
+// storageClassPrice returns the storage account type, eg Premium_LRS, and the price in USD/(GiB*h) of a disk sku name
+// such as "P10 LRS". The second return value is false for skus that aren't a known performance tier.
+func storageClassPrice(skuName string, price *VolumePrice) (string, float64, bool) {
+	tier, redundancy, ok := strings.Cut(skuName, " ")
+	if !ok || len(tier) < 2 || price == nil || price.Disk == 0 {
+		return "", 0, false
+	}
+	accountType, ok := diskTierAccountTypes[tier[:1]]
+	if !ok {
+		return "", 0, false
+	}
+	size, ok := diskTierSizesGiB[tier[1:]]
+	if !ok {
+		return "", 0, false
+	}
+	return accountType + "_" + redundancy, price.Disk / size / utils.HoursInMonth, true
+}
+
+// StorageClassMetrics returns a cloudcost_azure_storage_class_usd_per_gib_hour series per region and performance
+// tier of the fetched regions, sorted by region and sku. Managed disks are billed per tier, so the price per GiB is
+// the price of the tier divided by its capacity. It's safe to call on a nil store.
+func (p *VolumePriceStore) StorageClassMetrics() []prometheus.Metric {
+	if p == nil {
+		return nil
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	regions := make([]string, 0, len(p.RegionMap))
+	for region := range p.RegionMap {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	var metrics []prometheus.Metric
+	for _, region := range regions {
+		skus := make([]string, 0, len(p.RegionMap[region]))
+		for sku := range p.RegionMap[region] {
+			skus = append(skus, sku)
+		}
+		sort.Strings(skus)
+		for _, sku := range skus {
+			storageClass, price, ok := storageClassPrice(sku, p.RegionMap[region][sku])
+			if !ok {
+				continue
+			}
+			tier, _, _ := strings.Cut(sku, " ")
+			metrics = append(metrics, prometheus.MustNewConstMetric(StorageClassHourlyPriceDesc, prometheus.GaugeValue, price, storageClass, region, tier))
+		}
+	}
+	return metrics
+}
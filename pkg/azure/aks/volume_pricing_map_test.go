@@ -0,0 +1,212 @@
+package aks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestVolumeBuildQueryFilter(t *testing.T) {
+	p := VolumePriceStore{}
+	testTable := map[string]struct {
+		locationList   []string
+		expectedFilter string
+	}{
+		"no location list": {
+			locationList:   nil,
+			expectedFilter: `serviceName eq 'Storage' and priceType eq 'Consumption' and contains(productName, 'Managed Disks')`,
+		},
+		"location list with many items": {
+			locationList:   []string{"eastus", "westeurope"},
+			expectedFilter: `serviceName eq 'Storage' and priceType eq 'Consumption' and contains(productName, 'Managed Disks') and (armRegionName eq 'eastus' or armRegionName eq 'westeurope')`,
+		},
+	}
+
+	for name, test := range testTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expectedFilter, p.buildQueryFilter(test.locationList))
+		})
+	}
+}
+
+func TestVolumeSkuName(t *testing.T) {
+	testTable := map[string]struct {
+		tier               string
+		storageAccountType string
+		expected           string
+		expectedOk         bool
+	}{
+		"premium lrs": {
+			tier:               "P10",
+			storageAccountType: "Premium_LRS",
+			expected:           "P10 LRS",
+			expectedOk:         true,
+		},
+		"premium zrs": {
+			tier:               "P10",
+			storageAccountType: "Premium_ZRS",
+			expected:           "P10 ZRS",
+			expectedOk:         true,
+		},
+		"standard ssd zrs": {
+			tier:               "E30",
+			storageAccountType: "StandardSSD_ZRS",
+			expected:           "E30 ZRS",
+			expectedOk:         true,
+		},
+		"no redundancy": {
+			tier:               "P10",
+			storageAccountType: "Premium",
+		},
+		"empty redundancy": {
+			tier:               "P10",
+			storageAccountType: "Premium_",
+		},
+	}
+
+	for name, test := range testTable {
+		t.Run(name, func(t *testing.T) {
+			skuName, ok := VolumeSkuName(test.tier, test.storageAccountType)
+			assert.Equal(t, test.expectedOk, ok)
+			assert.Equal(t, test.expected, skuName)
+		})
+	}
+}
+
+func TestGetVolumePrice(t *testing.T) {
+	p := &VolumePriceStore{
+		lock:      &sync.RWMutex{},
+		logger:    testLogger,
+		context:   parentCtx,
+		RegionMap: make(map[string]VolumePriceBySku),
+	}
+	for _, item := range []retailPriceSdk.ResourceSKU{
+		{ArmRegionName: "eastus", ProductName: "Premium SSD Managed Disks", SkuName: "P30 LRS", MeterName: "P30 LRS Disk", UnitOfMeasure: "1/Month", RetailPrice: 135.168},
+		{ArmRegionName: "eastus", ProductName: "Premium SSD Managed Disks", SkuName: "P30 ZRS", MeterName: "P30 ZRS Disk", UnitOfMeasure: "1/Month", RetailPrice: 202.752},
+		{ArmRegionName: "eastus", ProductName: "Premium SSD Managed Disks", SkuName: "P30 LRS", MeterName: "P30 LRS Burst Enablement", UnitOfMeasure: "1/Month", RetailPrice: 33.792},
+		{ArmRegionName: "eastus", ProductName: "Premium SSD Managed Disks", SkuName: "P30 LRS", MeterName: "P30 LRS Burst Transactions", UnitOfMeasure: "10K", RetailPrice: 0.005},
+		{ArmRegionName: "", ProductName: "Premium SSD Managed Disks", SkuName: "P30 LRS", MeterName: "P30 LRS Disk", UnitOfMeasure: "1/Month", RetailPrice: 1},
+	} {
+		p.addVolumePrice(item)
+	}
+
+	testTable := map[string]struct {
+		region          string
+		skuName         string
+		burstingEnabled bool
+		expectedPrice   float64
+		expectedErr     error
+	}{
+		"lrs disk": {
+			region:        "eastus",
+			skuName:       "P30 LRS",
+			expectedPrice: 135.168 / utils.HoursInMonth,
+		},
+		"zrs disk is not priced as lrs": {
+			region:        "eastus",
+			skuName:       "P30 ZRS",
+			expectedPrice: 202.752 / utils.HoursInMonth,
+		},
+		"bursting adds the enablement fee": {
+			region:          "eastus",
+			skuName:         "P30 LRS",
+			burstingEnabled: true,
+			expectedPrice:   (135.168 + 33.792) / utils.HoursInMonth,
+		},
+		"missing region": {
+			region:      "westus",
+			skuName:     "P30 LRS",
+			expectedErr: ErrRegionNotFound,
+		},
+		"missing sku": {
+			region:      "eastus",
+			skuName:     "P40 LRS",
+			expectedErr: ErrSkuNotFound,
+		},
+	}
+
+	for name, test := range testTable {
+		t.Run(name, func(t *testing.T) {
+			price, err := p.GetVolumePrice(test.region, test.skuName, test.burstingEnabled)
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, test.expectedPrice, price, 1e-9)
+		})
+	}
+}
+
+func TestStorageClassPrice(t *testing.T) {
+	testTable := map[string]struct {
+		skuName          string
+		price            *VolumePrice
+		wantStorageClass string
+		wantPrice        float64
+		wantOk           bool
+	}{
+		"premium ssd": {
+			skuName:          "P30 LRS",
+			price:            &VolumePrice{Disk: 135.168},
+			wantStorageClass: "Premium_LRS",
+			wantPrice:        135.168 / 1024 / utils.HoursInMonth,
+			wantOk:           true,
+		},
+		"standard ssd zrs": {
+			skuName:          "E10 ZRS",
+			price:            &VolumePrice{Disk: 12.8},
+			wantStorageClass: "StandardSSD_ZRS",
+			wantPrice:        12.8 / 128 / utils.HoursInMonth,
+			wantOk:           true,
+		},
+		"standard hdd": {
+			skuName:          "S4 LRS",
+			price:            &VolumePrice{Disk: 1.54},
+			wantStorageClass: "Standard_LRS",
+			wantPrice:        1.54 / 32 / utils.HoursInMonth,
+			wantOk:           true,
+		},
+		"unknown tier": {
+			skuName: "Ultra LRS",
+			price:   &VolumePrice{Disk: 1},
+		},
+		"no redundancy": {
+			skuName: "P30",
+			price:   &VolumePrice{Disk: 1},
+		},
+		"only a bursting fee": {
+			skuName: "P30 LRS",
+			price:   &VolumePrice{BurstEnablement: 1},
+		},
+	}
+	for name, test := range testTable {
+		t.Run(name, func(t *testing.T) {
+			storageClass, price, ok := storageClassPrice(test.skuName, test.price)
+			assert.Equal(t, test.wantOk, ok)
+			assert.Equal(t, test.wantStorageClass, storageClass)
+			assert.InDelta(t, test.wantPrice, price, 1e-12)
+		})
+	}
+}
+
+func TestVolumePriceStore_StorageClassMetrics(t *testing.T) {
+	assert.Empty(t, (*VolumePriceStore)(nil).StorageClassMetrics())
+
+	p := newVolumePriceStore(nil, testLogger, parentCtx)
+	p.RegionMap["westeurope"] = VolumePriceBySku{"P10 LRS": {Disk: 19.71}, "Ultra LRS": {Disk: 1}}
+	p.RegionMap["eastus"] = VolumePriceBySku{"E10 ZRS": {Disk: 12.8}}
+
+	metrics := p.StorageClassMetrics()
+	require.Len(t, metrics, 2)
+	first, second := utils.ReadMetrics(metrics[0]), utils.ReadMetrics(metrics[1])
+	assert.Equal(t, "cloudcost_azure_storage_class_usd_per_gib_hour", first.FqName)
+	assert.Equal(t, utils.LabelMap{"storage_class": "StandardSSD_ZRS", "region": "eastus", "disk_tier": "E10"}, first.Labels)
+	assert.Equal(t, utils.LabelMap{"storage_class": "Premium_LRS", "region": "westeurope", "disk_tier": "P10"}, second.Labels)
+	assert.InDelta(t, 19.71/128/utils.HoursInMonth, second.Value, 1e-12)
+}
@@ -0,0 +1,203 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+)
+
+const (
+	// AuthDefault uses the DefaultAzureCredential chain: environment, workload identity, managed identity and the
+	// Azure CLI.
+	AuthDefault = "default"
+	// AuthWorkloadIdentity exchanges the service account token of the pod for a Microsoft Entra token of a federated
+	// application or user-assigned managed identity.
+	AuthWorkloadIdentity = "workload-identity"
+	// AuthVault reads the client secret of an application from a Vault secret, eg of the KV or Azure secrets engines,
+	// and rebuilds the credential when the secret is rotated.
+	AuthVault = "vault"
+
+	// tenantIDEnv, clientIDEnv and federatedTokenFileEnv are injected by the Azure Workload Identity webhook into pods
+	// labeled azure.workload.identity/use=true.
+	tenantIDEnv           = "AZURE_TENANT_ID"
+	clientIDEnv           = "AZURE_CLIENT_ID"
+	federatedTokenFileEnv = "AZURE_FEDERATED_TOKEN_FILE"
+)
+
+var (
+	ErrUnknownAuth          = errors.New("unknown azure auth")
+	ErrMissingTenantID      = errors.New("workload identity requires a tenant ID, set -azure.tenant-id or check that the workload identity webhook mutated the pod")
+	ErrMissingClientID      = errors.New("workload identity requires a client ID, set -azure.client-id or annotate the service account with azure.workload.identity/client-id")
+	ErrMissingTokenFile     = errors.New("workload identity requires a federated token file, set -azure.federated-token-file or label the pod with azure.workload.identity/use=true")
+	ErrUnreadableTokenFile  = errors.New("federated token file is not readable")
+	ErrIncompatibleAuthFlag = errors.New("tenant ID, client ID and federated token file are only used with workload identity auth")
+	ErrMissingVaultPath     = errors.New("vault auth requires the path of the secret, set -azure.vault-path")
+	ErrIncompatibleVault    = errors.New("the vault path is only used with vault auth, and the federated token file isn't used with it")
+	ErrInvalidVaultSecret   = errors.New("vault secret must have a client_secret, and a client_id unless -azure.client-id is set")
+)
+
+// AuthConfig selects how the Azure clients authenticate. DefaultAzureCredential moves on to managed identity when
+// workload identity isn't configured, which surfaces a broken setup as the identity of the node, so workload identity
+// can be required explicitly instead.
+type AuthConfig struct {
+	// Mode is AuthDefault, AuthWorkloadIdentity or AuthVault. An empty Mode is AuthDefault.
+	Mode string
+	// TenantID defaults to AZURE_TENANT_ID.
+	TenantID string
+	// ClientID defaults to AZURE_CLIENT_ID. With AuthVault, the client_id of the secret takes precedence.
+	ClientID string
+	// FederatedTokenFile defaults to AZURE_FEDERATED_TOKEN_FILE.
+	FederatedTokenFile string
+	// VaultPath is the path of the secret read with AuthVault, eg azure/creds/cloudcost-exporter. The secret has the
+	// client_secret and optionally client_id keys of the Azure secrets engine.
+	VaultPath string
+	// Vault is how to reach Vault with AuthVault.
+	Vault secrets.VaultConfig
+}
+
+// resolve validates the configuration and fills in the defaults from the environment.
+func (a AuthConfig) resolve() (AuthConfig, error) {
+	switch a.Mode {
+	case "", AuthDefault:
+		if a.TenantID != "" || a.ClientID != "" || a.FederatedTokenFile != "" {
+			return a, ErrIncompatibleAuthFlag
+		}
+		if a.VaultPath != "" {
+			return a, ErrIncompatibleVault
+		}
+		a.Mode = AuthDefault
+		return a, nil
+	case AuthWorkloadIdentity:
+		if a.VaultPath != "" {
+			return a, ErrIncompatibleVault
+		}
+	case AuthVault:
+		if a.FederatedTokenFile != "" {
+			return a, ErrIncompatibleVault
+		}
+		if a.VaultPath == "" {
+			return a, ErrMissingVaultPath
+		}
+	default:
+		return a, fmt.Errorf("%w %q, expected %s, %s or %s", ErrUnknownAuth, a.Mode, AuthDefault, AuthWorkloadIdentity, AuthVault)
+	}
+	if a.TenantID == "" {
+		a.TenantID = os.Getenv(tenantIDEnv)
+	}
+	if a.TenantID == "" {
+		return a, ErrMissingTenantID
+	}
+	if a.Mode == AuthVault {
+		// The client ID is usually part of the secret, so it's checked once the secret is read
+		if a.ClientID == "" {
+			a.ClientID = os.Getenv(clientIDEnv)
+		}
+		return a, nil
+	}
+	if a.ClientID == "" {
+		a.ClientID = os.Getenv(clientIDEnv)
+	}
+	if a.ClientID == "" {
+		return a, ErrMissingClientID
+	}
+	if a.FederatedTokenFile == "" {
+		a.FederatedTokenFile = os.Getenv(federatedTokenFileEnv)
+	}
+	if a.FederatedTokenFile == "" {
+		return a, ErrMissingTokenFile
+	}
+	// The token is only read when a Microsoft Entra token is requested, so it's checked upfront to fail at startup
+	f, err := os.Open(a.FederatedTokenFile)
+	if err != nil {
+		return a, fmt.Errorf("%w: %w", ErrUnreadableTokenFile, err)
+	}
+	_ = f.Close()
+	return a, nil
+}
+
+// credential returns the credential of every client. The Vault secret is read right away so that a missing or
+// malformed secret fails at startup.
+func (a AuthConfig) credential(ctx context.Context, options policy.ClientOptions) (azcore.TokenCredential, error) {
+	switch a.Mode {
+	case AuthWorkloadIdentity:
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions: options,
+			TenantID:      a.TenantID,
+			ClientID:      a.ClientID,
+			TokenFilePath: a.FederatedTokenFile,
+		})
+	case AuthVault:
+		secret, err := a.Vault.Rotating(ctx, a.VaultPath)
+		if err != nil {
+			return nil, err
+		}
+		credential := &vaultCredential{secret: secret, tenantID: a.TenantID, clientID: a.ClientID, options: options}
+		if _, err := credential.current(ctx); err != nil {
+			return nil, err
+		}
+		return credential, nil
+	}
+	return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: options})
+}
+
+// vaultCredential authenticates with the client secret of a Vault secret. The client secret credential is rebuilt
+// when the secret is rotated, the tokens it already issued are used until they expire.
+type vaultCredential struct {
+	secret   *secrets.Rotating
+	tenantID string
+	clientID string
+	options  policy.ClientOptions
+
+	m          sync.Mutex
+	version    uint64
+	credential azcore.TokenCredential
+}
+
+// GetToken satisfies azcore.TokenCredential.
+func (v *vaultCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	credential, err := v.current(ctx)
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	return credential.GetToken(ctx, options)
+}
+
+// current returns the credential of the current version of the secret.
+func (v *vaultCredential) current(ctx context.Context) (azcore.TokenCredential, error) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	secret, version := v.secret.Get(ctx)
+	if v.credential != nil && version == v.version {
+		return v.credential, nil
+	}
+	clientID := secret["client_id"]
+	if clientID == "" {
+		clientID = v.clientID
+	}
+	var credential azcore.TokenCredential
+	err := ErrInvalidVaultSecret
+	if clientID != "" && secret["client_secret"] != "" {
+		credential, err = azidentity.NewClientSecretCredential(v.tenantID, clientID, secret["client_secret"], &azidentity.ClientSecretCredentialOptions{ClientOptions: v.options})
+	}
+	if err != nil {
+		if v.credential == nil {
+			return nil, err
+		}
+		// The previous client secret is usually still valid for a while after a rotation
+		log.Printf("keeping the previous azure credential: %s", err)
+		v.version = version
+		return v.credential, nil
+	}
+	v.credential = credential
+	v.version = version
+	return credential, nil
+}
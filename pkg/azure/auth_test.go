@@ -0,0 +1,164 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+)
+
+func Test_AuthConfigResolve(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+	webhookEnv := map[string]string{
+		tenantIDEnv:           "tenant",
+		clientIDEnv:           "client",
+		federatedTokenFileEnv: tokenFile,
+	}
+
+	for _, tc := range []struct {
+		name          string
+		auth          AuthConfig
+		env           map[string]string
+		expected      AuthConfig
+		expectedError error
+	}{
+		{
+			name:     "empty mode is the default credential",
+			expected: AuthConfig{Mode: AuthDefault},
+		},
+		{
+			name:          "workload identity flags without workload identity",
+			auth:          AuthConfig{ClientID: "client"},
+			expectedError: ErrIncompatibleAuthFlag,
+		},
+		{
+			name:          "unknown mode",
+			auth:          AuthConfig{Mode: "managed-identity"},
+			expectedError: ErrUnknownAuth,
+		},
+		{
+			name:     "workload identity from the webhook",
+			auth:     AuthConfig{Mode: AuthWorkloadIdentity},
+			env:      webhookEnv,
+			expected: AuthConfig{Mode: AuthWorkloadIdentity, TenantID: "tenant", ClientID: "client", FederatedTokenFile: tokenFile},
+		},
+		{
+			name:     "flags take precedence over the webhook",
+			auth:     AuthConfig{Mode: AuthWorkloadIdentity, ClientID: "other-client"},
+			env:      webhookEnv,
+			expected: AuthConfig{Mode: AuthWorkloadIdentity, TenantID: "tenant", ClientID: "other-client", FederatedTokenFile: tokenFile},
+		},
+		{
+			name:          "missing tenant",
+			auth:          AuthConfig{Mode: AuthWorkloadIdentity, ClientID: "client", FederatedTokenFile: tokenFile},
+			expectedError: ErrMissingTenantID,
+		},
+		{
+			name:          "missing client",
+			auth:          AuthConfig{Mode: AuthWorkloadIdentity, TenantID: "tenant", FederatedTokenFile: tokenFile},
+			expectedError: ErrMissingClientID,
+		},
+		{
+			name:          "missing token file",
+			auth:          AuthConfig{Mode: AuthWorkloadIdentity, TenantID: "tenant", ClientID: "client"},
+			expectedError: ErrMissingTokenFile,
+		},
+		{
+			name:     "vault with the client ID of the secret",
+			auth:     AuthConfig{Mode: AuthVault, TenantID: "tenant", VaultPath: "azure/creds/cloudcost-exporter"},
+			expected: AuthConfig{Mode: AuthVault, TenantID: "tenant", VaultPath: "azure/creds/cloudcost-exporter"},
+		},
+		{
+			name:     "vault from the environment",
+			auth:     AuthConfig{Mode: AuthVault, VaultPath: "azure/creds/cloudcost-exporter"},
+			env:      webhookEnv,
+			expected: AuthConfig{Mode: AuthVault, TenantID: "tenant", ClientID: "client", VaultPath: "azure/creds/cloudcost-exporter"},
+		},
+		{
+			name:          "vault without a path",
+			auth:          AuthConfig{Mode: AuthVault, TenantID: "tenant"},
+			expectedError: ErrMissingVaultPath,
+		},
+		{
+			name:          "vault without a tenant",
+			auth:          AuthConfig{Mode: AuthVault, VaultPath: "azure/creds/cloudcost-exporter"},
+			expectedError: ErrMissingTenantID,
+		},
+		{
+			name:          "vault with a federated token file",
+			auth:          AuthConfig{Mode: AuthVault, TenantID: "tenant", VaultPath: "azure/creds/cloudcost-exporter", FederatedTokenFile: tokenFile},
+			expectedError: ErrIncompatibleVault,
+		},
+		{
+			name:          "vault path without vault",
+			auth:          AuthConfig{VaultPath: "azure/creds/cloudcost-exporter"},
+			expectedError: ErrIncompatibleVault,
+		},
+		{
+			name:          "unreadable token file",
+			auth:          AuthConfig{Mode: AuthWorkloadIdentity, TenantID: "tenant", ClientID: "client", FederatedTokenFile: filepath.Join(t.TempDir(), "missing")},
+			expectedError: ErrUnreadableTokenFile,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{tenantIDEnv, clientIDEnv, federatedTokenFileEnv} {
+				t.Setenv(name, tc.env[name])
+			}
+			auth, err := tc.auth.resolve()
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, auth)
+		})
+	}
+}
+
+func Test_VaultCredentialRotation(t *testing.T) {
+	body := `{"data": {"client_id": "client", "client_secret": "first"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_TOKEN", "token")
+	auth := AuthConfig{
+		Mode:      AuthVault,
+		TenantID:  "tenant",
+		VaultPath: "azure/creds/cloudcost-exporter",
+		// Every read of the secret fetches it again
+		Vault: secrets.VaultConfig{Address: server.URL, RefreshInterval: time.Nanosecond},
+	}
+	ctx := context.Background()
+	creds, err := auth.credential(ctx, policy.ClientOptions{})
+	require.NoError(t, err)
+	vault := creds.(*vaultCredential)
+	first, err := vault.current(ctx)
+	require.NoError(t, err)
+
+	same, err := vault.current(ctx)
+	require.NoError(t, err)
+	require.Same(t, first, same, "an unchanged secret keeps the credential")
+
+	body = `{"data": {"client_id": "client", "client_secret": "rotated"}}`
+	rotated, err := vault.current(ctx)
+	require.NoError(t, err)
+	require.NotSame(t, first, rotated, "a rotated secret rebuilds the credential")
+
+	body = `{"data": {"client_id": "client"}}`
+	kept, err := vault.current(ctx)
+	require.NoError(t, err)
+	require.Same(t, rotated, kept, "an invalid secret keeps the previous credential")
+
+	_, err = auth.credential(ctx, policy.ClientOptions{})
+	require.ErrorIs(t, err, ErrInvalidVaultSecret)
+}
@@ -3,16 +3,30 @@ package azure
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/grafana/cloudcost-exporter/pkg/azure/aks"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/loganalytics"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/managementgroups"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/messaging"
+	"github.com/grafana/cloudcost-exporter/pkg/azure/reservations"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 
 	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
 )
@@ -23,8 +37,16 @@ const (
 
 var (
 	InvalidSubscriptionId = errors.New("subscription id was invalid")
+	ErrUnknownCloud       = errors.New("unknown azure cloud")
 )
 
+// clouds are the Azure clouds that can be selected by name, see CloudConfiguration.
+var clouds = map[string]cloud.Configuration{
+	"public":       cloud.AzurePublic,
+	"china":        cloud.AzureChina,
+	"usgovernment": cloud.AzureGovernment,
+}
+
 var (
 	collectorDurationDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcost_exporter.ExporterName, "collector", "last_scrape_duration_seconds"),
@@ -89,19 +111,80 @@ type Azure struct {
 	logger  *slog.Logger
 
 	subscriptionId string
-	azCredentials  *azidentity.DefaultAzureCredential
+	azCredentials  azcore.TokenCredential
 
 	collectorTimeout time.Duration
 	collectors       []provider.Collector
+	summary          provider.CollectionSummary
+	watchdog         provider.Watchdog
+	inFlight         provider.InFlight
+	// httpClient is the HTTP client of the Azure clients, the SDK default is used when nil.
+	httpClient *http.Client
 }
 
 type Config struct {
 	Logger *slog.Logger
 
 	SubscriptionId string
+	// ManagementGroup is the management group the hierarchy is enumerated from by the managementgroups service.
+	ManagementGroup string
 
 	CollectorTimeout time.Duration
-	Services         []string
+	ScrapeInterval   time.Duration
+	// ScrapeIntervals overrides ScrapeInterval per service, keyed by the lowercased service name.
+	ScrapeIntervals map[string]time.Duration
+	Services        []string
+
+	// Cloud is the cloud the credentials and Resource Manager clients are configured for, the public cloud when it's
+	// the zero value.
+	Cloud cloud.Configuration
+	// HTTPClient sends the requests of every Azure client, eg through an egress proxy. The SDK default is used when nil.
+	HTTPClient *http.Client
+	// Auth selects how the clients authenticate, DefaultAzureCredential is used when it's empty.
+	Auth AuthConfig
+	// ResourceGroups selects the resource groups enumerated by the aks collector, every resource group is when nil.
+	ResourceGroups *aks.ResourceGroupFilter
+	// VMSizes selects the VM sizes whose prices are kept by the aks collector, every size is priced when nil.
+	VMSizes *pricing.FamilyFilter
+	// StorageClasses adjusts the cost of the persistent volumes listed by Volumes by their storage class.
+	StorageClasses *storageclass.Classes
+	Volumes        kubernetes.PersistentVolumeLister
+	// PriceLookup is set the price store of the aks collector, so the prices of virtual machines can be looked up when
+	// the exporter is embedded. It's left alone when nil.
+	PriceLookup *pricing.Lookup
+	// CommitmentPricing prices the VMs of the aks collector covered by reservations and savings plans at their rate.
+	// They're listed as often as the reservations service lists the reservations.
+	CommitmentPricing bool
+	// CapacityDelta exports the cost of the difference between the capacity of the scale sets of the aks collector and
+	// their running VMs, which lists the VMs of every scale set on every collection.
+	CapacityDelta bool
+}
+
+// CloudConfiguration returns the configuration of a named cloud, one of public, china or usgovernment, with its
+// authority host and Resource Manager endpoint overridden when they're set, eg to reach them through Private Link.
+func CloudConfiguration(name string, authorityHost string, resourceManagerEndpoint string) (cloud.Configuration, error) {
+	base, ok := clouds[strings.ToLower(name)]
+	if !ok {
+		return cloud.Configuration{}, fmt.Errorf("%w: %s", ErrUnknownCloud, name)
+	}
+	// The services map is shared by every copy of the predefined clouds, so it's copied before being modified
+	configuration := cloud.Configuration{
+		ActiveDirectoryAuthorityHost: base.ActiveDirectoryAuthorityHost,
+		Services:                     make(map[cloud.ServiceName]cloud.ServiceConfiguration, len(base.Services)),
+	}
+	for name, service := range base.Services {
+		configuration.Services[name] = service
+	}
+	if authorityHost != "" {
+		configuration.ActiveDirectoryAuthorityHost = authorityHost
+	}
+	if resourceManagerEndpoint != "" {
+		// The audience is kept, tokens are issued for Resource Manager regardless of the endpoint it's reached through
+		resourceManager := configuration.Services[cloud.ResourceManager]
+		resourceManager.Endpoint = resourceManagerEndpoint
+		configuration.Services[cloud.ResourceManager] = resourceManager
+	}
+	return configuration, nil
 }
 
 func New(ctx context.Context, config *Config) (*Azure, error) {
@@ -113,28 +196,35 @@ func New(ctx context.Context, config *Config) (*Azure, error) {
 		return nil, InvalidSubscriptionId
 	}
 
-	creds, err := azidentity.NewDefaultAzureCredential(nil)
+	clientOptions := policy.ClientOptions{Cloud: config.Cloud}
+	if config.HTTPClient != nil {
+		clientOptions.Transport = config.HTTPClient
+	}
+	auth, err := config.Auth.resolve()
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "invalid azure auth", slog.String("err", err.Error()))
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "authenticating", slog.String("auth", auth.Mode), slog.String("client_id", auth.ClientID))
+	creds, err := auth.credential(ctx, clientOptions)
 	if err != nil {
 		logger.LogAttrs(ctx, slog.LevelError, "failed to create azure credentials", slog.String("err", err.Error()))
 		return nil, err
 	}
 
 	// Collector Registration
+	c := &clients{config: config, logger: logger, creds: creds, clientOptions: clientOptions}
 	for _, svc := range config.Services {
-		switch strings.ToUpper(svc) {
-		case "AKS":
-			collector, err := aks.New(ctx, &aks.Config{
-				Credentials:    creds,
-				SubscriptionId: config.SubscriptionId,
-				Logger:         logger,
-			})
-			if err != nil {
-				return nil, err
-			}
-			collectors = append(collectors, collector)
-		default:
+		service, ok := collectorServices[strings.ToUpper(svc)]
+		if !ok {
 			logger.LogAttrs(ctx, slog.LevelInfo, "unknown service", slog.String("service", svc))
+			continue
 		}
+		collector, err := service.new(ctx, c, svc)
+		if err != nil {
+			return nil, err
+		}
+		collectors = append(collectors, collector)
 	}
 
 	return &Azure{
@@ -146,13 +236,172 @@ func New(ctx context.Context, config *Config) (*Azure, error) {
 
 		collectorTimeout: config.CollectorTimeout,
 		collectors:       collectors,
+		httpClient:       config.HTTPClient,
 	}, nil
 }
 
+// clients are the credentials and client options shared by the collectors of the services.
+type clients struct {
+	config        *Config
+	logger        *slog.Logger
+	creds         azcore.TokenCredential
+	clientOptions policy.ClientOptions
+}
+
+// collectorService creates the collector of a service, both to collect its costs and to describe its metrics.
+// Registering the service once keeps the documented metrics in line with the services the exporter accepts.
+type collectorService struct {
+	// new creates the collector with the shared credentials, svc is the name of the service as configured.
+	new func(ctx context.Context, c *clients, svc string) (provider.Collector, error)
+	// forDocs creates the collector without any Azure clients, see NewForDocs.
+	forDocs func(ctx context.Context, logger *slog.Logger) provider.Collector
+}
+
+// collectorServices are the services the exporter accepts, keyed by their uppercased name.
+var collectorServices = map[string]collectorService{
+	"AKS": {
+		new: func(ctx context.Context, c *clients, svc string) (provider.Collector, error) {
+			var benefits *reservations.BenefitsLister
+			if c.config.CommitmentPricing {
+				var err error
+				benefits, err = reservations.NewBenefitsLister(ctx, &reservations.BenefitsConfig{
+					Logger:         c.logger,
+					Credentials:    c.creds,
+					ClientOptions:  &arm.ClientOptions{ClientOptions: c.clientOptions},
+					SubscriptionId: c.config.SubscriptionId,
+					Interval:       utils.ScrapeIntervalFor(c.config.ScrapeIntervals, "reservations", c.config.ScrapeInterval),
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			collector, err := aks.New(ctx, &aks.Config{
+				Credentials:    c.creds,
+				ClientOptions:  &arm.ClientOptions{ClientOptions: c.clientOptions},
+				SubscriptionId: c.config.SubscriptionId,
+				Logger:         c.logger,
+				ResourceGroups: c.config.ResourceGroups,
+				StorageClasses: c.config.StorageClasses,
+				Volumes:        c.config.Volumes,
+				PriceLookup:    c.config.PriceLookup,
+				Benefits:       benefits,
+				VMSizes:        c.config.VMSizes,
+				CapacityDelta:  c.config.CapacityDelta,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return aks.NewForDocs(ctx, logger)
+		},
+	},
+	"MANAGEMENTGROUPS": {
+		new: func(ctx context.Context, c *clients, svc string) (provider.Collector, error) {
+			collector, err := managementgroups.New(ctx, &managementgroups.Config{
+				Credentials:     c.creds,
+				ClientOptions:   &arm.ClientOptions{ClientOptions: c.clientOptions},
+				ManagementGroup: c.config.ManagementGroup,
+				ScrapeInterval:  utils.ScrapeIntervalFor(c.config.ScrapeIntervals, svc, c.config.ScrapeInterval),
+				Logger:          c.logger,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return managementgroups.NewForDocs(ctx, logger)
+		},
+	},
+	"LOGANALYTICS": {
+		new: func(ctx context.Context, c *clients, svc string) (provider.Collector, error) {
+			collector, err := loganalytics.New(ctx, &loganalytics.Config{
+				Credentials:    c.creds,
+				ClientOptions:  &arm.ClientOptions{ClientOptions: c.clientOptions},
+				SubscriptionId: c.config.SubscriptionId,
+				ScrapeInterval: utils.ScrapeIntervalFor(c.config.ScrapeIntervals, svc, c.config.ScrapeInterval),
+				Logger:         c.logger,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return loganalytics.NewForDocs(ctx, logger)
+		},
+	},
+	"MESSAGING": {
+		new: func(ctx context.Context, c *clients, svc string) (provider.Collector, error) {
+			collector, err := messaging.New(ctx, &messaging.Config{
+				Credentials:    c.creds,
+				ClientOptions:  &arm.ClientOptions{ClientOptions: c.clientOptions},
+				SubscriptionId: c.config.SubscriptionId,
+				ScrapeInterval: utils.ScrapeIntervalFor(c.config.ScrapeIntervals, svc, c.config.ScrapeInterval),
+				Logger:         c.logger,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return messaging.NewForDocs(ctx, logger)
+		},
+	},
+	"RESERVATIONS": {
+		new: func(ctx context.Context, c *clients, svc string) (provider.Collector, error) {
+			collector, err := reservations.New(ctx, &reservations.Config{
+				Credentials:    c.creds,
+				ClientOptions:  &arm.ClientOptions{ClientOptions: c.clientOptions},
+				ScrapeInterval: utils.ScrapeIntervalFor(c.config.ScrapeIntervals, svc, c.config.ScrapeInterval),
+				Logger:         c.logger,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return collector, nil
+		},
+		forDocs: func(ctx context.Context, logger *slog.Logger) provider.Collector {
+			return reservations.NewForDocs(ctx, logger)
+		},
+	},
+}
+
+// NewForDocs returns an Azure provider with the collector of every service but without any Azure clients. It can't
+// collect anything and is only meant to describe the metrics the exporter exposes, eg for
+// `cloudcost-exporter docs metrics`.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *Azure {
+	logger = logger.With("provider", subsystem)
+	names := make([]string, 0, len(collectorServices))
+	for name := range collectorServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]provider.Collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, collectorServices[name].forDocs(ctx, logger))
+	}
+	return &Azure{
+		context: ctx,
+		logger:  logger,
+
+		collectors: collectors,
+	}
+}
+
 func (a *Azure) RegisterCollectors(registry provider.Registry) error {
 	a.logger.LogAttrs(a.context, slog.LevelInfo, "registering collectors", slog.Int("NumOfCollectors", len(a.collectors)))
 
 	registry.MustRegister(collectorScrapesTotalCounter)
+	registry.MustRegister(provider.ThrottledRefreshesTotal)
+	registry.MustRegister(provider.EmptyCollectionsTotal)
+	registry.MustRegister(provider.SeriesEmittedTotal)
+	registry.MustRegister(aks.UnpricedResourcesTotal)
+	registry.MustRegister(aks.MalformedPriceEntriesTotal)
+	registry.MustRegister(aks.PriceFetchDuration, aks.PriceFetchFailuresTotal, aks.PricedRegions)
 	for _, c := range a.collectors {
 		err := c.Register(registry)
 		if err != nil {
@@ -171,6 +420,7 @@ func (a *Azure) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collectorLastScrapeTime
 	ch <- providerLastScrapeTime
 	ch <- collectorSuccessDesc
+	ch <- provider.CollectorUpDesc
 	for _, c := range a.collectors {
 		if err := c.Describe(ch); err != nil {
 			a.logger.LogAttrs(a.context, slog.LevelInfo, "error describing collector", slog.String("collector", c.Name()), slog.String("error", err.Error()))
@@ -179,6 +429,7 @@ func (a *Azure) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (a *Azure) Collect(ch chan<- prometheus.Metric) {
+	defer a.inFlight.Start()()
 	// TODO - implement collector context
 	_, cancel := context.WithTimeout(a.context, a.collectorTimeout)
 	defer cancel()
@@ -192,7 +443,9 @@ func (a *Azure) Collect(ch chan<- prometheus.Metric) {
 			collectorStart := time.Now()
 			defer wg.Done()
 			collectorErrors := 0.0
-			if err := c.Collect(ch); err != nil {
+			err := a.watchdog.Collect(a.context, a.logger, subsystem, c, ch)
+			a.summary.Record(c.Name(), err)
+			if err != nil {
 				collectorErrors = 1.0
 				a.logger.LogAttrs(a.context, slog.LevelInfo, "error collecting metrics from collector", slog.String("collector", c.Name()), slog.String("error", err.Error()))
 			}
@@ -200,6 +453,7 @@ func (a *Azure) Collect(ch chan<- prometheus.Metric) {
 			ch <- prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, time.Since(collectorStart).Seconds(), subsystem, c.Name())
 			ch <- prometheus.MustNewConstMetric(collectorLastScrapeTime, prometheus.GaugeValue, float64(time.Now().Unix()), subsystem, c.Name())
 			ch <- prometheus.MustNewConstMetric(collectorSuccessDesc, prometheus.GaugeValue, collectorErrors, c.Name())
+			ch <- provider.NewCollectorUpMetric(subsystem, c.Name(), err)
 			collectorScrapesTotalCounter.WithLabelValues(subsystem, c.Name()).Inc()
 		}(c)
 
@@ -211,3 +465,18 @@ func (a *Azure) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(providerLastScrapeTime, prometheus.GaugeValue, float64(time.Now().Unix()), subsystem)
 	providerScrapesTotalCounter.WithLabelValues(subsystem).Inc()
 }
+
+// Close waits for the collections in flight, logs the collection summary of every collector and closes the idle
+// connections of Config.HTTPClient. The clients of the SDK don't hold any other resource, and the connections of their
+// default HTTP client are only released when the process exits.
+func (a *Azure) Close(ctx context.Context) error {
+	err := a.inFlight.Wait(ctx)
+	a.summary.Log(ctx, a.logger, subsystem)
+	if err != nil {
+		return err
+	}
+	if a.httpClient != nil {
+		a.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,3 +41,69 @@ func Test_New(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewForDocs(t *testing.T) {
+	a := NewForDocs(parentCtx, testLogger)
+	var documented []string
+	for _, c := range a.collectors {
+		documented = append(documented, c.Name())
+	}
+	var accepted []string
+	for name, svc := range collectorServices {
+		require.NotNil(t, svc.new, name)
+		accepted = append(accepted, svc.forDocs(parentCtx, testLogger).Name())
+	}
+	require.ElementsMatch(t, accepted, documented, "every accepted service is documented")
+}
+
+func Test_CloudConfiguration(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		cloud                   string
+		authorityHost           string
+		resourceManagerEndpoint string
+		expectedError           error
+		expectedAuthorityHost   string
+		expectedEndpoint        string
+	}{
+		{
+			name:                  "public cloud",
+			cloud:                 "public",
+			expectedAuthorityHost: cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+			expectedEndpoint:      cloud.AzurePublic.Services[cloud.ResourceManager].Endpoint,
+		},
+		{
+			name:                  "cloud names are case insensitive",
+			cloud:                 "USGovernment",
+			expectedAuthorityHost: cloud.AzureGovernment.ActiveDirectoryAuthorityHost,
+			expectedEndpoint:      cloud.AzureGovernment.Services[cloud.ResourceManager].Endpoint,
+		},
+		{
+			name:                    "private endpoints",
+			cloud:                   "public",
+			authorityHost:           "https://login.example.internal/",
+			resourceManagerEndpoint: "https://management.example.internal",
+			expectedAuthorityHost:   "https://login.example.internal/",
+			expectedEndpoint:        "https://management.example.internal",
+		},
+		{
+			name:          "unknown cloud",
+			cloud:         "moon",
+			expectedError: ErrUnknownCloud,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			configuration, err := CloudConfiguration(tc.cloud, tc.authorityHost, tc.resourceManagerEndpoint)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedAuthorityHost, configuration.ActiveDirectoryAuthorityHost)
+			require.Equal(t, tc.expectedEndpoint, configuration.Services[cloud.ResourceManager].Endpoint)
+			require.Equal(t, clouds[strings.ToLower(tc.cloud)].Services[cloud.ResourceManager].Audience, configuration.Services[cloud.ResourceManager].Audience)
+		})
+	}
+	// Overrides must not leak into the predefined clouds
+	require.Equal(t, "https://management.azure.com", cloud.AzurePublic.Services[cloud.ResourceManager].Endpoint)
+}
@@ -0,0 +1,354 @@
+package loganalytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/prometheus/client_golang/prometheus"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	workspaceType = "Microsoft.OperationalInsights/workspaces"
+
+	serviceName     = "Log Analytics"
+	ingestionMeter  = "Analytics Logs Data Ingestion"
+	retentionMeter  = "Analytics Logs Data Retention"
+	dataAnalyzedKey = "DataAnalyzed"
+
+	retailPricesAPIVersion = "2023-01-01-preview"
+	usagesAPIVersion       = "2020-08-01"
+
+	// bytesPerGB is the size of the GB Log Analytics bills ingestion in, which is decimal.
+	bytesPerGB = 1e9
+)
+
+// Errors
+var (
+	ErrClientCreationFailure = errors.New("failed to create client")
+	ErrPageAdvanceFailure    = errors.New("failed to advance page")
+)
+
+// Prometheus Metrics
+var (
+	IngestionPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_log_analytics", "ingestion_usd_per_gb"),
+		"The pay-as-you-go price of a GB of Analytics Logs ingested into a Log Analytics workspace in USD, past the free allowance.",
+		[]string{"region"},
+		nil,
+	)
+	RetentionPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_log_analytics", "retention_usd_per_gb_month"),
+		"The price of retaining a GB of Analytics Logs for a month past the retention included with ingestion in USD.",
+		[]string{"region"},
+		nil,
+	)
+	WorkspaceIngestedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_log_analytics", "workspace_ingested_gb"),
+		"The volume of data ingested into a Log Analytics workspace since the start of the day (UTC) in GB.",
+		[]string{"workspace", "resource_group", "region"},
+		nil,
+	)
+	WorkspaceIngestionCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_log_analytics", "workspace_ingestion_usd"),
+		"The pay-as-you-go cost of the data ingested into a Log Analytics workspace since the start of the day (UTC) in USD. Commitment tiers and the free allowance aren't taken into account.",
+		[]string{"workspace", "resource_group", "region"},
+		nil,
+	)
+)
+
+// prices are the ingestion and retention prices of a region.
+type prices struct {
+	ingestion float64
+	retention float64
+}
+
+// workspace is a Log Analytics workspace with the volume it ingested today.
+type workspace struct {
+	name          string
+	resourceGroup string
+	region        string
+	ingestedGB    float64
+}
+
+// usages is the response of the usages of a workspace. DataAnalyzed is the volume ingested since the last daily reset.
+type usages struct {
+	Value []struct {
+		Name struct {
+			Value string `json:"value"`
+		} `json:"name"`
+		Unit         string  `json:"unit"`
+		CurrentValue float64 `json:"currentValue"`
+	} `json:"value"`
+}
+
+// Collector exports the ingestion and retention prices of Log Analytics along with the daily ingestion and its cost
+// of every workspace of the subscription.
+type Collector struct {
+	context context.Context
+	logger  *slog.Logger
+
+	resourceClient    *armresources.Client
+	armClient         *arm.Client
+	retailPriceClient *retailPriceSdk.RetailPricesClient
+	subscriptionId    string
+
+	interval   time.Duration
+	nextScrape time.Time
+	prices     map[string]prices
+	m          sync.Mutex
+}
+
+type Config struct {
+	Logger      *slog.Logger
+	Credentials azcore.TokenCredential
+	// ClientOptions configures the cloud and transport of the clients, the SDK defaults are used when nil.
+	ClientOptions *arm.ClientOptions
+
+	SubscriptionId string
+	ScrapeInterval time.Duration
+}
+
+// New creates a Collector. The prices are only listed again every scrape interval, while the workspaces and their
+// usage are listed on every scrape.
+func New(ctx context.Context, cfg *Config) (*Collector, error) {
+	logger := cfg.Logger.With("collector", "loganalytics")
+	resourceClient, err := armresources.NewClient(cfg.SubscriptionId, cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create resource client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	// There is no SDK of the Log Analytics management API in the module, the usages are requested with a plain ARM client
+	armClient, err := arm.NewClient("loganalytics", "v0.1.0", cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create arm client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	retailPriceClient, err := retailPriceSdk.NewRetailPricesClient(cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create retail prices client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	return &Collector{
+		context:           ctx,
+		logger:            logger,
+		resourceClient:    resourceClient,
+		armClient:         armClient,
+		retailPriceClient: retailPriceClient,
+		subscriptionId:    cfg.SubscriptionId,
+		interval:          cfg.ScrapeInterval,
+	}, nil
+}
+
+// NewForDocs returns a Collector without any clients, which is only able to describe its metrics.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *Collector {
+	return &Collector{
+		context: ctx,
+		logger:  logger.With("collector", "loganalytics"),
+	}
+}
+
+func (c *Collector) Name() string {
+	return "LogAnalytics"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- IngestionPriceDesc
+	ch <- RetentionPriceDesc
+	ch <- WorkspaceIngestedDesc
+	ch <- WorkspaceIngestionCostDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		WorkspaceIngestedDesc,
+		WorkspaceIngestionCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect refreshes the prices when the scrape interval has passed, then exports them along with the daily ingestion
+// of every workspace. The cost of a workspace is only exported when its region is priced.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		p, err := c.listPrices()
+		if err != nil {
+			return err
+		}
+		c.prices = p
+		c.nextScrape = utils.NextScrape(now, c.interval)
+	}
+	regions := make([]string, 0, len(c.prices))
+	for region := range c.prices {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		price := c.prices[region]
+		if price.ingestion > 0 {
+			ch <- prometheus.MustNewConstMetric(IngestionPriceDesc, prometheus.GaugeValue, price.ingestion, region)
+		}
+		if price.retention > 0 {
+			ch <- prometheus.MustNewConstMetric(RetentionPriceDesc, prometheus.GaugeValue, price.retention, region)
+		}
+	}
+
+	workspaces, err := c.listWorkspaces()
+	if err != nil {
+		return err
+	}
+	for _, ws := range workspaces {
+		ch <- prometheus.MustNewConstMetric(WorkspaceIngestedDesc, prometheus.GaugeValue, ws.ingestedGB, ws.name, ws.resourceGroup, ws.region)
+		price, ok := c.prices[ws.region]
+		if !ok || price.ingestion == 0 {
+			c.logger.LogAttrs(c.context, slog.LevelWarn, "no ingestion price for workspace",
+				slog.String("workspace", ws.name), slog.String("region", ws.region))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(WorkspaceIngestionCostDesc, prometheus.GaugeValue, ws.ingestedGB*price.ingestion, ws.name, ws.resourceGroup, ws.region)
+	}
+	return nil
+}
+
+// listPrices lists the retail prices of Log Analytics in every region and keeps the ingestion and retention prices.
+func (c *Collector) listPrices() (map[string]prices, error) {
+	filter := fmt.Sprintf(`serviceName eq '%s' and priceType eq 'Consumption'`, serviceName)
+	pager := c.retailPriceClient.NewListPager(&retailPriceSdk.RetailPricesClientListOptions{
+		APIVersion:  to.Ptr(retailPricesAPIVersion),
+		Filter:      to.Ptr(filter),
+		MeterRegion: to.Ptr(`'primary'`),
+	})
+	var items []retailPriceSdk.ResourceSKU
+	for pager.More() {
+		page, err := pager.NextPage(c.context)
+		if err != nil {
+			c.logger.LogAttrs(c.context, slog.LevelError, "failed to list log analytics prices", slog.String("err", err.Error()))
+			return nil, ErrPageAdvanceFailure
+		}
+		items = append(items, page.Items...)
+	}
+	return parsePrices(items), nil
+}
+
+// parsePrices keeps the ingestion and retention prices of every region. The meters have a free tier, eg the first
+// 5 GB ingested every month, which is shared by the billing account, so the paid tier is kept.
+func parsePrices(items []retailPriceSdk.ResourceSKU) map[string]prices {
+	p := make(map[string]prices)
+	for _, item := range items {
+		if item.ServiceName != serviceName || item.ArmRegionName == "" || item.RetailPrice <= 0 {
+			continue
+		}
+		region := strings.ToLower(item.ArmRegionName)
+		price := p[region]
+		switch item.MeterName {
+		case ingestionMeter:
+			price.ingestion = item.RetailPrice
+		case retentionMeter:
+			price.retention = item.RetailPrice
+		default:
+			continue
+		}
+		p[region] = price
+	}
+	return p
+}
+
+// listWorkspaces lists the workspaces of the subscription along with the volume they ingested today. A workspace
+// whose usage can't be fetched is logged and skipped.
+func (c *Collector) listWorkspaces() ([]workspace, error) {
+	pager := c.resourceClient.NewListPager(&armresources.ClientListOptions{
+		Filter: to.Ptr("resourceType eq '" + workspaceType + "'"),
+	})
+	var workspaces []workspace
+	for pager.More() {
+		page, err := pager.NextPage(c.context)
+		if err != nil {
+			c.logger.LogAttrs(c.context, slog.LevelError, "failed to list workspaces", slog.String("err", err.Error()))
+			return nil, ErrPageAdvanceFailure
+		}
+		for _, resource := range page.Value {
+			if resource == nil || resource.ID == nil || resource.Name == nil || resource.Location == nil {
+				continue
+			}
+			id, err := arm.ParseResourceID(*resource.ID)
+			if err != nil {
+				continue
+			}
+			ingested, err := c.dataAnalyzed(id.ResourceGroupName, *resource.Name)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to get workspace usage",
+					slog.String("workspace", *resource.Name), slog.String("err", err.Error()))
+				continue
+			}
+			workspaces = append(workspaces, workspace{
+				name:          *resource.Name,
+				resourceGroup: id.ResourceGroupName,
+				region:        strings.ToLower(*resource.Location),
+				ingestedGB:    ingested / bytesPerGB,
+			})
+		}
+	}
+	return workspaces, nil
+}
+
+// dataAnalyzed returns the bytes ingested into a workspace since the last daily reset of its usages.
+func (c *Collector) dataAnalyzed(resourceGroup string, name string) (float64, error) {
+	path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s/%s/usages",
+		url.PathEscape(c.subscriptionId), url.PathEscape(resourceGroup), workspaceType, url.PathEscape(name))
+	req, err := runtime.NewRequest(c.context, http.MethodGet, runtime.JoinPaths(c.armClient.Endpoint(), path))
+	if err != nil {
+		return 0, err
+	}
+	query := req.Raw().URL.Query()
+	query.Set("api-version", usagesAPIVersion)
+	req.Raw().URL.RawQuery = query.Encode()
+	req.Raw().Header.Set("Accept", "application/json")
+	resp, err := c.armClient.Pipeline().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return 0, runtime.NewResponseError(resp)
+	}
+	var u usages
+	if err := runtime.UnmarshalAsJSON(resp, &u); err != nil {
+		return 0, err
+	}
+	for _, usage := range u.Value {
+		if usage.Name.Value == dataAnalyzedKey && usage.Unit == "Bytes" {
+			return usage.CurrentValue, nil
+		}
+	}
+	return 0, nil
+}
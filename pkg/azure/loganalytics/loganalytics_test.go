@@ -0,0 +1,140 @@
+package loganalytics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const testSubId = "1234-asdf-adsf-adsf"
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// fakeCredential returns a token without authenticating.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeTransport answers the requests with the JSON response returned by respond, a 404 when it returns nil.
+type fakeTransport struct {
+	respond func(req *http.Request) any
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	body := f.respond(req)
+	if body == nil {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}, Request: req}, nil
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(buf))), Header: http.Header{"Content-Type": {"application/json"}}, Request: req}, nil
+}
+
+func Test_parsePrices(t *testing.T) {
+	items := []retailPriceSdk.ResourceSKU{
+		// The free tier is skipped
+		{ServiceName: serviceName, ArmRegionName: "eastus", MeterName: ingestionMeter, RetailPrice: 0},
+		{ServiceName: serviceName, ArmRegionName: "eastus", MeterName: ingestionMeter, RetailPrice: 2.3},
+		{ServiceName: serviceName, ArmRegionName: "EastUS", MeterName: retentionMeter, RetailPrice: 0.1},
+		{ServiceName: serviceName, ArmRegionName: "westeurope", MeterName: ingestionMeter, RetailPrice: 2.99},
+		{ServiceName: serviceName, ArmRegionName: "westeurope", MeterName: "Basic Logs Data Ingestion", RetailPrice: 0.5},
+		{ServiceName: serviceName, MeterName: ingestionMeter, RetailPrice: 1},
+	}
+	assert.Equal(t, map[string]prices{
+		"eastus":     {ingestion: 2.3, retention: 0.1},
+		"westeurope": {ingestion: 2.99},
+	}, parsePrices(items))
+}
+
+func TestCollector_Collect(t *testing.T) {
+	workspacePath := "/subscriptions/" + testSubId + "/resourceGroups/observability/providers/" + workspaceType
+	priceRequests := 0
+	transport := &fakeTransport{respond: func(req *http.Request) any {
+		switch req.URL.Path {
+		case "/api/retail/prices":
+			priceRequests++
+			return map[string]any{"Items": []any{
+				map[string]any{"serviceName": serviceName, "armRegionName": "eastus", "meterName": ingestionMeter, "retailPrice": 2.3},
+				map[string]any{"serviceName": serviceName, "armRegionName": "eastus", "meterName": retentionMeter, "retailPrice": 0.1},
+			}}
+		case "/subscriptions/" + testSubId + "/resources":
+			workspace := func(name string, location string) map[string]any {
+				return map[string]any{"id": workspacePath + "/" + name, "name": name, "type": workspaceType, "location": location}
+			}
+			return map[string]any{"value": []any{
+				workspace("logs", "EastUS"),
+				// westus isn't priced
+				workspace("audit", "westus"),
+				// The usage of broken can't be fetched
+				workspace("broken", "eastus"),
+			}}
+		case workspacePath + "/logs/usages":
+			return map[string]any{"value": []any{
+				map[string]any{"name": map[string]any{"value": dataAnalyzedKey}, "unit": "Bytes", "currentValue": 5e9},
+			}}
+		case workspacePath + "/audit/usages":
+			return map[string]any{"value": []any{
+				map[string]any{"name": map[string]any{"value": dataAnalyzedKey}, "unit": "Bytes", "currentValue": 1e9},
+			}}
+		}
+		return nil
+	}}
+	c, err := New(context.Background(), &Config{
+		Logger:         testLogger,
+		Credentials:    fakeCredential{},
+		ClientOptions:  &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}},
+		SubscriptionId: testSubId,
+		ScrapeInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		var metrics []*utils.MetricResult
+		for metric := range ch {
+			metrics = append(metrics, utils.ReadMetrics(metric))
+		}
+		require.Len(t, metrics, 5)
+		assert.Equal(t, &utils.MetricResult{
+			FqName:     "cloudcost_azure_log_analytics_ingestion_usd_per_gb",
+			Labels:     utils.LabelMap{"region": "eastus"},
+			Value:      2.3,
+			MetricType: prometheus.GaugeValue,
+		}, metrics[0])
+		assert.Equal(t, "cloudcost_azure_log_analytics_retention_usd_per_gb_month", metrics[1].FqName)
+		assert.Equal(t, &utils.MetricResult{
+			FqName:     "cloudcost_azure_log_analytics_workspace_ingested_gb",
+			Labels:     utils.LabelMap{"workspace": "logs", "resource_group": "observability", "region": "eastus"},
+			Value:      5,
+			MetricType: prometheus.GaugeValue,
+		}, metrics[2])
+		assert.Equal(t, "cloudcost_azure_log_analytics_workspace_ingestion_usd", metrics[3].FqName)
+		assert.InDelta(t, 5*2.3, metrics[3].Value, 1e-9)
+		assert.Equal(t, "cloudcost_azure_log_analytics_workspace_ingested_gb", metrics[4].FqName)
+		assert.Equal(t, "audit", metrics[4].Labels["workspace"])
+	}
+	// The prices are only listed once per scrape interval
+	assert.Equal(t, 1, priceRequests)
+}
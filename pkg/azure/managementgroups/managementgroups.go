@@ -0,0 +1,389 @@
+package managementgroups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/costmanagement/armcostmanagement"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/managementgroups/armmanagementgroups"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	subsystem = "azure"
+
+	// subscriptionType is the type of the subscriptions returned by the entities API, management groups are of type
+	// Microsoft.Management/managementGroups.
+	subscriptionType = "/subscriptions"
+	// pathSeparator joins the names of the management groups from the root down to a subscription.
+	pathSeparator = "/"
+
+	costColumn           = "Cost"
+	subscriptionIdColumn = "SubscriptionId"
+	currencyColumn       = "Currency"
+)
+
+// Errors
+var (
+	ErrClientCreationFailure  = errors.New("failed to create client")
+	ErrMissingManagementGroup = errors.New("management group is required")
+	ErrUnexpectedColumns      = errors.New("cost query is missing columns")
+)
+
+// Prometheus Metrics
+var (
+	SubscriptionMonthToDateCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "subscription_month_to_date_cost"),
+		"The actual cost of a subscription since the start of the month in its billing currency.",
+		[]string{"subscription_id", "subscription_name", "management_group_path", "currency"},
+		nil,
+	)
+	ManagementGroupMonthToDateCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "management_group_month_to_date_cost"),
+		"The actual cost of every subscription below a management group, at any depth, since the start of the month in the billing currency.",
+		[]string{"management_group", "management_group_path", "currency"},
+		nil,
+	)
+)
+
+// EntitiesLister lists the management groups and subscriptions below a management group.
+type EntitiesLister interface {
+	ListEntities(ctx context.Context, groupName string) ([]*armmanagementgroups.EntityInfo, error)
+}
+
+// CostQuerier queries Cost Management, it's satisfied by *armcostmanagement.QueryClient.
+type CostQuerier interface {
+	Usage(ctx context.Context, scope string, parameters armcostmanagement.QueryDefinition, options *armcostmanagement.QueryClientUsageOptions) (armcostmanagement.QueryClientUsageResponse, error)
+}
+
+// subscription is a subscription with the names of the management groups above it, from the root down to its parent.
+type subscription struct {
+	name   string
+	groups []string
+}
+
+// subscriptionCost is the spend of a subscription in a single currency.
+type subscriptionCost struct {
+	id       string
+	cost     float64
+	currency string
+}
+
+// groupCost is the spend of every subscription below a management group in a single currency.
+type groupCost struct {
+	name     string
+	path     string
+	cost     float64
+	currency string
+}
+
+// Collector exports the actual spend of every subscription below a management group, labeled by the path of
+// management groups it belongs to, along with the spend rolled up to every management group of the hierarchy.
+type Collector struct {
+	context context.Context
+	logger  *slog.Logger
+
+	managementGroup string
+	entities        EntitiesLister
+	costs           CostQuerier
+
+	interval      time.Duration
+	nextScrape    time.Time
+	subscriptions []subscriptionMetric
+	groups        []groupCost
+	m             sync.Mutex
+}
+
+// subscriptionMetric holds the label values and value of SubscriptionMonthToDateCostDesc.
+type subscriptionMetric struct {
+	id       string
+	name     string
+	path     string
+	cost     float64
+	currency string
+}
+
+type Config struct {
+	Logger      *slog.Logger
+	Credentials azcore.TokenCredential
+	// ClientOptions configures the cloud and transport of the clients, the SDK defaults are used when nil.
+	ClientOptions *arm.ClientOptions
+
+	// ManagementGroup is the name, not the display name, of the management group the hierarchy is enumerated from,
+	// eg the tenant root group.
+	ManagementGroup string
+	ScrapeInterval  time.Duration
+}
+
+// New creates a Collector. Cost Management queries are throttled heavily, so the costs are only refreshed every
+// scrape interval.
+func New(ctx context.Context, cfg *Config) (*Collector, error) {
+	logger := cfg.Logger.With("collector", "managementgroups")
+	if cfg.ManagementGroup == "" {
+		return nil, ErrMissingManagementGroup
+	}
+
+	entitiesClient, err := armmanagementgroups.NewEntitiesClient(cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create management group entities client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+
+	queryClient, err := armcostmanagement.NewQueryClient(cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create cost management query client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+
+	return newCollector(ctx, logger, cfg.ManagementGroup, cfg.ScrapeInterval, &entitiesLister{client: entitiesClient}, queryClient), nil
+}
+
+// NewForDocs returns a Collector without any clients, which is only able to describe its metrics.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *Collector {
+	return &Collector{
+		context: ctx,
+		logger:  logger.With("collector", "managementgroups"),
+	}
+}
+
+func newCollector(ctx context.Context, logger *slog.Logger, managementGroup string, interval time.Duration, entities EntitiesLister, costs CostQuerier) *Collector {
+	return &Collector{
+		context: ctx,
+		logger:  logger,
+
+		managementGroup: managementGroup,
+		entities:        entities,
+		costs:           costs,
+		interval:        interval,
+	}
+}
+
+func (c *Collector) Name() string {
+	return "ManagementGroups"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- SubscriptionMonthToDateCostDesc
+	ch <- ManagementGroupMonthToDateCostDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect refreshes the hierarchy and the costs when the scrape interval has passed and exports them.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.subscriptions == nil || now.After(c.nextScrape) {
+		if err := c.refresh(); err != nil {
+			return err
+		}
+		c.nextScrape = utils.NextScrape(now, c.interval)
+	}
+	for _, s := range c.subscriptions {
+		ch <- prometheus.MustNewConstMetric(SubscriptionMonthToDateCostDesc, prometheus.GaugeValue, s.cost, s.id, s.name, s.path, s.currency)
+	}
+	for _, g := range c.groups {
+		ch <- prometheus.MustNewConstMetric(ManagementGroupMonthToDateCostDesc, prometheus.GaugeValue, g.cost, g.name, g.path, g.currency)
+	}
+	return nil
+}
+
+func (c *Collector) refresh() error {
+	entities, err := c.entities.ListEntities(c.context, c.managementGroup)
+	if err != nil {
+		return fmt.Errorf("error listing management group hierarchy: %w", err)
+	}
+	subscriptions := parseHierarchy(entities)
+	costs, truncated, err := queryMonthToDateCosts(c.context, c.costs, c.managementGroup)
+	if err != nil {
+		return fmt.Errorf("error querying costs of management group %s: %w", c.managementGroup, err)
+	}
+	if truncated {
+		c.logger.LogAttrs(c.context, slog.LevelWarn, "costs of management group were truncated", slog.String("management_group", c.managementGroup))
+	}
+	c.subscriptions, c.groups = rollUp(c.logger, subscriptions, costs)
+	return nil
+}
+
+// entitiesLister adapts *armmanagementgroups.EntitiesClient to EntitiesLister.
+type entitiesLister struct {
+	client *armmanagementgroups.EntitiesClient
+}
+
+func (l *entitiesLister) ListEntities(ctx context.Context, groupName string) ([]*armmanagementgroups.EntityInfo, error) {
+	var entities []*armmanagementgroups.EntityInfo
+	pager := l.client.NewListPager(&armmanagementgroups.EntitiesClientListOptions{GroupName: to.Ptr(groupName)})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, page.Value...)
+	}
+	return entities, nil
+}
+
+// parseHierarchy returns the subscriptions among entities keyed by subscription id. The parent name chain of an entity
+// goes from the root management group down to its direct parent.
+func parseHierarchy(entities []*armmanagementgroups.EntityInfo) map[string]subscription {
+	subscriptions := make(map[string]subscription)
+	for _, entity := range entities {
+		if entity == nil || entity.Type == nil || entity.Name == nil || *entity.Type != subscriptionType {
+			continue
+		}
+		var s subscription
+		if entity.Properties != nil {
+			if entity.Properties.DisplayName != nil {
+				s.name = *entity.Properties.DisplayName
+			}
+			for _, group := range entity.Properties.ParentNameChain {
+				if group != nil {
+					s.groups = append(s.groups, *group)
+				}
+			}
+		}
+		subscriptions[strings.ToLower(*entity.Name)] = s
+	}
+	return subscriptions
+}
+
+// queryMonthToDateCosts returns the actual cost of every subscription below managementGroup since the start of the
+// month. A single query at the management group scope is used as Cost Management throttles per scope. The SDK doesn't
+// follow the next link of a query, so the second return value reports whether the result was truncated, which only
+// happens with thousands of subscriptions.
+func queryMonthToDateCosts(ctx context.Context, client CostQuerier, managementGroup string) ([]subscriptionCost, bool, error) {
+	scope := "/providers/Microsoft.Management/managementGroups/" + managementGroup
+	definition := armcostmanagement.QueryDefinition{
+		Type:      to.Ptr(armcostmanagement.ExportTypeActualCost),
+		Timeframe: to.Ptr(armcostmanagement.TimeframeTypeMonthToDate),
+		Dataset: &armcostmanagement.QueryDataset{
+			Aggregation: map[string]*armcostmanagement.QueryAggregation{
+				costColumn: {
+					Name:     to.Ptr(costColumn),
+					Function: to.Ptr(armcostmanagement.FunctionTypeSum),
+				},
+			},
+			Grouping: []*armcostmanagement.QueryGrouping{
+				{
+					Type: to.Ptr(armcostmanagement.QueryColumnTypeDimension),
+					Name: to.Ptr(subscriptionIdColumn),
+				},
+			},
+		},
+	}
+
+	resp, err := client.Usage(ctx, scope, definition, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.Properties == nil {
+		return nil, false, nil
+	}
+	costs, err := parseCosts(resp.Properties)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := resp.Properties.NextLink != nil && *resp.Properties.NextLink != ""
+	return costs, truncated, nil
+}
+
+// parseCosts reads the rows of a cost query. Columns are looked up by name as their order isn't guaranteed.
+func parseCosts(properties *armcostmanagement.QueryProperties) ([]subscriptionCost, error) {
+	columns := make(map[string]int, len(properties.Columns))
+	for i, column := range properties.Columns {
+		if column != nil && column.Name != nil {
+			columns[strings.ToLower(*column.Name)] = i
+		}
+	}
+	costIndex, ok := columns[strings.ToLower(costColumn)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedColumns, costColumn)
+	}
+	subscriptionIndex, ok := columns[strings.ToLower(subscriptionIdColumn)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedColumns, subscriptionIdColumn)
+	}
+	currencyIndex, hasCurrency := columns[strings.ToLower(currencyColumn)]
+
+	costs := make([]subscriptionCost, 0, len(properties.Rows))
+	for _, row := range properties.Rows {
+		cost, ok := valueAt[float64](row, costIndex)
+		if !ok {
+			continue
+		}
+		id, ok := valueAt[string](row, subscriptionIndex)
+		if !ok || id == "" {
+			continue
+		}
+		var currency string
+		if hasCurrency {
+			currency, _ = valueAt[string](row, currencyIndex)
+		}
+		costs = append(costs, subscriptionCost{id: strings.ToLower(id), cost: cost, currency: currency})
+	}
+	return costs, nil
+}
+
+func valueAt[T any](row []any, i int) (T, bool) {
+	var zero T
+	if i >= len(row) {
+		return zero, false
+	}
+	v, ok := row[i].(T)
+	return v, ok
+}
+
+// rollUp labels the cost of every subscription with its management group path and sums the costs of the
+// subscriptions below every management group. Subscriptions missing from the hierarchy, eg because the exporter
+// can't read the management group they were moved to, keep an empty path and aren't rolled up.
+func rollUp(logger *slog.Logger, subscriptions map[string]subscription, costs []subscriptionCost) ([]subscriptionMetric, []groupCost) {
+	metrics := make([]subscriptionMetric, 0, len(costs))
+	var groups []groupCost
+	groupIndex := make(map[string]int)
+	for _, cost := range costs {
+		s, ok := subscriptions[cost.id]
+		if !ok {
+			logger.Debug("subscription not found in management group hierarchy", slog.String("subscription_id", cost.id))
+		}
+		metrics = append(metrics, subscriptionMetric{
+			id:       cost.id,
+			name:     s.name,
+			path:     strings.Join(s.groups, pathSeparator),
+			cost:     cost.cost,
+			currency: cost.currency,
+		})
+		for i, group := range s.groups {
+			path := strings.Join(s.groups[:i+1], pathSeparator)
+			key := path + "|" + cost.currency
+			if index, ok := groupIndex[key]; ok {
+				groups[index].cost += cost.cost
+				continue
+			}
+			groupIndex[key] = len(groups)
+			groups = append(groups, groupCost{name: group, path: path, cost: cost.cost, currency: cost.currency})
+		}
+	}
+	return metrics, groups
+}
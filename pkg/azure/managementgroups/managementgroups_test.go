@@ -0,0 +1,238 @@
+package managementgroups
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/costmanagement/armcostmanagement"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/managementgroups/armmanagementgroups"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+type fakeEntities struct {
+	entities []*armmanagementgroups.EntityInfo
+	err      error
+	calls    int
+}
+
+func (f *fakeEntities) ListEntities(_ context.Context, _ string) ([]*armmanagementgroups.EntityInfo, error) {
+	f.calls++
+	return f.entities, f.err
+}
+
+type fakeCosts struct {
+	properties *armcostmanagement.QueryProperties
+	err        error
+	scopes     []string
+}
+
+func (f *fakeCosts) Usage(_ context.Context, scope string, _ armcostmanagement.QueryDefinition, _ *armcostmanagement.QueryClientUsageOptions) (armcostmanagement.QueryClientUsageResponse, error) {
+	f.scopes = append(f.scopes, scope)
+	return armcostmanagement.QueryClientUsageResponse{QueryResult: armcostmanagement.QueryResult{Properties: f.properties}}, f.err
+}
+
+func subscriptionEntity(id string, name string, parents ...string) *armmanagementgroups.EntityInfo {
+	return &armmanagementgroups.EntityInfo{
+		Name: to.Ptr(id),
+		Type: to.Ptr(subscriptionType),
+		Properties: &armmanagementgroups.EntityInfoProperties{
+			DisplayName:     to.Ptr(name),
+			ParentNameChain: to.SliceOfPtrs(parents...),
+		},
+	}
+}
+
+func groupEntity(name string, parents ...string) *armmanagementgroups.EntityInfo {
+	return &armmanagementgroups.EntityInfo{
+		Name: to.Ptr(name),
+		Type: to.Ptr("Microsoft.Management/managementGroups"),
+		Properties: &armmanagementgroups.EntityInfoProperties{
+			DisplayName:     to.Ptr(name),
+			ParentNameChain: to.SliceOfPtrs(parents...),
+		},
+	}
+}
+
+func costProperties(rows ...[]any) *armcostmanagement.QueryProperties {
+	return &armcostmanagement.QueryProperties{
+		Columns: []*armcostmanagement.QueryColumn{
+			{Name: to.Ptr("Cost"), Type: to.Ptr("Number")},
+			{Name: to.Ptr("SubscriptionId"), Type: to.Ptr("String")},
+			{Name: to.Ptr("Currency"), Type: to.Ptr("String")},
+		},
+		Rows: rows,
+	}
+}
+
+func TestParseHierarchy(t *testing.T) {
+	got := parseHierarchy([]*armmanagementgroups.EntityInfo{
+		groupEntity("root"),
+		groupEntity("platform", "root"),
+		subscriptionEntity("AAAA-1111", "shared", "root", "platform"),
+		subscriptionEntity("bbbb-2222", "sandbox", "root"),
+		nil,
+		{Name: to.Ptr("cccc-3333"), Type: to.Ptr(subscriptionType)},
+	})
+	assert.Equal(t, map[string]subscription{
+		"aaaa-1111": {name: "shared", groups: []string{"root", "platform"}},
+		"bbbb-2222": {name: "sandbox", groups: []string{"root"}},
+		"cccc-3333": {},
+	}, got)
+}
+
+func TestParseCosts(t *testing.T) {
+	tests := map[string]struct {
+		properties *armcostmanagement.QueryProperties
+		want       []subscriptionCost
+		err        error
+	}{
+		"columns are looked up by name": {
+			properties: &armcostmanagement.QueryProperties{
+				Columns: []*armcostmanagement.QueryColumn{
+					{Name: to.Ptr("Currency")},
+					{Name: to.Ptr("SubscriptionId")},
+					{Name: to.Ptr("Cost")},
+				},
+				Rows: [][]any{{"EUR", "AAAA-1111", 12.5}},
+			},
+			want: []subscriptionCost{{id: "aaaa-1111", cost: 12.5, currency: "EUR"}},
+		},
+		"rows with unexpected values are skipped": {
+			properties: costProperties(
+				[]any{1.5, "aaaa-1111", "USD"},
+				[]any{"1.5", "bbbb-2222", "USD"},
+				[]any{2.0, "", "USD"},
+				[]any{3.0},
+			),
+			want: []subscriptionCost{{id: "aaaa-1111", cost: 1.5, currency: "USD"}},
+		},
+		"missing cost column": {
+			properties: &armcostmanagement.QueryProperties{
+				Columns: []*armcostmanagement.QueryColumn{{Name: to.Ptr("SubscriptionId")}},
+			},
+			err: ErrUnexpectedColumns,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseCosts(tt.properties)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRollUp(t *testing.T) {
+	subscriptions := map[string]subscription{
+		"a": {name: "shared", groups: []string{"root", "platform"}},
+		"b": {name: "observability", groups: []string{"root", "platform", "o11y"}},
+		"c": {name: "sandbox", groups: []string{"root"}},
+	}
+	costs := []subscriptionCost{
+		{id: "a", cost: 10, currency: "USD"},
+		{id: "b", cost: 5, currency: "USD"},
+		{id: "c", cost: 1, currency: "USD"},
+		{id: "c", cost: 2, currency: "EUR"},
+		{id: "unknown", cost: 100, currency: "USD"},
+	}
+	gotSubscriptions, gotGroups := rollUp(testLogger, subscriptions, costs)
+	assert.Equal(t, []subscriptionMetric{
+		{id: "a", name: "shared", path: "root/platform", cost: 10, currency: "USD"},
+		{id: "b", name: "observability", path: "root/platform/o11y", cost: 5, currency: "USD"},
+		{id: "c", name: "sandbox", path: "root", cost: 1, currency: "USD"},
+		{id: "c", name: "sandbox", path: "root", cost: 2, currency: "EUR"},
+		{id: "unknown", cost: 100, currency: "USD"},
+	}, gotSubscriptions)
+	assert.Equal(t, []groupCost{
+		{name: "root", path: "root", cost: 16, currency: "USD"},
+		{name: "platform", path: "root/platform", cost: 15, currency: "USD"},
+		{name: "o11y", path: "root/platform/o11y", cost: 5, currency: "USD"},
+		{name: "root", path: "root", cost: 2, currency: "EUR"},
+	}, gotGroups)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	tests := map[string]struct {
+		entities *fakeEntities
+		costs    *fakeCosts
+		want     []*utils.MetricResult
+		wantErr  bool
+	}{
+		"subscriptions are labeled and rolled up": {
+			entities: &fakeEntities{entities: []*armmanagementgroups.EntityInfo{
+				groupEntity("platform", "root"),
+				subscriptionEntity("aaaa-1111", "shared", "root", "platform"),
+			}},
+			costs: &fakeCosts{properties: costProperties([]any{42.0, "aaaa-1111", "USD"})},
+			want: []*utils.MetricResult{
+				{
+					FqName:     "cloudcost_azure_subscription_month_to_date_cost",
+					Labels:     utils.LabelMap{"subscription_id": "aaaa-1111", "subscription_name": "shared", "management_group_path": "root/platform", "currency": "USD"},
+					Value:      42,
+					MetricType: prometheus.GaugeValue,
+				},
+				{
+					FqName:     "cloudcost_azure_management_group_month_to_date_cost",
+					Labels:     utils.LabelMap{"management_group": "root", "management_group_path": "root", "currency": "USD"},
+					Value:      42,
+					MetricType: prometheus.GaugeValue,
+				},
+				{
+					FqName:     "cloudcost_azure_management_group_month_to_date_cost",
+					Labels:     utils.LabelMap{"management_group": "platform", "management_group_path": "root/platform", "currency": "USD"},
+					Value:      42,
+					MetricType: prometheus.GaugeValue,
+				},
+			},
+		},
+		"hierarchy errors propagate": {
+			entities: &fakeEntities{err: assert.AnError},
+			costs:    &fakeCosts{},
+			wantErr:  true,
+		},
+		"cost errors propagate": {
+			entities: &fakeEntities{},
+			costs:    &fakeCosts{err: assert.AnError},
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newCollector(context.Background(), testLogger, "root", time.Hour, tt.entities, tt.costs)
+			ch := make(chan prometheus.Metric, len(tt.want)+1)
+			err := c.Collect(ch)
+			close(ch)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{"/providers/Microsoft.Management/managementGroups/root"}, tt.costs.scopes)
+			var got []*utils.MetricResult
+			for m := range ch {
+				got = append(got, utils.ReadMetrics(m))
+			}
+			assert.Equal(t, tt.want, got)
+
+			// The hierarchy and costs are cached until the next scrape
+			ch = make(chan prometheus.Metric, len(tt.want)+1)
+			require.NoError(t, c.Collect(ch))
+			assert.Equal(t, 1, tt.entities.calls)
+			assert.Len(t, tt.costs.scopes, 1)
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/prometheus/client_golang/prometheus"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	providerName = "azure"
+
+	eventHubsNamespaceType  = "Microsoft.EventHub/namespaces"
+	serviceBusNamespaceType = "Microsoft.ServiceBus/namespaces"
+
+	eventHubsServiceName  = "Event Hubs"
+	serviceBusServiceName = "Service Bus"
+
+	retailPricesAPIVersion = "2023-01-01-preview"
+)
+
+// Errors
+var (
+	ErrClientCreationFailure = errors.New("failed to create client")
+	ErrPageAdvanceFailure    = errors.New("failed to advance page")
+)
+
+// Prometheus Metrics
+var (
+	EventHubsUnitHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_eventhubs", "unit_usd_per_hour"),
+		"The price of a capacity unit of an Event Hubs tier in USD/h: a throughput unit for Basic and Standard, a processing unit for Premium and a capacity unit for Dedicated.",
+		[]string{"region", "sku"},
+		nil,
+	)
+	ServiceBusUnitHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_servicebus", "unit_usd_per_hour"),
+		"The price of a capacity unit of a Service Bus tier in USD/h: the base charge of a Standard namespace and a messaging unit for Premium.",
+		[]string{"region", "sku"},
+		nil,
+	)
+	EventHubsNamespaceHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_eventhubs", "namespace_usd_per_hour"),
+		"The fixed cost of an Event Hubs namespace in USD/h, the price of its tier times its capacity units. Ingress events and capture are billed separately.",
+		[]string{"namespace", "resource_group", "region", "sku", "capacity"},
+		nil,
+	)
+	ServiceBusNamespaceHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_servicebus", "namespace_usd_per_hour"),
+		"The fixed cost of a Service Bus namespace in USD/h, the price of its tier times its messaging units. Operations are billed separately.",
+		[]string{"namespace", "resource_group", "region", "sku", "capacity"},
+		nil,
+	)
+)
+
+// service describes how the namespaces of a messaging service are priced.
+type service struct {
+	name          string
+	namespaceType string
+	unitDesc      *prometheus.Desc
+	namespaceDesc *prometheus.Desc
+	// meters maps a sku tier to the name of the meter of its capacity unit in the retail prices.
+	meters map[string]string
+}
+
+var services = []service{
+	{
+		name:          eventHubsServiceName,
+		namespaceType: eventHubsNamespaceType,
+		unitDesc:      EventHubsUnitHourlyPriceDesc,
+		namespaceDesc: EventHubsNamespaceHourlyCostDesc,
+		meters: map[string]string{
+			"Basic":     "Basic Throughput Unit",
+			"Standard":  "Standard Throughput Unit",
+			"Premium":   "Premium Processing Unit",
+			"Dedicated": "Dedicated Capacity Unit",
+		},
+	},
+	{
+		name:          serviceBusServiceName,
+		namespaceType: serviceBusNamespaceType,
+		unitDesc:      ServiceBusUnitHourlyPriceDesc,
+		namespaceDesc: ServiceBusNamespaceHourlyCostDesc,
+		meters: map[string]string{
+			"Standard": "Standard Base Unit",
+			"Premium":  "Premium Messaging Unit",
+		},
+	},
+}
+
+// unitHours converts the unit of measure of a retail price into hours, prices in any other unit are skipped.
+var unitHours = map[string]float64{
+	"1 Hour":  1,
+	"1/Hour":  1,
+	"1 Month": utils.HoursInMonth,
+	"1/Month": utils.HoursInMonth,
+}
+
+// unitPrices are the hourly prices of the capacity units, keyed by service, region and then sku tier.
+type unitPrices map[string]map[string]map[string]float64
+
+func (p unitPrices) get(service string, region string, tier string) (float64, bool) {
+	price, ok := p[service][strings.ToLower(region)][tier]
+	return price, ok
+}
+
+// namespace is an Event Hubs or Service Bus namespace with its sku.
+type namespace struct {
+	service       *service
+	name          string
+	resourceGroup string
+	region        string
+	tier          string
+	capacity      int32
+}
+
+// Collector exports the unit prices of Event Hubs and Service Bus along with the fixed hourly cost of every
+// namespace of the subscription.
+type Collector struct {
+	context context.Context
+	logger  *slog.Logger
+
+	resourceClient    *armresources.Client
+	retailPriceClient *retailPriceSdk.RetailPricesClient
+
+	backoff    *provider.Backoff
+	nextScrape time.Time
+	prices     unitPrices
+	m          sync.Mutex
+}
+
+type Config struct {
+	Logger      *slog.Logger
+	Credentials azcore.TokenCredential
+	// ClientOptions configures the cloud and transport of the clients, the SDK defaults are used when nil.
+	ClientOptions *arm.ClientOptions
+
+	SubscriptionId string
+	ScrapeInterval time.Duration
+}
+
+// New creates a Collector. The prices are only listed again every scrape interval, or less often while the retail
+// prices API throttles the collector, while the namespaces are listed on every scrape.
+func New(ctx context.Context, cfg *Config) (*Collector, error) {
+	logger := cfg.Logger.With("collector", "messaging")
+	resourceClient, err := armresources.NewClient(cfg.SubscriptionId, cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create resource client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	retailPriceClient, err := retailPriceSdk.NewRetailPricesClient(cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create retail prices client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	c := &Collector{
+		context:           ctx,
+		logger:            logger,
+		resourceClient:    resourceClient,
+		retailPriceClient: retailPriceClient,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), cfg.ScrapeInterval)
+	return c, nil
+}
+
+// NewForDocs returns a Collector without any clients, which is only able to describe its metrics.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *Collector {
+	return &Collector{
+		context: ctx,
+		logger:  logger.With("collector", "messaging"),
+	}
+}
+
+func (c *Collector) Name() string {
+	return "Messaging"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- EventHubsUnitHourlyPriceDesc
+	ch <- ServiceBusUnitHourlyPriceDesc
+	ch <- EventHubsNamespaceHourlyCostDesc
+	ch <- ServiceBusNamespaceHourlyCostDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		EventHubsNamespaceHourlyCostDesc,
+		ServiceBusNamespaceHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect refreshes the unit prices when the scrape interval has passed, then exports them along with the cost of
+// every namespace. Namespaces whose tier can't be priced are logged and skipped.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listUnitPrices()
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return err
+		}
+		c.prices = prices
+	}
+	for _, svc := range services {
+		for _, region := range sortedKeys(c.prices[svc.name]) {
+			for _, tier := range sortedKeys(c.prices[svc.name][region]) {
+				ch <- prometheus.MustNewConstMetric(svc.unitDesc, prometheus.GaugeValue, c.prices[svc.name][region][tier], region, tier)
+			}
+		}
+	}
+	c.backoff.Emit(ch)
+
+	namespaces, err := c.listNamespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		price, ok := c.prices.get(ns.service.name, ns.region, ns.tier)
+		if !ok {
+			c.logger.LogAttrs(c.context, slog.LevelWarn, "no price for namespace",
+				slog.String("namespace", ns.name), slog.String("region", ns.region), slog.String("sku", ns.tier))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(ns.service.namespaceDesc, prometheus.GaugeValue, price*float64(ns.capacity),
+			ns.name, ns.resourceGroup, ns.region, ns.tier, fmt.Sprint(ns.capacity))
+	}
+	return nil
+}
+
+// listUnitPrices lists the retail prices of Event Hubs and Service Bus in every region and keeps the hourly price of
+// the capacity unit of every tier.
+func (c *Collector) listUnitPrices() (unitPrices, error) {
+	filter := fmt.Sprintf(`(serviceName eq '%s' or serviceName eq '%s') and priceType eq 'Consumption'`, eventHubsServiceName, serviceBusServiceName)
+	pager := c.retailPriceClient.NewListPager(&retailPriceSdk.RetailPricesClientListOptions{
+		APIVersion:  to.Ptr(retailPricesAPIVersion),
+		Filter:      to.Ptr(filter),
+		MeterRegion: to.Ptr(`'primary'`),
+	})
+	var items []retailPriceSdk.ResourceSKU
+	for pager.More() {
+		page, err := pager.NextPage(c.context)
+		if err != nil {
+			c.logger.LogAttrs(c.context, slog.LevelError, "failed to list messaging prices", slog.String("err", err.Error()))
+			return nil, fmt.Errorf("%w: %w", ErrPageAdvanceFailure, err)
+		}
+		items = append(items, page.Items...)
+	}
+	return parseUnitPrices(items), nil
+}
+
+// parseUnitPrices keeps the retail prices of the capacity unit meters of every service, converted to USD/h.
+func parseUnitPrices(items []retailPriceSdk.ResourceSKU) unitPrices {
+	prices := make(unitPrices)
+	for _, item := range items {
+		hours, ok := unitHours[item.UnitOfMeasure]
+		if !ok || item.ArmRegionName == "" {
+			continue
+		}
+		for _, svc := range services {
+			if item.ServiceName != svc.name {
+				continue
+			}
+			for tier, meter := range svc.meters {
+				if item.MeterName != meter {
+					continue
+				}
+				region := strings.ToLower(item.ArmRegionName)
+				if prices[svc.name] == nil {
+					prices[svc.name] = make(map[string]map[string]float64)
+				}
+				if prices[svc.name][region] == nil {
+					prices[svc.name][region] = make(map[string]float64)
+				}
+				prices[svc.name][region][tier] = item.RetailPrice / hours
+			}
+		}
+	}
+	return prices
+}
+
+// listNamespaces lists the Event Hubs and Service Bus namespaces of the subscription. The list of resources includes
+// their sku, so the namespaces don't need to be fetched one by one.
+func (c *Collector) listNamespaces() ([]namespace, error) {
+	var namespaces []namespace
+	for i := range services {
+		svc := &services[i]
+		pager := c.resourceClient.NewListPager(&armresources.ClientListOptions{
+			Filter: to.Ptr("resourceType eq '" + svc.namespaceType + "'"),
+		})
+		for pager.More() {
+			page, err := pager.NextPage(c.context)
+			if err != nil {
+				c.logger.LogAttrs(c.context, slog.LevelError, "failed to list namespaces", slog.String("type", svc.namespaceType), slog.String("err", err.Error()))
+				return nil, fmt.Errorf("%w: %w", ErrPageAdvanceFailure, err)
+			}
+			for _, resource := range page.Value {
+				if ns, ok := namespaceFromResource(svc, resource); ok {
+					namespaces = append(namespaces, ns)
+				}
+			}
+		}
+	}
+	return namespaces, nil
+}
+
+func namespaceFromResource(svc *service, resource *armresources.GenericResourceExpanded) (namespace, bool) {
+	if resource == nil || resource.ID == nil || resource.Name == nil || resource.Location == nil || resource.SKU == nil {
+		return namespace{}, false
+	}
+	tier := ""
+	if resource.SKU.Tier != nil {
+		tier = *resource.SKU.Tier
+	} else if resource.SKU.Name != nil {
+		tier = *resource.SKU.Name
+	}
+	// Standard Service Bus namespaces have no capacity, they're billed a single base charge
+	capacity := int32(1)
+	if resource.SKU.Capacity != nil && *resource.SKU.Capacity > 0 {
+		capacity = *resource.SKU.Capacity
+	}
+	resourceGroup := ""
+	if id, err := arm.ParseResourceID(*resource.ID); err == nil {
+		resourceGroup = id.ResourceGroupName
+	}
+	return namespace{
+		service:       svc,
+		name:          *resource.Name,
+		resourceGroup: resourceGroup,
+		region:        strings.ToLower(*resource.Location),
+		tier:          tier,
+		capacity:      capacity,
+	}, true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
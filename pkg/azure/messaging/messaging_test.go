@@ -0,0 +1,194 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	retailPriceSdk "gomodules.xyz/azure-retail-prices-sdk-for-go/sdk"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const testSubId = "1234-asdf-adsf-adsf"
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// fakeCredential returns a token without authenticating.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeTransport answers the requests with the JSON response returned by respond, a 404 when it returns nil.
+type fakeTransport struct {
+	respond func(req *http.Request) any
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	body := f.respond(req)
+	if body == nil {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}, Request: req}, nil
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(buf))), Header: http.Header{"Content-Type": {"application/json"}}, Request: req}, nil
+}
+
+func Test_parseUnitPrices(t *testing.T) {
+	items := []retailPriceSdk.ResourceSKU{
+		{ServiceName: eventHubsServiceName, ArmRegionName: "eastus", MeterName: "Standard Throughput Unit", UnitOfMeasure: "1 Hour", RetailPrice: 0.03},
+		{ServiceName: eventHubsServiceName, ArmRegionName: "eastus", MeterName: "Premium Processing Unit", UnitOfMeasure: "1 Hour", RetailPrice: 1.233},
+		{ServiceName: serviceBusServiceName, ArmRegionName: "westeurope", MeterName: "Premium Messaging Unit", UnitOfMeasure: "1/Month", RetailPrice: 677.08},
+		// Ingress events aren't capacity units
+		{ServiceName: eventHubsServiceName, ArmRegionName: "eastus", MeterName: "Standard Ingress Events", UnitOfMeasure: "1M", RetailPrice: 0.028},
+		// A meter of another service with the same name
+		{ServiceName: "Relay", ArmRegionName: "eastus", MeterName: "Standard Throughput Unit", UnitOfMeasure: "1 Hour", RetailPrice: 1},
+		{ServiceName: eventHubsServiceName, MeterName: "Basic Throughput Unit", UnitOfMeasure: "1 Hour", RetailPrice: 0.015},
+	}
+	prices := parseUnitPrices(items)
+
+	price, ok := prices.get(eventHubsServiceName, "eastus", "Standard")
+	assert.True(t, ok)
+	assert.Equal(t, 0.03, price)
+	price, ok = prices.get(eventHubsServiceName, "EastUS", "Premium")
+	assert.True(t, ok)
+	assert.Equal(t, 1.233, price)
+	price, ok = prices.get(serviceBusServiceName, "westeurope", "Premium")
+	assert.True(t, ok)
+	assert.InDelta(t, 677.08/utils.HoursInMonth, price, 1e-12)
+	_, ok = prices.get(eventHubsServiceName, "eastus", "Basic")
+	assert.False(t, ok)
+	assert.Len(t, prices[eventHubsServiceName]["eastus"], 2)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	namespace := func(resourceType string, name string, sku map[string]any) map[string]any {
+		return map[string]any{
+			"id":       "/subscriptions/" + testSubId + "/resourceGroups/messaging-rg/providers/" + resourceType + "/" + name,
+			"name":     name,
+			"type":     resourceType,
+			"location": "eastus",
+			"sku":      sku,
+		}
+	}
+	priceRequests := 0
+	transport := &fakeTransport{respond: func(req *http.Request) any {
+		switch req.URL.Path {
+		case "/api/retail/prices":
+			priceRequests++
+			return map[string]any{"Items": []any{
+				map[string]any{"serviceName": eventHubsServiceName, "armRegionName": "eastus", "meterName": "Standard Throughput Unit", "unitOfMeasure": "1 Hour", "retailPrice": 0.03},
+				map[string]any{"serviceName": serviceBusServiceName, "armRegionName": "eastus", "meterName": "Premium Messaging Unit", "unitOfMeasure": "1 Hour", "retailPrice": 0.928},
+			}}
+		case "/subscriptions/" + testSubId + "/resources":
+			filter := req.URL.Query().Get("$filter")
+			switch {
+			case strings.Contains(filter, eventHubsNamespaceType):
+				return map[string]any{"value": []any{
+					namespace(eventHubsNamespaceType, "events", map[string]any{"name": "Standard", "tier": "Standard", "capacity": 4}),
+					// Dedicated isn't priced in eastus
+					namespace(eventHubsNamespaceType, "dedicated", map[string]any{"name": "Dedicated", "tier": "Dedicated", "capacity": 1}),
+				}}
+			case strings.Contains(filter, serviceBusNamespaceType):
+				return map[string]any{"value": []any{
+					namespace(serviceBusNamespaceType, "orders", map[string]any{"name": "Premium", "tier": "Premium", "capacity": 2}),
+				}}
+			}
+		}
+		return nil
+	}}
+	c, err := New(context.Background(), &Config{
+		Logger:         testLogger,
+		Credentials:    fakeCredential{},
+		ClientOptions:  &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}},
+		SubscriptionId: testSubId,
+		ScrapeInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		var metrics []*utils.MetricResult
+		for metric := range ch {
+			metrics = append(metrics, utils.ReadMetrics(metric))
+		}
+		require.Len(t, metrics, 5)
+		assert.Equal(t, &utils.MetricResult{
+			FqName:     "cloudcost_azure_eventhubs_unit_usd_per_hour",
+			Labels:     utils.LabelMap{"region": "eastus", "sku": "Standard"},
+			Value:      0.03,
+			MetricType: prometheus.GaugeValue,
+		}, metrics[0])
+		assert.Equal(t, "cloudcost_azure_servicebus_unit_usd_per_hour", metrics[1].FqName)
+		assert.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[2].FqName)
+		assert.Equal(t, "cloudcost_azure_eventhubs_namespace_usd_per_hour", metrics[3].FqName)
+		assert.Equal(t, utils.LabelMap{"namespace": "events", "resource_group": "messaging-rg", "region": "eastus", "sku": "Standard", "capacity": "4"}, metrics[3].Labels)
+		assert.InDelta(t, 4*0.03, metrics[3].Value, 1e-12)
+		assert.Equal(t, "cloudcost_azure_servicebus_namespace_usd_per_hour", metrics[4].FqName)
+		assert.InDelta(t, 2*0.928, metrics[4].Value, 1e-12)
+	}
+	// The prices are only listed once per scrape interval
+	assert.Equal(t, 1, priceRequests)
+}
+
+// throttlingTransport answers every request with a 429.
+type throttlingTransport struct{}
+
+func (throttlingTransport) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusTooManyRequests, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}, Request: req}, nil
+}
+
+func TestCollector_CollectThrottled(t *testing.T) {
+	c, err := New(context.Background(), &Config{
+		Logger:      testLogger,
+		Credentials: fakeCredential{},
+		ClientOptions: &arm.ClientOptions{ClientOptions: policy.ClientOptions{
+			Transport: throttlingTransport{},
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		}},
+		SubscriptionId: testSubId,
+		ScrapeInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	ch := make(chan prometheus.Metric, 10)
+	err = c.Collect(ch)
+	assert.ErrorIs(t, err, ErrPageAdvanceFailure)
+	assert.True(t, provider.IsThrottled(err))
+	assert.Empty(t, ch)
+	assert.Equal(t, 2*time.Hour, c.backoff.Interval())
+}
+
+func Test_namespaceFromResource(t *testing.T) {
+	svc := &services[1]
+	ns, ok := namespaceFromResource(svc, nil)
+	assert.False(t, ok)
+	assert.Equal(t, namespace{}, ns)
+
+	// Standard Service Bus namespaces have no capacity
+	standard := "Standard"
+	id := "/subscriptions/" + testSubId + "/resourceGroups/rg/providers/" + serviceBusNamespaceType + "/orders"
+	name, location := "orders", "WestEurope"
+	ns, ok = namespaceFromResource(svc, &armresources.GenericResourceExpanded{ID: &id, Name: &name, Location: &location, SKU: &armresources.SKU{Name: &standard}})
+	assert.True(t, ok)
+	assert.Equal(t, namespace{service: svc, name: "orders", resourceGroup: "rg", region: "westeurope", tier: "Standard", capacity: 1}, ns)
+}
@@ -0,0 +1,198 @@
+package reservations
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+)
+
+const (
+	savingsPlansPath       = "/providers/Microsoft.BillingBenefits/savingsPlans"
+	savingsPlansAPIVersion = "2022-11-01"
+
+	virtualMachinesResourceType = "VirtualMachines"
+	appliedScopeSingle          = "Single"
+	hourlyGrain                 = "Hourly"
+	usdCurrencyCode             = "USD"
+)
+
+// VMReservation is an active reservation of virtual machines, which covers Quantity VMs of its sku in its region.
+type VMReservation struct {
+	ID string
+	// Region is lowercased, eg eastus.
+	Region   string
+	SKU      string
+	Quantity int
+	// Term is the ISO 8601 duration of the reservation, eg P1Y.
+	Term string
+}
+
+// SavingsPlan is an active savings plan, which covers the usage billed at its rate up to its hourly commitment.
+type SavingsPlan struct {
+	ID string
+	// Term is the ISO 8601 duration of the savings plan, eg P3Y.
+	Term string
+	// HourlyCommitment is the usage covered by the savings plan every hour in USD.
+	HourlyCommitment float64
+}
+
+// Benefits are the reservations and savings plans applying to the virtual machines of a subscription, sorted by ID.
+type Benefits struct {
+	Reservations []VMReservation
+	SavingsPlans []SavingsPlan
+}
+
+type savingsPlanResponse struct {
+	ID         string `json:"id"`
+	Properties struct {
+		DisplayName            string `json:"displayName"`
+		ProvisioningState      string `json:"provisioningState"`
+		Term                   string `json:"term"`
+		AppliedScopeType       string `json:"appliedScopeType"`
+		AppliedScopeProperties struct {
+			SubscriptionID  string `json:"subscriptionId"`
+			ResourceGroupID string `json:"resourceGroupId"`
+		} `json:"appliedScopeProperties"`
+		Commitment struct {
+			Grain        string  `json:"grain"`
+			CurrencyCode string  `json:"currencyCode"`
+			Amount       float64 `json:"amount"`
+		} `json:"commitment"`
+	} `json:"properties"`
+}
+
+// BenefitsLister lists the reservations and savings plans applying to the virtual machines of a subscription, and
+// lists them again once interval passed since the last listing. It's safe for concurrent use.
+type BenefitsLister struct {
+	logger         *slog.Logger
+	armClient      *arm.Client
+	subscriptionID string
+	interval       time.Duration
+	now            func() time.Time
+
+	m        sync.Mutex
+	benefits *Benefits
+	listedAt time.Time
+}
+
+type BenefitsConfig struct {
+	Logger      *slog.Logger
+	Credentials azcore.TokenCredential
+	// ClientOptions configures the cloud and transport of the clients, the SDK defaults are used when nil.
+	ClientOptions *arm.ClientOptions
+
+	SubscriptionId string
+	Interval       time.Duration
+}
+
+// NewBenefitsLister creates a BenefitsLister of the reservations and savings plans applying to a subscription.
+func NewBenefitsLister(ctx context.Context, cfg *BenefitsConfig) (*BenefitsLister, error) {
+	logger := cfg.Logger.With("subsystem", "benefits")
+	armClient, err := arm.NewClient("reservations", "v0.1.0", cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create arm client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	return &BenefitsLister{
+		logger:         logger,
+		armClient:      armClient,
+		subscriptionID: cfg.SubscriptionId,
+		interval:       cfg.Interval,
+		now:            time.Now,
+	}, nil
+}
+
+// List returns the active reservations of virtual machines and savings plans applying to the subscription. They're
+// listed again when the interval passed since the last listing. When a listing fails, the benefits of the last
+// successful listing are returned and the listing is tried again on the next call, an error is only returned when no
+// listing succeeded yet. It's safe to call on a nil BenefitsLister, which returns nil benefits.
+func (l *BenefitsLister) List(ctx context.Context) (*Benefits, error) {
+	if l == nil {
+		return nil, nil
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	now := l.now()
+	if l.benefits != nil && now.Before(l.listedAt.Add(l.interval)) {
+		return l.benefits, nil
+	}
+	benefits, err := l.list(ctx)
+	if err != nil {
+		if l.benefits == nil {
+			return nil, err
+		}
+		l.logger.LogAttrs(ctx, slog.LevelWarn, "failed to list the benefits, keeping the last listing", slog.String("err", err.Error()))
+		return l.benefits, nil
+	}
+	l.benefits = benefits
+	l.listedAt = now
+	return l.benefits, nil
+}
+
+func (l *BenefitsLister) list(ctx context.Context) (*Benefits, error) {
+	reservations, err := listAll[reservationResponse](ctx, l.armClient, reservationsPath, reservationsAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	savingsPlans, err := listAll[savingsPlanResponse](ctx, l.armClient, savingsPlansPath, savingsPlansAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	benefits := &Benefits{}
+	for _, r := range reservations {
+		if !r.active() || r.Properties.ReservedResourceType != virtualMachinesResourceType || r.Properties.Quantity <= 0 {
+			continue
+		}
+		if r.Properties.AppliedScopeType == appliedScopeSingle && !l.inSubscription(r.Properties.AppliedScopes...) {
+			continue
+		}
+		benefits.Reservations = append(benefits.Reservations, VMReservation{
+			ID:       r.ID[strings.LastIndex(r.ID, "/")+1:],
+			Region:   strings.ToLower(r.Location),
+			SKU:      r.SKU.Name,
+			Quantity: r.Properties.Quantity,
+			Term:     r.Properties.Term,
+		})
+	}
+	for _, s := range savingsPlans {
+		properties := s.Properties
+		if properties.ProvisioningState != provisioningStateSucceeded {
+			continue
+		}
+		if properties.AppliedScopeType == appliedScopeSingle && !l.inSubscription(properties.AppliedScopeProperties.SubscriptionID, properties.AppliedScopeProperties.ResourceGroupID) {
+			continue
+		}
+		// The exporter exports USD, a commitment in another currency can't be compared to the retail prices
+		if !strings.EqualFold(properties.Commitment.Grain, hourlyGrain) || properties.Commitment.CurrencyCode != usdCurrencyCode {
+			l.logger.LogAttrs(ctx, slog.LevelWarn, "skipping savings plan without an hourly commitment in USD", slog.String("savings_plan", properties.DisplayName))
+			continue
+		}
+		benefits.SavingsPlans = append(benefits.SavingsPlans, SavingsPlan{
+			ID:               s.ID[strings.LastIndex(s.ID, "/")+1:],
+			Term:             properties.Term,
+			HourlyCommitment: properties.Commitment.Amount,
+		})
+	}
+	sort.Slice(benefits.Reservations, func(i, j int) bool { return benefits.Reservations[i].ID < benefits.Reservations[j].ID })
+	sort.Slice(benefits.SavingsPlans, func(i, j int) bool { return benefits.SavingsPlans[i].ID < benefits.SavingsPlans[j].ID })
+	return benefits, nil
+}
+
+// inSubscription reports whether any of the scopes, eg /subscriptions/<id> or /subscriptions/<id>/resourceGroups/<rg>,
+// is within the subscription of l.
+func (l *BenefitsLister) inSubscription(scopes ...string) bool {
+	prefix := strings.ToLower("/subscriptions/" + l.subscriptionID)
+	for _, scope := range scopes {
+		scope = strings.ToLower(scope)
+		if scope == prefix || strings.HasPrefix(scope, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
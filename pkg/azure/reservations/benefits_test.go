@@ -0,0 +1,117 @@
+package reservations
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func savingsPlanJSON(id string, scopeType string, subscription string, currency string, amount float64) map[string]any {
+	return map[string]any{
+		"id": "/providers/Microsoft.BillingBenefits/savingsPlanOrders/order/savingsPlans/" + id,
+		"properties": map[string]any{
+			"displayName":            "sp-" + id,
+			"provisioningState":      provisioningStateSucceeded,
+			"term":                   "P3Y",
+			"appliedScopeType":       scopeType,
+			"appliedScopeProperties": map[string]any{"subscriptionId": subscription},
+			"commitment":             map[string]any{"grain": "Hourly", "currencyCode": currency, "amount": amount},
+		},
+	}
+}
+
+func TestBenefitsLister_List(t *testing.T) {
+	later := time.Date(2028, time.January, 1, 0, 0, 0, 0, time.UTC)
+	vmReservation := func(id string, scopeType string, scopes ...string) map[string]any {
+		r := reservationJSON(id, provisioningStateSucceeded, later)
+		properties := r["properties"].(map[string]any)
+		properties["appliedScopeType"] = scopeType
+		properties["appliedScopes"] = scopes
+		properties["quantity"] = 3
+		properties["term"] = "P1Y"
+		return r
+	}
+	sqlReservation := vmReservation("sql", "Shared")
+	sqlReservation["properties"].(map[string]any)["reservedResourceType"] = "SqlDatabases"
+	failing := false
+	requests := 0
+	transport := &fakeTransport{respond: func(req *http.Request) any {
+		if failing {
+			return nil
+		}
+		requests++
+		switch req.URL.Path {
+		case reservationsPath:
+			return map[string]any{"value": []any{
+				vmReservation("shared", "Shared"),
+				vmReservation("single", "Single", "/subscriptions/SUB/resourceGroups/aks"),
+				// Reservations of other subscriptions, other resource types or expired ones don't apply
+				vmReservation("other", "Single", "/subscriptions/other-sub"),
+				sqlReservation,
+				reservationJSON("expired", "Expired", later),
+			}}
+		case savingsPlansPath:
+			assert.Equal(t, savingsPlansAPIVersion, req.URL.Query().Get("api-version"))
+			return map[string]any{"value": []any{
+				savingsPlanJSON("sp-1", "Single", "/subscriptions/sub", "USD", 5),
+				savingsPlanJSON("sp-2", "Single", "/subscriptions/other-sub", "USD", 5),
+				savingsPlanJSON("sp-3", "Shared", "", "EUR", 5),
+			}}
+		}
+		return nil
+	}}
+	l, err := NewBenefitsLister(context.Background(), &BenefitsConfig{
+		Logger:         testLogger,
+		Credentials:    fakeCredential{},
+		ClientOptions:  &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}},
+		SubscriptionId: "sub",
+		Interval:       time.Hour,
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	want := &Benefits{
+		Reservations: []VMReservation{
+			{ID: "shared", Region: "eastus", SKU: "Standard_D2s_v3", Quantity: 3, Term: "P1Y"},
+			{ID: "single", Region: "eastus", SKU: "Standard_D2s_v3", Quantity: 3, Term: "P1Y"},
+		},
+		SavingsPlans: []SavingsPlan{{ID: "sp-1", Term: "P3Y", HourlyCommitment: 5}},
+	}
+	benefits, err := l.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, benefits)
+
+	// The benefits aren't listed again before the interval passed, and a failed listing keeps the last one
+	_, err = l.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	failing = true
+	now = now.Add(time.Hour)
+	benefits, err = l.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, benefits)
+}
+
+func TestBenefitsLister_ListError(t *testing.T) {
+	l, err := NewBenefitsLister(context.Background(), &BenefitsConfig{
+		Logger:        testLogger,
+		Credentials:   fakeCredential{},
+		ClientOptions: &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: &fakeTransport{respond: func(*http.Request) any { return nil }}}},
+		Interval:      time.Hour,
+	})
+	require.NoError(t, err)
+	_, err = l.List(context.Background())
+	assert.ErrorIs(t, err, ErrPageAdvanceFailure)
+
+	var disabled *BenefitsLister
+	benefits, err := disabled.List(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, benefits)
+}
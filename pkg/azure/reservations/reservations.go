@@ -0,0 +1,288 @@
+package reservations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "azure"
+
+	reservationsPath       = "/providers/Microsoft.Capacity/reservations"
+	reservationsAPIVersion = "2022-11-01"
+
+	// provisioningStateSucceeded is the state of the reservations that are in effect, expired and cancelled
+	// reservations are listed too.
+	provisioningStateSucceeded = "Succeeded"
+)
+
+// Errors
+var (
+	ErrClientCreationFailure = errors.New("failed to create client")
+	ErrPageAdvanceFailure    = errors.New("failed to advance page")
+)
+
+// Prometheus Metrics
+var (
+	ExpiryTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_reservation", "expiry_timestamp_seconds"),
+		"The time at which an active Azure reservation expires, in seconds since the epoch.",
+		[]string{"reservation_id", "reservation_name", "reserved_resource_type", "sku", "region", "applied_scope_type"},
+		nil,
+	)
+	UtilizationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "azure_reservation", "utilization_percent"),
+		"The average utilization of an active Azure reservation over the last day, 7 days or 30 days in percent.",
+		[]string{"reservation_id", "reservation_name", "period"},
+		nil,
+	)
+)
+
+// page is a page of the reservations or savings plans the credentials can read, across every billing scope.
+type page[T any] struct {
+	Value    []T    `json:"value"`
+	NextLink string `json:"nextLink"`
+}
+
+type reservationResponse struct {
+	ID       string `json:"id"`
+	Location string `json:"location"`
+	SKU      struct {
+		Name string `json:"name"`
+	} `json:"sku"`
+	Properties struct {
+		DisplayName          string    `json:"displayName"`
+		ReservedResourceType string    `json:"reservedResourceType"`
+		AppliedScopeType     string    `json:"appliedScopeType"`
+		AppliedScopes        []string  `json:"appliedScopes"`
+		Quantity             int       `json:"quantity"`
+		Term                 string    `json:"term"`
+		ProvisioningState    string    `json:"provisioningState"`
+		ExpiryDateTime       time.Time `json:"expiryDateTime"`
+		Utilization          struct {
+			Aggregates []struct {
+				Grain     float64 `json:"grain"`
+				GrainUnit string  `json:"grainUnit"`
+				Value     float64 `json:"value"`
+				ValueUnit string  `json:"valueUnit"`
+			} `json:"aggregates"`
+		} `json:"utilization"`
+	} `json:"properties"`
+}
+
+// reservation is an active reservation along with its utilization, keyed by period, eg 7d.
+type reservation struct {
+	id               string
+	name             string
+	resourceType     string
+	sku              string
+	region           string
+	appliedScopeType string
+	expiry           time.Time
+	utilization      map[string]float64
+}
+
+// Collector exports the expiration and utilization of the active reservations, so that renewals and unused
+// reservations can be acted on alongside the EC2 Reserved Instances and GCP committed use discounts.
+type Collector struct {
+	context context.Context
+	logger  *slog.Logger
+
+	armClient *arm.Client
+
+	backoff      *provider.Backoff
+	nextScrape   time.Time
+	reservations []reservation
+	m            sync.Mutex
+}
+
+type Config struct {
+	Logger      *slog.Logger
+	Credentials azcore.TokenCredential
+	// ClientOptions configures the cloud and transport of the clients, the SDK defaults are used when nil.
+	ClientOptions *arm.ClientOptions
+
+	ScrapeInterval time.Duration
+}
+
+// New creates a Collector. The reservations are only listed again every scrape interval, or less often while the
+// reservations API throttles the collector, as their utilization is only updated daily.
+func New(ctx context.Context, cfg *Config) (*Collector, error) {
+	logger := cfg.Logger.With("collector", "reservations")
+	// There is no SDK of the Capacity API in the module, the reservations are requested with a plain ARM client
+	armClient, err := arm.NewClient("reservations", "v0.1.0", cfg.Credentials, cfg.ClientOptions)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "failed to create arm client", slog.String("err", err.Error()))
+		return nil, ErrClientCreationFailure
+	}
+	c := &Collector{
+		context:   ctx,
+		logger:    logger,
+		armClient: armClient,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), cfg.ScrapeInterval)
+	return c, nil
+}
+
+// NewForDocs returns a Collector without any clients, which is only able to describe its metrics.
+func NewForDocs(ctx context.Context, logger *slog.Logger) *Collector {
+	return &Collector{
+		context: ctx,
+		logger:  logger.With("collector", "reservations"),
+	}
+}
+
+func (c *Collector) Name() string {
+	return "Reservations"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- ExpiryTimestampDesc
+	ch <- UtilizationDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the reservations again when the scrape interval has passed and exports them.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.reservations == nil || now.After(c.nextScrape) {
+		reservations, err := c.listReservations()
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return err
+		}
+		c.reservations = reservations
+	}
+	for _, r := range c.reservations {
+		ch <- prometheus.MustNewConstMetric(ExpiryTimestampDesc, prometheus.GaugeValue, float64(r.expiry.Unix()),
+			r.id, r.name, r.resourceType, r.sku, r.region, r.appliedScopeType)
+		periods := make([]string, 0, len(r.utilization))
+		for period := range r.utilization {
+			periods = append(periods, period)
+		}
+		sort.Strings(periods)
+		for _, period := range periods {
+			ch <- prometheus.MustNewConstMetric(UtilizationDesc, prometheus.GaugeValue, r.utilization[period], r.id, r.name, period)
+		}
+	}
+	c.backoff.Emit(ch)
+	return nil
+}
+
+// listReservations lists the active reservations, sorted by expiration and ID.
+func (c *Collector) listReservations() ([]reservation, error) {
+	responses, err := listAll[reservationResponse](c.context, c.armClient, reservationsPath, reservationsAPIVersion)
+	if err != nil {
+		c.logger.LogAttrs(c.context, slog.LevelError, "failed to list reservations", slog.String("err", err.Error()))
+		return nil, err
+	}
+	reservations := []reservation{}
+	for _, r := range responses {
+		if !r.active() {
+			continue
+		}
+		reservations = append(reservations, newReservation(r))
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if !reservations[i].expiry.Equal(reservations[j].expiry) {
+			return reservations[i].expiry.Before(reservations[j].expiry)
+		}
+		return reservations[i].id < reservations[j].id
+	})
+	return reservations, nil
+}
+
+// active reports whether a reservation is in effect, expired and cancelled reservations are listed too.
+func (r reservationResponse) active() bool {
+	return r.Properties.ProvisioningState == provisioningStateSucceeded && !r.Properties.ExpiryDateTime.IsZero()
+}
+
+// listAll lists every page of the resources at path, eg the reservations.
+func listAll[T any](ctx context.Context, armClient *arm.Client, path string, apiVersion string) ([]T, error) {
+	var values []T
+	next := runtime.JoinPaths(armClient.Endpoint(), path)
+	for first := true; next != ""; first = false {
+		page, err := listPage[T](ctx, armClient, next, apiVersion, first)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPageAdvanceFailure, err)
+		}
+		values = append(values, page.Value...)
+		next = page.NextLink
+	}
+	return values, nil
+}
+
+// listPage requests a page of resources. The next links already carry the api-version, only the first page needs it
+// to be set.
+func listPage[T any](ctx context.Context, armClient *arm.Client, link string, apiVersion string, first bool) (*page[T], error) {
+	req, err := runtime.NewRequest(ctx, http.MethodGet, link)
+	if err != nil {
+		return nil, err
+	}
+	if first {
+		query := req.Raw().URL.Query()
+		query.Set("api-version", apiVersion)
+		req.Raw().URL.RawQuery = query.Encode()
+	}
+	req.Raw().Header.Set("Accept", "application/json")
+	resp, err := armClient.Pipeline().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return nil, runtime.NewResponseError(resp)
+	}
+	var p page[T]
+	if err := runtime.UnmarshalAsJSON(resp, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func newReservation(r reservationResponse) reservation {
+	res := reservation{
+		id:               r.ID[strings.LastIndex(r.ID, "/")+1:],
+		name:             r.Properties.DisplayName,
+		resourceType:     r.Properties.ReservedResourceType,
+		sku:              r.SKU.Name,
+		region:           strings.ToLower(r.Location),
+		appliedScopeType: r.Properties.AppliedScopeType,
+		expiry:           r.Properties.ExpiryDateTime,
+		utilization:      make(map[string]float64),
+	}
+	for _, aggregate := range r.Properties.Utilization.Aggregates {
+		if !strings.EqualFold(aggregate.GrainUnit, "days") || !strings.EqualFold(aggregate.ValueUnit, "percentage") {
+			continue
+		}
+		res.utilization[fmt.Sprintf("%gd", aggregate.Grain)] = aggregate.Value
+	}
+	return res
+}
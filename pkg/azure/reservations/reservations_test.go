@@ -0,0 +1,153 @@
+package reservations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// fakeCredential returns a token without authenticating.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeTransport answers the requests with the JSON response returned by respond, a 404 when it returns nil.
+type fakeTransport struct {
+	respond func(req *http.Request) any
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	body := f.respond(req)
+	if body == nil {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}, Request: req}, nil
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(buf))), Header: http.Header{"Content-Type": {"application/json"}}, Request: req}, nil
+}
+
+func reservationJSON(id string, state string, expiry time.Time, utilization ...float64) map[string]any {
+	var aggregates []any
+	for i, grain := range []float64{1, 7, 30}[:len(utilization)] {
+		aggregates = append(aggregates, map[string]any{"grain": grain, "grainUnit": "days", "value": utilization[i], "valueUnit": "percentage"})
+	}
+	return map[string]any{
+		"id":       "/providers/microsoft.capacity/reservationOrders/order/reservations/" + id,
+		"location": "EastUS",
+		"sku":      map[string]any{"name": "Standard_D2s_v3"},
+		"properties": map[string]any{
+			"displayName":          "vm-" + id,
+			"reservedResourceType": "VirtualMachines",
+			"appliedScopeType":     "Shared",
+			"provisioningState":    state,
+			"expiryDateTime":       expiry.Format(time.RFC3339),
+			"utilization":          map[string]any{"aggregates": aggregates},
+		},
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	soon := time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2028, time.January, 1, 0, 0, 0, 0, time.UTC)
+	requests := 0
+	transport := &fakeTransport{respond: func(req *http.Request) any {
+		if req.URL.Path != reservationsPath {
+			return nil
+		}
+		requests++
+		if req.URL.Query().Get("page") == "2" {
+			return map[string]any{"value": []any{
+				reservationJSON("b", provisioningStateSucceeded, soon, 100, 80, 60),
+			}}
+		}
+		assert.Equal(t, reservationsAPIVersion, req.URL.Query().Get("api-version"))
+		return map[string]any{
+			"value": []any{
+				reservationJSON("a", provisioningStateSucceeded, later, 50),
+				// Expired reservations aren't exported
+				reservationJSON("c", "Expired", soon),
+			},
+			"nextLink": "https://management.azure.com" + reservationsPath + "?api-version=" + reservationsAPIVersion + "&page=2",
+		}
+	}}
+	c, err := New(context.Background(), &Config{
+		Logger:         testLogger,
+		Credentials:    fakeCredential{},
+		ClientOptions:  &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: transport}},
+		ScrapeInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		var metrics []*utils.MetricResult
+		for metric := range ch {
+			metrics = append(metrics, utils.ReadMetrics(metric))
+		}
+		require.Len(t, metrics, 7)
+		assert.Equal(t, &utils.MetricResult{
+			FqName: "cloudcost_azure_reservation_expiry_timestamp_seconds",
+			Labels: utils.LabelMap{
+				"reservation_id":         "b",
+				"reservation_name":       "vm-b",
+				"reserved_resource_type": "VirtualMachines",
+				"sku":                    "Standard_D2s_v3",
+				"region":                 "eastus",
+				"applied_scope_type":     "Shared",
+			},
+			Value:      float64(soon.Unix()),
+			MetricType: prometheus.GaugeValue,
+		}, metrics[0])
+		for j, want := range []struct {
+			period string
+			value  float64
+		}{{"1d", 100}, {"30d", 60}, {"7d", 80}} {
+			assert.Equal(t, &utils.MetricResult{
+				FqName:     "cloudcost_azure_reservation_utilization_percent",
+				Labels:     utils.LabelMap{"reservation_id": "b", "reservation_name": "vm-b", "period": want.period},
+				Value:      want.value,
+				MetricType: prometheus.GaugeValue,
+			}, metrics[1+j])
+		}
+		assert.Equal(t, "cloudcost_azure_reservation_expiry_timestamp_seconds", metrics[4].FqName)
+		assert.Equal(t, "a", metrics[4].Labels["reservation_id"])
+		assert.Equal(t, 50.0, metrics[5].Value)
+		assert.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[6].FqName)
+	}
+	// The reservations are only listed once per scrape interval
+	assert.Equal(t, 2, requests)
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	c, err := New(context.Background(), &Config{
+		Logger:         testLogger,
+		Credentials:    fakeCredential{},
+		ClientOptions:  &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: &fakeTransport{respond: func(*http.Request) any { return nil }}}},
+		ScrapeInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.Collect(make(chan prometheus.Metric, 10)), ErrPageAdvanceFailure)
+}
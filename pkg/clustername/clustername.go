@@ -0,0 +1,40 @@
+package clustername
+
+import "strings"
+
+// Normalizer rewrites the cluster names found by the collectors so that cluster_name can be joined across providers.
+// EKS names come from instance tags, GKE names from the goog-k8s-cluster-name label and AKS names from the managed
+// cluster resources, each with their own casing rules.
+type Normalizer struct {
+	lowercase bool
+	// overrides maps a lowercased cluster name to the name it's exported as.
+	overrides map[string]string
+}
+
+// NewNormalizer returns a Normalizer. overrides is keyed by cluster name, keys are matched case-insensitively and
+// values are used as is, so they can also opt a cluster out of lowercasing.
+func NewNormalizer(lowercase bool, overrides map[string]string) *Normalizer {
+	normalized := make(map[string]string, len(overrides))
+	for from, to := range overrides {
+		normalized[strings.ToLower(strings.TrimSpace(from))] = to
+	}
+	return &Normalizer{
+		lowercase: lowercase,
+		overrides: normalized,
+	}
+}
+
+// Normalize returns the name a cluster is exported as. A nil Normalizer returns the name unchanged.
+func (n *Normalizer) Normalize(name string) string {
+	if n == nil {
+		return name
+	}
+	name = strings.TrimSpace(name)
+	if override, ok := n.overrides[strings.ToLower(name)]; ok {
+		return override
+	}
+	if n.lowercase {
+		return strings.ToLower(name)
+	}
+	return name
+}
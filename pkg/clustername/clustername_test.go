@@ -0,0 +1,45 @@
+package clustername
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizer_Normalize(t *testing.T) {
+	tests := map[string]struct {
+		normalizer *Normalizer
+		name       string
+		want       string
+	}{
+		"nil normalizer keeps the name": {
+			name: " Prod-EU ",
+			want: " Prod-EU ",
+		},
+		"lowercased": {
+			normalizer: NewNormalizer(true, nil),
+			name:       " Prod-EU",
+			want:       "prod-eu",
+		},
+		"lowercasing disabled": {
+			normalizer: NewNormalizer(false, nil),
+			name:       "Prod-EU ",
+			want:       "Prod-EU",
+		},
+		"overrides match regardless of casing": {
+			normalizer: NewNormalizer(true, map[string]string{"PROD-eu-1": "prod-eu"}),
+			name:       "Prod-EU-1",
+			want:       "prod-eu",
+		},
+		"overrides are used as is": {
+			normalizer: NewNormalizer(true, map[string]string{"prod-eu-1": "Prod-EU"}),
+			name:       "prod-eu-1",
+			want:       "Prod-EU",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.normalizer.Normalize(tt.name))
+		})
+	}
+}
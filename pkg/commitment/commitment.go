@@ -0,0 +1,195 @@
+// Package commitment recommends commitment purchases, eg EC2 Reserved Instances, Savings Plans or GCP committed use
+// discounts, from the on-demand cores the exporter saw continuously running over a window.
+package commitment
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+var (
+	// RecommendedCoresDesc is the number of on-demand cores of a family that ran continuously over the window.
+	RecommendedCoresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.ExporterName, "commitment", "recommended_cores"),
+		"The number of on-demand cores of a machine family and region that have been running continuously over the recommendation window, a candidate for a commitment purchase.",
+		[]string{"provider", "region", "family"},
+		nil,
+	)
+	// PotentialSavingsDesc is what committing to the recommended cores would save compared to on-demand.
+	PotentialSavingsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.ExporterName, "commitment", "potential_savings_usd_per_hour"),
+		"The hourly savings in USD of committing to the recommended cores of a machine family and region, at the configured commitment discount compared to on-demand.",
+		[]string{"provider", "region", "family"},
+		nil,
+	)
+)
+
+// Recommender keeps the history of the on-demand cores of every family and region over a window. It's safe for
+// concurrent use.
+type Recommender struct {
+	window time.Duration
+	// discount is the share of the on-demand price a commitment saves, between 0 and 1.
+	discount float64
+	now      func() time.Time
+
+	m      sync.Mutex
+	series map[seriesKey]*history
+}
+
+type seriesKey struct {
+	provider string
+	region   string
+	family   string
+}
+
+// sample is the cores of a family and region seen during a scrape, and their hourly cost.
+type sample struct {
+	at         time.Time
+	cores      float64
+	usdPerHour float64
+}
+
+type history struct {
+	samples []sample
+}
+
+// New returns a Recommender recommending the cores that ran continuously over window, saving discount of their
+// on-demand cost. nil is returned when window is 0, which disables the recommendations.
+func New(window time.Duration, discount float64) (*Recommender, error) {
+	if window == 0 {
+		return nil, nil
+	}
+	if window < 0 {
+		return nil, fmt.Errorf("commitment window must be positive, got %s", window)
+	}
+	if discount < 0 || discount > 1 {
+		return nil, fmt.Errorf("commitment discount must be between 0 and 1, got %v", discount)
+	}
+	return &Recommender{
+		window:   window,
+		discount: discount,
+		now:      time.Now,
+		series:   make(map[seriesKey]*history),
+	}, nil
+}
+
+// Scrape returns an accumulator of the on-demand cores seen during a single scrape. It returns nil, which is safe to
+// use, when r is nil.
+func (r *Recommender) Scrape() *Usage {
+	if r == nil {
+		return nil
+	}
+	return &Usage{recommender: r, families: make(map[familyKey]*sample)}
+}
+
+// Usage accumulates the on-demand cores of every family and region during a scrape.
+type Usage struct {
+	recommender *Recommender
+
+	m        sync.Mutex
+	families map[familyKey]*sample
+}
+
+type familyKey struct {
+	region string
+	family string
+}
+
+// Add adds an on-demand instance with cores, costing usdPerHour, to its family and region.
+func (u *Usage) Add(region string, family string, cores float64, usdPerHour float64) {
+	if u == nil {
+		return
+	}
+	u.m.Lock()
+	defer u.m.Unlock()
+	key := familyKey{region: region, family: family}
+	s, ok := u.families[key]
+	if !ok {
+		s = &sample{}
+		u.families[key] = s
+	}
+	s.cores += cores
+	s.usdPerHour += usdPerHour
+}
+
+// Metrics adds the cores of the scrape to the history of their family and region, and returns the recommendations of
+// the families of provider whose history covers the whole window. Families that weren't seen during the scrape have
+// no running on-demand cores, so they don't have any recommendation until the window passed again.
+func (u *Usage) Metrics(provider string) []prometheus.Metric {
+	if u == nil {
+		return nil
+	}
+	u.m.Lock()
+	defer u.m.Unlock()
+	r := u.recommender
+	r.m.Lock()
+	defer r.m.Unlock()
+	now := r.now()
+
+	for key := range r.series {
+		if _, ok := u.families[familyKey{region: key.region, family: key.family}]; !ok && key.provider == provider {
+			delete(r.series, key)
+		}
+	}
+	var keys []seriesKey
+	for family, s := range u.families {
+		key := seriesKey{provider: provider, region: family.region, family: family.family}
+		h, ok := r.series[key]
+		if !ok {
+			h = &history{}
+			r.series[key] = h
+		}
+		h.add(sample{at: now, cores: s.cores, usdPerHour: s.usdPerHour}, now.Add(-r.window))
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].region != keys[j].region {
+			return keys[i].region < keys[j].region
+		}
+		return keys[i].family < keys[j].family
+	})
+
+	var metrics []prometheus.Metric
+	for _, key := range keys {
+		h := r.series[key]
+		if now.Sub(h.samples[0].at) < r.window {
+			continue
+		}
+		cores, usdPerCoreHour := h.steadyState()
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(RecommendedCoresDesc, prometheus.GaugeValue, cores, key.provider, key.region, key.family),
+			prometheus.MustNewConstMetric(PotentialSavingsDesc, prometheus.GaugeValue, cores*usdPerCoreHour*r.discount, key.provider, key.region, key.family),
+		)
+	}
+	return metrics
+}
+
+// add appends s and drops the samples older than since, keeping the most recent of them so that the history still
+// covers the whole window.
+func (h *history) add(s sample, since time.Time) {
+	h.samples = append(h.samples, s)
+	drop := 0
+	for drop+1 < len(h.samples) && !h.samples[drop+1].at.After(since) {
+		drop++
+	}
+	h.samples = h.samples[drop:]
+}
+
+// steadyState returns the fewest cores seen over the window, and the hourly cost of a core of the latest sample.
+func (h *history) steadyState() (float64, float64) {
+	cores := h.samples[0].cores
+	for _, s := range h.samples[1:] {
+		cores = min(cores, s.cores)
+	}
+	latest := h.samples[len(h.samples)-1]
+	if latest.cores == 0 {
+		return cores, 0
+	}
+	return cores, latest.usdPerHour / latest.cores
+}
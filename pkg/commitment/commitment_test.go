@@ -0,0 +1,82 @@
+package commitment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestNew(t *testing.T) {
+	recommender, err := New(0, 0.3)
+	require.NoError(t, err)
+	assert.Nil(t, recommender)
+	usage := recommender.Scrape()
+	usage.Add("us-east-1", "m5", 4, 0.2)
+	assert.Nil(t, usage.Metrics("aws"), "a disabled recommender is safe to use")
+
+	_, err = New(-time.Hour, 0.3)
+	assert.Error(t, err)
+	_, err = New(time.Hour, 1.5)
+	assert.Error(t, err)
+}
+
+func TestUsage_Metrics(t *testing.T) {
+	recommender, err := New(time.Hour, 0.5)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recommender.now = func() time.Time { return now }
+
+	type recommendation struct {
+		cores   float64
+		savings float64
+	}
+	scrape := func(cores map[string]float64) map[string]recommendation {
+		u := recommender.Scrape()
+		for family, c := range cores {
+			// Every core costs 0.1 USD per hour, added as two instances to verify they are summed
+			u.Add("us-east-1", family, c/2, c/2*0.1)
+			u.Add("us-east-1", family, c/2, c/2*0.1)
+		}
+		recommendations := make(map[string]recommendation)
+		for _, m := range u.Metrics("aws") {
+			result := utils.ReadMetrics(m)
+			require.Equal(t, prometheus.GaugeValue, result.MetricType)
+			require.Equal(t, "aws", result.Labels["provider"])
+			require.Equal(t, "us-east-1", result.Labels["region"])
+			r := recommendations[result.Labels["family"]]
+			switch result.FqName {
+			case "cloudcost_exporter_commitment_recommended_cores":
+				r.cores = result.Value
+			case "cloudcost_exporter_commitment_potential_savings_usd_per_hour":
+				r.savings = result.Value
+			default:
+				t.Fatalf("unexpected metric %s", result.FqName)
+			}
+			recommendations[result.Labels["family"]] = r
+		}
+		now = now.Add(20 * time.Minute)
+		return recommendations
+	}
+
+	assert.Empty(t, scrape(map[string]float64{"m5": 8, "c5": 4}), "nothing is recommended until the history covers the window")
+	assert.Empty(t, scrape(map[string]float64{"m5": 4, "c5": 4}))
+	assert.Empty(t, scrape(map[string]float64{"m5": 8}), "c5 stopped running, so its history starts over")
+
+	recommendations := scrape(map[string]float64{"m5": 8, "c5": 4})
+	require.Len(t, recommendations, 1)
+	assert.Equal(t, 4.0, recommendations["m5"].cores, "the fewest cores seen over the window are recommended")
+	assert.InDelta(t, 4*0.1*0.5, recommendations["m5"].savings, 1e-9)
+
+	// The scrape with 4 m5 cores opens the window, until the next scrape does
+	recommendations = scrape(map[string]float64{"m5": 8, "c5": 4})
+	assert.Equal(t, 4.0, recommendations["m5"].cores)
+
+	recommendations = scrape(map[string]float64{"m5": 8, "c5": 4})
+	assert.Equal(t, 8.0, recommendations["m5"].cores)
+	assert.InDelta(t, 8*0.1*0.5, recommendations["m5"].savings, 1e-9)
+}
@@ -0,0 +1,394 @@
+// Package costhistory records the estimated daily cost of every resource exported by the collectors to a local file and
+// serves it on /api/v1/history, so that week over week costs can be looked up without the billing permissions of Cost
+// Explorer or a long term storage of the metrics.
+package costhistory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// Path serves the history when it's enabled.
+	Path = "/api/v1/history"
+	// DateLayout is the layout of the dates of the days, which are UTC days.
+	DateLayout = "2006-01-02"
+	// DefaultInterval is how often the costs are sampled when Config.Interval isn't set.
+	DefaultInterval = 5 * time.Minute
+	// DefaultDays is the number of days kept when Config.Days isn't set, enough for a month over month view.
+	DefaultDays = 35
+
+	hourlySuffix = "_usd_per_hour"
+	totalSuffix  = "_usd_total"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+// Config configures the History.
+type Config struct {
+	// File is the JSON file the history is persisted to after every sample. It's loaded on start when it exists.
+	File string
+	// Days is the number of days kept, the older ones are dropped.
+	Days int
+	// Interval is how often the costs are sampled.
+	Interval time.Duration
+	// Prefix selects the metrics of the module the history is recorded for, eg cloudcost_aws_.
+	Prefix string
+	Logger *slog.Logger
+}
+
+// Cost is the cost of a single resource over a day.
+type Cost struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels"`
+	USD    float64           `json:"usd"`
+}
+
+// Day is the cost of every resource over a UTC day. Hours is the time covered by the samples of the day, which is
+// less than 24 on the first and last day of the exporter or when it was down.
+type Day struct {
+	Date  string  `json:"date"`
+	Hours float64 `json:"hours"`
+	Costs []Cost  `json:"costs,omitempty"`
+}
+
+type day struct {
+	hours float64
+	costs map[string]*Cost
+}
+
+type file struct {
+	Days []Day `json:"days"`
+}
+
+// History accumulates the cost of the resources of the gauges in USD/h, which are integrated over the time between
+// two samples, and of the counters in USD, which add their increase between two samples. The first sample of a series
+// is only its baseline, and gaps between samples longer than twice the interval, eg while the exporter was down,
+// aren't accounted for. A nil History records nothing, which is the case when the cost history is disabled.
+type History struct {
+	cfg Config
+	now func() time.Time
+
+	m          sync.Mutex
+	days       map[string]*day
+	lastSample time.Time
+	counters   map[string]float64
+}
+
+// New returns a History loaded from the file of cfg when it exists, or nil when no file is configured.
+func New(cfg Config) (*History, error) {
+	if cfg.File == "" {
+		return nil, nil
+	}
+	if cfg.Days <= 0 {
+		cfg.Days = DefaultDays
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	h := &History{
+		cfg:      cfg,
+		now:      time.Now,
+		days:     make(map[string]*day),
+		counters: make(map[string]float64),
+	}
+	data, err := os.ReadFile(cfg.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cost history: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing cost history %s: %w", cfg.File, err)
+	}
+	for _, d := range f.Days {
+		loaded := &day{hours: d.Hours, costs: make(map[string]*Cost, len(d.Costs))}
+		for _, c := range d.Costs {
+			loaded.costs[seriesKey(c.Metric, c.Labels)] = &c
+		}
+		h.days[d.Date] = loaded
+	}
+	return h, nil
+}
+
+// Run samples the metrics of g every interval and persists the history until ctx is done.
+func (h *History) Run(ctx context.Context, g prometheus.Gatherer) {
+	if h == nil {
+		return
+	}
+	ticker := time.NewTicker(h.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			families, err := g.Gather()
+			if err != nil {
+				// Gather returns the families it could gather along with the errors of the others
+				h.cfg.Logger.LogAttrs(ctx, slog.LevelWarn, "Error gathering the metrics of the cost history", slog.String("message", err.Error()))
+			}
+			h.Record(families)
+			if err := h.Save(); err != nil {
+				h.cfg.Logger.LogAttrs(ctx, slog.LevelError, "Error saving the cost history", slog.String("message", err.Error()))
+			}
+		}
+	}
+}
+
+// Record adds a sample of the cost metrics of families to the day of the sample and drops the days older than the
+// retention.
+func (h *History) Record(families []*dto.MetricFamily) {
+	if h == nil {
+		return
+	}
+	h.m.Lock()
+	defer h.m.Unlock()
+	now := h.now().UTC()
+	var elapsed time.Duration
+	if !h.lastSample.IsZero() {
+		elapsed = now.Sub(h.lastSample)
+		if elapsed > 2*h.cfg.Interval || elapsed < 0 {
+			elapsed = 0
+		}
+	}
+	h.lastSample = now
+
+	date := now.Format(DateLayout)
+	d, ok := h.days[date]
+	if !ok {
+		d = &day{costs: make(map[string]*Cost)}
+		h.days[date] = d
+	}
+	d.hours += elapsed.Hours()
+
+	counters := make(map[string]float64, len(h.counters))
+	for _, family := range families {
+		name := family.GetName()
+		if !strings.HasPrefix(name, h.cfg.Prefix) {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			key := seriesKey(name, labels)
+			var usd float64
+			switch {
+			case family.GetType() == dto.MetricType_GAUGE && strings.HasSuffix(name, hourlySuffix):
+				usd = m.GetGauge().GetValue() * elapsed.Hours()
+			case family.GetType() == dto.MetricType_COUNTER && strings.HasSuffix(name, totalSuffix):
+				value := m.GetCounter().GetValue()
+				counters[key] = value
+				last, ok := h.counters[key]
+				if !ok || elapsed == 0 {
+					continue
+				}
+				usd = value - last
+				// The counter was reset, eg the resource was seen again after it disappeared
+				if usd < 0 {
+					usd = value
+				}
+			default:
+				continue
+			}
+			c, ok := d.costs[key]
+			if !ok {
+				c = &Cost{Metric: name, Labels: labels}
+				d.costs[key] = c
+			}
+			c.USD += usd
+		}
+	}
+	h.counters = counters
+
+	oldest := now.AddDate(0, 0, -h.cfg.Days+1).Format(DateLayout)
+	for date := range h.days {
+		if date < oldest {
+			delete(h.days, date)
+		}
+	}
+}
+
+// Days returns the days from from to to included, both in DateLayout, sorted by date. Empty bounds match any day.
+func (h *History) Days(from string, to string) []Day {
+	if h == nil {
+		return nil
+	}
+	h.m.Lock()
+	defer h.m.Unlock()
+	var days []Day
+	for date, d := range h.days {
+		if (from != "" && date < from) || (to != "" && date > to) {
+			continue
+		}
+		result := Day{Date: date, Hours: d.hours, Costs: make([]Cost, 0, len(d.costs))}
+		for _, c := range d.costs {
+			result.Costs = append(result.Costs, Cost{Metric: c.Metric, Labels: c.Labels, USD: c.USD})
+		}
+		sort.Slice(result.Costs, func(i, j int) bool {
+			return seriesKey(result.Costs[i].Metric, result.Costs[i].Labels) < seriesKey(result.Costs[j].Metric, result.Costs[j].Labels)
+		})
+		days = append(days, result)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// Save writes the history to its file. The file is replaced atomically so that a crash doesn't leave it truncated.
+func (h *History) Save() error {
+	if h == nil {
+		return nil
+	}
+	data, err := json.Marshal(file{Days: h.Days("", "")})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.cfg.File), filepath.Base(h.cfg.File)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error saving cost history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving cost history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving cost history: %w", err)
+	}
+	return os.Rename(tmp.Name(), h.cfg.File)
+}
+
+// Point is the cost of a series over a day.
+type Point struct {
+	Date string  `json:"date"`
+	USD  float64 `json:"usd"`
+}
+
+// Series is the daily cost of a resource, or of a group of resources when the costs are summed by labels.
+type Series struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels"`
+	Points []Point           `json:"points"`
+}
+
+// Query is a filter of the series, empty fields match any value.
+type Query struct {
+	Metric string
+	// From and To are the first and last days included, in DateLayout.
+	From string
+	To   string
+	// By sums the costs of the resources with the same values of these labels, like the by clause of sum in PromQL.
+	By []string
+}
+
+func (q Query) validate() error {
+	for _, date := range []string{q.From, q.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			return fmt.Errorf("%w %q, expected %s", ErrInvalidDate, date, DateLayout)
+		}
+	}
+	return nil
+}
+
+// Series returns the series matching the query sorted by metric and labels, along with the days they cover.
+func (h *History) Series(q Query) ([]Day, []Series) {
+	days := h.Days(q.From, q.To)
+	series := make(map[string]*Series)
+	for i, d := range days {
+		for _, c := range d.Costs {
+			if q.Metric != "" && q.Metric != c.Metric {
+				continue
+			}
+			labels := c.Labels
+			if q.By != nil {
+				labels = make(map[string]string, len(q.By))
+				for _, name := range q.By {
+					labels[name] = c.Labels[name]
+				}
+			}
+			key := seriesKey(c.Metric, labels)
+			s, ok := series[key]
+			if !ok {
+				s = &Series{Metric: c.Metric, Labels: labels}
+				series[key] = s
+			}
+			if len(s.Points) == 0 || s.Points[len(s.Points)-1].Date != d.Date {
+				s.Points = append(s.Points, Point{Date: d.Date})
+			}
+			s.Points[len(s.Points)-1].USD += c.USD
+		}
+		// Only the coverage of the days is returned along with the series
+		days[i].Costs = nil
+	}
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]Series, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *series[key])
+	}
+	return days, result
+}
+
+type response struct {
+	Days   []Day    `json:"days"`
+	Series []Series `json:"series"`
+}
+
+// Handler serves the series matching the metric, from, to and by query parameters as JSON, along with the days they
+// cover. by is a comma separated list of labels.
+func (h *History) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := Query{
+			Metric: params.Get("metric"),
+			From:   params.Get("from"),
+			To:     params.Get("to"),
+		}
+		if by := params.Get("by"); by != "" {
+			q.By = strings.Split(by, ",")
+		}
+		if err := q.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		days, series := h.Series(q)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response{Days: days, Series: series})
+	})
+}
+
+// seriesKey identifies a series by its metric and labels, regardless of the order of the labels.
+func seriesKey(metric string, labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return metric + "{" + strings.Join(pairs, ",") + "}"
+}
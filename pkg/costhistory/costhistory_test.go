@@ -0,0 +1,168 @@
+package costhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func gauge(name string, value float64, labels ...string) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name:   proto.String(name),
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{{Label: labelPairs(labels...), Gauge: &dto.Gauge{Value: proto.Float64(value)}}},
+	}
+}
+
+func counter(name string, value float64, labels ...string) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name:   proto.String(name),
+		Type:   dto.MetricType_COUNTER.Enum(),
+		Metric: []*dto.Metric{{Label: labelPairs(labels...), Counter: &dto.Counter{Value: proto.Float64(value)}}},
+	}
+}
+
+func labelPairs(labels ...string) []*dto.LabelPair {
+	var pairs []*dto.LabelPair
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, &dto.LabelPair{Name: proto.String(labels[i]), Value: proto.String(labels[i+1])})
+	}
+	return pairs
+}
+
+// newHistory returns a History sampled every hour whose clock is read from now.
+func newHistory(t *testing.T, days int, now *time.Time) *History {
+	t.Helper()
+	h, err := New(Config{File: filepath.Join(t.TempDir(), "history.json"), Days: days, Interval: time.Hour, Prefix: "cloudcost_aws_"})
+	require.NoError(t, err)
+	h.now = func() time.Time { return *now }
+	return h
+}
+
+func TestNew_disabled(t *testing.T) {
+	h, err := New(Config{})
+	require.NoError(t, err)
+	assert.Nil(t, h)
+	h.Record(nil)
+	require.NoError(t, h.Save())
+	assert.Nil(t, h.Days("", ""))
+}
+
+func TestHistory_Record(t *testing.T) {
+	now := time.Date(2024, 7, 1, 22, 0, 0, 0, time.UTC)
+	h := newHistory(t, 7, &now)
+	sample := func(volume float64, instance float64) []*dto.MetricFamily {
+		return []*dto.MetricFamily{
+			gauge("cloudcost_aws_rds_instance_usd_per_hour", volume, "instance", "db-1"),
+			counter("cloudcost_aws_eks_usd_total", instance, "instance", "node-1"),
+			// Prices aren't costs of a resource
+			gauge("cloudcost_aws_eks_instance_cpu_usd_per_core_hour", 0.5, "instance", "node-1"),
+			// Other modules are left out
+			gauge("cloudcost_gcp_observability_project_usd_per_hour", 1, "project", "p"),
+		}
+	}
+
+	// The first sample is the baseline
+	h.Record(sample(2, 10))
+	now = now.Add(time.Hour)
+	h.Record(sample(2, 11))
+	// The sample after midnight is accounted to the new day, the counter was reset in between
+	now = now.Add(90 * time.Minute)
+	h.Record(sample(4, 0.5))
+	// Gaps longer than twice the interval aren't accounted for
+	now = now.Add(3 * time.Hour)
+	h.Record(sample(4, 100))
+
+	assert.Equal(t, []Day{
+		{Date: "2024-07-01", Hours: 1, Costs: []Cost{
+			{Metric: "cloudcost_aws_eks_usd_total", Labels: map[string]string{"instance": "node-1"}, USD: 1},
+			{Metric: "cloudcost_aws_rds_instance_usd_per_hour", Labels: map[string]string{"instance": "db-1"}, USD: 2},
+		}},
+		{Date: "2024-07-02", Hours: 1.5, Costs: []Cost{
+			{Metric: "cloudcost_aws_eks_usd_total", Labels: map[string]string{"instance": "node-1"}, USD: 0.5},
+			{Metric: "cloudcost_aws_rds_instance_usd_per_hour", Labels: map[string]string{"instance": "db-1"}, USD: 6},
+		}},
+	}, h.Days("", ""))
+
+	// The days older than the retention are dropped
+	now = now.AddDate(0, 0, 6)
+	h.Record(nil)
+	days := h.Days("", "")
+	require.Len(t, days, 2)
+	assert.Equal(t, "2024-07-02", days[0].Date)
+}
+
+func TestHistory_Save(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	h := newHistory(t, 7, &now)
+	h.Record([]*dto.MetricFamily{gauge("cloudcost_aws_s3_bucket_usd_per_hour", 1, "bucket_name", "logs")})
+	now = now.Add(30 * time.Minute)
+	h.Record([]*dto.MetricFamily{gauge("cloudcost_aws_s3_bucket_usd_per_hour", 1, "bucket_name", "logs")})
+	require.NoError(t, h.Save())
+
+	loaded, err := New(h.cfg)
+	require.NoError(t, err)
+	assert.Equal(t, h.Days("", ""), loaded.Days("", ""))
+	assert.Equal(t, 0.5, loaded.Days("", "")[0].Costs[0].USD)
+}
+
+func TestHistory_Series(t *testing.T) {
+	h := newHistory(t, 7, new(time.Time))
+	h.days = map[string]*day{
+		"2024-07-01": {hours: 24, costs: map[string]*Cost{
+			"a": {Metric: "cloudcost_aws_eks_usd_total", Labels: map[string]string{"instance": "node-1", "cluster_name": "prod"}, USD: 1},
+			"b": {Metric: "cloudcost_aws_eks_usd_total", Labels: map[string]string{"instance": "node-2", "cluster_name": "prod"}, USD: 2},
+			"c": {Metric: "cloudcost_aws_rds_instance_usd_per_hour", Labels: map[string]string{"instance": "db-1"}, USD: 4},
+		}},
+		"2024-07-08": {hours: 12, costs: map[string]*Cost{
+			"a": {Metric: "cloudcost_aws_eks_usd_total", Labels: map[string]string{"instance": "node-1", "cluster_name": "prod"}, USD: 0.5},
+		}},
+	}
+
+	days, series := h.Series(Query{Metric: "cloudcost_aws_eks_usd_total", By: []string{"cluster_name"}})
+	assert.Equal(t, []Day{{Date: "2024-07-01", Hours: 24}, {Date: "2024-07-08", Hours: 12}}, days)
+	assert.Equal(t, []Series{{
+		Metric: "cloudcost_aws_eks_usd_total",
+		Labels: map[string]string{"cluster_name": "prod"},
+		Points: []Point{{Date: "2024-07-01", USD: 3}, {Date: "2024-07-08", USD: 0.5}},
+	}}, series)
+
+	days, series = h.Series(Query{From: "2024-07-02"})
+	assert.Equal(t, []Day{{Date: "2024-07-08", Hours: 12}}, days)
+	require.Len(t, series, 1)
+	assert.Equal(t, map[string]string{"instance": "node-1", "cluster_name": "prod"}, series[0].Labels)
+}
+
+func TestHistory_Handler(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	h := newHistory(t, 7, &now)
+	h.Record([]*dto.MetricFamily{counter("cloudcost_aws_eks_usd_total", 1, "instance", "node-1")})
+	now = now.Add(time.Hour)
+	h.Record([]*dto.MetricFamily{counter("cloudcost_aws_eks_usd_total", 3, "instance", "node-1")})
+
+	recorder := httptest.NewRecorder()
+	h.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path+"?from=2024-07-01&to=2024-07-01&by=instance", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var got response
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&got))
+	assert.Equal(t, response{
+		Days: []Day{{Date: "2024-07-01", Hours: 1}},
+		Series: []Series{{
+			Metric: "cloudcost_aws_eks_usd_total",
+			Labels: map[string]string{"instance": "node-1"},
+			Points: []Point{{Date: "2024-07-01", USD: 2}},
+		}},
+	}, got)
+
+	recorder = httptest.NewRecorder()
+	h.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path+"?from=last-week", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,387 @@
+// Package custom exports the costs of the resources the cloud providers don't know about, eg on-prem VMware hosts or
+// bare-metal servers, from a price and inventory feed provided by the user, so that they can be looked at along with
+// the costs of the clouds in the same exporter.
+package custom
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	// ProviderName is the provider label of the metrics of the custom feed, eg of cloudcost_exporter_collector_up.
+	ProviderName  = "custom"
+	collectorName = "feed"
+
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+
+	// DefaultRefreshInterval is how often the feed is read again when Config.RefreshInterval isn't set.
+	DefaultRefreshInterval = time.Hour
+)
+
+var (
+	ErrInvalidEntry  = errors.New("invalid feed entry")
+	ErrUnknownFormat = errors.New("unknown feed format")
+)
+
+var (
+	ResourceHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, ProviderName, "resource_usd_per_hour"),
+		"The hourly cost of a resource of the custom price feed in USD/h, either its own price or the price of its sku.",
+		[]string{"resource", "kind", "region", "sku", "cluster_name", "project"},
+		nil,
+	)
+	ResourceCostTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, ProviderName, "usd_total"),
+		"The cost of a resource of the custom price feed in USD accumulated by the exporter since it first saw the resource.",
+		[]string{"resource", "kind", "region", "sku", "cluster_name", "project"},
+		nil,
+	)
+	SKUHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, ProviderName, "sku_usd_per_hour"),
+		"The hourly price of a sku of the custom price feed in USD/h. region is empty for the prices of every region.",
+		[]string{"sku", "region"},
+		nil,
+	)
+)
+
+// Price is the hourly price of a sku, eg a VMware host model, in a region. An empty Region or * prices the sku in
+// every region, a price of the region of a resource wins over it.
+type Price struct {
+	SKU        string  `json:"sku"`
+	Region     string  `json:"region"`
+	USDPerHour float64 `json:"usd_per_hour"`
+}
+
+// Resource is a resource of the inventory. It's priced by USDPerHour when it's set, and by the price of its SKU
+// otherwise. Project is the scope of the resource, which maps it to a tenant like the project of a GCP resource.
+type Resource struct {
+	Resource    string   `json:"resource"`
+	Kind        string   `json:"kind"`
+	Region      string   `json:"region"`
+	SKU         string   `json:"sku"`
+	ClusterName string   `json:"cluster_name"`
+	Project     string   `json:"project"`
+	USDPerHour  *float64 `json:"usd_per_hour"`
+}
+
+// Feed is the content of a feed. In the CSV format, every row with a resource is a resource and every other row is
+// the price of its sku.
+type Feed struct {
+	Prices    []Price    `json:"prices"`
+	Resources []Resource `json:"resources"`
+}
+
+// price returns the hourly cost of a resource.
+func (f *Feed) price(r Resource) (float64, bool) {
+	if r.USDPerHour != nil {
+		return *r.USDPerHour, true
+	}
+	var price float64
+	found := false
+	for _, p := range f.Prices {
+		if p.SKU != r.SKU {
+			continue
+		}
+		if p.Region == r.Region {
+			return p.USDPerHour, true
+		}
+		if (p.Region == "" || p.Region == "*") && !found {
+			price, found = p.USDPerHour, true
+		}
+	}
+	return price, found
+}
+
+func (f *Feed) validate() error {
+	for i, p := range f.Prices {
+		if p.SKU == "" {
+			return fmt.Errorf("%w: price %d must have a sku", ErrInvalidEntry, i)
+		}
+		if p.USDPerHour < 0 {
+			return fmt.Errorf("%w: price %d has a negative price", ErrInvalidEntry, i)
+		}
+	}
+	for i, r := range f.Resources {
+		if r.Resource == "" {
+			return fmt.Errorf("%w: resource %d must have a name", ErrInvalidEntry, i)
+		}
+		if r.USDPerHour != nil && *r.USDPerHour < 0 {
+			return fmt.Errorf("%w: resource %s has a negative price", ErrInvalidEntry, r.Resource)
+		}
+		if _, ok := f.price(r); !ok {
+			return fmt.Errorf("%w: resource %s has neither a price nor a priced sku", ErrInvalidEntry, r.Resource)
+		}
+	}
+	return nil
+}
+
+// Parse reads a feed in format, FormatCSV or FormatJSON.
+func Parse(r io.Reader, format string) (*Feed, error) {
+	var feed *Feed
+	var err error
+	switch format {
+	case FormatJSON:
+		feed = &Feed{}
+		err = json.NewDecoder(r).Decode(feed)
+	case FormatCSV:
+		feed, err = parseCSV(r)
+	default:
+		return nil, fmt.Errorf("%w %q, expected %s or %s", ErrUnknownFormat, format, FormatCSV, FormatJSON)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := feed.validate(); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// parseCSV reads a CSV feed whose first row is the header. The columns are matched by name regardless of casing and
+// their order, the unknown ones are ignored.
+func parseCSV(r io.Reader) (*Feed, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	feed := &Feed{}
+	if len(rows) == 0 {
+		return feed, nil
+	}
+	columns := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for line, row := range rows[1:] {
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		var usdPerHour *float64
+		if value := get("usd_per_hour"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				// The header is the first line and lines are numbered from 1
+				return nil, fmt.Errorf("%w: line %d has an invalid usd_per_hour %q", ErrInvalidEntry, line+2, value)
+			}
+			usdPerHour = &parsed
+		}
+		if get("resource") == "" {
+			if usdPerHour == nil {
+				return nil, fmt.Errorf("%w: line %d has neither a resource nor a price", ErrInvalidEntry, line+2)
+			}
+			feed.Prices = append(feed.Prices, Price{SKU: get("sku"), Region: get("region"), USDPerHour: *usdPerHour})
+			continue
+		}
+		feed.Resources = append(feed.Resources, Resource{
+			Resource:    get("resource"),
+			Kind:        get("kind"),
+			Region:      get("region"),
+			SKU:         get("sku"),
+			ClusterName: get("cluster_name"),
+			Project:     get("project"),
+			USDPerHour:  usdPerHour,
+		})
+	}
+	return feed, nil
+}
+
+// Config configures the Collector.
+type Config struct {
+	// Source is the path or the http(s) URL of the feed.
+	Source string
+	// Format is FormatCSV or FormatJSON, it defaults to the extension of Source.
+	Format string
+	// RefreshInterval is how often the feed is read again.
+	RefreshInterval time.Duration
+	// Timeout bounds the request of a feed fetched from a URL.
+	Timeout      time.Duration
+	HTTPClient   *http.Client
+	ClusterNames *clustername.Normalizer
+	Logger       *slog.Logger
+}
+
+// Collector exports the costs of the resources of a feed. It satisfies provider.Provider, so that it can run along
+// with a cloud provider, see provider.Providers. The feed is read again every refresh interval, a feed that can't be
+// read or has invalid entries keeps the costs of the previous one and reports the collector as down.
+type Collector struct {
+	cfg   Config
+	now   func() time.Time
+	costs *utils.CostCounter
+
+	m           sync.Mutex
+	feed        *Feed
+	refreshedAt time.Time
+	err         error
+}
+
+// New returns a Collector of the feed of cfg. It fails when the feed can't be read or has invalid entries, so that a
+// mistake is caught at startup rather than when the feed is read again.
+func New(ctx context.Context, cfg Config) (*Collector, error) {
+	if cfg.Format == "" {
+		cfg.Format = formatFromExtension(cfg.Source)
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultRefreshInterval
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	c := &Collector{
+		cfg:   cfg,
+		now:   time.Now,
+		costs: utils.NewCostCounter(ResourceCostTotalDesc),
+	}
+	feed, err := c.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.feed = feed
+	c.refreshedAt = c.now()
+	return c, nil
+}
+
+// NewForDocs returns a Collector without a feed, used to document the metrics of the collector.
+func NewForDocs() *Collector {
+	return &Collector{now: time.Now, costs: utils.NewCostCounter(ResourceCostTotalDesc), feed: &Feed{}}
+}
+
+func formatFromExtension(source string) string {
+	if u, err := url.Parse(source); err == nil && u.Scheme != "" {
+		source = u.Path
+	}
+	return strings.TrimPrefix(strings.ToLower(path.Ext(source)), ".")
+}
+
+func (c *Collector) read(ctx context.Context) (*Feed, error) {
+	body, err := c.open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading custom price feed %s: %w", c.cfg.Source, err)
+	}
+	defer body.Close()
+	feed, err := Parse(body, c.cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing custom price feed %s: %w", c.cfg.Source, err)
+	}
+	return feed, nil
+}
+
+func (c *Collector) open(ctx context.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(c.cfg.Source, "http://") && !strings.HasPrefix(c.cfg.Source, "https://") {
+		return os.Open(c.cfg.Source)
+	}
+	var cancel context.CancelFunc
+	if c.cfg.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.Source, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	// The body is read once the request returned, so the context is only cancelled once it's closed
+	return cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// refresh reads the feed again when it's older than the refresh interval, and returns the current feed.
+func (c *Collector) refresh(ctx context.Context) (*Feed, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.cfg.Source == "" || c.now().Sub(c.refreshedAt) < c.cfg.RefreshInterval {
+		return c.feed, c.err
+	}
+	c.refreshedAt = c.now()
+	feed, err := c.read(ctx)
+	if err != nil {
+		c.cfg.Logger.LogAttrs(ctx, slog.LevelWarn, "Keeping the previous custom price feed", slog.String("message", err.Error()))
+		c.err = err
+		return c.feed, err
+	}
+	c.feed, c.err = feed, nil
+	return feed, nil
+}
+
+// Describe satisfies prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ResourceHourlyCostDesc
+	ch <- ResourceCostTotalDesc
+	ch <- SKUHourlyPriceDesc
+	ch <- provider.CollectorUpDesc
+}
+
+// Collect satisfies prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	feed, err := c.refresh(context.Background())
+	for _, p := range feed.Prices {
+		ch <- prometheus.MustNewConstMetric(SKUHourlyPriceDesc, prometheus.GaugeValue, p.USDPerHour, p.SKU, p.Region)
+	}
+	for _, r := range feed.Resources {
+		price, _ := feed.price(r)
+		labelValues := []string{r.Resource, r.Kind, r.Region, r.SKU, c.cfg.ClusterNames.Normalize(r.ClusterName), r.Project}
+		ch <- prometheus.MustNewConstMetric(ResourceHourlyCostDesc, prometheus.GaugeValue, price, labelValues...)
+		c.costs.Observe(price, labelValues...)
+	}
+	c.costs.Emit(ch)
+	ch <- provider.NewCollectorUpMetric(ProviderName, collectorName, err)
+}
+
+// RegisterCollectors satisfies provider.Provider, the feed has no collector of its own.
+func (c *Collector) RegisterCollectors(provider.Registry) error {
+	return nil
+}
+
+// Close satisfies provider.Provider.
+func (c *Collector) Close(context.Context) error {
+	return nil
+}
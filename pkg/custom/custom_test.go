@@ -0,0 +1,165 @@
+package custom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+)
+
+func usd(price float64) *float64 {
+	return &price
+}
+
+func TestParse(t *testing.T) {
+	want := &Feed{
+		Prices: []Price{
+			{SKU: "esx-large", USDPerHour: 1.5},
+			{SKU: "esx-large", Region: "ams", USDPerHour: 1.2},
+		},
+		Resources: []Resource{
+			{Resource: "esx-01", Kind: "host", Region: "ams", SKU: "esx-large", ClusterName: "vSphere-Prod", Project: "payments"},
+			{Resource: "nas-01", Kind: "storage", Region: "fra", USDPerHour: usd(0.3)},
+		},
+	}
+	tests := map[string]struct {
+		format  string
+		feed    string
+		want    *Feed
+		wantErr error
+	}{
+		"csv": {
+			format: FormatCSV,
+			feed: `Resource,kind,region,sku,cluster_name,project,usd_per_hour,owner
+,,,esx-large,,,1.5,
+,,ams,esx-large,,,1.2,
+esx-01,host,ams,esx-large,vSphere-Prod,payments,,team-a
+nas-01,storage,fra,,,,0.3,team-b
+`,
+			want: want,
+		},
+		"json": {
+			format: FormatJSON,
+			feed: `{
+  "prices": [{"sku": "esx-large", "usd_per_hour": 1.5}, {"sku": "esx-large", "region": "ams", "usd_per_hour": 1.2}],
+  "resources": [
+    {"resource": "esx-01", "kind": "host", "region": "ams", "sku": "esx-large", "cluster_name": "vSphere-Prod", "project": "payments"},
+    {"resource": "nas-01", "kind": "storage", "region": "fra", "usd_per_hour": 0.3}
+  ]
+}`,
+			want: want,
+		},
+		"invalid price": {
+			format:  FormatCSV,
+			feed:    "resource,usd_per_hour\nesx-01,free\n",
+			wantErr: ErrInvalidEntry,
+		},
+		"sku without a price": {
+			format:  FormatCSV,
+			feed:    "resource,sku\nesx-01,esx-large\n",
+			wantErr: ErrInvalidEntry,
+		},
+		"negative price": {
+			format:  FormatJSON,
+			feed:    `{"prices": [{"sku": "esx-large", "usd_per_hour": -1}]}`,
+			wantErr: ErrInvalidEntry,
+		},
+		"unknown format": {
+			format:  "yaml",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.feed), tt.format)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFeed_price(t *testing.T) {
+	feed := &Feed{Prices: []Price{
+		{SKU: "esx-large", Region: "*", USDPerHour: 1.5},
+		{SKU: "esx-large", Region: "ams", USDPerHour: 1.2},
+	}}
+	price, ok := feed.price(Resource{SKU: "esx-large", Region: "ams"})
+	assert.True(t, ok)
+	assert.Equal(t, 1.2, price)
+	price, ok = feed.price(Resource{SKU: "esx-large", Region: "fra"})
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, price)
+	price, ok = feed.price(Resource{SKU: "esx-large", USDPerHour: usd(2)})
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, price)
+	_, ok = feed.price(Resource{SKU: "esx-small"})
+	assert.False(t, ok)
+}
+
+func TestCollector_file(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "feed.csv")
+	require.NoError(t, os.WriteFile(source, []byte("resource,kind,region,cluster_name,project,usd_per_hour\nesx-01,host,ams,vSphere-Prod,payments,1.2\n"), 0o644))
+	c, err := New(context.Background(), Config{
+		Source:       source,
+		ClusterNames: clustername.NewNormalizer(true, nil),
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP cloudcost_custom_resource_usd_per_hour The hourly cost of a resource of the custom price feed in USD/h, either its own price or the price of its sku.
+# TYPE cloudcost_custom_resource_usd_per_hour gauge
+cloudcost_custom_resource_usd_per_hour{cluster_name="vsphere-prod",kind="host",project="payments",region="ams",resource="esx-01",sku=""} 1.2
+# HELP cloudcost_exporter_collector_up Was the last scrape of the collector successful. 1 indicates success.
+# TYPE cloudcost_exporter_collector_up gauge
+cloudcost_exporter_collector_up{collector="feed",provider="custom"} 1
+`), "cloudcost_custom_resource_usd_per_hour", "cloudcost_exporter_collector_up"))
+
+	// A broken feed keeps the costs of the previous one
+	require.NoError(t, os.WriteFile(source, []byte("resource,usd_per_hour\nesx-01,free\n"), 0o644))
+	now = now.Add(DefaultRefreshInterval)
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP cloudcost_custom_resource_usd_per_hour The hourly cost of a resource of the custom price feed in USD/h, either its own price or the price of its sku.
+# TYPE cloudcost_custom_resource_usd_per_hour gauge
+cloudcost_custom_resource_usd_per_hour{cluster_name="vsphere-prod",kind="host",project="payments",region="ams",resource="esx-01",sku=""} 1.2
+# HELP cloudcost_exporter_collector_up Was the last scrape of the collector successful. 1 indicates success.
+# TYPE cloudcost_exporter_collector_up gauge
+cloudcost_exporter_collector_up{collector="feed",provider="custom"} 0
+`), "cloudcost_custom_resource_usd_per_hour", "cloudcost_exporter_collector_up"))
+}
+
+func TestCollector_url(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"prices": [{"sku": "bm-gpu", "usd_per_hour": 4}], "resources": [{"resource": "gpu-01", "sku": "bm-gpu"}]}`))
+	}))
+	defer server.Close()
+
+	c, err := New(context.Background(), Config{Source: server.URL + "/feed.json?token=abc", Timeout: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP cloudcost_custom_sku_usd_per_hour The hourly price of a sku of the custom price feed in USD/h. region is empty for the prices of every region.
+# TYPE cloudcost_custom_sku_usd_per_hour gauge
+cloudcost_custom_sku_usd_per_hour{region="",sku="bm-gpu"} 4
+`), "cloudcost_custom_sku_usd_per_hour"))
+
+	status = http.StatusForbidden
+	_, err = New(context.Background(), Config{Source: server.URL + "/feed.json"})
+	require.ErrorContains(t, err, "403")
+}
@@ -0,0 +1,155 @@
+// Package deprecation keeps exporting the metrics that were renamed under their previous name for a deprecation
+// window, so that the dashboards and alerts built on the previous name keep working while they're migrated.
+package deprecation
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Label flags the series exported under the previous name of a renamed metric.
+	Label = "deprecated"
+	// LabelValue is the value of Label of the series exported under a previous name.
+	LabelValue = "true"
+	// DefaultWindow is how long a previous name is exported after its rename when the window isn't set.
+	DefaultWindow = 90 * 24 * time.Hour
+)
+
+// Rename is a metric renamed from From to To in the release of Since, which starts its deprecation window.
+type Rename struct {
+	From  string
+	To    string
+	Since time.Time
+}
+
+// Until returns when the previous name of the metric stops being exported.
+func (r Rename) Until(window time.Duration) time.Time {
+	return r.Since.Add(window)
+}
+
+// Renames are the metrics renamed by the exporter. Add an entry along with the rename of a metric, dated with the day
+// of its release, and drop it once its deprecation window is over.
+var Renames []Rename
+
+// Aliases exports the metrics of the renames within their window under their previous name as well, labelled with
+// deprecated="true". The series are copied from the metric under its new name, so a metric renamed twice is exported
+// under both of its previous names.
+type Aliases struct {
+	window time.Duration
+	now    func() time.Time
+	// renames are the renames keyed by the new name of their metric.
+	renames map[string][]Rename
+}
+
+// New returns the Aliases of renames exported for window after their rename. It returns nil when window is 0 or there
+// are no renames, which exports the metrics under their new name only.
+func New(renames []Rename, window time.Duration) *Aliases {
+	if window <= 0 || len(renames) == 0 {
+		return nil
+	}
+	a := &Aliases{
+		window:  window,
+		now:     time.Now,
+		renames: make(map[string][]Rename, len(renames)),
+	}
+	for _, r := range renames {
+		a.renames[r.To] = append(a.renames[r.To], r)
+	}
+	return a
+}
+
+// Warn logs a warning for every rename whose previous name is still exported, so that the users of the previous names
+// know to migrate before the end of the window.
+func (a *Aliases) Warn(ctx context.Context, logger *slog.Logger) {
+	if a == nil {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	now := a.now()
+	var active []Rename
+	for _, renames := range a.renames {
+		for _, r := range renames {
+			if now.Before(r.Until(a.window)) {
+				active = append(active, r)
+			}
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].From < active[j].From })
+	for _, r := range active {
+		logger.LogAttrs(ctx, slog.LevelWarn, "Metric renamed, its previous name is exported with deprecated=\"true\" until the end of its deprecation window",
+			slog.String("from", r.From),
+			slog.String("to", r.To),
+			slog.String("until", r.Until(a.window).Format(time.DateOnly)),
+		)
+	}
+}
+
+// Gatherer returns a Gatherer adding the families of g renamed within the window under their previous name. g is
+// returned unchanged when a is nil.
+func (a *Aliases) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	if a == nil {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		return a.apply(families), err
+	})
+}
+
+// apply appends a copy of every family renamed within the window under its previous name. A previous name that is
+// still gathered, eg because a collector wasn't migrated yet, isn't copied over.
+func (a *Aliases) apply(families []*dto.MetricFamily) []*dto.MetricFamily {
+	now := a.now()
+	byName := make(map[string]bool, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = true
+	}
+	// The copies are renamed again in turn, for the metrics renamed more than once
+	for i := 0; i < len(families); i++ {
+		family := families[i]
+		for _, r := range a.renames[family.GetName()] {
+			if byName[r.From] || !now.Before(r.Until(a.window)) {
+				continue
+			}
+			byName[r.From] = true
+			families = append(families, alias(family, r))
+		}
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families
+}
+
+// alias returns a copy of family under the previous name of r, its metrics labelled with deprecated="true" and their
+// labels kept sorted by name as the registry does.
+func alias(family *dto.MetricFamily, r Rename) *dto.MetricFamily {
+	copied := proto.Clone(family).(*dto.MetricFamily)
+	copied.Name = proto.String(r.From)
+	copied.Help = proto.String(strings.TrimSpace("Deprecated, renamed to " + r.To + ". " + family.GetHelp()))
+	for _, m := range copied.Metric {
+		if hasLabel(m) {
+			continue
+		}
+		m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(Label), Value: proto.String(LabelValue)})
+		sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+	}
+	return copied
+}
+
+func hasLabel(m *dto.Metric) bool {
+	for _, pair := range m.GetLabel() {
+		if pair.GetName() == Label {
+			return true
+		}
+	}
+	return false
+}
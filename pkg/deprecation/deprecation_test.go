@@ -0,0 +1,115 @@
+package deprecation
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenames(t *testing.T) {
+	for _, r := range Renames {
+		assert.NotEmpty(t, r.From)
+		assert.NotEmpty(t, r.To)
+		assert.NotEqual(t, r.From, r.To)
+		assert.False(t, r.Since.IsZero(), "rename of %s has no date", r.From)
+	}
+}
+
+func TestNew(t *testing.T) {
+	renames := []Rename{{From: "test_cost", To: "test_usd_per_hour", Since: time.Now()}}
+	assert.Nil(t, New(renames, 0))
+	assert.Nil(t, New(nil, DefaultWindow))
+	registry := prometheus.NewRegistry()
+	assert.Equal(t, prometheus.Gatherer(registry), New(nil, DefaultWindow).Gatherer(registry))
+}
+
+func TestAliases_Gatherer(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	now := since
+	aliases := New([]Rename{
+		{From: "test_instance_cost", To: "test_instance_usd_per_hour", Since: since},
+		// test_instance_cost was renamed from test_cost before
+		{From: "test_cost", To: "test_instance_cost", Since: since.Add(-24 * time.Hour)},
+		// test_volume_cost is still exported by a collector
+		{From: "test_volume_cost", To: "test_volume_usd_per_hour", Since: since},
+	}, 10*24*time.Hour)
+	aliases.now = func() time.Time { return now }
+
+	registry := prometheus.NewRegistry()
+	instanceCost := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_instance_usd_per_hour", Help: "Cost of an instance."}, []string{"instance"})
+	volumeCost := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_volume_usd_per_hour", Help: "Cost of a volume."}, []string{"volume"})
+	oldVolumeCost := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_volume_cost", Help: "Cost of a volume."}, []string{"volume"})
+	registry.MustRegister(instanceCost, volumeCost, oldVolumeCost)
+	instanceCost.WithLabelValues("a").Set(1)
+	volumeCost.WithLabelValues("v").Set(2)
+	oldVolumeCost.WithLabelValues("v").Set(2)
+	gatherer := aliases.Gatherer(registry)
+
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_cost Deprecated, renamed to test_instance_cost. Deprecated, renamed to test_instance_usd_per_hour. Cost of an instance.
+# TYPE test_cost gauge
+test_cost{deprecated="true",instance="a"} 1
+# HELP test_instance_cost Deprecated, renamed to test_instance_usd_per_hour. Cost of an instance.
+# TYPE test_instance_cost gauge
+test_instance_cost{deprecated="true",instance="a"} 1
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 1
+# HELP test_volume_cost Cost of a volume.
+# TYPE test_volume_cost gauge
+test_volume_cost{volume="v"} 2
+# HELP test_volume_usd_per_hour Cost of a volume.
+# TYPE test_volume_usd_per_hour gauge
+test_volume_usd_per_hour{volume="v"} 2
+`)))
+
+	// The window of test_cost is over
+	now = since.Add(9*24*time.Hour + time.Second)
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_instance_cost Deprecated, renamed to test_instance_usd_per_hour. Cost of an instance.
+# TYPE test_instance_cost gauge
+test_instance_cost{deprecated="true",instance="a"} 1
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 1
+`), "test_cost", "test_instance_cost", "test_instance_usd_per_hour"))
+
+	now = since.Add(10 * 24 * time.Hour)
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 1
+`), "test_cost", "test_instance_cost", "test_instance_usd_per_hour"))
+}
+
+func TestAliases_Warn(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	aliases := New([]Rename{
+		{From: "test_instance_cost", To: "test_instance_usd_per_hour", Since: since},
+		{From: "test_volume_cost", To: "test_volume_usd_per_hour", Since: since.Add(-30 * 24 * time.Hour)},
+	}, 20*24*time.Hour)
+	aliases.now = func() time.Time { return since }
+
+	var buf bytes.Buffer
+	aliases.Warn(context.Background(), slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	assert.Equal(t, `level=WARN msg="Metric renamed, its previous name is exported with deprecated=\"true\" until the end of its deprecation window" from=test_instance_cost to=test_instance_usd_per_hour until=2026-10-21`+"\n", buf.String())
+
+	// A nil Aliases has nothing to warn about
+	var none *Aliases
+	none.Warn(context.Background(), nil)
+}
@@ -0,0 +1,60 @@
+package egress
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// regionPlaceholder is replaced by the region of a client in endpoint overrides, as private endpoints are regional.
+const regionPlaceholder = "{region}"
+
+// Config configures how the cloud SDK clients reach the provider APIs.
+type Config struct {
+	// ProxyURL is the proxy every request to a provider API is sent through. The SDKs fall back to the HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY environment variables when it's empty.
+	ProxyURL string
+	// NoProxy is a comma separated list of hosts, domains and CIDRs that bypass ProxyURL, in the format of NO_PROXY.
+	NoProxy string
+}
+
+// NewHTTPClient returns the client the cloud SDKs should send their requests with. It returns nil when no proxy is
+// configured, in which case the SDKs keep their default clients.
+func NewHTTPClient(cfg Config) (*http.Client, error) {
+	if cfg.ProxyURL == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(cfg.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy url %q: scheme must be http, https or socks5", cfg.ProxyURL)
+	}
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  cfg.ProxyURL,
+		HTTPSProxy: cfg.ProxyURL,
+		NoProxy:    cfg.NoProxy,
+	}).ProxyFunc()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// Endpoints overrides the endpoints of the provider APIs, eg to reach them through private endpoints. It's keyed by
+// the lowercased name of the service.
+type Endpoints map[string]string
+
+// For returns the endpoint override of a service, with {region} replaced by region, or an empty string when the
+// service isn't overridden.
+func (e Endpoints) For(service string, region string) string {
+	endpoint := e[strings.ToLower(service)]
+	return strings.ReplaceAll(endpoint, regionPlaceholder, region)
+}
@@ -0,0 +1,80 @@
+package egress
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	tests := map[string]struct {
+		config  Config
+		nilHTTP bool
+		wantErr bool
+		proxied map[string]string
+	}{
+		"no proxy keeps the sdk defaults": {
+			config:  Config{},
+			nilHTTP: true,
+		},
+		"invalid scheme": {
+			config:  Config{ProxyURL: "ftp://proxy:3128"},
+			wantErr: true,
+		},
+		"invalid url": {
+			config:  Config{ProxyURL: "http://proxy:port"},
+			wantErr: true,
+		},
+		"proxy with exclusions": {
+			config: Config{ProxyURL: "http://proxy:3128", NoProxy: ".internal,10.0.0.0/8"},
+			proxied: map[string]string{
+				"https://ec2.us-east-1.amazonaws.com/": "http://proxy:3128",
+				"https://pricing.internal/":            "",
+				"https://10.1.2.3/":                    "",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewHTTPClient(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.nilHTTP {
+				assert.Nil(t, client)
+				return
+			}
+			transport, ok := client.Transport.(*http.Transport)
+			require.True(t, ok)
+			for target, want := range tt.proxied {
+				req, err := http.NewRequest(http.MethodGet, target, nil)
+				require.NoError(t, err)
+				got, err := transport.Proxy(req)
+				require.NoError(t, err)
+				if want == "" {
+					assert.Nil(t, got, target)
+					continue
+				}
+				require.NotNil(t, got, target)
+				assert.Equal(t, want, got.String())
+			}
+		})
+	}
+}
+
+func TestEndpoints_For(t *testing.T) {
+	endpoints := Endpoints{
+		"pricing": "https://vpce-1.api.pricing.us-east-1.vpce.amazonaws.com",
+		"ec2":     "https://ec2.{region}.example.internal",
+	}
+	assert.Equal(t, "https://vpce-1.api.pricing.us-east-1.vpce.amazonaws.com", endpoints.For("pricing", "eu-west-1"))
+	assert.Equal(t, "https://ec2.eu-west-1.example.internal", endpoints.For("EC2", "eu-west-1"))
+	assert.Equal(t, "", endpoints.For("eks", "eu-west-1"))
+
+	var none Endpoints
+	assert.Equal(t, "", none.For("ec2", "eu-west-1"))
+}
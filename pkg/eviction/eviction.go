@@ -0,0 +1,125 @@
+// Package eviction counts the evictions of the spot instances of the clusters and estimates what they cost on top of
+// the price of the instances: the replacement node is billed while it's provisioned and joins the cluster, before it
+// can run the evicted workloads. It quantifies the hidden cost of an aggressive use of spot instances.
+package eviction
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+// seenExpiry is how long an evicted instance is remembered so that it's only counted once. AWS keeps listing
+// terminated instances for about an hour.
+const seenExpiry = 6 * time.Hour
+
+var (
+	// SpotEvictionsTotalDesc counts the spot instances of a cluster that were evicted by the cloud provider.
+	SpotEvictionsTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.ExporterName, "spot", "evictions_total"),
+		"Total number of spot instances of a cluster evicted by the cloud provider since the exporter started.",
+		[]string{"provider", "cluster_name"},
+		nil,
+	)
+	// SpotEvictionChurnCostDesc is the estimated cost of provisioning the replacements of the evicted instances.
+	SpotEvictionChurnCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.ExporterName, "spot", "eviction_churn_cost_usd_total"),
+		"Estimated cost in USD of replacing the evicted spot instances of a cluster since the exporter started, the re-provisioning overhead times the hourly price of the evicted instances.",
+		[]string{"provider", "cluster_name"},
+		nil,
+	)
+)
+
+// Watcher counts the evicted spot instances of every cluster and accumulates their churn cost. It's safe for
+// concurrent use.
+type Watcher struct {
+	// overhead is how long a replacement node is billed before it can run the workloads of the evicted one.
+	overhead time.Duration
+	now      func() time.Time
+
+	m        sync.Mutex
+	seen     map[string]time.Time
+	clusters map[clusterKey]*evictions
+}
+
+type clusterKey struct {
+	provider    string
+	clusterName string
+}
+
+type evictions struct {
+	count float64
+	cost  float64
+}
+
+// New returns a Watcher estimating the churn cost of an eviction as overhead times the hourly price of the evicted
+// instance. nil is returned when overhead is 0, which disables the eviction metrics.
+func New(overhead time.Duration) *Watcher {
+	if overhead <= 0 {
+		return nil
+	}
+	return &Watcher{
+		overhead: overhead,
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+		clusters: make(map[clusterKey]*evictions),
+	}
+}
+
+// Observe records the eviction of a spot instance of a cluster, priced usdPerHour. An instance is only counted once
+// however many scrapes list it. It's a no-op when w is nil.
+func (w *Watcher) Observe(provider string, clusterName string, instanceID string, usdPerHour float64) {
+	if w == nil {
+		return
+	}
+	w.m.Lock()
+	defer w.m.Unlock()
+	now := w.now()
+	for id, seenAt := range w.seen {
+		if now.Sub(seenAt) > seenExpiry {
+			delete(w.seen, id)
+		}
+	}
+	id := provider + "/" + instanceID
+	if _, ok := w.seen[id]; ok {
+		return
+	}
+	w.seen[id] = now
+	key := clusterKey{provider: provider, clusterName: clusterName}
+	e, ok := w.clusters[key]
+	if !ok {
+		e = &evictions{}
+		w.clusters[key] = e
+	}
+	e.count++
+	e.cost += usdPerHour * w.overhead.Hours()
+}
+
+// Metrics returns the eviction counters of every cluster of provider that had an eviction since the exporter started.
+func (w *Watcher) Metrics(provider string) []prometheus.Metric {
+	if w == nil {
+		return nil
+	}
+	w.m.Lock()
+	defer w.m.Unlock()
+	var keys []clusterKey
+	for key := range w.clusters {
+		if key.provider == provider {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].clusterName < keys[j].clusterName })
+	metrics := make([]prometheus.Metric, 0, 2*len(keys))
+	for _, key := range keys {
+		e := w.clusters[key]
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(SpotEvictionsTotalDesc, prometheus.CounterValue, e.count, key.provider, key.clusterName),
+			prometheus.MustNewConstMetric(SpotEvictionChurnCostDesc, prometheus.CounterValue, e.cost, key.provider, key.clusterName),
+		)
+	}
+	return metrics
+}
@@ -0,0 +1,55 @@
+package eviction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestNew(t *testing.T) {
+	watcher := New(0)
+	assert.Nil(t, watcher)
+	watcher.Observe("aws", "prod", "i-1", 1)
+	assert.Nil(t, watcher.Metrics("aws"), "a disabled watcher is safe to use")
+}
+
+func TestWatcher_Metrics(t *testing.T) {
+	now := time.Now()
+	watcher := New(15 * time.Minute)
+	watcher.now = func() time.Time { return now }
+
+	watcher.Observe("aws", "prod", "i-1", 0.4)
+	// Terminated instances are listed on several scrapes, they're only counted once
+	watcher.Observe("aws", "prod", "i-1", 0.4)
+	watcher.Observe("aws", "prod", "i-2", 0.2)
+	watcher.Observe("aws", "dev", "i-3", 1)
+	watcher.Observe("gcp", "prod", "i-1", 1)
+
+	metrics := watcher.Metrics("aws")
+	assert.Len(t, metrics, 4)
+	for i, want := range []struct {
+		desc    *prometheus.Desc
+		cluster string
+		value   float64
+	}{
+		{SpotEvictionsTotalDesc, "dev", 1},
+		{SpotEvictionChurnCostDesc, "dev", 0.25},
+		{SpotEvictionsTotalDesc, "prod", 2},
+		{SpotEvictionChurnCostDesc, "prod", 0.15},
+	} {
+		assert.Equal(t, want.desc, metrics[i].Desc())
+		result := utils.ReadMetrics(metrics[i])
+		assert.Equal(t, prometheus.CounterValue, result.MetricType)
+		assert.Equal(t, utils.LabelMap{"provider": "aws", "cluster_name": want.cluster}, result.Labels)
+		assert.InDelta(t, want.value, result.Value, 1e-9)
+	}
+
+	// An instance is forgotten once it's not listed anymore
+	now = now.Add(seenExpiry + time.Minute)
+	watcher.Observe("aws", "dev", "i-4", 1)
+	assert.Len(t, watcher.seen, 1)
+}
@@ -0,0 +1,200 @@
+package google
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"golang.org/x/oauth2"
+	googleoauth "golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+)
+
+const (
+	// AuthDefault uses Application Default Credentials: GOOGLE_APPLICATION_CREDENTIALS, the gcloud credentials and
+	// the metadata server, which covers GKE Workload Identity.
+	AuthDefault = "default"
+	// AuthWorkloadIdentityFederation exchanges an external token, eg from the OIDC issuer of an EKS or AKS cluster,
+	// for Google credentials as described by a credential configuration file.
+	AuthWorkloadIdentityFederation = "workload-identity-federation"
+	// AuthVault reads a service account key from a Vault secret, eg of the KV or Google Cloud secrets engines, and
+	// rebuilds the credentials when the key is rotated.
+	AuthVault = "vault"
+
+	credentialsFileEnv  = "GOOGLE_APPLICATION_CREDENTIALS"
+	externalAccountType = "external_account"
+)
+
+var (
+	ErrUnknownAuth             = errors.New("unknown gcp auth")
+	ErrMissingCredentialsFile  = errors.New("workload identity federation requires a credential configuration file, set -gcp.credentials-file or GOOGLE_APPLICATION_CREDENTIALS")
+	ErrInvalidCredentialsFile  = errors.New("invalid credential configuration file")
+	ErrIncompatibleCredentials = errors.New("the credentials file is only used with workload identity federation, use GOOGLE_APPLICATION_CREDENTIALS for the default credentials")
+	ErrMissingVaultPath        = errors.New("vault auth requires the path of the secret, set -gcp.vault-path")
+	ErrIncompatibleVaultPath   = errors.New("the vault path is only used with vault auth")
+	ErrInvalidVaultSecret      = errors.New("vault secret must have a private_key_data or a credentials key with a service account key")
+)
+
+// AuthConfig selects how the GCP clients authenticate. Application Default Credentials fall through to the metadata
+// server when no credentials file is found, so a broken federation setup surfaces as the identity of the node
+// instead, which is why federation can be required explicitly.
+type AuthConfig struct {
+	// Mode is AuthDefault, AuthWorkloadIdentityFederation or AuthVault. An empty Mode is AuthDefault.
+	Mode string
+	// CredentialsFile is the credential configuration file generated by
+	// `gcloud iam workload-identity-pools create-cred-config`. It defaults to GOOGLE_APPLICATION_CREDENTIALS.
+	CredentialsFile string
+	// VaultPath is the path of the secret read with AuthVault, eg gcp/key/cloudcost-exporter. The secret has the
+	// base64 encoded private_key_data of the Google Cloud secrets engine, or the JSON key in credentials.
+	VaultPath string
+	// Vault is how to reach Vault with AuthVault.
+	Vault secrets.VaultConfig
+}
+
+// externalAccount is the subset of a credential configuration file that is validated.
+type externalAccount struct {
+	Type             string          `json:"type"`
+	Audience         string          `json:"audience"`
+	SubjectTokenType string          `json:"subject_token_type"`
+	CredentialSource json.RawMessage `json:"credential_source"`
+}
+
+// clientOptions validates the configuration and returns the options that authenticate every client. It returns no
+// options for the default credentials. The Vault secret is read right away so that a missing or malformed secret
+// fails at startup.
+func (a AuthConfig) clientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	if a.VaultPath != "" && a.Mode != AuthVault {
+		return nil, ErrIncompatibleVaultPath
+	}
+	switch a.Mode {
+	case "", AuthDefault:
+		if a.CredentialsFile != "" {
+			return nil, ErrIncompatibleCredentials
+		}
+		return nil, nil
+	case AuthWorkloadIdentityFederation:
+	case AuthVault:
+		if a.CredentialsFile != "" {
+			return nil, ErrIncompatibleCredentials
+		}
+		if a.VaultPath == "" {
+			return nil, ErrMissingVaultPath
+		}
+		secret, err := a.Vault.Rotating(ctx, a.VaultPath)
+		if err != nil {
+			return nil, err
+		}
+		tokenSource := &vaultTokenSource{ctx: ctx, secret: secret}
+		if _, err := tokenSource.current(); err != nil {
+			return nil, err
+		}
+		return []option.ClientOption{option.WithTokenSource(tokenSource)}, nil
+	default:
+		return nil, fmt.Errorf("%w %q, expected %s, %s or %s", ErrUnknownAuth, a.Mode, AuthDefault, AuthWorkloadIdentityFederation, AuthVault)
+	}
+	path := a.CredentialsFile
+	if path == "" {
+		path = os.Getenv(credentialsFileEnv)
+	}
+	if path == "" {
+		return nil, ErrMissingCredentialsFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentialsFile, err)
+	}
+	var account externalAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrInvalidCredentialsFile, path, err)
+	}
+	switch {
+	case account.Type != externalAccountType:
+		return nil, fmt.Errorf("%w %s: type is %q, expected %q, service account keys are used with the default auth", ErrInvalidCredentialsFile, path, account.Type, externalAccountType)
+	case account.Audience == "":
+		return nil, fmt.Errorf("%w %s: missing audience", ErrInvalidCredentialsFile, path)
+	case account.SubjectTokenType == "":
+		return nil, fmt.Errorf("%w %s: missing subject_token_type", ErrInvalidCredentialsFile, path)
+	case len(account.CredentialSource) == 0 || string(account.CredentialSource) == "null":
+		return nil, fmt.Errorf("%w %s: missing credential_source", ErrInvalidCredentialsFile, path)
+	}
+	return []option.ClientOption{option.WithCredentialsFile(path)}, nil
+}
+
+// vaultTokenSource issues the tokens of the service account key of a Vault secret. The token source of the key is
+// rebuilt when the secret is rotated, the token it already issued is used until it expires.
+type vaultTokenSource struct {
+	ctx    context.Context
+	secret *secrets.Rotating
+
+	m           sync.Mutex
+	version     uint64
+	tokenSource oauth2.TokenSource
+}
+
+// Token satisfies oauth2.TokenSource.
+func (v *vaultTokenSource) Token() (*oauth2.Token, error) {
+	tokenSource, err := v.current()
+	if err != nil {
+		return nil, err
+	}
+	return tokenSource.Token()
+}
+
+// current returns the token source of the current version of the secret.
+func (v *vaultTokenSource) current() (oauth2.TokenSource, error) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	secret, version := v.secret.Get(v.ctx)
+	if v.tokenSource != nil && version == v.version {
+		return v.tokenSource, nil
+	}
+	credentials, err := serviceAccountCredentials(v.ctx, secret)
+	if err != nil {
+		if v.tokenSource == nil {
+			return nil, err
+		}
+		// The previous key is usually still valid for a while after a rotation
+		log.Printf("keeping the previous gcp credentials: %s", err)
+		v.version = version
+		return v.tokenSource, nil
+	}
+	v.tokenSource = credentials.TokenSource
+	v.version = version
+	return v.tokenSource, nil
+}
+
+// serviceAccountCredentials returns the credentials of the service account key of secret.
+func serviceAccountCredentials(ctx context.Context, secret secrets.Secret) (*googleoauth.Credentials, error) {
+	key := []byte(secret["credentials"])
+	if data := secret["private_key_data"]; data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidVaultSecret, err)
+		}
+		key = decoded
+	}
+	if len(key) == 0 {
+		return nil, ErrInvalidVaultSecret
+	}
+	var account struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(key, &account); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVaultSecret, err)
+	}
+	if account.Type != "service_account" {
+		return nil, fmt.Errorf("%w: type is %q", ErrInvalidVaultSecret, account.Type)
+	}
+	credentials, err := googleoauth.CredentialsFromJSON(ctx, key, cloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVaultSecret, err)
+	}
+	return credentials, nil
+}
@@ -0,0 +1,157 @@
+package google
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/secrets"
+)
+
+func TestAuthConfig_clientOptions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	federation := write("federation.json", `{
+		"type": "external_account",
+		"audience": "//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/pool/providers/eks",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url": "https://sts.googleapis.com/v1/token",
+		"credential_source": {"file": "/var/run/secrets/tokens/gcp-ksa/token"}
+	}`)
+	serviceAccountKey := write("key.json", `{"type": "service_account", "project_id": "testing"}`)
+	missingSource := write("source.json", `{"type": "external_account", "audience": "//iam", "subject_token_type": "urn:ietf:params:oauth:token-type:jwt"}`)
+	malformed := write("malformed.json", `{`)
+
+	tests := map[string]struct {
+		auth     AuthConfig
+		env      string
+		wantOpts int
+		wantErr  error
+	}{
+		"empty mode is application default credentials": {
+			auth: AuthConfig{},
+		},
+		"default with a credentials file": {
+			auth:    AuthConfig{Mode: AuthDefault, CredentialsFile: federation},
+			wantErr: ErrIncompatibleCredentials,
+		},
+		"unknown mode": {
+			auth:    AuthConfig{Mode: "workload-identity"},
+			wantErr: ErrUnknownAuth,
+		},
+		"federation from the flag": {
+			auth:     AuthConfig{Mode: AuthWorkloadIdentityFederation, CredentialsFile: federation},
+			wantOpts: 1,
+		},
+		"federation from the environment": {
+			auth:     AuthConfig{Mode: AuthWorkloadIdentityFederation},
+			env:      federation,
+			wantOpts: 1,
+		},
+		"federation without a file": {
+			auth:    AuthConfig{Mode: AuthWorkloadIdentityFederation},
+			wantErr: ErrMissingCredentialsFile,
+		},
+		"federation with a service account key": {
+			auth:    AuthConfig{Mode: AuthWorkloadIdentityFederation, CredentialsFile: serviceAccountKey},
+			wantErr: ErrInvalidCredentialsFile,
+		},
+		"federation without credential source": {
+			auth:    AuthConfig{Mode: AuthWorkloadIdentityFederation, CredentialsFile: missingSource},
+			wantErr: ErrInvalidCredentialsFile,
+		},
+		"federation with a malformed file": {
+			auth:    AuthConfig{Mode: AuthWorkloadIdentityFederation, CredentialsFile: malformed},
+			wantErr: ErrInvalidCredentialsFile,
+		},
+		"vault without a path": {
+			auth:    AuthConfig{Mode: AuthVault},
+			wantErr: ErrMissingVaultPath,
+		},
+		"vault path without vault": {
+			auth:    AuthConfig{Mode: AuthWorkloadIdentityFederation, CredentialsFile: federation, VaultPath: "gcp/key/cloudcost-exporter"},
+			wantErr: ErrIncompatibleVaultPath,
+		},
+		"vault with a credentials file": {
+			auth:    AuthConfig{Mode: AuthVault, CredentialsFile: federation, VaultPath: "gcp/key/cloudcost-exporter"},
+			wantErr: ErrIncompatibleCredentials,
+		},
+		"federation with a missing file": {
+			auth:    AuthConfig{Mode: AuthWorkloadIdentityFederation, CredentialsFile: filepath.Join(dir, "missing.json")},
+			wantErr: ErrInvalidCredentialsFile,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(credentialsFileEnv, tt.env)
+			opts, err := tt.auth.clientOptions(context.Background())
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, opts, tt.wantOpts)
+		})
+	}
+}
+
+func Test_vaultTokenSource(t *testing.T) {
+	key := func(id string) string {
+		return `{"type": "service_account", "client_email": "exporter@testing.iam.gserviceaccount.com", "private_key_id": "` + id + `", "private_key": "key", "token_uri": "https://oauth2.googleapis.com/token"}`
+	}
+	body := `{"data": {"private_key_data": "` + base64.StdEncoding.EncodeToString([]byte(key("first"))) + `"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_TOKEN", "token")
+	auth := AuthConfig{
+		Mode:      AuthVault,
+		VaultPath: "gcp/key/cloudcost-exporter",
+		// Every read of the secret fetches it again
+		Vault: secrets.VaultConfig{Address: server.URL, RefreshInterval: time.Nanosecond},
+	}
+	ctx := context.Background()
+	opts, err := auth.clientOptions(ctx)
+	require.NoError(t, err)
+	require.Len(t, opts, 1)
+
+	tokenSource := &vaultTokenSource{ctx: ctx, secret: mustRotating(t, auth)}
+	first, err := tokenSource.current()
+	require.NoError(t, err)
+	same, err := tokenSource.current()
+	require.NoError(t, err)
+	assert.Same(t, first, same, "an unchanged key keeps the token source")
+
+	body = `{"data": {"data": {"credentials": ` + key("rotated") + `}, "metadata": {}}}`
+	rotated, err := tokenSource.current()
+	require.NoError(t, err)
+	assert.NotSame(t, first, rotated, "a rotated key rebuilds the token source")
+
+	body = `{"data": {"credentials": "{\"type\": \"external_account\"}"}}`
+	kept, err := tokenSource.current()
+	require.NoError(t, err)
+	assert.Same(t, rotated, kept, "an invalid key keeps the previous token source")
+
+	_, err = auth.clientOptions(ctx)
+	assert.ErrorIs(t, err, ErrInvalidVaultSecret)
+}
+
+func mustRotating(t *testing.T, auth AuthConfig) *secrets.Rotating {
+	t.Helper()
+	secret, err := auth.Vault.Rotating(context.Background(), auth.VaultPath)
+	require.NoError(t, err)
+	return secret
+}
@@ -3,7 +3,6 @@ package billing
 import (
 	"context"
 	"errors"
-	"log"
 
 	billingv1 "cloud.google.com/go/billing/apiv1"
 	"cloud.google.com/go/billing/apiv1/billingpb"
@@ -31,8 +30,10 @@ func GetServiceName(ctx context.Context, billingService *billingv1.CloudCatalogC
 	return "", ServiceNotFound
 }
 
-// GetPricing will collect all the pricing information for a given service and return a list of skus.
-func GetPricing(ctx context.Context, billingService *billingv1.CloudCatalogClient, serviceName string) []*billingpb.Sku {
+// GetPricing will collect all the pricing information for a given service and return a list of skus. When listing the
+// skus fails, eg because the Cloud Billing API throttled the exporter, the skus listed so far are returned along with
+// the error.
+func GetPricing(ctx context.Context, billingService *billingv1.CloudCatalogClient, serviceName string) ([]*billingpb.Sku, error) {
 	var skus []*billingpb.Sku
 	skuIterator := billingService.ListSkus(ctx, &billingpb.ListSkusRequest{Parent: serviceName})
 	for {
@@ -41,9 +42,32 @@ func GetPricing(ctx context.Context, billingService *billingv1.CloudCatalogClien
 			if errors.Is(err, iterator.Done) {
 				break
 			}
-			log.Println(err) // keep going if we get an error
+			return skus, err
 		}
 		skus = append(skus, sku)
 	}
-	return skus
+	return skus, nil
+}
+
+// FirstPaidTierPrice returns the first non-free tier price of a sku in USD, scaled by the scale of its usage unit, eg
+// GiBy. Skus priced in a usage unit without a scale or without a paid tier return false.
+func FirstPaidTierPrice(sku *billingpb.Sku, scales map[string]float64) (float64, bool) {
+	if len(sku.PricingInfo) == 0 || sku.PricingInfo[0].PricingExpression == nil {
+		return 0, false
+	}
+	expression := sku.PricingInfo[0].PricingExpression
+	scale, ok := scales[expression.UsageUnit]
+	if !ok {
+		return 0, false
+	}
+	for _, rate := range expression.TieredRates {
+		if rate.UnitPrice == nil {
+			continue
+		}
+		usd := float64(rate.UnitPrice.Units) + 1e-9*float64(rate.UnitPrice.Nanos)
+		if usd > 0 {
+			return usd * scale, true
+		}
+	}
+	return 0, false
 }
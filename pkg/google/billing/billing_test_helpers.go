@@ -198,6 +198,23 @@ func (s *FakeCloudCatalogServer) ListSkus(_ context.Context, req *billingpb.List
 					},
 				}},
 			},
+			{
+				Name:           "regional-standard-storage",
+				Description:    "Regional Storage PD Capacity",
+				ServiceRegions: []string{"us-central1"},
+				Category: &billingpb.Category{
+					ResourceFamily: "Storage",
+				},
+				PricingInfo: []*billingpb.PricingInfo{{
+					PricingExpression: &billingpb.PricingExpression{
+						TieredRates: []*billingpb.PricingExpression_TierRate{{
+							UnitPrice: &money.Money{
+								Nanos: 2e9,
+							},
+						}},
+					},
+				}},
+			},
 			{
 				Name:           "SSD Storage",
 				Description:    "SSD backed PD Capacity",
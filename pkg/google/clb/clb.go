@@ -0,0 +1,299 @@
+package clb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	computev1 "google.golang.org/api/compute/v1"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/google/billing"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "gcp"
+	subsystem    = "gcp_clb"
+
+	// serviceName is the service of the skus of Cloud Load Balancing, which are skus of Compute Engine.
+	serviceName = "Compute Engine"
+
+	// includedRules is the number of forwarding rules of a region covered by the minimum service charge, every rule
+	// above it is charged the additional rule price.
+	includedRules = 5
+
+	// locationGlobal is the location of the global forwarding rules, which is also the service region of the skus of
+	// the global load balancers.
+	locationGlobal = "global"
+)
+
+var (
+	ForwardingRuleHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "forwarding_rule_usd_per_hour"),
+		"The hourly cost of a forwarding rule in USD. The minimum service charge of a region is split between its first 5 rules, and the other rules cost the additional rule price.",
+		[]string{"project", "region", "forwarding_rule"},
+		nil,
+	)
+	DataProcessingPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "data_processing_usd_per_gib"),
+		"The price of a GiB of data processed by the load balancers of a region in USD.",
+		[]string{"region"},
+		nil,
+	)
+)
+
+// prices are the prices of the forwarding rules and of the data processing of the load balancers keyed by region, or
+// global for the global load balancers.
+type prices struct {
+	// minimumHour is the hourly price of the first 5 forwarding rules of a region, whatever their number
+	minimumHour map[string]float64
+	// additionalHour is the hourly price of every forwarding rule above the first 5
+	additionalHour map[string]float64
+	// gib is the price of a GiB of data processed
+	gib map[string]float64
+}
+
+// forwardingRule is a forwarding rule of a project along with the region it's priced in.
+type forwardingRule struct {
+	project string
+	region  string
+	name    string
+}
+
+// Collector exports the hourly cost of the forwarding rules of the load balancers of every project and the price of
+// the data they process, priced with the skus of Cloud Load Balancing of the billing catalog.
+type Collector struct {
+	billingService *billingv1.CloudCatalogClient
+	computeService *computev1.Service
+	projects       []string
+	logger         *slog.Logger
+	backoff        *provider.Backoff
+	nextScrape     time.Time
+	prices         *prices
+	m              sync.Mutex
+}
+
+type Config struct {
+	Projects       string
+	ScrapeInterval time.Duration
+	// Logger is the logger of the collector, the default logger is used when nil.
+	Logger *slog.Logger
+}
+
+// New creates a Collector. The prices are only listed again every scrape interval, or less often while the Cloud Billing
+// API throttles the collector, while the forwarding rules are listed on every scrape.
+func New(config *Config, billingService *billingv1.CloudCatalogClient, computeService *computev1.Service) *Collector {
+	var projects []string
+	if config.Projects != "" {
+		projects = strings.Split(config.Projects, ",")
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	c := &Collector{
+		billingService: billingService,
+		computeService: computeService,
+		projects:       projects,
+		logger:         logger.With("collector", "clb"),
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "CLB"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- ForwardingRuleHourlyCostDesc
+	ch <- DataProcessingPriceDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		ForwardingRuleHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices again when the scrape interval has passed, then exports the forwarding rules of every
+// project. A project failing is reported in its scope error metric and only fails the collector when every project
+// failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listPrices(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing cloud load balancing prices: %w", err)
+		}
+		c.prices = prices
+	}
+	c.backoff.Emit(ch)
+	for region, gib := range c.prices.gib {
+		ch <- prometheus.MustNewConstMetric(DataProcessingPriceDesc, prometheus.GaugeValue, gib, region)
+	}
+
+	var failedProjects []error
+	ctx := context.TODO()
+	for _, project := range c.projects {
+		rules, err := listForwardingRules(ctx, c.computeService, project)
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, project, err)
+		if err != nil {
+			c.logger.LogAttrs(ctx, slog.LevelError, "failed to list the forwarding rules", slog.String("project", project), slog.String("err", err.Error()))
+			failedProjects = append(failedProjects, fmt.Errorf("project %s: %w", project, err))
+			continue
+		}
+		for region, regionRules := range byRegion(rules) {
+			minimumHour, ok := c.prices.minimumHour[region]
+			if !ok {
+				c.logger.LogAttrs(ctx, slog.LevelWarn, "skipping the forwarding rules of a region without a price",
+					slog.String("project", project),
+					slog.String("region", region),
+					slog.Int("forwarding_rules", len(regionRules)),
+				)
+				continue
+			}
+			for i, cost := range ruleCosts(len(regionRules), minimumHour, c.prices.additionalHour[region]) {
+				ch <- prometheus.MustNewConstMetric(ForwardingRuleHourlyCostDesc, prometheus.GaugeValue, cost, project, region, regionRules[i].name)
+			}
+		}
+	}
+	if len(c.projects) > 0 && len(failedProjects) == len(c.projects) {
+		return errors.Join(failedProjects...)
+	}
+	return nil
+}
+
+// byRegion groups the forwarding rules by region, sorted by name so that the rules covered by the minimum service
+// charge don't change between scrapes.
+func byRegion(rules []forwardingRule) map[string][]forwardingRule {
+	regions := make(map[string][]forwardingRule)
+	for _, rule := range rules {
+		regions[rule.region] = append(regions[rule.region], rule)
+	}
+	for _, regionRules := range regions {
+		sort.Slice(regionRules, func(i, j int) bool { return regionRules[i].name < regionRules[j].name })
+	}
+	return regions
+}
+
+// ruleCosts returns the hourly cost of every one of n forwarding rules of a region. The minimum service charge is billed
+// for up to 5 rules whatever their number, so it's split between the first 5 rules, and the other rules cost the
+// additional rule price.
+func ruleCosts(n int, minimumHour float64, additionalHour float64) []float64 {
+	costs := make([]float64, n)
+	included := min(n, includedRules)
+	for i := range costs {
+		if i < included {
+			costs[i] = minimumHour / float64(included)
+		} else {
+			costs[i] = additionalHour
+		}
+	}
+	return costs
+}
+
+// listPrices lists the skus of Compute Engine and returns the prices of the load balancers of every region they're
+// offered in.
+func (c *Collector) listPrices(ctx context.Context) (*prices, error) {
+	name, err := billing.GetServiceName(ctx, c.billingService, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the service name of %s: %w", serviceName, err)
+	}
+	skus, err := billing.GetPricing(ctx, c.billingService, name)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the skus of %s: %w", serviceName, err)
+	}
+	return parsePrices(ctx, c.logger, skus), nil
+}
+
+// parsePrices returns the forwarding rule and data processing prices of the load balancing skus of Compute Engine, keyed
+// by the regions the skus are offered in. The skus of the other products of Compute Engine are skipped.
+func parsePrices(ctx context.Context, logger *slog.Logger, skus []*billingpb.Sku) *prices {
+	p := &prices{minimumHour: make(map[string]float64), additionalHour: make(map[string]float64), gib: make(map[string]float64)}
+	for _, sku := range skus {
+		if sku == nil || !strings.Contains(sku.Description, "Load Balancing") {
+			continue
+		}
+		var target map[string]float64
+		var scales map[string]float64
+		switch {
+		case strings.Contains(sku.Description, "Forwarding Rule Minimum"):
+			target, scales = p.minimumHour, map[string]float64{"h": 1}
+		case strings.Contains(sku.Description, "Forwarding Rule Additional"):
+			target, scales = p.additionalHour, map[string]float64{"h": 1}
+		case strings.Contains(sku.Description, "Data Processing"):
+			target, scales = p.gib, map[string]float64{"GiBy": 1}
+		default:
+			continue
+		}
+		value, ok := billing.FirstPaidTierPrice(sku, scales)
+		if !ok {
+			logger.LogAttrs(ctx, slog.LevelWarn, "skipping a sku without a supported price",
+				slog.String("sku", sku.Description),
+				slog.String("service", serviceName),
+				slog.Any("regions", sku.ServiceRegions),
+			)
+			continue
+		}
+		for _, region := range sku.ServiceRegions {
+			if _, ok := target[region]; !ok {
+				target[region] = value
+			}
+		}
+	}
+	return p
+}
+
+// listForwardingRules lists the regional and global forwarding rules of a project.
+func listForwardingRules(ctx context.Context, service *computev1.Service, project string) ([]forwardingRule, error) {
+	var rules []forwardingRule
+	err := service.ForwardingRules.AggregatedList(project).Pages(ctx, func(page *computev1.ForwardingRuleAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, rule := range scoped.ForwardingRules {
+				rules = append(rules, parseForwardingRule(project, rule))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// parseForwardingRule returns a forwarding rule along with its region, the last segment of the URL of the region of
+// the regional rules and global for the global rules.
+func parseForwardingRule(project string, rule *computev1.ForwardingRule) forwardingRule {
+	region := locationGlobal
+	if rule.Region != "" {
+		region = rule.Region[strings.LastIndex(rule.Region, "/")+1:]
+	}
+	return forwardingRule{project: project, region: region, name: rule.Name}
+}
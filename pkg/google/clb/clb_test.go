@@ -0,0 +1,179 @@
+package clb
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testSku(description string, usageUnit string, price *money.Money, regions ...string) *billingpb.Sku {
+	return &billingpb.Sku{
+		Description:    description,
+		ServiceRegions: regions,
+		PricingInfo: []*billingpb.PricingInfo{{
+			PricingExpression: &billingpb.PricingExpression{
+				UsageUnit:   usageUnit,
+				TieredRates: []*billingpb.PricingExpression_TierRate{{UnitPrice: price}},
+			},
+		}},
+	}
+}
+
+var (
+	minimumSku        = testSku("Network Load Balancing: Forwarding Rule Minimum Service Charge in Iowa", "h", &money.Money{Nanos: 25000000}, "us-central1")
+	additionalSku     = testSku("Network Load Balancing: Forwarding Rule Additional Service Charge in Iowa", "h", &money.Money{Nanos: 10000000}, "us-central1")
+	dataProcessingSku = testSku("Network Load Balancing: Data Processing Charge in Iowa", "GiBy", &money.Money{Nanos: 8000000}, "us-central1")
+	globalMinimumSku  = testSku("Global External Load Balancing: Forwarding Rule Minimum Service Charge", "h", &money.Money{Nanos: 25000000}, "global")
+)
+
+func Test_parsePrices(t *testing.T) {
+	p := parsePrices(context.Background(), slog.Default(), []*billingpb.Sku{
+		nil,
+		minimumSku,
+		additionalSku,
+		dataProcessingSku,
+		globalMinimumSku,
+		testSku("N2 Instance Core running in Americas", "h", &money.Money{Nanos: 31611000}, "us-central1"),
+		testSku("Network Load Balancing: Forwarding Rule Minimum Service Charge in Oregon", "GiBy", &money.Money{Units: 1}, "us-west1"),
+	})
+	assert.Equal(t, map[string]float64{"us-central1": 0.025, "global": 0.025}, p.minimumHour)
+	assert.Equal(t, map[string]float64{"us-central1": 0.01}, p.additionalHour)
+	assert.Equal(t, map[string]float64{"us-central1": 0.008}, p.gib)
+}
+
+func Test_ruleCosts(t *testing.T) {
+	assert.Equal(t, []float64{0.025}, ruleCosts(1, 0.025, 0.01))
+	assert.InDeltaSlice(t, []float64{0.005, 0.005, 0.005, 0.005, 0.005, 0.01, 0.01}, ruleCosts(7, 0.025, 0.01), 1e-12)
+	assert.Empty(t, ruleCosts(0, 0.025, 0.01))
+}
+
+func Test_parseForwardingRule(t *testing.T) {
+	assert.Equal(t, forwardingRule{project: "testing", region: "us-central1", name: "ingress"}, parseForwardingRule("testing", &computev1.ForwardingRule{
+		Name:   "ingress",
+		Region: "https://www.googleapis.com/compute/v1/projects/testing/regions/us-central1",
+	}))
+	assert.Equal(t, forwardingRule{project: "testing", region: "global", name: "https"}, parseForwardingRule("testing", &computev1.ForwardingRule{Name: "https"}))
+}
+
+type fakeCloudCatalogServer struct {
+	billingpb.UnimplementedCloudCatalogServer
+	listSkus atomic.Int32
+}
+
+func (s *fakeCloudCatalogServer) ListServices(_ context.Context, _ *billingpb.ListServicesRequest) (*billingpb.ListServicesResponse, error) {
+	return &billingpb.ListServicesResponse{Services: []*billingpb.Service{
+		{DisplayName: serviceName, Name: "services/compute"},
+	}}, nil
+}
+
+func (s *fakeCloudCatalogServer) ListSkus(_ context.Context, _ *billingpb.ListSkusRequest) (*billingpb.ListSkusResponse, error) {
+	s.listSkus.Add(1)
+	return &billingpb.ListSkusResponse{Skus: []*billingpb.Sku{minimumSku, additionalSku, dataProcessingSku, globalMinimumSku}}, nil
+}
+
+func TestCollector_Collect(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	gsrv := grpc.NewServer()
+	defer gsrv.Stop()
+	server := &fakeCloudCatalogServer{}
+	billingpb.RegisterCloudCatalogServer(gsrv, server)
+	go func() {
+		_ = gsrv.Serve(l)
+	}()
+	billingClient, err := billingv1.NewCloudCatalogClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+
+	regional := func(name string, region string) *computev1.ForwardingRule {
+		return &computev1.ForwardingRule{Name: name, Region: "https://www.googleapis.com/compute/v1/projects/testing/regions/" + region}
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/testing/aggregated/forwardingRules" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&computev1.ForwardingRuleAggregatedList{Items: map[string]computev1.ForwardingRulesScopedList{
+			"regions/us-central1": {ForwardingRules: []*computev1.ForwardingRule{
+				regional("f", "us-central1"), regional("e", "us-central1"), regional("d", "us-central1"),
+				regional("c", "us-central1"), regional("b", "us-central1"), regional("a", "us-central1"),
+			}},
+			"global": {ForwardingRules: []*computev1.ForwardingRule{{Name: "https"}}},
+			// There's no price of the forwarding rules of the region
+			"regions/mars-east1": {ForwardingRules: []*computev1.ForwardingRule{regional("rover", "mars-east1")}},
+		}})
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "testing,forbidden", ScrapeInterval: time.Hour}, billingClient, computeService)
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 20)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		metrics := make(map[string][]*utils.MetricResult)
+		for metric := range ch {
+			result := utils.ReadMetrics(metric)
+			metrics[result.FqName] = append(metrics[result.FqName], result)
+		}
+		assert.Len(t, metrics["cloudcost_exporter_collector_scope_last_scrape_error"], 2)
+		assert.Equal(t, []*utils.MetricResult{
+			{FqName: "cloudcost_gcp_clb_data_processing_usd_per_gib", Labels: utils.LabelMap{"region": "us-central1"}, Value: 0.008, MetricType: prometheus.GaugeValue},
+		}, metrics["cloudcost_gcp_clb_data_processing_usd_per_gib"])
+
+		costs := make(map[string]float64)
+		for _, m := range metrics["cloudcost_gcp_clb_forwarding_rule_usd_per_hour"] {
+			assert.Equal(t, "testing", m.Labels["project"])
+			costs[m.Labels["region"]+"/"+m.Labels["forwarding_rule"]] = m.Value
+		}
+		assert.InDeltaMapValues(t, map[string]float64{
+			"us-central1/a": 0.005,
+			"us-central1/b": 0.005,
+			"us-central1/c": 0.005,
+			"us-central1/d": 0.005,
+			"us-central1/e": 0.005,
+			"us-central1/f": 0.01,
+			"global/https":  0.025,
+		}, costs, 1e-12)
+	}
+	// The prices are only listed once per scrape interval
+	assert.Equal(t, int32(1), server.listSkus.Load())
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "forbidden"}, nil, computeService)
+	// The prices were already listed
+	c.prices = &prices{}
+	c.nextScrape = time.Now().Add(time.Hour)
+	ch := make(chan prometheus.Metric, 10)
+	assert.Error(t, c.Collect(ch))
+}
@@ -0,0 +1,326 @@
+package commitments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	computev1 "google.golang.org/api/compute/v1"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/google/compute"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "gcp"
+	subsystem    = "gcp_cud"
+
+	resourceVCPU   = "vcpu"
+	resourceMemory = "memory"
+
+	mibPerGib = 1024
+)
+
+var (
+	ExpiryTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "expiry_timestamp_seconds"),
+		"The time at which an active committed use discount expires, in seconds since the epoch.",
+		[]string{"project", "region", "commitment", "type", "plan"},
+		nil,
+	)
+	CommittedVCPUsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "committed_vcpus"),
+		"The number of vCPUs committed by the active committed use discounts of a machine family.",
+		[]string{"project", "region", "family"},
+		nil,
+	)
+	CommittedMemoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "committed_memory_gibibytes"),
+		"The GiB of memory committed by the active committed use discounts of a machine family.",
+		[]string{"project", "region", "family"},
+		nil,
+	)
+	UtilizationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "utilization_ratio"),
+		"The share of the committed vCPUs or memory of a machine family used by the running standard instances of the project, between 0 and 1.",
+		[]string{"project", "region", "family", "resource"},
+		nil,
+	)
+)
+
+// familiesByType maps the commitment types that don't end with their machine family to it, eg GENERAL_PURPOSE
+// commitments cover N1 machines while GENERAL_PURPOSE_N2 ones cover N2 machines.
+var familiesByType = map[string]string{
+	"GENERAL_PURPOSE":       "n1",
+	"COMPUTE_OPTIMIZED":     "c2",
+	"MEMORY_OPTIMIZED":      "m1",
+	"ACCELERATOR_OPTIMIZED": "a2",
+	"GRAPHICS_OPTIMIZED":    "g2",
+}
+
+type Config struct {
+	Projects       string
+	ScrapeInterval time.Duration
+}
+
+// commitment is an active committed use discount of a project.
+type commitment struct {
+	name      string
+	region    string
+	family    string
+	cudType   string
+	plan      string
+	end       time.Time
+	vcpus     float64
+	memoryGiB float64
+}
+
+// usage is the amount of vCPUs and memory of a region and machine family, either committed or used.
+type usage struct {
+	vcpus     float64
+	memoryGiB float64
+}
+
+type familyKey struct {
+	region string
+	family string
+}
+
+// projectCommitments is what's exported for a project, its commitments and their utilization.
+type projectCommitments struct {
+	commitments []commitment
+	committed   map[familyKey]usage
+	used        map[familyKey]usage
+}
+
+// Collector exports the expiration of the committed use discounts of the projects, the resources they commit and how
+// much of it the running instances use, so that renewals can be planned and alerted on. The commitments are only
+// listed again every scrape interval, or less often while the Compute Engine API throttles the collector.
+type Collector struct {
+	computeService *computev1.Service
+	machineShapes  *compute.MachineShapes
+	projects       []string
+	backoff        *provider.Backoff
+
+	m          sync.Mutex
+	nextScrape time.Time
+	results    map[string]*projectCommitments
+	errs       map[string]error
+}
+
+// New creates a Collector listing the commitments of config.Projects.
+func New(config *Config, computeService *computev1.Service) *Collector {
+	c := &Collector{
+		computeService: computeService,
+		machineShapes:  compute.NewMachineShapes(computeService),
+		projects:       strings.Split(config.Projects, ","),
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Commitments"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- ExpiryTimestampDesc
+	ch <- CommittedVCPUsDesc
+	ch <- CommittedMemoryDesc
+	ch <- UtilizationDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the commitments of every project again when the scrape interval has passed and exports them. A project
+// failing is reported in its scope error metric and only fails the collector when every project failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.results == nil || now.After(c.nextScrape) {
+		err := c.refresh(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+	}
+	c.backoff.Emit(ch)
+
+	var failedProjects []error
+	for _, project := range c.projects {
+		err := c.errs[project]
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, project, err)
+		if err != nil {
+			failedProjects = append(failedProjects, fmt.Errorf("project %s: %w", project, err))
+			continue
+		}
+		emit(ch, project, c.results[project])
+	}
+	if len(failedProjects) == len(c.projects) {
+		return errors.Join(failedProjects...)
+	}
+	return nil
+}
+
+// refresh lists the commitments and running instances of every project. It returns an error when every project failed.
+func (c *Collector) refresh(ctx context.Context) error {
+	results := make(map[string]*projectCommitments, len(c.projects))
+	errs := make(map[string]error)
+	var failed []error
+	for _, project := range c.projects {
+		result, err := c.listProject(ctx, project)
+		if err != nil {
+			log.Printf("error listing commitments in project %s: %s", project, err)
+			errs[project] = err
+			failed = append(failed, err)
+			continue
+		}
+		results[project] = result
+	}
+	c.results = results
+	c.errs = errs
+	if len(failed) == len(c.projects) {
+		return errors.Join(failed...)
+	}
+	return nil
+}
+
+// listProject lists the active commitments of a project, and when it has any, the running instances of the families
+// they cover.
+func (c *Collector) listProject(ctx context.Context, project string) (*projectCommitments, error) {
+	result := &projectCommitments{
+		committed: make(map[familyKey]usage),
+		used:      make(map[familyKey]usage),
+	}
+	err := c.computeService.RegionCommitments.AggregatedList(project).Filter("status=ACTIVE").Pages(ctx, func(list *computev1.CommitmentAggregatedList) error {
+		for _, scoped := range list.Items {
+			for _, cud := range scoped.Commitments {
+				result.commitments = append(result.commitments, newCommitment(cud))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing commitments: %w", err)
+	}
+	if len(result.commitments) == 0 {
+		return result, nil
+	}
+	sort.Slice(result.commitments, func(i, j int) bool {
+		if !result.commitments[i].end.Equal(result.commitments[j].end) {
+			return result.commitments[i].end.Before(result.commitments[j].end)
+		}
+		return result.commitments[i].name < result.commitments[j].name
+	})
+	for _, cud := range result.commitments {
+		key := familyKey{region: cud.region, family: cud.family}
+		committed := result.committed[key]
+		committed.vcpus += cud.vcpus
+		committed.memoryGiB += cud.memoryGiB
+		result.committed[key] = committed
+	}
+
+	err = c.computeService.Instances.AggregatedList(project).Filter("status=RUNNING").Pages(ctx, func(list *computev1.InstanceAggregatedList) error {
+		for _, scoped := range list.Items {
+			for _, instance := range scoped.Instances {
+				spec := compute.NewMachineSpec(instance)
+				key := familyKey{region: spec.Region, family: spec.Family}
+				// Committed use discounts only apply to standard instances of the families they were purchased for
+				if _, ok := result.committed[key]; !ok || spec.ProvisioningModel != compute.ProvisioningModelStandard {
+					continue
+				}
+				vcpus, memoryGiB, err := c.machineShapes.Resources(project, spec)
+				if err != nil {
+					return fmt.Errorf("error getting the machine type of instance %s: %w", spec.Instance, err)
+				}
+				used := result.used[key]
+				used.vcpus += vcpus
+				used.memoryGiB += memoryGiB
+				result.used[key] = used
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing instances: %w", err)
+	}
+	return result, nil
+}
+
+func newCommitment(cud *computev1.Commitment) commitment {
+	c := commitment{
+		name:    cud.Name,
+		region:  cud.Region[strings.LastIndex(cud.Region, "/")+1:],
+		family:  commitmentFamily(cud.Type),
+		cudType: cud.Type,
+		plan:    cud.Plan,
+	}
+	end, err := time.Parse(time.RFC3339, cud.EndTimestamp)
+	if err != nil {
+		log.Printf("error parsing the end of commitment %s: %s", cud.Name, err)
+	}
+	c.end = end
+	for _, resource := range cud.Resources {
+		switch resource.Type {
+		case "VCPU":
+			c.vcpus += float64(resource.Amount)
+		case "MEMORY":
+			c.memoryGiB += float64(resource.Amount) / mibPerGib
+		}
+	}
+	return c
+}
+
+// commitmentFamily returns the machine family a commitment type covers, eg n2 for GENERAL_PURPOSE_N2.
+func commitmentFamily(cudType string) string {
+	if family, ok := familiesByType[cudType]; ok {
+		return family
+	}
+	return strings.ToLower(cudType[strings.LastIndex(cudType, "_")+1:])
+}
+
+func emit(ch chan<- prometheus.Metric, project string, result *projectCommitments) {
+	for _, cud := range result.commitments {
+		if cud.end.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(ExpiryTimestampDesc, prometheus.GaugeValue, float64(cud.end.Unix()), project, cud.region, cud.name, cud.cudType, cud.plan)
+	}
+	keys := make([]familyKey, 0, len(result.committed))
+	for key := range result.committed {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].region != keys[j].region {
+			return keys[i].region < keys[j].region
+		}
+		return keys[i].family < keys[j].family
+	})
+	for _, key := range keys {
+		committed, used := result.committed[key], result.used[key]
+		ch <- prometheus.MustNewConstMetric(CommittedVCPUsDesc, prometheus.GaugeValue, committed.vcpus, project, key.region, key.family)
+		ch <- prometheus.MustNewConstMetric(CommittedMemoryDesc, prometheus.GaugeValue, committed.memoryGiB, project, key.region, key.family)
+		if committed.vcpus > 0 {
+			ch <- prometheus.MustNewConstMetric(UtilizationDesc, prometheus.GaugeValue, min(1, used.vcpus/committed.vcpus), project, key.region, key.family, resourceVCPU)
+		}
+		if committed.memoryGiB > 0 {
+			ch <- prometheus.MustNewConstMetric(UtilizationDesc, prometheus.GaugeValue, min(1, used.memoryGiB/committed.memoryGiB), project, key.region, key.family, resourceMemory)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package commitments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestCollector_Collect(t *testing.T) {
+	end := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf interface{}
+		switch r.URL.Path {
+		case "/projects/testing/aggregated/commitments":
+			assert.Equal(t, "status=ACTIVE", r.URL.Query().Get("filter"))
+			buf = &computev1.CommitmentAggregatedList{Items: map[string]computev1.CommitmentsScopedList{
+				"regions/us-central1": {Commitments: []*computev1.Commitment{
+					{
+						Name:         "cud-n2",
+						Region:       "https://www.googleapis.com/compute/v1/projects/testing/regions/us-central1",
+						Type:         "GENERAL_PURPOSE_N2",
+						Plan:         "TWELVE_MONTH",
+						EndTimestamp: end.Format(time.RFC3339),
+						Resources: []*computev1.ResourceCommitment{
+							{Type: "VCPU", Amount: 8},
+							{Type: "MEMORY", Amount: 32768},
+						},
+					},
+				}},
+			}}
+		case "/projects/testing/aggregated/instances":
+			assert.Equal(t, "status=RUNNING", r.URL.Query().Get("filter"))
+			buf = &computev1.InstanceAggregatedList{Items: map[string]computev1.InstancesScopedList{
+				"zones/us-central1-a": {Instances: []*computev1.Instance{
+					{Name: "n2", Zone: "us-central1-a", MachineType: "zones/us-central1-a/machineTypes/n2-standard-4"},
+					// Spot instances and the instances of other families don't use the commitment
+					{Name: "spot", Zone: "us-central1-a", MachineType: "zones/us-central1-a/machineTypes/n2-standard-4", Scheduling: &computev1.Scheduling{ProvisioningModel: "SPOT"}},
+					{Name: "e2", Zone: "us-central1-a", MachineType: "zones/us-central1-a/machineTypes/e2-standard-4"},
+				}},
+			}}
+		case "/projects/testing/zones/us-central1-a/machineTypes/n2-standard-4":
+			buf = &computev1.MachineType{GuestCpus: 4, MemoryMb: 16384}
+		case "/projects/failing/aggregated/commitments":
+			w.WriteHeader(http.StatusForbidden)
+			return
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(buf)
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "testing,failing", ScrapeInterval: time.Hour}, computeService)
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, c.Collect(ch))
+	close(ch)
+	metrics := map[string][]*utils.MetricResult{}
+	for metric := range ch {
+		result := utils.ReadMetrics(metric)
+		metrics[result.FqName] = append(metrics[result.FqName], result)
+	}
+
+	assert.Equal(t, []*utils.MetricResult{{
+		FqName:     "cloudcost_gcp_cud_expiry_timestamp_seconds",
+		Labels:     utils.LabelMap{"project": "testing", "region": "us-central1", "commitment": "cud-n2", "type": "GENERAL_PURPOSE_N2", "plan": "TWELVE_MONTH"},
+		Value:      float64(end.Unix()),
+		MetricType: prometheus.GaugeValue,
+	}}, metrics["cloudcost_gcp_cud_expiry_timestamp_seconds"])
+	family := utils.LabelMap{"project": "testing", "region": "us-central1", "family": "n2"}
+	require.Len(t, metrics["cloudcost_gcp_cud_committed_vcpus"], 1)
+	assert.Equal(t, family, metrics["cloudcost_gcp_cud_committed_vcpus"][0].Labels)
+	assert.Equal(t, 8.0, metrics["cloudcost_gcp_cud_committed_vcpus"][0].Value)
+	require.Len(t, metrics["cloudcost_gcp_cud_committed_memory_gibibytes"], 1)
+	assert.Equal(t, 32.0, metrics["cloudcost_gcp_cud_committed_memory_gibibytes"][0].Value)
+	require.Len(t, metrics["cloudcost_gcp_cud_utilization_ratio"], 2)
+	assert.Equal(t, utils.LabelMap{"project": "testing", "region": "us-central1", "family": "n2", "resource": "vcpu"}, metrics["cloudcost_gcp_cud_utilization_ratio"][0].Labels)
+	assert.Equal(t, 0.5, metrics["cloudcost_gcp_cud_utilization_ratio"][0].Value)
+	assert.Equal(t, 0.5, metrics["cloudcost_gcp_cud_utilization_ratio"][1].Value)
+	require.Len(t, metrics["cloudcost_exporter_collector_scope_last_scrape_error"], 2)
+	assert.Equal(t, utils.LabelMap{"provider": "gcp", "collector": "gcp_cud", "scope": "failing"}, metrics["cloudcost_exporter_collector_scope_last_scrape_error"][1].Labels)
+	assert.Equal(t, 1.0, metrics["cloudcost_exporter_collector_scope_last_scrape_error"][1].Value)
+}
+
+func TestCommitmentFamily(t *testing.T) {
+	for cudType, want := range map[string]string{
+		"GENERAL_PURPOSE":       "n1",
+		"GENERAL_PURPOSE_N2D":   "n2d",
+		"COMPUTE_OPTIMIZED_C2D": "c2d",
+		"MEMORY_OPTIMIZED":      "m1",
+	} {
+		assert.Equal(t, want, commitmentFamily(cudType), cudType)
+	}
+}
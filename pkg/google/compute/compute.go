@@ -12,14 +12,21 @@ import (
 	billingv1 "cloud.google.com/go/billing/apiv1"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/monitoring/v3"
 
 	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
 	"github.com/grafana/cloudcost-exporter/pkg/google/billing"
+	"github.com/grafana/cloudcost-exporter/pkg/google/discovery"
+	"github.com/grafana/cloudcost-exporter/pkg/google/hierarchy"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
-	subsystem = "gcp_compute"
+	subsystem    = "gcp_compute"
+	providerName = "gcp"
 )
 
 var (
@@ -36,36 +43,75 @@ var (
 	InstanceCPUHourlyCostDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_cpu_usd_per_core_hour"),
 		"The cpu cost a GCP Compute Instance in USD/(core*h)",
-		[]string{"instance", "region", "family", "machine_type", "project", "price_tier"},
+		[]string{"instance", "region", "zone", "family", "machine_type", "project", "price_tier", "provisioning_model", "folder", "org"},
 		nil,
 	)
 	InstanceMemoryHourlyCostDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_ram_usd_per_gib_hour"),
 		"The memory cost of a GCP Compute Instance in USD/(GiB*h)",
-		[]string{"instance", "region", "family", "machine_type", "project", "price_tier"},
+		[]string{"instance", "region", "zone", "family", "machine_type", "project", "price_tier", "provisioning_model", "folder", "org"},
+		nil,
+	)
+	// InstanceCreatedTimestampDesc is only emitted by the compute collector, which already covers the instances of GKE clusters.
+	InstanceCreatedTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp", "instance_created_timestamp_seconds"),
+		"The time the GCP Compute Instance was created as a unix timestamp in seconds.",
+		[]string{"instance", "region", "machine_type", "project", "folder", "org"},
+		nil,
+	)
+	// InstanceCostTotalDesc accumulates the hourly price of every instance between scrapes, see utils.CostCounter. Like
+	// InstanceCreatedTimestampDesc it covers the instances of GKE clusters as well.
+	InstanceCostTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "usd_total"),
+		"The cost of a GCP Compute Instance in USD accumulated by the exporter since it first saw the instance.",
+		[]string{"instance", "region", "machine_type", "project", "price_tier", "folder", "org"},
 		nil,
 	)
 )
 
 type Config struct {
-	Projects       string
+	Projects string
+	// Discovery lists the projects to collect instead of Projects when it's set.
+	Discovery      *discovery.Projects
 	ScrapeInterval time.Duration
+	// Hierarchy resolves the folder and organization labels of a project, they are left empty when it's nil.
+	Hierarchy *hierarchy.Resolver
+	// InstanceFilter is a filter expression of the instances.list API, every instance is listed when it's empty.
+	InstanceFilter string
+	// PriceHistory records every pricing map, they aren't recorded when it's nil.
+	PriceHistory *pricehistory.History
+	// PriceLookup is set every pricing map, it's left alone when it's nil.
+	PriceLookup *pricing.Lookup
+	// Families selects the machine families whose prices are kept, every family is priced when it's nil.
+	Families *pricing.FamilyFilter
 }
 
 // Collector implements the Collector interface for compute services in Compute.
 type Collector struct {
 	computeService *compute.Service
 	billingService *billingv1.CloudCatalogClient
-	PricingMap     *StructuredPricingMap
-	config         *Config
-	Projects       []string
-	NextScrape     time.Time
+	// monitoringService is only set when idle costs are enabled
+	monitoringService *monitoring.Service
+	machineShapes     *MachineShapes
+	costs             *utils.CostCounter
+	PricingMap        *StructuredPricingMap
+	config            *Config
+	Projects          []string
+	NextScrape        time.Time
+	backoff           *provider.Backoff
 }
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
 	ch <- NextScrapeDesc
 	ch <- InstanceCPUHourlyCostDesc
 	ch <- InstanceMemoryHourlyCostDesc
+	ch <- UnpricedMachineTypeInfoDesc
+	ch <- utils.PricingCoverageDesc
+	ch <- InstanceCreatedTimestampDesc
+	ch <- InstanceIdleHourlyCostDesc
+	ch <- InstanceCostTotalDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
 	return nil
 }
 
@@ -78,14 +124,42 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 }
 
 // New is a helper method to properly set up a compute.Collector struct.
-func New(config *Config, computeService *compute.Service, billingService *billingv1.CloudCatalogClient) *Collector {
+// monitoringService is optional, when set Cloud Monitoring is used to export the idle cost of each instance.
+func New(config *Config, computeService *compute.Service, billingService *billingv1.CloudCatalogClient, monitoringService *monitoring.Service) *Collector {
 	projects := strings.Split(config.Projects, ",")
-	return &Collector{
-		computeService: computeService,
-		billingService: billingService,
-		config:         config,
-		Projects:       projects,
+	c := &Collector{
+		computeService:    computeService,
+		billingService:    billingService,
+		monitoringService: monitoringService,
+		machineShapes:     NewMachineShapes(computeService),
+		costs:             utils.NewCostCounter(InstanceCostTotalDesc),
+		config:            config,
+		Projects:          projects,
 	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+// refreshPricingMap lists the skus of Compute Engine again and rebuilds the pricing map.
+func (c *Collector) refreshPricingMap(ctx context.Context) error {
+	serviceName, err := billing.GetServiceName(ctx, c.billingService, "Compute Engine")
+	if err != nil {
+		return fmt.Errorf("error getting service name: %w", err)
+	}
+	skus, err := billing.GetPricing(ctx, c.billingService, serviceName)
+	if err != nil {
+		return fmt.Errorf("error listing skus: %w", err)
+	}
+	pricingMap, err := GeneratePricingMap(FilterSkus(skus, c.config.Families))
+	if err != nil {
+		return fmt.Errorf("error generating pricing map: %w", err)
+	}
+	c.PricingMap = pricingMap
+	if c.config.PriceHistory != nil {
+		c.config.PriceHistory.Record(subsystem, pricingMap.HistoryPrices())
+	}
+	c.config.PriceLookup.Set(pricing.ProviderGCP, pricingMap)
+	return nil
 }
 
 // Name returns a well formatted string for the name of the collector. Helpful for logging
@@ -93,17 +167,21 @@ func (c *Collector) Name() string {
 	return "Compute Collector"
 }
 
-// ListInstancesInZone will list all instances in a given zone and return a slice of MachineSpecs
-func ListInstancesInZone(projectID, zone string, c *compute.Service) ([]*MachineSpec, error) {
+// ListInstancesInZone will list all instances in a given zone and return a slice of MachineSpecs. filter is an optional
+// filter expression of the instances.list API, eg labels.env=prod, which is evaluated server side.
+func ListInstancesInZone(projectID, zone, filter string, c *compute.Service) ([]*MachineSpec, error) {
 	var allInstances []*MachineSpec
 	var nextPageToken string
 	log.Printf("Listing instances for project %s in zone %s", projectID, zone)
 	now := time.Now()
 
 	for {
-		instances, err := c.Instances.List(projectID, zone).
-			PageToken(nextPageToken).
-			Do()
+		call := c.Instances.List(projectID, zone).
+			PageToken(nextPageToken)
+		if filter != "" {
+			call = call.Filter(filter)
+		}
+		instances, err := call.Do()
 		if err != nil {
 			log.Printf("Error listing instances in zone %s: %s", zone, err)
 			return nil, fmt.Errorf("%w: %s", ListInstancesError, err.Error())
@@ -130,30 +208,49 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 	start := time.Now()
 	log.Printf("Collecting %s metrics", c.Name())
 	ctx := context.TODO()
-	if c.PricingMap == nil || time.Now().After(c.NextScrape) {
+	if c.PricingMap == nil || start.After(c.NextScrape) {
 		log.Println("Refreshing pricing map")
-		serviceName, err := billing.GetServiceName(ctx, c.billingService, "Compute Engine")
-		if err != nil {
-			log.Printf("Error getting service name: %s", err)
-			return 0
-		}
-		skus := billing.GetPricing(ctx, c.billingService, serviceName)
-		pricingMap, err := GeneratePricingMap(skus)
+		err := c.refreshPricingMap(ctx)
+		c.NextScrape = c.backoff.Next(start, err)
 		if err != nil {
-			log.Printf("Error generating pricing map: %s", err)
+			log.Printf("Error refreshing pricing map: %s", err)
 			return 0
 		}
-
-		c.PricingMap = pricingMap
-		c.NextScrape = time.Now().Add(c.config.ScrapeInterval)
 		log.Printf("Finished refreshing pricing map in %s", time.Since(start))
 	}
 	ch <- prometheus.MustNewConstMetric(NextScrapeDesc, prometheus.GaugeValue, float64(c.NextScrape.Unix()))
-	for _, project := range c.Projects {
+	c.backoff.Emit(ch)
+	projects, err := c.projects(ctx)
+	if err != nil {
+		log.Printf("Error discovering projects: %s", err)
+		return 0
+	}
+	unpriced := NewUnpricedMachineTypes(subsystem)
+	defer unpriced.Emit(ch)
+	failedProjects := 0
+	projectErrs := make(map[string]error, len(projects))
+	defer func() {
+		for project, err := range projectErrs {
+			ch <- provider.NewScopeErrorMetric(providerName, c.Name(), project, err)
+		}
+	}()
+	for _, project := range projects {
 		zones, err := c.computeService.Zones.List(project).Do()
+		projectErrs[project] = err
 		if err != nil {
-			log.Printf("Error listing zones: %s", err)
-			return 0
+			// A single project failing, eg due to missing permissions, shouldn't prevent the other projects from being exported
+			log.Printf("Error listing zones in project %s: %s", project, err)
+			failedProjects++
+			continue
+		}
+		ancestry := c.config.Hierarchy.Ancestry(ctx, project)
+		var utilization map[string]float64
+		if c.monitoringService != nil {
+			// Failing to get the utilization only drops the idle cost metrics, the project is still exported
+			utilization, err = ListCPUUtilization(ctx, c.monitoringService, project, time.Now())
+			if err != nil {
+				log.Printf("Error getting cpu utilization in project %s: %s", project, err)
+			}
 		}
 		wg := sync.WaitGroup{}
 		wg.Add(len(zones.Items))
@@ -161,7 +258,7 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 		for _, zone := range zones.Items {
 			go func(zone *compute.Zone) {
 				defer wg.Done()
-				instances, err := ListInstancesInZone(project, zone.Name, c.computeService)
+				instances, err := ListInstancesInZone(project, zone.Name, c.config.InstanceFilter, c.computeService)
 				if err != nil {
 					log.Printf("Error listing instances in zone %s: %s", zone.Name, err)
 					results <- nil
@@ -180,34 +277,93 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 				continue
 			}
 			for _, instance := range instances {
+				if !instance.CreatedAt.IsZero() {
+					ch <- prometheus.MustNewConstMetric(
+						InstanceCreatedTimestampDesc,
+						prometheus.GaugeValue,
+						float64(instance.CreatedAt.Unix()),
+						instance.Instance,
+						instance.Region,
+						instance.MachineType,
+						project,
+						ancestry.Folder,
+						ancestry.Org)
+				}
 				cpuCost, ramCost, err := c.PricingMap.GetCostOfInstance(instance)
 				if err != nil {
 					log.Printf("Could not get cost of instance(%s): %s", instance.Instance, err)
+					unpriced.Add(instance.Region, instance.MachineType, err)
 					continue
 				}
+				unpriced.Priced()
 				ch <- prometheus.MustNewConstMetric(
 					InstanceCPUHourlyCostDesc,
 					prometheus.GaugeValue,
 					cpuCost,
 					instance.Instance,
 					instance.Region,
+					instance.Zone,
 					instance.Family,
 					instance.MachineType,
 					project,
-					instance.PriceTier)
+					instance.PriceTier,
+					instance.ProvisioningModel,
+					ancestry.Folder,
+					ancestry.Org)
 				ch <- prometheus.MustNewConstMetric(InstanceMemoryHourlyCostDesc,
 					prometheus.GaugeValue,
 					ramCost,
 					instance.Instance,
 					instance.Region,
+					instance.Zone,
 					instance.Family,
 					instance.MachineType,
 					project,
-					instance.PriceTier)
+					instance.PriceTier,
+					instance.ProvisioningModel,
+					ancestry.Folder,
+					ancestry.Org)
+				shape, err := c.getMachineShape(project, instance.Zone, instance.MachineType)
+				if err != nil {
+					log.Printf("Could not get machine type %s of instance(%s): %s", instance.MachineType, instance.Instance, err)
+					continue
+				}
+				c.costs.Observe(HourlyCost(cpuCost, ramCost, shape),
+					instance.Instance,
+					instance.Region,
+					instance.MachineType,
+					project,
+					instance.PriceTier,
+					ancestry.Folder,
+					ancestry.Org)
+				if u, ok := utilization[utilizationKey(instance.Zone, instance.Instance)]; ok {
+					ch <- prometheus.MustNewConstMetric(InstanceIdleHourlyCostDesc,
+						prometheus.GaugeValue,
+						IdleCost(cpuCost, ramCost, shape, u),
+						instance.Instance,
+						instance.Region,
+						instance.MachineType,
+						project,
+						instance.PriceTier,
+						ancestry.Folder,
+						ancestry.Org)
+				}
 			}
 		}
 	}
+	c.costs.Emit(ch)
 	log.Printf("Finished collecting Compute metrics in %s", time.Since(start))
 
+	if len(projects) > 0 && failedProjects == len(projects) {
+		return 0
+	}
 	return 1.0
 }
+
+// projects returns the projects to collect, the projects below the parent of Discovery when it's set.
+func (c *Collector) projects(ctx context.Context) ([]string, error) {
+	if c.config.Discovery == nil {
+		return c.Projects, nil
+	}
+	return c.config.Discovery.List(ctx)
+}
@@ -46,7 +46,7 @@ func TestMain(m *testing.M) {
 	}
 	collector = New(&Config{
 		Projects: "some_project",
-	}, computeService, billingService)
+	}, computeService, billingService, nil)
 	code := m.Run()
 	os.Exit(code)
 }
@@ -66,13 +66,14 @@ func TestNewMachineSpec(t *testing.T) {
 				},
 			},
 			want: &MachineSpec{
-				Instance:     "test",
-				Zone:         "abc-123",
-				Region:       "abc",
-				MachineType:  "abc-def",
-				Family:       "abc",
-				SpotInstance: false,
-				PriceTier:    "ondemand",
+				Instance:          "test",
+				Zone:              "abc-123",
+				Region:            "abc",
+				MachineType:       "abc-def",
+				Family:            "abc",
+				SpotInstance:      false,
+				ProvisioningModel: "standard",
+				PriceTier:         "ondemand",
 			},
 		},
 		"machine type with no value": {
@@ -85,13 +86,14 @@ func TestNewMachineSpec(t *testing.T) {
 				},
 			},
 			want: &MachineSpec{
-				Instance:     "test",
-				Zone:         "abc-123",
-				Region:       "abc",
-				MachineType:  "",
-				Family:       "",
-				SpotInstance: false,
-				PriceTier:    "ondemand",
+				Instance:          "test",
+				Zone:              "abc-123",
+				Region:            "abc",
+				MachineType:       "",
+				Family:            "",
+				SpotInstance:      false,
+				ProvisioningModel: "standard",
+				PriceTier:         "ondemand",
 			},
 		},
 		"spot instance": {
@@ -104,13 +106,73 @@ func TestNewMachineSpec(t *testing.T) {
 				},
 			},
 			want: &MachineSpec{
-				Instance:     "test",
-				Zone:         "abc-123",
-				Region:       "abc",
-				MachineType:  "abc-def",
-				Family:       "abc",
-				SpotInstance: true,
-				PriceTier:    "spot",
+				Instance:          "test",
+				Zone:              "abc-123",
+				Region:            "abc",
+				MachineType:       "abc-def",
+				Family:            "abc",
+				SpotInstance:      true,
+				ProvisioningModel: "spot",
+				PriceTier:         "spot",
+			},
+		},
+		"preemptible instance": {
+			instance: &compute.Instance{
+				Name:        "test",
+				MachineType: "abc/abc-def",
+				Zone:        "testing/abc-123",
+				Scheduling: &compute.Scheduling{
+					Preemptible: true,
+				},
+			},
+			want: &MachineSpec{
+				Instance:          "test",
+				Zone:              "abc-123",
+				Region:            "abc",
+				MachineType:       "abc-def",
+				Family:            "abc",
+				SpotInstance:      true,
+				ProvisioningModel: "preemptible",
+				PriceTier:         "spot",
+			},
+		},
+		"instance without scheduling": {
+			instance: &compute.Instance{
+				Name:        "test",
+				MachineType: "abc/abc-def",
+				Zone:        "testing/abc-123",
+			},
+			want: &MachineSpec{
+				Instance:          "test",
+				Zone:              "abc-123",
+				Region:            "abc",
+				MachineType:       "abc-def",
+				Family:            "abc",
+				SpotInstance:      false,
+				ProvisioningModel: "standard",
+				PriceTier:         "ondemand",
+			},
+		},
+		"instance with creation timestamp": {
+			instance: &compute.Instance{
+				Name:              "test",
+				MachineType:       "abc/abc-def",
+				Zone:              "testing/abc-123",
+				CreationTimestamp: "2024-05-01T00:00:00.000Z",
+				Scheduling: &compute.Scheduling{
+					ProvisioningModel: "test",
+				},
+			},
+			want: &MachineSpec{
+				Instance:          "test",
+				Zone:              "abc-123",
+				Region:            "abc",
+				MachineType:       "abc-def",
+				Family:            "abc",
+				SpotInstance:      false,
+				ProvisioningModel: "standard",
+				PriceTier:         "ondemand",
+				CreatedAt:         time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
 			},
 		},
 	}
@@ -150,12 +212,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -163,12 +229,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -176,12 +246,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -189,12 +263,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -202,12 +280,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -215,12 +297,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -228,12 +314,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-east1",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -241,12 +331,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-east1",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -254,12 +348,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -267,12 +365,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -280,12 +382,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -293,12 +399,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -306,12 +416,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing-1",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -319,12 +433,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing-1",
-						"region":       "us-central1",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -332,12 +450,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-east1",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -345,12 +467,16 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_compute_instance_ram_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-east1",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -403,6 +529,10 @@ func TestCollector_Collect(t *testing.T) {
 								Name: "us-central1-a",
 							}},
 					}
+				case "/projects/testing/zones/us-central1-a/machineTypes/n1-slim", "/projects/testing/zones/us-central1-a/machineTypes/n2-slim",
+					"/projects/testing/zones/us-east1-a/machineTypes/n2-slim", "/projects/testing-1/zones/us-central1-a/machineTypes/n1-slim",
+					"/projects/testing-1/zones/us-central1-a/machineTypes/n2-slim", "/projects/testing-1/zones/us-east1-a/machineTypes/n2-slim":
+					buf = &computev1.MachineType{GuestCpus: 2, MemoryMb: 4096}
 				}
 				w.WriteHeader(http.StatusOK)
 				_ = json.NewEncoder(w).Encode(buf)
@@ -432,7 +562,7 @@ func TestCollector_Collect(t *testing.T) {
 				option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
 			)
 
-			collector := New(test.config, computeService, cloudCatalogClient)
+			collector := New(test.config, computeService, cloudCatalogClient, nil)
 
 			require.NotNil(t, collector)
 
@@ -446,8 +576,8 @@ func TestCollector_Collect(t *testing.T) {
 
 			for _, expectedMetric := range test.expectedMetrics {
 				m := utils.ReadMetrics(<-ch)
-				if strings.Contains(m.FqName, "next_scrape") {
-					// We don't have a great way right now of mocking out the time, so we just skip this metric and read the next available metric
+				for strings.Contains(m.FqName, "next_scrape") || strings.Contains(m.FqName, "refresh_interval") {
+					// We don't have a great way right now of mocking out the time, so we just skip these metrics and read the next available metric
 					m = utils.ReadMetrics(<-ch)
 				}
 				require.Equal(t, expectedMetric, m)
@@ -504,6 +634,9 @@ func TestCollector_GetPricing(t *testing.T) {
 						Name: "us-central1-a",
 					}},
 			}
+		case "/projects/testing/zones/us-central1-a/machineTypes/n1-slim", "/projects/testing/zones/us-central1-a/machineTypes/n2-slim",
+			"/projects/testing/zones/us-east1-a/machineTypes/n2-slim":
+			buf = &computev1.MachineType{GuestCpus: 2, MemoryMb: 4096}
 		}
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(buf)
@@ -514,7 +647,7 @@ func TestCollector_GetPricing(t *testing.T) {
 	// Create the collector with a nil billing service so we can override it on each test case
 	collector := New(&Config{
 		Projects: "testing",
-	}, computeService, nil)
+	}, computeService, nil, nil)
 
 	var pricingMap *StructuredPricingMap
 	t.Run("Test that the pricing map is cached", func(t *testing.T) {
@@ -585,4 +718,50 @@ func TestCollector_GetPricing(t *testing.T) {
 		require.Equal(t, 1.0, up)
 		require.NotEqual(t, pricingMap, collector.PricingMap)
 	})
+
+	t.Run("Test that a collector without projects doesn't fail", func(t *testing.T) {
+		collector.Projects = nil
+		collector.NextScrape = time.Now().Add(time.Hour)
+		ch := make(chan prometheus.Metric)
+		defer close(ch)
+		go func() {
+			for range ch {
+			}
+		}()
+		require.Equal(t, 1.0, collector.CollectMetrics(ch))
+	})
+}
+
+func TestListInstancesInZone_filter(t *testing.T) {
+	tests := map[string]struct {
+		filter string
+	}{
+		"no filter": {},
+		"label filter": {
+			filter: "labels.env=prod",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, tt.filter, r.URL.Query().Get("filter"))
+				_ = json.NewEncoder(w).Encode(&computev1.InstanceList{
+					Items: []*computev1.Instance{
+						{
+							Name:        "test-n1",
+							MachineType: "abc/n1-slim",
+							Zone:        "testing/us-central1-a",
+						},
+					},
+				})
+			}))
+			defer testServer.Close()
+			computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+			require.NoError(t, err)
+
+			instances, err := ListInstancesInZone("testing", "us-central1-a", tt.filter, computeService)
+			require.NoError(t, err)
+			require.Len(t, instances, 1)
+		})
+	}
 }
@@ -4,14 +4,24 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	"google.golang.org/api/compute/v1"
 )
 
 var (
-	re              = regexp.MustCompile(`\bin\b`)
-	GkeClusterLabel = "goog-k8s-cluster-name"
-	GkeRegionLabel  = "goog-k8s-cluster-location"
+	re               = regexp.MustCompile(`\bin\b`)
+	GkeClusterLabel  = "goog-k8s-cluster-name"
+	GkeRegionLabel   = "goog-k8s-cluster-location"
+	GkeNodePoolLabel = "goog-k8s-node-pool-name"
+)
+
+// Provisioning models of an instance. Spot and legacy preemptible VMs share the same prices, but preemptible VMs are
+// always terminated after 24 hours while Spot VMs have no maximum runtime.
+const (
+	ProvisioningModelStandard    = "standard"
+	ProvisioningModelSpot        = "spot"
+	ProvisioningModelPreemptible = "preemptible"
 )
 
 // MachineSpec is a slimmed down representation of a google compute.Instance struct
@@ -22,8 +32,16 @@ type MachineSpec struct {
 	Family       string
 	MachineType  string
 	SpotInstance bool
-	Labels       map[string]string
-	PriceTier    string
+	// ProvisioningModel is one of ProvisioningModelStandard, ProvisioningModelSpot or ProvisioningModelPreemptible.
+	ProvisioningModel string
+	Labels            map[string]string
+	PriceTier         string
+	// CreatedAt is the zero time when the creation timestamp couldn't be parsed.
+	CreatedAt time.Time
+	// Status is the lifecycle status of the instance, eg RUNNING or TERMINATED.
+	Status string
+	// Confidential is set for confidential VMs, eg AMD SEV, which are billed a premium per core and GiB.
+	Confidential bool
 }
 
 // NewMachineSpec will create a new MachineSpec from compute.Instance objects.
@@ -33,23 +51,66 @@ func NewMachineSpec(instance *compute.Instance) *MachineSpec {
 	region := getRegionFromZone(zone)
 	machineType := getMachineTypeFromURL(instance.MachineType)
 	family := getMachineFamily(machineType)
-	spot := isSpotInstance(instance.Scheduling.ProvisioningModel)
+	provisioningModel := getProvisioningModel(instance.Scheduling)
+	spot := isSpotInstance(provisioningModel)
 	priceTier := priceTierForInstance(spot)
 
 	return &MachineSpec{
-		Instance:     instance.Name,
-		Zone:         zone,
-		Region:       region,
-		MachineType:  machineType,
-		Family:       family,
-		SpotInstance: spot,
-		Labels:       instance.Labels,
-		PriceTier:    priceTier,
+		Instance:          instance.Name,
+		Zone:              zone,
+		Region:            region,
+		MachineType:       machineType,
+		Family:            family,
+		SpotInstance:      spot,
+		ProvisioningModel: provisioningModel,
+		Labels:            instance.Labels,
+		PriceTier:         priceTier,
+		CreatedAt:         getCreationTime(instance.CreationTimestamp),
+		Status:            instance.Status,
+		Confidential:      isConfidential(instance.ConfidentialInstanceConfig),
+	}
+}
+
+// isConfidential reports whether confidential computing is enabled on an instance, either with the legacy flag of AMD
+// SEV or with a confidential instance type, eg SEV_SNP or TDX.
+func isConfidential(config *compute.ConfidentialInstanceConfig) bool {
+	if config == nil {
+		return false
+	}
+	return config.EnableConfidentialCompute || (config.ConfidentialInstanceType != "" && config.ConfidentialInstanceType != "CONFIDENTIAL_INSTANCE_TYPE_UNSPECIFIED")
+}
+
+// getProvisioningModel derives the provisioning model from the scheduling block of an instance. Spot VMs set the
+// provisioning model to SPOT, while legacy preemptible VMs only set the preemptible flag.
+func getProvisioningModel(scheduling *compute.Scheduling) string {
+	switch {
+	case scheduling == nil:
+		return ProvisioningModelStandard
+	case scheduling.ProvisioningModel == "SPOT":
+		return ProvisioningModelSpot
+	case scheduling.Preemptible:
+		return ProvisioningModelPreemptible
+	default:
+		return ProvisioningModelStandard
 	}
 }
 
-func isSpotInstance(model string) bool {
-	return model == "SPOT"
+// getCreationTime parses the RFC3339 creation timestamp of an instance. An empty or malformed timestamp results in the zero time.
+func getCreationTime(timestamp string) time.Time {
+	if timestamp == "" {
+		return time.Time{}
+	}
+	createdAt, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		log.Printf("Could not parse creation timestamp %s: %s", timestamp, err)
+		return time.Time{}
+	}
+	return createdAt
+}
+
+// isSpotInstance reports whether an instance is billed at spot prices, which is the case for preemptible VMs as well.
+func isSpotInstance(provisioningModel string) bool {
+	return provisioningModel == ProvisioningModelSpot || provisioningModel == ProvisioningModelPreemptible
 }
 
 func getRegionFromZone(zone string) string {
@@ -112,3 +173,10 @@ func (m *MachineSpec) GetClusterName() string {
 	}
 	return ""
 }
+
+func (m *MachineSpec) GetNodePoolName() string {
+	if nodePoolName, ok := m.Labels[GkeNodePoolLabel]; ok {
+		return nodePoolName
+	}
+	return ""
+}
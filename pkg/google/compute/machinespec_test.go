@@ -2,6 +2,7 @@ package compute
 
 import (
 	"testing"
+	"time"
 )
 
 func Test_stripOutKeyFromDescription(t *testing.T) {
@@ -109,3 +110,30 @@ func Test_GetMachineFamily(t *testing.T) {
 		})
 	}
 }
+
+func Test_getCreationTime(t *testing.T) {
+	tests := map[string]struct {
+		timestamp string
+		want      int64
+	}{
+		"empty": {
+			timestamp: "",
+			want:      time.Time{}.Unix(),
+		},
+		"malformed": {
+			timestamp: "yesterday",
+			want:      time.Time{}.Unix(),
+		},
+		"with offset": {
+			timestamp: "2024-05-01T03:00:00.123-07:00",
+			want:      1714557600,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := getCreationTime(test.timestamp).Unix(); got != test.want {
+				t.Errorf("getCreationTime() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
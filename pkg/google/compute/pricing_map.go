@@ -4,11 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"regexp"
 	"strings"
 
 	"cloud.google.com/go/billing/apiv1/billingpb"
 
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
@@ -20,22 +21,6 @@ var (
 	PricingDataIsOff   = errors.New("pricing data in sku isn't parsable")
 	RegionNotFound     = errors.New("region wasn't found in pricing map")
 	FamilyTypeNotFound = errors.New("family wasn't found in pricing map for this region")
-	spotRegex          = `(?P<spot>Spot Preemptible )`
-	machineTypeRegex   = `(?P<machineType>\w{1,3})`
-	amd                = `(?P<amd> AMD)`
-	n1Suffix           = `(?: Predefined)`
-	resource           = `(?P<resource>Core|Ram)`
-	regionRegex        = `\w+(?: \w+){0,2}`
-	computeOptimized   = `(?P<optimized> ?Compute optimized)`
-	onDemandString     = fmt.Sprintf(`^%v?(?:%v|%v)%v?%v?(?: Instance)? %v running in %v$`,
-		spotRegex,
-		machineTypeRegex,
-		computeOptimized,
-		n1Suffix,
-		amd,
-		resource,
-		regionRegex)
-	reOnDemand = regexp.MustCompile(onDemandString)
 )
 
 type PriceTier int64
@@ -59,6 +44,8 @@ type ParsedSkuData struct {
 	Price           int32
 	Description     string
 	ComputeResource Resource
+	// Confidential is set for the SKUs of the premium of confidential VMs.
+	Confidential bool
 }
 
 func NewParsedSkuData(region string, priceTier PriceTier, price int32, description string, computeResource Resource) *ParsedSkuData {
@@ -79,6 +66,10 @@ type Prices struct {
 type PriceTiers struct {
 	OnDemand Prices
 	Spot     Prices
+	// ConfidentialOnDemand and ConfidentialSpot are the premiums of confidential VMs, which are billed on top of the
+	// price of the family. They're zero for the families without confidential VMs.
+	ConfidentialOnDemand Prices
+	ConfidentialSpot     Prices
 }
 
 func NewPriceTiers() *PriceTiers {
@@ -122,6 +113,12 @@ func NewMachineTypePricing() *FamilyPricing {
 // StoragePricing is a map where the key is the storage type and the value is the price
 type StoragePricing struct {
 	Storage map[string]float64
+	// Regional holds the prices of the regional persistent disks, which are replicated across two zones of the region
+	// and priced separately from the zonal ones. It's nil when the region has no regional disk pricing.
+	Regional map[string]float64
+	// Snapshot holds the prices of the snapshots of the persistent disks stored in the region or multi-region by
+	// snapshot type, SnapshotStandard or SnapshotArchive. It's nil when the location has no snapshot pricing.
+	Snapshot map[string]float64
 }
 
 func NewStoragePricing() *StoragePricing {
@@ -141,9 +138,12 @@ func (m StructuredPricingMap) GetCostOfInstance(instance *MachineSpec) (float64,
 		return 0, 0, fmt.Errorf("%w: %s", FamilyTypeNotFound, instance.Family)
 	}
 	priceTiers := m.Compute[instance.Region].Family[instance.Family]
-	computePrices := priceTiers.OnDemand
+	computePrices, premium := priceTiers.OnDemand, priceTiers.ConfidentialOnDemand
 	if instance.SpotInstance {
-		computePrices = priceTiers.Spot
+		computePrices, premium = priceTiers.Spot, priceTiers.ConfidentialSpot
+	}
+	if instance.Confidential {
+		return computePrices.Cpu + premium.Cpu, computePrices.Ram + premium.Ram, nil
 	}
 
 	return computePrices.Cpu, computePrices.Ram, nil
@@ -162,6 +162,35 @@ func (m StructuredPricingMap) GetCostOfStorage(region, storageClass string) (flo
 	return m.Storage[region].Storage[storageClass], nil
 }
 
+// GetCostOfSnapshot returns the price of a GiB of a snapshot of snapshotType stored in location, a region or a
+// multi-region, for an hour.
+func (m StructuredPricingMap) GetCostOfSnapshot(location, snapshotType string) (float64, error) {
+	if len(m.Storage) == 0 {
+		return 0, RegionNotFound
+	}
+	if _, ok := m.Storage[location]; !ok {
+		return 0, fmt.Errorf("%w: %s", RegionNotFound, location)
+	}
+	if _, ok := m.Storage[location].Snapshot[snapshotType]; !ok {
+		return 0, fmt.Errorf("%w: %s snapshot", FamilyTypeNotFound, snapshotType)
+	}
+	return m.Storage[location].Snapshot[snapshotType], nil
+}
+
+// GetCostOfRegionalStorage returns the price of a GiB of a regional persistent disk of storageClass for an hour.
+func (m StructuredPricingMap) GetCostOfRegionalStorage(region, storageClass string) (float64, error) {
+	if len(m.Storage) == 0 {
+		return 0, RegionNotFound
+	}
+	if _, ok := m.Storage[region]; !ok {
+		return 0, fmt.Errorf("%w: %s", RegionNotFound, region)
+	}
+	if _, ok := m.Storage[region].Regional[storageClass]; !ok {
+		return 0, fmt.Errorf("%w: %s", FamilyTypeNotFound, storageClass)
+	}
+	return m.Storage[region].Regional[storageClass], nil
+}
+
 var (
 	storageClasses = map[string]string{
 		"Storage PD Capacity":    "pd-standard",
@@ -171,6 +200,49 @@ var (
 	}
 )
 
+// usageUnitByResource is the usage unit the prices of each resource are expected in, as they are added to the pricing
+// map as is for compute and divided by the hours in a month for storage.
+var usageUnitByResource = map[Resource]string{
+	Cpu:     "h",
+	Ram:     "GiBy.h",
+	Storage: "GiBy.mo",
+}
+
+const (
+	// maxComputeUnitPrice is the highest sane price of a vCPU or a GiB of memory in USD/h, and maxStorageUnitPrice of
+	// a GiB of persistent disk in USD/month. Both are an order of magnitude above the list prices.
+	maxComputeUnitPrice = 1.0
+	maxStorageUnitPrice = 5.0
+
+	priceSourceOnDemand = "ondemand"
+	priceSourceSpot     = "spot"
+	priceSourceStorage  = "storage"
+)
+
+// regionalStoragePrefix prefixes the description of the skus of regional persistent disks, eg "Regional Balanced PD Capacity".
+const regionalStoragePrefix = "Regional "
+
+const (
+	// SnapshotStandard and SnapshotArchive are the types of the snapshots of the persistent disks, as returned by the
+	// snapshots.list API. Archive snapshots are cheaper to store but billed for their retrieval.
+	SnapshotStandard = "STANDARD"
+	SnapshotArchive  = "ARCHIVE"
+)
+
+// snapshotType returns the type of the snapshots whose storage is priced by the sku of description, eg "Storage PD
+// Snapshot in Iowa" or "Storage PD Archive Snapshot". Instant snapshots are stored along with their disk and priced
+// separately, so they're not snapshot storage.
+func snapshotType(description string) (string, bool) {
+	switch {
+	case !strings.Contains(description, "Snapshot") || strings.Contains(description, "Instant"):
+		return "", false
+	case strings.Contains(description, "Archive"):
+		return SnapshotArchive, true
+	default:
+		return SnapshotStandard, true
+	}
+}
+
 func GeneratePricingMap(skus []*billingpb.Sku) (*StructuredPricingMap, error) {
 	if len(skus) == 0 {
 		return &StructuredPricingMap{}, SkuNotFound
@@ -202,19 +274,20 @@ func GeneratePricingMap(skus []*billingpb.Sku) (*StructuredPricingMap, error) {
 				}
 				floatPrice := float64(data.Price) * 1e-9
 				priceTier := pricingMap.Compute[data.Region].Family[data.Description]
-				if data.PriceTier == Spot {
-					if data.ComputeResource == Ram {
-						priceTier.Spot.Ram = floatPrice
-						continue
-					}
-					priceTier.Spot.Cpu = floatPrice
-					continue
+				prices := &priceTier.OnDemand
+				switch {
+				case data.Confidential && data.PriceTier == Spot:
+					prices = &priceTier.ConfidentialSpot
+				case data.Confidential:
+					prices = &priceTier.ConfidentialOnDemand
+				case data.PriceTier == Spot:
+					prices = &priceTier.Spot
 				}
 				if data.ComputeResource == Ram {
-					priceTier.OnDemand.Ram = floatPrice
+					prices.Ram = floatPrice
 					continue
 				}
-				priceTier.OnDemand.Cpu = floatPrice
+				prices.Cpu = floatPrice
 			case Storage:
 				// Right now this is somewhat tightly coupled to GKE persistent volumes.
 				// In GKE you can only provision the following classes: https://cloud.google.com/kubernetes-engine/docs/how-to/persistent-volumes/gce-pd-csi-driver#create_a_storageclass
@@ -224,12 +297,24 @@ func GeneratePricingMap(skus []*billingpb.Sku) (*StructuredPricingMap, error) {
 				if _, ok := pricingMap.Storage[data.Region]; !ok {
 					pricingMap.Storage[data.Region] = NewStoragePricing()
 				}
+				description, regional := strings.CutPrefix(data.Description, regionalStoragePrefix)
+				if snapshot, ok := snapshotType(description); ok {
+					if pricingMap.Storage[data.Region].Snapshot == nil {
+						pricingMap.Storage[data.Region].Snapshot = map[string]float64{}
+					}
+					if pricingMap.Storage[data.Region].Snapshot[snapshot] != 0 {
+						log.Printf("Snapshot type %s already exists in region %s", snapshot, data.Region)
+						continue
+					}
+					pricingMap.Storage[data.Region].Snapshot[snapshot] = float64(data.Price) * 1e-9 / utils.HoursInMonth
+					continue
+				}
 				storageClass := ""
-				for description, sc := range storageClasses {
+				for prefix, sc := range storageClasses {
 					// We check to see if the description starts with the storage class name
 					// This is primarily because this could return a false positive in cases of Regional storage which
 					// has a similar description.
-					if strings.Index(data.Description, description) == 0 {
+					if strings.Index(description, prefix) == 0 {
 						storageClass = sc
 						// Break to prevent overwritting the storage class
 						break
@@ -239,11 +324,18 @@ func GeneratePricingMap(skus []*billingpb.Sku) (*StructuredPricingMap, error) {
 					log.Printf("Storage class not found for %s. Skipping", data.Description)
 					continue
 				}
-				if pricingMap.Storage[data.Region].Storage[storageClass] != 0 {
+				prices := pricingMap.Storage[data.Region].Storage
+				if regional {
+					if pricingMap.Storage[data.Region].Regional == nil {
+						pricingMap.Storage[data.Region].Regional = map[string]float64{}
+					}
+					prices = pricingMap.Storage[data.Region].Regional
+				}
+				if prices[storageClass] != 0 {
 					log.Printf("Storage class %s already exists in region %s", storageClass, data.Region)
 					continue
 				}
-				pricingMap.Storage[data.Region].Storage[storageClass] = float64(data.Price) * 1e-9 / utils.HoursInMonth
+				prices[storageClass] = float64(data.Price) * 1e-9 / utils.HoursInMonth
 			}
 		}
 	}
@@ -260,6 +352,12 @@ var ignoreList = []string{
 	"Micro Instance",
 	"Small Instance",
 	"Memory-optimized",
+	// Extended memory and premium SKUs share the family of the standard SKUs, they'd overwrite the standard price
+	"Extended",
+	"Premium",
+	// The early deletion and the retrieval of archive snapshots are billed per GiB rather than per GiB and month
+	"Early Deletion",
+	"Retrieval",
 }
 
 func getDataFromSku(sku *billingpb.Sku) ([]*ParsedSkuData, error) {
@@ -274,41 +372,50 @@ func getDataFromSku(sku *billingpb.Sku) ([]*ParsedSkuData, error) {
 			return nil, SkuNotRelevant
 		}
 	}
+	if isIrrelevantCategory(sku.Category) {
+		return nil, SkuNotRelevant
+	}
 
-	if matches := reOnDemand.FindStringSubmatch(sku.Description); len(matches) > 0 {
-		price, err := getPricingInfoFromSku(sku)
+	if sku.Category != nil && sku.Category.ResourceFamily == "Storage" {
+		price, err := getLastTierPriceFromSku(sku)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %w", PricingDataIsOff, err)
 		}
-		matchMap := getMatchMap(reOnDemand, matches)
-		machineType := strings.ToLower(matchMap["machineType"])
-		if matchMap["optimized"] != "" {
-			machineType = "c2"
-		}
-		priceTier := OnDemand
-		if matchMap["spot"] != "" {
-			priceTier = Spot
+		if err := validatePrice(sku, priceSourceStorage, usageUnitByResource[Storage], price, maxStorageUnitPrice); err != nil {
+			return nil, err
 		}
 		for _, region := range sku.ServiceRegions {
 			parsedSku := NewParsedSkuData(
 				region,
-				priceTier,
+				OnDemand,
 				price,
-				machineType,
-				getResourceType(matchMap["resource"]))
+				sku.Description,
+				Storage)
 			parsedSkus = append(parsedSkus, parsedSku)
 		}
 		return parsedSkus, nil
 	}
-	if sku.Category != nil && sku.Category.ResourceFamily == "Storage" {
-		price := sku.PricingInfo[0].PricingExpression.TieredRates[len(sku.PricingInfo[0].PricingExpression.TieredRates)-1].UnitPrice.Nanos
+
+	if computeSku, ok := parseComputeSku(sku); ok {
+		price, err := getPricingInfoFromSku(sku)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", PricingDataIsOff, err)
+		}
+		source := priceSourceOnDemand
+		if computeSku.priceTier == Spot {
+			source = priceSourceSpot
+		}
+		if err := validatePrice(sku, source, usageUnitByResource[computeSku.resource], price, maxComputeUnitPrice); err != nil {
+			return nil, err
+		}
 		for _, region := range sku.ServiceRegions {
 			parsedSku := NewParsedSkuData(
 				region,
-				OnDemand,
+				computeSku.priceTier,
 				price,
-				sku.Description,
-				Storage)
+				computeSku.family,
+				computeSku.resource)
+			parsedSku.Confidential = computeSku.confidential
 			parsedSkus = append(parsedSkus, parsedSku)
 		}
 		return parsedSkus, nil
@@ -317,36 +424,114 @@ func getDataFromSku(sku *billingpb.Sku) ([]*ParsedSkuData, error) {
 	return nil, SkuNotParsable
 }
 
-// getResourceType will return the resource type for a given resource.
-// TODO: Need to ensure GPU's are handled as well, or at the very least we're not mixing GPU's and CPU's up.
-func getResourceType(resource string) Resource {
-	if resource == "Ram" {
-		return Ram
-	}
-	return Cpu
-}
-
 // getPricingInfoFromSku will return the pricing for a given sku.
 // Pricing is represented in nanos, so we need to divide by 1e9 to get the price in dollars.
 // If there are multiple pricing options, we'll just take the first one.
 func getPricingInfoFromSku(sku *billingpb.Sku) (int32, error) {
+	tieredRates, err := getTieredRatesFromSku(sku)
+	if err != nil {
+		return 0, err
+	}
+	// TODO: We need to consider if there are many teired rates here. For instance, Storage will have a standard disk that has two rates. The first one is zero for the first GiB, then $/GiB after.
+	return tieredRates[0].UnitPrice.Nanos, nil
+}
+
+// getLastTierPriceFromSku returns the price of the last tier of a sku in nanos, which is the rate charged past any free tier.
+func getLastTierPriceFromSku(sku *billingpb.Sku) (int32, error) {
+	tieredRates, err := getTieredRatesFromSku(sku)
+	if err != nil {
+		return 0, err
+	}
+	return tieredRates[len(tieredRates)-1].UnitPrice.Nanos, nil
+}
+
+// validatePrice checks that the price of a sku is per usageUnit, in USD and sane before it's added to the pricing map.
+// The usage unit and the currency are only checked when they're set. A rejected price is counted in
+// MalformedPriceEntriesTotal and PricingDataIsOff is returned, so that the sku is skipped.
+func validatePrice(sku *billingpb.Sku, source string, usageUnit string, nanos int32, max float64) error {
+	expression := sku.PricingInfo[0].PricingExpression
+	reason := ""
+	switch {
+	case expression.UsageUnit != "" && expression.UsageUnit != usageUnit:
+		reason = utils.ReasonUnexpectedUnit
+	case !pricedInUSD(expression.TieredRates):
+		reason = utils.ReasonUnexpectedCurrency
+	// Only the nanos of a price are read, so a price of a dollar or more per unit would be truncated
+	case !utils.SanePrice(float64(nanos)*1e-9, max) || hasWholeUnits(expression.TieredRates):
+		reason = utils.ReasonOutlier
+	default:
+		return nil
+	}
+	MalformedPriceEntriesTotal.WithLabelValues(source, reason).Inc()
+	return fmt.Errorf("%w: %s of sku %s", PricingDataIsOff, strings.ReplaceAll(reason, "_", " "), sku.Description)
+}
+
+func pricedInUSD(rates []*billingpb.PricingExpression_TierRate) bool {
+	for _, rate := range rates {
+		if rate.UnitPrice.CurrencyCode != "" && rate.UnitPrice.CurrencyCode != "USD" {
+			return false
+		}
+	}
+	return true
+}
+
+func hasWholeUnits(rates []*billingpb.PricingExpression_TierRate) bool {
+	for _, rate := range rates {
+		if rate.UnitPrice.Units != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func getTieredRatesFromSku(sku *billingpb.Sku) ([]*billingpb.PricingExpression_TierRate, error) {
 	if len(sku.PricingInfo) == 0 {
-		return 0, fmt.Errorf("no pricing info found for sku %s", sku.Name)
+		return nil, fmt.Errorf("no pricing info found for sku %s", sku.Name)
 	}
 	pricingInfo := sku.PricingInfo[0]
-	if pricingInfo.PricingExpression.TieredRates == nil || len(pricingInfo.PricingExpression.TieredRates) < 1 {
-		return 0, fmt.Errorf("no tiered rates found for sku %s", sku.Name)
+	if pricingInfo.PricingExpression == nil || len(pricingInfo.PricingExpression.TieredRates) < 1 {
+		return nil, fmt.Errorf("no tiered rates found for sku %s", sku.Name)
 	}
-	// TODO: We need to consider if there are many teired rates here. For instance, Storage will have a standard disk that has two rates. The first one is zero for the first GiB, then $/GiB after.
-	return pricingInfo.PricingExpression.TieredRates[0].UnitPrice.Nanos, nil
+	for _, rate := range pricingInfo.PricingExpression.TieredRates {
+		if rate.UnitPrice == nil {
+			return nil, fmt.Errorf("tiered rate without a unit price found for sku %s", sku.Name)
+		}
+	}
+	return pricingInfo.PricingExpression.TieredRates, nil
 }
 
-func getMatchMap(regex *regexp.Regexp, match []string) map[string]string {
-	result := make(map[string]string)
-	for i, name := range regex.SubexpNames() {
-		if i != 0 && name != "" {
-			result[name] = match[i]
+// PriceForInstance returns the price of the cores and memory of a machine type in region at tier, which makes the
+// pricing map a pricing.Source. Machine types are priced by family, so the price of the whole instance isn't known.
+func (m StructuredPricingMap) PriceForInstance(region string, machineType string, tier string) (pricing.Price, error) {
+	spec := &MachineSpec{
+		Region:       region,
+		MachineType:  machineType,
+		Family:       getMachineFamily(machineType),
+		SpotInstance: tier == pricing.TierSpot,
+	}
+	cpu, ram, err := m.GetCostOfInstance(spec)
+	if err != nil {
+		return pricing.Price{}, fmt.Errorf("%w: %w", pricing.ErrPriceNotFound, err)
+	}
+	return pricing.Price{USDPerCoreHour: cpu, USDPerGiBHour: ram}, nil
+}
+
+// HistoryPrices returns the cpu and memory prices of every machine family of the map. The price tiers a family isn't
+// priced at, eg spot for the families without spot VMs, are left out rather than recorded at 0.
+func (m StructuredPricingMap) HistoryPrices() []pricehistory.Price {
+	var prices []pricehistory.Price
+	for region, family := range m.Compute {
+		for name, tiers := range family.Family {
+			for priceTier, price := range map[string]Prices{"ondemand": tiers.OnDemand, "spot": tiers.Spot} {
+				if price == (Prices{}) {
+					continue
+				}
+				prices = append(prices,
+					pricehistory.Price{Region: region, SKU: name, PriceTier: priceTier, Unit: "usd_per_core_hour", Value: price.Cpu},
+					pricehistory.Price{Region: region, SKU: name, PriceTier: priceTier, Unit: "usd_per_gib_hour", Value: price.Ram},
+				)
+			}
 		}
 	}
-	return result
+	return prices
 }
@@ -3,16 +3,20 @@ package compute
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/genproto/googleapis/type/money"
 
 	"cloud.google.com/go/billing/apiv1/billingpb"
 
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
@@ -93,6 +97,28 @@ func TestStructuredPricingMap_GetCostOfInstance(t *testing.T) {
 				SpotInstance: true,
 			},
 		},
+		{
+			name: "confidential",
+			pm: StructuredPricingMap{
+				Compute: map[string]*FamilyPricing{
+					"region": {
+						Family: map[string]*PriceTiers{
+							"family": {
+								OnDemand:             Prices{Cpu: 1, Ram: 2},
+								ConfidentialOnDemand: Prices{Cpu: 0.5, Ram: 0.25},
+							},
+						},
+					},
+				},
+			},
+			ms: &MachineSpec{
+				Region:       "region",
+				Family:       "family",
+				Confidential: true,
+			},
+			expectedCPUPrice: 1.5,
+			expectedRAMPRice: 2.25,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			c, r, err := tc.pm.GetCostOfInstance(tc.ms)
@@ -199,6 +225,115 @@ func TestGeneratePricingMap(t *testing.T) {
 				Storage: map[string]*StoragePricing{},
 			},
 		},
+		{
+			// The confidential skus come last so that they'd overwrite the standard price if they were parsed as such
+			name: "confidential premiums don't overwrite the standard price",
+			skus: []*billingpb.Sku{
+				{
+					Description:    "N2D AMD Instance Core running in Americas",
+					Category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"},
+					ServiceRegions: []string{"us-central1"},
+					PricingInfo: []*billingpb.PricingInfo{{
+						PricingExpression: &billingpb.PricingExpression{
+							TieredRates: []*billingpb.PricingExpression_TierRate{{
+								UnitPrice: &money.Money{
+									Nanos: 1e8,
+								},
+							}},
+						},
+					}},
+				},
+				{
+					Description:    "N2D AMD Confidential Computing Instance Core running in Americas",
+					Category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"},
+					ServiceRegions: []string{"us-central1"},
+					PricingInfo: []*billingpb.PricingInfo{{
+						PricingExpression: &billingpb.PricingExpression{
+							TieredRates: []*billingpb.PricingExpression_TierRate{{
+								UnitPrice: &money.Money{
+									Nanos: 1e7,
+								},
+							}},
+						},
+					}},
+				},
+			},
+			expectedPricingMap: &StructuredPricingMap{
+				Compute: map[string]*FamilyPricing{
+					"us-central1": {
+						Family: map[string]*PriceTiers{
+							"n2d": {
+								OnDemand:             Prices{Cpu: 0.1},
+								ConfidentialOnDemand: Prices{Cpu: 0.01},
+							},
+						},
+					},
+				},
+				Storage: map[string]*StoragePricing{},
+			},
+		},
+		{
+			// The extended and premium skus come last so that they'd overwrite the standard price if they were parsed
+			name: "extended and premium ram don't overwrite the standard ram price",
+			skus: []*billingpb.Sku{
+				{
+					Description:    "N2 Instance Ram running in Americas",
+					Category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"},
+					ServiceRegions: []string{"us-central1"},
+					PricingInfo: []*billingpb.PricingInfo{{
+						PricingExpression: &billingpb.PricingExpression{
+							TieredRates: []*billingpb.PricingExpression_TierRate{{
+								UnitPrice: &money.Money{
+									Nanos: 1e8,
+								},
+							}},
+						},
+					}},
+				},
+				{
+					Description:    "N2 Extended Instance Ram running in Americas",
+					Category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"},
+					ServiceRegions: []string{"us-central1"},
+					PricingInfo: []*billingpb.PricingInfo{{
+						PricingExpression: &billingpb.PricingExpression{
+							TieredRates: []*billingpb.PricingExpression_TierRate{{
+								UnitPrice: &money.Money{
+									Nanos: 5e8,
+								},
+							}},
+						},
+					}},
+				},
+				{
+					Description:    "N2 Instance Ram Premium running in Americas",
+					Category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"},
+					ServiceRegions: []string{"us-central1"},
+					PricingInfo: []*billingpb.PricingInfo{{
+						PricingExpression: &billingpb.PricingExpression{
+							TieredRates: []*billingpb.PricingExpression_TierRate{{
+								UnitPrice: &money.Money{
+									Nanos: 7e8,
+								},
+							}},
+						},
+					}},
+				},
+			},
+			expectedPricingMap: &StructuredPricingMap{
+				Compute: map[string]*FamilyPricing{
+					"us-central1": {
+						Family: map[string]*PriceTiers{
+							"n2": {
+								OnDemand: Prices{
+									Ram: 0.1,
+								},
+							},
+						},
+					},
+				},
+				Storage: map[string]*StoragePricing{},
+			},
+		},
 		{
 			name: "on-demand cpu - multiple regions",
 			skus: []*billingpb.Sku{{
@@ -391,6 +526,78 @@ func TestGeneratePricingMap(t *testing.T) {
 						Storage: map[string]float64{
 							"pd-balanced": 1.0 / utils.HoursInMonth,
 						},
+						Regional: map[string]float64{
+							"pd-balanced": 2.0 / utils.HoursInMonth,
+						},
+					},
+				},
+				Compute: map[string]*FamilyPricing{},
+			},
+		},
+		{
+			name: "Snapshot Pricing",
+			skus: []*billingpb.Sku{{
+				Description:    "Storage PD Snapshot in US",
+				Category:       &billingpb.Category{ResourceFamily: "Storage"},
+				ServiceRegions: []string{"us"},
+				PricingInfo: []*billingpb.PricingInfo{{
+					PricingExpression: &billingpb.PricingExpression{
+						TieredRates: []*billingpb.PricingExpression_TierRate{{
+							UnitPrice: &money.Money{
+								Nanos: 1e9,
+							},
+						}},
+					},
+				}},
+			}, {
+				Description:    "Storage PD Archive Snapshot in US",
+				Category:       &billingpb.Category{ResourceFamily: "Storage"},
+				ServiceRegions: []string{"us"},
+				PricingInfo: []*billingpb.PricingInfo{{
+					PricingExpression: &billingpb.PricingExpression{
+						TieredRates: []*billingpb.PricingExpression_TierRate{{
+							UnitPrice: &money.Money{
+								Nanos: 1e8,
+							},
+						}},
+					},
+				}},
+			}, {
+				// Instant snapshots are stored along with their disk, they're not snapshot storage
+				Description:    "Instant Snapshot PD Capacity in US",
+				Category:       &billingpb.Category{ResourceFamily: "Storage"},
+				ServiceRegions: []string{"us"},
+				PricingInfo: []*billingpb.PricingInfo{{
+					PricingExpression: &billingpb.PricingExpression{
+						TieredRates: []*billingpb.PricingExpression_TierRate{{
+							UnitPrice: &money.Money{
+								Nanos: 5e8,
+							},
+						}},
+					},
+				}},
+			}, {
+				Description:    "Storage PD Archive Snapshot Retrieval in US",
+				Category:       &billingpb.Category{ResourceFamily: "Storage"},
+				ServiceRegions: []string{"us"},
+				PricingInfo: []*billingpb.PricingInfo{{
+					PricingExpression: &billingpb.PricingExpression{
+						TieredRates: []*billingpb.PricingExpression_TierRate{{
+							UnitPrice: &money.Money{
+								Nanos: 2e9,
+							},
+						}},
+					},
+				}},
+			}},
+			expectedPricingMap: &StructuredPricingMap{
+				Storage: map[string]*StoragePricing{
+					"us": {
+						Storage: map[string]float64{},
+						Snapshot: map[string]float64{
+							SnapshotStandard: 1.0 / utils.HoursInMonth,
+							SnapshotArchive:  1e8 * 1e-9 / utils.HoursInMonth,
+						},
 					},
 				},
 				Compute: map[string]*FamilyPricing{},
@@ -460,6 +667,9 @@ func TestGeneratePricingMap(t *testing.T) {
 						Storage: map[string]float64{
 							"pd-ssd": 187000000 * 1e-9 / utils.HoursInMonth,
 						},
+						Regional: map[string]float64{
+							"pd-ssd": 187000000 * 2 * 1e-9 / utils.HoursInMonth,
+						},
 					},
 				},
 				Compute: map[string]*FamilyPricing{},
@@ -521,11 +731,41 @@ func Test_getDataFromSku_sadPaths(t *testing.T) {
 	require.ErrorIs(t, err, SkuNotRelevant)
 }
 
+func TestFilterSkus(t *testing.T) {
+	skus := []*billingpb.Sku{
+		{Description: "N2 Instance Core running in Belgium"},
+		{Description: "Spot Preemptible N2D AMD Instance Ram running in Belgium"},
+		{Description: "H3 Instance Core running in Belgium"},
+		{Description: "Balanced PD Capacity in Belgium", Category: &billingpb.Category{ResourceFamily: "Storage"}},
+	}
+	descriptions := func(skus []*billingpb.Sku) []string {
+		var descriptions []string
+		for _, sku := range skus {
+			descriptions = append(descriptions, sku.Description)
+		}
+		return descriptions
+	}
+
+	assert.Equal(t, skus, FilterSkus(skus, nil))
+	assert.Equal(t, []string{
+		"N2 Instance Core running in Belgium",
+		"Spot Preemptible N2D AMD Instance Ram running in Belgium",
+		"Balanced PD Capacity in Belgium",
+	}, descriptions(FilterSkus(skus, &pricing.FamilyFilter{Include: []string{"N2*"}})))
+	assert.Equal(t, []string{
+		"N2 Instance Core running in Belgium",
+		"Balanced PD Capacity in Belgium",
+	}, descriptions(FilterSkus(skus, &pricing.FamilyFilter{Exclude: []string{"h3", "n2d"}})))
+}
+
 func Test_getDataFromSku(t *testing.T) {
 	tests := map[string]struct {
 		description       string
+		category          *billingpb.Category
+		usageUnit         string
 		serviceCompute    []string
 		price             int32
+		currency          string
 		wantParsedSkuData []*ParsedSkuData
 		wantError         error
 	}{
@@ -578,6 +818,17 @@ func Test_getDataFromSku(t *testing.T) {
 			wantParsedSkuData: []*ParsedSkuData{NewParsedSkuData("europe-west1", Spot, 12, "e2", Cpu)},
 			wantError:         nil,
 		},
+		"Confidential premium": {
+			description:    "N2D AMD Confidential Computing Instance Core running in Belgium",
+			serviceCompute: []string{"europe-west1"},
+			price:          12,
+			wantParsedSkuData: []*ParsedSkuData{func() *ParsedSkuData {
+				data := NewParsedSkuData("europe-west1", OnDemand, 12, "n2d", Cpu)
+				data.Confidential = true
+				return data
+			}()},
+			wantError: nil,
+		},
 		"Ignore GPU": {
 			description:       "Nvidia L4 GPU attached to Spot Preemptible VMs running in Hong Kong",
 			serviceCompute:    []string{"europe-west1"},
@@ -641,20 +892,105 @@ func Test_getDataFromSku(t *testing.T) {
 			wantParsedSkuData: nil,
 			wantError:         SkuNotRelevant,
 		},
+		"Ignore Extended": {
+			description:       "N2 Extended Instance Ram running in Americas",
+			category:          &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"},
+			serviceCompute:    []string{"us-central1"},
+			price:             12,
+			wantParsedSkuData: nil,
+			wantError:         SkuNotRelevant,
+		},
+		"Ignore Premium": {
+			description:       "N1 Predefined Instance Ram Premium running in Americas",
+			category:          &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"},
+			serviceCompute:    []string{"us-central1"},
+			price:             12,
+			wantParsedSkuData: nil,
+			wantError:         SkuNotRelevant,
+		},
 		"Not parsable": {
 			description: "No more guava's allowed in the codebase",
 			wantError:   SkuNotParsable,
 		},
+		"Arm": {
+			description:       "C4A Arm Instance Core running in Americas",
+			category:          &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"},
+			serviceCompute:    []string{"us-central1"},
+			price:             12,
+			wantParsedSkuData: []*ParsedSkuData{NewParsedSkuData("us-central1", OnDemand, 12, "c4a", Cpu)},
+		},
+		"Resource is taken from the category over the description": {
+			description:       "N4 Instance Memory running in Americas",
+			category:          &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"},
+			serviceCompute:    []string{"us-central1"},
+			price:             12,
+			wantParsedSkuData: []*ParsedSkuData{NewParsedSkuData("us-central1", OnDemand, 12, "n4", Ram)},
+		},
+		"Resource is taken from the usage unit for machine series groups": {
+			description:       "N1 Predefined Instance Memory running in Americas",
+			category:          &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "N1Standard", UsageType: "OnDemand"},
+			usageUnit:         "GiBy.h",
+			serviceCompute:    []string{"us-central1"},
+			price:             12,
+			wantParsedSkuData: []*ParsedSkuData{NewParsedSkuData("us-central1", OnDemand, 12, "n1", Ram)},
+		},
+		"Spot usage type without the spot prefix": {
+			description:       "N2 Instance Core running in Americas",
+			category:          &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "Preemptible"},
+			serviceCompute:    []string{"us-central1"},
+			price:             12,
+			wantParsedSkuData: []*ParsedSkuData{NewParsedSkuData("us-central1", Spot, 12, "n2", Cpu)},
+		},
+		"Reject a price per an unexpected unit": {
+			description:    "N2 Instance Core running in Americas",
+			category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"},
+			usageUnit:      "GiBy.h",
+			serviceCompute: []string{"us-central1"},
+			price:          12,
+			wantError:      PricingDataIsOff,
+		},
+		"Reject a price in another currency": {
+			description:    "N2 Instance Core running in Americas",
+			category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"},
+			serviceCompute: []string{"us-central1"},
+			price:          12,
+			currency:       "EUR",
+			wantError:      PricingDataIsOff,
+		},
+		"Reject a zero price": {
+			description:    "N2 Instance Core running in Americas",
+			category:       &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"},
+			serviceCompute: []string{"us-central1"},
+			wantError:      PricingDataIsOff,
+		},
+		"Ignore commitments by usage type": {
+			description: "N2 Instance Core running in Americas",
+			category:    &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "Commit1Yr"},
+			wantError:   SkuNotRelevant,
+		},
+		"Ignore GPUs by resource group": {
+			description: "A3 Instance Core running in Americas",
+			category:    &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "GPU", UsageType: "OnDemand"},
+			wantError:   SkuNotRelevant,
+		},
+		"Ignore licenses by resource family": {
+			description: "Licensing Fee for SLES 12 running in Americas",
+			category:    &billingpb.Category{ResourceFamily: "License", ResourceGroup: "SuseSles", UsageType: "OnDemand"},
+			wantError:   SkuNotRelevant,
+		},
 	}
 	for name, tt := range tests {
 		sku := &billingpb.Sku{
 			Description:    tt.description,
+			Category:       tt.category,
 			ServiceRegions: tt.serviceCompute,
 			PricingInfo: []*billingpb.PricingInfo{{
 				PricingExpression: &billingpb.PricingExpression{
+					UsageUnit: tt.usageUnit,
 					TieredRates: []*billingpb.PricingExpression_TierRate{{
 						UnitPrice: &money.Money{
-							Nanos: tt.price}}}}}},
+							CurrencyCode: tt.currency,
+							Nanos:        tt.price}}}}}},
 		}
 		t.Run(name, func(t *testing.T) {
 			gotParsedSkuData, gotErr := getDataFromSku(sku)
@@ -668,32 +1004,211 @@ func Test_getDataFromSku(t *testing.T) {
 	}
 }
 
-func Test_parseAllProducts(t *testing.T) {
-	t.Skip("Local only test. Comment this line to execute test.")
-	file, err := os.Open("testdata/all-products.json")
-	if err != nil {
-		fmt.Println("Error opening file:", err)
-		return
+// relevantComputeSku reports whether a sku is expected to be priced by the compute collector based upon its category alone.
+func relevantComputeSku(sku *billingpb.Sku) bool {
+	if sku.Category == nil || sku.Category.ResourceFamily != "Compute" {
+		return false
+	}
+	if _, ok := priceTierByUsageType[sku.Category.UsageType]; !ok {
+		return false
+	}
+	for _, ignoreString := range ignoreList {
+		if strings.Contains(sku.Description, ignoreString) {
+			return false
+		}
+	}
+	return !isIrrelevantCategory(sku.Category)
+}
+
+// Test_parseProductsCoverage measures how many of the relevant compute skus can be parsed. The sample fixture is committed
+// and has to be fully covered. testdata/all-products.json is a full dump of the Compute Engine skus and is only covered
+// when present, eg after running:
+// curl -H "Authorization: Bearer $(gcloud auth print-access-token)" "https://cloudbilling.googleapis.com/v1/services/6F81-5844-456A/skus"
+func Test_parseProductsCoverage(t *testing.T) {
+	tests := map[string]struct {
+		file        string
+		minCoverage float64
+		optional    bool
+	}{
+		"sample products": {
+			file:        "testdata/sample-products.json",
+			minCoverage: 1,
+		},
+		"all products": {
+			file:        "testdata/all-products.json",
+			minCoverage: 0.95,
+			optional:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			file, err := os.Open(tt.file)
+			if tt.optional && errors.Is(err, os.ErrNotExist) {
+				t.Skipf("%s not found", tt.file)
+			}
+			require.NoError(t, err)
+			defer file.Close()
+
+			var skus []*billingpb.Sku
+			require.NoError(t, json.NewDecoder(file).Decode(&skus))
+
+			relevant, parsed := 0, 0
+			for _, sku := range skus {
+				if !relevantComputeSku(sku) {
+					continue
+				}
+				relevant++
+				if _, err := getDataFromSku(sku); err != nil {
+					t.Logf("Not parsable yet: %s: %s", sku.Description, err)
+					continue
+				}
+				parsed++
+			}
+			require.NotZero(t, relevant)
+			coverage := float64(parsed) / float64(relevant)
+			t.Logf("parsed %d out of %d relevant compute skus (%.1f%%)", parsed, relevant, coverage*100)
+			assert.GreaterOrEqual(t, coverage, tt.minCoverage)
+		})
 	}
-	defer file.Close() // defer closing the file until the function exits
+}
 
-	// Read the file into memory
-	var pricing []*billingpb.Sku
-	err = json.NewDecoder(file).Decode(&pricing)
-	if err != nil {
-		t.Errorf("Error decoding JSON: %s", err)
-		return
+func TestStructuredPricingMap_HistoryPrices(t *testing.T) {
+	m := NewStructuredPricingMap()
+	m.Compute["us-central1"] = &FamilyPricing{Family: map[string]*PriceTiers{
+		"n2": {OnDemand: Prices{Cpu: 0.03, Ram: 0.004}, Spot: Prices{Cpu: 0.01, Ram: 0.001}},
+		// Families without spot VMs only have an on-demand price
+		"m1": {OnDemand: Prices{Cpu: 0.04, Ram: 0.005}},
+	}}
+
+	assert.ElementsMatch(t, []pricehistory.Price{
+		{Region: "us-central1", SKU: "n2", PriceTier: "ondemand", Unit: "usd_per_core_hour", Value: 0.03},
+		{Region: "us-central1", SKU: "n2", PriceTier: "ondemand", Unit: "usd_per_gib_hour", Value: 0.004},
+		{Region: "us-central1", SKU: "n2", PriceTier: "spot", Unit: "usd_per_core_hour", Value: 0.01},
+		{Region: "us-central1", SKU: "n2", PriceTier: "spot", Unit: "usd_per_gib_hour", Value: 0.001},
+		{Region: "us-central1", SKU: "m1", PriceTier: "ondemand", Unit: "usd_per_core_hour", Value: 0.04},
+		{Region: "us-central1", SKU: "m1", PriceTier: "ondemand", Unit: "usd_per_gib_hour", Value: 0.005},
+	}, m.HistoryPrices())
+}
+
+func TestStructuredPricingMap_PriceForInstance(t *testing.T) {
+	m := NewStructuredPricingMap()
+	m.Compute["us-central1"] = &FamilyPricing{Family: map[string]*PriceTiers{
+		"n2": {OnDemand: Prices{Cpu: 0.03, Ram: 0.004}, Spot: Prices{Cpu: 0.01, Ram: 0.001}},
+	}}
+
+	price, err := m.PriceForInstance("us-central1", "n2-standard-4", pricing.TierOnDemand)
+	require.NoError(t, err)
+	assert.Equal(t, pricing.Price{USDPerCoreHour: 0.03, USDPerGiBHour: 0.004}, price)
+
+	price, err = m.PriceForInstance("us-central1", "n2-standard-4", pricing.TierSpot)
+	require.NoError(t, err)
+	assert.Equal(t, pricing.Price{USDPerCoreHour: 0.01, USDPerGiBHour: 0.001}, price)
+
+	_, err = m.PriceForInstance("us-central1", "c3-standard-4", pricing.TierOnDemand)
+	assert.ErrorIs(t, err, pricing.ErrPriceNotFound)
+}
+
+// benchmarkRegions and benchmarkFamilies span about as many compute SKUs as the Cloud Billing catalog lists.
+var (
+	benchmarkRegions = []string{
+		"africa-south1", "asia-east1", "asia-east2", "asia-northeast1", "asia-northeast2", "asia-northeast3",
+		"asia-south1", "asia-south2", "asia-southeast1", "asia-southeast2", "australia-southeast1",
+		"australia-southeast2", "europe-central2", "europe-north1", "europe-southwest1", "europe-west1", "europe-west2",
+		"europe-west3", "europe-west4", "europe-west6", "europe-west8", "europe-west9", "europe-west10", "europe-west12",
+		"me-central1", "me-central2", "me-west1", "northamerica-northeast1", "northamerica-northeast2", "southamerica-east1",
+		"southamerica-west1", "us-central1", "us-east1", "us-east4", "us-east5", "us-south1", "us-west1", "us-west2",
+		"us-west3", "us-west4",
+	}
+	benchmarkFamilies = []string{
+		"A2", "A3", "C2D", "C3", "C3D", "C4", "C4A", "E2", "G2", "H3", "M1", "M2", "M3", "N1", "N2", "N2D", "N4", "T2A", "T2D",
+		"Z3",
 	}
-	counter := 0
-	for _, sku := range pricing {
-		_, err := getDataFromSku(sku)
-		if errors.Is(SkuNotParsable, err) {
-			fmt.Printf("Not parsable yet: %v\n", sku.Description)
-			counter++
+	benchmarkStorage = []string{
+		"Storage PD Capacity", "SSD backed PD Capacity", "Balanced PD Capacity", "Extreme PD Capacity",
+		"Regional Storage PD Capacity", "Regional SSD backed PD Capacity", "Regional Balanced PD Capacity",
+	}
+)
+
+// benchmarkSkus generates n SKUs with the mix of the Cloud Billing catalog: about a third of them are the compute
+// SKUs that end up in the pricing map, the others are storage, commitment, GPU and network SKUs. Every storage class is
+// listed once per region, as a duplicate would be logged and skipped.
+func benchmarkSkus(n int) []*billingpb.Sku {
+	skus := make([]*billingpb.Sku, 0, n)
+	for i := 0; i < n; i++ {
+		region := benchmarkRegions[i%len(benchmarkRegions)]
+		family := benchmarkFamilies[(i/len(benchmarkRegions))%len(benchmarkFamilies)]
+		kind := i % 9
+		if kind == 4 && i/9 >= len(benchmarkRegions)*len(benchmarkStorage) {
+			kind = 5
+		}
+		sku := &billingpb.Sku{
+			ServiceRegions: []string{region},
+			PricingInfo: []*billingpb.PricingInfo{{
+				PricingExpression: &billingpb.PricingExpression{
+					UsageUnit: "h",
+					TieredRates: []*billingpb.PricingExpression_TierRate{{
+						UnitPrice: &money.Money{Nanos: int32(20_000_000 + i%1000)},
+					}},
+				},
+			}},
 		}
-		if errors.Is(PricingDataIsOff, err) {
-			fmt.Printf("Pricing is off: %v\n", sku.Description)
+		switch kind {
+		case 0:
+			sku.Description = family + " Instance Core running in " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "OnDemand"}
+		case 1:
+			sku.Description = family + " Instance Ram running in " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "OnDemand"}
+			sku.PricingInfo[0].PricingExpression.UsageUnit = "GiBy.h"
+		case 2:
+			sku.Description = "Spot Preemptible " + family + " Instance Core running in " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "Preemptible"}
+		case 3:
+			sku.Description = "Spot Preemptible " + family + " Instance Ram running in " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "RAM", UsageType: "Preemptible"}
+			sku.PricingInfo[0].PricingExpression.UsageUnit = "GiBy.h"
+		case 4:
+			storage := i / 9
+			region = benchmarkRegions[storage%len(benchmarkRegions)]
+			sku.ServiceRegions = []string{region}
+			sku.Description = benchmarkStorage[storage/len(benchmarkRegions)] + " in " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Storage", ResourceGroup: "SSD", UsageType: "OnDemand"}
+			sku.PricingInfo[0].PricingExpression.UsageUnit = "GiBy.mo"
+		case 5, 6:
+			sku.Description = "Commitment v1: " + family + " Cpu in " + region + " for 1 Year"
+			sku.Category = &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "CPU", UsageType: "Commit1Yr"}
+		case 7:
+			sku.Description = "Nvidia L4 GPU running in " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Compute", ResourceGroup: "GPU", UsageType: "OnDemand"}
+		default:
+			sku.Description = "Network Inter Region Data Transfer Out from " + region
+			sku.Category = &billingpb.Category{ResourceFamily: "Network", ResourceGroup: "InterregionEgress", UsageType: "OnDemand"}
+			sku.PricingInfo[0].PricingExpression.UsageUnit = "GiBy"
 		}
+		skus = append(skus, sku)
+	}
+	return skus
+}
+
+func BenchmarkGeneratePricingMap(b *testing.B) {
+	for _, n := range []int{1_000, 30_000} {
+		skus := benchmarkSkus(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := GeneratePricingMap(skus); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetDataFromSku(b *testing.B) {
+	skus := benchmarkSkus(30_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = getDataFromSku(skus[i%len(skus)])
 	}
-	fmt.Printf("%v SKU weren't parsable", counter)
 }
@@ -0,0 +1,173 @@
+package compute
+
+import (
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/billing/apiv1/billingpb"
+
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
+)
+
+// SKUs are matched primarily on their category and usage unit, which are stable across the rewording of descriptions
+// Google does from time to time. The description is only used to find the machine family, which isn't part of the
+// category, and as a fallback when a SKU lacks a category.
+
+const (
+	spotDescriptionPrefix = "Spot Preemptible "
+	runningInSeparator    = " running in "
+)
+
+var (
+	// resourceByGroup maps Category.ResourceGroup of compute SKUs to the resource they price.
+	resourceByGroup = map[string]Resource{
+		"CPU": Cpu,
+		"RAM": Ram,
+	}
+	// resourceByUsageUnit maps the usage unit of the pricing expression to the resource it prices. It's used for
+	// SKUs that are grouped by machine series, eg N1Standard, rather than by resource.
+	resourceByUsageUnit = map[string]Resource{
+		"h":      Cpu,
+		"GiBy.h": Ram,
+	}
+	// resourceByDescription is the last resort when neither the category nor the usage unit is set.
+	resourceByDescription = map[string]Resource{
+		"Core": Cpu,
+		"Ram":  Ram,
+	}
+	// priceTierByUsageType maps Category.UsageType to a price tier. Commitments aren't listed on purpose as they are
+	// not relevant for list prices.
+	priceTierByUsageType = map[string]PriceTier{
+		"OnDemand":    OnDemand,
+		"Preemptible": Spot,
+	}
+	// irrelevantResourceFamilies and irrelevantResourceGroups are categories that are never priced by the compute
+	// collector.
+	irrelevantResourceFamilies = map[string]bool{
+		"Network": true,
+		"License": true,
+	}
+	irrelevantResourceGroups = map[string]bool{
+		"GPU": true,
+	}
+	// familyByDescriptionPrefix covers machine series whose description doesn't start with the series name.
+	familyByDescriptionPrefix = map[string]string{
+		"Compute optimized": "c2",
+	}
+	// reConfidential matches the marker of the SKUs of the confidential VM premiums in their description, eg "N2D AMD
+	// Confidential Computing Core".
+	reConfidential = regexp.MustCompile(`\bConfidential( Computing| VM)?\b`)
+	// reMachineSeries matches the name of a machine series at the start of a description, eg N1, N2D, T2A or C4A.
+	reMachineSeries = regexp.MustCompile(`^[A-Za-z]\d{1,2}[A-Za-z]?$`)
+)
+
+// computeSku holds what's needed to file the price of a compute SKU in the pricing map.
+type computeSku struct {
+	family    string
+	resource  Resource
+	priceTier PriceTier
+	// confidential SKUs price the premium of confidential VMs on top of the price of the family.
+	confidential bool
+}
+
+// isIrrelevantCategory reports whether the category of a SKU excludes it from compute pricing.
+func isIrrelevantCategory(category *billingpb.Category) bool {
+	if category == nil {
+		return false
+	}
+	if irrelevantResourceFamilies[category.ResourceFamily] || irrelevantResourceGroups[category.ResourceGroup] {
+		return true
+	}
+	return strings.HasPrefix(category.UsageType, "Commit")
+}
+
+// parseComputeSku classifies a compute SKU. The second return value is false when the SKU isn't a compute SKU that
+// can be priced per core or per GiB of memory.
+func parseComputeSku(sku *billingpb.Sku) (computeSku, bool) {
+	description, spot := strings.CutPrefix(sku.Description, spotDescriptionPrefix)
+	machine, location, found := strings.Cut(description, runningInSeparator)
+	if !found || location == "" {
+		return computeSku{}, false
+	}
+	machine, confidential := cutConfidential(machine)
+	family, ok := familyFromDescription(machine)
+	if !ok {
+		return computeSku{}, false
+	}
+	resource, ok := resourceFromSku(sku, machine)
+	if !ok {
+		return computeSku{}, false
+	}
+	priceTier := OnDemand
+	if spot {
+		priceTier = Spot
+	}
+	if sku.Category != nil {
+		if tier, ok := priceTierByUsageType[sku.Category.UsageType]; ok {
+			priceTier = tier
+		}
+	}
+	return computeSku{family: family, resource: resource, priceTier: priceTier, confidential: confidential}, true
+}
+
+// FilterSkus drops the compute SKUs of the machine families families doesn't select from the SKUs of the billing
+// catalog, before they're parsed into a pricing map. The Cloud Billing Catalog API can't filter the SKUs it lists, so
+// they're filtered once listed. The other SKUs, eg the persistent disks, are kept. families is optional.
+func FilterSkus(skus []*billingpb.Sku, families *pricing.FamilyFilter) []*billingpb.Sku {
+	if families == nil {
+		return skus
+	}
+	filtered := make([]*billingpb.Sku, 0, len(skus))
+	for _, sku := range skus {
+		if sku != nil {
+			if computeSku, ok := parseComputeSku(sku); ok && !families.Matches(computeSku.family, "") {
+				continue
+			}
+		}
+		filtered = append(filtered, sku)
+	}
+	return filtered
+}
+
+// cutConfidential removes the confidential marker from the part of a description that precedes "running in", eg
+// "N2D AMD Confidential Computing Core" results in "N2D AMD Core". The second return value reports whether the marker
+// was found.
+func cutConfidential(machine string) (string, bool) {
+	if !reConfidential.MatchString(machine) {
+		return machine, false
+	}
+	return strings.Join(strings.Fields(reConfidential.ReplaceAllString(machine, "")), " "), true
+}
+
+// familyFromDescription returns the lowercased machine family from the part of a description that precedes
+// "running in", eg "N2D AMD Instance Core" results in "n2d".
+func familyFromDescription(machine string) (string, bool) {
+	for prefix, family := range familyByDescriptionPrefix {
+		if strings.HasPrefix(machine, prefix) {
+			return family, true
+		}
+	}
+	fields := strings.Fields(machine)
+	if len(fields) < 2 || !reMachineSeries.MatchString(fields[0]) {
+		return "", false
+	}
+	return strings.ToLower(fields[0]), true
+}
+
+// resourceFromSku determines whether a SKU prices cores or memory, preferring the category over the usage unit over
+// the description.
+func resourceFromSku(sku *billingpb.Sku, machine string) (Resource, bool) {
+	if sku.Category != nil {
+		if resource, ok := resourceByGroup[sku.Category.ResourceGroup]; ok {
+			return resource, true
+		}
+	}
+	if len(sku.PricingInfo) > 0 && sku.PricingInfo[0].PricingExpression != nil {
+		if resource, ok := resourceByUsageUnit[sku.PricingInfo[0].PricingExpression.UsageUnit]; ok {
+			return resource, true
+		}
+	}
+	fields := strings.Fields(machine)
+	resource, ok := resourceByDescription[fields[len(fields)-1]]
+	return resource, ok
+}
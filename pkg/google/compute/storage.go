@@ -0,0 +1,68 @@
+package compute
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+)
+
+var (
+	// StorageClassHourlyPriceDesc is a price sheet of the persistent disk types backing the storage classes of GKE
+	// clusters, eg pd-balanced, rather than the cost of any disk.
+	StorageClassHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp", "storage_class_usd_per_gib_hour"),
+		"The price of the capacity of a persistent disk type in USD/(GiB*h). storage_class is the disk type, eg pd-balanced.",
+		[]string{"storage_class", "region"},
+		nil,
+	)
+)
+
+// StorageClassMetrics returns a cloudcost_gcp_storage_class_usd_per_gib_hour series per region and disk type of the
+// pricing map, sorted by region and disk type.
+func (m StructuredPricingMap) StorageClassMetrics() []prometheus.Metric {
+	regions := make([]string, 0, len(m.Storage))
+	for region := range m.Storage {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	var metrics []prometheus.Metric
+	for _, region := range regions {
+		storageClasses := make([]string, 0, len(m.Storage[region].Storage))
+		for storageClass := range m.Storage[region].Storage {
+			storageClasses = append(storageClasses, storageClass)
+		}
+		sort.Strings(storageClasses)
+		for _, storageClass := range storageClasses {
+			metrics = append(metrics, prometheus.MustNewConstMetric(StorageClassHourlyPriceDesc, prometheus.GaugeValue, m.Storage[region].Storage[storageClass], storageClass, region))
+		}
+	}
+	return metrics
+}
+
+// KubernetesStorageClassMetrics returns the price sheet of the Kubernetes storage classes per region of the pricing
+// map, sorted by region and class. A class is priced at the regional or zonal price of its disk type.
+func (m StructuredPricingMap) KubernetesStorageClassMetrics(classes []storageclass.Class) []prometheus.Metric {
+	if len(classes) == 0 {
+		return nil
+	}
+	regions := make([]string, 0, len(m.Storage))
+	for region := range m.Storage {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	var metrics []prometheus.Metric
+	for _, region := range regions {
+		for _, class := range classes {
+			getCostOfStorage := m.GetCostOfStorage
+			if class.Regional {
+				getCostOfStorage = m.GetCostOfRegionalStorage
+			}
+			price, err := getCostOfStorage(region, class.Type)
+			metrics = append(metrics, class.Metrics(region, price, err == nil)...)
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,61 @@
+package compute
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestStructuredPricingMap_StorageClassMetrics(t *testing.T) {
+	m := NewStructuredPricingMap()
+	m.Storage["us-central1"] = &StoragePricing{Storage: map[string]float64{"pd-ssd": 0.2, "pd-balanced": 0.1}}
+	m.Storage["europe-west1"] = &StoragePricing{Storage: map[string]float64{"pd-standard": 0.05}}
+
+	metrics := m.StorageClassMetrics()
+	require.Len(t, metrics, 3)
+	var labels []utils.LabelMap
+	var values []float64
+	for _, metric := range metrics {
+		result := utils.ReadMetrics(metric)
+		assert.Equal(t, "cloudcost_gcp_storage_class_usd_per_gib_hour", result.FqName)
+		labels = append(labels, result.Labels)
+		values = append(values, result.Value)
+	}
+	assert.Equal(t, []utils.LabelMap{
+		{"storage_class": "pd-standard", "region": "europe-west1"},
+		{"storage_class": "pd-balanced", "region": "us-central1"},
+		{"storage_class": "pd-ssd", "region": "us-central1"},
+	}, labels)
+	assert.Equal(t, []float64{0.05, 0.1, 0.2}, values)
+}
+
+func TestStructuredPricingMap_StorageClassMetrics_Empty(t *testing.T) {
+	assert.Empty(t, NewStructuredPricingMap().StorageClassMetrics())
+}
+
+func TestStructuredPricingMap_KubernetesStorageClassMetrics(t *testing.T) {
+	m := NewStructuredPricingMap()
+	m.Storage["us-central1"] = &StoragePricing{
+		Storage:  map[string]float64{"pd-balanced": 0.1},
+		Regional: map[string]float64{"pd-balanced": 0.2},
+	}
+	classes := []storageclass.Class{
+		{Name: "balanced", Provider: storageclass.ProviderGCP, Type: "pd-balanced"},
+		{Name: "regional-balanced", Provider: storageclass.ProviderGCP, Type: "pd-balanced", Regional: true},
+		{Name: "extreme", Provider: storageclass.ProviderGCP, Type: "pd-extreme"},
+	}
+
+	var values []float64
+	for _, metric := range m.KubernetesStorageClassMetrics(classes) {
+		result := utils.ReadMetrics(metric)
+		if result.FqName == "cloudcost_kubernetes_storage_class_usd_per_gib_hour" {
+			values = append(values, result.Value)
+		}
+	}
+	assert.Equal(t, []float64{0.1, 0.2}, values, "a class is priced at the price of its replication and skipped when its type is unpriced")
+	assert.Empty(t, m.KubernetesStorageClassMetrics(nil))
+}
@@ -0,0 +1,34 @@
+package compute
+
+import (
+	"errors"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var (
+	// UnpricedResourcesTotal is incremented by the compute and gke collectors and registered once by the gcp provider.
+	UnpricedResourcesTotal = utils.NewUnpricedResourcesTotal("gcp")
+	// UnpricedMachineTypeInfoDesc lists the machine types that could not be priced during the last collection.
+	UnpricedMachineTypeInfoDesc = utils.NewUnpricedMachineTypeInfoDesc("gcp")
+	// MalformedPriceEntriesTotal counts the skus skipped by GeneratePricingMap because of their unit, currency or price.
+	// It's registered once by the gcp provider.
+	MalformedPriceEntriesTotal = utils.NewMalformedPriceEntriesTotal("gcp")
+)
+
+// UnpricedReason maps an error returned by GetCostOfInstance or GetCostOfStorage to a low cardinality reason label.
+func UnpricedReason(err error) string {
+	switch {
+	case errors.Is(err, RegionNotFound):
+		return "region_not_found"
+	case errors.Is(err, FamilyTypeNotFound):
+		return "family_not_found"
+	default:
+		return "unknown"
+	}
+}
+
+// NewUnpricedMachineTypes returns a tracker of the machine types a collector couldn't price during a collection.
+func NewUnpricedMachineTypes(collector string) *utils.UnpricedMachineTypes {
+	return utils.NewUnpricedMachineTypes(providerName, collector, UnpricedMachineTypeInfoDesc, UnpricedResourcesTotal, UnpricedReason)
+}
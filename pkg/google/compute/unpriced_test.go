@@ -0,0 +1,66 @@
+package compute
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestUnpricedReason(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"region not found": {
+			err:  fmt.Errorf("%w: %s", RegionNotFound, "us-central1"),
+			want: "region_not_found",
+		},
+		"family not found": {
+			err:  fmt.Errorf("%w: %s", FamilyTypeNotFound, "n4"),
+			want: "family_not_found",
+		},
+		"unknown error": {
+			err:  fmt.Errorf("some other error"),
+			want: "unknown",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, UnpricedReason(tt.err))
+		})
+	}
+}
+
+func TestUnpricedMachineTypes_Emit(t *testing.T) {
+	unpriced := NewUnpricedMachineTypes("test")
+	instance := &MachineSpec{Region: "us-central1", MachineType: "n4-standard-2"}
+	unpriced.Add(instance.Region, instance.MachineType, FamilyTypeNotFound)
+	unpriced.Add(instance.Region, instance.MachineType, FamilyTypeNotFound)
+
+	ch := make(chan prometheus.Metric, 2)
+	unpriced.Emit(ch)
+	close(ch)
+
+	got := utils.ReadMetrics(<-ch)
+	require.Equal(t, &utils.MetricResult{
+		FqName: "cloudcost_gcp_unpriced_machine_type_info",
+		Labels: utils.LabelMap{
+			"collector":    "test",
+			"region":       "us-central1",
+			"machine_type": "n4-standard-2",
+			"reason":       "family_not_found",
+		},
+		Value:      2,
+		MetricType: prometheus.GaugeValue,
+	}, got)
+	require.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_exporter_pricing_coverage_ratio",
+		Labels:     utils.LabelMap{"provider": "gcp", "collector": "test"},
+		Value:      0,
+		MetricType: prometheus.GaugeValue,
+	}, utils.ReadMetrics(<-ch))
+}
@@ -0,0 +1,152 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/monitoring/v3"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+const (
+	// UtilizationWindow is how far back the average CPU utilization of an instance is looked up.
+	UtilizationWindow = time.Hour
+
+	cpuUtilizationFilter = `metric.type = "compute.googleapis.com/instance/cpu/utilization"`
+	mibPerGib            = 1024
+)
+
+var (
+	// InstanceIdleHourlyCostDesc is the share of the hourly price of an instance that wasn't used over the last hour, based
+	// upon the average CPU utilization reported by Cloud Monitoring. Like InstanceCreatedTimestampDesc it's only emitted by
+	// the compute collector.
+	InstanceIdleHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp", "instance_idle_usd_per_hour"),
+		"The hourly cost of a GCP Compute Instance in USD multiplied by its unused CPU share over the last hour.",
+		[]string{"instance", "region", "machine_type", "project", "price_tier", "folder", "org"},
+		nil,
+	)
+)
+
+// machineShape is the number of vCPUs and the memory in GiB of a machine type.
+type machineShape struct {
+	Cpus      float64
+	MemoryGiB float64
+}
+
+// utilizationKey identifies an instance in the result of ListCPUUtilization, instance names are only unique within a zone.
+func utilizationKey(zone string, instance string) string {
+	return zone + "/" + instance
+}
+
+// ListCPUUtilization returns the average CPU utilization between 0 and 1 of each instance in a project over the
+// UtilizationWindow ending at now, keyed by utilizationKey. Instances without datapoints are left out of the result.
+func ListCPUUtilization(ctx context.Context, service *monitoring.Service, project string, now time.Time) (map[string]float64, error) {
+	utilization := make(map[string]float64)
+	err := service.Projects.TimeSeries.List("projects/"+project).
+		Filter(cpuUtilizationFilter).
+		IntervalStartTime(now.Add(-UtilizationWindow).Format(time.RFC3339)).
+		IntervalEndTime(now.Format(time.RFC3339)).
+		AggregationAlignmentPeriod(fmt.Sprintf("%ds", int(UtilizationWindow.Seconds()))).
+		AggregationPerSeriesAligner("ALIGN_MEAN").
+		Pages(ctx, func(page *monitoring.ListTimeSeriesResponse) error {
+			for _, series := range page.TimeSeries {
+				if series.Metric == nil || series.Resource == nil || len(series.Points) == 0 {
+					continue
+				}
+				point := series.Points[0]
+				if point.Value == nil || point.Value.DoubleValue == nil {
+					continue
+				}
+				key := utilizationKey(series.Resource.Labels["zone"], series.Metric.Labels["instance_name"])
+				utilization[key] = clampUtilization(*point.Value.DoubleValue)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return utilization, nil
+}
+
+// MachineShapes looks up the number of vCPUs and the memory of machine types. Shapes are cached across scrapes as they
+// never change for a given machine type. It's safe for concurrent use.
+type MachineShapes struct {
+	computeService *compute.Service
+
+	m      sync.Mutex
+	shapes map[string]machineShape
+}
+
+// NewMachineShapes returns a MachineShapes that looks machine types up with computeService.
+func NewMachineShapes(computeService *compute.Service) *MachineShapes {
+	return &MachineShapes{
+		computeService: computeService,
+		shapes:         make(map[string]machineShape),
+	}
+}
+
+// HourlyCost returns the hourly price of an instance given the price per core and GiB of its machine type.
+func (s *MachineShapes) HourlyCost(project string, instance *MachineSpec, cpuCost float64, ramCost float64) (float64, error) {
+	shape, err := s.get(project, instance.Zone, instance.MachineType)
+	if err != nil {
+		return 0, err
+	}
+	return HourlyCost(cpuCost, ramCost, shape), nil
+}
+
+// Resources returns the number of vCPUs and the GiB of memory of the machine type of an instance.
+func (s *MachineShapes) Resources(project string, instance *MachineSpec) (float64, float64, error) {
+	shape, err := s.get(project, instance.Zone, instance.MachineType)
+	if err != nil {
+		return 0, 0, err
+	}
+	return shape.Cpus, shape.MemoryGiB, nil
+}
+
+func (s *MachineShapes) get(project string, zone string, machineType string) (machineShape, error) {
+	key := utilizationKey(zone, machineType)
+	s.m.Lock()
+	defer s.m.Unlock()
+	if shape, ok := s.shapes[key]; ok {
+		return shape, nil
+	}
+	mt, err := s.computeService.MachineTypes.Get(project, zone, machineType).Do()
+	if err != nil {
+		return machineShape{}, err
+	}
+	shape := newMachineShape(mt)
+	s.shapes[key] = shape
+	return shape, nil
+}
+
+// getMachineShape looks up the number of vCPUs and the memory of a machine type.
+func (c *Collector) getMachineShape(project string, zone string, machineType string) (machineShape, error) {
+	return c.machineShapes.get(project, zone, machineType)
+}
+
+func newMachineShape(mt *compute.MachineType) machineShape {
+	return machineShape{
+		Cpus:      float64(mt.GuestCpus),
+		MemoryGiB: float64(mt.MemoryMb) / mibPerGib,
+	}
+}
+
+// HourlyCost returns the hourly price of an instance given the price per core and GiB of its machine type.
+func HourlyCost(cpuCost float64, ramCost float64, shape machineShape) float64 {
+	return cpuCost*shape.Cpus + ramCost*shape.MemoryGiB
+}
+
+// IdleCost returns the share of the hourly price of an instance that wasn't used given a utilization between 0 and 1.
+func IdleCost(cpuCost float64, ramCost float64, shape machineShape, utilization float64) float64 {
+	return HourlyCost(cpuCost, ramCost, shape) * (1 - clampUtilization(utilization))
+}
+
+func clampUtilization(utilization float64) float64 {
+	return max(0, min(1, utilization))
+}
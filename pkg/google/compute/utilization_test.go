@@ -0,0 +1,152 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+func utilizationSeries(zone string, instance string, value float64) *monitoring.TimeSeries {
+	return &monitoring.TimeSeries{
+		Metric:   &monitoring.Metric{Labels: map[string]string{"instance_name": instance}},
+		Resource: &monitoring.MonitoredResource{Labels: map[string]string{"zone": zone}},
+		Points:   []*monitoring.Point{{Value: &monitoring.TypedValue{DoubleValue: &value}}},
+	}
+}
+
+func TestListCPUUtilization(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		pages      []*monitoring.ListTimeSeriesResponse
+		statusCode int
+		want       map[string]float64
+		wantErr    bool
+	}{
+		"no time series": {
+			pages: []*monitoring.ListTimeSeriesResponse{{}},
+			want:  map[string]float64{},
+		},
+		"instances are keyed by zone": {
+			pages: []*monitoring.ListTimeSeriesResponse{
+				{
+					TimeSeries: []*monitoring.TimeSeries{
+						utilizationSeries("us-central1-a", "test-n2", 0.25),
+						utilizationSeries("us-central1-b", "test-n2", 0.5),
+						// Series without points are skipped
+						{
+							Metric:   &monitoring.Metric{Labels: map[string]string{"instance_name": "test-empty"}},
+							Resource: &monitoring.MonitoredResource{Labels: map[string]string{"zone": "us-central1-a"}},
+						},
+					},
+				},
+			},
+			want: map[string]float64{
+				"us-central1-a/test-n2": 0.25,
+				"us-central1-b/test-n2": 0.5,
+			},
+		},
+		"pages are followed": {
+			pages: []*monitoring.ListTimeSeriesResponse{
+				{
+					TimeSeries:    []*monitoring.TimeSeries{utilizationSeries("us-central1-a", "first", 0.1)},
+					NextPageToken: "1",
+				},
+				{
+					TimeSeries: []*monitoring.TimeSeries{utilizationSeries("us-central1-a", "second", 1.5)},
+				},
+			},
+			want: map[string]float64{
+				"us-central1-a/first":  0.1,
+				"us-central1-a/second": 1,
+			},
+		},
+		"errors propagate": {
+			statusCode: http.StatusForbidden,
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.statusCode != 0 {
+					w.WriteHeader(tt.statusCode)
+					return
+				}
+				assert.Equal(t, "/v3/projects/testing/timeSeries", r.URL.Path)
+				assert.Equal(t, cpuUtilizationFilter, r.URL.Query().Get("filter"))
+				assert.Equal(t, now.Add(-UtilizationWindow).Format(time.RFC3339), r.URL.Query().Get("interval.startTime"))
+				page := 0
+				if r.URL.Query().Get("pageToken") == "1" {
+					page = 1
+				}
+				_ = json.NewEncoder(w).Encode(tt.pages[page])
+			}))
+			defer testServer.Close()
+			service, err := monitoring.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+			require.NoError(t, err)
+
+			got, err := ListCPUUtilization(context.Background(), service, "testing", now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCollector_getMachineShape(t *testing.T) {
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/projects/testing/zones/us-central1-a/machineTypes/n2-standard-4", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(&computev1.MachineType{Name: "n2-standard-4", GuestCpus: 4, MemoryMb: 16384})
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+	collector := New(&Config{Projects: "testing"}, computeService, nil, nil)
+
+	for i := 0; i < 2; i++ {
+		shape, err := collector.getMachineShape("testing", "us-central1-a", "n2-standard-4")
+		require.NoError(t, err)
+		assert.Equal(t, machineShape{Cpus: 4, MemoryGiB: 16}, shape)
+	}
+	assert.Equal(t, 1, requests, "machine shapes should be cached")
+}
+
+func TestIdleCost(t *testing.T) {
+	shape := machineShape{Cpus: 4, MemoryGiB: 16}
+	tests := map[string]struct {
+		utilization float64
+		want        float64
+	}{
+		"idle instance costs its full price": {
+			utilization: 0,
+			want:        4*0.03 + 16*0.004,
+		},
+		"partially used instance": {
+			utilization: 0.75,
+			want:        (4*0.03 + 16*0.004) * 0.25,
+		},
+		"fully used instance": {
+			utilization: 1,
+			want:        0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, IdleCost(0.03, 0.004, shape, tt.utilization), 1e-9)
+		})
+	}
+}
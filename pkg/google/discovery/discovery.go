@@ -0,0 +1,146 @@
+// Package discovery lists the active projects below a folder or an organization through Cloud Resource Manager, so
+// that the projects created after the exporter started are collected without redeploying it.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+const (
+	foldersPrefix       = "folders/"
+	organizationsPrefix = "organizations/"
+
+	stateActive = "ACTIVE"
+)
+
+var (
+	ErrInvalidParent = errors.New("the parent of the discovered projects must be folders/<id> or organizations/<id>")
+	ErrNoProjects    = errors.New("no active project found")
+)
+
+// ParseParent validates the parent of the discovered projects, eg folders/123 or organizations/456.
+func ParseParent(parent string) (string, error) {
+	for _, prefix := range []string{foldersPrefix, organizationsPrefix} {
+		if id, ok := strings.CutPrefix(parent, prefix); ok && id != "" && !strings.Contains(id, "/") {
+			return parent, nil
+		}
+	}
+	return "", fmt.Errorf("%w, got %q", ErrInvalidParent, parent)
+}
+
+// Projects lists the active projects of a parent and of every folder below it, and lists them again once interval
+// passed since the last listing. It's safe for concurrent use.
+type Projects struct {
+	service  *cloudresourcemanager.Service
+	parent   string
+	interval time.Duration
+	now      func() time.Time
+
+	m          sync.Mutex
+	projects   []string
+	listedAt   time.Time
+	hasListing bool
+}
+
+// New returns Projects listing the projects below parent, which must have been validated with ParseParent.
+func New(service *cloudresourcemanager.Service, parent string, interval time.Duration) *Projects {
+	return &Projects{
+		service:  service,
+		parent:   parent,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// List returns the active projects below the parent, sorted. They're listed again when the interval passed since the
+// last listing. When a listing fails, the projects of the last successful listing are returned and the listing is
+// tried again on the next call, an error is only returned when no listing succeeded yet.
+func (p *Projects) List(ctx context.Context) ([]string, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	now := p.now()
+	if p.hasListing && now.Before(p.listedAt.Add(p.interval)) {
+		return p.projects, nil
+	}
+	projects, err := p.list(ctx, p.parent)
+	if err == nil && len(projects) == 0 {
+		err = fmt.Errorf("%w below %s", ErrNoProjects, p.parent)
+	}
+	if err != nil {
+		if !p.hasListing {
+			return nil, err
+		}
+		log.Printf("error listing the projects below %s, keeping the %d projects of the last listing: %v", p.parent, len(p.projects), err)
+		return p.projects, nil
+	}
+	sort.Strings(projects)
+	if p.hasListing {
+		logChanges(p.projects, projects)
+	} else {
+		log.Printf("discovered %d projects below %s", len(projects), p.parent)
+	}
+	p.projects = projects
+	p.listedAt = now
+	p.hasListing = true
+	return p.projects, nil
+}
+
+// list returns the active projects of parent and of its folders, recursively.
+func (p *Projects) list(ctx context.Context, parent string) ([]string, error) {
+	var projects []string
+	err := p.service.Projects.List().Parent(parent).Pages(ctx, func(page *cloudresourcemanager.ListProjectsResponse) error {
+		for _, project := range page.Projects {
+			if project.State == stateActive {
+				projects = append(projects, project.ProjectId)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing the projects of %s: %w", parent, err)
+	}
+	var folders []string
+	err = p.service.Folders.List().Parent(parent).Pages(ctx, func(page *cloudresourcemanager.ListFoldersResponse) error {
+		for _, folder := range page.Folders {
+			if folder.State == stateActive {
+				folders = append(folders, folder.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing the folders of %s: %w", parent, err)
+	}
+	for _, folder := range folders {
+		folderProjects, err := p.list(ctx, folder)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, folderProjects...)
+	}
+	return projects, nil
+}
+
+// logChanges logs the projects added and removed between two sorted listings.
+func logChanges(before []string, after []string) {
+	for _, project := range after {
+		if _, found := slices.BinarySearch(before, project); !found {
+			log.Printf("discovered project %s, collecting it", project)
+		}
+	}
+	for _, project := range before {
+		if _, found := slices.BinarySearch(after, project); !found {
+			log.Printf("project %s is gone, no longer collecting it", project)
+		}
+	}
+}
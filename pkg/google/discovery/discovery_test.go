@@ -0,0 +1,112 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+func TestParseParent(t *testing.T) {
+	for _, parent := range []string{"folders/123", "organizations/456"} {
+		got, err := ParseParent(parent)
+		require.NoError(t, err)
+		assert.Equal(t, parent, got)
+	}
+	for _, parent := range []string{"", "123", "projects/testing", "folders/", "folders/1/2"} {
+		_, err := ParseParent(parent)
+		assert.ErrorIs(t, err, ErrInvalidParent, parent)
+	}
+}
+
+// resourceManager serves the projects and folders of every parent, and fails every request when failing is set.
+type resourceManager struct {
+	projects map[string][]*cloudresourcemanager.Project
+	folders  map[string][]*cloudresourcemanager.Folder
+	failing  bool
+}
+
+func (s *resourceManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.failing {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	parent := r.URL.Query().Get("parent")
+	switch r.URL.Path {
+	case "/v3/projects":
+		_ = json.NewEncoder(w).Encode(&cloudresourcemanager.ListProjectsResponse{Projects: s.projects[parent]})
+	case "/v3/folders":
+		_ = json.NewEncoder(w).Encode(&cloudresourcemanager.ListFoldersResponse{Folders: s.folders[parent]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestProjects_List(t *testing.T) {
+	rm := &resourceManager{
+		projects: map[string][]*cloudresourcemanager.Project{
+			"organizations/1": {
+				{ProjectId: "root-project", State: stateActive},
+				{ProjectId: "deleted-project", State: "DELETE_REQUESTED"},
+			},
+			"folders/2": {{ProjectId: "team-project", State: stateActive}},
+			"folders/3": {{ProjectId: "nested-project", State: stateActive}},
+		},
+		folders: map[string][]*cloudresourcemanager.Folder{
+			"organizations/1": {{Name: "folders/2", State: stateActive}},
+			"folders/2":       {{Name: "folders/3", State: stateActive}},
+		},
+	}
+	server := httptest.NewServer(rm)
+	defer server.Close()
+	service, err := cloudresourcemanager.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	now := time.Now()
+	p := New(service, "organizations/1", time.Hour)
+	p.now = func() time.Time { return now }
+
+	projects, err := p.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nested-project", "root-project", "team-project"}, projects)
+
+	// The projects aren't listed again before the interval passed
+	rm.projects["folders/3"] = append(rm.projects["folders/3"], &cloudresourcemanager.Project{ProjectId: "new-project", State: stateActive})
+	projects, err = p.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, projects, 3)
+
+	now = now.Add(time.Hour)
+	projects, err = p.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nested-project", "new-project", "root-project", "team-project"}, projects)
+
+	// A failed listing keeps the projects of the last listing
+	rm.failing = true
+	now = now.Add(time.Hour)
+	projects, err = p.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, projects, 4)
+}
+
+func TestProjects_List_Errors(t *testing.T) {
+	rm := &resourceManager{failing: true}
+	server := httptest.NewServer(rm)
+	defer server.Close()
+	service, err := cloudresourcemanager.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	_, err = New(service, "folders/2", time.Hour).List(context.Background())
+	assert.Error(t, err)
+
+	rm.failing = false
+	_, err = New(service, "folders/2", time.Hour).List(context.Background())
+	assert.ErrorIs(t, err, ErrNoProjects)
+}
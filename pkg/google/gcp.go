@@ -2,8 +2,13 @@ package google
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,17 +17,47 @@ import (
 	computeapiv1 "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/storage"
 	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	resourcemanagerv3 "google.golang.org/api/cloudresourcemanager/v3"
 	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/container/v1"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+	spannerv1 "google.golang.org/api/spanner/v1"
+	htransport "google.golang.org/api/transport/http"
 
 	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/anomaly"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/commitment"
+	"github.com/grafana/cloudcost-exporter/pkg/egress"
+	"github.com/grafana/cloudcost-exporter/pkg/google/clb"
+	"github.com/grafana/cloudcost-exporter/pkg/google/commitments"
 	"github.com/grafana/cloudcost-exporter/pkg/google/compute"
+	"github.com/grafana/cloudcost-exporter/pkg/google/discovery"
 	"github.com/grafana/cloudcost-exporter/pkg/google/gcs"
 	"github.com/grafana/cloudcost-exporter/pkg/google/gke"
+	"github.com/grafana/cloudcost-exporter/pkg/google/hierarchy"
+	"github.com/grafana/cloudcost-exporter/pkg/google/messaging"
+	"github.com/grafana/cloudcost-exporter/pkg/google/observability"
+	"github.com/grafana/cloudcost-exporter/pkg/google/spanner"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/pricehistory"
+	"github.com/grafana/cloudcost-exporter/pkg/pricesource"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/schedule"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
 	subsystem = "gcp"
+	// cloudPlatformScope covers every API the exporter calls. It's only requested when the exporter authenticates its
+	// own HTTP client, otherwise each client requests the scopes of its API.
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	// docsProjectId is the project of the provider returned by NewForDocs.
+	docsProjectId = "docs"
 )
 
 var (
@@ -79,80 +114,402 @@ var (
 )
 
 type GCP struct {
-	config     *Config
+	config *Config
+	// context is cancelled when the exporter shuts down, which stops the collections.
+	context    context.Context
 	collectors []provider.Collector
+	// closers are the clients closed on shutdown, the REST services of the Google API client don't need to be.
+	closers  []io.Closer
+	summary  provider.CollectionSummary
+	watchdog provider.Watchdog
+	inFlight provider.InFlight
 }
 
 type Config struct {
-	ProjectId       string // ProjectID is where the project is running. Used for authentication.
-	Region          string
-	Projects        string // Projects is a comma-separated list of projects to scrape metadata from
-	Services        []string
-	ScrapeInterval  time.Duration
+	ProjectId      string // ProjectID is where the project is running. Used for authentication.
+	Region         string
+	Projects       string // Projects is a comma-separated list of projects to scrape metadata from
+	Services       []string
+	ScrapeInterval time.Duration
+	// ScrapeIntervals overrides ScrapeInterval per service, keyed by the lowercased service name.
+	ScrapeIntervals map[string]time.Duration
 	DefaultDiscount int
+	// IdleCost enables calls to Cloud Monitoring to export the idle cost of compute instances based upon their average
+	// CPU utilization over the last hour.
+	IdleCost bool
+	// BucketCost enables calls to Cloud Monitoring to export the estimated cost of every bucket of the GCS collector
+	// based upon its size.
+	BucketCost bool
+	// HierarchyDepth enables calls to Cloud Resource Manager to label metrics with the folders and organization of
+	// their project, keeping at most HierarchyDepth folders from the top. Zero disables the labels.
+	HierarchyDepth int
+	// DiscoveryParent enables calls to Cloud Resource Manager to list the active projects below a folder or an
+	// organization, eg folders/123, which the compute and GKE collectors collect instead of Projects. The projects are
+	// listed again every DiscoveryInterval.
+	DiscoveryParent   string
+	DiscoveryInterval time.Duration
+	// ClusterNames normalizes the cluster_name label of the GKE metrics.
+	ClusterNames *clustername.Normalizer
+	// Nodes enables the allocatable cost metrics of the nodes of the cluster the exporter runs in.
+	Nodes kubernetes.NodeLister
+	// Pods enables the namespace cost metrics of the cluster the exporter runs in.
+	Pods kubernetes.PodLister
+	// Calendar enables the actual and expected cost metrics of the GKE clusters with a scale down schedule.
+	Calendar *schedule.Calendar
+	// Anomalies enables the anomaly scores of the cost of the GKE clusters.
+	Anomalies *anomaly.Detector
+	// Recommendations enables the commitment recommendations of the on-demand cores of the GKE clusters.
+	Recommendations *commitment.Recommender
+	// Headroom enables the cost of the nodes of the autoscaled node pools of the GKE clusters above their minimum size.
+	Headroom bool
+	// Prices overrides or discounts the on-demand prices of the instances of the GKE clusters.
+	Prices pricesource.Layers
+	// InstanceFilter scopes the instances listed by the compute and GKE collectors with a filter expression of the
+	// instances.list API, eg labels.env=prod.
+	InstanceFilter string
+	// MachineFamilies selects the machine families whose prices are kept by the compute and GKE collectors, every
+	// family is priced when nil.
+	MachineFamilies *pricing.FamilyFilter
+	// StorageClasses prices the storage classes of the GKE clusters, and adjusts the cost of the persistent volumes
+	// listed by Volumes by their storage class.
+	StorageClasses *storageclass.Classes
+	Volumes        kubernetes.PersistentVolumeLister
+	// PriceHistory records the pricing maps of the compute collector, they aren't recorded when nil.
+	PriceHistory *pricehistory.History
+	// PriceLookup is set the pricing maps of the compute collector, so the prices of instances can be looked up when
+	// the exporter is embedded. It's left alone when nil.
+	PriceLookup *pricing.Lookup
+	// HTTPClient sends the requests of every GCP client, eg through an egress proxy. The SDK defaults are used when nil.
+	HTTPClient *http.Client
+	// Endpoints overrides the endpoints of the compute, cloudbilling, storage, monitoring, container, spanner and
+	// cloudresourcemanager clients, eg with Private Service Connect endpoints.
+	Endpoints egress.Endpoints
+	// Auth selects how the clients authenticate, Application Default Credentials are used when it's empty.
+	Auth AuthConfig
+	// Logger is the logger of the collectors taking one, the default logger is used when nil.
+	Logger *slog.Logger
 }
 
 // New is responsible for parsing out a configuration file and setting up the associated services that could be required.
 // We instantiate services to avoid repeating common services that may be shared across many collectors. In the future we can push
-// collector specific services further down.
-func New(config *Config) (*GCP, error) {
-	ctx := context.Background()
+// collector specific services further down. The collections stop once ctx is done.
+func New(ctx context.Context, config *Config) (*GCP, error) {
+	authOptions, err := config.Auth.clientOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.MachineFamilies.Validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := newAuthenticatedHTTPClient(ctx, config.HTTPClient, authOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating http client: %w", err)
+	}
+	clientOptions := func(service string) []option.ClientOption {
+		var opts []option.ClientOption
+		if httpClient != nil {
+			opts = append(opts, option.WithHTTPClient(httpClient))
+		} else {
+			opts = append(opts, authOptions...)
+		}
+		if endpoint := config.Endpoints.For(service, ""); endpoint != "" {
+			opts = append(opts, option.WithEndpoint(endpoint))
+		}
+		return opts
+	}
 
-	computeService, err := computev1.NewService(ctx)
+	computeService, err := computev1.NewService(ctx, clientOptions("compute")...)
 	if err != nil {
 		return nil, fmt.Errorf("error creating compute computeService: %w", err)
 	}
 
-	cloudCatalogClient, err := billingv1.NewCloudCatalogClient(ctx)
+	newCloudCatalogClient := billingv1.NewCloudCatalogClient
+	if httpClient != nil {
+		// The gRPC client doesn't send its requests through an HTTP client, so the REST client is used instead
+		newCloudCatalogClient = billingv1.NewCloudCatalogRESTClient
+	}
+	cloudCatalogClient, err := newCloudCatalogClient(ctx, clientOptions("cloudbilling")...)
 	if err != nil {
 		return nil, fmt.Errorf("error creating cloudCatalogClient: %w", err)
 	}
 
-	regionsClient, err := computeapiv1.NewRegionsRESTClient(ctx)
+	regionsClient, err := computeapiv1.NewRegionsRESTClient(ctx, clientOptions("compute")...)
 	if err != nil {
 		return nil, fmt.Errorf("could not create regions client: %w", err)
 	}
 
-	storageClient, err := storage.NewClient(ctx)
+	storageClient, err := storage.NewClient(ctx, clientOptions("storage")...)
 	if err != nil {
 		return nil, fmt.Errorf("could not create bucket client: %w", err)
 	}
 
+	var resolver *hierarchy.Resolver
+	if config.HierarchyDepth > 0 {
+		resourceManagerService, err := cloudresourcemanager.NewService(ctx, clientOptions("cloudresourcemanager")...)
+		if err != nil {
+			return nil, fmt.Errorf("error creating resourceManagerService: %w", err)
+		}
+		resolver = hierarchy.NewResolver(resourceManagerService, config.HierarchyDepth)
+	}
+
+	var projects *discovery.Projects
+	if config.DiscoveryParent != "" {
+		parent, err := discovery.ParseParent(config.DiscoveryParent)
+		if err != nil {
+			return nil, err
+		}
+		resourceManagerService, err := resourcemanagerv3.NewService(ctx, clientOptions("cloudresourcemanager")...)
+		if err != nil {
+			return nil, fmt.Errorf("error creating resourceManagerService: %w", err)
+		}
+		projects = discovery.New(resourceManagerService, parent, config.DiscoveryInterval)
+	}
+
+	c := &clients{
+		ctx:                ctx,
+		config:             config,
+		clientOptions:      clientOptions,
+		computeService:     computeService,
+		cloudCatalogClient: cloudCatalogClient,
+		regionsClient:      regionsClient,
+		storageClient:      storageClient,
+		resolver:           resolver,
+		projects:           projects,
+	}
 	var collectors []provider.Collector
 	for _, service := range config.Services {
 		log.Printf("Creating collector for %s", service)
-		var collector provider.Collector
-		switch strings.ToUpper(service) {
-		case "GCS":
-			collector, err = gcs.New(&gcs.Config{
+		svc, ok := collectorServices[strings.ToUpper(service)]
+		if !ok {
+			log.Printf("Unknown service %s", service)
+			// Continue to next service, no need to halt here
+			continue
+		}
+		collector, err := svc.new(c, utils.ScrapeIntervalFor(config.ScrapeIntervals, service, config.ScrapeInterval))
+		if err != nil {
+			return nil, err
+		}
+		if collector != nil {
+			collectors = append(collectors, collector)
+		}
+	}
+	return &GCP{
+		config:     config,
+		context:    ctx,
+		collectors: collectors,
+		closers:    []io.Closer{cloudCatalogClient, regionsClient, storageClient},
+	}, nil
+}
+
+// clients are the clients shared by the collectors of the services.
+type clients struct {
+	ctx                context.Context
+	config             *Config
+	clientOptions      func(service string) []option.ClientOption
+	computeService     *computev1.Service
+	cloudCatalogClient *billingv1.CloudCatalogClient
+	regionsClient      *computeapiv1.RegionsClient
+	storageClient      *storage.Client
+	resolver           *hierarchy.Resolver
+	projects           *discovery.Projects
+}
+
+// collectorService creates the collector of a service, both to collect its costs and to describe its metrics.
+// Registering the service once keeps the documented metrics in line with the services the exporter accepts.
+type collectorService struct {
+	// new creates the collector with the shared clients. The service is skipped when it returns a nil collector.
+	new func(c *clients, scrapeInterval time.Duration) (provider.Collector, error)
+	// forDocs creates the collector without any GCP clients, see NewForDocs.
+	forDocs func() (provider.Collector, error)
+}
+
+// collectorServices are the services the exporter accepts, keyed by their uppercased name.
+var collectorServices = map[string]collectorService{
+	"GCS": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			var monitoringService *monitoring.Service
+			if config.BucketCost {
+				var err error
+				monitoringService, err = monitoring.NewService(c.ctx, c.clientOptions("monitoring")...)
+				if err != nil {
+					return nil, fmt.Errorf("error creating monitoringService: %w", err)
+				}
+			}
+			collector, err := gcs.New(&gcs.Config{
 				ProjectId:       config.ProjectId,
 				Projects:        config.Projects,
-				ScrapeInterval:  config.ScrapeInterval,
+				ScrapeInterval:  scrapeInterval,
 				DefaultDiscount: config.DefaultDiscount,
-			}, cloudCatalogClient, regionsClient, storageClient)
+			}, c.cloudCatalogClient, c.regionsClient, c.storageClient, monitoringService)
 			if err != nil {
 				log.Printf("Error creating GCS collector: %s", err)
-				continue
+				return nil, nil
+			}
+			return collector, nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return gcs.New(&gcs.Config{ProjectId: docsProjectId}, nil, nil, nil, nil)
+		},
+	},
+	"COMPUTE": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			var monitoringService *monitoring.Service
+			if config.IdleCost {
+				var err error
+				monitoringService, err = monitoring.NewService(c.ctx, c.clientOptions("monitoring")...)
+				if err != nil {
+					return nil, fmt.Errorf("error creating monitoringService: %w", err)
+				}
+			}
+			return compute.New(&compute.Config{
+				Projects:       config.Projects,
+				Discovery:      c.projects,
+				ScrapeInterval: scrapeInterval,
+				Hierarchy:      c.resolver,
+				InstanceFilter: config.InstanceFilter,
+				PriceHistory:   config.PriceHistory,
+				PriceLookup:    config.PriceLookup,
+				Families:       config.MachineFamilies,
+			}, c.computeService, c.cloudCatalogClient, monitoringService), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return compute.New(&compute.Config{}, nil, nil, nil), nil
+		},
+	},
+	"GKE": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			containerService, err := container.NewService(c.ctx, c.clientOptions("container")...)
+			if err != nil {
+				return nil, fmt.Errorf("error creating containerService: %w", err)
 			}
-		case "COMPUTE":
-			collector = compute.New(&compute.Config{
+			return gke.New(&gke.Config{
+				Projects:        config.Projects,
+				Discovery:       c.projects,
+				ScrapeInterval:  scrapeInterval,
+				Hierarchy:       c.resolver,
+				ClusterNames:    config.ClusterNames,
+				Nodes:           config.Nodes,
+				Pods:            config.Pods,
+				Calendar:        config.Calendar,
+				Anomalies:       config.Anomalies,
+				Recommendations: config.Recommendations,
+				Headroom:        config.Headroom,
+				Prices:          config.Prices,
+				InstanceFilter:  config.InstanceFilter,
+				StorageClasses:  config.StorageClasses,
+				Volumes:         config.Volumes,
+				Families:        config.MachineFamilies,
+			}, c.computeService, c.cloudCatalogClient, containerService), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return gke.New(&gke.Config{}, nil, nil, nil), nil
+		},
+	},
+	"COMMITMENTS": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			return commitments.New(&commitments.Config{
 				Projects:       config.Projects,
-				ScrapeInterval: config.ScrapeInterval,
-			}, computeService, cloudCatalogClient)
-		case "GKE":
-			collector = gke.New(&gke.Config{
+				ScrapeInterval: scrapeInterval,
+			}, c.computeService), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return commitments.New(&commitments.Config{}, nil), nil
+		},
+	},
+	"MESSAGING": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			return messaging.New(scrapeInterval, c.cloudCatalogClient), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return messaging.New(0, nil), nil
+		},
+	},
+	"OBSERVABILITY": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			monitoringService, err := monitoring.NewService(c.ctx, c.clientOptions("monitoring")...)
+			if err != nil {
+				return nil, fmt.Errorf("error creating monitoringService: %w", err)
+			}
+			return observability.New(&observability.Config{
 				Projects:       config.Projects,
-				ScrapeInterval: config.ScrapeInterval,
-			}, computeService, cloudCatalogClient)
-		default:
-			log.Printf("Unknown service %s", service)
-			// Continue to next service, no need to halt here
-			continue
+				ScrapeInterval: scrapeInterval,
+			}, c.cloudCatalogClient, monitoringService), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return observability.New(&observability.Config{}, nil, nil), nil
+		},
+	},
+	"CLB": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			return clb.New(&clb.Config{
+				Projects:       config.Projects,
+				ScrapeInterval: scrapeInterval,
+				Logger:         config.Logger,
+			}, c.cloudCatalogClient, c.computeService), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return clb.New(&clb.Config{}, nil, nil), nil
+		},
+	},
+	"SPANNER": {
+		new: func(c *clients, scrapeInterval time.Duration) (provider.Collector, error) {
+			config := c.config
+			spannerService, err := spannerv1.NewService(c.ctx, c.clientOptions("spanner")...)
+			if err != nil {
+				return nil, fmt.Errorf("error creating spannerService: %w", err)
+			}
+			return spanner.New(&spanner.Config{
+				Projects:       config.Projects,
+				ScrapeInterval: scrapeInterval,
+			}, c.cloudCatalogClient, spannerService), nil
+		},
+		forDocs: func() (provider.Collector, error) {
+			return spanner.New(&spanner.Config{}, nil, nil), nil
+		},
+	},
+}
+
+// newAuthenticatedHTTPClient adds the credentials selected by authOptions on top of the transport of httpClient, as
+// option.WithHTTPClient bypasses the authentication of the clients. It returns nil when httpClient is nil.
+func newAuthenticatedHTTPClient(ctx context.Context, httpClient *http.Client, authOptions ...option.ClientOption) (*http.Client, error) {
+	if httpClient == nil {
+		return nil, nil
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, err := htransport.NewTransport(ctx, base, append(authOptions, option.WithScopes(cloudPlatformScope))...)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: httpClient.Timeout}, nil
+}
+
+// NewForDocs returns a GCP provider with the collector of every service but without any GCP clients. It can't collect
+// anything and is only meant to describe the metrics the exporter exposes, eg for `cloudcost-exporter docs metrics`.
+func NewForDocs() (*GCP, error) {
+	names := make([]string, 0, len(collectorServices))
+	for name := range collectorServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]provider.Collector, 0, len(names))
+	for _, name := range names {
+		collector, err := collectorServices[name].forDocs()
+		if err != nil {
+			return nil, err
 		}
 		collectors = append(collectors, collector)
 	}
 	return &GCP{
-		config:     config,
+		config:     &Config{ProjectId: docsProjectId},
 		collectors: collectors,
 	}, nil
 }
@@ -161,6 +518,11 @@ func New(config *Config) (*GCP, error) {
 func (g *GCP) RegisterCollectors(registry provider.Registry) error {
 	registry.MustRegister(providerScrapesTotalCounter)
 	registry.MustRegister(collectorScrapesTotalCounter)
+	registry.MustRegister(provider.ThrottledRefreshesTotal)
+	registry.MustRegister(provider.EmptyCollectionsTotal)
+	registry.MustRegister(provider.SeriesEmittedTotal)
+	registry.MustRegister(compute.UnpricedResourcesTotal)
+	registry.MustRegister(compute.MalformedPriceEntriesTotal)
 	for _, c := range g.collectors {
 		if err := c.Register(registry); err != nil {
 			return err
@@ -177,6 +539,7 @@ func (g *GCP) Describe(ch chan<- *prometheus.Desc) {
 	ch <- providerLastScrapeDurationDesc
 	ch <- collectorLastScrapeTime
 	ch <- providerLastScrapeTime
+	ch <- provider.CollectorUpDesc
 	for _, c := range g.collectors {
 		if err := c.Describe(ch); err != nil {
 			log.Printf("Error describing collector %s: %s", c.Name(), err)
@@ -186,6 +549,11 @@ func (g *GCP) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements the prometheus.Collector interface and will iterate over all the collectors instantiated during New and collect their metrics.
 func (g *GCP) Collect(ch chan<- prometheus.Metric) {
+	defer g.inFlight.Start()()
+	ctx := g.context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	wg := sync.WaitGroup{}
 	wg.Add(len(g.collectors))
 	start := time.Now()
@@ -194,7 +562,9 @@ func (g *GCP) Collect(ch chan<- prometheus.Metric) {
 			now := time.Now()
 			defer wg.Done()
 			collectorErrors := 0.0
-			if err := c.Collect(ch); err != nil {
+			err := g.watchdog.Collect(ctx, slog.Default(), subsystem, c, ch)
+			g.summary.Record(c.Name(), err)
+			if err != nil {
 				log.Printf("Error collecting metrics from collector %s: %s", c.Name(), err)
 				collectorErrors = 1.0
 			}
@@ -202,6 +572,7 @@ func (g *GCP) Collect(ch chan<- prometheus.Metric) {
 			ch <- prometheus.MustNewConstMetric(collectorLastScrapeErrorDesc, prometheus.GaugeValue, collectorErrors, subsystem, c.Name())
 			ch <- prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, time.Since(now).Seconds(), subsystem, c.Name())
 			ch <- prometheus.MustNewConstMetric(collectorLastScrapeTime, prometheus.GaugeValue, float64(time.Now().Unix()), subsystem, c.Name())
+			ch <- provider.NewCollectorUpMetric(subsystem, c.Name(), err)
 			collectorScrapesTotalCounter.WithLabelValues(subsystem, c.Name()).Inc()
 		}(c)
 	}
@@ -212,3 +583,26 @@ func (g *GCP) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(providerLastScrapeTime, prometheus.GaugeValue, float64(time.Now().Unix()), subsystem)
 	providerScrapesTotalCounter.WithLabelValues(subsystem).Inc()
 }
+
+// Close waits for the collections in flight, logs the collection summary of every collector and closes the gRPC and
+// storage clients, along with the idle connections of the HTTP client.
+func (g *GCP) Close(ctx context.Context) error {
+	err := g.inFlight.Wait(ctx)
+	g.summary.Log(ctx, slog.Default(), subsystem)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, c := range g.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if g.config.HTTPClient != nil {
+		g.config.HTTPClient.CloseIdleConnections()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("error closing gcp clients: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,13 @@
 package google
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
@@ -15,6 +18,23 @@ import (
 	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
+func Test_NewForDocs(t *testing.T) {
+	gcp, err := NewForDocs()
+	require.NoError(t, err)
+	var documented []string
+	for _, c := range gcp.collectors {
+		documented = append(documented, c.Name())
+	}
+	var accepted []string
+	for name, svc := range collectorServices {
+		require.NotNil(t, svc.new, name)
+		collector, err := svc.forDocs()
+		require.NoError(t, err, name)
+		accepted = append(accepted, collector.Name())
+	}
+	require.ElementsMatch(t, accepted, documented, "every accepted service is documented")
+}
+
 func Test_RegisterCollectors(t *testing.T) {
 	tests := map[string]struct {
 		numCollectors int
@@ -94,6 +114,12 @@ func TestGCP_CollectMetrics(t *testing.T) {
 					Value:      0,
 					MetricType: prometheus.GaugeValue,
 				},
+				{
+					FqName:     "cloudcost_exporter_collector_up",
+					Labels:     utils.LabelMap{"provider": "gcp", "collector": "test"},
+					Value:      0,
+					MetricType: prometheus.GaugeValue,
+				},
 				{
 					FqName:     "cloudcost_exporter_last_scrape_error",
 					Labels:     utils.LabelMap{"provider": "gcp"},
@@ -128,6 +154,12 @@ func TestGCP_CollectMetrics(t *testing.T) {
 					Value:      0,
 					MetricType: prometheus.GaugeValue,
 				},
+				{
+					FqName:     "cloudcost_exporter_collector_up",
+					Labels:     utils.LabelMap{"provider": "gcp", "collector": "test"},
+					Value:      1,
+					MetricType: prometheus.GaugeValue,
+				},
 				{
 					FqName:     "cloudcost_exporter_collector_last_scrape_error",
 					Labels:     utils.LabelMap{"provider": "gcp", "collector": "test"},
@@ -147,6 +179,12 @@ func TestGCP_CollectMetrics(t *testing.T) {
 					Value:      0,
 					MetricType: prometheus.GaugeValue,
 				},
+				{
+					FqName:     "cloudcost_exporter_collector_up",
+					Labels:     utils.LabelMap{"provider": "gcp", "collector": "test"},
+					Value:      1,
+					MetricType: prometheus.GaugeValue,
+				},
 
 				{
 					FqName:     "cloudcost_exporter_last_scrape_error",
@@ -168,7 +206,8 @@ func TestGCP_CollectMetrics(t *testing.T) {
 			if tt.collect != nil {
 				c.EXPECT().Name().Return("test").AnyTimes()
 				// TODO: @pokom need to figure out why _sometimes_ this fails if we set it to *.Times(tt.numCollectors)
-				c.EXPECT().Collect(ch).DoAndReturn(tt.collect).AnyTimes()
+				// The watchdog collects into a channel of its own to count the metrics
+				c.EXPECT().Collect(gomock.Any()).DoAndReturn(tt.collect).AnyTimes()
 				c.EXPECT().Register(registry).Return(nil).AnyTimes()
 			}
 			gcp := &GCP{
@@ -209,3 +248,46 @@ func TestGCP_CollectMetrics(t *testing.T) {
 		})
 	}
 }
+
+type fakeCloser struct {
+	err    error
+	closed bool
+}
+
+func (c *fakeCloser) Close() error {
+	c.closed = true
+	return c.err
+}
+
+func TestGCP_Close(t *testing.T) {
+	healthy := &fakeCloser{}
+	failing := &fakeCloser{err: fmt.Errorf("test close error")}
+	gcp := &GCP{
+		config:  &Config{},
+		closers: []io.Closer{failing, healthy},
+	}
+	gcp.summary.Record("test", nil)
+
+	err := gcp.Close(context.Background())
+	require.ErrorContains(t, err, "test close error")
+	// Every client is closed even when one of them fails
+	require.True(t, healthy.closed)
+	require.True(t, failing.closed)
+}
+
+func TestGCP_CloseInFlight(t *testing.T) {
+	client := &fakeCloser{}
+	gcp := &GCP{
+		config:  &Config{},
+		closers: []io.Closer{client},
+	}
+	done := gcp.inFlight.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, gcp.Close(ctx), provider.ErrCollectionsInFlight)
+	require.False(t, client.closed, "the clients of a collection in flight aren't closed")
+	done()
+	require.NoError(t, gcp.Close(context.Background()))
+	require.True(t, client.closed)
+}
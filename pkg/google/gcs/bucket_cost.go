@@ -0,0 +1,133 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+const (
+	// BucketSizeWindow is how far back the size of the buckets is looked up. Cloud Monitoring measures the size of a
+	// bucket once a day and repeats it in every sample of the day, so the latest sample of the window is kept.
+	BucketSizeWindow = time.Hour
+
+	totalBytesFilter = `metric.type = "storage.googleapis.com/storage/total_bytes"`
+	bytesPerGiB      = 1 << 30
+)
+
+// BucketSize is the number of bytes stored in a bucket in a storage class, as reported by Cloud Monitoring.
+type BucketSize struct {
+	Bucket       string
+	Location     string
+	StorageClass string
+	Bytes        float64
+}
+
+// ListBucketSizes returns the latest size of every bucket of a project by storage class, over the BucketSizeWindow
+// ending at now. Buckets without datapoints are left out of the result.
+func ListBucketSizes(ctx context.Context, service *monitoring.Service, project string, now time.Time) ([]BucketSize, error) {
+	var sizes []BucketSize
+	err := service.Projects.TimeSeries.List("projects/"+project).
+		Filter(totalBytesFilter).
+		IntervalStartTime(now.Add(-BucketSizeWindow).Format(time.RFC3339)).
+		IntervalEndTime(now.Format(time.RFC3339)).
+		AggregationAlignmentPeriod(fmt.Sprintf("%ds", int(BucketSizeWindow.Seconds()))).
+		AggregationPerSeriesAligner("ALIGN_NEXT_OLDER").
+		Pages(ctx, func(page *monitoring.ListTimeSeriesResponse) error {
+			for _, series := range page.TimeSeries {
+				if series.Metric == nil || series.Resource == nil || len(series.Points) == 0 {
+					continue
+				}
+				bytes, ok := pointValue(series.Points[0])
+				if !ok {
+					continue
+				}
+				sizes = append(sizes, BucketSize{
+					Bucket:       series.Resource.Labels["bucket_name"],
+					Location:     strings.ToLower(series.Resource.Labels["location"]),
+					StorageClass: series.Metric.Labels["storage_class"],
+					Bytes:        bytes,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+func pointValue(point *monitoring.Point) (float64, bool) {
+	switch {
+	case point.Value == nil:
+		return 0, false
+	case point.Value.DoubleValue != nil:
+		return *point.Value.DoubleValue, true
+	case point.Value.Int64Value != nil:
+		return float64(*point.Value.Int64Value), true
+	default:
+		return 0, false
+	}
+}
+
+// storagePrices holds the storage prices exported by StorageGauge in USD/(GiB*h), keyed by location and storage class.
+// It's safe for concurrent use.
+type storagePrices struct {
+	m      sync.RWMutex
+	prices map[string]float64
+}
+
+func newStoragePrices() *storagePrices {
+	return &storagePrices{prices: make(map[string]float64)}
+}
+
+func (p *storagePrices) set(location string, storageClass string, price float64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.prices[location+"/"+storageClass] = price
+}
+
+// get returns the price of the bytes of a bucket in a storage class. Cloud Monitoring reports the current storage
+// classes of the bytes, while the skus price the standard class as REGIONAL in a region and as MULTI_REGIONAL in a
+// multi-region or a dual-region, the way StorageClassFromSkuDescription names them.
+func (p *storagePrices) get(location string, storageClass string) (float64, bool) {
+	if storageClass == "STANDARD" {
+		storageClass = "MULTI_REGIONAL"
+		if strings.Contains(location, "-") {
+			storageClass = "REGIONAL"
+		}
+	}
+	p.m.RLock()
+	defer p.m.RUnlock()
+	price, ok := p.prices[location+"/"+storageClass]
+	return price, ok
+}
+
+// ExportBucketCosts exports the estimated hourly cost of the bytes stored in every bucket of the projects, which is
+// the size of the bucket in a storage class multiplied by the storage price of the class in the location of the
+// bucket. The sizes of a project that can't be listed are left out, as are the bytes in a class that isn't priced.
+func ExportBucketCosts(ctx context.Context, service *monitoring.Service, projects []string, m *Metrics) error {
+	now := time.Now()
+	m.BucketEstimatedCost.Reset()
+	var errs []error
+	for _, project := range projects {
+		sizes, err := ListBucketSizes(ctx, service, project, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error listing the bucket sizes of %s: %w", project, err))
+			continue
+		}
+		for _, size := range sizes {
+			price, ok := m.prices.get(size.Location, size.StorageClass)
+			if !ok {
+				continue
+			}
+			m.BucketEstimatedCost.WithLabelValues(size.Bucket, size.Location, size.StorageClass).Set(size.Bytes / bytesPerGiB * price)
+		}
+	}
+	return errors.Join(errs...)
+}
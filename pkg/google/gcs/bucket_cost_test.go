@@ -0,0 +1,117 @@
+package gcs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+func sizeSeries(bucket string, location string, storageClass string, bytes float64) *monitoring.TimeSeries {
+	return &monitoring.TimeSeries{
+		Metric:   &monitoring.Metric{Labels: map[string]string{"storage_class": storageClass}},
+		Resource: &monitoring.MonitoredResource{Labels: map[string]string{"bucket_name": bucket, "location": location}},
+		Points:   []*monitoring.Point{{Value: &monitoring.TypedValue{DoubleValue: &bytes}}},
+	}
+}
+
+// newMonitoringService returns a Cloud Monitoring client listing the time series of every project from pages, or
+// failing with statusCode when it's set.
+func newMonitoringService(t *testing.T, pages map[string]*monitoring.ListTimeSeriesResponse, statusCode int) *monitoring.Service {
+	t.Helper()
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if statusCode != 0 {
+			w.WriteHeader(statusCode)
+			return
+		}
+		assert.Equal(t, totalBytesFilter, r.URL.Query().Get("filter"))
+		assert.Equal(t, "ALIGN_NEXT_OLDER", r.URL.Query().Get("aggregation.perSeriesAligner"))
+		page, ok := pages[strings.TrimPrefix(r.URL.Path, "/v3/projects/")+r.URL.Query().Get("pageToken")]
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(testServer.Close)
+	service, err := monitoring.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+	return service
+}
+
+func TestListBucketSizes(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	bytes := int64(1 << 30)
+	service := newMonitoringService(t, map[string]*monitoring.ListTimeSeriesResponse{
+		"testing/timeSeries": {
+			TimeSeries: []*monitoring.TimeSeries{
+				sizeSeries("logs", "US-EAST1", "STANDARD", 2*bytesPerGiB),
+				// Series without points are skipped
+				{
+					Metric:   &monitoring.Metric{Labels: map[string]string{"storage_class": "STANDARD"}},
+					Resource: &monitoring.MonitoredResource{Labels: map[string]string{"bucket_name": "empty"}},
+				},
+			},
+			NextPageToken: "1",
+		},
+		"testing/timeSeries1": {
+			TimeSeries: []*monitoring.TimeSeries{
+				{
+					Metric:   &monitoring.Metric{Labels: map[string]string{"storage_class": "NEARLINE"}},
+					Resource: &monitoring.MonitoredResource{Labels: map[string]string{"bucket_name": "logs", "location": "us-east1"}},
+					Points:   []*monitoring.Point{{Value: &monitoring.TypedValue{Int64Value: &bytes}}},
+				},
+			},
+		},
+	}, 0)
+
+	got, err := ListBucketSizes(context.Background(), service, "testing", now)
+	require.NoError(t, err)
+	assert.Equal(t, []BucketSize{
+		{Bucket: "logs", Location: "us-east1", StorageClass: "STANDARD", Bytes: 2 * bytesPerGiB},
+		{Bucket: "logs", Location: "us-east1", StorageClass: "NEARLINE", Bytes: bytesPerGiB},
+	}, got)
+
+	_, err = ListBucketSizes(context.Background(), newMonitoringService(t, nil, http.StatusForbidden), "testing", now)
+	require.Error(t, err)
+}
+
+func TestExportBucketCosts(t *testing.T) {
+	service := newMonitoringService(t, map[string]*monitoring.ListTimeSeriesResponse{
+		"project-1/timeSeries": {
+			TimeSeries: []*monitoring.TimeSeries{
+				sizeSeries("regional", "US-EAST1", "STANDARD", 2*bytesPerGiB),
+				sizeSeries("regional", "US-EAST1", "NEARLINE", 4*bytesPerGiB),
+				sizeSeries("multi-regional", "US", "STANDARD", 10*bytesPerGiB),
+				// There's no price of the archive class in us-east1
+				sizeSeries("regional", "US-EAST1", "ARCHIVE", bytesPerGiB),
+			},
+		},
+	}, 0)
+	m := NewMetrics()
+	m.prices.set("us-east1", "REGIONAL", 0.02)
+	m.prices.set("us-east1", "NEARLINE", 0.01)
+	m.prices.set("us", "MULTI_REGIONAL", 0.025)
+	// A stale bucket is dropped
+	m.BucketEstimatedCost.WithLabelValues("deleted", "us", "STANDARD").Set(1)
+
+	err := ExportBucketCosts(context.Background(), service, []string{"project-1", "project-2"}, m)
+	require.ErrorContains(t, err, "project-2")
+
+	require.NoError(t, testutil.CollectAndCompare(m.BucketEstimatedCost, strings.NewReader(`
+# HELP cloudcost_gcp_gcs_bucket_estimated_usd_per_hour Estimated storage cost of a GCS bucket by storage_class, its size reported by Cloud Monitoring multiplied by the storage price of its location. Cost represented in USD/h
+# TYPE cloudcost_gcp_gcs_bucket_estimated_usd_per_hour gauge
+cloudcost_gcp_gcs_bucket_estimated_usd_per_hour{bucket_name="multi-regional",location="us",storage_class="STANDARD"} 0.25
+cloudcost_gcp_gcs_bucket_estimated_usd_per_hour{bucket_name="regional",location="us-east1",storage_class="NEARLINE"} 0.04
+cloudcost_gcp_gcs_bucket_estimated_usd_per_hour{bucket_name="regional",location="us-east1",storage_class="STANDARD"} 0.04
+`)))
+}
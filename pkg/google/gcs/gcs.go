@@ -19,8 +19,10 @@ import (
 	"github.com/googleapis/gax-go/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/monitoring/v3"
 
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const subsystem = "gcp_gcs"
@@ -31,9 +33,12 @@ type Metrics struct {
 	OperationsGauge         *prometheus.GaugeVec
 	OperationsDiscountGauge *prometheus.GaugeVec
 	BucketInfo              *prometheus.GaugeVec
+	BucketEstimatedCost     *prometheus.GaugeVec
 	BucketListHistogram     *prometheus.HistogramVec
 	BucketListStatus        *prometheus.CounterVec
 	NextScrapeGauge         prometheus.Gauge
+	// prices holds the prices of StorageGauge, so that the cost of the buckets can be estimated from their size.
+	prices *storagePrices
 }
 
 func NewMetrics() *Metrics {
@@ -65,6 +70,12 @@ func NewMetrics() *Metrics {
 		},
 			[]string{"location", "location_type", "storage_class", "bucket_name"},
 		),
+		BucketEstimatedCost: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "bucket_estimated_usd_per_hour"),
+			Help: "Estimated storage cost of a GCS bucket by storage_class, its size reported by Cloud Monitoring multiplied by the storage price of its location. Cost represented in USD/h",
+		},
+			[]string{"bucket_name", "location", "storage_class"},
+		),
 		// todo: every module so far has a "next_scrape" metric. Should we have a metric cloudcost_exporter_next_scrape{module=<gcp_gcs,gcp_compute,aws...>}?
 		NextScrapeGauge: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, subsystem, "next_scrape"),
@@ -79,7 +90,9 @@ func NewMetrics() *Metrics {
 		BucketListStatus: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, subsystem, "bucket_list_status_total"),
 			Help: "Status of GCS bucket list operations",
-		}, []string{"project_id", "status"})}
+		}, []string{"project_id", "status"}),
+		prices: newStoragePrices(),
+	}
 }
 
 var (
@@ -171,6 +184,8 @@ type Collector struct {
 	discount           int
 	CachedBuckets      *BucketCache
 	metrics            *Metrics
+	// monitoringService is only set when the cost of the buckets is estimated
+	monitoringService *monitoring.Service
 }
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
@@ -193,7 +208,9 @@ type RegionsClient interface {
 	List(ctx context.Context, req *computepb.ListRegionsRequest, opts ...gax.CallOption) *compute.RegionIterator
 }
 
-func New(config *Config, cloudCatalogClient *billingv1.CloudCatalogClient, regionsClient RegionsClient, storageClient StorageClientInterface) (*Collector, error) {
+// New creates a GCS collector. monitoringService is optional, when set the estimated cost of every bucket is exported
+// based upon its size reported by Cloud Monitoring.
+func New(config *Config, cloudCatalogClient *billingv1.CloudCatalogClient, regionsClient RegionsClient, storageClient StorageClientInterface, monitoringService *monitoring.Service) (*Collector, error) {
 	if config.ProjectId == "" {
 		return nil, fmt.Errorf("projectID cannot be empty")
 	}
@@ -216,9 +233,10 @@ func New(config *Config, cloudCatalogClient *billingv1.CloudCatalogClient, regio
 		ctx:                ctx,
 		interval:           config.ScrapeInterval,
 		// Set nextScrape to the current time minus the scrape interval so that the first scrape will run immediately
-		nextScrape:    time.Now().Add(-config.ScrapeInterval),
-		CachedBuckets: NewBucketCache(),
-		metrics:       NewMetrics(),
+		nextScrape:        time.Now().Add(-config.ScrapeInterval),
+		CachedBuckets:     NewBucketCache(),
+		metrics:           NewMetrics(),
+		monitoringService: monitoringService,
 	}, nil
 }
 
@@ -234,6 +252,7 @@ func (c *Collector) Register(registry provider.Registry) error {
 	registry.MustRegister(c.metrics.OperationsDiscountGauge)
 	registry.MustRegister(c.metrics.OperationsGauge)
 	registry.MustRegister(c.metrics.BucketInfo)
+	registry.MustRegister(c.metrics.BucketEstimatedCost)
 	registry.MustRegister(c.metrics.BucketListHistogram)
 	registry.MustRegister(c.metrics.BucketListStatus)
 	registry.MustRegister(c.metrics.NextScrapeGauge)
@@ -251,7 +270,7 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 		// TODO: We should stuff in logic here to update pricing data if it's been more than 24 hours
 		return 1
 	}
-	c.nextScrape = time.Now().Add(c.interval)
+	c.nextScrape = utils.NextScrape(time.Now(), c.interval)
 	c.metrics.NextScrapeGauge.Set(float64(c.nextScrape.Unix()))
 	ExporterOperationsDiscounts(c.metrics)
 	err := ExportRegionalDiscounts(c.ctx, c.regionsClient, c.ProjectID, c.discount, c.metrics)
@@ -268,7 +287,14 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 		log.Printf("Error getting service name: %v", err)
 		return 0
 	}
-	return ExportGCPCostData(c.ctx, c.cloudCatalogClient, serviceName, c.metrics)
+	up := ExportGCPCostData(c.ctx, c.cloudCatalogClient, serviceName, c.metrics)
+	if c.monitoringService != nil {
+		// The storage prices are only known once the skus are parsed
+		if err := ExportBucketCosts(c.ctx, c.monitoringService, c.Projects, c.metrics); err != nil {
+			log.Printf("Error exporting bucket costs: %v", err)
+		}
+	}
+	return up
 }
 
 // ExportBucketInfo will list all buckets for a given project and export the data as a prometheus metric.
@@ -350,7 +376,11 @@ func ExporterOperationsDiscounts(m *Metrics) {
 
 // ExportGCPCostData will collect all the pricing information for the passed in serviceName and export cost related metrics for each sku
 func ExportGCPCostData(ctx context.Context, client *billingv1.CloudCatalogClient, serviceName string, m *Metrics) float64 {
-	skus := billing.GetPricing(ctx, client, serviceName)
+	skus, err := billing.GetPricing(ctx, client, serviceName)
+	if err != nil {
+		// Keep going with the skus that were listed
+		log.Printf("error listing the skus of %s: %s", serviceName, err)
+	}
 	for _, sku := range skus {
 		// Skip Egress and Download costs as we don't count them yet
 		// Check category first as I've had random segfaults locally
@@ -427,6 +457,7 @@ func parseStorageSku(sku *billingpb.Sku, m *Metrics) error {
 	region := RegionNameSameAsStackdriver(sku.ServiceRegions[0])
 	storageclass := StorageClassFromSkuDescription(sku.Description, region)
 	m.StorageGauge.WithLabelValues(region, storageclass).Set(price)
+	m.prices.set(region, storageclass, price)
 	return nil
 }
 
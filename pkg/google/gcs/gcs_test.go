@@ -161,7 +161,7 @@ func TestNew(t *testing.T) {
 	t.Run("should return a non-nil client", func(t *testing.T) {
 		gcsCollector, err := New(&Config{
 			ProjectId: "project-1",
-		}, nil, regionsClient, storageClient)
+		}, nil, regionsClient, storageClient, nil)
 		assert.NoError(t, err)
 		assert.NotNil(t, gcsCollector)
 	})
@@ -169,7 +169,7 @@ func TestNew(t *testing.T) {
 	t.Run("collectorName should be GCS", func(t *testing.T) {
 		gcsCollector, _ := New(&Config{
 			ProjectId: "project-1",
-		}, nil, regionsClient, storageClient)
+		}, nil, regionsClient, storageClient, nil)
 		assert.Equal(t, "GCS", gcsCollector.Name())
 	})
 }
@@ -556,7 +556,7 @@ func TestCollector_Collect(t *testing.T) {
 	assert.NoError(t, err)
 	collector, err := New(&Config{
 		ProjectId: "project-1",
-	}, cloudCatalogClient, regionsClient, storageClient)
+	}, cloudCatalogClient, regionsClient, storageClient, nil)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, collector)
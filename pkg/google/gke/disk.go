@@ -24,6 +24,7 @@ type Disk struct {
 	Project     string
 	name        string // Name of the disk as it appears in the GCP console. Used as a backup if the name can't be extracted from the description
 	zone        string
+	region      string // URL of the region of a regional disk, which is replicated across two zones and has no zone
 	labels      map[string]string
 	description map[string]string
 	diskType    string // type is a reserved word, which is why we're using diskType
@@ -37,6 +38,7 @@ func NewDisk(disk *compute.Disk, project string) *Disk {
 		Project:     project,
 		name:        disk.Name,
 		zone:        disk.Zone,
+		region:      disk.Region,
 		diskType:    disk.Type,
 		labels:      disk.Labels,
 		description: make(map[string]string),
@@ -55,9 +57,17 @@ func (d Disk) Namespace() string {
 	return coalesce(d.description, pvcNamespaceKey, pvcNamespaceShortKey)
 }
 
+// Regional returns true when the disk is a regional persistent disk, which is priced separately from the zonal disks.
+func (d Disk) Regional() bool {
+	return d.region != ""
+}
+
 // Region will return the region of the disk by search through the zone field and returning the region. If the region can't be determined
-// It will return an empty string
+// It will return an empty string. Regional disks return the region they belong to.
 func (d Disk) Region() string {
+	if d.Regional() {
+		return d.region[strings.LastIndex(d.region, "/")+1:]
+	}
 	zone := d.labels[gcpCompute.GkeRegionLabel]
 	if zone == "" {
 		// This would be a case where the disk is no longer mounted _or_ the disk is associated with a Compute instance
@@ -74,6 +84,11 @@ func (d Disk) Region() string {
 	return zone[:strings.LastIndex(zone, "-")]
 }
 
+// Zone returns the zone of a zonal disk. Regional disks are replicated across two zones, so their zone is empty.
+func (d Disk) Zone() string {
+	return d.zone[strings.LastIndex(d.zone, "/")+1:]
+}
+
 // Name will return the name of the disk. If the disk has a label "kubernetes.io/created-for/pv/name" it will return the value stored in that key.
 // otherwise it will return the disk name that is directly associated with the disk.
 func (d Disk) Name() string {
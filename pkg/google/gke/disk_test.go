@@ -118,6 +118,15 @@ func Test_getRegionFromDisk(t *testing.T) {
 			}, ""),
 			want: "us-central1",
 		},
+		"Regional disk should return the region of its URL rather than the zone of its label": {
+			disk: NewDisk(&computev1.Disk{
+				Region: "https://www.googleapis.com/compute/v1/projects/123/regions/europe-west1",
+				Labels: map[string]string{
+					compute.GkeRegionLabel: "us-central1-f",
+				},
+			}, ""),
+			want: "europe-west1",
+		},
 		"Disk with a label doesn't belong to a specific zone should return the full label": {
 			disk: NewDisk(&computev1.Disk{
 				Labels: map[string]string{
@@ -136,6 +145,36 @@ func Test_getRegionFromDisk(t *testing.T) {
 	}
 }
 
+func TestDisk_Zone(t *testing.T) {
+	tests := map[string]struct {
+		disk *Disk
+		want string
+	}{
+		"Zone formatted as a path should return the zone": {
+			disk: NewDisk(&computev1.Disk{
+				Zone: "projects/123/zones/us-central1-a",
+				Labels: map[string]string{
+					compute.GkeRegionLabel: "us-central1",
+				},
+			}, ""),
+			want: "us-central1-a",
+		},
+		"Regional disk should return an empty zone": {
+			disk: NewDisk(&computev1.Disk{
+				Region: "https://www.googleapis.com/compute/v1/projects/123/regions/europe-west1",
+			}, ""),
+			want: "",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.disk.Zone(); got != tt.want {
+				t.Errorf("Zone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_getNameFromDisk(t *testing.T) {
 	tests := map[string]struct {
 		disk *Disk
@@ -2,8 +2,10 @@ package gke
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,16 +13,32 @@ import (
 	billingv1 "cloud.google.com/go/billing/apiv1"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/container/v1"
 
+	"github.com/grafana/cloudcost-exporter/pkg/anomaly"
+	"github.com/grafana/cloudcost-exporter/pkg/clustername"
+	"github.com/grafana/cloudcost-exporter/pkg/commitment"
 	"github.com/grafana/cloudcost-exporter/pkg/google/billing"
 	gcpCompute "github.com/grafana/cloudcost-exporter/pkg/google/compute"
+	"github.com/grafana/cloudcost-exporter/pkg/google/discovery"
+	"github.com/grafana/cloudcost-exporter/pkg/google/hierarchy"
+	"github.com/grafana/cloudcost-exporter/pkg/headroom"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
 
 	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/pricesource"
+	"github.com/grafana/cloudcost-exporter/pkg/pricing"
 	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/schedule"
+	"github.com/grafana/cloudcost-exporter/pkg/storageclass"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
 )
 
 const (
-	subsystem = "gcp_gke"
+	subsystem    = "gcp_gke"
+	providerName = "gcp"
+
+	instanceStatusRunning = "RUNNING"
 )
 
 var (
@@ -29,36 +47,87 @@ var (
 
 		"The cpu cost a GKE Instance in USD/(core*h)",
 		// Cannot simply do cluster because many metric scrapers will add a label for cluster and would interfere with the label we want to add
-		[]string{"cluster_name", "instance", "region", "family", "machine_type", "project", "price_tier"},
+		[]string{"cluster_name", "instance", "region", "zone", "family", "machine_type", "project", "price_tier", "node_pool", "provisioning_model", "confidential", "folder", "org"},
 		nil,
 	)
 	gkeNodeCPUHourlyCostDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "instance_cpu_usd_per_core_hour"),
 		"The memory cost of a GKE Instance in USD/(GiB*h)",
 		// Cannot simply do cluster because many metric scrapers will add a label for cluster and would interfere with the label we want to add
-		[]string{"cluster_name", "instance", "region", "family", "machine_type", "project", "price_tier"},
+		[]string{"cluster_name", "instance", "region", "zone", "family", "machine_type", "project", "price_tier", "node_pool", "provisioning_model", "confidential", "folder", "org"},
 		nil,
 	)
 	persistentVolumeHourlyCostDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "persistent_volume_usd_per_hour"),
 		"The cost of a GKE Persistent Volume in USD.",
-		[]string{"cluster_name", "namespace", "persistentvolume", "region", "project", "storage_class", "disk_type"},
+		[]string{"cluster_name", "namespace", "persistentvolume", "region", "zone", "project", "storage_class", "disk_type", "folder", "org"},
+		nil,
+	)
+	// persistentVolumeCostTotalDesc accumulates the hourly cost of every persistent volume between scrapes, see
+	// utils.CostCounter. The instances of GKE clusters are accumulated by the compute collector.
+	persistentVolumeCostTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "persistent_volume_usd_total"),
+		"The cost of a GKE Persistent Volume in USD accumulated by the exporter since it first saw the volume.",
+		[]string{"cluster_name", "namespace", "persistentvolume", "region", "zone", "project", "storage_class", "disk_type", "folder", "org"},
+		nil,
+	)
+	// snapshotHourlyCostDesc is the storage cost of the snapshots of the persistent disks. source_disk is the name of
+	// the persistent volume of the disk, or the name of the disk.
+	snapshotHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "snapshot_usd_per_hour"),
+		"The storage cost of a snapshot of a persistent disk in USD/h. region is the region or multi-region the snapshot is stored in.",
+		[]string{"cluster_name", "snapshot", "source_disk", "region", "project", "snapshot_type", "folder", "org"},
 		nil,
 	)
 )
 
 type Config struct {
-	Projects       string
+	Projects string
+	// Discovery lists the projects to collect instead of Projects when it's set.
+	Discovery      *discovery.Projects
 	ScrapeInterval time.Duration
+	// Hierarchy resolves the folder and organization labels of a project, they are left empty when it's nil.
+	Hierarchy *hierarchy.Resolver
+	// ClusterNames normalizes the cluster_name label, names are exported as labeled by GKE when it's nil.
+	ClusterNames *clustername.Normalizer
+	// Nodes enables the allocatable cost metrics of the nodes of the cluster the exporter runs in.
+	Nodes kubernetes.NodeLister
+	// Pods enables, along with Nodes, the allocation of the cost of the nodes to the namespaces of their pods.
+	Pods kubernetes.PodLister
+	// Calendar enables the actual and expected cost metrics of the clusters with a scale down schedule.
+	Calendar *schedule.Calendar
+	// Anomalies enables the anomaly scores of the cost of the clusters.
+	Anomalies *anomaly.Detector
+	// Recommendations enables the commitment recommendations of the on-demand cores of the clusters.
+	Recommendations *commitment.Recommender
+	// Headroom enables the cost of the nodes of the autoscaled node pools above their minimum size. It requires the
+	// node pools to be listed through the Container API.
+	Headroom bool
+	// Prices overrides or discounts the on-demand prices of the instances of the clusters.
+	Prices pricesource.Layers
+	// InstanceFilter is a filter expression of the instances.list API, every instance is listed when it's empty.
+	InstanceFilter string
+	// StorageClasses exports the price sheet of the storage classes, and adjusts the cost of the persistent volumes
+	// of the cluster the exporter runs in by their storage class along with Volumes which lists them.
+	StorageClasses *storageclass.Classes
+	Volumes        kubernetes.PersistentVolumeLister
+	// Families selects the machine families whose prices are kept, every family is priced when it's nil.
+	Families *pricing.FamilyFilter
 }
 
 type Collector struct {
 	computeService    *compute.Service
 	billingService    *billingv1.CloudCatalogClient
+	gkeClient         *gkeClient
 	config            *Config
 	Projects          []string
 	ComputePricingMap *gcpCompute.StructuredPricingMap
 	NextScrape        time.Time
+	backoff           *provider.Backoff
+	volumeCosts       *utils.CostCounter
+	// machineShapes is only used to price instances of the clusters with a scale down schedule, anomaly scores,
+	// commitment recommendations, autoscaler headroom or price overrides
+	machineShapes *gcpCompute.MachineShapes
 }
 
 func (c *Collector) Register(_ provider.Registry) error {
@@ -76,33 +145,105 @@ func (c *Collector) CollectMetrics(ch chan<- prometheus.Metric) float64 {
 
 func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 	ctx := context.TODO()
-	if c.ComputePricingMap == nil || time.Now().After(c.NextScrape) {
-		serviceName, err := billing.GetServiceName(ctx, c.billingService, "Compute Engine")
+	now := time.Now()
+	if c.ComputePricingMap == nil || now.After(c.NextScrape) {
+		err := c.refreshPricingMap(ctx)
+		c.NextScrape = c.backoff.Next(now, err)
 		if err != nil {
 			return err
 		}
-		skus := billing.GetPricing(ctx, c.billingService, serviceName)
-		c.ComputePricingMap, err = gcpCompute.GeneratePricingMap(skus)
-		if err != nil {
-			return err
-		}
-		c.NextScrape = time.Now().Add(c.config.ScrapeInterval)
 	}
+	c.backoff.Emit(ch)
+	for _, metric := range c.ComputePricingMap.StorageClassMetrics() {
+		ch <- metric
+	}
+	for _, metric := range c.ComputePricingMap.KubernetesStorageClassMetrics(c.config.StorageClasses.ForProvider(storageclass.ProviderGCP)) {
+		ch <- metric
+	}
+	classes := c.config.StorageClasses.ByVolume(ctx, storageclass.ProviderGCP, c.config.Volumes)
 
-	for _, project := range c.Projects {
+	unpriced := gcpCompute.NewUnpricedMachineTypes(subsystem)
+	defer unpriced.Emit(ch)
+	defer c.volumeCosts.Emit(ch)
+	inventory := kubernetes.NewInventory(kubernetes.NodesByName(ctx, c.config.Nodes))
+	allocation := kubernetes.NewAllocation(kubernetes.PodsByNode(ctx, c.config.Pods))
+	defer func() {
+		for _, m := range inventory.Metrics(providerName) {
+			ch <- m
+		}
+		for _, m := range allocation.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	spend := c.config.Calendar.Spend()
+	defer func() {
+		for _, m := range spend.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	clusterCosts := c.config.Anomalies.Scrape()
+	defer func() {
+		for _, m := range clusterCosts.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	usage := c.config.Recommendations.Scrape()
+	defer func() {
+		for _, m := range usage.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	headroomCosts := headroom.NewScrape(c.config.Headroom && c.gkeClient != nil)
+	defer func() {
+		for _, m := range headroomCosts.Metrics(providerName) {
+			ch <- m
+		}
+	}()
+	projects, err := c.projects(ctx)
+	if err != nil {
+		return fmt.Errorf("error discovering projects: %w", err)
+	}
+	var failedProjects []error
+	projectErrs := make(map[string]error, len(projects))
+	defer func() {
+		for project, err := range projectErrs {
+			ch <- provider.NewScopeErrorMetric(providerName, subsystem, project, err)
+		}
+	}()
+	for _, project := range projects {
 		zones, err := c.computeService.Zones.List(project).Do()
+		projectErrs[project] = err
 		if err != nil {
-			return err
+			// A single project failing, eg due to missing permissions, shouldn't prevent the other projects from being exported
+			log.Printf("error listing zones in project %s: %v", project, err)
+			failedProjects = append(failedProjects, fmt.Errorf("project %s: %w", project, err))
+			continue
 		}
+		ancestry := c.config.Hierarchy.Ancestry(ctx, project)
+		if c.gkeClient != nil {
+			nodePools, err := c.gkeClient.ListNodePools(ctx, project)
+			if err != nil {
+				// Node pool metadata is only used for enrichment, so a failure shouldn't prevent the cost metrics from being emitted
+				log.Printf("error listing node pools in project %s: %v", project, err)
+			}
+			for _, nodePool := range nodePools {
+				nodePool.ClusterName = c.config.ClusterNames.Normalize(nodePool.ClusterName)
+				ch <- prometheus.MustNewConstMetric(nodePoolInfoDesc, prometheus.GaugeValue, 1, nodePool.labelValues(project, ancestry)...)
+				if nodePool.Autoscaling {
+					headroomCosts.SetMinNodes(nodePool.ClusterName, nodePool.Name, nodePool.TotalMinNodes)
+				}
+			}
+		}
+		regions := zoneRegions(zones.Items)
 		wg := sync.WaitGroup{}
-		// Multiply by 2 because we are making two requests per zone
-		wg.Add(len(zones.Items) * 2)
+		// Multiply by 2 because we are making two requests per zone, plus one request per region for the regional disks
+		wg.Add(len(zones.Items)*2 + len(regions))
 		instances := make(chan []*gcpCompute.MachineSpec, len(zones.Items))
-		disks := make(chan []*compute.Disk, len(zones.Items))
+		disks := make(chan []*compute.Disk, len(zones.Items)+len(regions))
 		for _, zone := range zones.Items {
 			go func(zone *compute.Zone) {
 				defer wg.Done()
-				results, err := gcpCompute.ListInstancesInZone(project, zone.Name, c.computeService)
+				results, err := gcpCompute.ListInstancesInZone(project, zone.Name, c.config.InstanceFilter, c.computeService)
 				if err != nil {
 					log.Printf("error listing instances in zone %s: %v", zone.Name, err)
 					instances <- nil
@@ -120,6 +261,17 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 				disks <- results
 			}(zone)
 		}
+		for _, region := range regions {
+			go func(region string) {
+				defer wg.Done()
+				results, err := ListRegionalDisks(project, region, c.computeService)
+				if err != nil {
+					log.Printf("error listing regional disks in region %s: %v", region, err)
+					return
+				}
+				disks <- results
+			}(region)
+		}
 
 		go func() {
 			wg.Wait()
@@ -137,18 +289,42 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 				if clusterName == "" {
 					continue
 				}
+				// Only nodes of the cluster the exporter runs in are known, and GKE names nodes after their instance
+				if instance.Status == instanceStatusRunning {
+					inventory.Observe(c.config.ClusterNames.Normalize(clusterName), instance.Instance, instance.CreatedAt)
+				}
 				labelValues := []string{
-					clusterName,
+					c.config.ClusterNames.Normalize(clusterName),
 					instance.Instance,
 					instance.Region,
+					instance.Zone,
 					instance.Family,
 					instance.MachineType,
 					project,
 					instance.PriceTier,
+					instance.GetNodePoolName(),
+					instance.ProvisioningModel,
+					strconv.FormatBool(instance.Confidential),
+					ancestry.Folder,
+					ancestry.Org,
 				}
 				cpuCost, ramCost, err := c.ComputePricingMap.GetCostOfInstance(instance)
 				if err != nil {
-					return err
+					log.Printf("could not get cost of instance(%s): %v", instance.Instance, err)
+					unpriced.Add(instance.Region, instance.MachineType, err)
+					continue
+				}
+				unpriced.Priced()
+				// Spot prices are market prices, only the on-demand prices are overridden or discounted. Overrides are
+				// per machine type, so the prices per core and GiB are scaled by the ratio to the retail machine price.
+				if len(c.config.Prices) > 0 && !instance.SpotInstance {
+					retail, err := c.machineShapes.HourlyCost(project, instance, cpuCost, ramCost)
+					if err != nil {
+						log.Printf("could not get machine type of instance(%s): %v", instance.Instance, err)
+					} else {
+						scale := c.config.Prices.Scale(providerName, instance.Region, instance.MachineType, retail)
+						cpuCost, ramCost = cpuCost*scale, ramCost*scale
+					}
 				}
 				ch <- prometheus.MustNewConstMetric(
 					gkeNodeCPUHourlyCostDesc,
@@ -162,12 +338,40 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 					ramCost,
 					labelValues...,
 				)
+				if spend != nil || clusterCosts != nil || usage != nil || headroomCosts != nil {
+					cost, err := c.machineShapes.HourlyCost(project, instance, cpuCost, ramCost)
+					if err != nil {
+						log.Printf("could not get machine type of instance(%s): %v", instance.Instance, err)
+					} else {
+						spend.Add(labelValues[0], cost)
+						clusterCosts.Add(labelValues[0], cost)
+						// Only the running nodes count towards the capacity of their node pool
+						if instance.Status == instanceStatusRunning {
+							headroomCosts.Add(labelValues[0], instance.GetNodePoolName(), cost)
+						}
+						// Only running standard instances can be covered by a committed use discount
+						if usage != nil && instance.Status == instanceStatusRunning && !instance.SpotInstance {
+							cores, _, err := c.machineShapes.Resources(project, instance)
+							if err == nil {
+								usage.Add(instance.Region, instance.Family, cores, cost)
+							}
+						}
+					}
+				}
+				if node, ok := inventory.Node(instance.Instance); ok {
+					for _, m := range kubernetes.AllocatableMetrics(node, cpuCost, ramCost, labelValues[0], providerName, instance.MachineType, instance.PriceTier) {
+						ch <- m
+					}
+					allocation.Observe(labelValues[0], node, cpuCost, ramCost)
+				}
 			}
 		}
 		seenDisks := make(map[string]bool)
+		disksBySelfLink := make(map[string]*Disk)
 		for group := range disks {
 			for _, disk := range group {
 				d := NewDisk(disk, project)
+				disksBySelfLink[disk.SelfLink] = d
 				// This an effort to deduplicate disks that have duplicate names
 				// See https://github.com/grafana/cloudcost-exporter/issues/143
 				if _, ok := seenDisks[d.Name()]; ok {
@@ -176,42 +380,124 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
 				seenDisks[d.Name()] = true
 
 				labelValues := []string{
-					d.Cluster,
+					c.config.ClusterNames.Normalize(d.Cluster),
 					d.Namespace(),
 					d.Name(),
 					d.Region(),
+					d.Zone(),
 					d.Project,
 					d.StorageClass(),
 					d.DiskType(),
+					ancestry.Folder,
+					ancestry.Org,
 				}
 
-				price, err := c.ComputePricingMap.GetCostOfStorage(d.Region(), d.StorageClass())
-				if err != nil {
+				getCostOfStorage := c.ComputePricingMap.GetCostOfStorage
+				if d.Regional() {
+					getCostOfStorage = c.ComputePricingMap.GetCostOfRegionalStorage
+				}
+				class, hasClass := classes[d.Name()]
+				price, err := getCostOfStorage(d.Region(), d.StorageClass())
+				if err != nil && !(hasClass && class.OverridesCatalog()) {
 					fmt.Printf("%s error getting cost of storage: %v\n", disk.Name, err)
+					unpriced.AddResource(utils.ResourceTypeDisk, err)
 					continue
 				}
+				cost := float64(d.Size) * price
+				if hasClass {
+					cost = class.Cost(float64(d.Size), cost)
+				}
+				unpriced.Priced()
 				ch <- prometheus.MustNewConstMetric(
 					persistentVolumeHourlyCostDesc,
 					prometheus.GaugeValue,
-					float64(d.Size)*price,
+					cost,
 					labelValues...,
 				)
+				c.volumeCosts.Observe(cost, labelValues...)
+			}
+		}
+
+		snapshots, err := ListSnapshots(ctx, project, c.computeService)
+		if err != nil {
+			log.Printf("error listing snapshots in project %s: %v", project, err)
+		}
+		for _, snapshot := range snapshots {
+			s := NewSnapshot(snapshot, disksBySelfLink)
+			price, err := c.ComputePricingMap.GetCostOfSnapshot(s.Location, s.Type)
+			if err != nil {
+				log.Printf("%s error getting cost of snapshot: %v", s.Name, err)
+				unpriced.AddResource(utils.ResourceTypeSnapshot, err)
+				continue
 			}
+			unpriced.Priced()
+			ch <- prometheus.MustNewConstMetric(
+				snapshotHourlyCostDesc,
+				prometheus.GaugeValue,
+				s.Cost(price),
+				c.config.ClusterNames.Normalize(s.Cluster),
+				s.Name,
+				s.SourceDisk,
+				s.Location,
+				project,
+				s.Type,
+				ancestry.Folder,
+				ancestry.Org,
+			)
 		}
 	}
+	if len(failedProjects) == len(projects) {
+		return errors.Join(failedProjects...)
+	}
 	return nil
 }
 
-func New(config *Config, computeService *compute.Service, billingService *billingv1.CloudCatalogClient) *Collector {
+// projects returns the projects to collect, the projects below the parent of Discovery when it's set.
+func (c *Collector) projects(ctx context.Context) ([]string, error) {
+	if c.config.Discovery == nil {
+		return c.Projects, nil
+	}
+	return c.config.Discovery.List(ctx)
+}
+
+// New creates a GKE collector. containerService is optional, when set the node pools of every cluster are listed
+// through the Container API and exported as cloudcost_gcp_gke_nodepool_info.
+func New(config *Config, computeService *compute.Service, billingService *billingv1.CloudCatalogClient, containerService *container.Service) *Collector {
 	projects := strings.Split(config.Projects, ",")
+	var client *gkeClient
+	if containerService != nil {
+		client = newGkeClient(containerService)
+	}
 	return &Collector{
 		computeService: computeService,
 		billingService: billingService,
+		gkeClient:      client,
 		config:         config,
 		Projects:       projects,
+		volumeCosts:    utils.NewCostCounter(persistentVolumeCostTotalDesc),
+		machineShapes:  gcpCompute.NewMachineShapes(computeService),
+		backoff:        provider.NewBackoff(providerName, subsystem, config.ScrapeInterval),
 	}
 }
 
+// refreshPricingMap lists the skus of Compute Engine again and rebuilds the pricing map.
+func (c *Collector) refreshPricingMap(ctx context.Context) error {
+	serviceName, err := billing.GetServiceName(ctx, c.billingService, "Compute Engine")
+	if err != nil {
+		return err
+	}
+	skus, err := billing.GetPricing(ctx, c.billingService, serviceName)
+	if err != nil {
+		return err
+	}
+	pricingMap, err := gcpCompute.GeneratePricingMap(gcpCompute.FilterSkus(skus, c.config.Families))
+	if err != nil {
+		return err
+	}
+	c.ComputePricingMap = pricingMap
+	return nil
+}
+
 func (c *Collector) Name() string {
 	return subsystem
 }
@@ -219,13 +505,50 @@ func (c *Collector) Name() string {
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
 	ch <- gkeNodeCPUHourlyCostDesc
 	ch <- gkeNodeMemoryHourlyCostDesc
+	ch <- gcpCompute.UnpricedMachineTypeInfoDesc
+	ch <- utils.PricingCoverageDesc
+	ch <- nodePoolInfoDesc
+	ch <- persistentVolumeHourlyCostDesc
+	ch <- persistentVolumeCostTotalDesc
+	ch <- snapshotHourlyCostDesc
+	ch <- gcpCompute.StorageClassHourlyPriceDesc
+	ch <- storageclass.HourlyPriceDesc
+	ch <- storageclass.VolumeHourlyPriceDesc
+	ch <- kubernetes.NodeCPUAllocatableHourlyCostDesc
+	ch <- kubernetes.NodeMemoryAllocatableHourlyCostDesc
+	ch <- kubernetes.OrphanedInstancesDesc
+	ch <- kubernetes.NamespaceHourlyCostDesc
+	ch <- schedule.ClusterActualHourlyCostDesc
+	ch <- anomaly.CostAnomalyScoreDesc
+	ch <- commitment.RecommendedCoresDesc
+	ch <- commitment.PotentialSavingsDesc
+	ch <- schedule.ClusterExpectedHourlyCostDesc
+	ch <- headroom.ClusterHeadroomHourlyCostDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
 	return nil
 }
 
-// ListDisks will list all disks in a given zone and return a slice of compute.Disk
+// ResourceDescs returns the metrics of the nodes and disks listed by the collector, the prices of the storage classes
+// aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		gkeNodeCPUHourlyCostDesc,
+		gkeNodeMemoryHourlyCostDesc,
+		nodePoolInfoDesc,
+		persistentVolumeHourlyCostDesc,
+		persistentVolumeCostTotalDesc,
+		snapshotHourlyCostDesc,
+		kubernetes.NodeCPUAllocatableHourlyCostDesc,
+		kubernetes.NodeMemoryAllocatableHourlyCostDesc,
+		kubernetes.NamespaceHourlyCostDesc,
+	}
+}
+
+// ListDisks will list all disks in a given zone and return a slice of compute.Disk. Regional disks aren't part of any
+// zone and are listed by ListRegionalDisks.
 func ListDisks(project string, zone string, service *compute.Service) ([]*compute.Disk, error) {
 	var disks []*compute.Disk
-	// TODO: How do we get this to work for multi regional disks?
 	err := service.Disks.List(project, zone).Pages(context.Background(), func(page *compute.DiskList) error {
 		if page == nil {
 			return nil
@@ -238,3 +561,34 @@ func ListDisks(project string, zone string, service *compute.Service) ([]*comput
 	}
 	return disks, nil
 }
+
+// ListRegionalDisks will list all regional disks in a given region and return a slice of compute.Disk
+func ListRegionalDisks(project string, region string, service *compute.Service) ([]*compute.Disk, error) {
+	var disks []*compute.Disk
+	err := service.RegionDisks.List(project, region).Pages(context.Background(), func(page *compute.DiskList) error {
+		if page == nil {
+			return nil
+		}
+		disks = append(disks, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+// zoneRegions returns the names of the regions of zones, each one once. Zones are returned with the URL of their region.
+func zoneRegions(zones []*compute.Zone) []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, zone := range zones {
+		region := zone.Region[strings.LastIndex(zone.Region, "/")+1:]
+		if region == "" || seen[region] {
+			continue
+		}
+		seen[region] = true
+		regions = append(regions, region)
+	}
+	return regions
+}
@@ -7,11 +7,14 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	billingv1 "cloud.google.com/go/billing/apiv1"
 	"cloud.google.com/go/billing/apiv1/billingpb"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	computev1 "google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
@@ -52,13 +55,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -66,13 +75,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -80,13 +95,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -94,13 +115,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -108,13 +135,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -122,13 +155,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -136,13 +175,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-east1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -150,13 +195,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing",
-						"region":       "us-east1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -168,7 +219,10 @@ func TestCollector_Collect(t *testing.T) {
 						"namespace":        "cloudcost-exporter",
 						"persistentvolume": "test-disk",
 						"region":           "us-central1",
+						"zone":             "us-central1-a",
 						"project":          "testing",
+						"folder":           "",
+						"org":              "",
 						"storage_class":    "pd-standard",
 						"disk_type":        "boot_disk",
 					},
@@ -182,7 +236,10 @@ func TestCollector_Collect(t *testing.T) {
 						"namespace":        "cloudcost-exporter",
 						"persistentvolume": "test-ssd-disk",
 						"region":           "us-east4",
+						"zone":             "us-east4",
 						"project":          "testing",
+						"folder":           "",
+						"org":              "",
 						"storage_class":    "pd-ssd",
 						"disk_type":        "persistent_volume",
 					},
@@ -193,13 +250,19 @@ func TestCollector_Collect(t *testing.T) {
 
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -207,13 +270,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1",
-						"machine_type": "n1-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1",
+						"machine_type":       "n1-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -221,13 +290,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -235,13 +310,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -249,13 +330,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing-1",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -263,13 +350,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n1",
-						"instance":     "test-n1-spot",
-						"machine_type": "n1-slim",
-						"price_tier":   "spot",
-						"project":      "testing-1",
-						"region":       "us-central1",
-						"cluster_name": "test",
+						"family":             "n1",
+						"instance":           "test-n1-spot",
+						"machine_type":       "n1-slim",
+						"price_tier":         "spot",
+						"provisioning_model": "spot",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-central1",
+						"zone":               "us-central1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -277,13 +370,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-east1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -291,13 +390,19 @@ func TestCollector_Collect(t *testing.T) {
 				{
 					FqName: "cloudcost_gcp_gke_instance_memory_usd_per_gib_hour",
 					Labels: map[string]string{
-						"family":       "n2",
-						"instance":     "test-n2-us-east1",
-						"machine_type": "n2-slim",
-						"price_tier":   "ondemand",
-						"project":      "testing-1",
-						"region":       "us-east1",
-						"cluster_name": "test",
+						"family":             "n2",
+						"instance":           "test-n2-us-east1",
+						"machine_type":       "n2-slim",
+						"price_tier":         "ondemand",
+						"provisioning_model": "standard",
+						"confidential":       "false",
+						"project":            "testing-1",
+						"folder":             "",
+						"org":                "",
+						"region":             "us-east1",
+						"zone":               "us-east1-a",
+						"cluster_name":       "test",
+						"node_pool":          "",
 					},
 					Value:      1,
 					MetricType: prometheus.GaugeValue,
@@ -399,6 +504,8 @@ func TestCollector_Collect(t *testing.T) {
 							},
 						},
 					}
+				case "/projects/testing/global/snapshots", "/projects/testing-1/global/snapshots":
+					buf = &computev1.SnapshotList{}
 				default:
 					fmt.Println(r.URL.Path)
 				}
@@ -427,7 +534,7 @@ func TestCollector_Collect(t *testing.T) {
 				option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
 			)
 			require.NoError(t, err)
-			collector := New(test.config, computeService, cloudCatalogClient)
+			collector := New(test.config, computeService, cloudCatalogClient, nil)
 			require.NotNil(t, collector)
 			ch := make(chan prometheus.Metric)
 			go func() {
@@ -444,10 +551,303 @@ func TestCollector_Collect(t *testing.T) {
 			if len(metrics) == 0 {
 				return
 			}
+			// The refresh interval and the storage class price sheet of the pricing map are emitted before the instances
+			// and volumes
+			require.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[0].FqName)
+			metrics = metrics[1:]
+			storageClasses := 0
+			for storageClasses < len(metrics) && metrics[storageClasses].FqName == "cloudcost_gcp_storage_class_usd_per_gib_hour" {
+				storageClasses++
+			}
+			require.Greater(t, storageClasses, 0)
+			metrics = metrics[storageClasses:]
 
 			for i, expectedMetric := range test.expectedMetrics {
 				require.Equal(t, expectedMetric, metrics[i])
 			}
+			// Every priced persistent volume accumulates its cost in a counter as well
+			volumes, counters := 0, 0
+			for _, metric := range metrics {
+				if metric.FqName == "cloudcost_gcp_gke_persistent_volume_usd_per_hour" {
+					volumes++
+				}
+				if metric.MetricType == prometheus.CounterValue {
+					counters++
+				}
+			}
+			require.Equal(t, volumes, counters)
+		})
+	}
+}
+
+func TestCollector_CollectRegionalDisks(t *testing.T) {
+	var regionalDisksLists atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf interface{}
+		switch r.URL.Path {
+		case "/projects/testing/zones":
+			// Both zones of the region of a regional disk are listed, the region must be listed only once
+			buf = &computev1.ZoneList{
+				Items: []*computev1.Zone{
+					{
+						Name:   "us-central1-a",
+						Region: "https://www.googleapis.com/compute/v1/projects/testing/regions/us-central1",
+					},
+					{
+						Name:   "us-central1-b",
+						Region: "https://www.googleapis.com/compute/v1/projects/testing/regions/us-central1",
+					},
+				},
+			}
+		case "/projects/testing/zones/us-central1-a/instances", "/projects/testing/zones/us-central1-b/instances":
+			buf = &computev1.InstanceList{}
+		case "/projects/testing/zones/us-central1-a/disks", "/projects/testing/zones/us-central1-b/disks":
+			buf = &computev1.DiskList{}
+		case "/projects/testing/global/snapshots":
+			buf = &computev1.SnapshotList{}
+		case "/projects/testing/regions/us-central1/disks":
+			regionalDisksLists.Add(1)
+			buf = &computev1.DiskList{
+				Items: []*computev1.Disk{
+					{
+						Name:   "regional-disk",
+						Region: "https://www.googleapis.com/compute/v1/projects/testing/regions/us-central1",
+						Labels: map[string]string{
+							compute.GkeClusterLabel: "test",
+						},
+						Description: `{"kubernetes.io/created-for/pvc/namespace":"cloudcost-exporter"}`,
+						Type:        "https://www.googleapis.com/compute/v1/projects/testing/regions/us-central1/diskTypes/pd-standard",
+						SizeGb:      10,
+					},
+				},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(buf)
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	gsrv := grpc.NewServer()
+	defer gsrv.Stop()
+	go func() {
+		if err := gsrv.Serve(l); err != nil {
+			t.Errorf("Failed to serve: %v", err)
+		}
+	}()
+	billingpb.RegisterCloudCatalogServer(gsrv, &billing.FakeCloudCatalogServer{})
+	cloudCatalogClient, err := billingv1.NewCloudCatalogClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	require.NoError(t, err)
+
+	collector := New(&Config{Projects: "testing"}, computeService, cloudCatalogClient, nil)
+	ch := make(chan prometheus.Metric)
+	go func() {
+		assert.Equal(t, 1.0, collector.CollectMetrics(ch))
+		close(ch)
+	}()
+
+	var volumes []*utils.MetricResult
+	for metric := range ch {
+		result := utils.ReadMetrics(metric)
+		if result.FqName == "cloudcost_gcp_gke_persistent_volume_usd_per_hour" {
+			volumes = append(volumes, result)
+		}
+	}
+	require.Equal(t, int32(1), regionalDisksLists.Load())
+	// The regional disk is priced at the regional price of its region rather than at the zonal one
+	require.Equal(t, []*utils.MetricResult{{
+		FqName: "cloudcost_gcp_gke_persistent_volume_usd_per_hour",
+		Labels: map[string]string{
+			"cluster_name":     "test",
+			"namespace":        "cloudcost-exporter",
+			"persistentvolume": "regional-disk",
+			"region":           "us-central1",
+			// A regional disk is replicated across two zones
+			"zone":          "",
+			"project":       "testing",
+			"storage_class": "pd-standard",
+			"disk_type":     "persistent_volume",
+			"folder":        "",
+			"org":           "",
+		},
+		Value:      10 * 2 / utils.HoursInMonth,
+		MetricType: prometheus.GaugeValue,
+	}}, volumes)
+}
+
+func TestCollector_CollectSnapshots(t *testing.T) {
+	diskSelfLink := "https://www.googleapis.com/compute/v1/projects/testing/zones/us-central1-a/disks/gke-test-pvc-1234"
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf interface{}
+		switch r.URL.Path {
+		case "/projects/testing/zones":
+			buf = &computev1.ZoneList{Items: []*computev1.Zone{{Name: "us-central1-a"}}}
+		case "/projects/testing/zones/us-central1-a/instances":
+			buf = &computev1.InstanceList{}
+		case "/projects/testing/zones/us-central1-a/disks":
+			buf = &computev1.DiskList{
+				Items: []*computev1.Disk{
+					{
+						Name:     "gke-test-pvc-1234",
+						SelfLink: diskSelfLink,
+						Zone:     "testing/us-central1-a",
+						Labels: map[string]string{
+							compute.GkeClusterLabel: "test",
+						},
+						Description: `{"kubernetes.io/created-for/pv/name":"pvc-1234","kubernetes.io/created-for/pvc/namespace":"cloudcost-exporter"}`,
+						Type:        "pd-standard",
+						SizeGb:      10,
+					},
+				},
+			}
+		case "/projects/testing/global/snapshots":
+			buf = &computev1.SnapshotList{
+				Items: []*computev1.Snapshot{
+					{
+						Name:             "daily-backup",
+						SourceDisk:       diskSelfLink,
+						StorageLocations: []string{"us"},
+						SnapshotType:     compute.SnapshotArchive,
+						StorageBytes:     2 * bytesPerGiB,
+					},
+					// There's no price of the snapshots stored in asia
+					{
+						Name:             "asia-backup",
+						SourceDisk:       diskSelfLink,
+						StorageLocations: []string{"asia"},
+					},
+				},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(buf)
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	collector := New(&Config{Projects: "testing"}, computeService, nil, nil)
+	collector.ComputePricingMap = &compute.StructuredPricingMap{
+		Compute: map[string]*compute.FamilyPricing{},
+		Storage: map[string]*compute.StoragePricing{
+			"us-central1": {Storage: map[string]float64{"pd-standard": 0.04}},
+			"us":          {Storage: map[string]float64{}, Snapshot: map[string]float64{compute.SnapshotArchive: 0.01}},
+		},
+	}
+	collector.NextScrape = time.Now().Add(time.Hour)
+	ch := make(chan prometheus.Metric)
+	go func() {
+		assert.Equal(t, 1.0, collector.CollectMetrics(ch))
+		close(ch)
+	}()
+
+	var snapshots []*utils.MetricResult
+	for metric := range ch {
+		result := utils.ReadMetrics(metric)
+		if result.FqName == "cloudcost_gcp_gke_snapshot_usd_per_hour" {
+			snapshots = append(snapshots, result)
+		}
+	}
+	// The snapshot is exported with the persistent volume and the cluster of its source disk
+	require.Equal(t, []*utils.MetricResult{{
+		FqName: "cloudcost_gcp_gke_snapshot_usd_per_hour",
+		Labels: map[string]string{
+			"cluster_name":  "test",
+			"snapshot":      "daily-backup",
+			"source_disk":   "pvc-1234",
+			"region":        "us",
+			"project":       "testing",
+			"snapshot_type": compute.SnapshotArchive,
+			"folder":        "",
+			"org":           "",
+		},
+		Value:      0.02,
+		MetricType: prometheus.GaugeValue,
+	}}, snapshots)
+}
+
+func TestCollector_CollectPartialProjectFailure(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf interface{}
+		switch r.URL.Path {
+		case "/projects/forbidden/zones":
+			w.WriteHeader(http.StatusForbidden)
+			return
+		case "/projects/testing/zones":
+			buf = &computev1.ZoneList{
+				Items: []*computev1.Zone{
+					{
+						Name: "us-central1-a",
+					}},
+			}
+		case "/projects/testing/zones/us-central1-a/instances":
+			buf = &computev1.InstanceList{}
+		case "/projects/testing/zones/us-central1-a/disks":
+			buf = &computev1.DiskList{}
+		case "/projects/testing/global/snapshots":
+			buf = &computev1.SnapshotList{}
+		}
+		_ = json.NewEncoder(w).Encode(buf)
+	}))
+	defer testServer.Close()
+	computeService, err := computev1.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	gsrv := grpc.NewServer()
+	defer gsrv.Stop()
+	go func() {
+		if err := gsrv.Serve(l); err != nil {
+			t.Errorf("Failed to serve: %v", err)
+		}
+	}()
+	billingpb.RegisterCloudCatalogServer(gsrv, &billing.FakeCloudCatalogServer{})
+	cloudCatalogClient, err := billingv1.NewCloudCatalogClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		projects        string
+		collectResponse float64
+		expectedScopes  map[string]float64
+	}{
+		"one of many projects fails": {
+			projects:        "testing,forbidden",
+			collectResponse: 1,
+			expectedScopes:  map[string]float64{"testing": 0, "forbidden": 1},
+		},
+		"every project fails": {
+			projects:        "forbidden",
+			collectResponse: 0,
+			expectedScopes:  map[string]float64{"forbidden": 1},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			collector := New(&Config{Projects: test.projects}, computeService, cloudCatalogClient, nil)
+			ch := make(chan prometheus.Metric)
+			go func() {
+				assert.Equal(t, test.collectResponse, collector.CollectMetrics(ch))
+				close(ch)
+			}()
+
+			scopes := make(map[string]float64)
+			for metric := range ch {
+				result := utils.ReadMetrics(metric)
+				if result.FqName == "cloudcost_exporter_collector_scope_last_scrape_error" {
+					require.Equal(t, "gcp", result.Labels["provider"])
+					require.Equal(t, subsystem, result.Labels["collector"])
+					scopes[result.Labels["scope"]] = result.Value
+				}
+			}
+			require.Equal(t, test.expectedScopes, scopes)
 		})
 	}
 }
@@ -0,0 +1,108 @@
+package gke
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/container/v1"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/google/hierarchy"
+)
+
+var (
+	nodePoolInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "nodepool_info"),
+		"Node pool configuration as declared in the GKE API. Join on cluster_name, node_pool and project to enrich the instance metrics.",
+		[]string{"cluster_name", "node_pool", "project", "location", "autoscaling_min_nodes", "autoscaling_max_nodes", "spot", "preemptible", "folder", "org"},
+		nil,
+	)
+)
+
+// NodePool is a slimmed down representation of a container.NodePool that only holds the fields relevant for cost analysis.
+type NodePool struct {
+	ClusterName string
+	Location    string
+	Name        string
+	// Autoscaling is true when the cluster autoscaler is enabled for the node pool. MinNodes and MaxNodes are only set when it is.
+	Autoscaling bool
+	// MinNodes and MaxNodes are per zone, which is how they're declared on the node pool.
+	MinNodes int64
+	MaxNodes int64
+	// TotalMinNodes is the minimum size of the node pool across its zones: the total minimum when it's declared
+	// instead of the minimum per zone, MinNodes times the zones of the node pool otherwise.
+	TotalMinNodes int64
+	Spot          bool
+	Preemptible   bool
+}
+
+// gkeClient lists the clusters and node pools of a project through the Container API.
+type gkeClient struct {
+	service *container.Service
+}
+
+func newGkeClient(service *container.Service) *gkeClient {
+	return &gkeClient{service: service}
+}
+
+// ListNodePools returns the node pools of every cluster in the project, across all locations.
+func (g *gkeClient) ListNodePools(ctx context.Context, project string) ([]*NodePool, error) {
+	resp, err := g.service.Projects.Locations.Clusters.List(fmt.Sprintf("projects/%s/locations/-", project)).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	var nodePools []*NodePool
+	for _, cluster := range resp.Clusters {
+		for _, np := range cluster.NodePools {
+			nodePools = append(nodePools, newNodePool(cluster, np))
+		}
+	}
+	return nodePools, nil
+}
+
+func newNodePool(cluster *container.Cluster, np *container.NodePool) *NodePool {
+	nodePool := &NodePool{
+		ClusterName: cluster.Name,
+		Location:    cluster.Location,
+		Name:        np.Name,
+	}
+	if np.Autoscaling != nil && np.Autoscaling.Enabled {
+		nodePool.Autoscaling = true
+		nodePool.MinNodes = np.Autoscaling.MinNodeCount
+		nodePool.MaxNodes = np.Autoscaling.MaxNodeCount
+		nodePool.TotalMinNodes = np.Autoscaling.TotalMinNodeCount
+		if nodePool.TotalMinNodes == 0 {
+			// The node pool of a zonal cluster doesn't list its zone
+			zones := int64(max(len(np.Locations), 1))
+			nodePool.TotalMinNodes = np.Autoscaling.MinNodeCount * zones
+		}
+	}
+	if np.Config != nil {
+		nodePool.Spot = np.Config.Spot
+		nodePool.Preemptible = np.Config.Preemptible
+	}
+	return nodePool
+}
+
+// labelValues returns the label values for nodePoolInfoDesc. The autoscaling bounds are left empty if autoscaling is disabled.
+func (n *NodePool) labelValues(project string, ancestry hierarchy.Ancestry) []string {
+	minNodes, maxNodes := "", ""
+	if n.Autoscaling {
+		minNodes = strconv.FormatInt(n.MinNodes, 10)
+		maxNodes = strconv.FormatInt(n.MaxNodes, 10)
+	}
+	return []string{
+		n.ClusterName,
+		n.Name,
+		project,
+		n.Location,
+		minNodes,
+		maxNodes,
+		strconv.FormatBool(n.Spot),
+		strconv.FormatBool(n.Preemptible),
+		ancestry.Folder,
+		ancestry.Org,
+	}
+}
@@ -0,0 +1,128 @@
+package gke
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/container/v1"
+	"google.golang.org/api/option"
+
+	"github.com/grafana/cloudcost-exporter/pkg/google/hierarchy"
+)
+
+func TestGkeClient_ListNodePools(t *testing.T) {
+	tests := map[string]struct {
+		handler  http.HandlerFunc
+		expected []*NodePool
+		wantErr  bool
+	}{
+		"Handle http error": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr: true,
+		},
+		"Node pools from every cluster": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/projects/testing/locations/-/clusters", r.URL.Path)
+				_ = json.NewEncoder(w).Encode(&container.ListClustersResponse{
+					Clusters: []*container.Cluster{
+						{
+							Name:     "test",
+							Location: "us-central1",
+							NodePools: []*container.NodePool{
+								{
+									Name: "default-pool",
+									Autoscaling: &container.NodePoolAutoscaling{
+										Enabled:      true,
+										MinNodeCount: 1,
+										MaxNodeCount: 5,
+									},
+									Locations: []string{"us-central1-a", "us-central1-b", "us-central1-c"},
+									Config:    &container.NodeConfig{},
+								},
+								{
+									Name: "total-pool",
+									Autoscaling: &container.NodePoolAutoscaling{
+										Enabled:           true,
+										TotalMinNodeCount: 2,
+										MaxNodeCount:      5,
+									},
+									Locations: []string{"us-central1-a", "us-central1-b"},
+								},
+								{
+									Name:   "spot-pool",
+									Config: &container.NodeConfig{Spot: true},
+								},
+							},
+						},
+						{
+							Name:     "test-1",
+							Location: "us-east1-b",
+							NodePools: []*container.NodePool{
+								{
+									Name:   "preemptible-pool",
+									Config: &container.NodeConfig{Preemptible: true},
+								},
+							},
+						},
+					},
+				})
+			},
+			expected: []*NodePool{
+				{ClusterName: "test", Location: "us-central1", Name: "default-pool", Autoscaling: true, MinNodes: 1, MaxNodes: 5, TotalMinNodes: 3},
+				{ClusterName: "test", Location: "us-central1", Name: "total-pool", Autoscaling: true, MaxNodes: 5, TotalMinNodes: 2},
+				{ClusterName: "test", Location: "us-central1", Name: "spot-pool", Spot: true},
+				{ClusterName: "test-1", Location: "us-east1-b", Name: "preemptible-pool", Preemptible: true},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+			service, err := container.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(server.URL))
+			require.NoError(t, err)
+
+			nodePools, err := newGkeClient(service).ListNodePools(context.Background(), "testing")
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, nodePools)
+		})
+	}
+}
+
+func TestNodePool_LabelValues(t *testing.T) {
+	tests := map[string]struct {
+		nodePool *NodePool
+		ancestry hierarchy.Ancestry
+		expected []string
+	}{
+		"autoscaling disabled": {
+			nodePool: &NodePool{ClusterName: "test", Location: "us-central1", Name: "spot-pool", Spot: true},
+			expected: []string{"test", "spot-pool", "testing", "us-central1", "", "", "true", "false", "", ""},
+		},
+		"autoscaling enabled": {
+			nodePool: &NodePool{ClusterName: "test", Location: "us-central1", Name: "default-pool", Autoscaling: true, MinNodes: 0, MaxNodes: 3},
+			expected: []string{"test", "default-pool", "testing", "us-central1", "0", "3", "false", "false", "", ""},
+		},
+		"hierarchy labels": {
+			nodePool: &NodePool{ClusterName: "test", Location: "us-central1", Name: "default-pool"},
+			ancestry: hierarchy.Ancestry{Folder: "1/2", Org: "3"},
+			expected: []string{"test", "default-pool", "testing", "us-central1", "", "", "false", "false", "1/2", "3"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.nodePool.labelValues("testing", test.ancestry))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package gke
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+
+	gcpCompute "github.com/grafana/cloudcost-exporter/pkg/google/compute"
+)
+
+const bytesPerGiB = 1 << 30
+
+type Snapshot struct {
+	Name string
+	// Cluster is the cluster of the source disk of the snapshot, or of the goog-k8s-cluster-name label of the
+	// snapshot once the disk is deleted.
+	Cluster    string
+	SourceDisk string
+	// Location is the region or multi-region the snapshot is stored in, eg us-central1 or us.
+	Location string
+	Type     string
+	// StorageBytes is the size of the snapshot that is billed, which is less than the size of its source disk.
+	StorageBytes int64
+}
+
+// NewSnapshot returns the Snapshot of a snapshot of project. disks are the disks of project keyed by their self link,
+// so that the snapshots of a persistent volume are exported with the name of the volume and its cluster.
+func NewSnapshot(snapshot *compute.Snapshot, disks map[string]*Disk) *Snapshot {
+	s := &Snapshot{
+		Name:         snapshot.Name,
+		Cluster:      snapshot.Labels[gcpCompute.GkeClusterLabel],
+		SourceDisk:   snapshot.SourceDisk[strings.LastIndex(snapshot.SourceDisk, "/")+1:],
+		Type:         snapshot.SnapshotType,
+		StorageBytes: snapshot.StorageBytes,
+	}
+	if disk, ok := disks[snapshot.SourceDisk]; ok {
+		s.SourceDisk = disk.Name()
+		if disk.Cluster != "" {
+			s.Cluster = disk.Cluster
+		}
+	}
+	if len(snapshot.StorageLocations) > 0 {
+		s.Location = snapshot.StorageLocations[0]
+	}
+	// Snapshots created before the archive snapshots were introduced have no type
+	if s.Type == "" {
+		s.Type = gcpCompute.SnapshotStandard
+	}
+	return s
+}
+
+// Cost returns the hourly cost of the snapshot given the price of a GiB of its type and location for an hour.
+func (s *Snapshot) Cost(price float64) float64 {
+	return float64(s.StorageBytes) / bytesPerGiB * price
+}
+
+// ListSnapshots will list all the snapshots of a project. Snapshots are global resources, stored in the location of
+// their storageLocations.
+func ListSnapshots(ctx context.Context, project string, service *compute.Service) ([]*compute.Snapshot, error) {
+	var snapshots []*compute.Snapshot
+	err := service.Snapshots.List(project).Pages(ctx, func(page *compute.SnapshotList) error {
+		if page == nil {
+			return nil
+		}
+		snapshots = append(snapshots, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
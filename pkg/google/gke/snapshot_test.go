@@ -0,0 +1,68 @@
+package gke
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	computev1 "google.golang.org/api/compute/v1"
+
+	"github.com/grafana/cloudcost-exporter/pkg/google/compute"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	diskSelfLink := "https://www.googleapis.com/compute/v1/projects/testing/zones/us-central1-a/disks/gke-test-pvc-1234"
+	disks := map[string]*Disk{
+		diskSelfLink: NewDisk(&computev1.Disk{
+			Name:        "gke-test-pvc-1234",
+			Labels:      map[string]string{compute.GkeClusterLabel: "test"},
+			Description: `{"kubernetes.io/created-for/pv/name":"pvc-1234"}`,
+		}, "testing"),
+	}
+	tests := map[string]struct {
+		snapshot *computev1.Snapshot
+		want     *Snapshot
+	}{
+		"snapshot of a persistent volume": {
+			snapshot: &computev1.Snapshot{
+				Name:             "daily-backup",
+				SourceDisk:       diskSelfLink,
+				StorageLocations: []string{"us"},
+				SnapshotType:     compute.SnapshotArchive,
+				StorageBytes:     bytesPerGiB,
+			},
+			want: &Snapshot{
+				Name:         "daily-backup",
+				Cluster:      "test",
+				SourceDisk:   "pvc-1234",
+				Location:     "us",
+				Type:         compute.SnapshotArchive,
+				StorageBytes: bytesPerGiB,
+			},
+		},
+		"snapshot of a deleted disk": {
+			snapshot: &computev1.Snapshot{
+				Name:             "old-backup",
+				SourceDisk:       "https://www.googleapis.com/compute/v1/projects/testing/zones/us-central1-a/disks/deleted",
+				Labels:           map[string]string{compute.GkeClusterLabel: "test"},
+				StorageLocations: []string{"us-central1"},
+			},
+			want: &Snapshot{
+				Name:       "old-backup",
+				Cluster:    "test",
+				SourceDisk: "deleted",
+				Location:   "us-central1",
+				Type:       compute.SnapshotStandard,
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewSnapshot(tt.snapshot, disks))
+		})
+	}
+}
+
+func TestSnapshot_Cost(t *testing.T) {
+	s := &Snapshot{StorageBytes: 3 * bytesPerGiB / 2}
+	assert.Equal(t, 0.375, s.Cost(0.25))
+}
@@ -0,0 +1,105 @@
+package hierarchy
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+)
+
+const (
+	// cacheTTL is how long the ancestry of a project is cached. Projects are rarely moved between folders, so there is
+	// no need to look it up on every scrape.
+	cacheTTL = 24 * time.Hour
+
+	folderType       = "folder"
+	organizationType = "organization"
+	// folderSeparator joins the ids of the folders from the top level folder down to a project.
+	folderSeparator = "/"
+)
+
+// Ancestry is the folder path and organization a project belongs to. Both are empty for projects without an
+// organization or when the ancestry couldn't be resolved.
+type Ancestry struct {
+	// Folder is the ids of the folders above a project, from the top level folder down, joined by a slash.
+	Folder string
+	// Org is the id of the organization of a project.
+	Org string
+}
+
+type cachedAncestry struct {
+	ancestry  Ancestry
+	expiresAt time.Time
+}
+
+// Resolver resolves the ancestry of projects through Cloud Resource Manager. A nil Resolver is valid and resolves
+// every project to an empty Ancestry, which is what collectors get when hierarchy labels are disabled.
+type Resolver struct {
+	service *cloudresourcemanager.Service
+	// depth is the maximum number of folders, starting from the top level folder, kept in Ancestry.Folder.
+	depth int
+	now   func() time.Time
+
+	m     sync.Mutex
+	cache map[string]cachedAncestry
+}
+
+// NewResolver returns a Resolver that keeps at most depth folders in the folder path of a project.
+func NewResolver(service *cloudresourcemanager.Service, depth int) *Resolver {
+	return &Resolver{
+		service: service,
+		depth:   depth,
+		now:     time.Now,
+		cache:   make(map[string]cachedAncestry),
+	}
+}
+
+// Ancestry returns the ancestry of a project. Errors are logged rather than returned as the ancestry only enriches
+// metrics, the project is looked up again on the next call.
+func (r *Resolver) Ancestry(ctx context.Context, project string) Ancestry {
+	if r == nil {
+		return Ancestry{}
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if cached, ok := r.cache[project]; ok && r.now().Before(cached.expiresAt) {
+		return cached.ancestry
+	}
+	resp, err := r.service.Projects.GetAncestry(project, &cloudresourcemanager.GetAncestryRequest{}).Context(ctx).Do()
+	if err != nil {
+		log.Printf("error getting ancestry of project %s: %v", project, err)
+		return Ancestry{}
+	}
+	ancestry := parseAncestry(resp.Ancestor, r.depth)
+	r.cache[project] = cachedAncestry{ancestry: ancestry, expiresAt: r.now().Add(cacheTTL)}
+	return ancestry
+}
+
+// parseAncestry turns the ancestors of a project, which are ordered from the project itself up to the organization,
+// into an Ancestry keeping at most depth folders from the top.
+func parseAncestry(ancestors []*cloudresourcemanager.Ancestor, depth int) Ancestry {
+	var ancestry Ancestry
+	var folders []string
+	for _, ancestor := range ancestors {
+		if ancestor == nil || ancestor.ResourceId == nil {
+			continue
+		}
+		switch ancestor.ResourceId.Type {
+		case folderType:
+			folders = append(folders, ancestor.ResourceId.Id)
+		case organizationType:
+			ancestry.Org = ancestor.ResourceId.Id
+		}
+	}
+	// The path starts at the top level folder
+	slices.Reverse(folders)
+	if len(folders) > depth {
+		folders = folders[:depth]
+	}
+	ancestry.Folder = strings.Join(folders, folderSeparator)
+	return ancestry
+}
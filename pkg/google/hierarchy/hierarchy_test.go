@@ -0,0 +1,105 @@
+package hierarchy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/option"
+)
+
+func ancestor(resourceType string, id string) *cloudresourcemanager.Ancestor {
+	return &cloudresourcemanager.Ancestor{ResourceId: &cloudresourcemanager.ResourceId{Type: resourceType, Id: id}}
+}
+
+func TestParseAncestry(t *testing.T) {
+	nested := []*cloudresourcemanager.Ancestor{
+		ancestor("project", "testing"),
+		ancestor(folderType, "3"),
+		ancestor(folderType, "2"),
+		ancestor(folderType, "1"),
+		ancestor(organizationType, "42"),
+	}
+	tests := map[string]struct {
+		ancestors []*cloudresourcemanager.Ancestor
+		depth     int
+		want      Ancestry
+	}{
+		"folders start at the top level folder": {
+			ancestors: nested,
+			depth:     5,
+			want:      Ancestry{Folder: "1/2/3", Org: "42"},
+		},
+		"folders are cut at depth": {
+			ancestors: nested,
+			depth:     2,
+			want:      Ancestry{Folder: "1/2", Org: "42"},
+		},
+		"project directly below the organization": {
+			ancestors: []*cloudresourcemanager.Ancestor{ancestor("project", "testing"), ancestor(organizationType, "42")},
+			depth:     2,
+			want:      Ancestry{Org: "42"},
+		},
+		"project without an organization": {
+			ancestors: []*cloudresourcemanager.Ancestor{ancestor("project", "testing"), nil, {}},
+			depth:     2,
+			want:      Ancestry{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAncestry(tt.ancestors, tt.depth))
+		})
+	}
+}
+
+func TestResolver_Ancestry(t *testing.T) {
+	requests := 0
+	statusCode := http.StatusOK
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if statusCode != http.StatusOK {
+			w.WriteHeader(statusCode)
+			return
+		}
+		assert.Equal(t, "/v1/projects/testing:getAncestry", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(&cloudresourcemanager.GetAncestryResponse{
+			Ancestor: []*cloudresourcemanager.Ancestor{
+				ancestor("project", "testing"),
+				ancestor(folderType, "2"),
+				ancestor(folderType, "1"),
+				ancestor(organizationType, "42"),
+			},
+		})
+	}))
+	defer testServer.Close()
+	service, err := cloudresourcemanager.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	resolver := NewResolver(service, 1)
+	resolver.now = func() time.Time { return now }
+
+	want := Ancestry{Folder: "1", Org: "42"}
+	assert.Equal(t, want, resolver.Ancestry(context.Background(), "testing"))
+	assert.Equal(t, want, resolver.Ancestry(context.Background(), "testing"))
+	assert.Equal(t, 1, requests, "ancestry should be cached")
+
+	// Errors are not cached and keep the labels empty
+	now = now.Add(cacheTTL + time.Minute)
+	statusCode = http.StatusForbidden
+	assert.Equal(t, Ancestry{}, resolver.Ancestry(context.Background(), "testing"))
+	assert.Equal(t, Ancestry{}, resolver.Ancestry(context.Background(), "testing"))
+	assert.Equal(t, 3, requests)
+}
+
+func TestResolver_Nil(t *testing.T) {
+	var resolver *Resolver
+	assert.Equal(t, Ancestry{}, resolver.Ancestry(context.Background(), "testing"))
+}
@@ -0,0 +1,179 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/google/billing"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "gcp"
+
+	pubSubServiceName     = "Cloud Pub/Sub"
+	cloudTasksServiceName = "Cloud Tasks"
+)
+
+var (
+	PubSubThroughputPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_pubsub", "throughput_usd_per_tib"),
+		"The price of a TiB of Pub/Sub message delivery throughput in USD, at the first paid tier. Pub/Sub throughput is priced globally, so region is global.",
+		[]string{"region"},
+		nil,
+	)
+	CloudTasksOperationPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_cloud_tasks", "usd_per_million_operations"),
+		"The price of a million Cloud Tasks operations, eg task creations and deliveries, in USD at the first paid tier.",
+		[]string{"region"},
+		nil,
+	)
+)
+
+// unitPrice selects the price of a unit among the skus of a service by their description, eg Message Delivery Basic.
+type unitPrice struct {
+	service     string
+	description string
+	desc        *prometheus.Desc
+	// scales converts the price per usage unit of a sku, eg GiBy, into the unit of the metric. Skus priced in another
+	// usage unit are skipped.
+	scales map[string]float64
+}
+
+var unitPrices = []unitPrice{
+	{
+		service:     pubSubServiceName,
+		description: "Message Delivery Basic",
+		desc:        PubSubThroughputPriceDesc,
+		scales:      map[string]float64{"TiBy": 1, "GiBy": 1024},
+	},
+	{
+		service:     cloudTasksServiceName,
+		description: "Operations",
+		desc:        CloudTasksOperationPriceDesc,
+		scales:      map[string]float64{"count": 1e6},
+	},
+}
+
+// price is the unit price of a region, in the unit of the metric of its unitPrice.
+type price struct {
+	unitPrice *unitPrice
+	region    string
+	value     float64
+}
+
+// Collector exports the unit prices of Pub/Sub and Cloud Tasks from the billing catalog. Combined with the usage
+// metrics of Cloud Monitoring they estimate the spend of the messaging services in PromQL.
+type Collector struct {
+	billingService *billingv1.CloudCatalogClient
+	backoff        *provider.Backoff
+	nextScrape     time.Time
+	prices         []price
+	m              sync.Mutex
+}
+
+// New creates a Collector. The prices are only listed again every scrapeInterval, or less often while the Cloud Billing
+// API throttles the collector.
+func New(scrapeInterval time.Duration, billingService *billingv1.CloudCatalogClient) *Collector {
+	c := &Collector{
+		billingService: billingService,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), scrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Messaging"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- PubSubThroughputPriceDesc
+	ch <- CloudTasksOperationPriceDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the unit prices again when the scrape interval has passed and exports them.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listPrices(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing messaging prices: %w", err)
+		}
+		c.prices = prices
+	}
+	for _, p := range c.prices {
+		ch <- prometheus.MustNewConstMetric(p.unitPrice.desc, prometheus.GaugeValue, p.value, p.region)
+	}
+	c.backoff.Emit(ch)
+	return nil
+}
+
+// listPrices lists the skus of every messaging service and returns their unit prices sorted by metric and region.
+func (c *Collector) listPrices(ctx context.Context) ([]price, error) {
+	prices := []price{}
+	for _, service := range []string{pubSubServiceName, cloudTasksServiceName} {
+		serviceName, err := billing.GetServiceName(ctx, c.billingService, service)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the service name of %s: %w", service, err)
+		}
+		skus, err := billing.GetPricing(ctx, c.billingService, serviceName)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the skus of %s: %w", service, err)
+		}
+		prices = append(prices, parsePrices(service, skus)...)
+	}
+	return prices, nil
+}
+
+// parsePrices returns the unit prices found among the skus of a service, one per region the skus are offered in.
+// A unit is priced at its first paid tier, as the free tiers, eg the first 10 GiB of Pub/Sub throughput of the month,
+// are shared by the whole billing account.
+func parsePrices(service string, skus []*billingpb.Sku) []price {
+	var prices []price
+	for _, sku := range skus {
+		if sku == nil {
+			continue
+		}
+		for i := range unitPrices {
+			up := &unitPrices[i]
+			if up.service != service || !strings.Contains(sku.Description, up.description) {
+				continue
+			}
+			value, ok := billing.FirstPaidTierPrice(sku, up.scales)
+			if !ok {
+				log.Printf("skipping sku %q of %s without a supported price", sku.Description, service)
+				continue
+			}
+			for _, region := range sku.ServiceRegions {
+				prices = append(prices, price{unitPrice: up, region: region, value: value})
+			}
+		}
+	}
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].region < prices[j].region })
+	return prices
+}
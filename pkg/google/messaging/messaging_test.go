@@ -0,0 +1,167 @@
+package messaging
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testSku(description string, usageUnit string, regions []string, prices ...*money.Money) *billingpb.Sku {
+	var rates []*billingpb.PricingExpression_TierRate
+	for _, price := range prices {
+		rates = append(rates, &billingpb.PricingExpression_TierRate{UnitPrice: price})
+	}
+	return &billingpb.Sku{
+		Description:    description,
+		ServiceRegions: regions,
+		PricingInfo: []*billingpb.PricingInfo{{
+			PricingExpression: &billingpb.PricingExpression{UsageUnit: usageUnit, TieredRates: rates},
+		}},
+	}
+}
+
+var (
+	pubSubSku = testSku("Message Delivery Basic", "TiBy", []string{"global"}, &money.Money{}, &money.Money{Units: 40})
+	tasksSku  = testSku("Cloud Tasks Operations", "count", []string{"us-central1", "europe-west1"}, &money.Money{}, &money.Money{Nanos: 400})
+)
+
+func Test_parsePrices(t *testing.T) {
+	tests := map[string]struct {
+		service string
+		skus    []*billingpb.Sku
+		want    map[string]float64
+	}{
+		"pubsub throughput is priced at the first paid tier": {
+			service: pubSubServiceName,
+			skus:    []*billingpb.Sku{pubSubSku, testSku("Topics message backlog", "GiBy.mo", []string{"global"}, &money.Money{Nanos: 270000000})},
+			want:    map[string]float64{"global": 40},
+		},
+		"pubsub throughput priced per GiB is converted to TiB": {
+			service: pubSubServiceName,
+			skus:    []*billingpb.Sku{testSku("Message Delivery Basic", "GiBy", []string{"global"}, &money.Money{Nanos: 39062500})},
+			want:    map[string]float64{"global": 40},
+		},
+		"cloud tasks operations are priced per region": {
+			service: cloudTasksServiceName,
+			skus:    []*billingpb.Sku{tasksSku},
+			want:    map[string]float64{"us-central1": 0.4, "europe-west1": 0.4},
+		},
+		"skus in another unit or without a paid tier are skipped": {
+			service: cloudTasksServiceName,
+			skus: []*billingpb.Sku{
+				nil,
+				testSku("Cloud Tasks Operations", "By", []string{"us-central1"}, &money.Money{Nanos: 400}),
+				testSku("Cloud Tasks Operations", "count", []string{"us-central1"}, &money.Money{}),
+				{Description: "Cloud Tasks Operations", ServiceRegions: []string{"us-central1"}},
+			},
+			want: map[string]float64{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make(map[string]float64)
+			for _, p := range parsePrices(tt.service, tt.skus) {
+				got[p.region] = p.value
+			}
+			require.Len(t, got, len(tt.want))
+			for region, value := range tt.want {
+				assert.InDelta(t, value, got[region], 1e-9, region)
+			}
+		})
+	}
+}
+
+type fakeCloudCatalogServer struct {
+	billingpb.UnimplementedCloudCatalogServer
+	listSkus atomic.Int32
+	// err is returned by ListSkus when it's set
+	err error
+}
+
+func (s *fakeCloudCatalogServer) ListServices(_ context.Context, _ *billingpb.ListServicesRequest) (*billingpb.ListServicesResponse, error) {
+	return &billingpb.ListServicesResponse{Services: []*billingpb.Service{
+		{DisplayName: pubSubServiceName, Name: "services/pubsub"},
+		{DisplayName: cloudTasksServiceName, Name: "services/tasks"},
+	}}, nil
+}
+
+func (s *fakeCloudCatalogServer) ListSkus(_ context.Context, req *billingpb.ListSkusRequest) (*billingpb.ListSkusResponse, error) {
+	s.listSkus.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if req.Parent == "services/pubsub" {
+		return &billingpb.ListSkusResponse{Skus: []*billingpb.Sku{pubSubSku}}, nil
+	}
+	return &billingpb.ListSkusResponse{Skus: []*billingpb.Sku{tasksSku}}, nil
+}
+
+func newTestClient(t *testing.T, server *fakeCloudCatalogServer) *billingv1.CloudCatalogClient {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	gsrv := grpc.NewServer()
+	t.Cleanup(gsrv.Stop)
+	billingpb.RegisterCloudCatalogServer(gsrv, server)
+	go func() {
+		_ = gsrv.Serve(l)
+	}()
+	client, err := billingv1.NewCloudCatalogClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+	return client
+}
+
+func TestCollector_Collect(t *testing.T) {
+	server := &fakeCloudCatalogServer{}
+	c := New(time.Hour, newTestClient(t, server))
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		var metrics []*utils.MetricResult
+		for metric := range ch {
+			metrics = append(metrics, utils.ReadMetrics(metric))
+		}
+		require.Len(t, metrics, 4)
+		assert.Equal(t, &utils.MetricResult{
+			FqName:     "cloudcost_gcp_pubsub_throughput_usd_per_tib",
+			Labels:     utils.LabelMap{"region": "global"},
+			Value:      40,
+			MetricType: prometheus.GaugeValue,
+		}, metrics[0])
+		assert.Equal(t, "cloudcost_gcp_cloud_tasks_usd_per_million_operations", metrics[1].FqName)
+		assert.Equal(t, utils.LabelMap{"region": "europe-west1"}, metrics[1].Labels)
+		assert.Equal(t, utils.LabelMap{"region": "us-central1"}, metrics[2].Labels)
+		assert.Equal(t, "cloudcost_exporter_collector_refresh_interval_seconds", metrics[3].FqName)
+	}
+	// The prices are only listed once per scrape interval
+	assert.Equal(t, int32(2), server.listSkus.Load())
+}
+
+func TestCollector_CollectThrottled(t *testing.T) {
+	server := &fakeCloudCatalogServer{err: status.Error(codes.ResourceExhausted, "quota exceeded")}
+	c := New(time.Hour, newTestClient(t, server))
+	ch := make(chan prometheus.Metric, 10)
+	assert.True(t, provider.IsThrottled(c.Collect(ch)))
+	assert.Empty(t, ch)
+	assert.Equal(t, 2*time.Hour, c.backoff.Interval())
+}
@@ -0,0 +1,350 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/google/billing"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+const (
+	providerName = "gcp"
+	subsystem    = "gcp_observability"
+
+	loggingServiceName    = "Cloud Logging"
+	monitoringServiceName = "Cloud Monitoring"
+
+	// priceRegion is the region of the skus of Cloud Logging and Cloud Monitoring, which are priced globally.
+	priceRegion = "global"
+
+	// The Cloud Monitoring metrics of the volumes billed to a project
+	loggingBytesMetric    = "logging.googleapis.com/billing/bytes_ingested"
+	monitoringBytesMetric = "monitoring.googleapis.com/billing/bytes_ingested"
+	samplesMetric         = "monitoring.googleapis.com/billing/samples_ingested"
+
+	// IngestionWindow is how far back the volume ingested by a project is looked up.
+	IngestionWindow = time.Hour
+
+	bytesPerGiB = 1 << 30
+	bytesPerMiB = 1 << 20
+	perMillion  = 1e6
+)
+
+var (
+	LoggingIngestionPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_logging", "ingestion_usd_per_gib"),
+		"The price of a GiB of logs ingested into Cloud Logging in USD, past the free allotment. Cloud Logging is priced globally, so region is global.",
+		[]string{"region"},
+		nil,
+	)
+	MonitoringIngestionPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_monitoring", "ingestion_usd_per_mib"),
+		"The price of a MiB of metrics ingested into Cloud Monitoring in USD at the first paid tier. Cloud Monitoring is priced globally, so region is global.",
+		[]string{"region"},
+		nil,
+	)
+	MonitoringSamplesPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_monitoring", "samples_usd_per_million"),
+		"The price of a million samples ingested into Cloud Monitoring, eg by Managed Service for Prometheus, in USD at the first paid tier.",
+		[]string{"region"},
+		nil,
+	)
+	LoggingProjectIngestedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_logging", "project_ingested_gib"),
+		"The volume of logs ingested into Cloud Logging by a project over the last hour in GiB.",
+		[]string{"project"},
+		nil,
+	)
+	MonitoringProjectIngestedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_monitoring", "project_ingested_mib"),
+		"The volume of metrics billed by volume ingested into Cloud Monitoring by a project over the last hour in MiB.",
+		[]string{"project"},
+		nil,
+	)
+	MonitoringProjectSamplesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, "gcp_monitoring", "project_samples_ingested"),
+		"The number of samples billed by sample ingested into Cloud Monitoring by a project over the last hour.",
+		[]string{"project"},
+		nil,
+	)
+	ProjectHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "project_usd_per_hour"),
+		"The cost of the data ingested by a project over the last hour in USD, at the first paid tier. service is either logging or monitoring.",
+		[]string{"project", "service"},
+		nil,
+	)
+)
+
+// unitPrice selects the price of a unit among the skus of a service by their description.
+type unitPrice struct {
+	service     string
+	description string
+	desc        *prometheus.Desc
+	// scales converts the price per usage unit of a sku into the unit of the metric. Skus priced in another usage unit
+	// are skipped.
+	scales map[string]float64
+}
+
+var unitPrices = []unitPrice{
+	{
+		service:     loggingServiceName,
+		description: "Log Storage cost",
+		desc:        LoggingIngestionPriceDesc,
+		scales:      map[string]float64{"GiBy": 1},
+	},
+	{
+		service:     monitoringServiceName,
+		description: "Metric Volume",
+		desc:        MonitoringIngestionPriceDesc,
+		scales:      map[string]float64{"MiBy": 1},
+	},
+	{
+		service:     monitoringServiceName,
+		description: "Prometheus Samples Ingested",
+		desc:        MonitoringSamplesPriceDesc,
+		scales:      map[string]float64{"count": perMillion},
+	},
+}
+
+// price is the unit price of a region, in the unit of the metric of its unitPrice.
+type price struct {
+	unitPrice *unitPrice
+	region    string
+	value     float64
+}
+
+// ingestion is the volume a project ingested into Cloud Logging and Cloud Monitoring over the IngestionWindow.
+type ingestion struct {
+	loggingBytes    float64
+	monitoringBytes float64
+	samples         float64
+}
+
+// Collector exports the ingestion prices of Cloud Logging and Cloud Monitoring from the billing catalog, along with
+// the volume every project ingested over the last hour and its cost.
+type Collector struct {
+	billingService    *billingv1.CloudCatalogClient
+	monitoringService *monitoring.Service
+	projects          []string
+	backoff           *provider.Backoff
+	nextScrape        time.Time
+	prices            []price
+	m                 sync.Mutex
+}
+
+type Config struct {
+	Projects       string
+	ScrapeInterval time.Duration
+}
+
+// New creates a Collector. The prices are only listed again every scrape interval, or less often while the Cloud Billing
+// API throttles the collector, while the volumes are looked up on every scrape.
+func New(config *Config, billingService *billingv1.CloudCatalogClient, monitoringService *monitoring.Service) *Collector {
+	var projects []string
+	if config.Projects != "" {
+		projects = strings.Split(config.Projects, ",")
+	}
+	c := &Collector{
+		billingService:    billingService,
+		monitoringService: monitoringService,
+		projects:          projects,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Observability"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- LoggingIngestionPriceDesc
+	ch <- MonitoringIngestionPriceDesc
+	ch <- MonitoringSamplesPriceDesc
+	ch <- LoggingProjectIngestedDesc
+	ch <- MonitoringProjectIngestedDesc
+	ch <- MonitoringProjectSamplesDesc
+	ch <- ProjectHourlyCostDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// ResourceDescs returns the metrics of the resources listed by the collector, its prices aren't resources.
+func (c *Collector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		LoggingProjectIngestedDesc,
+		MonitoringProjectIngestedDesc,
+		MonitoringProjectSamplesDesc,
+		ProjectHourlyCostDesc,
+	}
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices again when the scrape interval has passed, then exports them along with the ingestion of
+// every project. A project failing is reported in its scope error metric and only fails the collector when every
+// project failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listPrices(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing observability prices: %w", err)
+		}
+		c.prices = prices
+	}
+	c.backoff.Emit(ch)
+	global := make(map[*prometheus.Desc]float64)
+	for _, p := range c.prices {
+		ch <- prometheus.MustNewConstMetric(p.unitPrice.desc, prometheus.GaugeValue, p.value, p.region)
+		if p.region == priceRegion {
+			global[p.unitPrice.desc] = p.value
+		}
+	}
+
+	var failedProjects []error
+	for _, project := range c.projects {
+		usage, err := listIngestion(context.TODO(), c.monitoringService, project, now)
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, project, err)
+		if err != nil {
+			log.Printf("error listing the ingestion of project %s: %s", project, err)
+			failedProjects = append(failedProjects, fmt.Errorf("project %s: %w", project, err))
+			continue
+		}
+		loggingGiB := usage.loggingBytes / bytesPerGiB
+		monitoringMiB := usage.monitoringBytes / bytesPerMiB
+		ch <- prometheus.MustNewConstMetric(LoggingProjectIngestedDesc, prometheus.GaugeValue, loggingGiB, project)
+		ch <- prometheus.MustNewConstMetric(MonitoringProjectIngestedDesc, prometheus.GaugeValue, monitoringMiB, project)
+		ch <- prometheus.MustNewConstMetric(MonitoringProjectSamplesDesc, prometheus.GaugeValue, usage.samples, project)
+		if loggingPrice, ok := global[LoggingIngestionPriceDesc]; ok {
+			ch <- prometheus.MustNewConstMetric(ProjectHourlyCostDesc, prometheus.GaugeValue, loggingGiB*loggingPrice, project, "logging")
+		}
+		volumePrice, volumeOk := global[MonitoringIngestionPriceDesc]
+		samplesPrice, samplesOk := global[MonitoringSamplesPriceDesc]
+		if volumeOk || samplesOk {
+			cost := monitoringMiB*volumePrice + usage.samples/perMillion*samplesPrice
+			ch <- prometheus.MustNewConstMetric(ProjectHourlyCostDesc, prometheus.GaugeValue, cost, project, "monitoring")
+		}
+	}
+	if len(c.projects) > 0 && len(failedProjects) == len(c.projects) {
+		return errors.Join(failedProjects...)
+	}
+	return nil
+}
+
+// listPrices lists the skus of Cloud Logging and Cloud Monitoring and returns their unit prices sorted by region.
+func (c *Collector) listPrices(ctx context.Context) ([]price, error) {
+	prices := []price{}
+	for _, service := range []string{loggingServiceName, monitoringServiceName} {
+		serviceName, err := billing.GetServiceName(ctx, c.billingService, service)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the service name of %s: %w", service, err)
+		}
+		skus, err := billing.GetPricing(ctx, c.billingService, serviceName)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the skus of %s: %w", service, err)
+		}
+		prices = append(prices, parsePrices(service, skus)...)
+	}
+	return prices, nil
+}
+
+// parsePrices returns the unit prices found among the skus of a service, one per region the skus are offered in.
+// A unit is priced at its first paid tier, as the free allotments, eg the first 50 GiB of logs of the month, are
+// shared by the whole billing account.
+func parsePrices(service string, skus []*billingpb.Sku) []price {
+	var prices []price
+	for _, sku := range skus {
+		if sku == nil {
+			continue
+		}
+		for i := range unitPrices {
+			up := &unitPrices[i]
+			if up.service != service || !strings.Contains(sku.Description, up.description) {
+				continue
+			}
+			value, ok := billing.FirstPaidTierPrice(sku, up.scales)
+			if !ok {
+				log.Printf("skipping sku %q of %s without a supported price", sku.Description, service)
+				continue
+			}
+			for _, region := range sku.ServiceRegions {
+				prices = append(prices, price{unitPrice: up, region: region, value: value})
+			}
+		}
+	}
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].region < prices[j].region })
+	return prices
+}
+
+// listIngestion looks up the volumes billed to a project over the IngestionWindow ending at now.
+func listIngestion(ctx context.Context, service *monitoring.Service, project string, now time.Time) (ingestion, error) {
+	var usage ingestion
+	var err error
+	if usage.loggingBytes, err = sumBilled(ctx, service, project, loggingBytesMetric, now); err != nil {
+		return ingestion{}, err
+	}
+	if usage.monitoringBytes, err = sumBilled(ctx, service, project, monitoringBytesMetric, now); err != nil {
+		return ingestion{}, err
+	}
+	if usage.samples, err = sumBilled(ctx, service, project, samplesMetric, now); err != nil {
+		return ingestion{}, err
+	}
+	return usage, nil
+}
+
+// sumBilled sums a billing metric of a project across its series over the IngestionWindow ending at now.
+func sumBilled(ctx context.Context, service *monitoring.Service, project string, metricType string, now time.Time) (float64, error) {
+	total := 0.0
+	err := service.Projects.TimeSeries.List("projects/"+project).
+		Filter(fmt.Sprintf(`metric.type = %q`, metricType)).
+		IntervalStartTime(now.Add(-IngestionWindow).Format(time.RFC3339)).
+		IntervalEndTime(now.Format(time.RFC3339)).
+		AggregationAlignmentPeriod(fmt.Sprintf("%ds", int(IngestionWindow.Seconds()))).
+		AggregationPerSeriesAligner("ALIGN_SUM").
+		AggregationCrossSeriesReducer("REDUCE_SUM").
+		Pages(ctx, func(page *monitoring.ListTimeSeriesResponse) error {
+			for _, series := range page.TimeSeries {
+				// The points are sorted from the most recent, which covers the whole window
+				if len(series.Points) == 0 || series.Points[0].Value == nil {
+					continue
+				}
+				value := series.Points[0].Value
+				if value.Int64Value != nil {
+					total += float64(*value.Int64Value)
+				} else if value.DoubleValue != nil {
+					total += *value.DoubleValue
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return 0, fmt.Errorf("error listing %s: %w", metricType, err)
+	}
+	return total, nil
+}
@@ -0,0 +1,174 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testSku(description string, usageUnit string, prices ...*money.Money) *billingpb.Sku {
+	var rates []*billingpb.PricingExpression_TierRate
+	for _, price := range prices {
+		rates = append(rates, &billingpb.PricingExpression_TierRate{UnitPrice: price})
+	}
+	return &billingpb.Sku{
+		Description:    description,
+		ServiceRegions: []string{"global"},
+		PricingInfo: []*billingpb.PricingInfo{{
+			PricingExpression: &billingpb.PricingExpression{UsageUnit: usageUnit, TieredRates: rates},
+		}},
+	}
+}
+
+var (
+	logStorageSku   = testSku("Log Storage cost", "GiBy", &money.Money{}, &money.Money{Nanos: 500000000})
+	metricVolumeSku = testSku("Metric Volume", "MiBy", &money.Money{}, &money.Money{Nanos: 258000000})
+	samplesSku      = testSku("Prometheus Samples Ingested", "count", &money.Money{Nanos: 60})
+)
+
+func Test_parsePrices(t *testing.T) {
+	got := make(map[*prometheus.Desc]float64)
+	for _, p := range parsePrices(monitoringServiceName, []*billingpb.Sku{
+		nil,
+		metricVolumeSku,
+		samplesSku,
+		// A log sku isn't a price of Cloud Monitoring
+		logStorageSku,
+		testSku("Metric Volume", "GiBy", &money.Money{Units: 1}),
+	}) {
+		assert.Equal(t, "global", p.region)
+		got[p.unitPrice.desc] = p.value
+	}
+	require.Len(t, got, 2)
+	assert.InDelta(t, 0.258, got[MonitoringIngestionPriceDesc], 1e-9)
+	assert.InDelta(t, 0.06, got[MonitoringSamplesPriceDesc], 1e-9)
+}
+
+type fakeCloudCatalogServer struct {
+	billingpb.UnimplementedCloudCatalogServer
+	listSkus atomic.Int32
+}
+
+func (s *fakeCloudCatalogServer) ListServices(_ context.Context, _ *billingpb.ListServicesRequest) (*billingpb.ListServicesResponse, error) {
+	return &billingpb.ListServicesResponse{Services: []*billingpb.Service{
+		{DisplayName: loggingServiceName, Name: "services/logging"},
+		{DisplayName: monitoringServiceName, Name: "services/monitoring"},
+	}}, nil
+}
+
+func (s *fakeCloudCatalogServer) ListSkus(_ context.Context, req *billingpb.ListSkusRequest) (*billingpb.ListSkusResponse, error) {
+	s.listSkus.Add(1)
+	if req.Parent == "services/logging" {
+		return &billingpb.ListSkusResponse{Skus: []*billingpb.Sku{logStorageSku}}, nil
+	}
+	return &billingpb.ListSkusResponse{Skus: []*billingpb.Sku{metricVolumeSku, samplesSku}}, nil
+}
+
+func TestCollector_Collect(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	gsrv := grpc.NewServer()
+	defer gsrv.Stop()
+	server := &fakeCloudCatalogServer{}
+	billingpb.RegisterCloudCatalogServer(gsrv, server)
+	go func() {
+		_ = gsrv.Serve(l)
+	}()
+	billingClient, err := billingv1.NewCloudCatalogClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+
+	volumes := map[string]*monitoring.TypedValue{
+		`metric.type = "` + loggingBytesMetric + `"`:    {Int64Value: ptr(int64(2 * bytesPerGiB))},
+		`metric.type = "` + monitoringBytesMetric + `"`: {Int64Value: ptr(int64(10 * bytesPerMiB))},
+		`metric.type = "` + samplesMetric + `"`:         {Int64Value: ptr(int64(3e6))},
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/projects/testing/timeSeries" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Equal(t, "REDUCE_SUM", r.URL.Query().Get("aggregation.crossSeriesReducer"))
+		value := volumes[r.URL.Query().Get("filter")]
+		_ = json.NewEncoder(w).Encode(&monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{{
+			Points: []*monitoring.Point{{Value: value}},
+		}}})
+	}))
+	defer testServer.Close()
+	monitoringService, err := monitoring.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "testing,forbidden", ScrapeInterval: time.Hour}, billingClient, monitoringService)
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 20)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		metrics := make(map[string][]*utils.MetricResult)
+		for metric := range ch {
+			result := utils.ReadMetrics(metric)
+			metrics[result.FqName] = append(metrics[result.FqName], result)
+		}
+		assert.Equal(t, []*utils.MetricResult{{
+			FqName:     "cloudcost_gcp_logging_ingestion_usd_per_gib",
+			Labels:     utils.LabelMap{"region": "global"},
+			Value:      0.5,
+			MetricType: prometheus.GaugeValue,
+		}}, metrics["cloudcost_gcp_logging_ingestion_usd_per_gib"])
+		assert.Len(t, metrics["cloudcost_exporter_collector_scope_last_scrape_error"], 2)
+		require.Len(t, metrics["cloudcost_gcp_logging_project_ingested_gib"], 1)
+		assert.Equal(t, 2.0, metrics["cloudcost_gcp_logging_project_ingested_gib"][0].Value)
+		require.Len(t, metrics["cloudcost_gcp_monitoring_project_ingested_mib"], 1)
+		assert.Equal(t, 10.0, metrics["cloudcost_gcp_monitoring_project_ingested_mib"][0].Value)
+		require.Len(t, metrics["cloudcost_gcp_monitoring_project_samples_ingested"], 1)
+		assert.Equal(t, 3e6, metrics["cloudcost_gcp_monitoring_project_samples_ingested"][0].Value)
+
+		costs := metrics["cloudcost_gcp_observability_project_usd_per_hour"]
+		require.Len(t, costs, 2)
+		assert.Equal(t, utils.LabelMap{"project": "testing", "service": "logging"}, costs[0].Labels)
+		assert.InDelta(t, 2*0.5, costs[0].Value, 1e-9)
+		assert.Equal(t, utils.LabelMap{"project": "testing", "service": "monitoring"}, costs[1].Labels)
+		assert.InDelta(t, 10*0.258+3*0.06, costs[1].Value, 1e-9)
+	}
+	// The prices are only listed once per scrape interval
+	assert.Equal(t, int32(2), server.listSkus.Load())
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+	monitoringService, err := monitoring.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "forbidden"}, nil, monitoringService)
+	// The prices were already listed
+	c.prices = []price{}
+	c.nextScrape = time.Now().Add(time.Hour)
+	ch := make(chan prometheus.Metric, 10)
+	assert.Error(t, c.Collect(ch))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,277 @@
+package spanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/spanner/v1"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/google/billing"
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+const (
+	providerName = "gcp"
+	subsystem    = "gcp_spanner"
+
+	serviceName = "Cloud Spanner"
+
+	// processingUnitsPerNode is the number of processing units of a node, the compute capacity of an instance is
+	// either set in nodes or in processing units.
+	processingUnitsPerNode = 1000
+	// regionalConfigPrefix prefixes the instance configs of a single region, eg regional-us-central1. The other
+	// instance configs are multi-region configs, eg nam3.
+	regionalConfigPrefix = "regional-"
+
+	stateReady       = "READY"
+	typeFreeInstance = "FREE_INSTANCE"
+
+	locationTypeRegional    = "regional"
+	locationTypeMultiRegion = "multi_region"
+)
+
+var (
+	instanceLabels = []string{"project", "instance", "instance_config", "location", "location_type"}
+
+	InstanceProcessingUnitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_processing_units"),
+		"The compute capacity of a Spanner instance in processing units, a node being 1000 processing units.",
+		instanceLabels,
+		nil,
+	)
+	InstanceComputeHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_compute_usd_per_hour"),
+		"The hourly cost of the compute capacity of a Spanner instance in USD, at the price of its instance config. Free trial instances cost nothing.",
+		instanceLabels,
+		nil,
+	)
+	InstanceStorageHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, subsystem, "instance_storage_usd_per_gib_hour"),
+		"The hourly price of a GiB stored in a Spanner instance in USD, at the price of its instance config. Free trial instances cost nothing.",
+		instanceLabels,
+		nil,
+	)
+)
+
+// prices are the prices of the locations of the instance configs, a region for the regional configs and the name of
+// the config for the multi-region configs.
+type prices struct {
+	// nodeHour is the price of a node for an hour
+	nodeHour map[string]float64
+	// gibHour is the price of a GiB stored for an hour
+	gibHour map[string]float64
+}
+
+// instance is a Spanner instance along with the location its instance config is priced at.
+type instance struct {
+	project         string
+	name            string
+	config          string
+	location        string
+	locationType    string
+	processingUnits float64
+	free            bool
+}
+
+// Collector exports the hourly compute and storage prices of the Spanner instances of every project, priced with the
+// skus of Cloud Spanner of the billing catalog.
+type Collector struct {
+	billingService *billingv1.CloudCatalogClient
+	spannerService *spanner.Service
+	projects       []string
+	backoff        *provider.Backoff
+	nextScrape     time.Time
+	prices         *prices
+	m              sync.Mutex
+}
+
+type Config struct {
+	Projects       string
+	ScrapeInterval time.Duration
+}
+
+// New creates a Collector. The prices are only listed again every scrape interval, or less often while the Cloud Billing
+// API throttles the collector, while the instances are listed on every scrape.
+func New(config *Config, billingService *billingv1.CloudCatalogClient, spannerService *spanner.Service) *Collector {
+	var projects []string
+	if config.Projects != "" {
+		projects = strings.Split(config.Projects, ",")
+	}
+	c := &Collector{
+		billingService: billingService,
+		spannerService: spannerService,
+		projects:       projects,
+	}
+	c.backoff = provider.NewBackoff(providerName, c.Name(), config.ScrapeInterval)
+	return c
+}
+
+func (c *Collector) Name() string {
+	return "Spanner"
+}
+
+func (c *Collector) Register(_ provider.Registry) error {
+	return nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- InstanceProcessingUnitsDesc
+	ch <- InstanceComputeHourlyCostDesc
+	ch <- InstanceStorageHourlyCostDesc
+	ch <- provider.ScopeLastScrapeErrorDesc
+	ch <- provider.RefreshIntervalDesc
+	return nil
+}
+
+// CollectMetrics is a no-op function that satisfies the provider.Collector interface.
+// Deprecated: CollectMetrics is deprecated and will be removed in a future release.
+func (c *Collector) CollectMetrics(_ chan<- prometheus.Metric) float64 {
+	return 0
+}
+
+// Collect lists the prices again when the scrape interval has passed, then exports the instances of every project. A
+// project failing is reported in its scope error metric and only fails the collector when every project failed.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.prices == nil || now.After(c.nextScrape) {
+		prices, err := c.listPrices(context.TODO())
+		c.nextScrape = c.backoff.Next(now, err)
+		if err != nil {
+			return fmt.Errorf("error listing spanner prices: %w", err)
+		}
+		c.prices = prices
+	}
+	c.backoff.Emit(ch)
+
+	var failedProjects []error
+	for _, project := range c.projects {
+		instances, err := listInstances(context.TODO(), c.spannerService, project)
+		ch <- provider.NewScopeErrorMetric(providerName, subsystem, project, err)
+		if err != nil {
+			log.Printf("error listing the spanner instances of project %s: %s", project, err)
+			failedProjects = append(failedProjects, fmt.Errorf("project %s: %w", project, err))
+			continue
+		}
+		for _, i := range instances {
+			labels := []string{i.project, i.name, i.config, i.location, i.locationType}
+			ch <- prometheus.MustNewConstMetric(InstanceProcessingUnitsDesc, prometheus.GaugeValue, i.processingUnits, labels...)
+			nodeHour, ok := c.prices.nodeHour[i.location]
+			if !ok && !i.free {
+				log.Printf("skipping the cost of spanner instance %s of project %s: no price for location %s", i.name, i.project, i.location)
+				continue
+			}
+			gibHour := c.prices.gibHour[i.location]
+			if i.free {
+				nodeHour, gibHour = 0, 0
+			}
+			ch <- prometheus.MustNewConstMetric(InstanceComputeHourlyCostDesc, prometheus.GaugeValue, i.processingUnits/processingUnitsPerNode*nodeHour, labels...)
+			ch <- prometheus.MustNewConstMetric(InstanceStorageHourlyCostDesc, prometheus.GaugeValue, gibHour, labels...)
+		}
+	}
+	if len(c.projects) > 0 && len(failedProjects) == len(c.projects) {
+		return errors.Join(failedProjects...)
+	}
+	return nil
+}
+
+// listPrices lists the skus of Cloud Spanner and returns the prices of every location they're offered in.
+func (c *Collector) listPrices(ctx context.Context) (*prices, error) {
+	name, err := billing.GetServiceName(ctx, c.billingService, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the service name of %s: %w", serviceName, err)
+	}
+	skus, err := billing.GetPricing(ctx, c.billingService, name)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the skus of %s: %w", serviceName, err)
+	}
+	return parsePrices(skus), nil
+}
+
+// parsePrices returns the compute and storage prices of the skus of Cloud Spanner, keyed by the locations the skus are
+// offered in. Compute is priced either per node or per processing unit, and both are converted to the price of a node.
+// Backup storage and the skus of the Enterprise editions are skipped, as the instances aren't listed with their edition
+// and are priced at the Standard edition.
+func parsePrices(skus []*billingpb.Sku) *prices {
+	p := &prices{nodeHour: make(map[string]float64), gibHour: make(map[string]float64)}
+	for _, sku := range skus {
+		if sku == nil || strings.Contains(sku.Description, "Backup") || strings.Contains(sku.Description, "Enterprise") {
+			continue
+		}
+		var target map[string]float64
+		var scales map[string]float64
+		switch {
+		case strings.Contains(sku.Description, "Processing Unit"):
+			target, scales = p.nodeHour, map[string]float64{"h": processingUnitsPerNode}
+		case strings.Contains(sku.Description, "Node"):
+			target, scales = p.nodeHour, map[string]float64{"h": 1}
+		case strings.Contains(sku.Description, "Storage"):
+			target, scales = p.gibHour, map[string]float64{"GiBy.h": 1, "GiBy.mo": 1 / utils.HoursInMonth}
+		default:
+			continue
+		}
+		value, ok := billing.FirstPaidTierPrice(sku, scales)
+		if !ok {
+			log.Printf("skipping sku %q of %s without a supported price", sku.Description, serviceName)
+			continue
+		}
+		for _, region := range sku.ServiceRegions {
+			if _, ok := target[region]; !ok {
+				target[region] = value
+			}
+		}
+	}
+	return p
+}
+
+// listInstances lists the ready instances of a project.
+func listInstances(ctx context.Context, service *spanner.Service, project string) ([]instance, error) {
+	var instances []instance
+	err := service.Projects.Instances.List("projects/"+project).Pages(ctx, func(page *spanner.ListInstancesResponse) error {
+		for _, i := range page.Instances {
+			if i.State != stateReady {
+				continue
+			}
+			instances = append(instances, parseInstance(project, i))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// parseInstance returns the location an instance is priced at and its compute capacity in processing units. The
+// capacity is only set in nodes by the instances created before the processing units were introduced.
+func parseInstance(project string, i *spanner.Instance) instance {
+	config := i.Config[strings.LastIndex(i.Config, "/")+1:]
+	location, locationType := config, locationTypeMultiRegion
+	if region, ok := strings.CutPrefix(config, regionalConfigPrefix); ok {
+		location, locationType = region, locationTypeRegional
+	}
+	processingUnits := i.ProcessingUnits
+	if processingUnits == 0 {
+		processingUnits = i.NodeCount * processingUnitsPerNode
+	}
+	return instance{
+		project:         project,
+		name:            i.Name[strings.LastIndex(i.Name, "/")+1:],
+		config:          config,
+		location:        location,
+		locationType:    locationType,
+		processingUnits: float64(processingUnits),
+		free:            i.InstanceType == typeFreeInstance,
+	}
+}
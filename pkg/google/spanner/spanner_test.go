@@ -0,0 +1,178 @@
+package spanner
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	billingv1 "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/api/spanner/v1"
+	"google.golang.org/genproto/googleapis/type/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func testSku(description string, usageUnit string, price *money.Money, regions ...string) *billingpb.Sku {
+	return &billingpb.Sku{
+		Description:    description,
+		ServiceRegions: regions,
+		PricingInfo: []*billingpb.PricingInfo{{
+			PricingExpression: &billingpb.PricingExpression{
+				UsageUnit:   usageUnit,
+				TieredRates: []*billingpb.PricingExpression_TierRate{{UnitPrice: price}},
+			},
+		}},
+	}
+}
+
+var (
+	regionalNodeSku    = testSku("Cloud Spanner Instance Node - Regional: Iowa", "h", &money.Money{Nanos: 900000000}, "us-central1")
+	multiRegionNodeSku = testSku("Cloud Spanner Instance Processing Unit - Multi-Regional: nam3", "h", &money.Money{Nanos: 3000000}, "nam3")
+	regionalStorageSku = testSku("Cloud Spanner Storage - Regional: Iowa", "GiBy.mo", &money.Money{Nanos: 300000000}, "us-central1")
+)
+
+func Test_parsePrices(t *testing.T) {
+	p := parsePrices([]*billingpb.Sku{
+		nil,
+		regionalNodeSku,
+		multiRegionNodeSku,
+		regionalStorageSku,
+		testSku("Cloud Spanner Backup Storage - Regional: Iowa", "GiBy.mo", &money.Money{Nanos: 100000000}, "us-central1"),
+		testSku("Spanner Enterprise Edition Processing Unit - Regional: Iowa", "h", &money.Money{Nanos: 1200000}, "us-central1"),
+		testSku("Cloud Spanner Instance Node - Regional: Oregon", "GiBy", &money.Money{Units: 1}, "us-west1"),
+	})
+	assert.Equal(t, map[string]float64{"us-central1": 0.9, "nam3": 3}, p.nodeHour)
+	require.Len(t, p.gibHour, 1)
+	assert.InDelta(t, 0.3/utils.HoursInMonth, p.gibHour["us-central1"], 1e-12)
+}
+
+func Test_parseInstance(t *testing.T) {
+	tests := map[string]struct {
+		instance *spanner.Instance
+		want     instance
+	}{
+		"regional config in processing units": {
+			instance: &spanner.Instance{Name: "projects/testing/instances/orders", Config: "projects/testing/instanceConfigs/regional-us-central1", ProcessingUnits: 500},
+			want:     instance{project: "testing", name: "orders", config: "regional-us-central1", location: "us-central1", locationType: "regional", processingUnits: 500},
+		},
+		"multi-region config in nodes": {
+			instance: &spanner.Instance{Name: "projects/testing/instances/ledger", Config: "projects/testing/instanceConfigs/nam3", NodeCount: 2},
+			want:     instance{project: "testing", name: "ledger", config: "nam3", location: "nam3", locationType: "multi_region", processingUnits: 2000},
+		},
+		"free trial instance": {
+			instance: &spanner.Instance{Name: "projects/testing/instances/trial", Config: "projects/testing/instanceConfigs/regional-us-central1", ProcessingUnits: 100, InstanceType: "FREE_INSTANCE"},
+			want:     instance{project: "testing", name: "trial", config: "regional-us-central1", location: "us-central1", locationType: "regional", processingUnits: 100, free: true},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseInstance("testing", tt.instance))
+		})
+	}
+}
+
+type fakeCloudCatalogServer struct {
+	billingpb.UnimplementedCloudCatalogServer
+	listSkus atomic.Int32
+}
+
+func (s *fakeCloudCatalogServer) ListServices(_ context.Context, _ *billingpb.ListServicesRequest) (*billingpb.ListServicesResponse, error) {
+	return &billingpb.ListServicesResponse{Services: []*billingpb.Service{
+		{DisplayName: serviceName, Name: "services/spanner"},
+	}}, nil
+}
+
+func (s *fakeCloudCatalogServer) ListSkus(_ context.Context, _ *billingpb.ListSkusRequest) (*billingpb.ListSkusResponse, error) {
+	s.listSkus.Add(1)
+	return &billingpb.ListSkusResponse{Skus: []*billingpb.Sku{regionalNodeSku, multiRegionNodeSku, regionalStorageSku}}, nil
+}
+
+func TestCollector_Collect(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	gsrv := grpc.NewServer()
+	defer gsrv.Stop()
+	server := &fakeCloudCatalogServer{}
+	billingpb.RegisterCloudCatalogServer(gsrv, server)
+	go func() {
+		_ = gsrv.Serve(l)
+	}()
+	billingClient, err := billingv1.NewCloudCatalogClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/testing/instances" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&spanner.ListInstancesResponse{Instances: []*spanner.Instance{
+			{Name: "projects/testing/instances/orders", Config: "projects/testing/instanceConfigs/regional-us-central1", ProcessingUnits: 500, State: "READY"},
+			{Name: "projects/testing/instances/ledger", Config: "projects/testing/instanceConfigs/nam3", NodeCount: 2, ProcessingUnits: 2000, State: "READY"},
+			// Neither an instance being created nor a config without prices is priced
+			{Name: "projects/testing/instances/creating", Config: "projects/testing/instanceConfigs/regional-us-central1", ProcessingUnits: 100, State: "CREATING"},
+			{Name: "projects/testing/instances/custom", Config: "projects/testing/instanceConfigs/custom-nam3", ProcessingUnits: 100, State: "READY"},
+		}})
+	}))
+	defer testServer.Close()
+	spannerService, err := spanner.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "testing,forbidden", ScrapeInterval: time.Hour}, billingClient, spannerService)
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 20)
+		require.NoError(t, c.Collect(ch))
+		close(ch)
+		metrics := make(map[string][]*utils.MetricResult)
+		for metric := range ch {
+			result := utils.ReadMetrics(metric)
+			metrics[result.FqName] = append(metrics[result.FqName], result)
+		}
+		assert.Len(t, metrics["cloudcost_exporter_collector_scope_last_scrape_error"], 2)
+		assert.Len(t, metrics["cloudcost_gcp_spanner_instance_processing_units"], 3)
+
+		compute := metrics["cloudcost_gcp_spanner_instance_compute_usd_per_hour"]
+		require.Len(t, compute, 2)
+		assert.Equal(t, utils.LabelMap{"project": "testing", "instance": "orders", "instance_config": "regional-us-central1", "location": "us-central1", "location_type": "regional"}, compute[0].Labels)
+		assert.InDelta(t, 0.5*0.9, compute[0].Value, 1e-9)
+		assert.Equal(t, "multi_region", compute[1].Labels["location_type"])
+		assert.InDelta(t, 2*3.0, compute[1].Value, 1e-9)
+
+		storage := metrics["cloudcost_gcp_spanner_instance_storage_usd_per_gib_hour"]
+		require.Len(t, storage, 2)
+		assert.InDelta(t, 0.3/utils.HoursInMonth, storage[0].Value, 1e-12)
+		assert.Equal(t, 0.0, storage[1].Value, "the multi-region config has no storage sku")
+	}
+	// The prices are only listed once per scrape interval
+	assert.Equal(t, int32(1), server.listSkus.Load())
+}
+
+func TestCollector_CollectError(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+	spannerService, err := spanner.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint(testServer.URL))
+	require.NoError(t, err)
+
+	c := New(&Config{Projects: "forbidden"}, nil, spannerService)
+	// The prices were already listed
+	c.prices = &prices{}
+	c.nextScrape = time.Now().Add(time.Hour)
+	ch := make(chan prometheus.Metric, 10)
+	assert.Error(t, c.Collect(ch))
+}
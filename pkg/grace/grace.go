@@ -0,0 +1,150 @@
+// Package grace keeps exporting the resources that disappear from a collection for a grace window, so that a list
+// API temporarily returning partial pages doesn't make their series drop and come back between scrapes.
+package grace
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// PresenceLabel flags the series of a resource that is only exported because it's within its grace window.
+	PresenceLabel = "presence"
+	// PresenceGrace is the value of PresenceLabel of the series within their grace window.
+	PresenceGrace = "grace"
+)
+
+// Window remembers the gauges gathered within the last window and exports the ones that disappeared again, labelled
+// with presence="grace", until they're older than window. A gauge that comes back is exported as before, without the
+// label. Counters and the other metric types are never kept, a disappeared counter of a resource must reset anyway.
+type Window struct {
+	window time.Duration
+	now    func() time.Time
+
+	m    sync.Mutex
+	seen map[string]*seenMetric
+}
+
+type seenMetric struct {
+	family   *dto.MetricFamily
+	metric   *dto.Metric
+	lastSeen time.Time
+}
+
+// New returns a Window keeping the disappeared gauges for window. It returns nil when window is 0, which disables the
+// grace window.
+func New(window time.Duration) *Window {
+	if window <= 0 {
+		return nil
+	}
+	return &Window{
+		window: window,
+		now:    time.Now,
+		seen:   make(map[string]*seenMetric),
+	}
+}
+
+// Gatherer returns a Gatherer adding the gauges of g that disappeared within the window to its families. g is returned
+// unchanged when w is nil.
+func (w *Window) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	if w == nil {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		return w.apply(families), err
+	})
+}
+
+// apply records the gauges of families as seen now and appends the ones seen within the window but missing from them.
+func (w *Window) apply(families []*dto.MetricFamily) []*dto.MetricFamily {
+	w.m.Lock()
+	defer w.m.Unlock()
+	now := w.now()
+	present := make(map[string]bool)
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+		if family.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+		// Only the metadata of the family is kept, its metrics are kept one by one
+		header := &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type, Unit: family.Unit}
+		for _, m := range family.GetMetric() {
+			if hasPresence(m) {
+				continue
+			}
+			key := seriesKey(family.GetName(), m)
+			present[key] = true
+			w.seen[key] = &seenMetric{
+				family:   header,
+				metric:   proto.Clone(m).(*dto.Metric),
+				lastSeen: now,
+			}
+		}
+	}
+
+	var missing []string
+	for key, s := range w.seen {
+		if present[key] {
+			continue
+		}
+		if now.Sub(s.lastSeen) > w.window {
+			delete(w.seen, key)
+			continue
+		}
+		missing = append(missing, key)
+	}
+	// Keep the order of the exposition stable from one scrape to the next
+	sort.Strings(missing)
+	for _, key := range missing {
+		s := w.seen[key]
+		family, ok := byName[s.family.GetName()]
+		if !ok {
+			// Every series of the family disappeared, eg because its collector failed
+			family = proto.Clone(s.family).(*dto.MetricFamily)
+			byName[family.GetName()] = family
+			families = append(families, family)
+		}
+		family.Metric = append(family.Metric, withPresence(s.metric))
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families
+}
+
+// seriesKey identifies a series by the name of its family and its labels, which the registry sorts by name.
+func seriesKey(name string, m *dto.Metric) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, pair := range m.GetLabel() {
+		b.WriteByte(0xff)
+		b.WriteString(pair.GetName())
+		b.WriteByte('=')
+		b.WriteString(pair.GetValue())
+	}
+	return b.String()
+}
+
+func hasPresence(m *dto.Metric) bool {
+	for _, pair := range m.GetLabel() {
+		if pair.GetName() == PresenceLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// withPresence returns a copy of a metric labelled with presence="grace", keeping its labels sorted by name as the
+// registry does.
+func withPresence(m *dto.Metric) *dto.Metric {
+	grace := proto.Clone(m).(*dto.Metric)
+	grace.Label = append(grace.Label, &dto.LabelPair{Name: proto.String(PresenceLabel), Value: proto.String(PresenceGrace)})
+	sort.Slice(grace.Label, func(i, j int) bool { return grace.Label[i].GetName() < grace.Label[j].GetName() })
+	return grace
+}
@@ -0,0 +1,87 @@
+package grace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(0))
+	registry := prometheus.NewRegistry()
+	assert.Equal(t, prometheus.Gatherer(registry), New(0).Gatherer(registry))
+}
+
+func TestWindow_Gatherer(t *testing.T) {
+	now := time.Now()
+	window := New(10 * time.Minute)
+	window.now = func() time.Time { return now }
+
+	registry := prometheus.NewRegistry()
+	instanceCost := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_instance_usd_per_hour", Help: "Cost of an instance."}, []string{"instance"})
+	volumeCost := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_volume_usd_per_hour", Help: "Cost of a volume."}, []string{"volume"})
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests."}, []string{"instance"})
+	registry.MustRegister(instanceCost, volumeCost, requests)
+	instanceCost.WithLabelValues("a").Set(1)
+	instanceCost.WithLabelValues("b").Set(2)
+	volumeCost.WithLabelValues("v").Set(3)
+	requests.WithLabelValues("a").Inc()
+	gatherer := window.Gatherer(registry)
+
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 1
+test_instance_usd_per_hour{instance="b"} 2
+# HELP test_volume_usd_per_hour Cost of a volume.
+# TYPE test_volume_usd_per_hour gauge
+test_volume_usd_per_hour{volume="v"} 3
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{instance="a"} 1
+`)))
+
+	// A partial page drops an instance, every volume and the counter
+	now = now.Add(5 * time.Minute)
+	instanceCost.DeleteLabelValues("b")
+	instanceCost.WithLabelValues("a").Set(4)
+	volumeCost.Reset()
+	requests.Reset()
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 4
+test_instance_usd_per_hour{instance="b",presence="grace"} 2
+# HELP test_volume_usd_per_hour Cost of a volume.
+# TYPE test_volume_usd_per_hour gauge
+test_volume_usd_per_hour{presence="grace",volume="v"} 3
+`)))
+
+	// The volume comes back while the instance is kept until the end of its window
+	now = now.Add(5 * time.Minute)
+	volumeCost.WithLabelValues("v").Set(3)
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 4
+test_instance_usd_per_hour{instance="b",presence="grace"} 2
+# HELP test_volume_usd_per_hour Cost of a volume.
+# TYPE test_volume_usd_per_hour gauge
+test_volume_usd_per_hour{volume="v"} 3
+`)))
+
+	now = now.Add(time.Second)
+	require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(`
+# HELP test_instance_usd_per_hour Cost of an instance.
+# TYPE test_instance_usd_per_hour gauge
+test_instance_usd_per_hour{instance="a"} 4
+# HELP test_volume_usd_per_hour Cost of a volume.
+# TYPE test_volume_usd_per_hour gauge
+test_volume_usd_per_hour{volume="v"} 3
+`)))
+}
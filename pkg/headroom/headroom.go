@@ -0,0 +1,120 @@
+// Package headroom estimates the cost of the autoscaler headroom of the clusters: the nodes of their autoscaled node
+// pools above the minimum size of the pool, which the cluster autoscaler could remove if the workloads didn't need them.
+package headroom
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+var (
+	// ClusterHeadroomHourlyCostDesc is the cost of the current capacity of the autoscaled node pools of a cluster minus
+	// the cost of their minimum capacity.
+	ClusterHeadroomHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "cluster", "headroom_usd_per_hour"),
+		"The cost of the nodes of the autoscaled node pools of a cluster above the minimum size of their node pool in USD/h, the cost of their current capacity minus the cost of their minimum capacity.",
+		[]string{"cluster_name", "provider"},
+		nil,
+	)
+)
+
+type poolKey struct {
+	cluster  string
+	nodePool string
+}
+
+type pool struct {
+	autoscaled bool
+	minNodes   int64
+	nodes      int64
+	cost       float64
+}
+
+// Scrape accumulates the autoscaling bounds and the hourly cost of the nodes of the node pools of every cluster during
+// a scrape. A nil Scrape is safe to use and exports nothing, which disables the headroom. It's safe for concurrent use.
+type Scrape struct {
+	m     sync.Mutex
+	pools map[poolKey]*pool
+}
+
+// NewScrape returns a Scrape when enabled is set, nil otherwise.
+func NewScrape(enabled bool) *Scrape {
+	if !enabled {
+		return nil
+	}
+	return &Scrape{pools: make(map[poolKey]*pool)}
+}
+
+// SetMinNodes marks a node pool as autoscaled down to minNodes nodes, across every zone of the pool. The node pools
+// that are never set aren't autoscaled and have no headroom.
+func (s *Scrape) SetMinNodes(cluster string, nodePool string, minNodes int64) {
+	if s == nil {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	p := s.pool(cluster, nodePool)
+	p.autoscaled = true
+	p.minNodes = minNodes
+}
+
+// Add adds the hourly cost of a node to its node pool.
+func (s *Scrape) Add(cluster string, nodePool string, usdPerHour float64) {
+	if s == nil {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	p := s.pool(cluster, nodePool)
+	p.nodes++
+	p.cost += usdPerHour
+}
+
+// pool returns the node pool of a cluster, adding it when it wasn't seen yet. s.m must be held.
+func (s *Scrape) pool(cluster string, nodePool string) *pool {
+	key := poolKey{cluster: cluster, nodePool: nodePool}
+	p, ok := s.pools[key]
+	if !ok {
+		p = &pool{}
+		s.pools[key] = p
+	}
+	return p
+}
+
+// headroom is the cost of the nodes of the pool above its minimum size, at the average cost of its nodes.
+func (p *pool) headroom() float64 {
+	if p.nodes <= p.minNodes {
+		return 0
+	}
+	return p.cost * float64(p.nodes-p.minNodes) / float64(p.nodes)
+}
+
+// Metrics returns the headroom of every cluster with at least one autoscaled node pool.
+func (s *Scrape) Metrics(provider string) []prometheus.Metric {
+	if s == nil {
+		return nil
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	clusters := make(map[string]float64)
+	for key, p := range s.pools {
+		if !p.autoscaled {
+			continue
+		}
+		clusters[key.cluster] += p.headroom()
+	}
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]prometheus.Metric, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, prometheus.MustNewConstMetric(ClusterHeadroomHourlyCostDesc, prometheus.GaugeValue, clusters[name], name, provider))
+	}
+	return metrics
+}
@@ -0,0 +1,43 @@
+package headroom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestNewScrape(t *testing.T) {
+	s := NewScrape(false)
+	assert.Nil(t, s)
+	s.SetMinNodes("test", "default-pool", 1)
+	s.Add("test", "default-pool", 0.1)
+	assert.Nil(t, s.Metrics("gcp"), "a disabled scrape is safe to use")
+}
+
+func TestScrape_Metrics(t *testing.T) {
+	s := NewScrape(true)
+	// 3 nodes above a minimum of 1, at an average of 0.2 USD/h
+	s.SetMinNodes("test", "default-pool", 1)
+	s.Add("test", "default-pool", 0.1)
+	s.Add("test", "default-pool", 0.2)
+	s.Add("test", "default-pool", 0.3)
+	// Node pools that aren't autoscaled have no headroom
+	s.Add("test", "static-pool", 1)
+	// Node pools at their minimum size or below have no headroom
+	s.SetMinNodes("test-1", "default-pool", 2)
+	s.Add("test-1", "default-pool", 0.5)
+	// Clusters without an autoscaled node pool aren't exported
+	s.Add("test-2", "static-pool", 1)
+
+	headroom := make(map[string]float64)
+	for _, m := range s.Metrics("gcp") {
+		result := utils.ReadMetrics(m)
+		require.Equal(t, "cloudcost_cluster_headroom_usd_per_hour", result.FqName)
+		require.Equal(t, "gcp", result.Labels["provider"])
+		headroom[result.Labels["cluster_name"]] = result.Value
+	}
+	assert.InDeltaMapValues(t, map[string]float64{"test": 0.4, "test-1": 0}, headroom, 1e-9)
+}
@@ -0,0 +1,86 @@
+// Package httpsd serves the exporter as a Prometheus HTTP service discovery target group labelled with the identity of
+// the cloud account it collects, so that a central Prometheus scraping a fleet of exporters, eg one per AWS account,
+// GCP project or Azure subscription, can tell them apart without hand-maintained scrape configs.
+package httpsd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// Path serves the target groups of the exporter.
+const Path = "/sd"
+
+// IdentityLabelPrefix prefixes the identity labels of the targets. The metrics already carry scope labels like
+// project, account_id or subscription_id, which Prometheus would rename to exported_* if the targets had them too.
+const IdentityLabelPrefix = "cloudcost_"
+
+// metricsPathLabel is the label Prometheus scrapes a target at when it's set.
+const metricsPathLabel = "__metrics_path__"
+
+// TargetGroup is a target group of the HTTP service discovery, see
+// https://prometheus.io/docs/prometheus/latest/http_sd/.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type Config struct {
+	// Target is the address Prometheus scrapes the exporter at, eg cloudcost-exporter.monitoring:8080. The host the
+	// service discovery is requested at is used when empty.
+	Target      string
+	MetricsPath string
+	// Identity labels the targets with the cloud account of the exporter, eg provider=aws. Its keys are prefixed with
+	// IdentityLabelPrefix and empty values are left out.
+	Identity map[string]string
+	// Labels are added to the targets as is, eg to label an exporter with the account its credentials belong to.
+	Labels map[string]string
+	// TenantPaths maps the tenants served on their own path to it, see tenant.Tenants. Every tenant is an additional
+	// target group labelled with its tenant.
+	TenantPaths map[string]string
+}
+
+// Handler serves the target groups of the exporter as JSON: one scraping the metrics path, and one per tenant path.
+func Handler(cfg Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := cfg.Target
+		if target == "" {
+			target = r.Host
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(cfg.targetGroups(target))
+	})
+}
+
+// targetGroups returns the target group of the metrics path followed by the ones of the tenants, sorted by tenant.
+func (cfg Config) targetGroups(target string) []TargetGroup {
+	groups := []TargetGroup{{Targets: []string{target}, Labels: cfg.labels(cfg.MetricsPath)}}
+	tenants := make([]string, 0, len(cfg.TenantPaths))
+	for tenant := range cfg.TenantPaths {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	for _, tenant := range tenants {
+		labels := cfg.labels(cfg.TenantPaths[tenant])
+		labels[IdentityLabelPrefix+"tenant"] = tenant
+		groups = append(groups, TargetGroup{Targets: []string{target}, Labels: labels})
+	}
+	return groups
+}
+
+func (cfg Config) labels(metricsPath string) map[string]string {
+	labels := make(map[string]string, len(cfg.Identity)+len(cfg.Labels)+1)
+	for name, value := range cfg.Identity {
+		if value != "" {
+			labels[IdentityLabelPrefix+name] = value
+		}
+	}
+	for name, value := range cfg.Labels {
+		labels[name] = value
+	}
+	if metricsPath != "" {
+		labels[metricsPathLabel] = metricsPath
+	}
+	return labels
+}
@@ -0,0 +1,67 @@
+package httpsd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cfg      Config
+		expected []TargetGroup
+	}{
+		{
+			name: "the host of the request is the target when none is set",
+			cfg: Config{
+				MetricsPath: "/metrics",
+				Identity:    map[string]string{"provider": "gcp", "project": "payments-prod", "region": ""},
+			},
+			expected: []TargetGroup{{
+				Targets: []string{"exporter.monitoring:8080"},
+				Labels:  map[string]string{"__metrics_path__": "/metrics", "cloudcost_provider": "gcp", "cloudcost_project": "payments-prod"},
+			}},
+		},
+		{
+			name: "every tenant path is a target group",
+			cfg: Config{
+				Target:      "cloudcost-exporter:8080",
+				MetricsPath: "/metrics",
+				Identity:    map[string]string{"provider": "azure", "subscription_id": "sub"},
+				Labels:      map[string]string{"team": "platform"},
+				TenantPaths: map[string]string{"search": "/metrics/search", "payments": "/metrics/payments"},
+			},
+			expected: []TargetGroup{
+				{
+					Targets: []string{"cloudcost-exporter:8080"},
+					Labels:  map[string]string{"__metrics_path__": "/metrics", "cloudcost_provider": "azure", "cloudcost_subscription_id": "sub", "team": "platform"},
+				},
+				{
+					Targets: []string{"cloudcost-exporter:8080"},
+					Labels:  map[string]string{"__metrics_path__": "/metrics/payments", "cloudcost_provider": "azure", "cloudcost_subscription_id": "sub", "team": "platform", "cloudcost_tenant": "payments"},
+				},
+				{
+					Targets: []string{"cloudcost-exporter:8080"},
+					Labels:  map[string]string{"__metrics_path__": "/metrics/search", "cloudcost_provider": "azure", "cloudcost_subscription_id": "sub", "team": "platform", "cloudcost_tenant": "search"},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://exporter.monitoring:8080"+Path, nil)
+			res := httptest.NewRecorder()
+
+			Handler(tc.cfg).ServeHTTP(res, req)
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+			var groups []TargetGroup
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &groups))
+			assert.Equal(t, tc.expected, groups)
+		})
+	}
+}
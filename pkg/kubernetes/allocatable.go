@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+const (
+	subsystem = "node"
+)
+
+var (
+	// NodeCPUAllocatableHourlyCostDesc and NodeMemoryAllocatableHourlyCostDesc spread the price of a node over the
+	// resources pods can request, rather than over the resources of the machine. They are emitted by the collectors
+	// that price the instances backing the nodes, eg eks and gke.
+	NodeCPUAllocatableHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "cpu_allocatable_usd_per_core_hour"),
+		"The cpu cost of a Kubernetes node in USD/(core*h) per allocatable core, which accounts for the cores reserved for the system.",
+		[]string{"node", "cluster_name", "provider", "machine_type", "price_tier"},
+		nil,
+	)
+	NodeMemoryAllocatableHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "memory_allocatable_usd_per_gib_hour"),
+		"The memory cost of a Kubernetes node in USD/(GiB*h) per allocatable GiB, which accounts for the memory reserved for the system and the eviction threshold.",
+		[]string{"node", "cluster_name", "provider", "machine_type", "price_tier"},
+		nil,
+	)
+)
+
+// NodesByName lists the nodes of the cluster keyed by name. It returns nil when lister is nil, which is the case when
+// allocatable costs are disabled, or when the nodes can't be listed as they only enrich the instance metrics.
+func NodesByName(ctx context.Context, lister NodeLister) map[string]Node {
+	if lister == nil {
+		return nil
+	}
+	nodes, err := lister.ListNodes(ctx)
+	if err != nil {
+		log.Printf("error listing kubernetes nodes: %s", err)
+		return nil
+	}
+	byName := make(map[string]Node, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+	return byName
+}
+
+// AllocatableCost returns the price per allocatable unit of a resource given its price per unit of capacity. The
+// machine is billed for its capacity, but pods can only request what's allocatable, so the price per allocatable
+// unit is higher. The price is returned as is when the node reports no allocatable resources.
+func AllocatableCost(price float64, capacity float64, allocatable float64) float64 {
+	if allocatable <= 0 || capacity <= 0 {
+		return price
+	}
+	return price * capacity / allocatable
+}
+
+// AllocatableMetrics returns the allocatable cost metrics of a node given the cpu and memory prices of its instance.
+func AllocatableMetrics(node Node, cpuPrice float64, ramPrice float64, labelValues ...string) []prometheus.Metric {
+	labelValues = append([]string{node.Name}, labelValues...)
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(NodeCPUAllocatableHourlyCostDesc, prometheus.GaugeValue, AllocatableCost(cpuPrice, node.Capacity.Cpus, node.Allocatable.Cpus), labelValues...),
+		prometheus.MustNewConstMetric(NodeMemoryAllocatableHourlyCostDesc, prometheus.GaugeValue, AllocatableCost(ramPrice, node.Capacity.MemoryGiB, node.Allocatable.MemoryGiB), labelValues...),
+	}
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+type fakeNodeLister struct {
+	nodes []Node
+	err   error
+}
+
+func (f *fakeNodeLister) ListNodes(_ context.Context) ([]Node, error) {
+	return f.nodes, f.err
+}
+
+func TestAllocatableCost(t *testing.T) {
+	tests := map[string]struct {
+		price       float64
+		capacity    float64
+		allocatable float64
+		want        float64
+	}{
+		"reservations raise the price per allocatable unit": {
+			price:       0.04,
+			capacity:    4,
+			allocatable: 3.92,
+			want:        0.04 * 4 / 3.92,
+		},
+		"nothing reserved": {
+			price:       0.04,
+			capacity:    4,
+			allocatable: 4,
+			want:        0.04,
+		},
+		"no allocatable resources keeps the price": {
+			price:    0.04,
+			capacity: 4,
+			want:     0.04,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, AllocatableCost(tt.price, tt.capacity, tt.allocatable), 1e-9)
+		})
+	}
+}
+
+func TestNodesByName(t *testing.T) {
+	assert.Nil(t, NodesByName(context.Background(), nil))
+	assert.Nil(t, NodesByName(context.Background(), &fakeNodeLister{err: assert.AnError}))
+	node := Node{Name: "node-1"}
+	assert.Equal(t, map[string]Node{"node-1": node}, NodesByName(context.Background(), &fakeNodeLister{nodes: []Node{node}}))
+}
+
+func TestAllocatableMetrics(t *testing.T) {
+	node := Node{
+		Name:        "node-1",
+		Capacity:    Resources{Cpus: 4, MemoryGiB: 16},
+		Allocatable: Resources{Cpus: 2, MemoryGiB: 12},
+	}
+	labels := utils.LabelMap{
+		"node":         "node-1",
+		"cluster_name": "prod",
+		"provider":     "aws",
+		"machine_type": "m5.xlarge",
+		"price_tier":   "ondemand",
+	}
+	metrics := AllocatableMetrics(node, 0.05, 0.006, "prod", "aws", "m5.xlarge", "ondemand")
+	assert.Equal(t, []*utils.MetricResult{
+		{
+			FqName:     "cloudcost_node_cpu_allocatable_usd_per_core_hour",
+			Labels:     labels,
+			Value:      0.1,
+			MetricType: prometheus.GaugeValue,
+		},
+		{
+			FqName:     "cloudcost_node_memory_allocatable_usd_per_gib_hour",
+			Labels:     labels,
+			Value:      0.008,
+			MetricType: prometheus.GaugeValue,
+		},
+	}, []*utils.MetricResult{utils.ReadMetrics(metrics[0]), utils.ReadMetrics(metrics[1])})
+}
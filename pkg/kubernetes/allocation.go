@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+var (
+	// NamespaceHourlyCostDesc is the cost of the resources requested by the running pods of a namespace, at the price
+	// per allocatable unit of the nodes they run on. The capacity pods don't request isn't attributed to any
+	// namespace.
+	NamespaceHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "namespace", "usd_per_hour"),
+		"The cost of the cpu and memory requested by the running pods of a Kubernetes namespace in USD/h, at the price per allocatable core and GiB of their nodes.",
+		[]string{"cluster_name", "provider", "namespace"},
+		nil,
+	)
+)
+
+// PodsByNode lists the running pods of the cluster keyed by the name of their node. It returns nil when lister is nil,
+// which is the case when namespace costs are disabled, or when the pods can't be listed.
+func PodsByNode(ctx context.Context, lister PodLister) map[string][]Pod {
+	if lister == nil {
+		return nil
+	}
+	pods, err := lister.ListPods(ctx)
+	if err != nil {
+		log.Printf("error listing kubernetes pods: %s", err)
+		return nil
+	}
+	byNode := make(map[string][]Pod)
+	for _, pod := range pods {
+		byNode[pod.NodeName] = append(byNode[pod.NodeName], pod)
+	}
+	return byNode
+}
+
+// Allocation allocates the cost of the nodes of the cluster the exporter runs in to the namespaces of the pods
+// scheduled on them, in proportion to their requests. It's safe for concurrent use.
+type Allocation struct {
+	pods map[string][]Pod
+
+	m          sync.Mutex
+	namespaces map[namespaceKey]float64
+}
+
+type namespaceKey struct {
+	cluster   string
+	namespace string
+}
+
+// NewAllocation returns an Allocation of the pods keyed by node, as returned by PodsByNode. A nil Allocation is
+// returned when pods is nil, which is the case when namespace costs are disabled or the pods couldn't be listed.
+func NewAllocation(pods map[string][]Pod) *Allocation {
+	if pods == nil {
+		return nil
+	}
+	return &Allocation{
+		pods:       pods,
+		namespaces: make(map[namespaceKey]float64),
+	}
+}
+
+// Observe allocates the cost of a node of clusterName given the cpu and memory prices of its instance, per core and
+// GiB of capacity, to the namespaces of its pods.
+func (a *Allocation) Observe(clusterName string, node Node, cpuPrice float64, ramPrice float64) {
+	if a == nil {
+		return
+	}
+	cpuCost := AllocatableCost(cpuPrice, node.Capacity.Cpus, node.Allocatable.Cpus)
+	ramCost := AllocatableCost(ramPrice, node.Capacity.MemoryGiB, node.Allocatable.MemoryGiB)
+	a.m.Lock()
+	defer a.m.Unlock()
+	for _, pod := range a.pods[node.Name] {
+		key := namespaceKey{cluster: clusterName, namespace: pod.Namespace}
+		a.namespaces[key] += pod.Requests.Cpus*cpuCost + pod.Requests.MemoryGiB*ramCost
+	}
+}
+
+// Metrics returns the NamespaceHourlyCostDesc metrics of the namespaces whose pods run on an observed node.
+func (a *Allocation) Metrics(provider string) []prometheus.Metric {
+	if a == nil {
+		return nil
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	keys := make([]namespaceKey, 0, len(a.namespaces))
+	for key := range a.namespaces {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].cluster != keys[j].cluster {
+			return keys[i].cluster < keys[j].cluster
+		}
+		return keys[i].namespace < keys[j].namespace
+	})
+	metrics := make([]prometheus.Metric, 0, len(keys))
+	for _, key := range keys {
+		metrics = append(metrics, prometheus.MustNewConstMetric(NamespaceHourlyCostDesc, prometheus.GaugeValue, a.namespaces[key], key.cluster, provider, key.namespace))
+	}
+	return metrics
+}
@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+type fakePodLister struct {
+	pods []Pod
+	err  error
+}
+
+func (f *fakePodLister) ListPods(_ context.Context) ([]Pod, error) {
+	return f.pods, f.err
+}
+
+func TestPodsByNode(t *testing.T) {
+	assert.Nil(t, PodsByNode(context.Background(), nil))
+	assert.Nil(t, PodsByNode(context.Background(), &fakePodLister{err: errors.New("forbidden")}))
+
+	pods := PodsByNode(context.Background(), &fakePodLister{pods: []Pod{
+		{Namespace: "checkout", NodeName: "node-1"},
+		{Namespace: "batch", NodeName: "node-1"},
+		{Namespace: "batch", NodeName: "node-2"},
+	}})
+	require.Len(t, pods, 2)
+	assert.Len(t, pods["node-1"], 2)
+	assert.Len(t, pods["node-2"], 1)
+}
+
+func TestAllocation(t *testing.T) {
+	node := func(name string) Node {
+		return Node{Name: name, Capacity: Resources{Cpus: 4, MemoryGiB: 16}, Allocatable: Resources{Cpus: 2, MemoryGiB: 8}}
+	}
+	pods := map[string][]Pod{
+		"node-1": {
+			{Namespace: "checkout", NodeName: "node-1", Requests: Resources{Cpus: 1, MemoryGiB: 2}},
+			{Namespace: "batch", NodeName: "node-1", Requests: Resources{Cpus: 0.5}},
+		},
+		"node-2": {
+			{Namespace: "checkout", NodeName: "node-2", Requests: Resources{MemoryGiB: 4}},
+		},
+	}
+
+	t.Run("namespace costs disabled", func(t *testing.T) {
+		allocation := NewAllocation(nil)
+		allocation.Observe("dev", node("node-1"), 0.04, 0.005)
+		assert.Empty(t, allocation.Metrics("aws"))
+	})
+
+	t.Run("requests are priced per allocatable unit of their node", func(t *testing.T) {
+		allocation := NewAllocation(pods)
+		// The price per allocatable unit is twice the price per unit of capacity
+		allocation.Observe("dev", node("node-1"), 0.04, 0.005)
+		allocation.Observe("dev", node("node-2"), 0.04, 0.005)
+		// Nodes without pods don't allocate anything
+		allocation.Observe("dev", node("node-3"), 0.04, 0.005)
+
+		var got []*utils.MetricResult
+		for _, m := range allocation.Metrics("aws") {
+			got = append(got, utils.ReadMetrics(m))
+		}
+		require.Len(t, got, 2)
+		assert.Equal(t, "cloudcost_namespace_usd_per_hour", got[0].FqName)
+		assert.Equal(t, utils.LabelMap{"cluster_name": "dev", "provider": "aws", "namespace": "batch"}, got[0].Labels)
+		assert.InDelta(t, 0.5*0.08, got[0].Value, 1e-9)
+		assert.Equal(t, prometheus.GaugeValue, got[0].MetricType)
+		assert.Equal(t, utils.LabelMap{"cluster_name": "dev", "provider": "aws", "namespace": "checkout"}, got[1].Labels)
+		assert.InDelta(t, 1*0.08+2*0.01+4*0.01, got[1].Value, 1e-9)
+	})
+}
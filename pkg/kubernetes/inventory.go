@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+// registrationGracePeriod is how long an instance can take to register as a node before it's considered orphaned.
+const registrationGracePeriod = 15 * time.Minute
+
+var (
+	// OrphanedInstancesDesc counts the instances attributed to the cluster the exporter runs in by their cluster tag or
+	// label that aren't registered as nodes. They are usually stuck instances that are still billed.
+	OrphanedInstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "cluster", "orphaned_instances"),
+		"The number of running instances attributed to the cluster that aren't registered as Kubernetes nodes.",
+		[]string{"cluster_name", "provider"},
+		nil,
+	)
+)
+
+// Inventory compares the instances the cloud provider attributes to clusters with the nodes of the cluster the
+// exporter runs in. Only nodes of that cluster are known, so the cluster is identified as the one with at least one
+// instance registered as a node. It's safe for concurrent use.
+type Inventory struct {
+	nodes map[string]Node
+	now   func() time.Time
+
+	m        sync.Mutex
+	clusters map[string]*clusterInventory
+}
+
+type clusterInventory struct {
+	registered int
+	orphaned   int
+}
+
+// NewInventory returns an Inventory of nodes keyed by name, as returned by NodesByName. A nil Inventory is returned
+// when nodes is nil, which is the case when the Kubernetes integration is disabled or the nodes couldn't be listed.
+func NewInventory(nodes map[string]Node) *Inventory {
+	if nodes == nil {
+		return nil
+	}
+	return &Inventory{
+		nodes:    nodes,
+		now:      time.Now,
+		clusters: make(map[string]*clusterInventory),
+	}
+}
+
+// Node returns the node registered under name.
+func (i *Inventory) Node(name string) (Node, bool) {
+	if i == nil {
+		return Node{}, false
+	}
+	node, ok := i.nodes[name]
+	return node, ok
+}
+
+// Observe records a running instance of clusterName. nodeName is the name the instance would be registered under, eg
+// its private DNS name on EKS. Instances launched within registrationGracePeriod are not counted as orphaned,
+// launchedAt can be zero when unknown.
+func (i *Inventory) Observe(clusterName string, nodeName string, launchedAt time.Time) {
+	if i == nil {
+		return
+	}
+	_, registered := i.nodes[nodeName]
+	i.m.Lock()
+	defer i.m.Unlock()
+	cluster, ok := i.clusters[clusterName]
+	if !ok {
+		cluster = &clusterInventory{}
+		i.clusters[clusterName] = cluster
+	}
+	switch {
+	case registered:
+		cluster.registered++
+	case launchedAt.IsZero() || i.now().Sub(launchedAt) > registrationGracePeriod:
+		cluster.orphaned++
+	}
+}
+
+// Metrics returns the OrphanedInstancesDesc metric of the cluster the exporter runs in. Clusters without any
+// registered instance are other clusters, whose nodes aren't known.
+func (i *Inventory) Metrics(provider string) []prometheus.Metric {
+	if i == nil {
+		return nil
+	}
+	i.m.Lock()
+	defer i.m.Unlock()
+	names := make([]string, 0, len(i.clusters))
+	for name, cluster := range i.clusters {
+		if cluster.registered > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	metrics := make([]prometheus.Metric, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, prometheus.MustNewConstMetric(OrphanedInstancesDesc, prometheus.GaugeValue, float64(i.clusters[name].orphaned), name, provider))
+	}
+	return metrics
+}
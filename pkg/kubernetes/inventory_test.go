@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+type observation struct {
+	cluster    string
+	node       string
+	launchedAt time.Time
+}
+
+func TestInventory(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	nodes := map[string]Node{
+		"node-1": {Name: "node-1"},
+		"node-2": {Name: "node-2"},
+	}
+	tests := map[string]struct {
+		nodes        map[string]Node
+		observations []observation
+		want         []*utils.MetricResult
+	}{
+		"kubernetes integration disabled": {
+			observations: []observation{{cluster: "dev", node: "node-1"}},
+		},
+		"every instance is registered": {
+			nodes: nodes,
+			observations: []observation{
+				{cluster: "dev", node: "node-1"},
+				{cluster: "dev", node: "node-2"},
+			},
+			want: []*utils.MetricResult{
+				{FqName: "cloudcost_cluster_orphaned_instances", Labels: utils.LabelMap{"cluster_name": "dev", "provider": "aws"}, Value: 0, MetricType: prometheus.GaugeValue},
+			},
+		},
+		"orphaned instances of the cluster": {
+			nodes: nodes,
+			observations: []observation{
+				{cluster: "dev", node: "node-1"},
+				{cluster: "dev", node: "stuck-1", launchedAt: now.Add(-24 * time.Hour)},
+				{cluster: "dev", node: "stuck-2"},
+			},
+			want: []*utils.MetricResult{
+				{FqName: "cloudcost_cluster_orphaned_instances", Labels: utils.LabelMap{"cluster_name": "dev", "provider": "aws"}, Value: 2, MetricType: prometheus.GaugeValue},
+			},
+		},
+		"instances still registering aren't orphaned": {
+			nodes: nodes,
+			observations: []observation{
+				{cluster: "dev", node: "node-1"},
+				{cluster: "dev", node: "booting", launchedAt: now.Add(-time.Minute)},
+			},
+			want: []*utils.MetricResult{
+				{FqName: "cloudcost_cluster_orphaned_instances", Labels: utils.LabelMap{"cluster_name": "dev", "provider": "aws"}, Value: 0, MetricType: prometheus.GaugeValue},
+			},
+		},
+		"other clusters are ignored": {
+			nodes: nodes,
+			observations: []observation{
+				{cluster: "dev", node: "node-1"},
+				{cluster: "prod", node: "prod-node-1"},
+			},
+			want: []*utils.MetricResult{
+				{FqName: "cloudcost_cluster_orphaned_instances", Labels: utils.LabelMap{"cluster_name": "dev", "provider": "aws"}, Value: 0, MetricType: prometheus.GaugeValue},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			inventory := NewInventory(tt.nodes)
+			if inventory != nil {
+				inventory.now = func() time.Time { return now }
+			}
+			for _, o := range tt.observations {
+				inventory.Observe(o.cluster, o.node, o.launchedAt)
+				node, ok := inventory.Node(o.node)
+				assert.Equal(t, tt.nodes[o.node], node)
+				_, registered := tt.nodes[o.node]
+				assert.Equal(t, registered, ok)
+			}
+			var got []*utils.MetricResult
+			for _, m := range inventory.Metrics("aws") {
+				got = append(got, utils.ReadMetrics(m))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
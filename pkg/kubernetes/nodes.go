@@ -0,0 +1,185 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// listLimit is the number of nodes requested per page.
+	listLimit = "500"
+	// requestTimeout bounds a single request to the API server.
+	requestTimeout = 30 * time.Second
+
+	resourceCPU    = "cpu"
+	resourceMemory = "memory"
+	bytesPerGiB    = 1 << 30
+)
+
+var (
+	ErrNotInCluster = errors.New("unable to load in-cluster configuration, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be defined")
+)
+
+// Resources is the CPU and memory of a node.
+type Resources struct {
+	Cpus      float64
+	MemoryGiB float64
+}
+
+// Node is a Kubernetes node. Capacity is what the machine has and Allocatable what is left for pods once the
+// system and kube reservations and the eviction threshold are subtracted.
+type Node struct {
+	Name        string
+	Capacity    Resources
+	Allocatable Resources
+}
+
+// NodeLister lists the nodes of the cluster the exporter runs in.
+type NodeLister interface {
+	ListNodes(ctx context.Context) ([]Node, error)
+}
+
+// Client is a minimal client of the Kubernetes API that authenticates with the service account of the pod.
+type Client struct {
+	httpClient *http.Client
+	host       string
+	tokenFile  string
+}
+
+// NewInClusterClient returns a Client for the API server of the cluster the exporter runs in. The service account
+// needs the permission to list nodes, and pods when the namespace costs are enabled.
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in service account ca")
+	}
+	httpClient := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	return newClient(httpClient, "https://"+net.JoinHostPort(host, port), filepath.Join(serviceAccountDir, "token")), nil
+}
+
+func newClient(httpClient *http.Client, host string, tokenFile string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		host:       host,
+		tokenFile:  tokenFile,
+	}
+}
+
+type nodeList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Capacity    map[string]string `json:"capacity"`
+			Allocatable map[string]string `json:"allocatable"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListNodes returns every node of the cluster. Nodes whose resources can't be parsed are skipped.
+func (c *Client) ListNodes(ctx context.Context) ([]Node, error) {
+	var nodes []Node
+	continueToken := ""
+	for {
+		list, err := c.listNodesPage(ctx, continueToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			capacity, err := parseResources(item.Status.Capacity)
+			if err != nil {
+				continue
+			}
+			allocatable, err := parseResources(item.Status.Allocatable)
+			if err != nil {
+				continue
+			}
+			nodes = append(nodes, Node{Name: item.Metadata.Name, Capacity: capacity, Allocatable: allocatable})
+		}
+		if list.Metadata.Continue == "" {
+			break
+		}
+		continueToken = list.Metadata.Continue
+	}
+	return nodes, nil
+}
+
+func (c *Client) listNodesPage(ctx context.Context, continueToken string) (*nodeList, error) {
+	var list nodeList
+	if err := c.listPage(ctx, "/api/v1/nodes", url.Values{}, continueToken, &list); err != nil {
+		return nil, fmt.Errorf("error listing nodes: %w", err)
+	}
+	return &list, nil
+}
+
+// listPage decodes a page of listLimit objects of path into list, query narrowing down the objects listed.
+func (c *Client) listPage(ctx context.Context, path string, query url.Values, continueToken string, list any) error {
+	query.Set("limit", listLimit)
+	if continueToken != "" {
+		query.Set("continue", continueToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// The token is read on every request as projected service account tokens are rotated by the kubelet
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return fmt.Errorf("error reading service account token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(list); err != nil {
+		return fmt.Errorf("error decoding: %w", err)
+	}
+	return nil
+}
+
+func parseResources(resources map[string]string) (Resources, error) {
+	cpus, err := ParseQuantity(resources[resourceCPU])
+	if err != nil {
+		return Resources{}, fmt.Errorf("error parsing cpu: %w", err)
+	}
+	memory, err := ParseQuantity(resources[resourceMemory])
+	if err != nil {
+		return Resources{}, fmt.Errorf("error parsing memory: %w", err)
+	}
+	return Resources{Cpus: cpus, MemoryGiB: memory / bytesPerGiB}, nil
+}
@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodesPage1 = `{
+  "metadata": {"continue": "next"},
+  "items": [
+    {
+      "metadata": {"name": "ip-10-0-0-1.ec2.internal"},
+      "status": {
+        "capacity": {"cpu": "4", "memory": "16Gi"},
+        "allocatable": {"cpu": "3920m", "memory": "14Gi"}
+      }
+    },
+    {
+      "metadata": {"name": "unparsable"},
+      "status": {
+        "capacity": {"cpu": "4"},
+        "allocatable": {"cpu": "3920m"}
+      }
+    }
+  ]
+}`
+
+const nodesPage2 = `{
+  "metadata": {},
+  "items": [
+    {
+      "metadata": {"name": "gke-node"},
+      "status": {
+        "capacity": {"cpu": "2", "memory": "8Gi"},
+        "allocatable": {"cpu": "1930m", "memory": "6Gi"}
+      }
+    }
+  ]
+}`
+
+func TestClient_ListNodes(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+	tests := map[string]struct {
+		statusCode int
+		want       []Node
+		wantErr    bool
+	}{
+		"pages are followed and unparsable nodes are skipped": {
+			statusCode: http.StatusOK,
+			want: []Node{
+				{
+					Name:        "ip-10-0-0-1.ec2.internal",
+					Capacity:    Resources{Cpus: 4, MemoryGiB: 16},
+					Allocatable: Resources{Cpus: 3.92, MemoryGiB: 14},
+				},
+				{
+					Name:        "gke-node",
+					Capacity:    Resources{Cpus: 2, MemoryGiB: 8},
+					Allocatable: Resources{Cpus: 1.93, MemoryGiB: 6},
+				},
+			},
+		},
+		"errors propagate": {
+			statusCode: http.StatusForbidden,
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				assert.Equal(t, "/api/v1/nodes", r.URL.Path)
+				if tt.statusCode != http.StatusOK {
+					w.WriteHeader(tt.statusCode)
+					return
+				}
+				if r.URL.Query().Get("continue") == "next" {
+					_, _ = w.Write([]byte(nodesPage2))
+					return
+				}
+				_, _ = w.Write([]byte(nodesPage1))
+			}))
+			defer testServer.Close()
+
+			client := newClient(testServer.Client(), testServer.URL, tokenFile)
+			got, err := client.ListNodes(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				assert.Equal(t, tt.want[i].Name, got[i].Name)
+				assert.InDelta(t, tt.want[i].Capacity.Cpus, got[i].Capacity.Cpus, 1e-9)
+				assert.InDelta(t, tt.want[i].Capacity.MemoryGiB, got[i].Capacity.MemoryGiB, 1e-9)
+				assert.InDelta(t, tt.want[i].Allocatable.Cpus, got[i].Allocatable.Cpus, 1e-9)
+				assert.InDelta(t, tt.want[i].Allocatable.MemoryGiB, got[i].Allocatable.MemoryGiB, 1e-9)
+			}
+		})
+	}
+}
+
+func TestNewInClusterClient_NotInCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err := NewInClusterClient()
+	require.ErrorIs(t, err, ErrNotInCluster)
+}
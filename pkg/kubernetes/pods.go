@@ -0,0 +1,106 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+)
+
+// Pod is a running pod scheduled on a node. Requests are the resources the scheduler reserves for the pod on its
+// node, see effectiveRequests.
+type Pod struct {
+	Namespace string
+	NodeName  string
+	Requests  Resources
+}
+
+// PodLister lists the running pods of the cluster the exporter runs in.
+type PodLister interface {
+	ListPods(ctx context.Context) ([]Pod, error)
+}
+
+type podList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName       string            `json:"nodeName"`
+			Containers     []container       `json:"containers"`
+			InitContainers []container       `json:"initContainers"`
+			Overhead       map[string]string `json:"overhead"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type container struct {
+	Resources struct {
+		Requests map[string]string `json:"requests"`
+	} `json:"resources"`
+}
+
+// ListPods returns every running pod of the cluster. Requests that can't be parsed are counted as zero.
+func (c *Client) ListPods(ctx context.Context) ([]Pod, error) {
+	var pods []Pod
+	continueToken := ""
+	for {
+		var list podList
+		query := url.Values{"fieldSelector": {"status.phase=Running"}}
+		if err := c.listPage(ctx, "/api/v1/pods", query, continueToken, &list); err != nil {
+			return nil, fmt.Errorf("error listing pods: %w", err)
+		}
+		for _, item := range list.Items {
+			if item.Spec.NodeName == "" {
+				continue
+			}
+			pods = append(pods, Pod{
+				Namespace: item.Metadata.Namespace,
+				NodeName:  item.Spec.NodeName,
+				Requests:  effectiveRequests(item.Spec.Containers, item.Spec.InitContainers, item.Spec.Overhead),
+			})
+		}
+		if list.Metadata.Continue == "" {
+			break
+		}
+		continueToken = list.Metadata.Continue
+	}
+	return pods, nil
+}
+
+// effectiveRequests returns the resources the scheduler reserves for a pod: the larger of the sum of the requests of
+// its containers and of the largest request of its init containers, which run one after the other, plus the
+// overhead of its runtime class.
+func effectiveRequests(containers []container, initContainers []container, overhead map[string]string) Resources {
+	var requests, initRequests Resources
+	for _, c := range containers {
+		r := parseRequests(c.Resources.Requests)
+		requests.Cpus += r.Cpus
+		requests.MemoryGiB += r.MemoryGiB
+	}
+	for _, c := range initContainers {
+		r := parseRequests(c.Resources.Requests)
+		initRequests.Cpus = math.Max(initRequests.Cpus, r.Cpus)
+		initRequests.MemoryGiB = math.Max(initRequests.MemoryGiB, r.MemoryGiB)
+	}
+	o := parseRequests(overhead)
+	return Resources{
+		Cpus:      math.Max(requests.Cpus, initRequests.Cpus) + o.Cpus,
+		MemoryGiB: math.Max(requests.MemoryGiB, initRequests.MemoryGiB) + o.MemoryGiB,
+	}
+}
+
+// parseRequests parses the cpu and memory of a resource list, a missing or unparsable resource being zero.
+func parseRequests(resources map[string]string) Resources {
+	var r Resources
+	if cpus, err := ParseQuantity(resources[resourceCPU]); err == nil {
+		r.Cpus = cpus
+	}
+	if memory, err := ParseQuantity(resources[resourceMemory]); err == nil {
+		r.MemoryGiB = memory / bytesPerGiB
+	}
+	return r
+}
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const podsPage1 = `{
+  "metadata": {"continue": "next"},
+  "items": [
+    {
+      "metadata": {"namespace": "checkout"},
+      "spec": {
+        "nodeName": "ip-10-0-0-1.ec2.internal",
+        "containers": [
+          {"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}},
+          {"resources": {"requests": {"cpu": "250m", "memory": "512Mi"}}}
+        ],
+        "initContainers": [
+          {"resources": {"requests": {"cpu": "2", "memory": "256Mi"}}}
+        ],
+        "overhead": {"cpu": "100m", "memory": "128Mi"}
+      }
+    },
+    {
+      "metadata": {"namespace": "batch"},
+      "spec": {
+        "containers": [{"resources": {"requests": {"cpu": "1"}}}]
+      }
+    }
+  ]
+}`
+
+const podsPage2 = `{
+  "metadata": {},
+  "items": [
+    {
+      "metadata": {"namespace": "kube-system"},
+      "spec": {
+        "nodeName": "gke-node",
+        "containers": [{"resources": {}}]
+      }
+    }
+  ]
+}`
+
+func TestClient_ListPods(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+	tests := map[string]struct {
+		statusCode int
+		want       []Pod
+		wantErr    bool
+	}{
+		"pages are followed and unscheduled pods are skipped": {
+			statusCode: http.StatusOK,
+			want: []Pod{
+				// The init container requests more cpu than the containers, the containers more memory
+				{Namespace: "checkout", NodeName: "ip-10-0-0-1.ec2.internal", Requests: Resources{Cpus: 2.1, MemoryGiB: 1.625}},
+				{Namespace: "kube-system", NodeName: "gke-node"},
+			},
+		},
+		"errors propagate": {
+			statusCode: http.StatusForbidden,
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				assert.Equal(t, "/api/v1/pods", r.URL.Path)
+				assert.Equal(t, "status.phase=Running", r.URL.Query().Get("fieldSelector"))
+				if tt.statusCode != http.StatusOK {
+					w.WriteHeader(tt.statusCode)
+					return
+				}
+				if r.URL.Query().Get("continue") == "next" {
+					_, _ = w.Write([]byte(podsPage2))
+					return
+				}
+				_, _ = w.Write([]byte(podsPage1))
+			}))
+			defer testServer.Close()
+
+			client := newClient(testServer.Client(), testServer.URL, tokenFile)
+			got, err := client.ListPods(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				assert.Equal(t, tt.want[i].Namespace, got[i].Namespace)
+				assert.Equal(t, tt.want[i].NodeName, got[i].NodeName)
+				assert.InDelta(t, tt.want[i].Requests.Cpus, got[i].Requests.Cpus, 1e-9)
+				assert.InDelta(t, tt.want[i].Requests.MemoryGiB, got[i].Requests.MemoryGiB, 1e-9)
+			}
+		})
+	}
+}
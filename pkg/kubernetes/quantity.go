@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// binarySuffixes and decimalSuffixes are the suffixes of Kubernetes resource quantities, eg 16Gi or 3920m.
+	binarySuffixes = map[string]float64{
+		"Ki": 1 << 10,
+		"Mi": 1 << 20,
+		"Gi": 1 << 30,
+		"Ti": 1 << 40,
+		"Pi": 1 << 50,
+		"Ei": 1 << 60,
+	}
+	decimalSuffixes = map[string]float64{
+		"m": 1e-3,
+		"k": 1e3,
+		"M": 1e6,
+		"G": 1e9,
+		"T": 1e12,
+		"P": 1e15,
+		"E": 1e18,
+	}
+)
+
+// ParseQuantity parses a Kubernetes resource quantity, eg "3920m" cores or "16369748Ki" of memory, into a float in
+// the base unit of the resource.
+func ParseQuantity(quantity string) (float64, error) {
+	quantity = strings.TrimSpace(quantity)
+	if quantity == "" {
+		return 0, fmt.Errorf("empty quantity")
+	}
+	if len(quantity) > 2 {
+		if multiplier, ok := binarySuffixes[quantity[len(quantity)-2:]]; ok {
+			return parseNumber(quantity[:len(quantity)-2], multiplier)
+		}
+	}
+	// Plain numbers and decimal exponents, eg 1e3 or 1E3, have to be tried before the E suffix
+	if value, err := strconv.ParseFloat(quantity, 64); err == nil {
+		return value, nil
+	}
+	if multiplier, ok := decimalSuffixes[quantity[len(quantity)-1:]]; ok {
+		return parseNumber(quantity[:len(quantity)-1], multiplier)
+	}
+	return 0, fmt.Errorf("invalid quantity %q", quantity)
+}
+
+func parseNumber(number string, multiplier float64) (float64, error) {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", number, err)
+	}
+	return value * multiplier, nil
+}
@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]struct {
+		quantity string
+		want     float64
+		wantErr  bool
+	}{
+		"cores":             {quantity: "4", want: 4},
+		"millicores":        {quantity: "3920m", want: 3.92},
+		"kibibytes":         {quantity: "16369748Ki", want: 16369748 * 1024},
+		"gibibytes":         {quantity: "16Gi", want: 16 * (1 << 30)},
+		"decimal suffix":    {quantity: "2G", want: 2e9},
+		"decimal exponent":  {quantity: "1E3", want: 1000},
+		"exa suffix":        {quantity: "1E", want: 1e18},
+		"fractional":        {quantity: "1.5Gi", want: 1.5 * (1 << 30)},
+		"empty":             {quantity: "", wantErr: true},
+		"unknown suffix":    {quantity: "4x", wantErr: true},
+		"suffix only":       {quantity: "Gi", wantErr: true},
+		"invalid with unit": {quantity: "aGi", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseQuantity(tt.quantity)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
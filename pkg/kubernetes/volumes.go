@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PersistentVolume is a persistent volume and the storage class that provisioned it.
+type PersistentVolume struct {
+	Name         string
+	StorageClass string
+}
+
+// PersistentVolumeLister lists the persistent volumes of the cluster the exporter runs in.
+type PersistentVolumeLister interface {
+	ListPersistentVolumes(ctx context.Context) ([]PersistentVolume, error)
+}
+
+type persistentVolumeList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			StorageClassName string `json:"storageClassName"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// ListPersistentVolumes returns every persistent volume of the cluster. Volumes without a storage class, eg
+// provisioned by hand, are skipped.
+func (c *Client) ListPersistentVolumes(ctx context.Context) ([]PersistentVolume, error) {
+	var volumes []PersistentVolume
+	continueToken := ""
+	for {
+		var list persistentVolumeList
+		if err := c.listPage(ctx, "/api/v1/persistentvolumes", url.Values{}, continueToken, &list); err != nil {
+			return nil, fmt.Errorf("error listing persistent volumes: %w", err)
+		}
+		for _, item := range list.Items {
+			if item.Spec.StorageClassName == "" {
+				continue
+			}
+			volumes = append(volumes, PersistentVolume{Name: item.Metadata.Name, StorageClass: item.Spec.StorageClassName})
+		}
+		if list.Metadata.Continue == "" {
+			break
+		}
+		continueToken = list.Metadata.Continue
+	}
+	return volumes, nil
+}
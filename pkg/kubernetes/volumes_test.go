@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const volumesPage1 = `{
+  "metadata": {"continue": "next"},
+  "items": [
+    {"metadata": {"name": "pvc-1"}, "spec": {"storageClassName": "fast-ssd"}},
+    {"metadata": {"name": "static"}, "spec": {}}
+  ]
+}`
+
+const volumesPage2 = `{
+  "metadata": {},
+  "items": [
+    {"metadata": {"name": "pvc-2"}, "spec": {"storageClassName": "standard"}}
+  ]
+}`
+
+func TestClient_ListPersistentVolumes(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+	tests := map[string]struct {
+		statusCode int
+		want       []PersistentVolume
+		wantErr    bool
+	}{
+		"pages are followed and volumes without a class are skipped": {
+			statusCode: http.StatusOK,
+			want: []PersistentVolume{
+				{Name: "pvc-1", StorageClass: "fast-ssd"},
+				{Name: "pvc-2", StorageClass: "standard"},
+			},
+		},
+		"errors propagate": {
+			statusCode: http.StatusForbidden,
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				assert.Equal(t, "/api/v1/persistentvolumes", r.URL.Path)
+				if tt.statusCode != http.StatusOK {
+					w.WriteHeader(tt.statusCode)
+					return
+				}
+				if r.URL.Query().Get("continue") == "next" {
+					_, _ = w.Write([]byte(volumesPage2))
+					return
+				}
+				_, _ = w.Write([]byte(volumesPage1))
+			}))
+			defer testServer.Close()
+
+			client := newClient(testServer.Client(), testServer.URL, tokenFile)
+			got, err := client.ListPersistentVolumes(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package metricsdoc documents the metrics exposed by the exporter from the descriptors of the registered collectors,
+// so that the documentation can't drift from what is actually exported.
+package metricsdoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+// reDesc matches the output of prometheus.Desc.String, which is the only way to get at the fields of a descriptor.
+var reDesc = regexp.MustCompile(`^Desc\{fqName: (".*"), help: (".*"), constLabels: \{(.*)\}, variableLabels: \{(.*)\}\}$`)
+
+// Metric documents a single metric.
+type Metric struct {
+	Provider string   `json:"provider"`
+	Name     string   `json:"name"`
+	Help     string   `json:"help"`
+	Labels   []string `json:"labels"`
+}
+
+// Describe registers a provider and its collectors on a new registry the same way the exporter does and returns the
+// metrics described by the registry, sorted by name.
+func Describe(providerName string, csp provider.Provider) ([]Metric, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(csp); err != nil {
+		return nil, fmt.Errorf("error registering provider %s: %w", providerName, err)
+	}
+	if err := csp.RegisterCollectors(registry); err != nil {
+		return nil, fmt.Errorf("error registering collectors of provider %s: %w", providerName, err)
+	}
+
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		registry.Describe(ch)
+		close(ch)
+	}()
+
+	metricsByName := make(map[string]Metric)
+	var err error
+	for desc := range ch {
+		metric, parseErr := parseDesc(desc)
+		if parseErr != nil {
+			// Keep draining the channel so that the describing goroutine can finish
+			err = parseErr
+			continue
+		}
+		metric.Provider = providerName
+		metricsByName[metric.Name] = metric
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]Metric, 0, len(metricsByName))
+	for _, metric := range metricsByName {
+		metrics = append(metrics, metric)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics, nil
+}
+
+func parseDesc(desc *prometheus.Desc) (Metric, error) {
+	matches := reDesc.FindStringSubmatch(desc.String())
+	if matches == nil {
+		return Metric{}, fmt.Errorf("unexpected descriptor format: %s", desc)
+	}
+	name, err := strconv.Unquote(matches[1])
+	if err != nil {
+		return Metric{}, fmt.Errorf("error parsing name of %s: %w", desc, err)
+	}
+	help, err := strconv.Unquote(matches[2])
+	if err != nil {
+		return Metric{}, fmt.Errorf("error parsing help of %s: %w", desc, err)
+	}
+	labels := []string{}
+	if matches[4] != "" {
+		for _, label := range strings.Split(matches[4], ",") {
+			// Constrained labels are printed as c(label)
+			label = strings.TrimSuffix(strings.TrimPrefix(label, "c("), ")")
+			labels = append(labels, label)
+		}
+	}
+	return Metric{Name: name, Help: help, Labels: labels}, nil
+}
+
+// WriteJSON writes the metrics as an indented JSON array.
+func WriteJSON(w io.Writer, metrics []Metric) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(metrics)
+}
+
+// WriteMarkdown writes the metrics as one table per provider, in the order the providers first appear in.
+func WriteMarkdown(w io.Writer, metrics []Metric) error {
+	var providers []string
+	metricsByProvider := make(map[string][]Metric)
+	for _, metric := range metrics {
+		if _, ok := metricsByProvider[metric.Provider]; !ok {
+			providers = append(providers, metric.Provider)
+		}
+		metricsByProvider[metric.Provider] = append(metricsByProvider[metric.Provider], metric)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Metrics\n")
+	for _, p := range providers {
+		fmt.Fprintf(&sb, "\n## %s\n\n", p)
+		sb.WriteString("| Metric name | Description | Labels |\n")
+		sb.WriteString("|-------------|-------------|--------|\n")
+		for _, metric := range metricsByProvider[p] {
+			labels := make([]string, 0, len(metric.Labels))
+			for _, label := range metric.Labels {
+				labels = append(labels, "`"+label+"`")
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", metric.Name, escapeMarkdown(metric.Help), strings.Join(labels, " <br/> "))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,79 @@
+package metricsdoc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/provider"
+)
+
+var (
+	testCostDesc = prometheus.NewDesc("cloudcost_test_cost_usd_per_hour", "Cost | of a test resource.", []string{"region", "instance"}, nil)
+	testUpDesc   = prometheus.NewDesc("cloudcost_exporter_test_up", "Was the last scrape successful.", nil, nil)
+	testCounter  = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cloudcost_exporter_test_requests_total", Help: "Total number of requests."}, []string{"status"})
+)
+
+type testProvider struct{}
+
+func (testProvider) Describe(ch chan<- *prometheus.Desc) {
+	ch <- testUpDesc
+	ch <- testCostDesc
+	// Shared descriptors can be described by several collectors
+	ch <- testCostDesc
+}
+
+func (testProvider) Collect(_ chan<- prometheus.Metric) {}
+
+func (testProvider) RegisterCollectors(registry provider.Registry) error {
+	return registry.Register(testCounter)
+}
+
+func (testProvider) Close(context.Context) error {
+	return nil
+}
+
+func TestDescribe(t *testing.T) {
+	metrics, err := Describe("test", testProvider{})
+	require.NoError(t, err)
+	assert.Equal(t, []Metric{
+		{Provider: "test", Name: "cloudcost_exporter_test_requests_total", Help: "Total number of requests.", Labels: []string{"status"}},
+		{Provider: "test", Name: "cloudcost_exporter_test_up", Help: "Was the last scrape successful.", Labels: []string{}},
+		{Provider: "test", Name: "cloudcost_test_cost_usd_per_hour", Help: "Cost | of a test resource.", Labels: []string{"region", "instance"}},
+	}, metrics)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	metrics := []Metric{
+		{Provider: "aws", Name: "cloudcost_aws_cost", Help: "Cost | of AWS.", Labels: []string{"region", "instance"}},
+		{Provider: "gcp", Name: "cloudcost_gcp_cost", Help: "Cost of GCP.", Labels: []string{}},
+		{Provider: "aws", Name: "cloudcost_aws_up", Help: "Up.", Labels: []string{}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarkdown(&buf, metrics))
+	assert.Equal(t, "# Metrics\n"+
+		"\n## aws\n\n"+
+		"| Metric name | Description | Labels |\n"+
+		"|-------------|-------------|--------|\n"+
+		"| cloudcost_aws_cost | Cost \\| of AWS. | `region` <br/> `instance` |\n"+
+		"| cloudcost_aws_up | Up. |  |\n"+
+		"\n## gcp\n\n"+
+		"| Metric name | Description | Labels |\n"+
+		"|-------------|-------------|--------|\n"+
+		"| cloudcost_gcp_cost | Cost of GCP. |  |\n", buf.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	metrics := []Metric{{Provider: "aws", Name: "cloudcost_aws_cost", Help: "Cost of AWS.", Labels: []string{"region"}}}
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, metrics))
+
+	var got []Metric
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, metrics, got)
+}
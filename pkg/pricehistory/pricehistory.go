@@ -0,0 +1,212 @@
+// Package pricehistory keeps the prices of the last pricing map snapshots of the collectors in memory, so that price
+// changes of the catalogs, eg when the price of a machine type changed, can be looked up without storing the catalogs
+// in an external TSDB.
+package pricehistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Price is a single price of a pricing map snapshot.
+type Price struct {
+	Region string `json:"region"`
+	// SKU is what is priced, eg a machine type or a machine family.
+	SKU       string `json:"sku"`
+	PriceTier string `json:"price_tier"`
+	// Unit is the unit of the price, eg usd_per_hour or usd_per_core_hour.
+	Unit  string  `json:"unit"`
+	Value float64 `json:"-"`
+}
+
+type seriesKey struct {
+	Region    string
+	SKU       string
+	PriceTier string
+	Unit      string
+}
+
+// Point is the price of a series from a snapshot on. Only the snapshots where the price changed have a point.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+type series struct {
+	points   []Point
+	lastSeen time.Time
+}
+
+// source is the history of the snapshots of a single collector.
+type source struct {
+	snapshots []time.Time
+	series    map[seriesKey]*series
+}
+
+// History keeps the prices of the last snapshots of every collector. Consecutive snapshots with the same price of a
+// series only keep a point for the first one, so memory grows with the number of price changes rather than with the
+// number of snapshots. A nil History records nothing, which is the case when the price history is disabled.
+type History struct {
+	size int
+	now  func() time.Time
+
+	m       sync.Mutex
+	sources map[string]*source
+}
+
+// New returns a History keeping the last size snapshots of every collector, or nil when size isn't positive.
+func New(size int) *History {
+	if size <= 0 {
+		return nil
+	}
+	return &History{
+		size:    size,
+		now:     time.Now,
+		sources: make(map[string]*source),
+	}
+}
+
+// Record adds a snapshot of the pricing map of a collector. The oldest snapshot is dropped once more than the
+// configured number of snapshots are kept, along with the points and series it's the last one to cover.
+func (h *History) Record(collector string, prices []Price) {
+	if h == nil {
+		return
+	}
+	h.m.Lock()
+	defer h.m.Unlock()
+	now := h.now()
+	src, ok := h.sources[collector]
+	if !ok {
+		src = &source{series: make(map[seriesKey]*series)}
+		h.sources[collector] = src
+	}
+	src.snapshots = append(src.snapshots, now)
+	if len(src.snapshots) > h.size {
+		src.snapshots = src.snapshots[len(src.snapshots)-h.size:]
+	}
+	for _, price := range prices {
+		key := seriesKey{Region: price.Region, SKU: price.SKU, PriceTier: price.PriceTier, Unit: price.Unit}
+		s, ok := src.series[key]
+		if !ok {
+			s = &series{}
+			src.series[key] = s
+		}
+		if len(s.points) == 0 || s.points[len(s.points)-1].Value != price.Value {
+			s.points = append(s.points, Point{Time: now, Value: price.Value})
+		}
+		s.lastSeen = now
+	}
+
+	oldest := src.snapshots[0]
+	for key, s := range src.series {
+		if s.lastSeen.Before(oldest) {
+			delete(src.series, key)
+			continue
+		}
+		// The last point before the oldest snapshot is kept as it's the price at the time of the oldest snapshot
+		drop := 0
+		for drop+1 < len(s.points) && !s.points[drop+1].Time.After(oldest) {
+			drop++
+		}
+		s.points = s.points[drop:]
+	}
+}
+
+// Series is the price history of a single price of a collector.
+type Series struct {
+	Collector string `json:"collector"`
+	Price
+	Points []Point `json:"points"`
+}
+
+// Query is a filter of the series, empty fields match any value.
+type Query struct {
+	Collector string
+	Region    string
+	SKU       string
+	PriceTier string
+}
+
+func (q Query) matches(collector string, key seriesKey) bool {
+	return (q.Collector == "" || q.Collector == collector) &&
+		(q.Region == "" || q.Region == key.Region) &&
+		(q.SKU == "" || q.SKU == key.SKU) &&
+		(q.PriceTier == "" || q.PriceTier == key.PriceTier)
+}
+
+// Series returns the series matching the query sorted by collector, region, sku, price tier and unit.
+func (h *History) Series(q Query) []Series {
+	if h == nil {
+		return nil
+	}
+	h.m.Lock()
+	defer h.m.Unlock()
+	var result []Series
+	for collector, src := range h.sources {
+		for key, s := range src.series {
+			if !q.matches(collector, key) {
+				continue
+			}
+			result = append(result, Series{
+				Collector: collector,
+				Price:     Price{Region: key.Region, SKU: key.SKU, PriceTier: key.PriceTier, Unit: key.Unit},
+				Points:    append([]Point(nil), s.points...),
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Collector != b.Collector {
+			return a.Collector < b.Collector
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.SKU != b.SKU {
+			return a.SKU < b.SKU
+		}
+		if a.PriceTier != b.PriceTier {
+			return a.PriceTier < b.PriceTier
+		}
+		return a.Unit < b.Unit
+	})
+	return result
+}
+
+// Snapshots returns the times of the snapshots kept for every collector.
+func (h *History) Snapshots() map[string][]time.Time {
+	if h == nil {
+		return nil
+	}
+	h.m.Lock()
+	defer h.m.Unlock()
+	snapshots := make(map[string][]time.Time, len(h.sources))
+	for collector, src := range h.sources {
+		snapshots[collector] = append([]time.Time(nil), src.snapshots...)
+	}
+	return snapshots
+}
+
+type response struct {
+	Snapshots map[string][]time.Time `json:"snapshots"`
+	Series    []Series               `json:"series"`
+}
+
+// Handler serves the series matching the collector, region, sku and price_tier query parameters as JSON, along with
+// the times of the snapshots they cover.
+func (h *History) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := Query{
+			Collector: params.Get("collector"),
+			Region:    params.Get("region"),
+			SKU:       params.Get("sku"),
+			PriceTier: params.Get("price_tier"),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response{Snapshots: h.Snapshots(), Series: h.Series(q)})
+	})
+}
@@ -0,0 +1,136 @@
+package pricehistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(0))
+	assert.NotNil(t, New(1))
+}
+
+func TestHistory_nil(t *testing.T) {
+	var h *History
+	h.Record("aws_eks", []Price{{Region: "us-east-1", SKU: "m5.large", Value: 0.096}})
+	assert.Nil(t, h.Series(Query{}))
+	assert.Nil(t, h.Snapshots())
+}
+
+func TestHistory_Record(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := func(value float64) Price {
+		return Price{Region: "us-east-1", SKU: "m5.large", PriceTier: "ondemand", Unit: "usd_per_hour", Value: value}
+	}
+	tests := map[string]struct {
+		size       int
+		snapshots  [][]Price
+		wantPoints []float64
+		wantTimes  []int
+		wantSeries int
+	}{
+		"unchanged price keeps a single point": {
+			size:       3,
+			snapshots:  [][]Price{{price(1)}, {price(1)}, {price(1)}},
+			wantPoints: []float64{1},
+			wantTimes:  []int{0},
+			wantSeries: 1,
+		},
+		"every change is a point": {
+			size:       3,
+			snapshots:  [][]Price{{price(1)}, {price(2)}, {price(1)}},
+			wantPoints: []float64{1, 2, 1},
+			wantTimes:  []int{0, 1, 2},
+			wantSeries: 1,
+		},
+		"points before the oldest snapshot are dropped": {
+			size:       2,
+			snapshots:  [][]Price{{price(1)}, {price(2)}, {price(3)}},
+			wantPoints: []float64{2, 3},
+			wantTimes:  []int{1, 2},
+			wantSeries: 1,
+		},
+		"price of the oldest snapshot is kept": {
+			size:       2,
+			snapshots:  [][]Price{{price(1)}, {price(1)}, {price(1)}, {price(2)}},
+			wantPoints: []float64{1, 2},
+			wantTimes:  []int{0, 3},
+			wantSeries: 1,
+		},
+		"series missing from every snapshot are dropped": {
+			size:       2,
+			snapshots:  [][]Price{{price(1)}, {}, {}},
+			wantSeries: 0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := New(tt.size)
+			for i, prices := range tt.snapshots {
+				h.now = func() time.Time { return start.Add(time.Duration(i) * time.Hour) }
+				h.Record("aws_eks", prices)
+			}
+			assert.Len(t, h.Snapshots()["aws_eks"], min(tt.size, len(tt.snapshots)))
+			series := h.Series(Query{})
+			require.Len(t, series, tt.wantSeries)
+			if tt.wantSeries == 0 {
+				return
+			}
+			var values []float64
+			var times []int
+			for _, point := range series[0].Points {
+				values = append(values, point.Value)
+				times = append(times, int(point.Time.Sub(start).Hours()))
+			}
+			assert.Equal(t, tt.wantPoints, values)
+			assert.Equal(t, tt.wantTimes, times)
+		})
+	}
+}
+
+func TestHistory_Handler(t *testing.T) {
+	h := New(2)
+	h.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	h.Record("aws_eks", []Price{
+		{Region: "us-east-1", SKU: "m5.large", PriceTier: "ondemand", Unit: "usd_per_hour", Value: 0.096},
+		{Region: "us-east-1a", SKU: "m5.large", PriceTier: "spot", Unit: "usd_per_hour", Value: 0.04},
+		{Region: "us-east-1", SKU: "m5.xlarge", PriceTier: "ondemand", Unit: "usd_per_hour", Value: 0.192},
+	})
+	h.Record("gcp_compute", []Price{
+		{Region: "us-central1", SKU: "n2", PriceTier: "ondemand", Unit: "usd_per_core_hour", Value: 0.03},
+	})
+
+	tests := map[string]struct {
+		query string
+		want  []string
+	}{
+		"every series":      {query: "", want: []string{"m5.large", "m5.xlarge", "m5.large", "n2"}},
+		"by collector":      {query: "?collector=gcp_compute", want: []string{"n2"}},
+		"by sku and tier":   {query: "?sku=m5.large&price_tier=spot", want: []string{"m5.large"}},
+		"no matching sku":   {query: "?sku=m6i.large"},
+		"by region and sku": {query: "?region=us-east-1&sku=m5.xlarge", want: []string{"m5.xlarge"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/prices/history"+tt.query, nil))
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var resp response
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+			assert.Len(t, resp.Snapshots, 2)
+			var skus []string
+			for _, series := range resp.Series {
+				skus = append(skus, series.SKU)
+				require.Len(t, series.Points, 1)
+			}
+			assert.Equal(t, tt.want, skus)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package pricesource layers prices on top of the retail prices of the catalogs of the providers, eg the negotiated
+// rates of an account or prices fixed by hand until a wrong catalog price is fixed in a release.
+package pricesource
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// reloadInterval is how often the override file is checked for changes.
+const reloadInterval = time.Minute
+
+var ErrInvalidEntry = errors.New("invalid price entry")
+
+// Source is a layer of prices consulted before the retail price of a sku.
+type Source interface {
+	// Price returns the hourly price in USD of sku, eg a machine type, in region given its retail price. The second
+	// return value is false when the source doesn't price the sku, in which case the next layer is consulted.
+	Price(provider string, region string, sku string, retail float64) (float64, bool)
+}
+
+// Layers consults its sources in order, the first one pricing a sku wins. The retail price is used when none of them
+// does. A nil Layers always returns the retail price, which is the case when no override file is configured.
+type Layers []Source
+
+// Price returns the hourly price in USD of sku in region, see Layers.
+func (l Layers) Price(provider string, region string, sku string, retail float64) float64 {
+	for _, source := range l {
+		if price, ok := source.Price(provider, region, sku, retail); ok {
+			return price
+		}
+	}
+	return retail
+}
+
+// Scale returns the factor the retail price of sku in region is multiplied by, 1 when none of the layers prices it.
+// It's used to adjust the prices derived from the retail price, eg the price per core of an instance.
+func (l Layers) Scale(provider string, region string, sku string, retail float64) float64 {
+	if len(l) == 0 || retail <= 0 {
+		return 1
+	}
+	return l.Price(provider, region, sku, retail) / retail
+}
+
+// Override fixes the hourly price of a sku.
+type Override struct {
+	Provider string `yaml:"provider"`
+	// Region is the region of the sku, every region when empty or *.
+	Region string `yaml:"region"`
+	// SKU is the machine type, eg m5.large, n2-standard-4 or Standard_D4_v5.
+	SKU        string  `yaml:"sku"`
+	USDPerHour float64 `yaml:"usd_per_hour"`
+}
+
+// Negotiated is a discount off the retail price of the skus of a provider.
+type Negotiated struct {
+	Provider string `yaml:"provider"`
+	// Region is the region of the skus, every region when empty or *.
+	Region string `yaml:"region"`
+	// SKU is the machine type, every machine type when empty or *.
+	SKU string `yaml:"sku"`
+	// Discount is the share of the retail price that isn't billed, between 0 and 1.
+	Discount float64 `yaml:"discount"`
+}
+
+// prices is the content of an override file.
+type prices struct {
+	Overrides  []Override   `yaml:"overrides"`
+	Negotiated []Negotiated `yaml:"negotiated"`
+}
+
+// File is a Source backed by a YAML file of overrides and negotiated rates, an override wins over a negotiated rate.
+// The first matching entry of each list wins. The file is reloaded when it changes, a file that can't be loaded keeps
+// the prices of the previous one.
+type File struct {
+	path string
+	now  func() time.Time
+
+	m         sync.RWMutex
+	prices    prices
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// LoadFile loads the override file at path. It fails when the file can't be read or has invalid entries, so that a
+// mistake is caught at startup rather than when the file is reloaded.
+func LoadFile(path string) (*File, error) {
+	f := &File{path: path, now: time.Now}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("error reading price override file: %w", err)
+	}
+	f.checkedAt = f.now()
+	if info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("error reading price override file: %w", err)
+	}
+	var p prices
+	if err := yaml.Unmarshal(content, &p); err != nil {
+		return fmt.Errorf("error parsing price override file %s: %w", f.path, err)
+	}
+	if err := p.validate(); err != nil {
+		return fmt.Errorf("error parsing price override file %s: %w", f.path, err)
+	}
+	f.prices = p
+	f.modTime = info.ModTime()
+	return nil
+}
+
+func (p prices) validate() error {
+	for i, o := range p.Overrides {
+		if o.Provider == "" || o.SKU == "" || o.SKU == "*" {
+			return fmt.Errorf("%w: override %d must have a provider and a sku", ErrInvalidEntry, i)
+		}
+		if o.USDPerHour < 0 {
+			return fmt.Errorf("%w: override %d has a negative price", ErrInvalidEntry, i)
+		}
+	}
+	for i, n := range p.Negotiated {
+		if n.Provider == "" {
+			return fmt.Errorf("%w: negotiated rate %d must have a provider", ErrInvalidEntry, i)
+		}
+		if n.Discount < 0 || n.Discount > 1 {
+			return fmt.Errorf("%w: negotiated rate %d must have a discount between 0 and 1", ErrInvalidEntry, i)
+		}
+	}
+	return nil
+}
+
+// reload loads the file again when it wasn't checked for a while.
+func (f *File) reload() {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.now().Sub(f.checkedAt) < reloadInterval {
+		return
+	}
+	if err := f.load(); err != nil {
+		log.Printf("keeping the previous price overrides: %s", err)
+	}
+}
+
+// Price satisfies Source.
+func (f *File) Price(provider string, region string, sku string, retail float64) (float64, bool) {
+	if f == nil {
+		return 0, false
+	}
+	f.reload()
+	f.m.RLock()
+	defer f.m.RUnlock()
+	for _, o := range f.prices.Overrides {
+		if strings.EqualFold(o.Provider, provider) && matches(o.Region, region) && o.SKU == sku {
+			return o.USDPerHour, true
+		}
+	}
+	for _, n := range f.prices.Negotiated {
+		if strings.EqualFold(n.Provider, provider) && matches(n.Region, region) && matches(n.SKU, sku) {
+			return retail * (1 - n.Discount), true
+		}
+	}
+	return 0, false
+}
+
+// matches reports whether the region or sku of an entry matches value, an empty one or * matches any value.
+func matches(pattern string, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
@@ -0,0 +1,106 @@
+package pricesource
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestLayers_Price(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	writeFile(t, path, `
+overrides:
+  - provider: aws
+    region: us-east-1
+    sku: m5.large
+    usd_per_hour: 0.05
+negotiated:
+  - provider: aws
+    sku: m5.large
+    discount: 0.5
+  - provider: gcp
+    region: "*"
+    discount: 0.1
+`, time.Now())
+	file, err := LoadFile(path)
+	require.NoError(t, err)
+	layers := Layers{file}
+
+	for _, tc := range []struct {
+		name     string
+		provider string
+		region   string
+		sku      string
+		expected float64
+	}{
+		{name: "override wins over a negotiated rate", provider: "aws", region: "us-east-1", sku: "m5.large", expected: 0.05},
+		{name: "negotiated rate of a sku", provider: "aws", region: "eu-west-1", sku: "m5.large", expected: 0.5},
+		{name: "negotiated rate of every sku", provider: "gcp", region: "us-central1", sku: "n2-standard-4", expected: 0.9},
+		{name: "retail price", provider: "aws", region: "us-east-1", sku: "m5.xlarge", expected: 1},
+		{name: "other provider", provider: "azure", region: "eastus", sku: "m5.large", expected: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, layers.Price(tc.provider, tc.region, tc.sku, 1), 1e-9)
+		})
+	}
+
+	assert.InDelta(t, 0.05/0.1, layers.Scale("aws", "us-east-1", "m5.large", 0.1), 1e-9, "an override scales the retail price to the overridden price")
+	assert.InDelta(t, 0.9, layers.Scale("gcp", "us-central1", "n2-standard-4", 0.2), 1e-9)
+
+	var none Layers
+	assert.Equal(t, 1.0, none.Price("aws", "us-east-1", "m5.large", 1), "no layers use the retail price")
+	assert.Equal(t, 1.0, none.Scale("aws", "us-east-1", "m5.large", 1))
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	for name, content := range map[string]string{
+		"not yaml":             "overrides: [",
+		"override without sku": "overrides:\n  - provider: aws\n    usd_per_hour: 1\n",
+		"negative price":       "overrides:\n  - provider: aws\n    sku: m5.large\n    usd_per_hour: -1\n",
+		"discount above 1":     "negotiated:\n  - provider: aws\n    discount: 1.5\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "prices.yaml")
+			writeFile(t, path, content, time.Now())
+			_, err := LoadFile(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeFile(t, path, "overrides:\n  - provider: aws\n    sku: m5.large\n    usd_per_hour: 0.05\n", modTime)
+	file, err := LoadFile(path)
+	require.NoError(t, err)
+	now := time.Now()
+	file.now = func() time.Time { return now }
+
+	writeFile(t, path, "overrides:\n  - provider: aws\n    sku: m5.large\n    usd_per_hour: 0.07\n", modTime.Add(time.Second))
+	price, _ := file.Price("aws", "us-east-1", "m5.large", 1)
+	assert.Equal(t, 0.05, price, "the file isn't checked again before the reload interval")
+
+	now = now.Add(reloadInterval)
+	price, _ = file.Price("aws", "us-east-1", "m5.large", 1)
+	assert.Equal(t, 0.07, price)
+
+	writeFile(t, path, "overrides: [", modTime.Add(2*time.Second))
+	now = now.Add(reloadInterval)
+	price, _ = file.Price("aws", "us-east-1", "m5.large", 1)
+	assert.Equal(t, 0.07, price, "an invalid file keeps the previous prices")
+}
@@ -0,0 +1,62 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrInvalidFamilyPattern = errors.New("invalid instance family pattern")
+
+// FamilyFilter selects the instance families and SKUs whose prices are kept when a pricing map is refreshed, so that
+// the prices of the families a deployment never runs, eg bare metal or HPC families, are dropped as they're fetched
+// rather than parsed. Patterns use the syntax of path.Match and are matched regardless of casing against both the
+// family and the SKU of a price, eg m5 or *.metal for AWS, n2d for GCP and Standard_HB* for Azure.
+type FamilyFilter struct {
+	// Include only keeps the prices of the families or SKUs matching one of the patterns, every price is kept when it's
+	// empty.
+	Include []string
+	// Exclude drops the prices of the families or SKUs matching any of the patterns, even when they are included.
+	Exclude []string
+}
+
+// Validate checks the syntax of every pattern. It's safe to call on a nil filter.
+func (f *FamilyFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrInvalidFamilyPattern, pattern, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the price of sku, which belongs to family, is kept. Either can be empty when the catalog
+// doesn't price it, eg GCP only prices families. It's safe to call on a nil filter.
+func (f *FamilyFilter) Matches(family string, sku string) bool {
+	if f == nil {
+		return true
+	}
+	family, sku = strings.ToLower(family), strings.ToLower(sku)
+	if len(f.Include) > 0 && !matchesFamily(f.Include, family, sku) {
+		return false
+	}
+	return !matchesFamily(f.Exclude, family, sku)
+}
+
+func matchesFamily(patterns []string, family string, sku string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		// The patterns are validated upfront, so the errors can be ignored
+		if matched, _ := path.Match(pattern, family); matched && family != "" {
+			return true
+		}
+		if matched, _ := path.Match(pattern, sku); matched && sku != "" {
+			return true
+		}
+	}
+	return false
+}
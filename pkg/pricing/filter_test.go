@@ -0,0 +1,71 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFamilyFilter_Matches(t *testing.T) {
+	tests := map[string]struct {
+		filter *FamilyFilter
+		family string
+		sku    string
+		want   bool
+	}{
+		"nil filter": {
+			family: "m5",
+			sku:    "m5.large",
+			want:   true,
+		},
+		"family included": {
+			filter: &FamilyFilter{Include: []string{"m5", "c6*"}},
+			family: "m5",
+			sku:    "m5.large",
+			want:   true,
+		},
+		"family not included": {
+			filter: &FamilyFilter{Include: []string{"m5", "c6*"}},
+			family: "p4d",
+			sku:    "p4d.24xlarge",
+		},
+		"sku excluded": {
+			filter: &FamilyFilter{Exclude: []string{"*.metal"}},
+			family: "m5",
+			sku:    "m5.metal",
+		},
+		"included and excluded": {
+			filter: &FamilyFilter{Include: []string{"m5*"}, Exclude: []string{"*.metal"}},
+			family: "m5",
+			sku:    "m5.metal",
+		},
+		"regardless of casing": {
+			filter: &FamilyFilter{Exclude: []string{"standard_hb*"}},
+			sku:    "Standard_HB120rs_v3",
+		},
+		"family only": {
+			filter: &FamilyFilter{Include: []string{"n2d"}},
+			family: "n2d",
+			want:   true,
+		},
+		"empty sku doesn't match a wildcard": {
+			filter: &FamilyFilter{Exclude: []string{"*"}},
+			family: "",
+			sku:    "",
+			want:   true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(tt.family, tt.sku))
+		})
+	}
+}
+
+func TestFamilyFilter_Validate(t *testing.T) {
+	var filter *FamilyFilter
+	require.NoError(t, filter.Validate())
+	require.NoError(t, (&FamilyFilter{Include: []string{"m5*"}, Exclude: []string{"*.metal"}}).Validate())
+	require.ErrorIs(t, (&FamilyFilter{Exclude: []string{"[m5"}}).Validate(), ErrInvalidFamilyPattern)
+}
@@ -0,0 +1,84 @@
+// Package pricing looks up the prices of instances in the pricing maps loaded by the collectors of every provider, so
+// that a Go service embedding the exporter can price an instance without scraping its metrics.
+//
+// A Lookup is passed to the config of a provider, eg aws.Config.PriceLookup, and the collectors pricing instances set
+// their pricing map in it every time they refresh it. A provider is only known once its pricing map was loaded, which
+// happens on the first collection.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+
+	TierOnDemand = "ondemand"
+	TierSpot     = "spot"
+)
+
+var (
+	ErrProviderNotLoaded = errors.New("no pricing map loaded for provider")
+	ErrUnknownTier       = errors.New("unknown price tier")
+	// ErrPriceNotFound is wrapped by the sources when the pricing map doesn't price an instance.
+	ErrPriceNotFound = errors.New("price not found")
+)
+
+// Price is the hourly list price of an instance in USD. The catalogs don't all price the same units: AWS prices an
+// instance type and splits it between its cores and memory, GCP only prices the cores and memory of a machine family
+// and Azure only prices a VM size. The units a catalog doesn't price are zero.
+type Price struct {
+	USDPerHour            float64
+	USDPerCoreHour        float64
+	USDPerGiBHour         float64
+	USDPerAcceleratorHour float64
+}
+
+// Source prices the instances of a provider. tier is either TierOnDemand or TierSpot.
+type Source interface {
+	PriceForInstance(region string, machineType string, tier string) (Price, error)
+}
+
+// Lookup holds the latest pricing map of every provider. A nil Lookup has no pricing maps, which is the case when the
+// exporter isn't embedded, and is safe to set pricing maps in.
+type Lookup struct {
+	m       sync.RWMutex
+	sources map[string]Source
+}
+
+// New returns an empty Lookup.
+func New() *Lookup {
+	return &Lookup{sources: make(map[string]Source)}
+}
+
+// Set replaces the pricing map of provider.
+func (l *Lookup) Set(provider string, source Source) {
+	if l == nil {
+		return
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.sources[provider] = source
+}
+
+// PriceForInstance returns the price of an instance of machineType in region at tier, eg
+// PriceForInstance("aws", "us-east-1", "m5.large", "ondemand").
+func (l *Lookup) PriceForInstance(provider string, region string, machineType string, tier string) (Price, error) {
+	if tier != TierOnDemand && tier != TierSpot {
+		return Price{}, fmt.Errorf("%w: %q", ErrUnknownTier, tier)
+	}
+	if l == nil {
+		return Price{}, fmt.Errorf("%w %s", ErrProviderNotLoaded, provider)
+	}
+	l.m.RLock()
+	source, ok := l.sources[provider]
+	l.m.RUnlock()
+	if !ok {
+		return Price{}, fmt.Errorf("%w %s", ErrProviderNotLoaded, provider)
+	}
+	return source.PriceForInstance(region, machineType, tier)
+}
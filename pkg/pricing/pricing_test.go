@@ -0,0 +1,69 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource map[string]Price
+
+func (f fakeSource) PriceForInstance(region string, machineType string, tier string) (Price, error) {
+	price, ok := f[region+"/"+machineType+"/"+tier]
+	if !ok {
+		return Price{}, ErrPriceNotFound
+	}
+	return price, nil
+}
+
+func TestLookup_PriceForInstance(t *testing.T) {
+	lookup := New()
+	lookup.Set(ProviderAWS, fakeSource{"us-east-1/m5.large/ondemand": {USDPerHour: 0.096}})
+
+	tests := map[string]struct {
+		provider string
+		tier     string
+		want     Price
+		wantErr  error
+	}{
+		"loaded provider": {
+			provider: ProviderAWS,
+			tier:     TierOnDemand,
+			want:     Price{USDPerHour: 0.096},
+		},
+		"price the source doesn't have": {
+			provider: ProviderAWS,
+			tier:     TierSpot,
+			wantErr:  ErrPriceNotFound,
+		},
+		"provider not loaded": {
+			provider: ProviderGCP,
+			tier:     TierOnDemand,
+			wantErr:  ErrProviderNotLoaded,
+		},
+		"unknown tier": {
+			provider: ProviderAWS,
+			tier:     "reserved",
+			wantErr:  ErrUnknownTier,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			price, err := lookup.PriceForInstance(tt.provider, "us-east-1", "m5.large", tt.tier)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, price)
+		})
+	}
+}
+
+func TestLookup_Nil(t *testing.T) {
+	var lookup *Lookup
+	lookup.Set(ProviderAWS, fakeSource{})
+	_, err := lookup.PriceForInstance(ProviderAWS, "us-east-1", "m5.large", TierOnDemand)
+	assert.ErrorIs(t, err, ErrProviderNotLoaded)
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+// maxBackoffFactor caps how many times longer than its configured interval the refresh interval of a throttled
+// collector grows.
+const maxBackoffFactor = 8
+
+var (
+	// RefreshIntervalDesc reports the current refresh interval of a collector, which is lengthened while the cloud
+	// provider APIs throttle its refreshes and goes back to the configured interval once they succeed again.
+	RefreshIntervalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.ExporterName, "collector", "refresh_interval_seconds"),
+		"The current interval between two refreshes of the prices of a collector in seconds. It grows above the configured interval while the cloud provider APIs throttle the collector.",
+		[]string{"provider", "collector"},
+		nil,
+	)
+	// ThrottledRefreshesTotal counts the refreshes that failed because the cloud provider APIs throttled the exporter,
+	// once the retries of the SDK were exhausted.
+	ThrottledRefreshesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, "collector", "throttled_refreshes_total"),
+			Help: "Total number of refreshes of a collector that failed because the cloud provider APIs throttled them.",
+		},
+		[]string{"provider", "collector"},
+	)
+)
+
+// IsThrottled reports whether err, or any error it wraps, is a throttling response of AWS, GCP or Azure.
+func IsThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		if _, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; ok {
+			return true
+		}
+	}
+	var httpErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &httpErr) && httpErr.HTTPStatusCode() == http.StatusTooManyRequests {
+		return true
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) && googleErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	var azureErr *azcore.ResponseError
+	if errors.As(err, &azureErr) && azureErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if s, ok := status.FromError(err); ok && s.Code() == codes.ResourceExhausted {
+		return true
+	}
+	return false
+}
+
+// Backoff adapts the refresh interval of a collector to the throttling of the cloud provider APIs. Every throttled
+// refresh doubles the interval, up to maxBackoffFactor times the configured one, and every successful refresh halves it
+// until it's back to the configured interval.
+type Backoff struct {
+	provider  string
+	collector string
+	base      time.Duration
+	m         sync.Mutex
+	interval  time.Duration
+}
+
+// NewBackoff returns a Backoff for a collector refreshing its prices every interval.
+func NewBackoff(provider string, collector string, interval time.Duration) *Backoff {
+	return &Backoff{
+		provider:  provider,
+		collector: collector,
+		base:      interval,
+		interval:  interval,
+	}
+}
+
+// Next adapts the interval to the outcome of a refresh that started at now and returns when the collector should
+// refresh next. A throttled refresh is retried after the lengthened interval, instead of on the next scrape, while
+// any other failure is retried right away as before. Collectors without any prices yet retry on every scrape anyway.
+func (b *Backoff) Next(now time.Time, err error) time.Time {
+	b.m.Lock()
+	defer b.m.Unlock()
+	switch {
+	case IsThrottled(err):
+		ThrottledRefreshesTotal.WithLabelValues(b.provider, b.collector).Inc()
+		b.interval = min(2*b.interval, maxBackoffFactor*b.base)
+	case err != nil:
+		return now
+	default:
+		b.interval = max(b.interval/2, b.base)
+	}
+	return utils.NextScrape(now, b.interval)
+}
+
+// Interval returns the current refresh interval.
+func (b *Backoff) Interval() time.Duration {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.interval
+}
+
+// Emit sends the RefreshIntervalDesc metric of the collector.
+func (b *Backoff) Emit(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(RefreshIntervalDesc, prometheus.GaugeValue, b.Interval().Seconds(), b.provider, b.collector)
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestIsThrottled(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"no error":           {err: nil, want: false},
+		"other error":        {err: errors.New("boom"), want: false},
+		"aws throttling":     {err: fmt.Errorf("listing prices: %w", &smithy.GenericAPIError{Code: "ThrottlingException"}), want: true},
+		"aws other api":      {err: &smithy.GenericAPIError{Code: "AccessDeniedException"}, want: false},
+		"gcp rest":           {err: fmt.Errorf("listing skus: %w", &googleapi.Error{Code: http.StatusTooManyRequests}), want: true},
+		"gcp rest forbidden": {err: &googleapi.Error{Code: http.StatusForbidden}, want: false},
+		"gcp grpc":           {err: status.Error(codes.ResourceExhausted, "quota exceeded"), want: true},
+		"gcp grpc not found": {err: status.Error(codes.NotFound, "not found"), want: false},
+		"azure":              {err: fmt.Errorf("listing namespaces: %w", &azcore.ResponseError{StatusCode: http.StatusTooManyRequests}), want: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsThrottled(tt.err))
+		})
+	}
+}
+
+func TestBackoff_Next(t *testing.T) {
+	now := time.Now()
+	throttled := &smithy.GenericAPIError{Code: "Throttling"}
+	backoff := NewBackoff("test", "TestBackoff_Next", time.Hour)
+
+	next := backoff.Next(now, throttled)
+	assert.Equal(t, 2*time.Hour, backoff.Interval())
+	assert.False(t, next.Before(now.Add(2*time.Hour)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ThrottledRefreshesTotal.WithLabelValues("test", "TestBackoff_Next")))
+
+	for i := 0; i < 5; i++ {
+		backoff.Next(now, throttled)
+	}
+	assert.Equal(t, maxBackoffFactor*time.Hour, backoff.Interval(), "the interval is capped")
+
+	// Other errors are retried on the next scrape and don't change the interval
+	assert.Equal(t, now, backoff.Next(now, errors.New("boom")))
+	assert.Equal(t, maxBackoffFactor*time.Hour, backoff.Interval())
+
+	backoff.Next(now, nil)
+	assert.Equal(t, 4*time.Hour, backoff.Interval())
+	for i := 0; i < 5; i++ {
+		backoff.Next(now, nil)
+	}
+	assert.Equal(t, time.Hour, backoff.Interval(), "the interval goes back to the configured one")
+	ch := make(chan prometheus.Metric, 1)
+	backoff.Emit(ch)
+	assert.Equal(t, &utils.MetricResult{
+		FqName:     "cloudcost_exporter_collector_refresh_interval_seconds",
+		Labels:     utils.LabelMap{"provider": "test", "collector": "TestBackoff_Next"},
+		Value:      3600,
+		MetricType: prometheus.GaugeValue,
+	}, utils.ReadMetrics(<-ch))
+}
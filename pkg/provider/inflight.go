@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrCollectionsInFlight = errors.New("collections still in flight")
+
+// InFlight counts the collections of a provider in flight, so that its clients are only closed once no collection uses
+// them anymore. The zero value is ready to use.
+type InFlight struct {
+	m     sync.Mutex
+	count int
+	// idle is closed once count drops to zero, it's only made while Wait waits.
+	idle chan struct{}
+}
+
+// Start counts a collection until the returned function is called.
+func (f *InFlight) Start() (done func()) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.count++
+	return func() {
+		f.m.Lock()
+		defer f.m.Unlock()
+		f.count--
+		if f.count == 0 && f.idle != nil {
+			close(f.idle)
+			f.idle = nil
+		}
+	}
+}
+
+// Wait waits until no collection is in flight. It returns ErrCollectionsInFlight when ctx is done first.
+func (f *InFlight) Wait(ctx context.Context) error {
+	f.m.Lock()
+	if f.count == 0 {
+		f.m.Unlock()
+		return nil
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle, count := f.idle, f.count
+	f.m.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %d collections: %w", ErrCollectionsInFlight, count, ctx.Err())
+	}
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInFlight_Wait(t *testing.T) {
+	var inFlight InFlight
+	require.NoError(t, inFlight.Wait(context.Background()), "nothing to wait for")
+
+	done := inFlight.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := inFlight.Wait(ctx)
+	assert.ErrorIs(t, err, ErrCollectionsInFlight)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	waited := make(chan error)
+	go func() { waited <- inFlight.Wait(context.Background()) }()
+	done()
+	select {
+	case err := <-waited:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Wait didn't return once the collection completed")
+	}
+}
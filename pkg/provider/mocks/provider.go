@@ -5,10 +5,12 @@
 //
 //	mockgen -source=provider.go -destination mocks/provider.go
 //
+
 // Package mock_provider is a generated GoMock package.
 package mock_provider
 
 import (
+	context "context"
 	reflect "reflect"
 
 	provider "github.com/grafana/cloudcost-exporter/pkg/provider"
@@ -216,6 +218,43 @@ func (mr *MockCollectorMockRecorder) Register(r any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockCollector)(nil).Register), r)
 }
 
+// MockResourceCollector is a mock of ResourceCollector interface.
+type MockResourceCollector struct {
+	ctrl     *gomock.Controller
+	recorder *MockResourceCollectorMockRecorder
+}
+
+// MockResourceCollectorMockRecorder is the mock recorder for MockResourceCollector.
+type MockResourceCollectorMockRecorder struct {
+	mock *MockResourceCollector
+}
+
+// NewMockResourceCollector creates a new mock instance.
+func NewMockResourceCollector(ctrl *gomock.Controller) *MockResourceCollector {
+	mock := &MockResourceCollector{ctrl: ctrl}
+	mock.recorder = &MockResourceCollectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResourceCollector) EXPECT() *MockResourceCollectorMockRecorder {
+	return m.recorder
+}
+
+// ResourceDescs mocks base method.
+func (m *MockResourceCollector) ResourceDescs() []*prometheus.Desc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResourceDescs")
+	ret0, _ := ret[0].([]*prometheus.Desc)
+	return ret0
+}
+
+// ResourceDescs indicates an expected call of ResourceDescs.
+func (mr *MockResourceCollectorMockRecorder) ResourceDescs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResourceDescs", reflect.TypeOf((*MockResourceCollector)(nil).ResourceDescs))
+}
+
 // MockProvider is a mock of Provider interface.
 type MockProvider struct {
 	ctrl     *gomock.Controller
@@ -239,6 +278,20 @@ func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockProvider) Close(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockProviderMockRecorder) Close(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockProvider)(nil).Close), ctx)
+}
+
 // Collect mocks base method.
 func (m *MockProvider) Collect(arg0 chan<- prometheus.Metric) {
 	m.ctrl.T.Helper()
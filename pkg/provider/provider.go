@@ -1,6 +1,8 @@
 package provider
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -20,7 +22,18 @@ type Collector interface {
 	Name() string
 }
 
+// ResourceCollector is implemented by the collectors exporting prices, eg the price sheet of a region, along with the
+// metrics of the resources they list. Only the metrics of ResourceDescs make a collection non-empty for the Watchdog, so
+// that a collector losing its whole inventory is reported even though its prices are still exported.
+type ResourceCollector interface {
+	ResourceDescs() []*prometheus.Desc
+}
+
 type Provider interface {
 	prometheus.Collector
 	RegisterCollectors(r Registry) error
+	// Close waits for the collections in flight until ctx is done, logs a summary of the collections and closes the
+	// clients of the provider. The clients are left open when collections are still in flight once ctx is done, in which
+	// case it returns ErrCollectionsInFlight.
+	Close(ctx context.Context) error
 }
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Providers runs several providers in the same exporter, eg a cloud provider along with the custom price feed. The
+// providers are collected concurrently, so that a slow one doesn't hold back the others.
+type Providers []Provider
+
+// Describe satisfies prometheus.Collector.
+func (p Providers) Describe(ch chan<- *prometheus.Desc) {
+	for _, csp := range p {
+		csp.Describe(ch)
+	}
+}
+
+// Collect satisfies prometheus.Collector.
+func (p Providers) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, csp := range p {
+		wg.Add(1)
+		go func(csp Provider) {
+			defer wg.Done()
+			csp.Collect(ch)
+		}(csp)
+	}
+	wg.Wait()
+}
+
+// RegisterCollectors registers the collectors of every provider.
+func (p Providers) RegisterCollectors(r Registry) error {
+	for _, csp := range p {
+		if err := csp.RegisterCollectors(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every provider, even when one of them fails to.
+func (p Providers) Close(ctx context.Context) error {
+	var errs []error
+	for _, csp := range p {
+		errs = append(errs, csp.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
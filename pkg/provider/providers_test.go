@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider exports whether the last scrape of its only collector succeeded.
+type fakeProvider struct {
+	name     string
+	closeErr error
+}
+
+func (f *fakeProvider) Describe(ch chan<- *prometheus.Desc) { ch <- CollectorUpDesc }
+
+func (f *fakeProvider) Collect(ch chan<- prometheus.Metric) {
+	ch <- NewCollectorUpMetric(f.name, "fake", nil)
+}
+
+func (f *fakeProvider) RegisterCollectors(Registry) error { return nil }
+
+func (f *fakeProvider) Close(context.Context) error { return f.closeErr }
+
+func TestProviders(t *testing.T) {
+	providers := Providers{&fakeProvider{name: "aws"}, &fakeProvider{name: "custom", closeErr: errors.New("boom")}}
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(providers))
+	require.NoError(t, providers.RegisterCollectors(registry))
+
+	count, err := testutil.GatherAndCount(registry, "cloudcost_exporter_collector_up")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	require.ErrorContains(t, providers.Close(context.Background()), "boom")
+}
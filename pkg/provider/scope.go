@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+var (
+	// ScopeLastScrapeErrorDesc reports the outcome of a single scope, eg a project, account or region, within a collector.
+	// Collectors that iterate over several scopes continue past a failing one and only fail as a whole when every scope failed.
+	ScopeLastScrapeErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.ExporterName, "collector", "scope_last_scrape_error"),
+		"Was the last scrape of a scope, eg a project, account or region, an error. 1 indicates an error.",
+		[]string{"provider", "collector", "scope"},
+		nil,
+	)
+)
+
+// NewScopeErrorMetric returns the ScopeLastScrapeErrorDesc metric for a scope, set to 1 when err is not nil.
+func NewScopeErrorMetric(provider string, collector string, scope string, err error) prometheus.Metric {
+	value := 0.0
+	if err != nil {
+		value = 1.0
+	}
+	return prometheus.MustNewConstMetric(ScopeLastScrapeErrorDesc, prometheus.GaugeValue, value, provider, collector, scope)
+}
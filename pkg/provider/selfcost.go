@@ -0,0 +1,20 @@
+package provider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+var (
+	// SelfCostTotal estimates what the requests of the exporter to the APIs of the cloud provider are billed, eg
+	// Cost Explorer charges every request. Requests to free APIs are recorded at 0 so that their volume is visible too.
+	// It lets the refresh interval of a collector be weighed against the bill it generates.
+	SelfCostTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, "", "self_cost_usd_total"),
+			Help: "Estimated cost in USD of the requests made by the exporter to the cloud provider APIs.",
+		},
+		[]string{"provider", "service", "operation"},
+	)
+)
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// CollectionSummary counts the collections of every collector of a provider since the exporter started, so that they
+// can be logged when the exporter shuts down.
+type CollectionSummary struct {
+	m          sync.Mutex
+	collectors map[string]*collectorSummary
+}
+
+type collectorSummary struct {
+	collections int
+	errors      int
+	lastErr     error
+}
+
+// Record counts a collection of a collector, failed when err isn't nil.
+func (s *CollectionSummary) Record(collector string, err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.collectors == nil {
+		s.collectors = make(map[string]*collectorSummary)
+	}
+	summary, ok := s.collectors[collector]
+	if !ok {
+		summary = &collectorSummary{}
+		s.collectors[collector] = summary
+	}
+	summary.collections++
+	if err != nil {
+		summary.errors++
+		summary.lastErr = err
+	}
+}
+
+// Log logs the collections and errors of every collector of a provider, sorted by collector.
+func (s *CollectionSummary) Log(ctx context.Context, logger *slog.Logger, provider string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	names := make([]string, 0, len(s.collectors))
+	for name := range s.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		summary := s.collectors[name]
+		attrs := []slog.Attr{
+			slog.String("provider", provider),
+			slog.String("collector", name),
+			slog.Int("collections", summary.collections),
+			slog.Int("errors", summary.errors),
+		}
+		if summary.lastErr != nil {
+			attrs = append(attrs, slog.String("last_error", summary.lastErr.Error()))
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "collection summary", attrs...)
+	}
+}
@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionSummary_Log(t *testing.T) {
+	var summary CollectionSummary
+	summary.Record("S3", nil)
+	summary.Record("EKS", errors.New("test collect error"))
+	summary.Record("EKS", nil)
+	summary.Record("S3", nil)
+
+	var out bytes.Buffer
+	summary.Log(context.Background(), slog.New(slog.NewTextHandler(&out, nil)), "aws")
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	assert.Contains(t, string(lines[0]), `provider=aws collector=EKS collections=2 errors=1 last_error="test collect error"`)
+	assert.Contains(t, string(lines[1]), `provider=aws collector=S3 collections=2 errors=0`)
+	assert.NotContains(t, string(lines[1]), "last_error")
+}
@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+var (
+	// CollectorUpDesc reports whether the last scrape of a single collector within a provider succeeded, so a broken
+	// collector, eg S3 billing, can be told apart from a healthy one, eg EC2 pricing, of the same provider.
+	CollectorUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.ExporterName, "collector", "up"),
+		"Was the last scrape of the collector successful. 1 indicates success.",
+		[]string{"provider", "collector"},
+		nil,
+	)
+)
+
+// NewCollectorUpMetric returns the CollectorUpDesc metric for a collector, set to 0 when err is not nil.
+func NewCollectorUpMetric(provider string, collector string, err error) prometheus.Metric {
+	value := 1.0
+	if err != nil {
+		value = 0.0
+	}
+	return prometheus.MustNewConstMetric(CollectorUpDesc, prometheus.GaugeValue, value, provider, collector)
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+var (
+	// EmptyCollectionsTotal counts the collections that succeeded without a single resource metric right after one that
+	// had some. A collector doesn't lose its whole inventory from a scrape to the next, so it's most likely a regression,
+	// eg a label mismatch silently dropping every metric, rather than the resources being deleted.
+	EmptyCollectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, "", "empty_collection_total"),
+			Help: "Total number of successful collections of a collector without any resource metric, while its previous collection had some.",
+		},
+		[]string{"provider", "collector"},
+	)
+	// SeriesEmittedTotal counts every metric sent by the collections of a collector, bookkeeping metrics included, so
+	// that the cardinality of the exporter, and what it costs on the metrics platform, can be broken down by collector.
+	SeriesEmittedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(cloudcost_exporter.ExporterName, "", "series_emitted_total"),
+			Help: "Total number of series emitted by the collections of a collector. Its rate divided by the scrape rate is the number of series of a collection.",
+		},
+		[]string{"provider", "collector"},
+	)
+)
+
+// bookkeepingDescs are the metrics a collector exports about itself rather than about the resources it lists, they
+// don't make a collection non-empty. The collectors implementing ResourceCollector only count their resource metrics.
+var bookkeepingDescs = map[*prometheus.Desc]bool{
+	RefreshIntervalDesc:       true,
+	ScopeLastScrapeErrorDesc:  true,
+	utils.PricingCoverageDesc: true,
+}
+
+// Watchdog remembers how many resource metrics the last successful collection of every collector of a provider sent, and
+// reports a collector whose metrics suddenly all disappear. The zero value is ready to use.
+type Watchdog struct {
+	m         sync.Mutex
+	resources map[string]int
+}
+
+// Collect collects c into ch, counting the metrics it sends in SeriesEmittedTotal and the resource metrics among them,
+// the metrics of its ResourceDescs when c is a ResourceCollector and every metric but the bookkeeping ones otherwise. A
+// successful collection without any resource metric after one with some is logged at error level and counted in
+// EmptyCollectionsTotal, with the default logger when logger is nil. A failed collection is already reported by
+// CollectorUpDesc, so it's neither checked nor remembered. c isn't collected anymore once ctx is done, eg when the
+// exporter shuts down.
+func (w *Watchdog) Collect(ctx context.Context, logger *slog.Logger, provider string, c Collector, ch chan<- prometheus.Metric) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	isResource := func(desc *prometheus.Desc) bool { return !bookkeepingDescs[desc] }
+	if rc, ok := c.(ResourceCollector); ok {
+		descs := make(map[*prometheus.Desc]bool)
+		for _, desc := range rc.ResourceDescs() {
+			descs[desc] = true
+		}
+		isResource = func(desc *prometheus.Desc) bool { return descs[desc] }
+	}
+	counted := make(chan prometheus.Metric)
+	done := make(chan int)
+	go func() {
+		resources, series := 0, 0
+		for metric := range counted {
+			if isResource(metric.Desc()) {
+				resources++
+			}
+			series++
+			ch <- metric
+		}
+		SeriesEmittedTotal.WithLabelValues(provider, c.Name()).Add(float64(series))
+		done <- resources
+	}()
+	err := c.Collect(counted)
+	close(counted)
+	resources := <-done
+	if err != nil {
+		return err
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.resources == nil {
+		w.resources = make(map[string]int)
+	}
+	previous := w.resources[c.Name()]
+	w.resources[c.Name()] = resources
+	if resources == 0 && previous > 0 {
+		EmptyCollectionsTotal.WithLabelValues(provider, c.Name()).Inc()
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.LogAttrs(ctx, slog.LevelError, "collection without any resource metric",
+			slog.String("provider", provider),
+			slog.String("collector", c.Name()),
+			slog.Int("previous_resource_metrics", previous))
+	}
+	return nil
+}
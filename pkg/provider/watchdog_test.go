@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testResourceDesc = prometheus.NewDesc("cloudcost_test_usd_per_hour", "A test resource.", nil, nil)
+	testPriceDesc    = prometheus.NewDesc("cloudcost_test_usd_per_gib", "A test price.", nil, nil)
+)
+
+// fakeCollector sends resources resource metrics, along with its refresh interval, and fails with err.
+type fakeCollector struct {
+	resources int
+	err       error
+}
+
+func (f *fakeCollector) Name() string                                      { return "Fake" }
+func (f *fakeCollector) Describe(_ chan<- *prometheus.Desc) error          { return nil }
+func (f *fakeCollector) CollectMetrics(_ chan<- prometheus.Metric) float64 { return 0 }
+func (f *fakeCollector) Register(_ Registry) error                         { return nil }
+
+func (f *fakeCollector) Collect(ch chan<- prometheus.Metric) error {
+	for i := 0; i < f.resources; i++ {
+		ch <- prometheus.MustNewConstMetric(testResourceDesc, prometheus.GaugeValue, 1)
+	}
+	ch <- prometheus.MustNewConstMetric(RefreshIntervalDesc, prometheus.GaugeValue, 60, "test", "Fake")
+	return f.err
+}
+
+func TestWatchdog_Collect(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, nil))
+	var watchdog Watchdog
+	collector := &fakeCollector{}
+	collect := func() int {
+		ch := make(chan prometheus.Metric, 10)
+		err := watchdog.Collect(context.Background(), logger, "test", collector, ch)
+		close(ch)
+		if collector.err != nil {
+			require.ErrorIs(t, err, collector.err)
+		} else {
+			require.NoError(t, err)
+		}
+		sent := 0
+		for range ch {
+			sent++
+		}
+		return sent
+	}
+	empty := EmptyCollectionsTotal.WithLabelValues("test", "Fake")
+
+	assert.Equal(t, 1, collect(), "every metric is forwarded")
+	assert.Equal(t, 0.0, testutil.ToFloat64(empty), "a collector that never had resources isn't empty")
+
+	collector.resources = 2
+	assert.Equal(t, 3, collect())
+	// A failed collection isn't checked, and doesn't hide the empty one after it
+	collector.resources, collector.err = 0, errors.New("test collect error")
+	collect()
+	assert.Equal(t, 0.0, testutil.ToFloat64(empty))
+	assert.Empty(t, out.String())
+
+	collector.err = nil
+	collect()
+	assert.Equal(t, 1.0, testutil.ToFloat64(empty))
+	assert.Contains(t, out.String(), `level=ERROR msg="collection without any resource metric" provider=test collector=Fake previous_resource_metrics=2`)
+
+	collect()
+	assert.Equal(t, 1.0, testutil.ToFloat64(empty), "a collector is only reported when its metrics disappear")
+	assert.Equal(t, 7.0, testutil.ToFloat64(SeriesEmittedTotal.WithLabelValues("test", "Fake")), "every metric sent is counted, failed collections included")
+}
+
+// fakePriceCollector sends a price metric on every collection along with resources resource metrics, and only counts
+// the resource metrics as resources.
+type fakePriceCollector struct {
+	fakeCollector
+}
+
+func (f *fakePriceCollector) Name() string { return "FakePrices" }
+
+func (f *fakePriceCollector) ResourceDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{testResourceDesc}
+}
+
+func (f *fakePriceCollector) Collect(ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(testPriceDesc, prometheus.GaugeValue, 0.1)
+	return f.fakeCollector.Collect(ch)
+}
+
+func TestWatchdog_CollectResourceCollector(t *testing.T) {
+	var watchdog Watchdog
+	collector := &fakePriceCollector{fakeCollector{resources: 1}}
+	empty := EmptyCollectionsTotal.WithLabelValues("test", "FakePrices")
+	collect := func() {
+		ch := make(chan prometheus.Metric, 10)
+		require.NoError(t, watchdog.Collect(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), "test", collector, ch))
+		close(ch)
+	}
+
+	collect()
+	assert.Equal(t, 0.0, testutil.ToFloat64(empty))
+	// Only the prices are left, which aren't resources of the collector
+	collector.resources = 0
+	collect()
+	assert.Equal(t, 1.0, testutil.ToFloat64(empty))
+}
+
+func TestWatchdog_CollectCancelled(t *testing.T) {
+	var watchdog Watchdog
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch := make(chan prometheus.Metric, 10)
+	err := watchdog.Collect(ctx, nil, "test", &fakeCollector{resources: 1}, ch)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, ch, "a collector isn't collected once the exporter shuts down")
+}
@@ -0,0 +1,150 @@
+// Package recordingrules generates the recommended Prometheus recording rules over the metrics of the exporter. Rules
+// are checked against the descriptors of the collectors, so a rule set generated by a given binary always matches the
+// metric names and labels that binary exports.
+package recordingrules
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/cloudcost-exporter/pkg/metricsdoc"
+)
+
+// GroupName is the name of the rule group the rules are written in.
+const GroupName = "cloudcost-exporter"
+
+// Source is a metric of the exporter a rule reads, with the labels the rule relies on.
+type Source struct {
+	Name   string
+	Labels []string
+}
+
+// Rule is a recording rule. A rule is only generated when every one of its sources is exported by the selected
+// providers.
+type Rule struct {
+	Record string
+	Expr   string
+	Labels map[string]string
+	// Sources are the metrics of the exporter the rule reads. Metrics of other exporters, eg kube-state-metrics, aren't
+	// listed.
+	Sources []Source
+}
+
+// Rules are the recommended recording rules. Hourly costs are recorded in USD/h, counters are turned into hourly
+// costs with a 1h rate.
+var Rules = []Rule{
+	{
+		Record:  "cluster:cloudcost_instance_usd_per_hour:sum",
+		Expr:    "sum by (cluster_name) (rate(cloudcost_aws_eks_usd_total[1h])) * 3600",
+		Labels:  map[string]string{"provider": "aws"},
+		Sources: []Source{{Name: "cloudcost_aws_eks_usd_total", Labels: []string{"cluster_name"}}},
+	},
+	{
+		Record: "cluster:cloudcost_instance_usd_per_hour:sum",
+		// The compute counters aren't labeled with the cluster, which is joined from the gke instance metrics
+		Expr:   "sum by (cluster_name) (rate(cloudcost_gcp_compute_usd_total[1h]) * on (instance, project) group_left (cluster_name) (max by (instance, project, cluster_name) (cloudcost_gcp_gke_instance_cpu_usd_per_core_hour) * 0 + 1)) * 3600",
+		Labels: map[string]string{"provider": "gcp"},
+		Sources: []Source{
+			{Name: "cloudcost_gcp_compute_usd_total", Labels: []string{"instance", "project"}},
+			{Name: "cloudcost_gcp_gke_instance_cpu_usd_per_core_hour", Labels: []string{"instance", "project", "cluster_name"}},
+		},
+	},
+	{
+		Record:  "project:cloudcost_instance_usd_per_hour:sum",
+		Expr:    "sum by (project, folder, org) (rate(cloudcost_gcp_compute_usd_total[1h])) * 3600",
+		Labels:  map[string]string{"provider": "gcp"},
+		Sources: []Source{{Name: "cloudcost_gcp_compute_usd_total", Labels: []string{"project", "folder", "org"}}},
+	},
+	{
+		Record:  "namespace:cloudcost_persistent_volume_usd_per_hour:sum",
+		Expr:    "sum by (cluster_name, namespace) (cloudcost_gcp_gke_persistent_volume_usd_per_hour)",
+		Labels:  map[string]string{"provider": "gcp"},
+		Sources: []Source{{Name: "cloudcost_gcp_gke_persistent_volume_usd_per_hour", Labels: []string{"cluster_name", "namespace"}}},
+	},
+	{
+		Record:  "namespace:cloudcost_cpu_requests_usd_per_hour:sum",
+		Expr:    `sum by (cluster_name, provider, namespace) (kube_pod_container_resource_requests{resource="cpu"} * on (node) group_left (cluster_name, provider) cloudcost_node_cpu_allocatable_usd_per_core_hour)`,
+		Sources: []Source{{Name: "cloudcost_node_cpu_allocatable_usd_per_core_hour", Labels: []string{"node", "cluster_name", "provider"}}},
+	},
+	{
+		Record:  "namespace:cloudcost_memory_requests_usd_per_hour:sum",
+		Expr:    `sum by (cluster_name, provider, namespace) (kube_pod_container_resource_requests{resource="memory"} / 2^30 * on (node) group_left (cluster_name, provider) cloudcost_node_memory_allocatable_usd_per_gib_hour)`,
+		Sources: []Source{{Name: "cloudcost_node_memory_allocatable_usd_per_gib_hour", Labels: []string{"node", "cluster_name", "provider"}}},
+	},
+	{
+		Record:  "cluster:cloudcost_custom_usd_per_hour:sum",
+		Expr:    "sum by (cluster_name, kind) (rate(cloudcost_custom_usd_total[1h])) * 3600",
+		Labels:  map[string]string{"provider": "custom"},
+		Sources: []Source{{Name: "cloudcost_custom_usd_total", Labels: []string{"cluster_name", "kind"}}},
+	},
+	{
+		Record:  "provider:cloudcost_exporter_self_cost_usd_per_hour:sum",
+		Expr:    "sum by (provider, service) (rate(cloudcost_exporter_self_cost_usd_total[1h])) * 3600",
+		Sources: []Source{{Name: "cloudcost_exporter_self_cost_usd_total", Labels: []string{"provider", "service"}}},
+	},
+}
+
+// Generate returns the rules whose sources are all described in metrics, in the order of rules. An error is returned
+// when a source is exported without one of the labels a rule relies on, which means the rule drifted from the metric.
+func Generate(rules []Rule, metrics []metricsdoc.Metric) ([]Rule, error) {
+	labelsByName := make(map[string][]string, len(metrics))
+	for _, metric := range metrics {
+		labelsByName[metric.Name] = metric.Labels
+	}
+	var generated []Rule
+	for _, rule := range rules {
+		exported := true
+		for _, source := range rule.Sources {
+			labels, ok := labelsByName[source.Name]
+			if !ok {
+				exported = false
+				break
+			}
+			for _, label := range source.Labels {
+				if !slices.Contains(labels, label) {
+					return nil, fmt.Errorf("rule %s relies on label %s of %s, which isn't exported", rule.Record, label, source.Name)
+				}
+			}
+		}
+		if exported {
+			generated = append(generated, rule)
+		}
+	}
+	return generated, nil
+}
+
+// WriteYAML writes the rules as a Prometheus rule file. version is the version of the exporter the rules were
+// generated by, which is written in a header comment.
+func WriteYAML(w io.Writer, rules []Rule, version string) error {
+	var sb strings.Builder
+	if version == "" {
+		version = "unknown version"
+	}
+	fmt.Fprintf(&sb, "# Recording rules for cloudcost-exporter %s, generated with `cloudcost-exporter docs rules`.\n", version)
+	sb.WriteString("groups:\n")
+	fmt.Fprintf(&sb, "  - name: %s\n", GroupName)
+	sb.WriteString("    rules:\n")
+	for _, rule := range rules {
+		// JSON strings are valid double quoted YAML scalars
+		fmt.Fprintf(&sb, "      - record: %s\n", rule.Record)
+		fmt.Fprintf(&sb, "        expr: %s\n", strconv.Quote(rule.Expr))
+		if len(rule.Labels) == 0 {
+			continue
+		}
+		sb.WriteString("        labels:\n")
+		names := make([]string, 0, len(rule.Labels))
+		for name := range rule.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&sb, "          %s: %s\n", name, strconv.Quote(rule.Labels[name]))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
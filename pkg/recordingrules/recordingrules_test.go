@@ -0,0 +1,96 @@
+package recordingrules
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/metricsdoc"
+)
+
+func TestGenerate(t *testing.T) {
+	rules := []Rule{
+		{
+			Record:  "cluster:cost:sum",
+			Expr:    "sum by (cluster_name) (cost)",
+			Sources: []Source{{Name: "cost", Labels: []string{"cluster_name"}}},
+		},
+		{
+			Record:  "project:other_cost:sum",
+			Expr:    "sum by (project) (other_cost)",
+			Sources: []Source{{Name: "other_cost", Labels: []string{"project"}}},
+		},
+	}
+	tests := map[string]struct {
+		metrics []metricsdoc.Metric
+		want    []string
+		wantErr bool
+	}{
+		"no metrics": {},
+		"every source exported": {
+			metrics: []metricsdoc.Metric{
+				{Name: "cost", Labels: []string{"cluster_name", "instance"}},
+				{Name: "other_cost", Labels: []string{"project"}},
+			},
+			want: []string{"cluster:cost:sum", "project:other_cost:sum"},
+		},
+		"source of another provider": {
+			metrics: []metricsdoc.Metric{
+				{Name: "cost", Labels: []string{"cluster_name"}},
+			},
+			want: []string{"cluster:cost:sum"},
+		},
+		"missing label": {
+			metrics: []metricsdoc.Metric{
+				{Name: "cost", Labels: []string{"cluster"}},
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Generate(rules, tt.metrics)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var records []string
+			for _, rule := range got {
+				records = append(records, rule.Record)
+			}
+			assert.Equal(t, tt.want, records)
+		})
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	rules := []Rule{
+		{
+			Record: "cluster:cost:sum",
+			Expr:   `sum by (cluster_name) (cost{resource="cpu"})`,
+			Labels: map[string]string{"provider": "gcp", "aggregation": "cluster"},
+		},
+		{
+			Record: "project:cost:sum",
+			Expr:   "sum by (project) (cost)",
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteYAML(&buf, rules, "0.1.0"))
+	want := `# Recording rules for cloudcost-exporter 0.1.0, generated with ` + "`cloudcost-exporter docs rules`" + `.
+groups:
+  - name: cloudcost-exporter
+    rules:
+      - record: cluster:cost:sum
+        expr: "sum by (cluster_name) (cost{resource=\"cpu\"})"
+        labels:
+          aggregation: "cluster"
+          provider: "gcp"
+      - record: project:cost:sum
+        expr: "sum by (project) (cost)"
+`
+	assert.Equal(t, want, buf.String())
+}
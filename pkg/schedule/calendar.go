@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+)
+
+const (
+	periodOnHours  = "on_hours"
+	periodOffHours = "off_hours"
+)
+
+var (
+	// ClusterActualHourlyCostDesc and ClusterExpectedHourlyCostDesc let teams verify that scaling a cluster down during
+	// its off-hours actually reduces its cost. They are only emitted for clusters with a schedule.
+	ClusterActualHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "cluster", "actual_usd_per_hour"),
+		"The cost of the priced instances of a cluster with a scale down schedule in USD/h.",
+		[]string{"cluster_name", "provider", "period"},
+		nil,
+	)
+	ClusterExpectedHourlyCostDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, "cluster", "expected_usd_per_hour"),
+		"The expected cost of a cluster with a scale down schedule in USD/h. It's the actual cost during on-hours, and the cost of the last on-hours scrape multiplied by the off-hours cost factor during off-hours.",
+		[]string{"cluster_name", "provider", "period"},
+		nil,
+	)
+)
+
+// Calendar holds the off-hours schedules of clusters and the cost of every cluster during its last on-hours scrape,
+// which the expected off-hours cost is projected from. It's safe for concurrent use.
+type Calendar struct {
+	schedules map[string]Schedule
+	location  *time.Location
+	// offHoursFactor is the share of the on-hours cost a cluster is expected to cost during its off-hours.
+	offHoursFactor float64
+	now            func() time.Time
+
+	m         sync.Mutex
+	baselines map[string]float64
+}
+
+// NewCalendar returns a Calendar of schedules keyed by cluster name, see Parse for the format of the schedules.
+// Cluster names are matched case-insensitively and schedules are evaluated in location, which defaults to UTC. nil is
+// returned when there are no schedules.
+func NewCalendar(schedules map[string]string, location *time.Location, offHoursFactor float64) (*Calendar, error) {
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	if offHoursFactor < 0 || offHoursFactor > 1 {
+		return nil, fmt.Errorf("off-hours cost factor must be between 0 and 1, got %v", offHoursFactor)
+	}
+	parsed := make(map[string]Schedule, len(schedules))
+	for cluster, spec := range schedules {
+		s, err := Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("schedule of cluster %s: %w", cluster, err)
+		}
+		parsed[strings.ToLower(cluster)] = s
+	}
+	return &Calendar{
+		schedules:      parsed,
+		location:       location,
+		offHoursFactor: offHoursFactor,
+		now:            time.Now,
+		baselines:      make(map[string]float64),
+	}, nil
+}
+
+// Spend returns an accumulator of the cost of the clusters during a single scrape. It returns nil, which is safe to use,
+// when c is nil.
+func (c *Calendar) Spend() *Spend {
+	if c == nil {
+		return nil
+	}
+	return &Spend{calendar: c, clusters: make(map[string]float64)}
+}
+
+// Spend accumulates the hourly cost of the instances of every scheduled cluster during a scrape.
+type Spend struct {
+	calendar *Calendar
+
+	m        sync.Mutex
+	clusters map[string]float64
+}
+
+// Add adds the hourly cost of an instance to its cluster. Instances of clusters without a schedule are ignored.
+func (s *Spend) Add(clusterName string, usdPerHour float64) {
+	if s == nil {
+		return
+	}
+	if _, ok := s.calendar.schedules[strings.ToLower(clusterName)]; !ok {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.clusters[clusterName] += usdPerHour
+}
+
+// Metrics returns the actual and expected cost metrics of the scheduled clusters seen during the scrape. The actual
+// cost during on-hours becomes the baseline the off-hours cost is projected from, so the expected cost is only known
+// once a cluster has been scraped during its on-hours.
+func (s *Spend) Metrics(provider string) []prometheus.Metric {
+	if s == nil {
+		return nil
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	c := s.calendar
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := c.now().In(c.location)
+
+	names := make([]string, 0, len(s.clusters))
+	for name := range s.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var metrics []prometheus.Metric
+	for _, name := range names {
+		actual := s.clusters[name]
+		period := periodOnHours
+		expected := actual
+		if c.schedules[strings.ToLower(name)].Contains(now) {
+			period = periodOffHours
+			baseline, ok := c.baselines[name]
+			if !ok {
+				metrics = append(metrics, prometheus.MustNewConstMetric(ClusterActualHourlyCostDesc, prometheus.GaugeValue, actual, name, provider, period))
+				continue
+			}
+			expected = baseline * c.offHoursFactor
+		} else {
+			c.baselines[name] = actual
+		}
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(ClusterActualHourlyCostDesc, prometheus.GaugeValue, actual, name, provider, period),
+			prometheus.MustNewConstMetric(ClusterExpectedHourlyCostDesc, prometheus.GaugeValue, expected, name, provider, period),
+		)
+	}
+	return metrics
+}
@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestNewCalendar(t *testing.T) {
+	calendar, err := NewCalendar(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Nil(t, calendar, "no schedules disable the calendar")
+	_, err = NewCalendar(map[string]string{"dev": "Sat-Sun"}, nil, 1.5)
+	assert.Error(t, err)
+	_, err = NewCalendar(map[string]string{"dev": "weekends"}, nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
+
+func TestSpend_Metrics(t *testing.T) {
+	calendar, err := NewCalendar(map[string]string{"Dev": "Sat-Sun"}, time.UTC, 0.25)
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC) // Friday
+	calendar.now = func() time.Time { return now }
+
+	scrape := func(costs map[string]float64) []*utils.MetricResult {
+		spend := calendar.Spend()
+		for cluster, cost := range costs {
+			spend.Add(cluster, cost/2)
+			spend.Add(cluster, cost/2)
+		}
+		var got []*utils.MetricResult
+		for _, m := range spend.Metrics("gcp") {
+			got = append(got, utils.ReadMetrics(m))
+		}
+		return got
+	}
+	metric := func(name string, value float64, period string) *utils.MetricResult {
+		return &utils.MetricResult{
+			FqName:     name,
+			Labels:     utils.LabelMap{"cluster_name": "dev", "provider": "gcp", "period": period},
+			Value:      value,
+			MetricType: prometheus.GaugeValue,
+		}
+	}
+
+	// Clusters without a schedule are ignored
+	assert.Equal(t, []*utils.MetricResult{
+		metric("cloudcost_cluster_actual_usd_per_hour", 10, "on_hours"),
+		metric("cloudcost_cluster_expected_usd_per_hour", 10, "on_hours"),
+	}, scrape(map[string]float64{"dev": 10, "prod": 100}))
+
+	now = now.Add(24 * time.Hour) // Saturday
+	assert.Equal(t, []*utils.MetricResult{
+		metric("cloudcost_cluster_actual_usd_per_hour", 4, "off_hours"),
+		metric("cloudcost_cluster_expected_usd_per_hour", 2.5, "off_hours"),
+	}, scrape(map[string]float64{"dev": 4}))
+
+	var nilCalendar *Calendar
+	spend := nilCalendar.Spend()
+	spend.Add("dev", 1)
+	assert.Empty(t, spend.Metrics("gcp"))
+}
+
+func TestSpend_MetricsWithoutBaseline(t *testing.T) {
+	calendar, err := NewCalendar(map[string]string{"dev": "Sat-Sun"}, time.UTC, 0)
+	require.NoError(t, err)
+	calendar.now = func() time.Time { return time.Date(2024, 5, 11, 12, 0, 0, 0, time.UTC) }
+	spend := calendar.Spend()
+	spend.Add("dev", 3)
+	metrics := spend.Metrics("aws")
+	require.Len(t, metrics, 1, "the expected cost is unknown until the cluster is scraped during its on-hours")
+	assert.Equal(t, "cloudcost_cluster_actual_usd_per_hour", utils.ReadMetrics(metrics[0]).FqName)
+}
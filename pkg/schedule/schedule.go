@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+var (
+	ErrInvalidSchedule = errors.New("invalid schedule")
+
+	weekdays = map[string]time.Weekday{
+		"sun": time.Sunday,
+		"mon": time.Monday,
+		"tue": time.Tuesday,
+		"wed": time.Wednesday,
+		"thu": time.Thursday,
+		"fri": time.Friday,
+		"sat": time.Saturday,
+	}
+)
+
+// Window is a weekly period, eg every weekday from 19:00 to 07:00. A window whose end is before its start ends the
+// next day, so Mon-Fri 19:00-07:00 ends on Saturday morning.
+type Window struct {
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+// Schedule is the set of weekly windows a cluster is scaled down in.
+type Schedule []Window
+
+// Parse parses a schedule of windows separated by semicolons, eg "Mon-Fri 19:00-07:00;Sat-Sun". Every window is a day or
+// a range of days, optionally followed by a range of times. Days without times are covered entirely.
+func Parse(spec string) (Schedule, error) {
+	var s Schedule
+	for _, window := range strings.Split(spec, ";") {
+		window = strings.TrimSpace(window)
+		if window == "" {
+			continue
+		}
+		w, err := parseWindow(window)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidSchedule, window, err)
+		}
+		s = append(s, w)
+	}
+	if len(s) == 0 {
+		return nil, fmt.Errorf("%w %q: no windows", ErrInvalidSchedule, spec)
+	}
+	return s, nil
+}
+
+func parseWindow(window string) (Window, error) {
+	days, times, _ := strings.Cut(window, " ")
+	w := Window{end: day}
+	first, last, isRange := strings.Cut(days, "-")
+	from, ok := weekdays[strings.ToLower(first)]
+	if !ok {
+		return w, fmt.Errorf("unknown day %q", first)
+	}
+	to := from
+	if isRange {
+		if to, ok = weekdays[strings.ToLower(last)]; !ok {
+			return w, fmt.Errorf("unknown day %q", last)
+		}
+	}
+	for d := from; ; d = (d + 1) % 7 {
+		w.days[d] = true
+		if d == to {
+			break
+		}
+	}
+	times = strings.TrimSpace(times)
+	if times == "" {
+		return w, nil
+	}
+	start, end, ok := strings.Cut(times, "-")
+	if !ok {
+		return w, fmt.Errorf("expected a range of times, eg 19:00-07:00")
+	}
+	var err error
+	if w.start, err = parseTime(start); err != nil {
+		return w, err
+	}
+	if w.end, err = parseTime(end); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("empty range of times")
+	}
+	return w, nil
+}
+
+// parseTime parses a time of day, eg 07:00, into the time since midnight. 24:00 is the end of the day.
+func parseTime(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return day, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns whether t falls within one of the windows of the schedule, in the location of t.
+func (s Schedule) Contains(t time.Time) bool {
+	for _, w := range s {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Window) contains(t time.Time) bool {
+	weekday := t.Weekday()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start < w.end {
+		return w.days[weekday] && offset >= w.start && offset < w.end
+	}
+	// The window ends the day after it started
+	previous := (weekday + 6) % 7
+	return (w.days[weekday] && offset >= w.start) || (w.days[previous] && offset < w.end)
+}
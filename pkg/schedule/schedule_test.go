@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		spec    string
+		wantErr bool
+	}{
+		"weekday nights and weekends": {spec: "Mon-Fri 19:00-07:00;Sat-Sun"},
+		"single day":                  {spec: "sun"},
+		"until the end of the day":    {spec: "Fri 18:00-24:00"},
+		"empty":                       {spec: " ; ", wantErr: true},
+		"unknown day":                 {spec: "Monday", wantErr: true},
+		"missing end time":            {spec: "Mon 19:00", wantErr: true},
+		"invalid time":                {spec: "Mon 7pm-8am", wantErr: true},
+		"empty range":                 {spec: "Mon 07:00-07:00", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidSchedule)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSchedule_Contains(t *testing.T) {
+	s, err := Parse("Mon-Fri 19:00-07:00;Sat-Sun")
+	require.NoError(t, err)
+	tests := map[string]struct {
+		time time.Time
+		want bool
+	}{
+		"monday morning":           {time: time.Date(2024, 5, 6, 6, 59, 0, 0, time.UTC), want: false},
+		"monday during the day":    {time: time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC), want: false},
+		"monday night":             {time: time.Date(2024, 5, 6, 19, 0, 0, 0, time.UTC), want: true},
+		"tuesday before 07:00":     {time: time.Date(2024, 5, 7, 6, 59, 0, 0, time.UTC), want: true},
+		"tuesday at 07:00":         {time: time.Date(2024, 5, 7, 7, 0, 0, 0, time.UTC), want: false},
+		"saturday after friday":    {time: time.Date(2024, 5, 11, 6, 0, 0, 0, time.UTC), want: true},
+		"sunday during the day":    {time: time.Date(2024, 5, 12, 12, 0, 0, 0, time.UTC), want: true},
+		"end of the weekend":       {time: time.Date(2024, 5, 12, 23, 59, 59, 0, time.UTC), want: true},
+		"monday after the weekend": {time: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), want: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Contains(tt.time))
+		})
+	}
+}
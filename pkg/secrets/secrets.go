@@ -0,0 +1,246 @@
+// Package secrets fetches the credentials of the cloud providers from a secret store, eg HashiCorp Vault, instead of
+// environment variables and mounted files, and refetches them so that rotated credentials are picked up without a
+// restart.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRefreshInterval is how often a secret is fetched again when no interval is configured.
+	DefaultRefreshInterval = 15 * time.Minute
+	// requestTimeout bounds a single request to Vault.
+	requestTimeout = 30 * time.Second
+
+	// addressEnv, tokenEnv and namespaceEnv are the environment variables of the Vault CLI.
+	addressEnv   = "VAULT_ADDR"
+	tokenEnv     = "VAULT_TOKEN"
+	namespaceEnv = "VAULT_NAMESPACE"
+)
+
+var (
+	ErrMissingAddress = errors.New("vault auth requires an address, set -vault.address or VAULT_ADDR")
+	ErrMissingToken   = errors.New("vault auth requires a token, set -vault.token-file or VAULT_TOKEN")
+	ErrMissingPath    = errors.New("vault auth requires the path of the secret")
+	ErrEmptySecret    = errors.New("secret has no data")
+)
+
+// Secret is the data of a secret, eg access_key and secret_key for AWS credentials.
+type Secret map[string]string
+
+// Source fetches the current version of a secret.
+type Source interface {
+	Fetch(ctx context.Context) (Secret, error)
+}
+
+// VaultConfig is how to reach Vault. It's shared by the providers, which each read their own path.
+type VaultConfig struct {
+	// Address is the URL of Vault, eg https://vault.example.com:8200. It defaults to VAULT_ADDR.
+	Address string
+	// TokenFile is read on every request, so that a token renewed by the Vault Agent is picked up. VAULT_TOKEN is
+	// used when it's empty.
+	TokenFile string
+	// Namespace is the Vault Enterprise namespace of the secret. It defaults to VAULT_NAMESPACE.
+	Namespace string
+	// RefreshInterval is how often the secret is fetched again. It defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+	// HTTPClient is the client of the requests to Vault, a client with a timeout is used when it's nil.
+	HTTPClient *http.Client
+}
+
+// Rotating fetches the secret at path and returns it as a Rotating secret. It fails when the secret can't be fetched
+// so that a mistake is caught at startup.
+func (c VaultConfig) Rotating(ctx context.Context, path string) (*Rotating, error) {
+	vault, err := NewVault(c, path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRotating(ctx, vault, c.RefreshInterval)
+}
+
+// Vault is a Source reading a secret with the HTTP API of Vault. It supports version 1 and 2 of the KV secrets engine
+// and the engines generating credentials, eg aws/creds/<role>.
+type Vault struct {
+	httpClient *http.Client
+	url        string
+	token      string
+	tokenFile  string
+	namespace  string
+}
+
+// NewVault returns a Source reading the secret at path, eg secret/data/cloudcost-exporter/aws for a KV v2 engine
+// mounted at secret.
+func NewVault(config VaultConfig, path string) (*Vault, error) {
+	address := config.Address
+	if address == "" {
+		address = os.Getenv(addressEnv)
+	}
+	if address == "" {
+		return nil, ErrMissingAddress
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+	v := &Vault{
+		httpClient: config.HTTPClient,
+		url:        strings.TrimSuffix(address, "/") + "/v1/" + path,
+		tokenFile:  config.TokenFile,
+		namespace:  config.Namespace,
+	}
+	if v.httpClient == nil {
+		v.httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	if v.namespace == "" {
+		v.namespace = os.Getenv(namespaceEnv)
+	}
+	if v.tokenFile == "" {
+		v.token = os.Getenv(tokenEnv)
+		if v.token == "" {
+			return nil, ErrMissingToken
+		}
+	}
+	return v, nil
+}
+
+// vaultResponse is the subset of a read response that is used. KV v2 nests the secret in another data field along
+// with its metadata.
+type vaultResponse struct {
+	Data map[string]any `json:"data"`
+}
+
+// Fetch satisfies Source.
+func (v *Vault) Fetch(ctx context.Context) (Secret, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := v.token
+	if v.tokenFile != "" {
+		content, err := os.ReadFile(v.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading vault secret: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error reading vault secret %s: unexpected status: %s", v.url, resp.Status)
+	}
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding vault secret: %w", err)
+	}
+	data := body.Data
+	_, hasData := data["data"]
+	_, hasMetadata := data["metadata"]
+	if hasData && hasMetadata {
+		// The data of a deleted version of a KV v2 secret is null
+		data, _ = data["data"].(map[string]any)
+	}
+	secret := Secret{}
+	for key, value := range data {
+		switch value := value.(type) {
+		case nil:
+		case string:
+			secret[key] = value
+		default:
+			// Nested values, eg the service account key of a KV secret written from a JSON file, are kept as JSON
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding vault secret key %s: %w", key, err)
+			}
+			secret[key] = string(encoded)
+		}
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrEmptySecret, v.url)
+	}
+	return secret, nil
+}
+
+// Rotating is a secret that is fetched again from its source once it's older than the refresh interval. The version
+// changes every time the secret does, which tells the clients built from it to be rebuilt. A secret that can't be
+// fetched keeps the previous one, as its credentials are usually still valid for a while.
+type Rotating struct {
+	source   Source
+	interval time.Duration
+	now      func() time.Time
+
+	m         sync.Mutex
+	secret    Secret
+	version   uint64
+	fetchedAt time.Time
+}
+
+// NewRotating fetches the secret of source, and again every interval, DefaultRefreshInterval when it's not positive.
+func NewRotating(ctx context.Context, source Source, interval time.Duration) (*Rotating, error) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	r := &Rotating{source: source, interval: interval, now: time.Now}
+	secret, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.secret = secret
+	r.version = 1
+	r.fetchedAt = r.now()
+	return r, nil
+}
+
+// Get returns the current secret and its version.
+func (r *Rotating) Get(ctx context.Context) (Secret, uint64) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.now().Sub(r.fetchedAt) < r.interval {
+		return r.secret, r.version
+	}
+	// Failures are retried on the next interval rather than on every call, which would flood the secret store
+	r.fetchedAt = r.now()
+	secret, err := r.source.Fetch(ctx)
+	if err != nil {
+		log.Printf("keeping the previous secret: %s", err)
+		return r.secret, r.version
+	}
+	if !equal(secret, r.secret) {
+		r.secret = secret
+		r.version++
+	}
+	return r.secret, r.version
+}
+
+// Interval is how often the secret is fetched again.
+func (r *Rotating) Interval() time.Duration {
+	return r.interval
+}
+
+func equal(a Secret, b Secret) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
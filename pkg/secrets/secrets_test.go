@@ -0,0 +1,203 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVault_Fetch(t *testing.T) {
+	tests := map[string]struct {
+		body string
+		want Secret
+	}{
+		"kv v2": {
+			body: `{"data": {"data": {"access_key": "AKIA", "secret_key": "secret"}, "metadata": {"version": 3}}}`,
+			want: Secret{"access_key": "AKIA", "secret_key": "secret"},
+		},
+		"kv v1": {
+			body: `{"data": {"client_id": "client", "client_secret": "secret"}}`,
+			want: Secret{"client_id": "client", "client_secret": "secret"},
+		},
+		"aws secrets engine without a session token": {
+			body: `{"lease_duration": 3600, "data": {"access_key": "AKIA", "secret_key": "secret", "security_token": null}}`,
+			want: Secret{"access_key": "AKIA", "secret_key": "secret"},
+		},
+		"nested json is kept as json": {
+			body: `{"data": {"data": {"credentials": {"type": "service_account"}}, "metadata": {}}}`,
+			want: Secret{"credentials": `{"type":"service_account"}`},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/secret/data/aws", r.URL.Path)
+				assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			t.Setenv(tokenEnv, "token")
+
+			vault, err := NewVault(VaultConfig{Address: server.URL}, "/secret/data/aws")
+			require.NoError(t, err)
+			got, err := vault.Fetch(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVault_FetchTokenFile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "team", r.Header.Get("X-Vault-Namespace"))
+		_, _ = w.Write([]byte(`{"data": {"key": "value"}}`))
+	}))
+	defer server.Close()
+
+	vault, err := NewVault(VaultConfig{Address: server.URL, TokenFile: tokenFile, Namespace: "team"}, "secret/aws")
+	require.NoError(t, err)
+	for _, token := range []string{"first\n", "renewed\n"} {
+		require.NoError(t, os.WriteFile(tokenFile, []byte(token), 0o600))
+		_, err := vault.Fetch(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"first", "renewed"}, tokens)
+}
+
+func TestVault_FetchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/v1/secret/empty":
+			_, _ = w.Write([]byte(`{"data": {"data": null, "metadata": {}}}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv(tokenEnv, "token")
+
+	vault, err := NewVault(VaultConfig{Address: server.URL}, "secret/forbidden")
+	require.NoError(t, err)
+	_, err = vault.Fetch(context.Background())
+	assert.ErrorContains(t, err, "403")
+
+	vault, err = NewVault(VaultConfig{Address: server.URL}, "secret/empty")
+	require.NoError(t, err)
+	_, err = vault.Fetch(context.Background())
+	assert.ErrorIs(t, err, ErrEmptySecret)
+}
+
+func TestNewVault(t *testing.T) {
+	tests := map[string]struct {
+		config  VaultConfig
+		path    string
+		env     map[string]string
+		wantErr error
+	}{
+		"from the environment": {
+			path: "secret/aws",
+			env:  map[string]string{addressEnv: "https://vault:8200", tokenEnv: "token"},
+		},
+		"token file": {
+			config: VaultConfig{Address: "https://vault:8200", TokenFile: "/vault/token"},
+			path:   "secret/aws",
+		},
+		"missing address": {
+			path:    "secret/aws",
+			env:     map[string]string{tokenEnv: "token"},
+			wantErr: ErrMissingAddress,
+		},
+		"missing token": {
+			config:  VaultConfig{Address: "https://vault:8200"},
+			path:    "secret/aws",
+			wantErr: ErrMissingToken,
+		},
+		"missing path": {
+			config:  VaultConfig{Address: "https://vault:8200", TokenFile: "/vault/token"},
+			wantErr: ErrMissingPath,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(addressEnv, tt.env[addressEnv])
+			t.Setenv(tokenEnv, tt.env[tokenEnv])
+			_, err := NewVault(tt.config, tt.path)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+type fakeSource struct {
+	secrets []Secret
+	err     error
+	calls   int
+}
+
+func (f *fakeSource) Fetch(context.Context) (Secret, error) {
+	f.calls++
+	if f.err != nil && f.calls > 1 {
+		return nil, f.err
+	}
+	return f.secrets[min(f.calls, len(f.secrets))-1], nil
+}
+
+func TestRotating_Get(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{secrets: []Secret{{"key": "first"}, {"key": "first"}, {"key": "rotated"}}}
+	rotating, err := NewRotating(ctx, source, time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	rotating.now = func() time.Time { return now }
+	rotating.fetchedAt = now
+
+	secret, version := rotating.Get(ctx)
+	assert.Equal(t, Secret{"key": "first"}, secret)
+	assert.Equal(t, uint64(1), version)
+	assert.Equal(t, 1, source.calls, "the secret isn't fetched again within the interval")
+
+	now = now.Add(time.Minute)
+	secret, version = rotating.Get(ctx)
+	assert.Equal(t, Secret{"key": "first"}, secret)
+	assert.Equal(t, uint64(1), version, "an unchanged secret keeps its version")
+
+	now = now.Add(time.Minute)
+	secret, version = rotating.Get(ctx)
+	assert.Equal(t, Secret{"key": "rotated"}, secret)
+	assert.Equal(t, uint64(2), version)
+	assert.Equal(t, 3, source.calls)
+}
+
+func TestRotating_GetKeepsThePreviousSecret(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{secrets: []Secret{{"key": "first"}}, err: errors.New("vault is sealed")}
+	rotating, err := NewRotating(ctx, source, time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	rotating.now = func() time.Time { return now.Add(time.Hour) }
+
+	secret, version := rotating.Get(ctx)
+	assert.Equal(t, Secret{"key": "first"}, secret)
+	assert.Equal(t, uint64(1), version)
+	rotating.Get(ctx)
+	assert.Equal(t, 2, source.calls, "a failed fetch is retried on the next interval")
+}
+
+func TestNewRotating_FailsAtStartup(t *testing.T) {
+	_, err := NewRotating(context.Background(), &fakeSource{err: errors.New("vault is sealed"), calls: 1}, 0)
+	assert.ErrorContains(t, err, "vault is sealed")
+}
@@ -0,0 +1,199 @@
+// Package storageclass maps the Kubernetes storage classes of clusters to the parameters their persistent volumes are
+// priced with, eg a gp3 class provisioning IOPS and throughput above the baseline, which the catalog price of the
+// volume type alone doesn't account for.
+package storageclass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+
+	cloudcostexporter "github.com/grafana/cloudcost-exporter"
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+)
+
+const (
+	subsystem = "kubernetes"
+
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+)
+
+var ErrInvalidClass = errors.New("invalid storage class")
+
+var (
+	// HourlyPriceDesc and VolumeHourlyPriceDesc are a price sheet of the mapped storage classes, to be joined with the
+	// capacity of the persistent volumes, eg kube_persistentvolume_capacity_bytes of kube-state-metrics, where the
+	// exporter doesn't price the volumes itself.
+	HourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "storage_class_usd_per_gib_hour"),
+		"The price of the capacity of the persistent volumes of a Kubernetes storage class in USD/(GiB*h), from the storage class file. volume_type is the type of the volumes the class provisions, eg gp3.",
+		[]string{"storage_class", "provider", "region", "volume_type"},
+		nil,
+	)
+	VolumeHourlyPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcostexporter.MetricPrefix, subsystem, "storage_class_usd_per_volume_hour"),
+		"The price every persistent volume of a Kubernetes storage class costs on top of its capacity in USD/h, eg provisioned IOPS and throughput, from the storage class file.",
+		[]string{"storage_class", "provider", "region"},
+		nil,
+	)
+)
+
+// Class is the pricing of the persistent volumes of a Kubernetes storage class.
+type Class struct {
+	// Name is the name of the StorageClass object.
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"`
+	// Type is the volume type the class provisions, eg gp3, pd-balanced or Premium_LRS. It prices the class in the
+	// price sheet, the volumes priced by the exporter keep the type of their disk.
+	Type string `yaml:"type"`
+	// Regional prices a GCP class at the price of the regional persistent disks, which are replicated across two
+	// zones.
+	Regional bool `yaml:"regional"`
+	// USDPerGiBHour replaces the catalog price of the capacity of the volumes when it's set, eg a negotiated rate.
+	USDPerGiBHour float64 `yaml:"usd_per_gib_hour"`
+	// USDPerHour is added to the cost of every volume, eg the provisioned IOPS and throughput of the class.
+	USDPerHour float64 `yaml:"usd_per_hour"`
+}
+
+// Cost returns the hourly cost of a volume of sizeGiB given the catalog cost of its capacity.
+func (c Class) Cost(sizeGiB float64, catalogCost float64) float64 {
+	if c.USDPerGiBHour > 0 {
+		catalogCost = c.USDPerGiBHour * sizeGiB
+	}
+	return catalogCost + c.USDPerHour
+}
+
+// OverridesCatalog reports whether the capacity of the volumes is priced without the catalog.
+func (c Class) OverridesCatalog() bool {
+	return c.USDPerGiBHour > 0
+}
+
+// Metrics returns the price sheet of the class in region given the catalog price of its volume type in USD/(GiB*h).
+// It returns nothing when the catalog doesn't price the type, found being false, and the class doesn't override it.
+func (c Class) Metrics(region string, catalogPrice float64, found bool) []prometheus.Metric {
+	if !found && !c.OverridesCatalog() {
+		return nil
+	}
+	price := catalogPrice
+	if c.OverridesCatalog() {
+		price = c.USDPerGiBHour
+	}
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(HourlyPriceDesc, prometheus.GaugeValue, price, c.Name, c.Provider, region, c.Type),
+		prometheus.MustNewConstMetric(VolumeHourlyPriceDesc, prometheus.GaugeValue, c.USDPerHour, c.Name, c.Provider, region),
+	}
+}
+
+func (c Class) validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("%w: missing name", ErrInvalidClass)
+	case c.Provider != ProviderAWS && c.Provider != ProviderGCP && c.Provider != ProviderAzure:
+		return fmt.Errorf("%w %s: provider must be one of %s, %s or %s", ErrInvalidClass, c.Name, ProviderAWS, ProviderGCP, ProviderAzure)
+	case c.USDPerGiBHour < 0 || c.USDPerHour < 0:
+		return fmt.Errorf("%w %s: negative price", ErrInvalidClass, c.Name)
+	case c.Regional && c.Provider != ProviderGCP:
+		return fmt.Errorf("%w %s: regional only applies to gcp", ErrInvalidClass, c.Name)
+	case c.Type == "" && c.USDPerGiBHour == 0 && c.USDPerHour == 0:
+		return fmt.Errorf("%w %s: needs a type or a price", ErrInvalidClass, c.Name)
+	}
+	return nil
+}
+
+// Classes are the storage classes of a storage class file, keyed by provider and then by name. A nil Classes has
+// no classes, which is the case when no file is configured.
+type Classes struct {
+	byProvider map[string]map[string]Class
+}
+
+// file is the content of a storage class file.
+type file struct {
+	StorageClasses []Class `yaml:"storage_classes"`
+}
+
+// LoadFile loads the storage class file at path. It fails when a class is invalid so that a mistake is caught at
+// startup.
+func LoadFile(path string) (*Classes, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage class file: %w", err)
+	}
+	var f file
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("error parsing storage class file %s: %w", path, err)
+	}
+	classes, err := New(f.StorageClasses)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing storage class file %s: %w", path, err)
+	}
+	return classes, nil
+}
+
+// New returns the Classes of classes, which have to be valid and unique per provider.
+func New(classes []Class) (*Classes, error) {
+	c := &Classes{byProvider: make(map[string]map[string]Class)}
+	for _, class := range classes {
+		if err := class.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := c.byProvider[class.Provider][class.Name]; ok {
+			return nil, fmt.Errorf("%w %s: duplicate class of %s", ErrInvalidClass, class.Name, class.Provider)
+		}
+		if c.byProvider[class.Provider] == nil {
+			c.byProvider[class.Provider] = make(map[string]Class)
+		}
+		c.byProvider[class.Provider][class.Name] = class
+	}
+	return c, nil
+}
+
+// Lookup returns the class named name of provider. The second return value is false when there's no such class.
+func (c *Classes) Lookup(provider string, name string) (Class, bool) {
+	if c == nil {
+		return Class{}, false
+	}
+	class, ok := c.byProvider[provider][name]
+	return class, ok
+}
+
+// ForProvider returns the classes of provider sorted by name.
+func (c *Classes) ForProvider(provider string) []Class {
+	if c == nil {
+		return nil
+	}
+	classes := make([]Class, 0, len(c.byProvider[provider]))
+	for _, class := range c.byProvider[provider] {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+	return classes
+}
+
+// ByVolume returns the classes of provider keyed by the name of the persistent volumes of the cluster the exporter
+// runs in that they provisioned. It returns nil when c or lister is nil, or when the volumes can't be listed as the
+// volumes are then priced from the catalog.
+func (c *Classes) ByVolume(ctx context.Context, provider string, lister kubernetes.PersistentVolumeLister) map[string]Class {
+	if c == nil || lister == nil || len(c.byProvider[provider]) == 0 {
+		return nil
+	}
+	volumes, err := lister.ListPersistentVolumes(ctx)
+	if err != nil {
+		log.Printf("error listing kubernetes persistent volumes: %s", err)
+		return nil
+	}
+	byVolume := make(map[string]Class)
+	for _, volume := range volumes {
+		if class, ok := c.Lookup(provider, volume.StorageClass); ok {
+			byVolume[volume.Name] = class
+		}
+	}
+	return byVolume
+}
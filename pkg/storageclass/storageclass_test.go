@@ -0,0 +1,167 @@
+package storageclass
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/cloudcost-exporter/pkg/kubernetes"
+	"github.com/grafana/cloudcost-exporter/pkg/utils"
+)
+
+func TestLoadFile(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		wantErr error
+	}{
+		"valid": {
+			content: `
+storage_classes:
+  - name: fast-ssd
+    provider: aws
+    type: gp3
+    usd_per_hour: 0.0137
+  - name: regional-balanced
+    provider: gcp
+    type: pd-balanced
+    regional: true
+  - name: fast-ssd
+    provider: azure
+    usd_per_gib_hour: 0.0002
+`,
+		},
+		"missing name": {
+			content: "storage_classes: [{provider: aws, type: gp3}]",
+			wantErr: ErrInvalidClass,
+		},
+		"unknown provider": {
+			content: "storage_classes: [{name: fast, provider: oci, type: gp3}]",
+			wantErr: ErrInvalidClass,
+		},
+		"negative price": {
+			content: "storage_classes: [{name: fast, provider: aws, usd_per_hour: -1}]",
+			wantErr: ErrInvalidClass,
+		},
+		"regional outside gcp": {
+			content: "storage_classes: [{name: fast, provider: aws, type: gp3, regional: true}]",
+			wantErr: ErrInvalidClass,
+		},
+		"neither a type nor a price": {
+			content: "storage_classes: [{name: fast, provider: aws}]",
+			wantErr: ErrInvalidClass,
+		},
+		"duplicate class": {
+			content: "storage_classes: [{name: fast, provider: aws, type: gp3}, {name: fast, provider: aws, type: io2}]",
+			wantErr: ErrInvalidClass,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage-classes.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			classes, err := LoadFile(path)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			class, ok := classes.Lookup(ProviderGCP, "regional-balanced")
+			require.True(t, ok)
+			assert.True(t, class.Regional)
+			_, ok = classes.Lookup(ProviderGCP, "fast-ssd")
+			assert.False(t, ok, "classes are looked up per provider")
+		})
+	}
+
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestClass_Cost(t *testing.T) {
+	tests := map[string]struct {
+		class       Class
+		sizeGiB     float64
+		catalogCost float64
+		want        float64
+	}{
+		"catalog": {
+			class:       Class{Type: "pd-ssd"},
+			sizeGiB:     100,
+			catalogCost: 0.2,
+			want:        0.2,
+		},
+		"catalog and provisioned iops": {
+			class:       Class{Type: "gp3", USDPerHour: 0.01},
+			sizeGiB:     100,
+			catalogCost: 0.2,
+			want:        0.21,
+		},
+		"negotiated rate": {
+			class:       Class{USDPerGiBHour: 0.001, USDPerHour: 0.01},
+			sizeGiB:     100,
+			catalogCost: 0.2,
+			want:        0.11,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.class.Cost(tt.sizeGiB, tt.catalogCost), 1e-9)
+		})
+	}
+}
+
+func TestClass_Metrics(t *testing.T) {
+	class := Class{Name: "fast-ssd", Provider: ProviderAWS, Type: "gp3", USDPerHour: 0.01}
+	assert.Empty(t, class.Metrics("us-east-1", 0, false), "a type the catalog doesn't price has no price sheet")
+
+	metrics := class.Metrics("us-east-1", 0.0001, true)
+	require.Len(t, metrics, 2)
+	price := utils.ReadMetrics(metrics[0])
+	assert.Equal(t, "cloudcost_kubernetes_storage_class_usd_per_gib_hour", price.FqName)
+	assert.Equal(t, utils.LabelMap{"storage_class": "fast-ssd", "provider": "aws", "region": "us-east-1", "volume_type": "gp3"}, price.Labels)
+	assert.Equal(t, 0.0001, price.Value)
+	perVolume := utils.ReadMetrics(metrics[1])
+	assert.Equal(t, "cloudcost_kubernetes_storage_class_usd_per_volume_hour", perVolume.FqName)
+	assert.Equal(t, 0.01, perVolume.Value)
+
+	class.USDPerGiBHour = 0.0002
+	metrics = class.Metrics("us-east-1", 0, false)
+	require.Len(t, metrics, 2, "an overridden price doesn't need the catalog")
+	assert.Equal(t, 0.0002, utils.ReadMetrics(metrics[0]).Value)
+}
+
+type fakeVolumes struct {
+	volumes []kubernetes.PersistentVolume
+	err     error
+}
+
+func (f fakeVolumes) ListPersistentVolumes(context.Context) ([]kubernetes.PersistentVolume, error) {
+	return f.volumes, f.err
+}
+
+func TestClasses_ByVolume(t *testing.T) {
+	classes, err := New([]Class{
+		{Name: "fast-ssd", Provider: ProviderGCP, Type: "pd-ssd", USDPerHour: 0.01},
+		{Name: "fast-ssd", Provider: ProviderAWS, Type: "gp3"},
+	})
+	require.NoError(t, err)
+	volumes := fakeVolumes{volumes: []kubernetes.PersistentVolume{
+		{Name: "pvc-1", StorageClass: "fast-ssd"},
+		{Name: "pvc-2", StorageClass: "standard-rwo"},
+	}}
+
+	byVolume := classes.ByVolume(context.Background(), ProviderGCP, volumes)
+	assert.Equal(t, map[string]Class{"pvc-1": {Name: "fast-ssd", Provider: ProviderGCP, Type: "pd-ssd", USDPerHour: 0.01}}, byVolume)
+
+	assert.Nil(t, classes.ByVolume(context.Background(), ProviderAzure, volumes), "a provider without classes doesn't list the volumes")
+	assert.Nil(t, classes.ByVolume(context.Background(), ProviderGCP, nil))
+	assert.Nil(t, classes.ByVolume(context.Background(), ProviderGCP, fakeVolumes{err: errors.New("forbidden")}))
+	var none *Classes
+	assert.Nil(t, none.ByVolume(context.Background(), ProviderGCP, volumes))
+	assert.Empty(t, none.ForProvider(ProviderGCP))
+}
@@ -0,0 +1,162 @@
+// Package tenant labels the metrics of the exporter with the tenant their scope, an AWS account, a GCP project or an
+// Azure subscription, belongs to, so that a single exporter can be shared by several organizations.
+package tenant
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultLabel is the name of the label the tenant is injected in when none is configured.
+const DefaultLabel = "tenant"
+
+// ScopeLabels are the labels that hold the scope of a metric: the GCP project, the AWS account of a linked account or
+// the Azure subscription. The first label a metric has is its scope.
+var ScopeLabels = []string{"project", "account_id", "subscription_id"}
+
+// exporterFamilyPrefixes are the prefixes of the metric families of the exporter process itself: the Go runtime, the
+// process and the metrics handler. They don't belong to any tenant, not even the default one.
+var exporterFamilyPrefixes = []string{"go_", "process_", "promhttp_"}
+
+// Tenants maps scopes to tenants. A nil Tenants labels nothing, which is the case when no tenant is configured.
+type Tenants struct {
+	label string
+	// scopes maps a lowercased scope to its tenant.
+	scopes map[string]string
+	// defaultTenant is the tenant of the metrics without a scope label, eg the prices or the costs of the account the
+	// exporter runs against.
+	defaultTenant string
+}
+
+// New returns Tenants injecting the tenant in label, or DefaultLabel when it's empty. scopes is keyed by scope, keys
+// are matched case-insensitively. It returns nil when neither scopes nor defaultTenant are set.
+func New(label string, scopes map[string]string, defaultTenant string) *Tenants {
+	if len(scopes) == 0 && defaultTenant == "" {
+		return nil
+	}
+	if label == "" {
+		label = DefaultLabel
+	}
+	normalized := make(map[string]string, len(scopes))
+	for scope, tenant := range scopes {
+		normalized[strings.ToLower(strings.TrimSpace(scope))] = tenant
+	}
+	return &Tenants{
+		label:         label,
+		scopes:        normalized,
+		defaultTenant: defaultTenant,
+	}
+}
+
+// Names returns the tenants, sorted and without duplicates.
+func (t *Tenants) Names() []string {
+	if t == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, tenant := range t.scopes {
+		if tenant != "" && !seen[tenant] {
+			seen[tenant] = true
+			names = append(names, tenant)
+		}
+	}
+	if t.defaultTenant != "" && !seen[t.defaultTenant] {
+		names = append(names, t.defaultTenant)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tenant returns the tenant of a metric. A metric with a scope label belongs to the tenant of its scope, or to none
+// when its scope isn't mapped, and a metric without one belongs to the default tenant.
+func (t *Tenants) Tenant(m *dto.Metric) string {
+	if t == nil {
+		return ""
+	}
+	for _, scopeLabel := range ScopeLabels {
+		for _, pair := range m.GetLabel() {
+			if pair.GetName() == scopeLabel {
+				return t.scopes[strings.ToLower(pair.GetValue())]
+			}
+		}
+	}
+	return t.defaultTenant
+}
+
+// isExporterFamily reports whether a metric family is one of the exporter process itself, see exporterFamilyPrefixes.
+func isExporterFamily(name string) bool {
+	for _, prefix := range exporterFamilyPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Gatherer returns a Gatherer labelling the metrics of g with their tenant. Metrics without a tenant, that already have
+// the tenant label or of the exporter process itself are left as is. g is returned unchanged when t is nil.
+func (t *Tenants) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	if t == nil {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, family := range families {
+			if isExporterFamily(family.GetName()) {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				t.inject(m)
+			}
+		}
+		return families, err
+	})
+}
+
+// TenantGatherer returns a Gatherer that only keeps the metrics of g belonging to tenant, labelled with it, so that
+// every tenant can be served its own metrics. The metrics of the exporter process itself, eg go_*, don't belong to any
+// tenant and are dropped.
+func (t *Tenants) TenantGatherer(g prometheus.Gatherer, tenant string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		var kept []*dto.MetricFamily
+		for _, family := range families {
+			if isExporterFamily(family.GetName()) {
+				continue
+			}
+			var metrics []*dto.Metric
+			for _, m := range family.GetMetric() {
+				if t.Tenant(m) != tenant {
+					continue
+				}
+				t.inject(m)
+				metrics = append(metrics, m)
+			}
+			if len(metrics) > 0 {
+				family.Metric = metrics
+				kept = append(kept, family)
+			}
+		}
+		return kept, err
+	})
+}
+
+// inject adds the tenant label to a metric, keeping its labels sorted by name as the registry does.
+func (t *Tenants) inject(m *dto.Metric) {
+	tenant := t.Tenant(m)
+	if tenant == "" {
+		return
+	}
+	for _, pair := range m.GetLabel() {
+		if pair.GetName() == t.label {
+			return
+		}
+	}
+	m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(t.label), Value: proto.String(tenant)})
+	sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+}
@@ -0,0 +1,97 @@
+package tenant
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRegistry(t *testing.T) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	projectCost := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_project_usd"}, []string{"project"})
+	projectCost.WithLabelValues("Payments-Prod").Set(1)
+	projectCost.WithLabelValues("search-prod").Set(2)
+	price := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_usd_per_hour"}, []string{"region"})
+	price.WithLabelValues("us-east-1").Set(4)
+	goroutines := prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_goroutines"})
+	goroutines.Set(8)
+	require.NoError(t, registry.Register(projectCost))
+	require.NoError(t, registry.Register(price))
+	require.NoError(t, registry.Register(goroutines))
+	return registry
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New("", nil, ""))
+	tenants := New("", map[string]string{"a": "payments", "b": "payments", "c": "search"}, "platform")
+	assert.Equal(t, DefaultLabel, tenants.label)
+	assert.Equal(t, []string{"payments", "platform", "search"}, tenants.Names())
+}
+
+func TestTenants_Tenant(t *testing.T) {
+	tenants := New("", map[string]string{"payments-prod": "payments"}, "platform")
+	tests := map[string]struct {
+		labels prometheus.Labels
+		want   string
+	}{
+		"scopes match regardless of casing": {labels: prometheus.Labels{"project": "Payments-Prod"}, want: "payments"},
+		"aws linked accounts":               {labels: prometheus.Labels{"account_id": "payments-prod"}, want: "payments"},
+		"an unmapped scope has no tenant":   {labels: prometheus.Labels{"subscription_id": "unmapped"}, want: ""},
+		"no scope is the default tenant":    {labels: prometheus.Labels{"region": "us-east-1"}, want: "platform"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test", ConstLabels: tt.labels})
+			var m dto.Metric
+			require.NoError(t, gauge.Write(&m))
+			assert.Equal(t, tt.want, tenants.Tenant(&m))
+		})
+	}
+}
+
+func TestTenants_Gatherer(t *testing.T) {
+	registry := testRegistry(t)
+	tenants := New("org", map[string]string{"payments-prod": "payments", "search-prod": "search"}, "platform")
+
+	err := testutil.GatherAndCompare(tenants.Gatherer(registry), strings.NewReader(`
+# HELP go_goroutines
+# TYPE go_goroutines gauge
+go_goroutines 8
+# HELP test_project_usd
+# TYPE test_project_usd gauge
+test_project_usd{org="payments",project="Payments-Prod"} 1
+test_project_usd{org="search",project="search-prod"} 2
+# HELP test_usd_per_hour
+# TYPE test_usd_per_hour gauge
+test_usd_per_hour{org="platform",region="us-east-1"} 4
+`))
+	assert.NoError(t, err)
+
+	// A nil Tenants leaves the metrics as is
+	var disabled *Tenants
+	assert.Equal(t, prometheus.Gatherer(registry), disabled.Gatherer(registry))
+}
+
+func TestTenants_TenantGatherer(t *testing.T) {
+	registry := testRegistry(t)
+	tenants := New("", map[string]string{"payments-prod": "payments", "search-prod": "search"}, "platform")
+
+	err := testutil.GatherAndCompare(tenants.TenantGatherer(registry, "payments"), strings.NewReader(`
+# HELP test_project_usd
+# TYPE test_project_usd gauge
+test_project_usd{project="Payments-Prod",tenant="payments"} 1
+`))
+	assert.NoError(t, err)
+	// The metrics of the exporter process aren't served to the default tenant
+	err = testutil.GatherAndCompare(tenants.TenantGatherer(registry, "platform"), strings.NewReader(`
+# HELP test_usd_per_hour
+# TYPE test_usd_per_hour gauge
+test_usd_per_hour{region="us-east-1",tenant="platform"} 4
+`))
+	assert.NoError(t, err)
+}
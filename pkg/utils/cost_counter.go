@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// costCounterExpiry is how long a series that isn't observed anymore is kept. A series that comes back within the
+// expiry, eg after a region failed to be listed for a few scrapes, keeps its previous total rather than being reset.
+// The time it went unobserved isn't billed though, as whether the resource existed in between is unknown.
+const costCounterExpiry = 24 * time.Hour
+
+type accumulatedCost struct {
+	labelValues []string
+	usdPerHour  float64
+	total       float64
+	lastSeen    time.Time
+	observed    bool
+	// missed is set when the series wasn't observed between two calls to Emit
+	missed bool
+}
+
+// CostCounter accumulates the hourly cost of resources into monotonically increasing counters in USD. Every
+// observation adds the hourly cost of the previous observation multiplied by the time elapsed since, so that
+// increase() over the counters gives the cost of a period regardless of how often the exporter was scraped or how
+// often the price changed in between.
+type CostCounter struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+
+	m      sync.Mutex
+	series map[string]*accumulatedCost
+}
+
+// NewCostCounter returns a CostCounter emitting desc, which must be a counter in USD.
+func NewCostCounter(desc *prometheus.Desc) *CostCounter {
+	return &CostCounter{
+		desc:   desc,
+		now:    time.Now,
+		series: make(map[string]*accumulatedCost),
+	}
+}
+
+// Observe records the current hourly cost of the resource identified by labelValues. The first observation of a
+// resource starts its counter at 0, and the observation of a resource that was missing from the previous Emit resumes
+// its counter without billing the time it was missing.
+func (c *CostCounter) Observe(usdPerHour float64, labelValues ...string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := c.now()
+	key := strings.Join(labelValues, "\xff")
+	cost, ok := c.series[key]
+	if !ok {
+		cost = &accumulatedCost{labelValues: slices.Clone(labelValues)}
+		c.series[key] = cost
+	} else if cost.missed {
+		// The series reappeared, accumulate from now on rather than billing the whole gap
+		cost.missed = false
+	} else if elapsed := now.Sub(cost.lastSeen); elapsed > 0 {
+		cost.total += cost.usdPerHour * elapsed.Hours()
+	}
+	cost.usdPerHour = usdPerHour
+	cost.lastSeen = now
+	cost.observed = true
+}
+
+// Emit sends the counters of the resources observed since the last call to Emit. Resources that haven't been
+// observed for longer than costCounterExpiry are forgotten.
+func (c *CostCounter) Emit(ch chan<- prometheus.Metric) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := c.now()
+	for key, cost := range c.series {
+		if !cost.observed {
+			cost.missed = true
+			if now.Sub(cost.lastSeen) > costCounterExpiry {
+				delete(c.series, key)
+			}
+			continue
+		}
+		cost.observed = false
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, cost.total, cost.labelValues...)
+	}
+}
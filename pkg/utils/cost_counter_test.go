@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCostDesc = prometheus.NewDesc("cloudcost_test_usd_total", "test", []string{"instance"}, nil)
+
+func emitted(t *testing.T, counter *CostCounter) map[string]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 10)
+	counter.Emit(ch)
+	close(ch)
+	totals := make(map[string]float64)
+	for m := range ch {
+		result := ReadMetrics(m)
+		require.NotNil(t, result)
+		assert.Equal(t, prometheus.CounterValue, result.MetricType)
+		totals[result.Labels["instance"]] = result.Value
+	}
+	return totals
+}
+
+func TestCostCounter(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	counter := NewCostCounter(testCostDesc)
+	counter.now = func() time.Time { return now }
+
+	counter.Observe(2, "a")
+	assert.Equal(t, map[string]float64{"a": 0}, emitted(t, counter))
+
+	// The elapsed time is accumulated at the previous price, even when the exporter wasn't scraped in between
+	now = now.Add(90 * time.Minute)
+	counter.Observe(4, "a")
+	counter.Observe(1, "b")
+	assert.Equal(t, map[string]float64{"a": 3, "b": 0}, emitted(t, counter))
+
+	// Resources that aren't observed are left out, and keep their total when they come back without billing the gap
+	now = now.Add(30 * time.Minute)
+	counter.Observe(1, "b")
+	assert.Equal(t, map[string]float64{"b": 0.5}, emitted(t, counter))
+	now = now.Add(30 * time.Minute)
+	counter.Observe(4, "a")
+	assert.Equal(t, map[string]float64{"a": 3}, emitted(t, counter))
+	now = now.Add(30 * time.Minute)
+	counter.Observe(4, "a")
+	assert.Equal(t, map[string]float64{"a": 5}, emitted(t, counter))
+
+	// Resources that aren't observed for longer than the expiry start over
+	now = now.Add(costCounterExpiry + time.Hour)
+	assert.Empty(t, emitted(t, counter))
+	counter.Observe(1, "b")
+	assert.Equal(t, map[string]float64{"b": 0}, emitted(t, counter))
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// maxJitterFraction is the largest share of the interval that is added as jitter to a refresh.
+const maxJitterFraction = 0.1
+
+// ScrapeIntervalFor returns the refresh interval of a collector. overrides is keyed by the lowercased collector name,
+// when the collector has no override the fallback is used.
+func ScrapeIntervalFor(overrides map[string]time.Duration, collector string, fallback time.Duration) time.Duration {
+	if interval, ok := overrides[strings.ToLower(collector)]; ok && interval > 0 {
+		return interval
+	}
+	return fallback
+}
+
+// NextScrape returns when a collector should refresh its data next. A random jitter of up to 10% of the interval is
+// added so that collectors that started together, and would otherwise hit the cloud provider APIs at the same time,
+// drift apart after their first refresh.
+func NextScrape(now time.Time, interval time.Duration) time.Time {
+	jitter := time.Duration(0)
+	if maxJitter := int64(float64(interval) * maxJitterFraction); maxJitter > 0 {
+		jitter = time.Duration(rand.Int64N(maxJitter))
+	}
+	return now.Add(interval + jitter)
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScrapeIntervalFor(t *testing.T) {
+	overrides := map[string]time.Duration{
+		"s3":  24 * time.Hour,
+		"eks": 0,
+	}
+	tests := map[string]struct {
+		collector string
+		want      time.Duration
+	}{
+		"override": {
+			collector: "s3",
+			want:      24 * time.Hour,
+		},
+		"override is case insensitive": {
+			collector: "S3",
+			want:      24 * time.Hour,
+		},
+		"zero override uses the fallback": {
+			collector: "eks",
+			want:      time.Hour,
+		},
+		"no override": {
+			collector: "gcs",
+			want:      time.Hour,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ScrapeIntervalFor(overrides, tt.collector, time.Hour); got != tt.want {
+				t.Errorf("ScrapeIntervalFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextScrape(t *testing.T) {
+	now := time.Now()
+	tests := map[string]struct {
+		interval time.Duration
+	}{
+		"zero interval": {
+			interval: 0,
+		},
+		"one hour": {
+			interval: time.Hour,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := NextScrape(now, tt.interval)
+				earliest := now.Add(tt.interval)
+				latest := now.Add(tt.interval + time.Duration(float64(tt.interval)*maxJitterFraction))
+				if got.Before(earliest) || (tt.interval > 0 && !got.Before(latest)) || (tt.interval == 0 && !got.Equal(earliest)) {
+					t.Fatalf("NextScrape() = %v, want between %v and %v", got, earliest, latest)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+const (
+	// ReasonUnexpectedUnit is the reason of the price entries priced in another unit than the one the pricing map
+	// converts from, eg a price per month where a price per hour is expected.
+	ReasonUnexpectedUnit = "unexpected_unit"
+	// ReasonUnexpectedCurrency is the reason of the price entries that aren't priced in USD.
+	ReasonUnexpectedCurrency = "unexpected_currency"
+	// ReasonOutlier is the reason of the prices that aren't positive or are above the sane maximum of their unit.
+	ReasonOutlier = "outlier"
+)
+
+// NewMalformedPriceEntriesTotal returns the cloudcost_<provider>_pricing_malformed_entries_total counter, which counts
+// the price entries skipped while generating the pricing maps of a provider, because they couldn't be parsed or
+// because their unit, currency or value isn't what the pricing map assumes. A change of the schema of the price list
+// of a provider then shows up as an increase of the counter rather than absurd costs. Every provider creates a single
+// counter shared by its collectors and registers it once.
+func NewMalformedPriceEntriesTotal(provider string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, provider, "pricing_malformed_entries_total"),
+		Help: "Total number of price entries that were skipped because they could not be parsed, or their unit, currency or price was unexpected.",
+	},
+		[]string{"source", "reason"},
+	)
+}
+
+// SanePrice reports whether a price is positive, finite and at most max.
+func SanePrice(price float64, max float64) bool {
+	return price > 0 && price <= max && !math.IsInf(price, 0) && !math.IsNaN(price)
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudcost_exporter "github.com/grafana/cloudcost-exporter"
+)
+
+const (
+	// ResourceTypeInstance is the resource_type label value used for compute instances.
+	ResourceTypeInstance = "instance"
+	// ResourceTypeDisk is the resource_type label value used for persistent disks.
+	ResourceTypeDisk = "disk"
+	// ResourceTypeSnapshot is the resource_type label value used for the snapshots of persistent disks.
+	ResourceTypeSnapshot = "snapshot"
+)
+
+// NewUnpricedResourcesTotal returns the cloudcost_<provider>_unpriced_resources_total counter, which counts the
+// resources that were discovered but dropped from the output because no price could be found. Every provider creates
+// a single counter shared by its collectors and registers it once.
+func NewUnpricedResourcesTotal(provider string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, provider, "unpriced_resources_total"),
+		Help: "Total number of resources that were skipped because no price could be found for them.",
+	},
+		[]string{"reason", "resource_type"},
+	)
+}
+
+// NewUnpricedMachineTypeInfoDesc returns the description of cloudcost_<provider>_unpriced_machine_type_info, which
+// lists the machine types that could not be priced during the last collection.
+func NewUnpricedMachineTypeInfoDesc(provider string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(cloudcost_exporter.MetricPrefix, provider, "unpriced_machine_type_info"),
+		"Machine types that were found during the last collection but could not be priced. Value is the number of instances affected.",
+		[]string{"collector", "region", "machine_type", "reason"},
+		nil,
+	)
+}
+
+// PricingCoverageDesc is the share of the resources a collector discovered during its last collection that it could
+// price. It turns the resources skipped because of a missing price into a single signal that can be alerted on.
+var PricingCoverageDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(cloudcost_exporter.ExporterName, "pricing", "coverage_ratio"),
+	"Share of the resources discovered by a collector during its last collection that could be priced, between 0 and 1.",
+	[]string{"provider", "collector"},
+	nil,
+)
+
+type unpricedKey struct {
+	region      string
+	machineType string
+	reason      string
+}
+
+// UnpricedMachineTypes tracks the machine types that couldn't be priced during a single collection, along with the
+// number of resources that were priced to compute the pricing coverage of the collector. It is not safe for
+// concurrent use.
+type UnpricedMachineTypes struct {
+	provider  string
+	collector string
+	desc      *prometheus.Desc
+	total     *prometheus.CounterVec
+	reason    func(error) string
+	seen      map[unpricedKey]float64
+	priced    float64
+	unpriced  float64
+}
+
+// NewUnpricedMachineTypes returns an UnpricedMachineTypes tracker for the given collector of provider. desc and total
+// are the metrics of the provider, and reason maps the pricing errors of the provider to a low cardinality reason
+// label.
+func NewUnpricedMachineTypes(provider, collector string, desc *prometheus.Desc, total *prometheus.CounterVec, reason func(error) string) *UnpricedMachineTypes {
+	return &UnpricedMachineTypes{
+		provider:  provider,
+		collector: collector,
+		desc:      desc,
+		total:     total,
+		reason:    reason,
+		seen:      make(map[unpricedKey]float64),
+	}
+}
+
+// Add records an instance of machineType in region that could not be priced and increments the unpriced resources
+// counter.
+func (u *UnpricedMachineTypes) Add(region, machineType string, err error) {
+	reason := u.reason(err)
+	u.total.WithLabelValues(reason, ResourceTypeInstance).Inc()
+	u.seen[unpricedKey{region: region, machineType: machineType, reason: reason}]++
+	u.unpriced++
+}
+
+// AddResource records a resource of resourceType other than an instance, eg a disk, that could not be priced and
+// increments the unpriced resources counter.
+func (u *UnpricedMachineTypes) AddResource(resourceType string, err error) {
+	u.total.WithLabelValues(u.reason(err), resourceType).Inc()
+	u.unpriced++
+}
+
+// Priced records a resource that could be priced.
+func (u *UnpricedMachineTypes) Priced() {
+	u.priced++
+}
+
+// Emit sends an unpriced machine type info metric for each machine type that was recorded, and the pricing coverage of
+// the collector when it discovered any resource.
+func (u *UnpricedMachineTypes) Emit(ch chan<- prometheus.Metric) {
+	for key, count := range u.seen {
+		ch <- prometheus.MustNewConstMetric(u.desc, prometheus.GaugeValue, count, u.collector, key.region, key.machineType, key.reason)
+	}
+	if discovered := u.priced + u.unpriced; discovered > 0 {
+		ch <- prometheus.MustNewConstMetric(PricingCoverageDesc, prometheus.GaugeValue, u.priced/discovered, u.provider, u.collector)
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpricedMachineTypes(t *testing.T) {
+	errNotFound := errors.New("not found")
+	total := NewUnpricedResourcesTotal("test")
+	unpriced := NewUnpricedMachineTypes("test", "collector", NewUnpricedMachineTypeInfoDesc("test"), total, func(err error) string {
+		if errors.Is(err, errNotFound) {
+			return "not_found"
+		}
+		return "unknown"
+	})
+	unpriced.Add("region", "machine-type", errNotFound)
+	unpriced.Add("region", "machine-type", errNotFound)
+	unpriced.Add("region", "other-machine-type", errors.New("other"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(total.WithLabelValues("not_found", ResourceTypeInstance)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(total.WithLabelValues("unknown", ResourceTypeInstance)))
+
+	unpriced.AddResource(ResourceTypeDisk, errNotFound)
+	assert.Equal(t, 1.0, testutil.ToFloat64(total.WithLabelValues("not_found", ResourceTypeDisk)))
+	for i := 0; i < 4; i++ {
+		unpriced.Priced()
+	}
+
+	ch := make(chan prometheus.Metric, 3)
+	unpriced.Emit(ch)
+	close(ch)
+	values := map[string]float64{}
+	for m := range ch {
+		result := ReadMetrics(m)
+		if result.FqName == "cloudcost_exporter_pricing_coverage_ratio" {
+			// 4 of the 8 discovered resources were priced
+			assert.Equal(t, LabelMap{"provider": "test", "collector": "collector"}, result.Labels)
+			assert.Equal(t, 0.5, result.Value)
+			continue
+		}
+		require.Equal(t, "cloudcost_test_unpriced_machine_type_info", result.FqName)
+		require.Equal(t, "collector", result.Labels["collector"])
+		values[result.Labels["machine_type"]+"/"+result.Labels["reason"]] = result.Value
+	}
+	assert.Equal(t, map[string]float64{"machine-type/not_found": 2, "other-machine-type/unknown": 1}, values)
+}
+
+func TestUnpricedMachineTypes_NoResources(t *testing.T) {
+	unpriced := NewUnpricedMachineTypes("test", "collector", NewUnpricedMachineTypeInfoDesc("test"), NewUnpricedResourcesTotal("test"), func(error) string { return "unknown" })
+	ch := make(chan prometheus.Metric, 1)
+	unpriced.Emit(ch)
+	close(ch)
+	assert.Empty(t, ch, "the coverage of a collector that didn't discover anything is unknown")
+}
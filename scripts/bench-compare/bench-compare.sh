@@ -0,0 +1,36 @@
+#!/usr/bin/env bash
+# Runs the benchmarks of the pricing maps on a base git ref and on the working tree, then compares both runs with
+# benchstat. Usage: bench-compare.sh [base-ref], the base defaults to origin/main.
+#
+# BENCH_PKGS, BENCH_COUNT and BENCH_TIME tune which packages are benchmarked, how many times and for how long, and
+# BENCH_OUT is where the results of both runs are written.
+set -euo pipefail
+
+base_ref="${1:-origin/main}"
+pkgs="${BENCH_PKGS:-./pkg/google/compute ./pkg/aws/compute ./pkg/azure/aks}"
+count="${BENCH_COUNT:-6}"
+benchtime="${BENCH_TIME:-1s}"
+out="${BENCH_OUT:-$(mktemp -d)}"
+mkdir -p "$out"
+
+root="$(git rev-parse --show-toplevel)"
+worktree="$(mktemp -d)"
+trap 'git -C "$root" worktree remove --force "$worktree"' EXIT
+
+run_benchmarks() {
+	# Packages without benchmarks, eg on a base that predates them, report nothing rather than failing the run
+	# shellcheck disable=SC2086
+	(cd "$1" && go test -run '^$' -bench . -benchmem -count "$count" -benchtime "$benchtime" $pkgs) | tee "$2"
+}
+
+git -C "$root" worktree add --detach "$worktree" "$base_ref" >/dev/null
+echo "Benchmarking ${base_ref}"
+run_benchmarks "$worktree" "$out/base.txt"
+echo "Benchmarking the working tree"
+run_benchmarks "$root" "$out/head.txt"
+
+benchstat=(go run golang.org/x/perf/cmd/benchstat@latest)
+if command -v benchstat >/dev/null; then
+	benchstat=(benchstat)
+fi
+"${benchstat[@]}" "$out/base.txt" "$out/head.txt" | tee "$out/benchstat.txt"
@@ -43,7 +43,10 @@ func run(config *Config) error {
 	if err != nil {
 		log.Fatal(err)
 	}
-	skus := billing.GetPricing(ctx, client, svcid)
+	skus, err := billing.GetPricing(ctx, client, svcid)
+	if err != nil {
+		log.Fatal(err)
+	}
 	file, err := os.Create(config.OutputFile)
 	if err != nil {
 		log.Fatal(err)